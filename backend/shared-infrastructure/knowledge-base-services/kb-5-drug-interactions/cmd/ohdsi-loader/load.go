@@ -0,0 +1,707 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// =============================================================================
+// Direct Load (Athena release -> Postgres, single transaction)
+// =============================================================================
+// Strategy: Stream filter → COPY into staging → Delta vs loaded release →
+//           Swap live tables → Closure → Rule impact report → Commit
+//
+// The live ohdsi_* tables are replaced inside one transaction, so the service
+// sees either the previous release or the new one, never a partial load.
+// =============================================================================
+
+// Concept change types reported in the delta
+const (
+	changeAdded      = "added"
+	changeDeprecated = "deprecated"
+	changeRemapped   = "remapped"
+	changeRemoved    = "removed" // Still valid, but no longer a member of the rule class
+)
+
+// loadOptions configures a direct release load
+type loadOptions struct {
+	SourcePath  string
+	DSN         string
+	Release     string
+	ReportPath  string
+	ReportLimit int
+	DryRun      bool
+}
+
+// DeltaReport is the validation report for a release load
+type DeltaReport struct {
+	PreviousRelease string       `json:"previous_release"`
+	Release         string       `json:"release"`
+	Source          string       `json:"source"`
+	DryRun          bool         `json:"dry_run"`
+	LoadedAt        time.Time    `json:"loaded_at"`
+	DurationMs      int64        `json:"duration_ms"`
+	Loaded          LoadCounts   `json:"loaded"`
+	Delta           DeltaCounts  `json:"delta"`
+	AffectedRules   []RuleImpact `json:"affected_rules"`
+}
+
+// LoadCounts are the row counts written to the live tables
+type LoadCounts struct {
+	Concepts       int64  `json:"concepts"`
+	Relationships  int64  `json:"relationships"`
+	Ancestors      int64  `json:"ancestors"`
+	AncestorSource string `json:"ancestor_source"` // imported, computed
+}
+
+// DeltaCounts summarize concept changes against the previously loaded release
+type DeltaCounts struct {
+	Added      int64 `json:"added"`
+	Deprecated int64 `json:"deprecated"`
+	Remapped   int64 `json:"remapped"`
+}
+
+// RuleImpact lists the concept changes that affect one active constitutional rule
+type RuleImpact struct {
+	RuleID           int             `json:"rule_id"`
+	TriggerClassName string          `json:"trigger_class_name"`
+	TargetClassName  string          `json:"target_class_name"`
+	RuleAuthority    string          `json:"rule_authority"`
+	ChangeCounts     map[string]int  `json:"change_counts"`
+	Changes          []ConceptChange `json:"changes"`
+	Truncated        bool            `json:"truncated,omitempty"`
+}
+
+// ConceptChange is a class anchor or member concept that changed between releases
+type ConceptChange struct {
+	Role                 string `json:"role"` // trigger_class, target_class, trigger_member, target_member
+	ConceptID            int64  `json:"concept_id"`
+	ConceptName          string `json:"concept_name"`
+	ChangeType           string `json:"change_type"`
+	ReplacementConceptID *int64 `json:"replacement_concept_id,omitempty"`
+}
+
+// runLoad loads an Athena release directly into Postgres and writes the delta report
+func runLoad(opts loadOptions) error {
+	if opts.DSN == "" {
+		return fmt.Errorf("no database DSN: pass -dsn or set SHARED_DATABASE_URL")
+	}
+
+	source, err := openReleaseSource(opts.SourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	for _, name := range []string{conceptFileName, relationshipFileName} {
+		if !source.Has(name) {
+			return fmt.Errorf("%s not found in release %s", name, opts.SourcePath)
+		}
+	}
+
+	release := opts.Release
+	if release == "" {
+		release = source.ReleaseVersion()
+	}
+	if release == "" {
+		return fmt.Errorf("cannot determine release version from %s - pass -release", vocabularyFileName)
+	}
+
+	db, err := sql.Open("postgres", opts.DSN)
+	if err != nil {
+		return fmt.Errorf("cannot open database: %w", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	start := time.Now()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+	defer tx.Rollback() // No-op after commit
+
+	report := &DeltaReport{
+		Release:  release,
+		Source:   opts.SourcePath,
+		DryRun:   opts.DryRun,
+		LoadedAt: time.Now().UTC(),
+	}
+
+	if report.PreviousRelease, err = currentRelease(ctx, tx); err != nil {
+		return err
+	}
+	logLoad("Loading release %q (previous: %q)", release, report.PreviousRelease)
+
+	if err := createStagingTables(ctx, tx); err != nil {
+		return err
+	}
+
+	// Stage filtered release files
+	conceptVocab := make(map[string]string)
+	staged, err := copyRows(ctx, tx, source, conceptFileName, "stage_concept", []string{
+		"concept_id", "concept_name", "domain_id", "vocabulary_id", "concept_class_id",
+		"standard_concept", "concept_code", "valid_start_date", "valid_end_date", "invalid_reason",
+	}, 10, func(record []string) ([]interface{}, bool) {
+		return conceptStageRow(record, conceptVocab)
+	})
+	if err != nil {
+		return err
+	}
+	logLoad("Staged %d concepts", staged)
+
+	staged, err = copyRows(ctx, tx, source, relationshipFileName, "stage_concept_relationship", []string{
+		"concept_id_1", "concept_id_2", "relationship_id", "valid_start_date", "valid_end_date", "invalid_reason",
+	}, 6, relationshipStageRow)
+	if err != nil {
+		return err
+	}
+	logLoad("Staged %d relationships", staged)
+
+	ancestorsImported := source.Has(ancestorFileName)
+	if ancestorsImported {
+		staged, err = copyRows(ctx, tx, source, ancestorFileName, "stage_concept_ancestor", []string{
+			"ancestor_concept_id", "descendant_concept_id", "min_levels_of_separation", "max_levels_of_separation",
+		}, 4, func(record []string) ([]interface{}, bool) {
+			return ancestorStageRow(record, conceptVocab)
+		})
+		if err != nil {
+			return err
+		}
+		logLoad("Staged %d ancestor rows", staged)
+	}
+	conceptVocab = nil
+
+	if err := indexStagingTables(ctx, tx); err != nil {
+		return err
+	}
+
+	// Delta and rule membership must be captured before the live tables are replaced
+	if err := computeDelta(ctx, tx, &report.Delta); err != nil {
+		return err
+	}
+	if err := snapshotRuleMembers(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := swapLiveTables(ctx, tx, ancestorsImported, &report.Loaded); err != nil {
+		return err
+	}
+
+	if report.AffectedRules, err = ruleImpacts(ctx, tx, opts.ReportLimit); err != nil {
+		return err
+	}
+	report.DurationMs = time.Since(start).Milliseconds()
+
+	if opts.DryRun {
+		logLoad("Dry run - rolling back")
+	} else {
+		if err := recordRelease(ctx, tx, report); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit failed: %w", err)
+		}
+		logLoad("Release %q committed - call POST /api/v1/admin/vocabulary/reload to refresh running services", release)
+	}
+
+	logLoad("Concepts: %d | Relationships: %d | Ancestors: %d (%s)",
+		report.Loaded.Concepts, report.Loaded.Relationships, report.Loaded.Ancestors, report.Loaded.AncestorSource)
+	logLoad("Delta: %d added | %d deprecated | %d remapped | %d active rules affected",
+		report.Delta.Added, report.Delta.Deprecated, report.Delta.Remapped, len(report.AffectedRules))
+
+	return writeReport(report, opts.ReportPath)
+}
+
+// currentRelease returns the version of the release in service ("" if none recorded)
+func currentRelease(ctx context.Context, tx *sql.Tx) (string, error) {
+	var version string
+	err := tx.QueryRowContext(ctx, `
+		SELECT vocabulary_version FROM ohdsi_vocabulary_release
+		ORDER BY loaded_at DESC, release_id DESC LIMIT 1`).Scan(&version)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot read current release: %w", err)
+	}
+	return version, nil
+}
+
+func createStagingTables(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TEMP TABLE stage_concept (
+			concept_id BIGINT NOT NULL,
+			concept_name VARCHAR(500) NOT NULL,
+			domain_id VARCHAR(50),
+			vocabulary_id VARCHAR(50),
+			concept_class_id VARCHAR(50),
+			standard_concept VARCHAR(1),
+			concept_code VARCHAR(100),
+			valid_start_date DATE,
+			valid_end_date DATE,
+			invalid_reason VARCHAR(1)
+		) ON COMMIT DROP;
+
+		CREATE TEMP TABLE stage_concept_relationship (
+			concept_id_1 BIGINT NOT NULL,
+			concept_id_2 BIGINT NOT NULL,
+			relationship_id VARCHAR(50) NOT NULL,
+			valid_start_date DATE,
+			valid_end_date DATE,
+			invalid_reason VARCHAR(1)
+		) ON COMMIT DROP;
+
+		CREATE TEMP TABLE stage_concept_ancestor (
+			ancestor_concept_id BIGINT NOT NULL,
+			descendant_concept_id BIGINT NOT NULL,
+			min_levels_of_separation INTEGER NOT NULL,
+			max_levels_of_separation INTEGER NOT NULL
+		) ON COMMIT DROP;`)
+	if err != nil {
+		return fmt.Errorf("cannot create staging tables: %w", err)
+	}
+	return nil
+}
+
+func indexStagingTables(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE INDEX ON stage_concept (concept_id);
+		CREATE INDEX ON stage_concept_relationship (concept_id_1, relationship_id);
+		ANALYZE stage_concept;
+		ANALYZE stage_concept_relationship;
+		ANALYZE stage_concept_ancestor;`)
+	if err != nil {
+		return fmt.Errorf("cannot index staging tables: %w", err)
+	}
+	return nil
+}
+
+// conceptStageRow converts a kept CONCEPT.csv row to stage_concept values and
+// records the concept's vocabulary for the ancestor filter
+func conceptStageRow(record []string, conceptVocab map[string]string) ([]interface{}, bool) {
+	if !keepConcept(record) {
+		return nil, false
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(record[conceptIdIdx]), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	conceptVocab[strings.TrimSpace(record[conceptIdIdx])] = strings.TrimSpace(record[vocabularyIdIdx])
+	return []interface{}{
+		id,
+		record[conceptNameIdx],
+		nullIfEmpty(record[domainIdIdx]),
+		nullIfEmpty(record[vocabularyIdIdx]),
+		nullIfEmpty(record[conceptClassIdIdx]),
+		nullIfEmpty(record[standardConceptIdx]),
+		nullIfEmpty(record[conceptCodeIdx]),
+		nullIfEmpty(record[validStartDateIdx]),
+		nullIfEmpty(record[validEndDateIdx]),
+		nullIfEmpty(record[invalidReasonIdx]),
+	}, true
+}
+
+// relationshipStageRow converts a kept CONCEPT_RELATIONSHIP.csv row to
+// stage_concept_relationship values
+func relationshipStageRow(record []string) ([]interface{}, bool) {
+	if !keepRelationship(record) {
+		return nil, false
+	}
+	id1, err1 := strconv.ParseInt(strings.TrimSpace(record[conceptId1Idx]), 10, 64)
+	id2, err2 := strconv.ParseInt(strings.TrimSpace(record[conceptId2Idx]), 10, 64)
+	if err1 != nil || err2 != nil {
+		return nil, false
+	}
+	return []interface{}{
+		id1,
+		id2,
+		strings.TrimSpace(record[relationshipIdIdx]),
+		nullIfEmpty(record[relValidStartIdx]),
+		nullIfEmpty(record[relValidEndIdx]),
+		nullIfEmpty(record[relInvalidIdx]),
+	}, true
+}
+
+// ancestorStageRow converts a kept CONCEPT_ANCESTOR.csv row to stage_concept_ancestor values
+func ancestorStageRow(record []string, conceptVocab map[string]string) ([]interface{}, bool) {
+	if !keepAncestor(record, conceptVocab) {
+		return nil, false
+	}
+	values := make([]interface{}, 4)
+	for i := 0; i < 4; i++ {
+		v, err := strconv.ParseInt(strings.TrimSpace(record[i]), 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		values[i] = v
+	}
+	return values, true
+}
+
+// copyRows streams rows kept by convert from a release file into a staging table via COPY
+func copyRows(
+	ctx context.Context,
+	tx *sql.Tx,
+	source *releaseSource,
+	fileName string,
+	table string,
+	columns []string,
+	minFields int,
+	convert func(record []string) ([]interface{}, bool),
+) (int64, error) {
+	file, err := source.Open(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader := newReleaseReader(file)
+
+	// Skip header
+	if _, err := reader.Read(); err != nil {
+		return 0, fmt.Errorf("cannot read %s header: %w", fileName, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return 0, fmt.Errorf("cannot start COPY into %s: %w", table, err)
+	}
+
+	var read, count int64
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		read++
+		if err != nil || len(record) < minFields {
+			continue // Skip malformed rows
+		}
+
+		row, keep := convert(record)
+		if !keep {
+			continue
+		}
+
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			stmt.Close()
+			return count, fmt.Errorf("COPY into %s failed: %w", table, err)
+		}
+		count++
+
+		// Progress indicator (every 1M rows read)
+		if read%1000000 == 0 {
+			logLoad("[Progress] %s: read %dM rows, kept %dk", fileName, read/1000000, count/1000)
+		}
+	}
+
+	// Flush the COPY buffer
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return count, fmt.Errorf("COPY into %s failed: %w", table, err)
+	}
+
+	return count, stmt.Close()
+}
+
+// computeDelta records added, deprecated and remapped concepts in vocab_delta
+func computeDelta(ctx context.Context, tx *sql.Tx, delta *DeltaCounts) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TEMP TABLE vocab_delta ON COMMIT DROP AS
+		-- Added: valid in the new release, not valid before
+		SELECT s.concept_id, s.concept_name, s.vocabulary_id,
+		       'added'::TEXT AS change_type, NULL::BIGINT AS replacement_concept_id
+		FROM stage_concept s
+		WHERE s.invalid_reason IS NULL
+		  AND NOT EXISTS (
+		      SELECT 1 FROM ohdsi_concept c
+		      WHERE c.concept_id = s.concept_id AND c.invalid_reason IS NULL)
+		UNION ALL
+		-- Deprecated: valid before, now deleted or absent
+		SELECT c.concept_id, c.concept_name, c.vocabulary_id, 'deprecated', NULL
+		FROM ohdsi_concept c
+		LEFT JOIN stage_concept s ON s.concept_id = c.concept_id
+		WHERE c.invalid_reason IS NULL
+		  AND (s.concept_id IS NULL OR s.invalid_reason = 'D')
+		UNION ALL
+		-- Remapped: valid before, now upgraded to a replacement concept
+		SELECT c.concept_id, c.concept_name, c.vocabulary_id, 'remapped',
+		       (SELECT MIN(m.concept_id_2)
+		        FROM stage_concept_relationship m
+		        WHERE m.concept_id_1 = c.concept_id
+		          AND m.relationship_id = 'Maps to'
+		          AND m.concept_id_2 <> c.concept_id
+		          AND m.invalid_reason IS NULL)
+		FROM ohdsi_concept c
+		JOIN stage_concept s ON s.concept_id = c.concept_id
+		WHERE c.invalid_reason IS NULL
+		  AND s.invalid_reason = 'U';
+
+		CREATE INDEX ON vocab_delta (concept_id);`)
+	if err != nil {
+		return fmt.Errorf("cannot compute release delta: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT change_type, COUNT(*) FROM vocab_delta GROUP BY change_type`)
+	if err != nil {
+		return fmt.Errorf("cannot count release delta: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var changeType string
+		var count int64
+		if err := rows.Scan(&changeType, &count); err != nil {
+			return err
+		}
+		switch changeType {
+		case changeAdded:
+			delta.Added = count
+		case changeDeprecated:
+			delta.Deprecated = count
+		case changeRemapped:
+			delta.Remapped = count
+		}
+	}
+	return rows.Err()
+}
+
+// snapshotRuleMembers captures the current class members of active constitutional rules
+func snapshotRuleMembers(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TEMP TABLE prev_rule_members ON COMMIT DROP AS
+		SELECT DISTINCT ca.ancestor_concept_id, ca.descendant_concept_id, c.concept_name
+		FROM ohdsi_concept_ancestor ca
+		JOIN ohdsi_concept c
+		  ON c.concept_id = ca.descendant_concept_id
+		 AND c.standard_concept = 'S'
+		WHERE ca.min_levels_of_separation > 0
+		  AND ca.ancestor_concept_id IN (
+		      SELECT trigger_concept_id FROM ddi_constitutional_rules WHERE active = TRUE
+		      UNION
+		      SELECT target_concept_id FROM ddi_constitutional_rules WHERE active = TRUE);
+
+		CREATE INDEX ON prev_rule_members (ancestor_concept_id, descendant_concept_id);`)
+	if err != nil {
+		return fmt.Errorf("cannot snapshot rule class members: %w", err)
+	}
+	return nil
+}
+
+// swapLiveTables replaces the live vocabulary tables with the staged release
+func swapLiveTables(ctx context.Context, tx *sql.Tx, ancestorsImported bool, counts *LoadCounts) error {
+	if _, err := tx.ExecContext(ctx, `
+		TRUNCATE TABLE ohdsi_concept_ancestor, ohdsi_concept_relationship, ohdsi_concept`); err != nil {
+		return fmt.Errorf("cannot clear live tables: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO ohdsi_concept (
+			concept_id, concept_name, domain_id, vocabulary_id, concept_class_id,
+			standard_concept, concept_code, valid_start_date, valid_end_date, invalid_reason)
+		SELECT DISTINCT ON (concept_id)
+			concept_id, concept_name, domain_id, vocabulary_id, concept_class_id,
+			standard_concept, concept_code, valid_start_date, valid_end_date, invalid_reason
+		FROM stage_concept
+		ORDER BY concept_id`)
+	if err != nil {
+		return fmt.Errorf("cannot load concepts: %w", err)
+	}
+	counts.Concepts, _ = result.RowsAffected()
+
+	// Relationships to concepts outside the filtered subset are dropped (foreign keys)
+	result, err = tx.ExecContext(ctx, `
+		INSERT INTO ohdsi_concept_relationship (
+			concept_id_1, concept_id_2, relationship_id, valid_start_date, valid_end_date, invalid_reason)
+		SELECT DISTINCT ON (r.concept_id_1, r.concept_id_2, r.relationship_id)
+			r.concept_id_1, r.concept_id_2, r.relationship_id, r.valid_start_date, r.valid_end_date, r.invalid_reason
+		FROM stage_concept_relationship r
+		JOIN ohdsi_concept a ON a.concept_id = r.concept_id_1
+		JOIN ohdsi_concept b ON b.concept_id = r.concept_id_2
+		ORDER BY r.concept_id_1, r.concept_id_2, r.relationship_id`)
+	if err != nil {
+		return fmt.Errorf("cannot load relationships: %w", err)
+	}
+	counts.Relationships, _ = result.RowsAffected()
+
+	if ancestorsImported {
+		result, err = tx.ExecContext(ctx, `
+			INSERT INTO ohdsi_concept_ancestor (
+				ancestor_concept_id, descendant_concept_id, min_levels_of_separation, max_levels_of_separation)
+			SELECT DISTINCT ON (ancestor_concept_id, descendant_concept_id)
+				ancestor_concept_id, descendant_concept_id, min_levels_of_separation, max_levels_of_separation
+			FROM stage_concept_ancestor
+			ORDER BY ancestor_concept_id, descendant_concept_id`)
+		if err != nil {
+			return fmt.Errorf("cannot load concept ancestors: %w", err)
+		}
+		counts.Ancestors, _ = result.RowsAffected()
+		counts.AncestorSource = "imported"
+		return nil
+	}
+
+	if err := tx.QueryRowContext(ctx, `SELECT rebuild_ohdsi_concept_ancestor()`).Scan(&counts.Ancestors); err != nil {
+		return fmt.Errorf("cannot compute concept ancestors: %w", err)
+	}
+	counts.AncestorSource = "computed"
+	return nil
+}
+
+// ruleImpacts reports, per active constitutional rule, class anchors that were
+// deprecated or remapped and class members that were added, removed, deprecated
+// or remapped. At most limit changes are listed per rule; counts are complete.
+func ruleImpacts(ctx context.Context, tx *sql.Tx, limit int) ([]RuleImpact, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT r.rule_id, r.trigger_class_name, r.target_class_name, r.rule_authority,
+		       x.role, x.concept_id, x.concept_name, x.change_type, x.replacement_concept_id
+		FROM ddi_constitutional_rules r
+		JOIN LATERAL (
+		    -- Class anchors
+		    SELECT CASE WHEN d.concept_id = r.trigger_concept_id THEN 'trigger_class' ELSE 'target_class' END AS role,
+		           d.concept_id, d.concept_name, d.change_type, d.replacement_concept_id
+		    FROM vocab_delta d
+		    WHERE d.concept_id IN (r.trigger_concept_id, r.target_concept_id)
+		      AND d.change_type <> 'added'
+		    UNION ALL
+		    -- Members gained
+		    SELECT CASE WHEN n.ancestor_concept_id = r.trigger_concept_id THEN 'trigger_member' ELSE 'target_member' END,
+		           n.descendant_concept_id, c.concept_name, 'added', NULL::BIGINT
+		    FROM ohdsi_concept_ancestor n
+		    JOIN ohdsi_concept c
+		      ON c.concept_id = n.descendant_concept_id
+		     AND c.standard_concept = 'S'
+		    WHERE n.ancestor_concept_id IN (r.trigger_concept_id, r.target_concept_id)
+		      AND n.min_levels_of_separation > 0
+		      AND NOT EXISTS (
+		          SELECT 1 FROM prev_rule_members p
+		          WHERE p.ancestor_concept_id = n.ancestor_concept_id
+		            AND p.descendant_concept_id = n.descendant_concept_id)
+		    UNION ALL
+		    -- Members lost, deprecated or remapped
+		    SELECT CASE WHEN p.ancestor_concept_id = r.trigger_concept_id THEN 'trigger_member' ELSE 'target_member' END,
+		           p.descendant_concept_id, p.concept_name, COALESCE(d.change_type, 'removed'), d.replacement_concept_id
+		    FROM prev_rule_members p
+		    LEFT JOIN vocab_delta d
+		      ON d.concept_id = p.descendant_concept_id
+		     AND d.change_type <> 'added'
+		    WHERE p.ancestor_concept_id IN (r.trigger_concept_id, r.target_concept_id)
+		      AND (d.concept_id IS NOT NULL OR NOT EXISTS (
+		          SELECT 1
+		          FROM ohdsi_concept_ancestor n
+		          JOIN ohdsi_concept c
+		            ON c.concept_id = n.descendant_concept_id
+		           AND c.standard_concept = 'S'
+		          WHERE n.ancestor_concept_id = p.ancestor_concept_id
+		            AND n.descendant_concept_id = p.descendant_concept_id))
+		) x ON TRUE
+		WHERE r.active = TRUE
+		ORDER BY r.rule_id, x.role, x.change_type, x.concept_id`)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compute rule impact: %w", err)
+	}
+	defer rows.Close()
+
+	var impacts []RuleImpact
+	var current *RuleImpact
+	for rows.Next() {
+		var ruleID int
+		var triggerName, targetName, authority string
+		var change ConceptChange
+		var replacement sql.NullInt64
+
+		if err := rows.Scan(&ruleID, &triggerName, &targetName, &authority,
+			&change.Role, &change.ConceptID, &change.ConceptName, &change.ChangeType, &replacement); err != nil {
+			return nil, err
+		}
+		if replacement.Valid {
+			change.ReplacementConceptID = &replacement.Int64
+		}
+
+		if current == nil || current.RuleID != ruleID {
+			impacts = append(impacts, RuleImpact{
+				RuleID:           ruleID,
+				TriggerClassName: triggerName,
+				TargetClassName:  targetName,
+				RuleAuthority:    authority,
+				ChangeCounts:     make(map[string]int),
+			})
+			current = &impacts[len(impacts)-1]
+		}
+
+		current.ChangeCounts[change.ChangeType]++
+		if limit > 0 && len(current.Changes) >= limit {
+			current.Truncated = true
+			continue
+		}
+		current.Changes = append(current.Changes, change)
+	}
+
+	return impacts, rows.Err()
+}
+
+// recordRelease stores the loaded release and its report
+func recordRelease(ctx context.Context, tx *sql.Tx, report *DeltaReport) error {
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("cannot encode report: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO ohdsi_vocabulary_release (
+			vocabulary_version, previous_version, source_path,
+			concept_count, relationship_count, ancestor_count, ancestor_source,
+			concepts_added, concepts_deprecated, concepts_remapped, affected_rule_count,
+			report, loaded_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		report.Release, report.PreviousRelease, report.Source,
+		report.Loaded.Concepts, report.Loaded.Relationships, report.Loaded.Ancestors, report.Loaded.AncestorSource,
+		report.Delta.Added, report.Delta.Deprecated, report.Delta.Remapped, len(report.AffectedRules),
+		string(reportJSON), report.LoadedAt)
+	if err != nil {
+		return fmt.Errorf("cannot record release: %w", err)
+	}
+	return nil
+}
+
+// writeReport writes the report as indented JSON to path, or stdout when path is empty
+func writeReport(report *DeltaReport, path string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode report: %w", err)
+	}
+	data = append(data, '\n')
+
+	if path == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("cannot write report: %w", err)
+	}
+	logLoad("Report written to %s", path)
+	return nil
+}
+
+// nullIfEmpty converts empty CSV fields to NULL for COPY
+func nullIfEmpty(value string) interface{} {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func logLoad(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "[OHDSI Loader] "+format+"\n", args...)
+}
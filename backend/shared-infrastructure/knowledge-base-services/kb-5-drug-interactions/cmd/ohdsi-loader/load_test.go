@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================================
+// RECORDING SQL DRIVER
+// Captures the statements and COPY rows the loader sends inside its
+// transaction and answers queries from canned results
+// ============================================================================
+
+type fakeLoaderExec struct {
+	query string
+	args  []driver.Value
+}
+
+type fakeLoaderResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+type fakeLoaderDB struct {
+	affected map[string]int64            // statement fragment -> rows affected
+	results  map[string]fakeLoaderResult // query fragment -> rows returned
+	execs    []fakeLoaderExec
+	copied   [][]driver.Value
+	flushes  int
+}
+
+func (db *fakeLoaderDB) Connect(context.Context) (driver.Conn, error) {
+	return &fakeLoaderConn{db: db}, nil
+}
+
+func (db *fakeLoaderDB) Driver() driver.Driver { return fakeLoaderDriver{} }
+
+type fakeLoaderDriver struct{}
+
+func (fakeLoaderDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("open through the connector")
+}
+
+type fakeLoaderConn struct{ db *fakeLoaderDB }
+
+func (c *fakeLoaderConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeLoaderStmt{db: c.db, query: query}, nil
+}
+func (c *fakeLoaderConn) Close() error              { return nil }
+func (c *fakeLoaderConn) Begin() (driver.Tx, error) { return fakeLoaderTx{}, nil }
+
+type fakeLoaderTx struct{}
+
+func (fakeLoaderTx) Commit() error   { return nil }
+func (fakeLoaderTx) Rollback() error { return nil }
+
+type fakeLoaderStmt struct {
+	db    *fakeLoaderDB
+	query string
+}
+
+func (s *fakeLoaderStmt) Close() error  { return nil }
+func (s *fakeLoaderStmt) NumInput() int { return -1 }
+
+func (s *fakeLoaderStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if strings.HasPrefix(s.query, "COPY") {
+		if len(args) == 0 {
+			s.db.flushes++
+		} else {
+			s.db.copied = append(s.db.copied, args)
+		}
+		return driver.RowsAffected(0), nil
+	}
+
+	s.db.execs = append(s.db.execs, fakeLoaderExec{query: s.query, args: args})
+	for fragment, n := range s.db.affected {
+		if strings.Contains(s.query, fragment) {
+			return driver.RowsAffected(n), nil
+		}
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (s *fakeLoaderStmt) Query(args []driver.Value) (driver.Rows, error) {
+	for fragment, result := range s.db.results {
+		if strings.Contains(s.query, fragment) {
+			return &fakeLoaderRows{result: result}, nil
+		}
+	}
+	return &fakeLoaderRows{result: fakeLoaderResult{columns: []string{"value"}}}, nil
+}
+
+type fakeLoaderRows struct {
+	result fakeLoaderResult
+	next   int
+}
+
+func (r *fakeLoaderRows) Columns() []string { return r.result.columns }
+func (r *fakeLoaderRows) Close() error      { return nil }
+
+func (r *fakeLoaderRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
+
+func beginFakeLoaderTx(t *testing.T, db *fakeLoaderDB) *sql.Tx {
+	t.Helper()
+	conn := sql.OpenDB(db)
+	t.Cleanup(func() { conn.Close() })
+
+	tx, err := conn.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+func conceptRecord(id, vocabulary, domain, standard, invalid string) []string {
+	return []string{id, "concept " + id, domain, vocabulary, "Ingredient", standard, "C" + id, "20200101", "20991231", invalid}
+}
+
+// ============================================================================
+// ROW CONVERSION AND CONCEPT FILTER TESTS
+// ============================================================================
+
+func TestKeepConcept(t *testing.T) {
+	tests := []struct {
+		name   string
+		record []string
+		keep   bool
+	}{
+		{"ATC class, non-standard", conceptRecord("21600001", "ATC", "Drug", "C", ""), true},
+		{"VA Class", conceptRecord("4000001", "VA Class", "Drug", "", ""), true},
+		{"RxNorm standard ingredient", conceptRecord("1125315", "RxNorm", "Drug", "S", ""), true},
+		{"RxNorm non-standard in Drug domain", conceptRecord("1125316", "RxNorm", "Drug", "", ""), true},
+		{"RxNorm Extension outside Drug domain", conceptRecord("9000001", "RxNorm Extension", "Device", "", ""), false},
+		{"LOINC lab", conceptRecord("3016723", "LOINC", "Measurement", "S", ""), true},
+		{"SNOMED excluded", conceptRecord("201826", "SNOMED", "Condition", "S", ""), false},
+		{"vocabulary padded with spaces", conceptRecord("1125317", " RxNorm ", "Drug", "S", ""), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.keep, keepConcept(tt.record))
+		})
+	}
+}
+
+func TestConceptStageRow(t *testing.T) {
+	tests := []struct {
+		name   string
+		record []string
+		keep   bool
+		want   []interface{}
+	}{
+		{
+			name:   "kept concept",
+			record: []string{" 1125315 ", "warfarin", "Drug", "RxNorm", "Ingredient", "S", " 11289 ", "20200101", "20991231", ""},
+			keep:   true,
+			want: []interface{}{int64(1125315), "warfarin", "Drug", "RxNorm", "Ingredient", "S",
+				"11289", "20200101", "20991231", nil},
+		},
+		{
+			name:   "empty optional fields become NULL",
+			record: []string{"21600001", "ANTITHROMBOTIC AGENTS", "", "ATC", "", " ", "", "", "", "D"},
+			keep:   true,
+			want:   []interface{}{int64(21600001), "ANTITHROMBOTIC AGENTS", nil, "ATC", nil, nil, nil, nil, nil, "D"},
+		},
+		{name: "unparseable concept_id", record: conceptRecord("abc", "RxNorm", "Drug", "S", ""), keep: false},
+		{name: "filtered vocabulary", record: conceptRecord("201826", "SNOMED", "Condition", "S", ""), keep: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conceptVocab := make(map[string]string)
+			row, keep := conceptStageRow(tt.record, conceptVocab)
+			assert.Equal(t, tt.keep, keep)
+			assert.Equal(t, tt.want, row)
+			if tt.keep {
+				assert.Equal(t, strings.TrimSpace(tt.record[vocabularyIdIdx]), conceptVocab[strings.TrimSpace(tt.record[conceptIdIdx])])
+			} else {
+				assert.Empty(t, conceptVocab, "dropped concepts are not available to the ancestor filter")
+			}
+		})
+	}
+}
+
+func TestRelationshipStageRow(t *testing.T) {
+	tests := []struct {
+		name   string
+		record []string
+		keep   bool
+		want   []interface{}
+	}{
+		{
+			name:   "maps to",
+			record: []string{" 1125316", "1125315 ", " Maps to ", "20200101", "20991231", ""},
+			keep:   true,
+			want:   []interface{}{int64(1125316), int64(1125315), "Maps to", "20200101", "20991231", nil},
+		},
+		{
+			name:   "drug to ATC class",
+			record: []string{"1125315", "21600001", "RxNorm - ATC", "", "", "D"},
+			keep:   true,
+			want:   []interface{}{int64(1125315), int64(21600001), "RxNorm - ATC", nil, nil, "D"},
+		},
+		{name: "relationship not in whitelist", record: []string{"1", "2", "Has brand name", "", "", ""}, keep: false},
+		{name: "unparseable concept_id_2", record: []string{"1", "x", "Maps to", "", "", ""}, keep: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row, keep := relationshipStageRow(tt.record)
+			assert.Equal(t, tt.keep, keep)
+			assert.Equal(t, tt.want, row)
+		})
+	}
+}
+
+func TestAncestorStageRow(t *testing.T) {
+	conceptVocab := map[string]string{
+		"21600001": "ATC",
+		"1125315":  "RxNorm",
+		"1125316":  "RxNorm",
+	}
+	tests := []struct {
+		name   string
+		record []string
+		keep   bool
+		want   []interface{}
+	}{
+		{
+			name:   "class to member",
+			record: []string{"21600001", "1125315", "2", " 3 "},
+			keep:   true,
+			want:   []interface{}{int64(21600001), int64(1125315), int64(2), int64(3)},
+		},
+		{name: "ancestor is not a class", record: []string{"1125316", "1125315", "1", "1"}, keep: false},
+		{name: "descendant filtered out", record: []string{"21600001", "999", "1", "1"}, keep: false},
+		{name: "unparseable level", record: []string{"21600001", "1125315", "one", "1"}, keep: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row, keep := ancestorStageRow(tt.record, conceptVocab)
+			assert.Equal(t, tt.keep, keep)
+			assert.Equal(t, tt.want, row)
+		})
+	}
+}
+
+// ============================================================================
+// STAGING AND LIVE TABLE TESTS
+// ============================================================================
+
+func TestCopyRows_StagesKeptRows(t *testing.T) {
+	dir := t.TempDir()
+	content := strings.Join([]string{
+		"concept_id\tconcept_name\tdomain_id\tvocabulary_id\tconcept_class_id\tstandard_concept\tconcept_code\tvalid_start_date\tvalid_end_date\tinvalid_reason",
+		strings.Join(conceptRecord("1125315", "RxNorm", "Drug", "S", ""), "\t"),
+		strings.Join(conceptRecord("201826", "SNOMED", "Condition", "S", ""), "\t"),
+		"1125399\ttoo few fields",
+		strings.Join(conceptRecord("bad", "RxNorm", "Drug", "S", ""), "\t"),
+		strings.Join(conceptRecord("21600001", "ATC", "Drug", "C", ""), "\t"),
+	}, "\n") + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "concept.csv"), []byte(content), 0644))
+
+	source, err := openReleaseSource(dir)
+	require.NoError(t, err)
+	defer source.Close()
+
+	db := &fakeLoaderDB{}
+	tx := beginFakeLoaderTx(t, db)
+	conceptVocab := make(map[string]string)
+
+	count, err := copyRows(context.Background(), tx, source, conceptFileName, "stage_concept",
+		[]string{"concept_id", "concept_name"}, 10, func(record []string) ([]interface{}, bool) {
+			return conceptStageRow(record, conceptVocab)
+		})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	require.Len(t, db.copied, 2)
+	assert.Equal(t, int64(1125315), db.copied[0][0])
+	assert.Equal(t, int64(21600001), db.copied[1][0])
+	assert.Equal(t, 1, db.flushes, "the COPY buffer is flushed once")
+	assert.Equal(t, map[string]string{"1125315": "RxNorm", "21600001": "ATC"}, conceptVocab)
+
+	_, err = copyRows(context.Background(), tx, source, ancestorFileName, "stage_concept_ancestor",
+		[]string{"ancestor_concept_id"}, 4, func([]string) ([]interface{}, bool) { return nil, false })
+	assert.Error(t, err, "missing release file")
+}
+
+func TestSwapLiveTables(t *testing.T) {
+	t.Run("imported ancestors", func(t *testing.T) {
+		db := &fakeLoaderDB{affected: map[string]int64{
+			"INSERT INTO ohdsi_concept (":            3,
+			"INSERT INTO ohdsi_concept_relationship": 5,
+			"INSERT INTO ohdsi_concept_ancestor":     7,
+		}}
+		var counts LoadCounts
+		require.NoError(t, swapLiveTables(context.Background(), beginFakeLoaderTx(t, db), true, &counts))
+
+		assert.Equal(t, LoadCounts{Concepts: 3, Relationships: 5, Ancestors: 7, AncestorSource: "imported"}, counts)
+		require.Len(t, db.execs, 4)
+		assert.Contains(t, db.execs[0].query, "TRUNCATE TABLE")
+		assert.Contains(t, db.execs[3].query, "FROM stage_concept_ancestor")
+	})
+
+	t.Run("computed ancestors", func(t *testing.T) {
+		db := &fakeLoaderDB{
+			affected: map[string]int64{"INSERT INTO ohdsi_concept (": 3},
+			results: map[string]fakeLoaderResult{
+				"rebuild_ohdsi_concept_ancestor": {columns: []string{"rows"}, rows: [][]driver.Value{{int64(42)}}},
+			},
+		}
+		var counts LoadCounts
+		require.NoError(t, swapLiveTables(context.Background(), beginFakeLoaderTx(t, db), false, &counts))
+
+		assert.Equal(t, LoadCounts{Concepts: 3, Ancestors: 42, AncestorSource: "computed"}, counts)
+		for _, exec := range db.execs {
+			assert.NotContains(t, exec.query, "stage_concept_ancestor", "staged ancestors are not inserted")
+		}
+	})
+}
+
+func TestComputeDelta_CountsChangeTypes(t *testing.T) {
+	db := &fakeLoaderDB{results: map[string]fakeLoaderResult{
+		"GROUP BY change_type": {
+			columns: []string{"change_type", "count"},
+			rows:    [][]driver.Value{{"added", int64(2)}, {"deprecated", int64(1)}, {"remapped", int64(4)}},
+		},
+	}}
+	var delta DeltaCounts
+	require.NoError(t, computeDelta(context.Background(), beginFakeLoaderTx(t, db), &delta))
+
+	assert.Equal(t, DeltaCounts{Added: 2, Deprecated: 1, Remapped: 4}, delta)
+	require.Len(t, db.execs, 1)
+	assert.Contains(t, db.execs[0].query, "CREATE TEMP TABLE vocab_delta")
+}
+
+func TestRuleImpacts_GroupsAndTruncates(t *testing.T) {
+	row := func(ruleID int64, role string, conceptID int64, changeType string, replacement driver.Value) []driver.Value {
+		return []driver.Value{ruleID, "Anticoagulants", "NSAIDs", "ONC", role, conceptID, "concept", changeType, replacement}
+	}
+	db := &fakeLoaderDB{results: map[string]fakeLoaderResult{
+		"FROM ddi_constitutional_rules r": {
+			columns: []string{"rule_id", "trigger_class_name", "target_class_name", "rule_authority",
+				"role", "concept_id", "concept_name", "change_type", "replacement_concept_id"},
+			rows: [][]driver.Value{
+				row(1, "target_member", 10, changeAdded, nil),
+				row(1, "target_member", 11, changeAdded, nil),
+				row(1, "trigger_member", 12, changeRemoved, nil),
+				row(2, "trigger_class", 20, changeRemapped, int64(21)),
+			},
+		},
+	}}
+
+	impacts, err := ruleImpacts(context.Background(), beginFakeLoaderTx(t, db), 2)
+	require.NoError(t, err)
+	require.Len(t, impacts, 2)
+
+	assert.Equal(t, 1, impacts[0].RuleID)
+	assert.Equal(t, map[string]int{changeAdded: 2, changeRemoved: 1}, impacts[0].ChangeCounts, "counts are complete")
+	assert.Len(t, impacts[0].Changes, 2)
+	assert.True(t, impacts[0].Truncated)
+
+	assert.Equal(t, "ONC", impacts[1].RuleAuthority)
+	require.Len(t, impacts[1].Changes, 1)
+	require.NotNil(t, impacts[1].Changes[0].ReplacementConceptID)
+	assert.Equal(t, int64(21), *impacts[1].Changes[0].ReplacementConceptID)
+	assert.False(t, impacts[1].Truncated)
+}
+
+func TestReleaseBookkeeping(t *testing.T) {
+	db := &fakeLoaderDB{}
+	tx := beginFakeLoaderTx(t, db)
+
+	// No release recorded yet
+	previous, err := currentRelease(context.Background(), tx)
+	require.NoError(t, err)
+	assert.Equal(t, "", previous)
+
+	report := &DeltaReport{
+		PreviousRelease: "v5.0 30-AUG-24",
+		Release:         "v5.0 27-FEB-25",
+		Source:          "/data/athena.zip",
+		LoadedAt:        time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		Loaded:          LoadCounts{Concepts: 3, Relationships: 5, Ancestors: 7, AncestorSource: "imported"},
+		Delta:           DeltaCounts{Added: 2, Deprecated: 1, Remapped: 4},
+		AffectedRules:   []RuleImpact{{RuleID: 1}},
+	}
+	require.NoError(t, recordRelease(context.Background(), tx, report))
+	require.Len(t, db.execs, 1)
+
+	args := db.execs[0].args
+	require.Len(t, args, 13)
+	assert.Equal(t, []driver.Value{"v5.0 27-FEB-25", "v5.0 30-AUG-24", "/data/athena.zip",
+		int64(3), int64(5), int64(7), "imported", int64(2), int64(1), int64(4), int64(1)}, args[:11])
+
+	var stored DeltaReport
+	require.NoError(t, json.Unmarshal([]byte(args[11].(string)), &stored))
+	assert.Equal(t, report.Release, stored.Release)
+
+	db.results = map[string]fakeLoaderResult{
+		"FROM ohdsi_vocabulary_release": {columns: []string{"vocabulary_version"}, rows: [][]driver.Value{{"v5.0 27-FEB-25"}}},
+	}
+	previous, err = currentRelease(context.Background(), tx)
+	require.NoError(t, err)
+	assert.Equal(t, "v5.0 27-FEB-25", previous)
+}
@@ -10,9 +10,15 @@
 // - Filter by Relationship ID whitelist
 // - Output lean, execution-grade substrate
 //
-// Usage:
+// Usage (direct load of an Athena release into Postgres):
+//   ./ohdsi-loader -load=/path/to/athena_release[.zip] -dsn=postgres://... \
+//       [-release=v5.0_2025-02-27] [-report=report.json] [-dry-run]
+//
+// Usage (stream filters):
 //   cat CONCEPT.csv | ./ohdsi-loader -type=concept > filtered_concept.tsv
 //   cat CONCEPT_RELATIONSHIP.csv | ./ohdsi-loader -type=rel > filtered_rel.tsv
+//   cat CONCEPT_ANCESTOR.csv | ./ohdsi-loader -type=ancestor \
+//       -concepts=filtered_concept.tsv > filtered_ancestor.tsv
 // =============================================================================
 
 package main
@@ -102,6 +108,24 @@ const (
 // 4: valid_end_date
 // 5: invalid_reason
 
+// CONCEPT_ANCESTOR.csv columns (tab-delimited):
+// 0: ancestor_concept_id
+// 1: descendant_concept_id
+// 2: min_levels_of_separation
+// 3: max_levels_of_separation
+
+const (
+	ancestorIdIdx   = 0
+	descendantIdIdx = 1
+)
+
+// classVocabularies are the vocabularies whose concepts act as expansion anchors
+var classVocabularies = map[string]bool{
+	"ATC":      true,
+	"VA Class": true,
+	"MED-RT":   true,
+}
+
 const (
 	conceptId1Idx     = 0
 	conceptId2Idx     = 1
@@ -136,12 +160,34 @@ func (s *LoaderStats) Summary() string {
 
 func main() {
 	// Parse command line flags
-	filterType := flag.String("type", "", "Filter type: 'concept' or 'rel'")
+	filterType := flag.String("type", "", "Filter type: 'concept', 'rel' or 'ancestor'")
+	conceptsFile := flag.String("concepts", "", "Filtered concept file (required for -type=ancestor)")
 	verbose := flag.Bool("v", false, "Verbose output (stats to stderr)")
+	loadPath := flag.String("load", "", "Athena release directory or zip to load directly into Postgres")
+	dsn := flag.String("dsn", os.Getenv("SHARED_DATABASE_URL"), "Postgres DSN for -load (default $SHARED_DATABASE_URL)")
+	release := flag.String("release", "", "Release version for -load (default: read from VOCABULARY.csv)")
+	reportPath := flag.String("report", "", "Write the -load delta report (JSON) to this file instead of stdout")
+	reportLimit := flag.Int("report-limit", 100, "Maximum concept changes listed per rule in the -load report")
+	dryRun := flag.Bool("dry-run", false, "Compute the -load delta report and roll back")
 	flag.Parse()
 
+	if *loadPath != "" {
+		err := runLoad(loadOptions{
+			SourcePath:  *loadPath,
+			DSN:         *dsn,
+			Release:     *release,
+			ReportPath:  *reportPath,
+			ReportLimit: *reportLimit,
+			DryRun:      *dryRun,
+		})
+		if err != nil {
+			log.Fatalf("Vocabulary load failed: %v", err)
+		}
+		return
+	}
+
 	if *filterType == "" {
-		log.Fatal("Usage: ohdsi-loader -type=concept|rel [-v]")
+		log.Fatal("Usage: ohdsi-loader -load=<release dir|zip> [-dsn=...] [-dry-run] | -type=concept|rel|ancestor [-concepts=filtered_concept.tsv] [-v]")
 	}
 
 	stats := &LoaderStats{}
@@ -151,8 +197,17 @@ func main() {
 		filterConcepts(os.Stdin, os.Stdout, stats)
 	case "rel":
 		filterRelationships(os.Stdin, os.Stdout, stats)
+	case "ancestor":
+		if *conceptsFile == "" {
+			log.Fatal("-type=ancestor requires -concepts=<filtered concept file>")
+		}
+		conceptVocab, err := loadConceptVocabularies(*conceptsFile)
+		if err != nil {
+			log.Fatalf("Failed to read concept file: %v", err)
+		}
+		filterAncestors(os.Stdin, os.Stdout, conceptVocab, stats)
 	default:
-		log.Fatalf("Unknown type: %s. Use 'concept' or 'rel'", *filterType)
+		log.Fatalf("Unknown type: %s. Use 'concept', 'rel' or 'ancestor'", *filterType)
 	}
 
 	if *verbose {
@@ -160,6 +215,55 @@ func main() {
 	}
 }
 
+// =============================================================================
+// Filter Predicates (shared by stream filters and direct load)
+// =============================================================================
+
+// keepConcept applies the vocabulary whitelist to a CONCEPT.csv row
+func keepConcept(record []string) bool {
+	vocabularyId := strings.TrimSpace(record[vocabularyIdIdx])
+	if !allowedVocabularies[vocabularyId] {
+		return false
+	}
+
+	// Additional filter: For Drug domain, prefer standard concepts
+	domain := strings.TrimSpace(record[domainIdIdx])
+	standardConcept := strings.TrimSpace(record[standardConceptIdx])
+
+	// Keep if:
+	// 1. It's a class vocabulary (ATC, VA Class, MED-RT) - always keep
+	// 2. It's a drug vocabulary (RxNorm) AND is standard concept
+	// 3. It's LOINC/UCUM - always keep (for context engine)
+	isClassVocab := vocabularyId == "ATC" || vocabularyId == "VA Class" || vocabularyId == "MED-RT"
+	isContextVocab := vocabularyId == "LOINC" || vocabularyId == "UCUM"
+	isDrugVocab := vocabularyId == "RxNorm" || vocabularyId == "RxNorm Extension"
+
+	if isClassVocab || isContextVocab {
+		return true
+	}
+	if isDrugVocab {
+		// For drugs: keep standard concepts OR ingredients
+		// standard_concept = 'S' means standard
+		// Also keep 'C' (classification) concepts
+		return standardConcept == "S" || standardConcept == "C" ||
+			domain == "Drug" // Keep all drug domain for now (can tighten later)
+	}
+	return false
+}
+
+// keepRelationship applies the relationship whitelist to a CONCEPT_RELATIONSHIP.csv row
+func keepRelationship(record []string) bool {
+	return allowedRelationships[strings.TrimSpace(record[relationshipIdIdx])]
+}
+
+// keepAncestor keeps closure rows whose ancestor is a class concept and whose
+// descendant survived the concept filter
+func keepAncestor(record []string, conceptVocab map[string]string) bool {
+	ancestorVocab := conceptVocab[strings.TrimSpace(record[ancestorIdIdx])]
+	_, descendantKept := conceptVocab[strings.TrimSpace(record[descendantIdIdx])]
+	return classVocabularies[ancestorVocab] && descendantKept
+}
+
 // =============================================================================
 // Concept Filter (CONCEPT.csv)
 // =============================================================================
@@ -201,39 +305,9 @@ func filterConcepts(input io.Reader, output io.Writer, stats *LoaderStats) {
 			continue
 		}
 
-		// Apply vocabulary filter
-		vocabularyId := strings.TrimSpace(record[vocabularyIdIdx])
-
-		if allowedVocabularies[vocabularyId] {
-			// Additional filter: For Drug domain, prefer standard concepts
-			domain := strings.TrimSpace(record[domainIdIdx])
-			standardConcept := strings.TrimSpace(record[standardConceptIdx])
-
-			// Keep if:
-			// 1. It's a class vocabulary (ATC, VA Class, MED-RT) - always keep
-			// 2. It's a drug vocabulary (RxNorm) AND is standard concept
-			// 3. It's LOINC/UCUM - always keep (for context engine)
-			isClassVocab := vocabularyId == "ATC" || vocabularyId == "VA Class" || vocabularyId == "MED-RT"
-			isContextVocab := vocabularyId == "LOINC" || vocabularyId == "UCUM"
-			isDrugVocab := vocabularyId == "RxNorm" || vocabularyId == "RxNorm Extension"
-
-			shouldKeep := false
-			if isClassVocab || isContextVocab {
-				shouldKeep = true
-			} else if isDrugVocab {
-				// For drugs: keep standard concepts OR ingredients
-				// standard_concept = 'S' means standard
-				// Also keep 'C' (classification) concepts
-				shouldKeep = standardConcept == "S" || standardConcept == "C" ||
-					domain == "Drug" // Keep all drug domain for now (can tighten later)
-			}
-
-			if shouldKeep {
-				writer.Write(record)
-				stats.TotalWritten++
-			} else {
-				stats.Skipped++
-			}
+		if keepConcept(record) {
+			writer.Write(record)
+			stats.TotalWritten++
 		} else {
 			stats.Skipped++
 		}
@@ -287,10 +361,7 @@ func filterRelationships(input io.Reader, output io.Writer, stats *LoaderStats)
 			continue
 		}
 
-		// Apply relationship filter
-		relationshipId := strings.TrimSpace(record[relationshipIdIdx])
-
-		if allowedRelationships[relationshipId] {
+		if keepRelationship(record) {
 			writer.Write(record)
 			stats.TotalWritten++
 		} else {
@@ -304,3 +375,92 @@ func filterRelationships(input io.Reader, output io.Writer, stats *LoaderStats)
 		}
 	}
 }
+
+// =============================================================================
+// Ancestor Filter (CONCEPT_ANCESTOR.csv)
+// =============================================================================
+
+// loadConceptVocabularies reads a filtered concept file into concept_id -> vocabulary_id
+func loadConceptVocabularies(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(bufio.NewReaderSize(file, 1024*1024))
+	reader.Comma = '\t'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	// Skip header
+	if _, err := reader.Read(); err != nil {
+		return nil, err
+	}
+
+	vocabularies := make(map[string]string)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil || len(record) < 10 {
+			continue
+		}
+		vocabularies[strings.TrimSpace(record[conceptIdIdx])] = strings.TrimSpace(record[vocabularyIdIdx])
+	}
+
+	return vocabularies, nil
+}
+
+// filterAncestors keeps closure rows whose ancestor is a class concept and whose
+// descendant survived the concept filter. Self rows (level 0) are kept for classes.
+func filterAncestors(input io.Reader, output io.Writer, conceptVocab map[string]string, stats *LoaderStats) {
+	reader := csv.NewReader(bufio.NewReaderSize(input, 1024*1024)) // 1MB buffer
+	reader.Comma = '\t'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	writer := csv.NewWriter(output)
+	writer.Comma = '\t'
+	defer writer.Flush()
+
+	// Read and write header
+	header, err := reader.Read()
+	if err != nil {
+		log.Fatal("Failed to read header:", err)
+	}
+	writer.Write(header)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			stats.Errors++
+			continue
+		}
+
+		stats.TotalRead++
+
+		// Safety check for malformed rows
+		if len(record) < 4 {
+			stats.Skipped++
+			continue
+		}
+
+		if keepAncestor(record, conceptVocab) {
+			writer.Write(record)
+			stats.TotalWritten++
+		} else {
+			stats.Skipped++
+		}
+
+		// Progress indicator (every 1M rows - ancestor files are the largest)
+		if stats.TotalRead%1000000 == 0 {
+			fmt.Fprintf(os.Stderr, "[Progress] Processed %dM rows, kept %dk\n",
+				stats.TotalRead/1000000, stats.TotalWritten/1000)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// =============================================================================
+// Athena Release Source (directory or zip)
+// =============================================================================
+
+// Athena release file names
+const (
+	conceptFileName      = "CONCEPT.csv"
+	relationshipFileName = "CONCEPT_RELATIONSHIP.csv"
+	ancestorFileName     = "CONCEPT_ANCESTOR.csv"
+	vocabularyFileName   = "VOCABULARY.csv"
+)
+
+// releaseSource reads vocabulary files from an Athena download, either the
+// extracted directory or the zip as downloaded. File names are matched
+// case-insensitively anywhere in the tree.
+type releaseSource struct {
+	path     string
+	archive  *zip.ReadCloser
+	zipFiles map[string]*zip.File // upper-case base name -> zip entry
+	dirFiles map[string]string    // upper-case base name -> file path
+}
+
+// openReleaseSource opens an Athena release directory or zip archive
+func openReleaseSource(path string) (*releaseSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open release: %w", err)
+	}
+
+	rs := &releaseSource{
+		path:     path,
+		zipFiles: make(map[string]*zip.File),
+		dirFiles: make(map[string]string),
+	}
+
+	if info.IsDir() {
+		err = filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.IsDir() {
+				rs.dirFiles[strings.ToUpper(fi.Name())] = p
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot scan release directory: %w", err)
+		}
+		return rs, nil
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("release is neither a directory nor a zip archive: %w", err)
+	}
+	rs.archive = archive
+	for _, f := range archive.File {
+		rs.zipFiles[strings.ToUpper(filepath.Base(f.Name))] = f
+	}
+
+	return rs, nil
+}
+
+// Has reports whether the release contains the named file
+func (rs *releaseSource) Has(name string) bool {
+	key := strings.ToUpper(name)
+	_, inZip := rs.zipFiles[key]
+	_, inDir := rs.dirFiles[key]
+	return inZip || inDir
+}
+
+// Open opens the named file from the release
+func (rs *releaseSource) Open(name string) (io.ReadCloser, error) {
+	key := strings.ToUpper(name)
+	if f, exists := rs.zipFiles[key]; exists {
+		return f.Open()
+	}
+	if p, exists := rs.dirFiles[key]; exists {
+		return os.Open(p)
+	}
+	return nil, fmt.Errorf("%s not found in release %s", name, rs.path)
+}
+
+// Close releases the underlying archive, if any
+func (rs *releaseSource) Close() error {
+	if rs.archive != nil {
+		return rs.archive.Close()
+	}
+	return nil
+}
+
+// ReleaseVersion returns the vocabulary version from VOCABULARY.csv
+// (the "None" row carries the overall release, e.g. "v5.0 27-FEB-25")
+func (rs *releaseSource) ReleaseVersion() string {
+	if !rs.Has(vocabularyFileName) {
+		return ""
+	}
+
+	file, err := rs.Open(vocabularyFileName)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	reader := newReleaseReader(file)
+	if _, err := reader.Read(); err != nil {
+		return ""
+	}
+
+	// VOCABULARY.csv columns: vocabulary_id, vocabulary_name, vocabulary_reference,
+	// vocabulary_version, vocabulary_concept_id
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil || len(record) < 4 {
+			continue
+		}
+		if strings.TrimSpace(record[0]) == "None" {
+			return strings.TrimSpace(record[3])
+		}
+	}
+
+	return ""
+}
+
+// newReleaseReader creates a tab-separated reader tolerant of messy medical strings
+func newReleaseReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(bufio.NewReaderSize(r, 1024*1024)) // 1MB buffer
+	reader.Comma = '\t'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	return reader
+}
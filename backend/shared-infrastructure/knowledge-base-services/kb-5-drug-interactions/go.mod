@@ -19,7 +19,10 @@ require (
 	gorm.io/gorm v1.25.5
 )
 
-require github.com/stretchr/testify v1.8.4
+require (
+	github.com/jackc/pgx/v5 v5.4.3
+	github.com/stretchr/testify v1.8.4
+)
 
 require (
 	github.com/beorn7/perks v1.0.1 // indirect
@@ -38,7 +41,6 @@ require (
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
-	github.com/jackc/pgx/v5 v5.4.3 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
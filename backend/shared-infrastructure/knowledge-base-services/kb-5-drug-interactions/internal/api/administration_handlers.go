@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"kb-drug-interactions/internal/services"
+)
+
+// AdministrationHandlers handles administration-timing separation endpoints
+type AdministrationHandlers struct {
+	scheduleEngine *services.AdministrationScheduleEngine
+}
+
+// NewAdministrationHandlers creates handlers for the administration schedule engine
+func NewAdministrationHandlers(scheduleEngine *services.AdministrationScheduleEngine) *AdministrationHandlers {
+	return &AdministrationHandlers{
+		scheduleEngine: scheduleEngine,
+	}
+}
+
+// sendScheduleInputError sends 400 for an invalid meal, wake or sleep time or dosing interval, returning false for other errors
+func sendScheduleInputError(c *gin.Context, err error) bool {
+	var inputErr *services.ScheduleInputError
+	if !errors.As(err, &inputErr) {
+		return false
+	}
+	sendError(c, http.StatusBadRequest, "Invalid schedule time", "INVALID_SCHEDULE_INPUT", map[string]interface{}{
+		"field":  inputErr.Field,
+		"reason": inputErr.Reason,
+	})
+	return true
+}
+
+// buildAdministrationSchedule handles POST /api/v1/administration/schedule
+// Proposes daily administration times for a regimen that keep the required
+// separations, or reports the separations that cannot be kept
+func (h *AdministrationHandlers) buildAdministrationSchedule(c *gin.Context) {
+	if h.scheduleEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Administration schedule engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	var request services.AdministrationScheduleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.scheduleEngine.BuildSchedule(c.Request.Context(), request)
+	if err != nil {
+		if sendScheduleInputError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to build administration schedule", "SCHEDULE_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, response, map[string]interface{}{
+		"analysis_type":     "administration_schedule",
+		"orders_checked":    len(request.MedicationOrders),
+		"separations_found": len(response.Separations),
+		"conflicts":         len(response.Conflicts),
+	})
+}
+
+// getDrugSeparationRules handles GET /api/v1/administration/separations/:drug_code
+// Returns the separation rules naming a drug on either side
+func (h *AdministrationHandlers) getDrugSeparationRules(c *gin.Context) {
+	if h.scheduleEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Administration schedule engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	drugCode := c.Param("drug_code")
+	rules, err := h.scheduleEngine.GetDrugSeparationRules(c.Request.Context(), drugCode)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to get separation rules", "LOOKUP_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, rules, map[string]interface{}{
+		"drug_code": drugCode,
+		"count":     len(rules),
+	})
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"kb-drug-interactions/internal/models"
+	"kb-drug-interactions/internal/services"
+)
+
+// DosingHandlers handles patient-specific dose estimation endpoints
+type DosingHandlers struct {
+	warfarinEngine       *services.WarfarinDosingEngine
+	organDosingEngine    *services.OrganDoseAdjustmentEngine
+	cumulativeDoseEngine *services.CumulativeDoseEngine
+}
+
+// NewDosingHandlers creates handlers for dosing engines
+func NewDosingHandlers(
+	warfarinEngine *services.WarfarinDosingEngine,
+	organDosingEngine *services.OrganDoseAdjustmentEngine,
+	cumulativeDoseEngine *services.CumulativeDoseEngine,
+) *DosingHandlers {
+	return &DosingHandlers{
+		warfarinEngine:       warfarinEngine,
+		organDosingEngine:    organDosingEngine,
+		cumulativeDoseEngine: cumulativeDoseEngine,
+	}
+}
+
+// sendDosingInputError sends 400 for an invalid dosing input, returning false for other errors
+func sendDosingInputError(c *gin.Context, err error) bool {
+	var inputErr *services.DosingInputError
+	if !errors.As(err, &inputErr) {
+		return false
+	}
+	sendError(c, http.StatusBadRequest, "Invalid dosing input", "INVALID_DOSING_INPUT", map[string]interface{}{
+		"field":  inputErr.Field,
+		"reason": inputErr.Reason,
+	})
+	return true
+}
+
+// sendLabInputError sends 400 for an invalid patient lab value, returning false for other errors
+func sendLabInputError(c *gin.Context, err error) bool {
+	var inputErr *services.LabInputError
+	if !errors.As(err, &inputErr) {
+		return false
+	}
+	sendError(c, http.StatusBadRequest, "Invalid patient lab value", "INVALID_LAB_INPUT", map[string]interface{}{
+		"field":  inputErr.Field,
+		"reason": inputErr.Reason,
+	})
+	return true
+}
+
+// assessOrganFunction handles POST /api/v1/dosing/organ-function
+// Calculates eGFR (CKD-EPI 2021), CrCl (Cockcroft-Gault) and Child-Pugh class from
+// raw labs, returning the formulas and inputs used
+func (h *DosingHandlers) assessOrganFunction(c *gin.Context) {
+	var labs models.PatientLabs
+	if err := c.ShouldBindJSON(&labs); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	assessment, err := services.AssessOrganFunction(labs)
+	if err != nil {
+		if sendLabInputError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to assess organ function", "ORGAN_FUNCTION_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, assessment, nil)
+}
+
+// estimateWarfarinDose handles POST /api/v1/dosing/warfarin
+// Estimates the genotype-guided weekly warfarin dose (IWPC) and adjusts it for
+// interacting drugs in the regimen
+func (h *DosingHandlers) estimateWarfarinDose(c *gin.Context) {
+	if h.warfarinEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Warfarin dosing not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	var request services.WarfarinDosingRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	estimate, err := h.warfarinEngine.EstimateDose(c.Request.Context(), request)
+	if err != nil {
+		if sendDosingInputError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to estimate warfarin dose", "DOSE_ESTIMATION_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, estimate, map[string]interface{}{
+		"analysis_type": "warfarin_dosing",
+		"algorithm":     estimate.Algorithm,
+	})
+}
+
+// checkOrganDoseAdjustments handles POST /api/v1/dosing/organ-adjustment
+// Checks ordered doses and frequencies against renal (CrCl/eGFR, dialysis) and
+// hepatic (Child-Pugh) dosing limits
+func (h *DosingHandlers) checkOrganDoseAdjustments(c *gin.Context) {
+	if h.organDosingEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Organ dose adjustment not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	var request services.DoseAdjustmentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.organDosingEngine.CheckDoses(c.Request.Context(), request)
+	if err != nil {
+		if sendLabInputError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to check dose adjustments", "DOSE_CHECK_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	adjustmentsRequired := 0
+	for _, result := range response.Results {
+		if result.Status == services.DoseStatusAvoid || result.Status == services.DoseStatusExceedsMax {
+			adjustmentsRequired++
+		}
+	}
+
+	sendSuccess(c, response, map[string]interface{}{
+		"analysis_type":        "organ_dose_adjustment",
+		"orders_checked":       len(request.Orders),
+		"adjustments_required": adjustmentsRequired,
+	})
+}
+
+// getDrugDoseAdjustments handles GET /api/v1/dosing/organ-adjustment/:drug_code
+// Returns the renal and hepatic dosing limits for a drug
+func (h *DosingHandlers) getDrugDoseAdjustments(c *gin.Context) {
+	if h.organDosingEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Organ dose adjustment not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	drugCode := c.Param("drug_code")
+	rules, err := h.organDosingEngine.GetDrugAdjustments(c.Request.Context(), drugCode)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to get dose adjustments", "LOOKUP_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, rules, map[string]interface{}{
+		"drug_code": drugCode,
+		"count":     len(rules),
+	})
+}
+
+// checkCumulativeDoses handles POST /api/v1/dosing/cumulative
+// Sums the daily dose of each ingredient across all orders, including combination
+// products, and checks the totals against age- and organ-specific maximums
+func (h *DosingHandlers) checkCumulativeDoses(c *gin.Context) {
+	if h.cumulativeDoseEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Cumulative dose checking not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	var request services.CumulativeDoseRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.cumulativeDoseEngine.CheckRegimen(c.Request.Context(), request)
+	if err != nil {
+		if sendLabInputError(c, err) || sendPediatricInputError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to check cumulative doses", "DOSE_CHECK_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	limitsExceeded := 0
+	for _, result := range response.Results {
+		if result.Status == services.DoseStatusExceedsMax {
+			limitsExceeded++
+		}
+	}
+
+	sendSuccess(c, response, map[string]interface{}{
+		"analysis_type":   "cumulative_dose",
+		"orders_checked":  len(request.MedicationOrders),
+		"limits_exceeded": limitsExceeded,
+	})
+}
+
+// getIngredientDoseLimits handles GET /api/v1/dosing/cumulative/:ingredient_code
+// Returns the maximum daily dose limits for an ingredient
+func (h *DosingHandlers) getIngredientDoseLimits(c *gin.Context) {
+	if h.cumulativeDoseEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Cumulative dose checking not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	ingredientCode := c.Param("ingredient_code")
+	limits, err := h.cumulativeDoseEngine.GetIngredientLimits(c.Request.Context(), ingredientCode)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to get ingredient dose limits", "LOOKUP_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, limits, map[string]interface{}{
+		"ingredient_code": ingredientCode,
+		"count":           len(limits),
+	})
+}
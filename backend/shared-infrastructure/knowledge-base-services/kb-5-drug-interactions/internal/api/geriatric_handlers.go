@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"kb-drug-interactions/internal/services"
+)
+
+// GeriatricHandlers handles older adult medication screening endpoints
+type GeriatricHandlers struct {
+	pimEngine *services.PIMScreeningEngine
+}
+
+// NewGeriatricHandlers creates handlers for the PIM screening engine
+func NewGeriatricHandlers(pimEngine *services.PIMScreeningEngine) *GeriatricHandlers {
+	return &GeriatricHandlers{
+		pimEngine: pimEngine,
+	}
+}
+
+// screenPIM handles POST /api/v1/geriatric/pim-screen
+// Screens a regimen against Beers/STOPP-style explicit criteria using the
+// patient's age, conditions and renal function
+func (h *GeriatricHandlers) screenPIM(c *gin.Context) {
+	if h.pimEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "PIM screening engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	var request services.PIMScreeningRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.pimEngine.Screen(c.Request.Context(), request)
+	if err != nil {
+		if sendLabInputError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to screen for potentially inappropriate medications", "PIM_SCREEN_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, response, map[string]interface{}{
+		"analysis_type":  "pim_screening",
+		"drugs_checked":  len(request.DrugCodes),
+		"total_findings": len(response.Findings),
+	})
+}
+
+// getCriteriaSets handles GET /api/v1/geriatric/criteria
+// Returns every criteria set version, current versions first
+func (h *GeriatricHandlers) getCriteriaSets(c *gin.Context) {
+	if h.pimEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "PIM screening engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	sets, err := h.pimEngine.GetCriteriaSets(c.Request.Context())
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to get PIM criteria sets", "LOOKUP_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, sets, map[string]interface{}{
+		"count": len(sets),
+	})
+}
+
+// getCriteria handles GET /api/v1/geriatric/criteria/:set_code?version=
+// Returns the criteria of a set version (current version by default)
+func (h *GeriatricHandlers) getCriteria(c *gin.Context) {
+	if h.pimEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "PIM screening engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	selector := c.Param("set_code")
+	if version := c.Query("version"); version != "" {
+		selector += ":" + version
+	}
+
+	set, criteria, err := h.pimEngine.GetCriteria(c.Request.Context(), selector)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to get PIM criteria", "LOOKUP_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if set == nil {
+		sendError(c, http.StatusNotFound, "Criteria set not found", "CRITERIA_SET_NOT_FOUND", map[string]interface{}{
+			"criteria_set": selector,
+		})
+		return
+	}
+
+	sendSuccess(c, criteria, map[string]interface{}{
+		"criteria_set": set,
+		"count":        len(criteria),
+	})
+}
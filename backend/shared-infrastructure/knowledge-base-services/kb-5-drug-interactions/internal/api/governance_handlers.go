@@ -17,6 +17,11 @@ type GovernanceHandlers struct {
 	governanceEngine *services.GovernancePolicyEngine
 	interactionService *services.InteractionService
 	integrationService *services.EnhancedIntegrationService
+	pgxEngine          *services.PharmacogenomicEngine
+	reproductiveEngine *services.ReproductiveSafetyEngine
+	pimEngine          *services.PIMScreeningEngine
+	pediatricEngine    *services.PediatricSafetyEngine
+	overrideEngine     *services.GovernanceOverrideEngine
 }
 
 // NewGovernanceHandlers creates new governance handlers
@@ -24,11 +29,21 @@ func NewGovernanceHandlers(
 	governanceEngine *services.GovernancePolicyEngine,
 	interactionService *services.InteractionService,
 	integrationService *services.EnhancedIntegrationService,
+	pgxEngine *services.PharmacogenomicEngine,
+	reproductiveEngine *services.ReproductiveSafetyEngine,
+	pimEngine *services.PIMScreeningEngine,
+	pediatricEngine *services.PediatricSafetyEngine,
+	overrideEngine *services.GovernanceOverrideEngine,
 ) *GovernanceHandlers {
 	return &GovernanceHandlers{
 		governanceEngine:   governanceEngine,
 		interactionService: interactionService,
 		integrationService: integrationService,
+		pgxEngine:          pgxEngine,
+		reproductiveEngine: reproductiveEngine,
+		pimEngine:          pimEngine,
+		pediatricEngine:    pediatricEngine,
+		overrideEngine:     overrideEngine,
 	}
 }
 
@@ -43,6 +58,7 @@ type GovernedInteractionCheckRequest struct {
 	DrugCodes       []string                     `json:"drug_codes" binding:"required,min=2"`
 	PatientContext  *models.PatientContextData   `json:"patient_context,omitempty"`
 	InstitutionID   string                       `json:"institution_id,omitempty"`
+	PatientID       string                       `json:"patient_id,omitempty"` // Enables approved override requests
 	DatasetVersion  string                       `json:"dataset_version,omitempty"`
 	IncludeGuidance bool                         `json:"include_guidance"`
 }
@@ -63,6 +79,40 @@ func (h *GovernanceHandlers) governedInteractionCheck(c *gin.Context) {
 		return
 	}
 
+	// Derive renal and hepatic stages from raw labs for governance escalation
+	organFunction, err := services.ResolvePatientContextData(request.PatientContext)
+	if err != nil {
+		if sendLabInputError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to assess organ function", "ORGAN_FUNCTION_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Pregnancy and lactation status drive reproductive safety findings and program flags
+	if request.PatientContext != nil {
+		request.PatientContext.Reproductive, err = services.ResolveReproductiveStatus(request.PatientContext.Reproductive)
+		if err != nil {
+			sendReproductiveInputError(c, err)
+			return
+		}
+	}
+
+	// Exact age and weight drive pediatric rules; an age under 18 sets the pediatric band
+	pediatricPatient, err := services.PediatricPatientFromContextData(request.PatientContext)
+	if err != nil {
+		sendPediatricInputError(c, err)
+		return
+	}
+	if pediatricPatient.IsPediatric() && request.PatientContext.AgeBand == "" {
+		request.PatientContext.AgeBand = "pediatric"
+	}
+
+	// Every result in the response is governed by the same policy version
+	policy := h.governanceEngine.GetPolicy(request.InstitutionID)
+
 	// First, get base interactions from interaction service
 	baseRequest := models.InteractionCheckRequest{
 		DrugCodes:           request.DrugCodes,
@@ -87,14 +137,131 @@ func (h *GovernanceHandlers) governedInteractionCheck(c *gin.Context) {
 		enhanced := convertToEnhancedResult(interaction)
 
 		// Apply governance layer
-		governed := h.governanceEngine.EnhanceWithGovernance(
+		governed := h.governanceEngine.EnhanceWithPolicy(
 			enhanced,
-			request.InstitutionID,
+			policy,
 			request.PatientContext,
 		)
 		governedInteractions = append(governedInteractions, governed)
 	}
 
+	// PGx safety checks (HLA risk alleles, dose-critical genes) carry their own
+	// minimum governance action. Without patient context, risk alleles are unscreened.
+	if h.pgxEngine != nil {
+		var phenotypes, riskAlleles map[string]string
+		if request.PatientContext != nil {
+			phenotypes, riskAlleles = request.PatientContext.PGX, request.PatientContext.PGXRiskAlleles
+		}
+		findings, err := h.pgxEngine.EvaluatePGXSafety(
+			c.Request.Context(),
+			request.DrugCodes,
+			phenotypes,
+			riskAlleles,
+		)
+		if err != nil {
+			sendError(c, http.StatusInternalServerError, "Failed to run PGx safety checks", "PGX_SAFETY_FAILED", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		for _, finding := range findings {
+			governed := h.governanceEngine.EnhanceWithPolicy(
+				finding,
+				policy,
+				request.PatientContext,
+			)
+			governedInteractions = append(governedInteractions, governed)
+		}
+		enginesUsed = append(enginesUsed, "pgx_safety")
+	}
+
+	// Pregnancy and lactation findings carry their own minimum governance action
+	if h.reproductiveEngine != nil && request.PatientContext != nil && request.PatientContext.Reproductive != nil {
+		findings, _, err := h.reproductiveEngine.EvaluateReproductiveSafety(
+			c.Request.Context(),
+			request.DrugCodes,
+			request.PatientContext.Reproductive,
+		)
+		if err != nil {
+			sendError(c, http.StatusInternalServerError, "Failed to run reproductive safety checks", "REPRODUCTIVE_CHECK_FAILED", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		for _, finding := range findings {
+			governed := h.governanceEngine.EnhanceWithPolicy(
+				finding,
+				policy,
+				request.PatientContext,
+			)
+			governedInteractions = append(governedInteractions, governed)
+		}
+		enginesUsed = append(enginesUsed, "reproductive_safety")
+	}
+
+	// Older adults are screened against the current Beers/STOPP criteria
+	if h.pimEngine != nil && request.PatientContext != nil {
+		patient := services.PIMPatientFromContextData(request.PatientContext, organFunction)
+		if patient.IsOlderAdult() {
+			screen, err := h.pimEngine.ScreenRegimen(c.Request.Context(), request.DrugCodes, patient, nil)
+			if err != nil {
+				sendError(c, http.StatusInternalServerError, "Failed to screen for potentially inappropriate medications", "PIM_SCREEN_FAILED", map[string]interface{}{
+					"error": err.Error(),
+				})
+				return
+			}
+			for _, finding := range screen.Findings {
+				governed := h.governanceEngine.EnhanceWithPolicy(
+					finding,
+					policy,
+					request.PatientContext,
+				)
+				governedInteractions = append(governedInteractions, governed)
+			}
+			enginesUsed = append(enginesUsed, "pim_screening")
+		}
+	}
+
+	// Pediatric contraindications and interactions carry their own minimum governance action
+	if h.pediatricEngine != nil && pediatricPatient.MayBePediatric() {
+		findings, _, err := h.pediatricEngine.EvaluatePediatricSafety(
+			c.Request.Context(),
+			request.DrugCodes,
+			nil,
+			pediatricPatient,
+		)
+		if err != nil {
+			sendError(c, http.StatusInternalServerError, "Failed to run pediatric safety checks", "PEDIATRIC_CHECK_FAILED", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		for _, finding := range findings {
+			governed := h.governanceEngine.EnhanceWithPolicy(
+				finding,
+				policy,
+				request.PatientContext,
+			)
+			governedInteractions = append(governedInteractions, governed)
+		}
+		enginesUsed = append(enginesUsed, "pediatric_safety")
+	}
+
+	// Approved, still-valid override requests for this patient at this
+	// institution lift matching blocks and escalations
+	if h.overrideEngine != nil && request.PatientID != "" {
+		applied, err := h.overrideEngine.ApplyApprovedOverrides(c.Request.Context(), request.PatientID, request.InstitutionID, governedInteractions)
+		if err != nil {
+			sendError(c, http.StatusInternalServerError, "Failed to apply governance overrides", "OVERRIDE_LOOKUP_FAILED", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		if applied > 0 {
+			enginesUsed = append(enginesUsed, "governance_overrides")
+		}
+	}
+
 	// Build governed summary
 	summary := h.governanceEngine.BuildGovernedSummary(governedInteractions)
 
@@ -104,19 +271,21 @@ func (h *GovernanceHandlers) governedInteractionCheck(c *gin.Context) {
 		governedInteractions,
 		enginesUsed,
 		processingTime,
-		request.InstitutionID,
+		policy,
 	)
 	attribution.RequestHash = h.governanceEngine.HashRequest(request)
 
 	// Build full response
 	response := models.GovernedInteractionCheckResponse{
 		TransactionID:    uuid.New().String(),
-		DatasetVersion:   h.governanceEngine.GetPolicy(request.InstitutionID).PolicyName,
+		DatasetVersion:   policy.PolicyName,
 		Interactions:     governedInteractions,
 		Summary:          summary,
-		PolicyApplied:    h.governanceEngine.GetPolicy(request.InstitutionID).PolicyName,
-		PolicyVersion:    "1.0",
+		PolicyApplied:    policy.PolicyName,
+		PolicyVersion:    policy.VersionLabel(),
+		PolicyVersionID:  policy.VersionID(),
 		InstitutionID:    request.InstitutionID,
+		OrganFunction:    organFunction,
 		Attribution:      attribution,
 		Recommendations:  baseResponse.Recommendations,
 		CheckTimestamp:   time.Now(),
@@ -143,6 +312,7 @@ func (h *GovernanceHandlers) governedInteractionCheck(c *gin.Context) {
 // ============================================================================
 
 // getGovernancePolicy handles GET /api/v1/governance/policy
+// Returns the policy version in effect now for an institution
 func (h *GovernanceHandlers) getGovernancePolicy(c *gin.Context) {
 	institutionID := c.Query("institution_id")
 
@@ -151,7 +321,11 @@ func (h *GovernanceHandlers) getGovernancePolicy(c *gin.Context) {
 	sendSuccess(c, map[string]interface{}{
 		"policy_name":             policy.PolicyName,
 		"institution_id":          policy.InstitutionID,
+		"version":                 policy.Version,
+		"policy_version_id":       policy.VersionID(),
 		"effective_date":          policy.EffectiveDate,
+		"expiry_date":             policy.ExpiryDate,
+		"approved_by":             policy.ApprovedBy,
 		"severity_mappings": map[string]string{
 			"contraindicated": string(policy.ContraindicatedAction),
 			"major":           string(policy.MajorAction),
@@ -192,13 +366,25 @@ func (h *GovernanceHandlers) translateSeverity(c *gin.Context) {
 		return
 	}
 
+	organFunction, err := services.ResolvePatientContextData(request.PatientContext)
+	if err != nil {
+		if sendLabInputError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to assess organ function", "ORGAN_FUNCTION_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	// Convert string to DDISeverity
 	severity := models.DDISeverity(request.Severity)
 
 	// Translate to governance action
-	action := h.governanceEngine.TranslateSeverity(
+	policy := h.governanceEngine.GetPolicy(request.InstitutionID)
+	action := h.governanceEngine.TranslateSeverityWithPolicy(
 		severity,
-		request.InstitutionID,
+		policy,
 		request.PatientContext,
 	)
 
@@ -211,9 +397,11 @@ func (h *GovernanceHandlers) translateSeverity(c *gin.Context) {
 		"requires_acknowledgment": action.RequiresAcknowledgment(),
 		"allows_clinical_override": action.AllowsClinicalOverride(),
 		"override_level":      models.GetOverrideLevel(action),
-		"policy_applied":      h.governanceEngine.GetPolicy(request.InstitutionID).PolicyName,
+		"policy_applied":      policy.PolicyName,
+		"policy_version_id":   policy.VersionID(),
 	}, map[string]interface{}{
 		"context_applied": request.PatientContext != nil,
+		"organ_function":  organFunction,
 	})
 }
 
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"kb-drug-interactions/internal/models"
+	"kb-drug-interactions/internal/services"
+)
+
+// ============================================================================
+// GOVERNANCE OVERRIDE REQUEST ENDPOINTS
+// Submit, decide and list requests to proceed past escalations and
+// governance blocks for a patient
+// ============================================================================
+
+// sendOverrideRequestError sends the status for a workflow rule violation,
+// returning false for other errors
+func sendOverrideRequestError(c *gin.Context, err error) bool {
+	var overrideErr *services.OverrideRequestError
+	if !errors.As(err, &overrideErr) {
+		return false
+	}
+
+	status := http.StatusBadRequest
+	switch overrideErr.Code {
+	case services.OverrideErrorNotFound:
+		status = http.StatusNotFound
+	case services.OverrideErrorNotPending:
+		status = http.StatusConflict
+	case services.OverrideErrorSelfDecision, services.OverrideErrorUnauthorized:
+		status = http.StatusForbidden
+	}
+	sendError(c, status, overrideErr.Reason, overrideErr.Code, nil)
+	return true
+}
+
+// requireCaller returns the authenticated caller's user ID and role, sending
+// 401 if either is absent
+func requireCaller(c *gin.Context) (string, string, bool) {
+	callerID, ok := requireCallerID(c)
+	if !ok {
+		return "", "", false
+	}
+	callerRole := strings.TrimSpace(c.GetHeader(callerRoleHeader))
+	if callerRole == "" {
+		sendError(c, http.StatusUnauthorized, "Caller role required", "UNAUTHENTICATED", map[string]interface{}{
+			"header": callerRoleHeader,
+		})
+		return "", "", false
+	}
+	return callerID, callerRole, true
+}
+
+// parseOverrideRequestID parses the :override_id path parameter, sending 400 if invalid
+func parseOverrideRequestID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("override_id"))
+	if err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid override request ID format", "INVALID_OVERRIDE_ID", nil)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// submitOverrideRequest handles POST /api/v1/governance/overrides
+// Requests an override for a mandatory_escalation or hard_block_governance_override
+// alert; the caller is the requester
+func (h *GovernanceHandlers) submitOverrideRequest(c *gin.Context) {
+	if h.overrideEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Override request engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+	callerID, callerRole, ok := requireCaller(c)
+	if !ok {
+		return
+	}
+
+	var request services.OverrideRequestSubmission
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	request.RequesterID, request.RequesterRole = callerID, callerRole
+	overrideRequest, err := h.overrideEngine.SubmitOverrideRequest(c.Request.Context(), request)
+	if err != nil {
+		if sendOverrideRequestError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to submit override request", "OVERRIDE_SUBMIT_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, overrideRequest, nil)
+}
+
+// listOverrideRequests handles GET /api/v1/governance/overrides
+// Filters by patient_id, institution_id and status
+func (h *GovernanceHandlers) listOverrideRequests(c *gin.Context) {
+	if h.overrideEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Override request engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	filter := services.OverrideRequestFilter{
+		PatientID:     c.Query("patient_id"),
+		InstitutionID: c.Query("institution_id"),
+		Status:        c.Query("status"),
+	}
+	switch filter.Status {
+	case "", models.OverrideStatusPending, models.OverrideStatusApproved, models.OverrideStatusDenied, models.OverrideStatusExpired:
+	default:
+		sendError(c, http.StatusBadRequest, "Invalid status filter", "INVALID_REQUEST", map[string]interface{}{
+			"status":   filter.Status,
+			"accepted": []string{models.OverrideStatusPending, models.OverrideStatusApproved, models.OverrideStatusDenied, models.OverrideStatusExpired},
+		})
+		return
+	}
+
+	requests, err := h.overrideEngine.ListOverrideRequests(c.Request.Context(), filter)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to list override requests", "OVERRIDE_LIST_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, requests, map[string]interface{}{
+		"total_requests": len(requests),
+	})
+}
+
+// getOverrideRequest handles GET /api/v1/governance/overrides/:override_id
+func (h *GovernanceHandlers) getOverrideRequest(c *gin.Context) {
+	if h.overrideEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Override request engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+	id, ok := parseOverrideRequestID(c)
+	if !ok {
+		return
+	}
+
+	overrideRequest, err := h.overrideEngine.GetOverrideRequest(c.Request.Context(), id)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to load override request", "OVERRIDE_LOOKUP_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if overrideRequest == nil {
+		sendError(c, http.StatusNotFound, "Override request not found", services.OverrideErrorNotFound, map[string]interface{}{
+			"override_id": id,
+		})
+		return
+	}
+
+	sendSuccess(c, overrideRequest, nil)
+}
+
+// approveOverrideRequest handles POST /api/v1/governance/overrides/:override_id/approve
+func (h *GovernanceHandlers) approveOverrideRequest(c *gin.Context) {
+	h.decideOverrideRequest(c, true)
+}
+
+// denyOverrideRequest handles POST /api/v1/governance/overrides/:override_id/deny
+func (h *GovernanceHandlers) denyOverrideRequest(c *gin.Context) {
+	h.decideOverrideRequest(c, false)
+}
+
+// decideOverrideRequest records an approval or denial by the caller, whose role
+// must have authority for the request's override level
+func (h *GovernanceHandlers) decideOverrideRequest(c *gin.Context, approve bool) {
+	if h.overrideEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Override request engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+	id, ok := parseOverrideRequestID(c)
+	if !ok {
+		return
+	}
+	callerID, callerRole, ok := requireCaller(c)
+	if !ok {
+		return
+	}
+
+	var decision services.OverrideDecision
+	if err := c.ShouldBindJSON(&decision); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	decision.ApproverID, decision.ApproverRole = callerID, callerRole
+	decide := h.overrideEngine.DenyOverrideRequest
+	if approve {
+		decide = h.overrideEngine.ApproveOverrideRequest
+	}
+	overrideRequest, err := decide(c.Request.Context(), id, decision)
+	if err != nil {
+		if sendOverrideRequestError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to record override decision", "OVERRIDE_DECISION_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, overrideRequest, nil)
+}
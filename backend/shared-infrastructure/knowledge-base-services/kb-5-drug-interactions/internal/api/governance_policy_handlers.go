@@ -0,0 +1,284 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"kb-drug-interactions/internal/services"
+)
+
+// ============================================================================
+// GOVERNANCE POLICY VERSION ENDPOINTS
+// Draft → approve → retire workflow; approved versions are immutable
+// ============================================================================
+
+// sendGovernancePolicyError sends the status for a workflow rule violation,
+// returning false for other errors
+func sendGovernancePolicyError(c *gin.Context, err error) bool {
+	var policyErr *services.GovernancePolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	status := http.StatusBadRequest
+	switch policyErr.Code {
+	case services.PolicyErrorNotFound:
+		status = http.StatusNotFound
+	case services.PolicyErrorImmutable, services.PolicyErrorNotApproved, services.PolicyErrorRetired:
+		status = http.StatusConflict
+	case services.PolicyErrorSelfApproval, services.PolicyErrorNotAuthor:
+		status = http.StatusForbidden
+	}
+	sendError(c, status, policyErr.Reason, policyErr.Code, nil)
+	return true
+}
+
+// Caller identity headers, set by the API gateway from the authenticated
+// session. Workflow identities come from these, never from the request body.
+const (
+	callerIDHeader   = "X-User-ID"
+	callerRoleHeader = "X-User-Role"
+)
+
+// requireCallerID returns the authenticated caller's user ID, sending 401 if absent
+func requireCallerID(c *gin.Context) (string, bool) {
+	callerID := strings.TrimSpace(c.GetHeader(callerIDHeader))
+	if callerID == "" {
+		sendError(c, http.StatusUnauthorized, "Caller identity required", "UNAUTHENTICATED", map[string]interface{}{
+			"header": callerIDHeader,
+		})
+		return "", false
+	}
+	return callerID, true
+}
+
+// parsePolicyVersionID parses the :policy_id path parameter, sending 400 if invalid
+func parsePolicyVersionID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("policy_id"))
+	if err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid policy ID format", "INVALID_POLICY_ID", nil)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// listGovernancePolicies handles GET /api/v1/governance/policies
+// Returns every stored version, optionally filtered by policy_name and institution_id
+func (h *GovernanceHandlers) listGovernancePolicies(c *gin.Context) {
+	if h.governanceEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Governance engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	versions, err := h.governanceEngine.ListPolicyVersions(c.Request.Context(), c.Query("policy_name"), c.Query("institution_id"))
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to list governance policies", "POLICY_LIST_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, versions, map[string]interface{}{
+		"total_versions": len(versions),
+	})
+}
+
+// getGovernancePolicyVersion handles GET /api/v1/governance/policies/:policy_id
+func (h *GovernanceHandlers) getGovernancePolicyVersion(c *gin.Context) {
+	if h.governanceEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Governance engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+	id, ok := parsePolicyVersionID(c)
+	if !ok {
+		return
+	}
+
+	version, err := h.governanceEngine.GetPolicyVersion(c.Request.Context(), id)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to load governance policy", "POLICY_LOOKUP_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if version == nil {
+		sendError(c, http.StatusNotFound, "Governance policy version not found", services.PolicyErrorNotFound, map[string]interface{}{
+			"policy_id": id,
+		})
+		return
+	}
+
+	sendSuccess(c, version, nil)
+}
+
+// createGovernancePolicyDraft handles POST /api/v1/governance/policies
+// Creates a new draft version of a policy, authored by the caller; drafts are
+// not applied until approved
+func (h *GovernanceHandlers) createGovernancePolicyDraft(c *gin.Context) {
+	if h.governanceEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Governance engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+	callerID, ok := requireCallerID(c)
+	if !ok {
+		return
+	}
+
+	var request services.GovernancePolicyDraft
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	request.CreatedBy = callerID
+	version, err := h.governanceEngine.CreatePolicyDraft(c.Request.Context(), request)
+	if err != nil {
+		if sendGovernancePolicyError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to create policy draft", "POLICY_CREATE_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, version, nil)
+}
+
+// updateGovernancePolicyDraft handles PUT /api/v1/governance/policies/:policy_id
+// Replaces a draft's settings; only its author may edit it, and approved
+// versions return 409
+func (h *GovernanceHandlers) updateGovernancePolicyDraft(c *gin.Context) {
+	if h.governanceEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Governance engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+	id, ok := parsePolicyVersionID(c)
+	if !ok {
+		return
+	}
+	callerID, ok := requireCallerID(c)
+	if !ok {
+		return
+	}
+
+	var request services.GovernancePolicyDraftUpdate
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	request.UpdatedBy = callerID
+	version, err := h.governanceEngine.UpdatePolicyDraft(c.Request.Context(), id, request)
+	if err != nil {
+		if sendGovernancePolicyError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to update policy draft", "POLICY_UPDATE_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, version, nil)
+}
+
+// deleteGovernancePolicyDraft handles DELETE /api/v1/governance/policies/:policy_id
+// Discards a draft; only its author may discard it, and approved versions return 409
+func (h *GovernanceHandlers) deleteGovernancePolicyDraft(c *gin.Context) {
+	if h.governanceEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Governance engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+	id, ok := parsePolicyVersionID(c)
+	if !ok {
+		return
+	}
+	callerID, ok := requireCallerID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.governanceEngine.DeletePolicyDraft(c.Request.Context(), id, callerID); err != nil {
+		if sendGovernancePolicyError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to delete policy draft", "POLICY_DELETE_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, map[string]interface{}{
+		"policy_id": id,
+		"deleted":   true,
+	}, nil)
+}
+
+// approveGovernancePolicy handles POST /api/v1/governance/policies/:policy_id/approve
+// Approves a draft as the caller, who must not be its author
+func (h *GovernanceHandlers) approveGovernancePolicy(c *gin.Context) {
+	if h.governanceEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Governance engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+	id, ok := parsePolicyVersionID(c)
+	if !ok {
+		return
+	}
+	callerID, ok := requireCallerID(c)
+	if !ok {
+		return
+	}
+
+	version, err := h.governanceEngine.ApprovePolicy(c.Request.Context(), id, callerID)
+	if err != nil {
+		if sendGovernancePolicyError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to approve policy", "POLICY_APPROVE_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, version, nil)
+}
+
+// retireGovernancePolicy handles POST /api/v1/governance/policies/:policy_id/retire
+// Deactivates an approved version so it no longer applies; drafts return 409
+func (h *GovernanceHandlers) retireGovernancePolicy(c *gin.Context) {
+	if h.governanceEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Governance engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+	id, ok := parsePolicyVersionID(c)
+	if !ok {
+		return
+	}
+	callerID, ok := requireCallerID(c)
+	if !ok {
+		return
+	}
+
+	version, err := h.governanceEngine.RetirePolicy(c.Request.Context(), id, callerID)
+	if err != nil {
+		if sendGovernancePolicyError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to retire policy", "POLICY_RETIRE_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, version, nil)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -365,6 +366,15 @@ func parseIntQuery(c *gin.Context, key string, defaultValue int) int {
 	return defaultValue
 }
 
+func parseFloatQuery(c *gin.Context, key string, defaultValue float64) float64 {
+	if value := c.Query(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func parseBoolQuery(c *gin.Context, key string, defaultValue bool) bool {
 	if value := c.Query(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -395,6 +405,7 @@ func (h *InteractionHandlers) comprehensiveAnalysis(c *gin.Context) {
 
 	var request struct {
 		DrugCodes       []string                    `json:"drug_codes" binding:"required,min=2"`
+		MedicationOrders []models.MedicationOrder   `json:"medication_orders,omitempty"`
 		PatientContext  *models.PatientContext      `json:"patient_context,omitempty"`
 		ModifierContext *services.ModifierContext   `json:"modifier_context,omitempty"`
 		DatasetVersion  string                      `json:"dataset_version,omitempty"`
@@ -415,8 +426,9 @@ func (h *InteractionHandlers) comprehensiveAnalysis(c *gin.Context) {
 
 	// Build analysis request with proper types
 	analysisRequest := services.ComprehensiveInteractionRequest{
-		DrugCodes:      request.DrugCodes,
-		DatasetVersion: datasetVersion,
+		DrugCodes:        request.DrugCodes,
+		MedicationOrders: request.MedicationOrders,
+		DatasetVersion:   datasetVersion,
 	}
 
 	// Add patient context if provided
@@ -431,6 +443,18 @@ func (h *InteractionHandlers) comprehensiveAnalysis(c *gin.Context) {
 
 	// Perform comprehensive analysis
 	response, err := h.integrationService.PerformComprehensiveAnalysis(c.Request.Context(), analysisRequest)
+	if sendLabInputError(c, err) || sendReproductiveInputError(c, err) || sendPediatricInputError(c, err) {
+		return
+	}
+	var genotypeErr *services.GenotypeError
+	if errors.As(err, &genotypeErr) {
+		sendError(c, http.StatusBadRequest, "Invalid genotype", "INVALID_GENOTYPE", map[string]interface{}{
+			"gene":      genotypeErr.Gene,
+			"diplotype": genotypeErr.Diplotype,
+			"reason":    genotypeErr.Reason,
+		})
+		return
+	}
 	if err != nil {
 		sendError(c, http.StatusInternalServerError, "Failed to perform comprehensive analysis", "ANALYSIS_FAILED", map[string]interface{}{
 			"error": err.Error(),
@@ -439,7 +463,7 @@ func (h *InteractionHandlers) comprehensiveAnalysis(c *gin.Context) {
 	}
 
 	sendSuccess(c, response, map[string]interface{}{
-		"engines_used":        []string{"pgx", "class", "modifier", "matrix"},
+		"engines_used":        []string{"pgx", "class", "modifier", "matrix", "dose_adjustment", "cumulative_dose", "reproductive_safety", "pim_screening", "pediatric_safety"},
 		"analysis_type":       "comprehensive",
 		"has_critical_alerts": len(response.CriticalAlerts) > 0,
 	})
@@ -660,6 +684,139 @@ func (h *InteractionHandlers) cypEnzymeInteractions(c *gin.Context) {
 	})
 }
 
+// translateGenotypes handles POST /api/v1/cyp/genotype/translate
+// Translates lab-reported diplotypes into phenotypes using the current translation tables.
+// When drug_codes are given, phenotypes are also phenoconverted for that regimen.
+func (h *InteractionHandlers) translateGenotypes(c *gin.Context) {
+	if h.pgxEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "PGx analysis not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	var request struct {
+		Genotypes  map[string]models.PGXGenotype `json:"genotypes" binding:"required,min=1"`
+		Phenotypes map[string]string             `json:"phenotypes,omitempty"`
+		DrugCodes  []string                      `json:"drug_codes,omitempty"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	markers, translations, err := h.pgxEngine.ResolvePatientPhenotypes(request.Phenotypes, request.Genotypes)
+	var genotypeErr *services.GenotypeError
+	if errors.As(err, &genotypeErr) {
+		sendError(c, http.StatusBadRequest, "Invalid genotype", "INVALID_GENOTYPE", map[string]interface{}{
+			"gene":      genotypeErr.Gene,
+			"diplotype": genotypeErr.Diplotype,
+			"reason":    genotypeErr.Reason,
+		})
+		return
+	}
+	if err != nil {
+		sendError(c, http.StatusServiceUnavailable, "Genotype translation not available", "TRANSLATION_UNAVAILABLE", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	effective, conversions, err := h.pgxEngine.ApplyPhenoconversion(c.Request.Context(), request.DrugCodes, markers, translations)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to apply phenoconversion", "PHENOCONVERSION_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, map[string]interface{}{
+		"translations":          translations,
+		"pgx_markers":           markers,
+		"effective_pgx_markers": effective,
+		"phenoconversions":      conversions,
+	}, map[string]interface{}{
+		"analysis_type": "genotype_translation",
+		"table_version": h.pgxEngine.TranslationVersion(),
+	})
+}
+
+// pgxSafetyCheck handles POST /api/v1/cyp/safety/check
+// Checks a regimen against HLA risk alleles and dose-critical gene phenotypes
+func (h *InteractionHandlers) pgxSafetyCheck(c *gin.Context) {
+	if h.pgxEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "PGx analysis not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	var request struct {
+		DrugCodes   []string          `json:"drug_codes" binding:"required,min=1"`
+		PGXMarkers  map[string]string `json:"pgx_markers,omitempty"`
+		RiskAlleles map[string]string `json:"pgx_risk_alleles,omitempty"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	findings, err := h.pgxEngine.EvaluatePGXSafety(c.Request.Context(), request.DrugCodes, request.PGXMarkers, request.RiskAlleles)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to run PGx safety checks", "PGX_SAFETY_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	blocked := false
+	for _, f := range findings {
+		if models.GovernanceAction(f.Qualifiers["governance_action"]).IsBlocking() {
+			blocked = true
+		}
+	}
+
+	sendSuccess(c, map[string]interface{}{
+		"findings":         findings,
+		"is_order_blocked": blocked,
+	}, map[string]interface{}{
+		"analysis_type":  "pgx_safety",
+		"total_findings": len(findings),
+	})
+}
+
+// pgxGuidelines handles GET /api/v1/cyp/guidelines
+// Looks up PGx guidance by gene, phenotype and/or drug code
+func (h *InteractionHandlers) pgxGuidelines(c *gin.Context) {
+	if h.pgxEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "PGx analysis not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	gene := c.Query("gene")
+	phenotype := c.Query("phenotype")
+	drugCode := c.Query("drug_code")
+
+	guidelines, err := h.pgxEngine.LookupGuidelines(c.Request.Context(), gene, phenotype, drugCode)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to look up PGx guidelines", "GUIDELINE_LOOKUP_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, map[string]interface{}{
+		"guidelines": guidelines,
+	}, map[string]interface{}{
+		"gene":          gene,
+		"phenotype":     phenotype,
+		"drug_code":     drugCode,
+		"total_results": len(guidelines),
+	})
+}
+
 // getEnzymeDescription returns a clinical description for a CYP enzyme
 func getEnzymeDescription(enzyme string) map[string]interface{} {
 	descriptions := map[string]map[string]interface{}{
@@ -754,6 +911,9 @@ func (h *Phase3Handlers) checkDrugDiseaseContraindications(c *gin.Context) {
 		request,
 		datasetVersion,
 	)
+	if sendLabInputError(c, err) {
+		return
+	}
 	if err != nil {
 		sendError(c, http.StatusInternalServerError, "Failed to evaluate drug-disease contraindications", "EVALUATION_FAILED", map[string]interface{}{
 			"error": err.Error(),
@@ -884,7 +1044,7 @@ func (h *Phase3Handlers) checkAllergyRisk(c *gin.Context) {
 	}
 
 	// Evaluate allergy risk
-	results, err := h.allergyEngine.EvaluateAllergyRisk(
+	results, notes, err := h.allergyEngine.EvaluateAllergyRisk(
 		c.Request.Context(),
 		request,
 		datasetVersion,
@@ -896,24 +1056,32 @@ func (h *Phase3Handlers) checkAllergyRisk(c *gin.Context) {
 		return
 	}
 
-	// Count by alert level
+	// Count by alert level; de-labeled results are returned but not counted as alerts
 	criticalCount := 0
 	highCount := 0
+	suppressedCount := 0
 	for _, result := range results {
-		switch result.AlertLevel {
-		case "critical":
+		switch {
+		case result.Suppressed:
+			suppressedCount++
+		case result.AlertLevel == "critical":
 			criticalCount++
-		case "high":
+		case result.AlertLevel == "high":
 			highCount++
 		}
 	}
 
-	sendSuccess(c, results, map[string]interface{}{
-		"total_alerts":   len(results),
-		"critical_count": criticalCount,
-		"high_count":     highCount,
-		"analysis_type":  "allergy_cross_reactivity",
-	})
+	meta := map[string]interface{}{
+		"total_alerts":     len(results) - suppressedCount,
+		"critical_count":   criticalCount,
+		"high_count":       highCount,
+		"suppressed_count": suppressedCount,
+		"analysis_type":    "allergy_cross_reactivity",
+	}
+	if len(notes) > 0 {
+		meta["notes"] = notes
+	}
+	sendSuccess(c, results, meta)
 }
 
 // getCrossReactivity handles GET /api/v1/allergy/cross-reactivity/:allergen
@@ -953,6 +1121,34 @@ func (h *Phase3Handlers) getCrossReactivity(c *gin.Context) {
 	})
 }
 
+// getProductExcipients handles GET /api/v1/allergy/products/:product_code/excipients
+// Returns a drug product and the allergenic excipients it contains
+func (h *Phase3Handlers) getProductExcipients(c *gin.Context) {
+	if h.allergyEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Allergy analysis not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	productCode := c.Param("product_code")
+	product, err := h.allergyEngine.GetProductExcipients(c.Request.Context(), productCode)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to get product excipients", "QUERY_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if product == nil {
+		sendError(c, http.StatusNotFound, "Product not found", "PRODUCT_NOT_FOUND", map[string]interface{}{
+			"product_code": productCode,
+		})
+		return
+	}
+
+	sendSuccess(c, product, map[string]interface{}{
+		"excipient_count": len(product.Excipients),
+	})
+}
+
 // getCommonCrossReactivities handles GET /api/v1/allergy/common-patterns
 // Returns well-known cross-reactivity patterns
 func (h *Phase3Handlers) getCommonCrossReactivities(c *gin.Context) {
@@ -1002,6 +1198,10 @@ func (h *Phase3Handlers) checkDuplicateTherapy(c *gin.Context) {
 		})
 		return
 	}
+	if len(request.DrugCodes)+len(request.MedicationOrders) < 2 {
+		sendError(c, http.StatusBadRequest, "At least two drug codes or medication orders are required", "INVALID_REQUEST", nil)
+		return
+	}
 
 	datasetVersion := c.Query("dataset_version")
 	if datasetVersion == "" {
@@ -1024,7 +1224,11 @@ func (h *Phase3Handlers) checkDuplicateTherapy(c *gin.Context) {
 	// Count by type
 	exactCount := 0
 	classCount := 0
+	allowedCount := 0
 	for _, result := range results {
+		if result.AllowedCombination {
+			allowedCount++
+		}
 		switch result.DuplicateType {
 		case "exact":
 			exactCount++
@@ -1037,6 +1241,8 @@ func (h *Phase3Handlers) checkDuplicateTherapy(c *gin.Context) {
 		"total_duplicates":      len(results),
 		"exact_duplicates":      exactCount,
 		"class_duplicates":      classCount,
+		"allowed_duplicates":    allowedCount,
+		"orders_checked":        len(request.MedicationOrders),
 		"analysis_type":         "duplicate_therapy",
 	})
 }
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"kb-drug-interactions/internal/services"
+)
+
+// PediatricHandlers handles pediatric age- and weight-based safety endpoints
+type PediatricHandlers struct {
+	pediatricEngine *services.PediatricSafetyEngine
+}
+
+// NewPediatricHandlers creates handlers for the pediatric safety engine
+func NewPediatricHandlers(pediatricEngine *services.PediatricSafetyEngine) *PediatricHandlers {
+	return &PediatricHandlers{
+		pediatricEngine: pediatricEngine,
+	}
+}
+
+// sendPediatricInputError sends 400 for an invalid patient age or weight, returning false for other errors
+func sendPediatricInputError(c *gin.Context, err error) bool {
+	var inputErr *services.PediatricInputError
+	if !errors.As(err, &inputErr) {
+		return false
+	}
+	sendError(c, http.StatusBadRequest, "Invalid patient age or weight", "INVALID_PEDIATRIC_INPUT", map[string]interface{}{
+		"field":  inputErr.Field,
+		"reason": inputErr.Reason,
+	})
+	return true
+}
+
+// checkPediatricSafety handles POST /api/v1/pediatric/check
+// Checks a regimen against age- and weight-specific contraindications and
+// interactions, and its orders against weight-based dose maximums
+func (h *PediatricHandlers) checkPediatricSafety(c *gin.Context) {
+	if h.pediatricEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Pediatric safety engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	var request services.PediatricSafetyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.pediatricEngine.CheckRegimen(c.Request.Context(), request)
+	if err != nil {
+		if sendPediatricInputError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to check pediatric safety", "PEDIATRIC_CHECK_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, response, map[string]interface{}{
+		"analysis_type":  "pediatric_safety",
+		"drugs_checked":  len(request.DrugCodes),
+		"orders_checked": len(request.MedicationOrders),
+		"total_findings": len(response.Findings),
+	})
+}
+
+// getDrugPediatricRules handles GET /api/v1/pediatric/drug/:drug_code
+// Returns the age and weight ranges, interactions and dose limits for a drug
+func (h *PediatricHandlers) getDrugPediatricRules(c *gin.Context) {
+	if h.pediatricEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Pediatric safety engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	drugCode := c.Param("drug_code")
+	rules, err := h.pediatricEngine.GetDrugRules(c.Request.Context(), drugCode)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to get pediatric safety rules", "LOOKUP_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, rules, map[string]interface{}{
+		"drug_code": drugCode,
+		"count":     len(rules),
+	})
+}
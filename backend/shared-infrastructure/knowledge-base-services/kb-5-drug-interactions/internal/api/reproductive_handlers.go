@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"kb-drug-interactions/internal/services"
+)
+
+// ReproductiveHandlers handles pregnancy and lactation safety endpoints
+type ReproductiveHandlers struct {
+	reproductiveEngine *services.ReproductiveSafetyEngine
+}
+
+// NewReproductiveHandlers creates handlers for the reproductive safety engine
+func NewReproductiveHandlers(reproductiveEngine *services.ReproductiveSafetyEngine) *ReproductiveHandlers {
+	return &ReproductiveHandlers{
+		reproductiveEngine: reproductiveEngine,
+	}
+}
+
+// sendReproductiveInputError sends 400 for an invalid reproductive status, returning false for other errors
+func sendReproductiveInputError(c *gin.Context, err error) bool {
+	var inputErr *services.ReproductiveInputError
+	if !errors.As(err, &inputErr) {
+		return false
+	}
+	sendError(c, http.StatusBadRequest, "Invalid reproductive status", "INVALID_REPRODUCTIVE_STATUS", map[string]interface{}{
+		"field":  inputErr.Field,
+		"reason": inputErr.Reason,
+	})
+	return true
+}
+
+// checkReproductiveSafety handles POST /api/v1/reproductive/check
+// Checks a regimen against trimester-specific pregnancy risk and breast milk
+// infant exposure, returning safer alternatives
+func (h *ReproductiveHandlers) checkReproductiveSafety(c *gin.Context) {
+	if h.reproductiveEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Reproductive safety engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	var request services.ReproductiveSafetyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	response, err := h.reproductiveEngine.CheckRegimen(c.Request.Context(), request)
+	if err != nil {
+		if sendReproductiveInputError(c, err) {
+			return
+		}
+		sendError(c, http.StatusInternalServerError, "Failed to check reproductive safety", "REPRODUCTIVE_CHECK_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, response, map[string]interface{}{
+		"analysis_type":  "reproductive_safety",
+		"drugs_checked":  len(request.DrugCodes),
+		"total_findings": len(response.Findings),
+	})
+}
+
+// getDrugReproductiveRules handles GET /api/v1/reproductive/drug/:drug_code
+// Returns the pregnancy windows and lactation exposure for a drug
+func (h *ReproductiveHandlers) getDrugReproductiveRules(c *gin.Context) {
+	if h.reproductiveEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Reproductive safety engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	drugCode := c.Param("drug_code")
+	rules, err := h.reproductiveEngine.GetDrugRules(c.Request.Context(), drugCode)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to get reproductive safety rules", "LOOKUP_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, rules, map[string]interface{}{
+		"drug_code": drugCode,
+		"count":     len(rules),
+	})
+}
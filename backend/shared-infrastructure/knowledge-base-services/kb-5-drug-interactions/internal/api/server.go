@@ -34,6 +34,22 @@ type Server struct {
 	duplicateTherapyEngine *services.DuplicateTherapyEngine
 	// Phase 4: Governance and Attribution
 	governanceEngine       *services.GovernancePolicyEngine
+	// Terminology: free-text drug name search
+	drugSearchService      *services.DrugSearchService
+	// Dosing
+	warfarinDosingEngine   *services.WarfarinDosingEngine
+	organDosingEngine      *services.OrganDoseAdjustmentEngine
+	cumulativeDoseEngine   *services.CumulativeDoseEngine
+	// Pregnancy and lactation safety
+	reproductiveEngine     *services.ReproductiveSafetyEngine
+	// Older adult PIM screening (Beers/STOPP)
+	pimEngine              *services.PIMScreeningEngine
+	// Pediatric age- and weight-based safety
+	pediatricEngine        *services.PediatricSafetyEngine
+	// Administration-timing separation scheduler
+	scheduleEngine         *services.AdministrationScheduleEngine
+	// Governance override requests
+	overrideEngine         *services.GovernanceOverrideEngine
 }
 
 // NewServer creates a new HTTP server
@@ -55,6 +71,22 @@ func NewServer(
 	duplicateTherapyEngine *services.DuplicateTherapyEngine,
 	// Phase 4: Governance and Attribution
 	governanceEngine *services.GovernancePolicyEngine,
+	// Terminology: free-text drug name search
+	drugSearchService *services.DrugSearchService,
+	// Dosing: genotype-guided warfarin, renal/hepatic dose adjustment, cumulative daily dose
+	warfarinDosingEngine *services.WarfarinDosingEngine,
+	organDosingEngine *services.OrganDoseAdjustmentEngine,
+	cumulativeDoseEngine *services.CumulativeDoseEngine,
+	// Pregnancy and lactation safety
+	reproductiveEngine *services.ReproductiveSafetyEngine,
+	// Older adult PIM screening (Beers/STOPP)
+	pimEngine *services.PIMScreeningEngine,
+	// Pediatric age- and weight-based safety
+	pediatricEngine *services.PediatricSafetyEngine,
+	// Administration-timing separation scheduler
+	scheduleEngine *services.AdministrationScheduleEngine,
+	// Governance override request workflow
+	overrideEngine *services.GovernanceOverrideEngine,
 ) *Server {
 	// Create Gin router
 	router := gin.New()
@@ -83,6 +115,22 @@ func NewServer(
 		duplicateTherapyEngine: duplicateTherapyEngine,
 		// Phase 4: Governance
 		governanceEngine:       governanceEngine,
+		// Terminology
+		drugSearchService:      drugSearchService,
+		// Dosing
+		warfarinDosingEngine:   warfarinDosingEngine,
+		organDosingEngine:      organDosingEngine,
+		cumulativeDoseEngine:   cumulativeDoseEngine,
+		// Pregnancy and lactation safety
+		reproductiveEngine:     reproductiveEngine,
+		// Older adult PIM screening
+		pimEngine:              pimEngine,
+		// Pediatric safety
+		pediatricEngine:        pediatricEngine,
+		// Administration scheduling
+		scheduleEngine:         scheduleEngine,
+		// Governance override requests
+		overrideEngine:         overrideEngine,
 	}
 
 	// Add custom middleware
@@ -135,6 +183,9 @@ func (s *Server) setupRoutes() {
 		{
 			cyp.GET("/profile/:drug_code", interactionHandlers.cypProfile)
 			cyp.GET("/interactions/:enzyme", interactionHandlers.cypEnzymeInteractions)
+			cyp.POST("/genotype/translate", interactionHandlers.translateGenotypes)
+			cyp.GET("/guidelines", interactionHandlers.pgxGuidelines)
+			cyp.POST("/safety/check", interactionHandlers.pgxSafetyCheck)
 		}
 
 		// Patient-specific endpoints
@@ -152,6 +203,8 @@ func (s *Server) setupRoutes() {
 		// Drug information endpoints
 		drugs := v1.Group("/drugs")
 		{
+			drugs.GET("/search", s.searchDrugs)
+			drugs.POST("/resolve", s.resolveDrugNames)
 			drugs.GET("/:drug_code/interactions", s.getDrugInteractions)
 			drugs.GET("/:drug_code/synonyms", s.getDrugSynonyms)
 			drugs.GET("/:drug_code/alternatives", s.getDrugAlternatives)
@@ -164,6 +217,10 @@ func (s *Server) setupRoutes() {
 			admin.POST("/cache/clear", s.clearCache)
 			admin.GET("/database/health", s.getDatabaseHealth)
 			admin.POST("/rules/reload", s.reloadRules)
+			admin.POST("/vocabulary/reload", s.reloadVocabulary)
+			admin.POST("/pgx/translation/reload", s.reloadPGXTranslation)
+			admin.POST("/pgx/guidelines/import", s.importPGXGuidelines)
+			admin.POST("/excipients/import", s.importExcipientProducts)
 			admin.GET("/analytics", s.getAnalytics)
 		}
 
@@ -188,6 +245,7 @@ func (s *Server) setupRoutes() {
 			allergy.POST("/check", phase3Handlers.checkAllergyRisk)
 			allergy.GET("/cross-reactivity/:allergen", phase3Handlers.getCrossReactivity)
 			allergy.GET("/common-patterns", phase3Handlers.getCommonCrossReactivities)
+			allergy.GET("/products/:product_code/excipients", phase3Handlers.getProductExcipients)
 		}
 
 		// Duplicate therapy detection endpoints (Phase 3)
@@ -198,17 +256,79 @@ func (s *Server) setupRoutes() {
 			duplicates.GET("/common-classes", phase3Handlers.getCommonDuplicateClasses)
 		}
 
+		// Patient-specific dosing endpoints
+		dosingHandlers := NewDosingHandlers(s.warfarinDosingEngine, s.organDosingEngine, s.cumulativeDoseEngine)
+		dosing := v1.Group("/dosing")
+		{
+			dosing.POST("/warfarin", dosingHandlers.estimateWarfarinDose)
+			dosing.POST("/organ-function", dosingHandlers.assessOrganFunction)
+			dosing.POST("/organ-adjustment", dosingHandlers.checkOrganDoseAdjustments)
+			dosing.GET("/organ-adjustment/:drug_code", dosingHandlers.getDrugDoseAdjustments)
+			dosing.POST("/cumulative", dosingHandlers.checkCumulativeDoses)
+			dosing.GET("/cumulative/:ingredient_code", dosingHandlers.getIngredientDoseLimits)
+		}
+
+		// Pregnancy and lactation safety endpoints
+		reproductiveHandlers := NewReproductiveHandlers(s.reproductiveEngine)
+		reproductive := v1.Group("/reproductive")
+		{
+			reproductive.POST("/check", reproductiveHandlers.checkReproductiveSafety)
+			reproductive.GET("/drug/:drug_code", reproductiveHandlers.getDrugReproductiveRules)
+		}
+
+		// Older adult potentially inappropriate medication screening endpoints
+		geriatricHandlers := NewGeriatricHandlers(s.pimEngine)
+		geriatric := v1.Group("/geriatric")
+		{
+			geriatric.POST("/pim-screen", geriatricHandlers.screenPIM)
+			geriatric.GET("/criteria", geriatricHandlers.getCriteriaSets)
+			geriatric.GET("/criteria/:set_code", geriatricHandlers.getCriteria)
+		}
+
+		// Pediatric age- and weight-based safety endpoints
+		pediatricHandlers := NewPediatricHandlers(s.pediatricEngine)
+		pediatric := v1.Group("/pediatric")
+		{
+			pediatric.POST("/check", pediatricHandlers.checkPediatricSafety)
+			pediatric.GET("/drug/:drug_code", pediatricHandlers.getDrugPediatricRules)
+		}
+
+		// Administration-timing separation endpoints
+		administrationHandlers := NewAdministrationHandlers(s.scheduleEngine)
+		administration := v1.Group("/administration")
+		{
+			administration.POST("/schedule", administrationHandlers.buildAdministrationSchedule)
+			administration.GET("/separations/:drug_code", administrationHandlers.getDrugSeparationRules)
+		}
+
 		// Phase 4: Governance and Attribution endpoints
 		governanceHandlers := NewGovernanceHandlers(
 			s.governanceEngine,
 			s.interactionService,
 			s.integrationService,
+			s.pgxEngine,
+			s.reproductiveEngine,
+			s.pimEngine,
+			s.pediatricEngine,
+			s.overrideEngine,
 		)
 
 		// Governance endpoints
 		governance := v1.Group("/governance")
 		{
 			governance.GET("/policy", governanceHandlers.getGovernancePolicy)
+			governance.GET("/policies", governanceHandlers.listGovernancePolicies)
+			governance.POST("/policies", governanceHandlers.createGovernancePolicyDraft)
+			governance.GET("/policies/:policy_id", governanceHandlers.getGovernancePolicyVersion)
+			governance.PUT("/policies/:policy_id", governanceHandlers.updateGovernancePolicyDraft)
+			governance.DELETE("/policies/:policy_id", governanceHandlers.deleteGovernancePolicyDraft)
+			governance.POST("/policies/:policy_id/approve", governanceHandlers.approveGovernancePolicy)
+			governance.POST("/policies/:policy_id/retire", governanceHandlers.retireGovernancePolicy)
+			governance.GET("/overrides", governanceHandlers.listOverrideRequests)
+			governance.POST("/overrides", governanceHandlers.submitOverrideRequest)
+			governance.GET("/overrides/:override_id", governanceHandlers.getOverrideRequest)
+			governance.POST("/overrides/:override_id/approve", governanceHandlers.approveOverrideRequest)
+			governance.POST("/overrides/:override_id/deny", governanceHandlers.denyOverrideRequest)
 			governance.POST("/translate", governanceHandlers.translateSeverity)
 			governance.GET("/actions", governanceHandlers.getGovernanceActions)
 			governance.GET("/attribution/template", governanceHandlers.getAttributionTemplate)
@@ -292,6 +412,82 @@ func (s *Server) getDrugSynonyms(c *gin.Context) {
 	}, nil)
 }
 
+// searchDrugs handles GET /api/v1/drugs/search?q=warfarn&limit=10&min_score=0.8
+// Fuzzy/autocomplete search over synonyms, brand names and OHDSI concept names
+// limit defaults to 10 and is capped at 50
+func (s *Server) searchDrugs(c *gin.Context) {
+	if s.drugSearchService == nil {
+		sendError(c, http.StatusServiceUnavailable, "Drug search service not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	query := c.Query("q")
+	if query == "" {
+		sendError(c, http.StatusBadRequest, "Query parameter 'q' is required", "MISSING_QUERY", nil)
+		return
+	}
+
+	limit := parseIntQuery(c, "limit", 10)
+	minScore := parseFloatQuery(c, "min_score", 0)
+
+	results, err := s.drugSearchService.SearchDrugs(c.Request.Context(), query, limit, minScore)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Drug search failed", "SEARCH_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, results, map[string]interface{}{
+		"query":                      query,
+		"total_results":              len(results),
+		"synonym_resolution_enabled": s.config.EnableSynonymResolution,
+	})
+}
+
+// resolveDrugNames handles POST /api/v1/drugs/resolve
+// Codes a free-text medication list (e.g. admission home medications)
+func (s *Server) resolveDrugNames(c *gin.Context) {
+	if s.drugSearchService == nil {
+		sendError(c, http.StatusServiceUnavailable, "Drug search service not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	var request struct {
+		DrugNames         []string `json:"drug_names" binding:"required,min=1"`
+		CandidatesPerName int      `json:"candidates_per_name"`
+		MinScore          float64  `json:"min_score"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid request format", "INVALID_REQUEST", map[string]interface{}{
+			"validation_error": err.Error(),
+		})
+		return
+	}
+
+	resolutions, err := s.drugSearchService.ResolveDrugNames(
+		c.Request.Context(), request.DrugNames, request.CandidatesPerName, request.MinScore)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Drug name resolution failed", "RESOLUTION_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	resolved := 0
+	for _, r := range resolutions {
+		if r.Resolved {
+			resolved++
+		}
+	}
+
+	sendSuccess(c, resolutions, map[string]interface{}{
+		"total_names":    len(resolutions),
+		"resolved_count": resolved,
+		"unresolved":     len(resolutions) - resolved,
+	})
+}
+
 func (s *Server) getDrugAlternatives(c *gin.Context) {
 	drugCode := c.Param("drug_code")
 	indication := c.Query("indication")
@@ -390,6 +586,137 @@ func (s *Server) reloadRules(c *gin.Context) {
 	}, nil)
 }
 
+// reloadVocabulary rebuilds the in-memory ATC class index after the OHDSI
+// vocabulary tables have been refreshed
+func (s *Server) reloadVocabulary(c *gin.Context) {
+	if s.classEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Class interaction engine not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	stats, err := s.classEngine.ReloadATCIndex(c.Request.Context())
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to reload vocabulary", "VOCABULARY_RELOAD_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Duplicate therapy caches hold class mappings built from the previous index
+	if s.duplicateTherapyEngine != nil {
+		s.duplicateTherapyEngine.ClearCache()
+	}
+
+	sendSuccess(c, map[string]interface{}{
+		"status":    "reloaded",
+		"atc_index": stats,
+		"timestamp": time.Now().UTC(),
+	}, nil)
+}
+
+// reloadPGXTranslation reloads the current genotype translation table version
+func (s *Server) reloadPGXTranslation(c *gin.Context) {
+	if s.pgxEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "PGx analysis not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	stats, err := s.pgxEngine.ReloadTranslationTables(c.Request.Context())
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to reload PGx translation tables", "PGX_TRANSLATION_RELOAD_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sendSuccess(c, map[string]interface{}{
+		"status":            "reloaded",
+		"translation_table": stats,
+		"timestamp":         time.Now().UTC(),
+	}, nil)
+}
+
+// importPGXGuidelines imports a CPIC-style guideline file (CSV or TSV) from the
+// request body. Query params: format (csv, tsv; detected if omitted) and
+// source_version (used for rows without a source_version column).
+func (s *Server) importPGXGuidelines(c *gin.Context) {
+	if s.pgxEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "PGx analysis not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	guidelines, issues, err := services.ParseGuidelineFile(c.Request.Body, c.Query("format"), c.Query("source_version"))
+	if err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid guideline file", "INVALID_GUIDELINE_FILE", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	imported, err := s.pgxEngine.ImportGuidelines(c.Request.Context(), guidelines)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to import PGx guidelines", "GUIDELINE_IMPORT_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	genes := []string{}
+	seen := make(map[string]bool)
+	for _, g := range guidelines {
+		if !seen[g.Gene] {
+			seen[g.Gene] = true
+			genes = append(genes, g.Gene)
+		}
+	}
+
+	sendSuccess(c, services.GuidelineImportResult{
+		RowsRead:     len(guidelines) + len(issues),
+		RowsImported: imported,
+		Genes:        genes,
+		Issues:       issues,
+	}, map[string]interface{}{
+		"timestamp": time.Now().UTC(),
+	})
+}
+
+// importExcipientProducts imports a product ingredient list (CSV or TSV) from the
+// request body and links products to their allergenic excipients. Query params:
+// format (csv, tsv; detected if omitted) and source (recorded on each product).
+func (s *Server) importExcipientProducts(c *gin.Context) {
+	if s.allergyEngine == nil {
+		sendError(c, http.StatusServiceUnavailable, "Allergy analysis not available", "ENGINE_UNAVAILABLE", nil)
+		return
+	}
+
+	products, issues, err := services.ParseExcipientFile(c.Request.Body, c.Query("format"))
+	if err != nil {
+		sendError(c, http.StatusBadRequest, "Invalid product ingredient file", "INVALID_PRODUCT_FILE", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	source := c.Query("source")
+	if source == "" {
+		source = "import"
+	}
+
+	result, err := s.allergyEngine.ImportExcipientProducts(c.Request.Context(), products, source)
+	if err != nil {
+		sendError(c, http.StatusInternalServerError, "Failed to import products", "PRODUCT_IMPORT_FAILED", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	result.RowsRead = len(products) + len(issues)
+	result.Issues = issues
+
+	sendSuccess(c, result, map[string]interface{}{
+		"timestamp": time.Now().UTC(),
+	})
+}
+
 func (s *Server) getAnalytics(c *gin.Context) {
 	days := parseIntQuery(c, "days", 30)
 	
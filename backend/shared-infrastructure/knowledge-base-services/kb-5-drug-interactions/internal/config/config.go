@@ -59,6 +59,7 @@ type Config struct {
 	SynonymMatchThreshold     float64
 	UpdateDrugDatabase        bool
 	DrugDatabaseSyncInterval  time.Duration
+	DiseaseVocabularyPath     string // SNOMED hierarchy + ICD-10/SNOMED crosswalk (TSV)
 	
 	// Performance tuning
 	BatchSize                 int
@@ -132,6 +133,7 @@ func Load() (*Config, error) {
 		SynonymMatchThreshold:    getEnvAsFloat("SYNONYM_MATCH_THRESHOLD", 0.8),
 		UpdateDrugDatabase:       getEnvAsBool("UPDATE_DRUG_DATABASE", false),
 		DrugDatabaseSyncInterval: getEnvAsDuration("DRUG_DATABASE_SYNC_INTERVAL", "24h"),
+		DiseaseVocabularyPath:    getEnv("DISEASE_VOCABULARY_PATH", "config/disease_vocabulary.tsv"),
 
 		// Performance
 		BatchSize:               getEnvAsInt("BATCH_SIZE", 100),
@@ -290,6 +290,26 @@ func (r *SynonymRepository) FindSynonymsByDrug(drugCode string) ([]models.DrugSy
 	return synonyms, err
 }
 
+// SearchByName returns synonym rows whose name is trigram-similar to, or starts with,
+// the query. Candidates are ranked by pg_trgm similarity; callers apply their own cutoff.
+func (r *SynonymRepository) SearchByName(query string, limit int) ([]models.DrugNameMatch, error) {
+	var matches []models.DrugNameMatch
+	err := r.db.Raw(`
+		SELECT primary_drug_code AS canonical_code,
+		       synonym_name AS matched_name,
+		       synonym_code AS matched_code,
+		       synonym_type,
+		       mapping_confidence,
+		       similarity(LOWER(synonym_name), LOWER(?)) AS similarity
+		FROM drug_synonyms
+		WHERE active = true
+		  AND (LOWER(synonym_name) % LOWER(?) OR LOWER(synonym_name) LIKE LOWER(?) || '%')
+		ORDER BY similarity DESC
+		LIMIT ?`, query, query, query, limit).
+		Scan(&matches).Error
+	return matches, err
+}
+
 type PatientAlertRepository struct {
 	db *gorm.DB
 }
@@ -44,6 +44,7 @@ type Collector struct {
 	SynonymResolutionsTotal  *prometheus.CounterVec
 	SynonymResolutionTime    *prometheus.HistogramVec
 	SynonymMappingAccuracy   *prometheus.GaugeVec
+	ATCClassResolutionsTotal *prometheus.CounterVec
 	
 	// Rule evaluation metrics
 	RuleEvaluationsTotal     *prometheus.CounterVec
@@ -278,6 +279,15 @@ func NewCollector() *Collector {
 			[]string{"synonym_type"},
 		),
 		
+		ATCClassResolutionsTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "kb5",
+				Name:      "atc_class_resolutions_total",
+				Help:      "Total number of drug to ATC class resolutions",
+			},
+			[]string{"source", "status"},
+		),
+		
 		// Rule evaluation metrics
 		RuleEvaluationsTotal: promauto.NewCounterVec(
 			prometheus.CounterOpts{
@@ -665,6 +675,10 @@ func (c *Collector) RecordClassInteraction(drugClass1, drugClass2, severity stri
 	c.RuleMatchesTotal.WithLabelValues("class_interaction", severity).Inc()
 }
 
+func (c *Collector) RecordATCClassResolution(source, status string) {
+	c.ATCClassResolutionsTotal.WithLabelValues(source, status).Inc()
+}
+
 // Matrix Load Metrics
 
 func (c *Collector) RecordMatrixLoad(loadType string, duration time.Duration) {
@@ -683,6 +697,54 @@ func (c *Collector) RecordPGXInteraction(markerType, severity string) {
 	c.RuleMatchesTotal.WithLabelValues("pgx_"+markerType, severity).Inc()
 }
 
+func (c *Collector) RecordPGXTranslation(gene, phenotype string) {
+	c.RuleMatchesTotal.WithLabelValues("pgx_translation_"+gene, phenotype).Inc()
+}
+
+func (c *Collector) RecordPGXPhenoconversion(gene, effectivePhenotype string) {
+	c.RuleMatchesTotal.WithLabelValues("pgx_phenoconversion_"+gene, effectivePhenotype).Inc()
+}
+
+// RecordPGXSafetyMatch records a high-risk PGx safety rule match
+func (c *Collector) RecordPGXSafetyMatch(checkType, governanceAction string) {
+	c.RuleMatchesTotal.WithLabelValues("pgx_safety_"+checkType, governanceAction).Inc()
+}
+
+// RecordReproductiveSafetyMatch records a pregnancy or lactation safety rule match
+func (c *Collector) RecordReproductiveSafetyMatch(context, governanceAction string) {
+	c.RuleMatchesTotal.WithLabelValues("reproductive_safety_"+context, governanceAction).Inc()
+}
+
+// RecordPIMMatch records a potentially inappropriate medication criterion match
+func (c *Collector) RecordPIMMatch(criteriaSet, criterionType string) {
+	c.RuleMatchesTotal.WithLabelValues("pim_"+criteriaSet, criterionType).Inc()
+}
+
+// RecordPediatricSafetyMatch records a pediatric age or weight rule match
+func (c *Collector) RecordPediatricSafetyMatch(ruleType, governanceAction string) {
+	c.RuleMatchesTotal.WithLabelValues("pediatric_"+ruleType, governanceAction).Inc()
+}
+
+// RecordAdministrationSchedule records a proposed administration schedule by outcome
+func (c *Collector) RecordAdministrationSchedule(outcome string) {
+	c.RuleMatchesTotal.WithLabelValues("administration_schedule", outcome).Inc()
+}
+
+// RecordGovernancePolicyChange records a governance policy workflow action
+func (c *Collector) RecordGovernancePolicyChange(action string) {
+	c.RuleMatchesTotal.WithLabelValues("governance_policy", action).Inc()
+}
+
+// RecordGovernanceOverride records a governance override request event by outcome
+func (c *Collector) RecordGovernanceOverride(outcome string) {
+	c.RuleMatchesTotal.WithLabelValues("governance_override", outcome).Inc()
+}
+
+// RecordDoseEstimate records a dose estimate by algorithm
+func (c *Collector) RecordDoseEstimate(algorithm string) {
+	c.RuleMatchesTotal.WithLabelValues("dose_estimate", algorithm).Inc()
+}
+
 // RecordGRPCRequest records metrics for a gRPC request
 func (c *Collector) RecordGRPCRequest(method, status string, duration time.Duration) {
 	c.RequestDuration.WithLabelValues("grpc", method).Observe(duration.Seconds())
@@ -76,12 +76,26 @@ type ConfigProvider interface {
 type PatientContext struct {
 	PatientID         string            `json:"patient_id"`
 	Age               int               `json:"age,omitempty"`
-	Weight            *decimal.Decimal  `json:"weight,omitempty"`
-	RenalFunction     *decimal.Decimal  `json:"renal_function,omitempty"`
+	AgeMonths         *int              `json:"age_months,omitempty"` // Infants and young children; overrides age
+	AgeDays           *int              `json:"age_days,omitempty"`   // Neonates; overrides age_months and age
+	Weight            *decimal.Decimal  `json:"weight,omitempty"`     // kg
+	RenalFunction     *decimal.Decimal  `json:"renal_function,omitempty"`        // eGFR, mL/min/1.73m²
+	CreatinineClearance *decimal.Decimal `json:"creatinine_clearance,omitempty"` // Cockcroft-Gault CrCl, mL/min
+	DialysisModality  string            `json:"dialysis_modality,omitempty"`     // "HD", "PD", "CRRT"
 	HepaticFunction   string            `json:"hepatic_function,omitempty"`
 	PGXMarkers        map[string]string `json:"pgx_markers,omitempty"`
+	PGXGenotypes      map[string]PGXGenotype `json:"pgx_genotypes,omitempty"` // gene -> lab genotype, translated to PGXMarkers
+	PGXRiskAlleles    map[string]string `json:"pgx_risk_alleles,omitempty"` // {"HLA-B*57:01": "positive"}
 	Allergies         []string          `json:"allergies,omitempty"`
 	Comorbidities     []string          `json:"comorbidities,omitempty"`
+	Labs              *PatientLabs      `json:"labs,omitempty"` // raw labs; derive renal/hepatic function left empty
+	Reproductive      *ReproductiveStatus `json:"reproductive,omitempty"`
+}
+
+// PGXGenotype is a lab-reported genotype for one gene
+type PGXGenotype struct {
+	Diplotype  string `json:"diplotype"`             // Star-allele diplotype, e.g. "*1/*4", "*1x2/*41", "*2xN/*4"
+	CopyNumber *int   `json:"copy_number,omitempty"` // Total gene copies from a CNV assay (CYP2D6)
 }
 
 // RequestPriority for interaction check prioritization
@@ -304,11 +318,118 @@ type EnhancedInteractionCheckRequest struct {
 // Patient context for personalized checking
 type PatientContextData struct {
 	PGX           map[string]string `json:"pgx,omitempty"`           // {"CYP2D6": "ultrarapid"}
+	PGXRiskAlleles map[string]string `json:"pgx_risk_alleles,omitempty"` // {"HLA-B*57:01": "positive"}
 	HepaticStage  string            `json:"hepatic_stage,omitempty"` // "ChildPugh_A", "ChildPugh_B", "ChildPugh_C"
 	RenalStage    string            `json:"renal_stage,omitempty"`   // "CKD_1", "CKD_2", etc.
 	AgeBand       string            `json:"age_band,omitempty"`      // "pediatric", "adult", "older_adult"
+	AgeMonths     *int              `json:"age_months,omitempty"`    // Pediatric age; overrides labs.age_years
+	AgeDays       *int              `json:"age_days,omitempty"`      // Neonatal age; overrides age_months
+	WeightKg      *float64          `json:"weight_kg,omitempty"`     // Overrides labs.weight_kg
 	Comorbidities []string          `json:"comorbidities,omitempty"` // SNOMED codes
 	Allergies     map[string]string `json:"allergies,omitempty"`     // drug allergies
+	Labs          *PatientLabs      `json:"labs,omitempty"`          // raw labs; derive stages left empty
+	Reproductive  *ReproductiveStatus `json:"reproductive,omitempty"`
+}
+
+// PatientLabs are raw labs and vitals from which renal and hepatic function are derived
+type PatientLabs struct {
+	SerumCreatinine *float64 `json:"serum_creatinine_mg_dl,omitempty"`
+	CystatinC       *float64 `json:"cystatin_c_mg_l,omitempty"`
+	AgeYears        *int     `json:"age_years,omitempty"`
+	Sex             string   `json:"sex,omitempty"`                  // "male", "female"
+	WeightKg        *float64 `json:"weight_kg,omitempty"`            // actual body weight (Cockcroft-Gault)
+	OnDialysis      bool     `json:"on_dialysis,omitempty"`
+	TotalBilirubin  *float64 `json:"total_bilirubin_mg_dl,omitempty"`
+	Albumin         *float64 `json:"albumin_g_dl,omitempty"`
+	INR             *float64 `json:"inr,omitempty"`
+	Ascites         string   `json:"ascites,omitempty"`              // "none", "mild", "moderate_severe"
+	Encephalopathy  *int     `json:"encephalopathy_grade,omitempty"` // West Haven grade 0-4
+}
+
+// Pregnancy statuses (ReproductiveStatus.PregnancyStatus)
+const (
+	PregnancyStatusPregnant    = "pregnant"
+	PregnancyStatusNotPregnant = "not_pregnant"
+	PregnancyStatusPossible    = "possible" // Of reproductive potential, pregnancy not excluded
+	PregnancyStatusUnknown     = "unknown"
+)
+
+// ReproductiveStatus is the pregnancy and lactation status used for reproductive safety checks
+type ReproductiveStatus struct {
+	PregnancyStatus  string `json:"pregnancy_status,omitempty"`
+	GestationalWeeks *int   `json:"gestational_weeks,omitempty"` // Completed weeks of gestation
+	Lactating        bool   `json:"lactating,omitempty"`
+	InfantAgeWeeks   *int   `json:"infant_age_weeks,omitempty"` // Age of the breastfed infant
+}
+
+// IsPregnant reports whether pregnancy is confirmed
+func (rs *ReproductiveStatus) IsPregnant() bool {
+	return rs != nil && rs.PregnancyStatus == PregnancyStatusPregnant
+}
+
+// IsLactating reports whether the patient is breastfeeding
+func (rs *ReproductiveStatus) IsLactating() bool {
+	return rs != nil && rs.Lactating
+}
+
+// MedicationOrder is an ordered dose of a drug
+type MedicationOrder struct {
+	OrderID       string  `json:"order_id,omitempty"`
+	DrugCode      string  `json:"drug_code"`
+	DrugName      string  `json:"drug_name,omitempty"`
+	Dose          float64 `json:"dose"`
+	DoseUnit      string  `json:"dose_unit"`                // "mg", "g", "mcg"
+	Frequency     string  `json:"frequency,omitempty"`      // "q12h", "BID", "daily"
+	IntervalHours float64 `json:"interval_hours,omitempty"` // Overrides frequency when set
+	Route         string  `json:"route,omitempty"`
+	PRN           bool    `json:"prn,omitempty"`            // As-needed; the frequency is the minimum interval
+	Status        string  `json:"status,omitempty"`         // OrderStatus*; empty is active
+	Indication    string  `json:"indication,omitempty"`     // Disease code the drug is ordered for
+	StartTime     *time.Time `json:"start_time,omitempty"`
+	StopTime      *time.Time `json:"stop_time,omitempty"` // Scheduled end, e.g. of the outgoing order in a transition
+}
+
+// Medication order statuses (MedicationOrder.Status)
+const (
+	OrderStatusActive             = "active"
+	OrderStatusPending            = "pending"
+	OrderStatusPendingDiscontinue = "pending_discontinue" // Being replaced; still given until its stop time
+	OrderStatusDiscontinued       = "discontinued"
+	OrderStatusCompleted          = "completed"
+	OrderStatusCancelled          = "cancelled"
+)
+
+// IsActive reports whether the order is, or will be, administered
+func (o MedicationOrder) IsActive() bool {
+	switch o.Status {
+	case OrderStatusDiscontinued, OrderStatusCompleted, OrderStatusCancelled:
+		return false
+	}
+	return true
+}
+
+// OrganFunctionAssessment records the renal and hepatic function derived from
+// patient labs, with the formulas and inputs used, for audit
+type OrganFunctionAssessment struct {
+	EGFR               *LabDerivation `json:"egfr,omitempty"`
+	CrCl               *LabDerivation `json:"crcl,omitempty"`
+	ChildPugh          *LabDerivation `json:"child_pugh,omitempty"`
+	RenalStage         string         `json:"renal_stage,omitempty"`
+	RenalStageSource   string         `json:"renal_stage_source,omitempty"`   // "provided", "derived"
+	HepaticStage       string         `json:"hepatic_stage,omitempty"`
+	HepaticStageSource string         `json:"hepatic_stage_source,omitempty"` // "provided", "derived"
+	AgeBand            string         `json:"age_band,omitempty"`
+	Notes              []string       `json:"notes,omitempty"`
+}
+
+// LabDerivation is one value calculated from labs
+type LabDerivation struct {
+	Value      float64                `json:"value"`
+	Unit       string                 `json:"unit"`
+	Formula    string                 `json:"formula"`
+	Inputs     map[string]interface{} `json:"inputs"`
+	Components map[string]int         `json:"components,omitempty"` // Child-Pugh points per criterion
+	Stage      string                 `json:"stage,omitempty"`      // "CKD_3b", "ChildPugh_B"
 }
 
 // Enhanced interaction result with full clinical data
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -73,11 +74,29 @@ func (ga GovernanceAction) AllowsClinicalOverride() bool {
 // Configurable mapping layer for institutional policy enforcement
 // ============================================================================
 
-// SeverityGovernanceMapping defines the mapping from clinical severity to governance action
+// Policy version status
+const (
+	PolicyStatusDraft    = "draft"    // Editable, not applied
+	PolicyStatusApproved = "approved" // Immutable, applied from its effective date
+)
+
+// Policy version lifecycle states, derived from status and dates
+const (
+	PolicyStateDraft     = "draft"
+	PolicyStateScheduled = "scheduled" // Approved, effective date not reached
+	PolicyStateInEffect  = "in_effect"
+	PolicyStateExpired   = "expired"
+	PolicyStateRetired   = "retired" // Approved but deactivated
+)
+
+// SeverityGovernanceMapping defines the mapping from clinical severity to governance action.
+// Each row is one version of a named policy; approved versions are never modified.
 type SeverityGovernanceMapping struct {
 	ID                 uuid.UUID         `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	PolicyName         string            `gorm:"size:100;not null;uniqueIndex" json:"policy_name"`
+	PolicyName         string            `gorm:"size:100;not null;index" json:"policy_name"`
 	InstitutionID      string            `gorm:"size:100;index" json:"institution_id,omitempty"`
+	Version            int               `gorm:"not null;default:1" json:"version"`
+	Status             string            `gorm:"size:20;not null;default:'draft'" json:"status"`
 
 	// Severity → Governance mappings
 	ContraindicatedAction GovernanceAction `gorm:"not null;default:'hard_block'" json:"contraindicated_action"`
@@ -99,6 +118,11 @@ type SeverityGovernanceMapping struct {
 	ApprovedBy    string     `gorm:"size:100" json:"approved_by,omitempty"`
 	ApprovedAt    *time.Time `json:"approved_at,omitempty"`
 
+	// Version history
+	CreatedBy     string     `gorm:"size:100;not null" json:"created_by"`
+	ChangeSummary string     `gorm:"type:text" json:"change_summary,omitempty"`
+	SupersedesID  *uuid.UUID `gorm:"type:uuid" json:"supersedes_id,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -107,6 +131,44 @@ func (SeverityGovernanceMapping) TableName() string {
 	return "ddi_severity_governance_mappings"
 }
 
+// InEffectAt returns true if this version is approved and applies at t
+func (sgm *SeverityGovernanceMapping) InEffectAt(t time.Time) bool {
+	if sgm.Status != PolicyStatusApproved || !sgm.Active || sgm.EffectiveDate.After(t) {
+		return false
+	}
+	return sgm.ExpiryDate == nil || t.Before(*sgm.ExpiryDate)
+}
+
+// LifecycleState reports where this version is in its lifecycle at now
+func (sgm *SeverityGovernanceMapping) LifecycleState(now time.Time) string {
+	switch {
+	case sgm.Status != PolicyStatusApproved:
+		return PolicyStateDraft
+	case !sgm.Active:
+		return PolicyStateRetired
+	case sgm.ExpiryDate != nil && !now.Before(*sgm.ExpiryDate):
+		return PolicyStateExpired
+	case sgm.EffectiveDate.After(now):
+		return PolicyStateScheduled
+	default:
+		return PolicyStateInEffect
+	}
+}
+
+// VersionID identifies the applied policy version in governed responses.
+// The built-in default policy has no row and is reported by name.
+func (sgm *SeverityGovernanceMapping) VersionID() string {
+	if sgm.ID == uuid.Nil {
+		return "builtin:" + sgm.PolicyName
+	}
+	return sgm.ID.String()
+}
+
+// VersionLabel returns the version number as reported in PolicyVersion
+func (sgm *SeverityGovernanceMapping) VersionLabel() string {
+	return strconv.Itoa(sgm.Version)
+}
+
 // MapSeverityToAction translates clinical severity to governance action
 func (sgm *SeverityGovernanceMapping) MapSeverityToAction(severity DDISeverity) GovernanceAction {
 	switch severity {
@@ -123,6 +185,89 @@ func (sgm *SeverityGovernanceMapping) MapSeverityToAction(severity DDISeverity)
 	}
 }
 
+// ============================================================================
+// GOVERNANCE OVERRIDE REQUESTS
+// Approval to proceed past an escalation or governance block for one patient
+// ============================================================================
+
+// Override request status
+const (
+	OverrideStatusPending  = "pending"
+	OverrideStatusApproved = "approved"
+	OverrideStatusDenied   = "denied"
+	OverrideStatusExpired  = "expired"
+)
+
+// GovernanceOverrideRequest asks for approval to proceed with a drug pair for a patient
+type GovernanceOverrideRequest struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AuditID       *uuid.UUID `gorm:"type:uuid" json:"audit_id,omitempty"`
+	TransactionID string     `gorm:"size:100;not null" json:"transaction_id"`
+
+	// Requester
+	RequesterID   string `gorm:"size:100;not null" json:"requester_id"`
+	RequesterRole string `gorm:"size:50;not null" json:"requester_role"`
+	Department    string `gorm:"size:100" json:"department,omitempty"`
+
+	// Patient and alert
+	PatientID             string           `gorm:"size:100" json:"patient_id"`
+	EncounterID           string           `gorm:"size:100" json:"encounter_id,omitempty"`
+	InstitutionID         string           `gorm:"size:100;not null;default:''" json:"institution_id,omitempty"`
+	InteractionID         string           `gorm:"size:100" json:"interaction_id,omitempty"`
+	Drug1Code             string           `gorm:"column:drug1_code;size:100" json:"drug1_code"`
+	Drug2Code             string           `gorm:"column:drug2_code;size:100" json:"drug2_code,omitempty"`
+	GovernanceAction      GovernanceAction `gorm:"type:governance_action;not null" json:"governance_action"`
+	RequiredOverrideLevel string           `gorm:"size:30" json:"required_override_level"`
+	PolicyVersionID       string           `gorm:"size:100" json:"policy_version_id,omitempty"`
+
+	// Justification
+	OverrideJustification     string `gorm:"type:text;not null" json:"override_justification"`
+	ClinicalRationale         string `gorm:"type:text" json:"clinical_rationale,omitempty"`
+	AlternativeMonitoringPlan string `gorm:"type:text" json:"alternative_monitoring_plan,omitempty"`
+
+	// Decision
+	Status             string     `gorm:"size:20;default:'pending'" json:"status"`
+	DecisionDueAt      *time.Time `json:"decision_due_at,omitempty"`
+	ApproverID         string     `gorm:"size:100" json:"approver_id,omitempty"`
+	ApproverRole       string     `gorm:"size:50" json:"approver_role,omitempty"`
+	ApprovalDecisionAt *time.Time `json:"approval_decision_at,omitempty"`
+	ApprovalNotes      string     `gorm:"type:text" json:"approval_notes,omitempty"`
+
+	// Validity of an approval
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (GovernanceOverrideRequest) TableName() string {
+	return "ddi_governance_override_requests"
+}
+
+// StatusAt returns the request status at a time, treating pending requests
+// past their decision deadline and approvals past their validity as expired
+func (r *GovernanceOverrideRequest) StatusAt(t time.Time) string {
+	switch r.Status {
+	case OverrideStatusPending:
+		if r.DecisionDueAt != nil && !t.Before(*r.DecisionDueAt) {
+			return OverrideStatusExpired
+		}
+	case OverrideStatusApproved:
+		if r.ValidUntil != nil && !t.Before(*r.ValidUntil) {
+			return OverrideStatusExpired
+		}
+	}
+	return r.Status
+}
+
+// ValidAt returns true if the request is an approval in force at a time
+func (r *GovernanceOverrideRequest) ValidAt(t time.Time) bool {
+	return r.Status == OverrideStatusApproved &&
+		r.ValidFrom != nil && !t.Before(*r.ValidFrom) &&
+		r.ValidUntil != nil && t.Before(*r.ValidUntil)
+}
+
 // ============================================================================
 // ATTRIBUTION + EVIDENCE LABELS
 // Provides provenance, traceability, and medico-legal documentation
@@ -196,6 +341,7 @@ const (
 	ProgramFlagPediatric      ProgramFlag = "PEDIATRIC"
 	ProgramFlagGeriatric      ProgramFlag = "GERIATRIC"
 	ProgramFlagPregnancy      ProgramFlag = "PREGNANCY"
+	ProgramFlagLactation      ProgramFlag = "LACTATION"
 	ProgramFlagRenalDosing    ProgramFlag = "RENAL_DOSING"
 	ProgramFlagHepaticDosing  ProgramFlag = "HEPATIC_DOSING"
 	ProgramFlagPGx            ProgramFlag = "PHARMACOGENOMICS"
@@ -357,8 +503,12 @@ type GovernedInteractionCheckResponse struct {
 	// Policy information
 	PolicyApplied      string             `json:"policy_applied"`
 	PolicyVersion      string             `json:"policy_version"`
+	PolicyVersionID    string             `json:"policy_version_id"`
 	InstitutionID      string             `json:"institution_id,omitempty"`
 
+	// Renal/hepatic function derived from patient labs
+	OrganFunction      *OrganFunctionAssessment `json:"organ_function,omitempty"`
+
 	// Attribution header
 	Attribution        ResponseAttribution `json:"attribution"`
 
@@ -382,6 +532,9 @@ type GovernedInteractionSummary struct {
 	// Highest governance action
 	HighestGovernanceAction  GovernanceAction `json:"highest_governance_action"`
 
+	// Results lifted by an approved override request
+	OverriddenCount          int `json:"overridden_count"`
+
 	// Blocking status
 	IsOrderBlocked           bool   `json:"is_order_blocked"`
 	BlockingReason           string `json:"blocking_reason,omitempty"`
@@ -414,6 +567,7 @@ type ResponseAttribution struct {
 
 	// Governance policy
 	GovernancePolicy   string    `json:"governance_policy"`
+	PolicyVersionID    string    `json:"policy_version_id"`
 	PolicyEffectiveDate time.Time `json:"policy_effective_date"`
 
 	// Regulatory compliance
@@ -433,6 +587,8 @@ type ResponseAttribution struct {
 func DefaultGovernancePolicy() *SeverityGovernanceMapping {
 	return &SeverityGovernanceMapping{
 		PolicyName:            "default_clinical_safety",
+		Version:               1,
+		Status:                PolicyStatusApproved,
 		ContraindicatedAction: GovernanceHardBlock,
 		MajorAction:           GovernanceWarnAcknowledge,
 		ModerateAction:        GovernanceNotify,
@@ -76,6 +76,16 @@ type DrugSynonym struct {
 	CreatedAt           time.Time `json:"created_at"`
 }
 
+// DrugNameMatch represents a candidate row from a fuzzy drug name search
+type DrugNameMatch struct {
+	CanonicalCode     string          `json:"canonical_code"`
+	MatchedName       string          `json:"matched_name"`
+	MatchedCode       string          `json:"matched_code"`
+	SynonymType       string          `json:"synonym_type"`
+	MappingConfidence decimal.Decimal `json:"mapping_confidence"`
+	Similarity        float64         `json:"similarity"` // pg_trgm similarity (0-1)
+}
+
 // InteractionPattern represents patterns for similar drug classes
 type InteractionPattern struct {
 	ID                  uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
@@ -158,6 +168,12 @@ type DrugFormulation struct {
 	RouteOfAdministration   string    `gorm:"size:50;not null;index" json:"route_of_administration"`
 	Strength                *string   `gorm:"size:100" json:"strength,omitempty"`
 	
+	// Product identity (NDC/SPL or local code) for product-level checks
+	ProductCode             *string   `gorm:"size:100;index" json:"product_code,omitempty"`
+	ProductName             *string   `gorm:"size:300" json:"product_name,omitempty"`
+	Manufacturer            *string   `gorm:"size:200" json:"manufacturer,omitempty"`
+	Source                  *string   `gorm:"size:100" json:"source,omitempty"`
+	
 	// Bioavailability and pharmacokinetics
 	Bioavailability         *decimal.Decimal `gorm:"type:decimal(5,4)" json:"bioavailability,omitempty"`
 	HalfLifeHours           *decimal.Decimal `gorm:"type:decimal(8,2)" json:"half_life_hours,omitempty"`
@@ -168,6 +184,7 @@ type DrugFormulation struct {
 	
 	Active                  bool      `gorm:"default:true" json:"active"`
 	CreatedAt               time.Time `json:"created_at"`
+	UpdatedAt               time.Time `json:"updated_at"`
 }
 
 // CDSConfiguration represents clinical decision support configurations
@@ -0,0 +1,850 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kb-drug-interactions/internal/database"
+	"kb-drug-interactions/internal/metrics"
+	"kb-drug-interactions/internal/models"
+)
+
+// What a separation rule spaces a drug from (administration_separation_rules.separate_from)
+const (
+	SeparateFromDrug    = "drug"
+	SeparateFromMeal    = "meal"
+	SeparateFromAnyDrug = "any_drug" // Every other enterally given drug
+)
+
+// Separation statuses in a proposed schedule
+const (
+	SeparationSatisfied = "satisfied"
+	SeparationConflict  = "conflict"
+	SeparationAsNeeded  = "as_needed"     // A PRN order; the separation is an administration instruction
+	SeparationUnplaced  = "not_scheduled" // An order with an unrecognised frequency
+)
+
+const (
+	minutesPerDay          = 24 * 60
+	scheduleSlotMinutes    = 30     // Granularity of proposed administration times
+	maxScheduleSearchSteps = 100000 // Backtracking budget before falling back to the fewest conflicts
+	defaultWakeTime        = "06:00"
+	defaultSleepTime       = "22:00"
+	defaultMorningDoseTime = "08:00"
+	defaultEveningDoseTime = "18:00"
+)
+
+// defaultMealTimes are used when a request gives no meal times
+var defaultMealTimes = []string{"08:00", "12:00", "18:00"}
+
+// Routes separation rules apply to; an order without a route is taken as oral
+var enteralRoutes = map[string]bool{
+	"": true, "po": true, "oral": true, "bymouth": true, "enteral": true,
+	"ng": true, "nasogastric": true, "og": true, "orogastric": true, "peg": true, "gtube": true, "nj": true,
+}
+
+// AdministrationSeparationRule is the minimum spacing between a drug and a
+// chelating drug, meals or any other oral drug
+type AdministrationSeparationRule struct {
+	ID                   int                `json:"id" gorm:"primaryKey"`
+	RuleCode             string             `json:"rule_code"`
+	DrugDescription      string             `json:"drug_description"`
+	DrugCodes            models.StringArray `json:"drug_codes,omitempty" gorm:"type:text[]"`
+	ATCClasses           models.StringArray `json:"atc_classes,omitempty" gorm:"column:atc_classes;type:text[]"`
+	SeparateFrom         string             `json:"separate_from"`
+	SeparatedDescription string             `json:"separated_description"`
+	SeparatedDrugCodes   models.StringArray `json:"separated_drug_codes,omitempty" gorm:"type:text[]"`
+	SeparatedATCClasses  models.StringArray `json:"separated_atc_classes,omitempty" gorm:"column:separated_atc_classes;type:text[]"`
+	MinHoursBefore       float64            `json:"min_hours_before"` // The drug at least this long before the separated item...
+	MinHoursAfter        float64            `json:"min_hours_after"`  // ...or at least this long after it
+	Severity             models.DDISeverity `json:"severity"`
+	Mechanism            string             `json:"mechanism"`
+	Recommendation       string             `json:"recommendation"`
+	Source               string             `json:"source"`
+	Active               bool               `json:"active"`
+}
+
+// TableName specifies the database table for GORM
+func (AdministrationSeparationRule) TableName() string {
+	return "administration_separation_rules"
+}
+
+// ScheduleInputError reports an invalid meal, wake or sleep time, or a dosing
+// interval shorter than the schedule's granularity
+type ScheduleInputError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ScheduleInputError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// AdministrationScheduleRequest asks for a daily administration schedule for a regimen
+type AdministrationScheduleRequest struct {
+	MedicationOrders []models.MedicationOrder `json:"medication_orders" binding:"required,min=1"`
+	MealTimes        []string                 `json:"meal_times,omitempty"` // "HH:MM"; default 08:00, 12:00, 18:00
+	WakeTime         string                   `json:"wake_time,omitempty"`  // Doses up to three a day fall between wake and sleep; default 06:00
+	SleepTime        string                   `json:"sleep_time,omitempty"` // Default 22:00
+}
+
+// ScheduledOrder is an order with its proposed daily administration times
+type ScheduledOrder struct {
+	OrderID      string   `json:"order_id,omitempty"`
+	DrugCode     string   `json:"drug_code"`
+	DrugName     string   `json:"drug_name,omitempty"`
+	Dose         float64  `json:"dose,omitempty"`
+	DoseUnit     string   `json:"dose_unit,omitempty"`
+	Frequency    string   `json:"frequency,omitempty"`
+	Route        string   `json:"route,omitempty"`
+	PRN          bool     `json:"prn,omitempty"`
+	Times        []string `json:"times"` // "HH:MM"; empty for PRN and unschedulable orders
+	Instructions []string `json:"instructions,omitempty"`
+}
+
+// ScheduleSlotItem is one order given at a slot
+type ScheduleSlotItem struct {
+	OrderID  string `json:"order_id,omitempty"`
+	DrugCode string `json:"drug_code"`
+	DrugName string `json:"drug_name,omitempty"`
+}
+
+// ScheduleSlot is a time of day with the orders given then, for MAR builders
+type ScheduleSlot struct {
+	Time   string             `json:"time"`
+	Meal   bool               `json:"meal,omitempty"`
+	Orders []ScheduleSlotItem `json:"orders"`
+}
+
+// AppliedSeparation is a separation rule that applies to two orders, or to an order and meals
+type AppliedSeparation struct {
+	RuleCode         string             `json:"rule_code"`
+	Severity         models.DDISeverity `json:"severity"`
+	OrderID          string             `json:"order_id,omitempty"`
+	DrugCode         string             `json:"drug_code"`
+	DrugName         string             `json:"drug_name,omitempty"`
+	SeparatedFrom    string             `json:"separated_from"` // Drug name, or "meals"
+	SeparatedOrderID string             `json:"separated_order_id,omitempty"`
+	SeparatedCode    string             `json:"separated_code,omitempty"`
+	MinHoursBefore   float64            `json:"min_hours_before"`
+	MinHoursAfter    float64            `json:"min_hours_after"`
+	Status           string             `json:"status"`
+	Mechanism        string             `json:"mechanism,omitempty"`
+	Recommendation   string             `json:"recommendation,omitempty"`
+}
+
+// ScheduleConflict is a separation the proposed schedule could not satisfy
+type ScheduleConflict struct {
+	RuleCode string             `json:"rule_code"`
+	Severity models.DDISeverity `json:"severity"`
+	OrderIDs []string           `json:"order_ids,omitempty"`
+	Drugs    []string           `json:"drugs"`
+	Times    []string           `json:"times"` // A pair of administrations that are too close
+	Message  string             `json:"message"`
+}
+
+// AdministrationScheduleResponse is a proposed daily schedule satisfying the
+// regimen's separation rules, or the closest schedule with its conflicts
+type AdministrationScheduleResponse struct {
+	Feasible    bool                `json:"feasible"`
+	Schedule    []ScheduledOrder    `json:"schedule"`
+	Slots       []ScheduleSlot      `json:"slots"`
+	Separations []AppliedSeparation `json:"separations"`
+	Conflicts   []ScheduleConflict  `json:"conflicts"`
+	Notes       []string            `json:"notes,omitempty"`
+}
+
+// AdministrationScheduleEngine proposes daily administration times that keep
+// chelating drugs, meals and bile acid sequestrants apart
+type AdministrationScheduleEngine struct {
+	db       *database.Database
+	atcIndex *ATCClassIndex
+	metrics  *metrics.Collector
+
+	rules       []AdministrationSeparationRule
+	rulesLoaded time.Time
+	cacheTTL    time.Duration
+	mu          sync.Mutex
+}
+
+// NewAdministrationScheduleEngine creates a new administration-timing engine.
+// Drugs match class rules through the ATC index; without it only listed drug codes match.
+func NewAdministrationScheduleEngine(db *database.Database, atcIndex *ATCClassIndex, metrics *metrics.Collector) *AdministrationScheduleEngine {
+	return &AdministrationScheduleEngine{
+		db:       db,
+		atcIndex: atcIndex,
+		metrics:  metrics,
+		cacheTTL: 30 * time.Minute,
+	}
+}
+
+// BuildSchedule proposes a daily schedule for the regimen's active orders.
+// Invalid meal, wake or sleep times and intervals shorter than a schedule slot
+// are reported as *ScheduleInputError.
+func (ase *AdministrationScheduleEngine) BuildSchedule(ctx context.Context, request AdministrationScheduleRequest) (*AdministrationScheduleResponse, error) {
+	options, err := resolveScheduleOptions(request)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateScheduleIntervals(request.MedicationOrders); err != nil {
+		return nil, err
+	}
+
+	rules, err := ase.loadRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load separation rules: %w", err)
+	}
+
+	response := planAdministrationSchedule(rules, request.MedicationOrders, options, ase.drugClasses)
+	outcome := "feasible"
+	if !response.Feasible {
+		outcome = "conflicts"
+	}
+	ase.metrics.RecordAdministrationSchedule(outcome)
+	return response, nil
+}
+
+// GetDrugSeparationRules returns the rules naming a drug, by code or ATC class,
+// on either side. Sequestrant rules that apply to any drug are returned only
+// for the sequestrants.
+func (ase *AdministrationScheduleEngine) GetDrugSeparationRules(ctx context.Context, drugCode string) ([]AdministrationSeparationRule, error) {
+	rules, err := ase.loadRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load separation rules: %w", err)
+	}
+
+	drug := regimenDrug{Code: drugCode, Key: normalizeATCDrugKey(drugCode), Classes: ase.drugClasses(drugCode)}
+	drugRules := []AdministrationSeparationRule{}
+	for _, rule := range rules {
+		if regimenDrugMatches(drug, rule.DrugCodes, rule.ATCClasses) ||
+			(rule.SeparateFrom == SeparateFromDrug && regimenDrugMatches(drug, rule.SeparatedDrugCodes, rule.SeparatedATCClasses)) {
+			drugRules = append(drugRules, rule)
+		}
+	}
+	return drugRules, nil
+}
+
+// scheduleOptions are the meal, wake and sleep times in minutes after midnight
+type scheduleOptions struct {
+	meals []int
+	wake  int
+	sleep int
+}
+
+// resolveScheduleOptions parses the request's clock times, applying defaults
+func resolveScheduleOptions(request AdministrationScheduleRequest) (scheduleOptions, error) {
+	var options scheduleOptions
+	var err error
+
+	mealTimes := request.MealTimes
+	if len(mealTimes) == 0 {
+		mealTimes = defaultMealTimes
+	}
+	for _, mealTime := range mealTimes {
+		minute, err := parseClockMinutes("meal_times", mealTime)
+		if err != nil {
+			return scheduleOptions{}, err
+		}
+		options.meals = append(options.meals, minute)
+	}
+	sort.Ints(options.meals)
+
+	if options.wake, err = parseClockMinutes("wake_time", orDefault(request.WakeTime, defaultWakeTime)); err != nil {
+		return scheduleOptions{}, err
+	}
+	if options.sleep, err = parseClockMinutes("sleep_time", orDefault(request.SleepTime, defaultSleepTime)); err != nil {
+		return scheduleOptions{}, err
+	}
+	if options.wake == options.sleep {
+		return scheduleOptions{}, &ScheduleInputError{Field: "sleep_time", Reason: "must differ from wake_time"}
+	}
+	return options, nil
+}
+
+// validateScheduleIntervals rejects scheduled orders given more often than
+// once per schedule slot
+func validateScheduleIntervals(orders []models.MedicationOrder) error {
+	for _, order := range orders {
+		if !order.IsActive() || order.PRN {
+			continue
+		}
+		if interval, ok := orderIntervalHours(order); ok && interval*60 < scheduleSlotMinutes {
+			return &ScheduleInputError{
+				Field:  "interval_hours",
+				Reason: fmt.Sprintf("%s: every %s h is shorter than the %d minute schedule slot", orDefault(order.DrugName, order.DrugCode), formatHours(interval), scheduleSlotMinutes),
+			}
+		}
+	}
+	return nil
+}
+
+// parseClockMinutes parses "HH:MM" to minutes after midnight
+func parseClockMinutes(field, value string) (int, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, &ScheduleInputError{Field: field, Reason: fmt.Sprintf("%q is not a 24-hour HH:MM time", value)}
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+func orDefault(value, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
+
+// formatClockMinutes formats minutes after midnight as "HH:MM"
+func formatClockMinutes(minute int) string {
+	minute = wrapMinutes(minute)
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+// wrapMinutes maps a minute offset onto a single day
+func wrapMinutes(minute int) int {
+	return ((minute % minutesPerDay) + minutesPerDay) % minutesPerDay
+}
+
+// scheduleItem is an order being placed in the day
+type scheduleItem struct {
+	order      models.MedicationOrder
+	label      string
+	drug       regimenDrug
+	enteral    bool
+	scheduled  bool  // False for PRN orders and unknown frequencies
+	doses      int   // Per day
+	spacing    int   // Minutes between doses
+	candidates []int // First dose times, most preferred first
+	times      []int // Assigned times; nil until placed
+
+	instructions []string
+}
+
+// separationPair is a rule applied to two items, or to an item and meals (second = -1)
+type separationPair struct {
+	rule   AdministrationSeparationRule
+	first  int // The rule's drug
+	second int
+	before int // Minutes
+	after  int
+}
+
+// planAdministrationSchedule places the active orders' doses in a daily schedule
+// that satisfies every applicable separation, searching the candidate times of
+// the most constrained orders first. When no such schedule exists the orders
+// are placed with the fewest conflicts and the remaining conflicts reported.
+func planAdministrationSchedule(
+	rules []AdministrationSeparationRule,
+	orders []models.MedicationOrder,
+	options scheduleOptions,
+	classesFor func(drugCode string) []string, // A drug's ATC codes at every level
+) *AdministrationScheduleResponse {
+	response := &AdministrationScheduleResponse{
+		Schedule:    []ScheduledOrder{},
+		Slots:       []ScheduleSlot{},
+		Separations: []AppliedSeparation{},
+		Conflicts:   []ScheduleConflict{},
+	}
+
+	var items []*scheduleItem
+	for _, order := range orders {
+		if !order.IsActive() {
+			continue
+		}
+		item, note := newScheduleItem(order, options, classesFor)
+		if note != "" {
+			response.Notes = append(response.Notes, note)
+		}
+		items = append(items, item)
+	}
+
+	pairs := separationPairs(rules, items)
+	planner := newSchedulePlanner(items, pairs, options)
+	if !planner.search(0) {
+		planner.placeWithFewestConflicts()
+		if planner.steps > maxScheduleSearchSteps {
+			response.Notes = append(response.Notes,
+				"The schedule search stopped before trying every combination; the conflicts below may be resolvable by hand")
+		}
+	}
+
+	response.Feasible = true
+	for _, pair := range pairs {
+		separation, conflict := describeSeparation(pair, items, options)
+		response.Separations = append(response.Separations, separation)
+		if conflict != nil {
+			response.Feasible = false
+			response.Conflicts = append(response.Conflicts, *conflict)
+		}
+		addSeparationInstructions(pair, items)
+	}
+
+	for _, item := range items {
+		scheduled := ScheduledOrder{
+			OrderID:      item.order.OrderID,
+			DrugCode:     item.order.DrugCode,
+			DrugName:     item.order.DrugName,
+			Dose:         item.order.Dose,
+			DoseUnit:     item.order.DoseUnit,
+			Frequency:    item.order.Frequency,
+			Route:        item.order.Route,
+			PRN:          item.order.PRN,
+			Times:        []string{},
+			Instructions: item.instructions,
+		}
+		for _, minute := range item.sortedTimes() {
+			scheduled.Times = append(scheduled.Times, formatClockMinutes(minute))
+		}
+		response.Schedule = append(response.Schedule, scheduled)
+	}
+	response.Slots = scheduleSlots(items, options)
+
+	sort.SliceStable(response.Conflicts, func(i, j int) bool {
+		return response.Conflicts[i].Severity.GetPriority() > response.Conflicts[j].Severity.GetPriority()
+	})
+	return response
+}
+
+// newScheduleItem works out an order's doses per day and candidate first dose
+// times. Orders up to three times a day keep to waking hours when they can.
+func newScheduleItem(order models.MedicationOrder, options scheduleOptions, classesFor func(string) []string) (*scheduleItem, string) {
+	item := &scheduleItem{
+		order:   order,
+		label:   order.DrugName,
+		drug:    regimenDrug{Code: order.DrugCode, Key: normalizeATCDrugKey(order.DrugCode), Classes: classesFor(order.DrugCode)},
+		enteral: enteralRoutes[strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(order.Route))],
+	}
+	if item.label == "" {
+		item.label = order.DrugCode
+	}
+	if order.PRN {
+		return item, ""
+	}
+
+	interval, ok := orderIntervalHours(order)
+	if !ok {
+		return item, fmt.Sprintf("%s: frequency %q not recognised; not scheduled", item.label, order.Frequency)
+	}
+
+	var note string
+	item.scheduled = true
+	item.doses = 1
+	if interval < 24 {
+		item.doses = int(math.Round(24 / interval))
+		if math.Abs(24/interval-float64(item.doses)) > 1e-9 {
+			note = fmt.Sprintf("%s: every %s h does not divide the day; scheduled %d times a day",
+				item.label, formatHours(interval), item.doses)
+		}
+	} else if interval > 24 {
+		note = fmt.Sprintf("%s: given every %s days; the time of day is scheduled", item.label, formatHours(interval/24))
+	}
+	item.spacing = minutesPerDay / item.doses
+
+	preferred := preferredFirstDose(order, options)
+	var candidates, daytime []int
+	for start := 0; start < item.spacing; start += scheduleSlotMinutes {
+		candidates = append(candidates, start)
+		if item.doses <= 3 && allWithinWakingHours(doseTimes(start, item.doses, item.spacing), options) {
+			daytime = append(daytime, start)
+		}
+	}
+	if item.doses <= 3 {
+		if len(daytime) > 0 {
+			candidates = daytime
+		} else {
+			note = fmt.Sprintf("%s: doses every %s h cannot all fall between %s and %s; night doses scheduled",
+				item.label, formatHours(float64(item.spacing)/60), formatClockMinutes(options.wake), formatClockMinutes(options.sleep))
+		}
+	}
+
+	distance := func(start int) int {
+		d := ((start-preferred)%item.spacing + item.spacing) % item.spacing
+		if item.spacing-d < d {
+			return item.spacing - d
+		}
+		return d
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return distance(candidates[i]) < distance(candidates[j]) })
+	item.candidates = candidates
+	return item, note
+}
+
+// preferredFirstDose returns the conventional first dose time for an order's frequency
+func preferredFirstDose(order models.MedicationOrder, options scheduleOptions) int {
+	frequency := strings.ToLower(strings.NewReplacer(" ", "", ".", "").Replace(order.Frequency))
+	switch frequency {
+	case "qhs", "atbedtime", "nightly":
+		return options.sleep
+	case "qpm", "evening":
+		minute, _ := parseClockMinutes("", defaultEveningDoseTime)
+		return minute
+	}
+	if interval, ok := orderIntervalHours(order); ok && interval < 12 {
+		return options.wake
+	}
+	minute, _ := parseClockMinutes("", defaultMorningDoseTime)
+	return minute
+}
+
+// doseTimes returns the times of day of evenly spaced doses
+func doseTimes(start, doses, spacing int) []int {
+	times := make([]int, doses)
+	for i := range times {
+		times[i] = wrapMinutes(start + i*spacing)
+	}
+	return times
+}
+
+func allWithinWakingHours(times []int, options scheduleOptions) bool {
+	for _, minute := range times {
+		if options.wake < options.sleep {
+			if minute < options.wake || minute > options.sleep {
+				return false
+			}
+		} else if minute < options.wake && minute > options.sleep {
+			return false
+		}
+	}
+	return true
+}
+
+// separationPairs applies each rule to the enterally given orders it covers
+func separationPairs(rules []AdministrationSeparationRule, items []*scheduleItem) []separationPair {
+	var pairs []separationPair
+	for _, rule := range rules {
+		before := int(math.Round(rule.MinHoursBefore * 60))
+		after := int(math.Round(rule.MinHoursAfter * 60))
+		for i, item := range items {
+			if !item.enteral || !regimenDrugMatches(item.drug, rule.DrugCodes, rule.ATCClasses) {
+				continue
+			}
+			if rule.SeparateFrom == SeparateFromMeal {
+				pairs = append(pairs, separationPair{rule: rule, first: i, second: -1, before: before, after: after})
+				continue
+			}
+			for j, other := range items {
+				if j == i || !other.enteral || other.drug.Key == item.drug.Key {
+					continue
+				}
+				var matches bool
+				switch rule.SeparateFrom {
+				case SeparateFromDrug:
+					matches = regimenDrugMatches(other.drug, rule.SeparatedDrugCodes, rule.SeparatedATCClasses)
+				case SeparateFromAnyDrug:
+					matches = !regimenDrugMatches(other.drug, rule.DrugCodes, rule.ATCClasses)
+				}
+				if matches {
+					pairs = append(pairs, separationPair{rule: rule, first: i, second: j, before: before, after: after})
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// separationViolation returns the first pair of administrations closer than a
+// rule allows: the separated time may not fall within (first - after, first + before).
+// Schedules repeat daily, so times are compared around the clock.
+func separationViolation(firstTimes, secondTimes []int, before, after int) (int, int, bool) {
+	for _, first := range firstTimes {
+		for _, second := range secondTimes {
+			gap := wrapMinutes(second - first) // second follows first by gap, and precedes it by a day minus gap
+			if gap == 0 || gap < before || minutesPerDay-gap < after {
+				return first, second, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// schedulePlanner assigns dose times to the scheduled items
+type schedulePlanner struct {
+	items  []*scheduleItem
+	pairs  []separationPair
+	meals  []int
+	order  []int         // Item indexes, most constrained first
+	byItem map[int][]int // Pair indexes per item
+	steps  int
+}
+
+func newSchedulePlanner(items []*scheduleItem, pairs []separationPair, options scheduleOptions) *schedulePlanner {
+	planner := &schedulePlanner{items: items, pairs: pairs, meals: options.meals, byItem: make(map[int][]int)}
+	for p, pair := range pairs {
+		planner.byItem[pair.first] = append(planner.byItem[pair.first], p)
+		if pair.second >= 0 {
+			planner.byItem[pair.second] = append(planner.byItem[pair.second], p)
+		}
+	}
+	for i, item := range items {
+		// An item without candidate times cannot be placed and is left unscheduled
+		if item.scheduled && len(item.candidates) > 0 {
+			planner.order = append(planner.order, i)
+		}
+	}
+	sort.SliceStable(planner.order, func(a, b int) bool {
+		ia, ib := planner.order[a], planner.order[b]
+		if len(planner.byItem[ia]) != len(planner.byItem[ib]) {
+			return len(planner.byItem[ia]) > len(planner.byItem[ib])
+		}
+		return len(items[ia].candidates) < len(items[ib].candidates)
+	})
+	return planner
+}
+
+// pairTimes returns the times of a pair's two sides, or false while either is unplaced
+func (sp *schedulePlanner) pairTimes(pair separationPair) ([]int, []int, bool) {
+	first := sp.items[pair.first].times
+	second := sp.meals
+	if pair.second >= 0 {
+		second = sp.items[pair.second].times
+	}
+	return first, second, first != nil && second != nil
+}
+
+// conflicts counts the item's separations violated by the items placed so far
+func (sp *schedulePlanner) conflicts(index int) int {
+	count := 0
+	for _, p := range sp.byItem[index] {
+		first, second, placed := sp.pairTimes(sp.pairs[p])
+		if !placed {
+			continue
+		}
+		if _, _, violated := separationViolation(first, second, sp.pairs[p].before, sp.pairs[p].after); violated {
+			count++
+		}
+	}
+	return count
+}
+
+// search places the items from position pos on without conflicts, backtracking
+func (sp *schedulePlanner) search(pos int) bool {
+	sp.steps++
+	if sp.steps > maxScheduleSearchSteps {
+		return false
+	}
+	if pos == len(sp.order) {
+		return true
+	}
+
+	item := sp.items[sp.order[pos]]
+	for _, start := range item.candidates {
+		item.times = doseTimes(start, item.doses, item.spacing)
+		if sp.conflicts(sp.order[pos]) == 0 && sp.search(pos+1) {
+			return true
+		}
+	}
+	item.times = nil
+	return false
+}
+
+// placeWithFewestConflicts places each item in turn at its preferred time among
+// those with the fewest conflicts with the items already placed
+func (sp *schedulePlanner) placeWithFewestConflicts() {
+	for _, index := range sp.order {
+		sp.items[index].times = nil
+	}
+	for _, index := range sp.order {
+		item := sp.items[index]
+		best, bestConflicts := item.candidates[0], -1
+		for _, start := range item.candidates {
+			item.times = doseTimes(start, item.doses, item.spacing)
+			if conflicts := sp.conflicts(index); bestConflicts < 0 || conflicts < bestConflicts {
+				best, bestConflicts = start, conflicts
+			}
+		}
+		item.times = doseTimes(best, item.doses, item.spacing)
+	}
+}
+
+// describeSeparation reports a pair's status in the placed schedule, and a
+// conflict when the placed doses are too close
+func describeSeparation(pair separationPair, items []*scheduleItem, options scheduleOptions) (AppliedSeparation, *ScheduleConflict) {
+	first := items[pair.first]
+	separation := AppliedSeparation{
+		RuleCode:       pair.rule.RuleCode,
+		Severity:       pair.rule.Severity,
+		OrderID:        first.order.OrderID,
+		DrugCode:       first.order.DrugCode,
+		DrugName:       first.order.DrugName,
+		SeparatedFrom:  "meals",
+		MinHoursBefore: pair.rule.MinHoursBefore,
+		MinHoursAfter:  pair.rule.MinHoursAfter,
+		Status:         SeparationSatisfied,
+		Mechanism:      pair.rule.Mechanism,
+		Recommendation: pair.rule.Recommendation,
+	}
+	secondTimes := options.meals
+	var second *scheduleItem
+	if pair.second >= 0 {
+		second = items[pair.second]
+		separation.SeparatedFrom = second.label
+		separation.SeparatedOrderID = second.order.OrderID
+		separation.SeparatedCode = second.order.DrugCode
+		secondTimes = second.times
+	}
+
+	switch {
+	case first.order.PRN || (second != nil && second.order.PRN):
+		separation.Status = SeparationAsNeeded
+		return separation, nil
+	case !first.scheduled || (second != nil && !second.scheduled):
+		separation.Status = SeparationUnplaced
+		return separation, nil
+	}
+	firstTime, secondTime, violated := separationViolation(first.times, secondTimes, pair.before, pair.after)
+	if !violated {
+		return separation, nil
+	}
+
+	separation.Status = SeparationConflict
+	conflict := &ScheduleConflict{
+		RuleCode: pair.rule.RuleCode,
+		Severity: pair.rule.Severity,
+		Drugs:    []string{first.label, separation.SeparatedFrom},
+		Times:    []string{formatClockMinutes(firstTime), formatClockMinutes(secondTime)},
+		Message: fmt.Sprintf("%s (%s) and %s (%s) could not be separated at the ordered frequencies: %s",
+			first.label, formatClockMinutes(firstTime), separation.SeparatedFrom, formatClockMinutes(secondTime),
+			separationWindow(first.label, pair.rule.MinHoursBefore, pair.rule.MinHoursAfter, separation.SeparatedFrom)),
+	}
+	for _, orderID := range []string{first.order.OrderID, separation.SeparatedOrderID} {
+		if orderID != "" {
+			conflict.OrderIDs = append(conflict.OrderIDs, orderID)
+		}
+	}
+	return separation, conflict
+}
+
+// addSeparationInstructions records a pair's spacing on both of its orders, as
+// seen from each: "at least 2 h before or 6 h after" mirrors to "6 h before or 2 h after"
+func addSeparationInstructions(pair separationPair, items []*scheduleItem) {
+	first := items[pair.first]
+	if pair.second < 0 {
+		first.addInstruction(separationInstruction(pair.rule.MinHoursBefore, pair.rule.MinHoursAfter, "meals"))
+		return
+	}
+	second := items[pair.second]
+	first.addInstruction(separationInstruction(pair.rule.MinHoursBefore, pair.rule.MinHoursAfter, second.label))
+	second.addInstruction(separationInstruction(pair.rule.MinHoursAfter, pair.rule.MinHoursBefore, first.label))
+}
+
+// separationInstruction phrases a spacing for the order being given
+func separationInstruction(before, after float64, other string) string {
+	switch {
+	case before > 0 && after > 0:
+		return fmt.Sprintf("Give at least %s before or %s after %s", formatSeparationHours(before), formatSeparationHours(after), other)
+	case before > 0:
+		return fmt.Sprintf("Do not give in the %s before %s", formatSeparationHours(before), other)
+	default:
+		return fmt.Sprintf("Do not give in the %s after %s", formatSeparationHours(after), other)
+	}
+}
+
+// separationWindow phrases a rule's spacing for conflict messages
+func separationWindow(drug string, before, after float64, other string) string {
+	return fmt.Sprintf("%s must be given at least %s before or %s after %s",
+		drug, formatSeparationHours(before), formatSeparationHours(after), other)
+}
+
+// formatSeparationHours formats a separation: "30 min", "2 h", "1.5 h"
+func formatSeparationHours(hours float64) string {
+	if hours > 0 && hours < 1 {
+		return fmt.Sprintf("%d min", int(math.Round(hours*60)))
+	}
+	return formatHours(hours) + " h"
+}
+
+// addInstruction adds an administration instruction once; several rules can
+// give the same spacing for a pair
+func (item *scheduleItem) addInstruction(instruction string) {
+	if item.order.PRN {
+		instruction = "When needed, " + strings.ToLower(instruction[:1]) + instruction[1:]
+	}
+	for _, existing := range item.instructions {
+		if existing == instruction {
+			return
+		}
+	}
+	item.instructions = append(item.instructions, instruction)
+}
+
+func (item *scheduleItem) sortedTimes() []int {
+	times := append([]int(nil), item.times...)
+	sort.Ints(times)
+	return times
+}
+
+// scheduleSlots groups the placed doses and meals by time of day
+func scheduleSlots(items []*scheduleItem, options scheduleOptions) []ScheduleSlot {
+	slots := make(map[int]*ScheduleSlot)
+	slotAt := func(minute int) *ScheduleSlot {
+		slot, exists := slots[minute]
+		if !exists {
+			slot = &ScheduleSlot{Time: formatClockMinutes(minute), Orders: []ScheduleSlotItem{}}
+			slots[minute] = slot
+		}
+		return slot
+	}
+
+	for _, meal := range options.meals {
+		slotAt(meal).Meal = true
+	}
+	for _, item := range items {
+		for _, minute := range item.times {
+			slot := slotAt(minute)
+			slot.Orders = append(slot.Orders, ScheduleSlotItem{
+				OrderID:  item.order.OrderID,
+				DrugCode: item.order.DrugCode,
+				DrugName: item.order.DrugName,
+			})
+		}
+	}
+
+	minutes := make([]int, 0, len(slots))
+	for minute := range slots {
+		minutes = append(minutes, minute)
+	}
+	sort.Ints(minutes)
+	result := make([]ScheduleSlot, 0, len(minutes))
+	for _, minute := range minutes {
+		result = append(result, *slots[minute])
+	}
+	return result
+}
+
+// drugClasses returns a drug's ATC codes at every level, or none without the index
+func (ase *AdministrationScheduleEngine) drugClasses(drugCode string) []string {
+	if ase.atcIndex == nil {
+		return nil
+	}
+	return ase.atcIndex.ClassesForDrug(drugCode)
+}
+
+// loadRules returns the active separation rules
+func (ase *AdministrationScheduleEngine) loadRules(ctx context.Context) ([]AdministrationSeparationRule, error) {
+	ase.mu.Lock()
+	defer ase.mu.Unlock()
+
+	if ase.rules != nil && time.Since(ase.rulesLoaded) < ase.cacheTTL {
+		return ase.rules, nil
+	}
+
+	var rules []AdministrationSeparationRule
+	if err := ase.db.DB.WithContext(ctx).
+		Where("active = TRUE").
+		Order("rule_code").
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = []AdministrationSeparationRule{}
+	}
+
+	ase.rules = rules
+	ase.rulesLoaded = time.Now()
+	return rules, nil
+}
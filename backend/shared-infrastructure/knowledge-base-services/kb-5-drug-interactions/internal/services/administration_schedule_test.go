@@ -0,0 +1,229 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// ADMINISTRATION SCHEDULE TESTS
+// ============================================================================
+
+func TestPlanAdministrationSchedule_FluoroquinoloneCation(t *testing.T) {
+	rules := []AdministrationSeparationRule{
+		{RuleCode: "SEP-FLUOROQUINOLONE-CATION", ATCClasses: models.StringArray{"J01MA"}, SeparateFrom: SeparateFromDrug,
+			SeparatedATCClasses: models.StringArray{"A02AA", "A12AA", "B03AA"}, MinHoursBefore: 2, MinHoursAfter: 6, Severity: models.SeverityMajor},
+	}
+	classes := map[string][]string{
+		"RxCUI:2551": {"J01MA02", "J01MA", "J01M", "J01", "J"},
+		"RxCUI:1897": {"A12AA04", "A12AA", "A12A", "A12", "A"},
+	}
+	orders := []models.MedicationOrder{
+		{OrderID: "o1", DrugCode: "RxCUI:2551", DrugName: "Ciprofloxacin", Frequency: "q12h"},
+		{OrderID: "o2", DrugCode: "RxCUI:1897", DrugName: "Calcium carbonate", Frequency: "BID"},
+	}
+	options, err := resolveScheduleOptions(AdministrationScheduleRequest{MedicationOrders: orders})
+	require.NoError(t, err)
+	response := planAdministrationSchedule(rules, orders, options, func(code string) []string { return classes[code] })
+
+	assert.True(t, response.Feasible)
+	require.Len(t, response.Schedule, 2)
+	assert.Equal(t, []string{"08:00", "20:00"}, response.Schedule[0].Times)
+	assert.Equal(t, []string{"10:00", "22:00"}, response.Schedule[1].Times)
+	assert.Equal(t, []string{"Give at least 2 h before or 6 h after Calcium carbonate"}, response.Schedule[0].Instructions)
+	assert.Equal(t, []string{"Give at least 6 h before or 2 h after Ciprofloxacin"}, response.Schedule[1].Instructions)
+
+	require.Len(t, response.Separations, 1)
+	assert.Equal(t, SeparationSatisfied, response.Separations[0].Status)
+	assert.Equal(t, "o2", response.Separations[0].SeparatedOrderID)
+
+	// Meals appear in the slots alongside the doses
+	require.NotEmpty(t, response.Slots)
+	assert.Equal(t, "08:00", response.Slots[0].Time)
+	assert.True(t, response.Slots[0].Meal)
+	assert.Equal(t, "o1", response.Slots[0].Orders[0].OrderID)
+}
+
+func TestPlanAdministrationSchedule_LevothyroxineMealsAndCalcium(t *testing.T) {
+	rules := []AdministrationSeparationRule{
+		{RuleCode: "SEP-LEVOTHYROXINE-CALCIUM-IRON", DrugCodes: models.StringArray{"RxCUI:10582"}, SeparateFrom: SeparateFromDrug,
+			SeparatedATCClasses: models.StringArray{"A02AA", "A12AA", "B03AA"}, MinHoursBefore: 4, MinHoursAfter: 4, Severity: models.SeverityModerate},
+		{RuleCode: "SEP-LEVOTHYROXINE-MEAL", DrugCodes: models.StringArray{"RxCUI:10582"}, SeparateFrom: SeparateFromMeal,
+			MinHoursBefore: 0.5, MinHoursAfter: 3, Severity: models.SeverityModerate},
+	}
+	classes := map[string][]string{
+		"RxCUI:1897": {"A12AA04", "A12AA", "A12A", "A12", "A"},
+	}
+	orders := []models.MedicationOrder{
+		{OrderID: "calcium", DrugCode: "RxCUI:1897", Frequency: "daily"},
+		{OrderID: "levo", DrugCode: "RxCUI:10582", DrugName: "Levothyroxine", Frequency: "daily"},
+	}
+	options, err := resolveScheduleOptions(AdministrationScheduleRequest{MedicationOrders: orders})
+	require.NoError(t, err)
+	response := planAdministrationSchedule(rules, orders, options, func(code string) []string { return classes[code] })
+
+	assert.True(t, response.Feasible)
+	// Levothyroxine goes before breakfast; calcium at least 4 h later
+	assert.Equal(t, []string{"11:30"}, response.Schedule[0].Times)
+	assert.Equal(t, []string{"07:30"}, response.Schedule[1].Times)
+	assert.Contains(t, response.Schedule[1].Instructions, "Give at least 30 min before or 3 h after meals")
+	assert.Len(t, response.Separations, 2)
+}
+
+func TestPlanAdministrationSchedule_UnresolvableConflict(t *testing.T) {
+	// An antacid every 4 h leaves no 8 h gap for ciprofloxacin
+	rules := []AdministrationSeparationRule{
+		{RuleCode: "SEP-FLUOROQUINOLONE-CATION", ATCClasses: models.StringArray{"J01MA"}, SeparateFrom: SeparateFromDrug,
+			SeparatedATCClasses: models.StringArray{"A02AA", "A12AA", "B03AA"}, MinHoursBefore: 2, MinHoursAfter: 6, Severity: models.SeverityMajor},
+	}
+	classes := map[string][]string{
+		"RxCUI:2551": {"J01MA02", "J01MA", "J01M", "J01", "J"},
+		"RxCUI:1897": {"A12AA04", "A12AA", "A12A", "A12", "A"},
+	}
+	orders := []models.MedicationOrder{
+		{OrderID: "o1", DrugCode: "RxCUI:2551", DrugName: "Ciprofloxacin", Frequency: "q12h"},
+		{OrderID: "o2", DrugCode: "RxCUI:1897", DrugName: "Calcium carbonate", Frequency: "q4h"},
+	}
+	options, err := resolveScheduleOptions(AdministrationScheduleRequest{MedicationOrders: orders})
+	require.NoError(t, err)
+	response := planAdministrationSchedule(rules, orders, options, func(code string) []string { return classes[code] })
+
+	assert.False(t, response.Feasible)
+	require.Len(t, response.Conflicts, 1)
+	conflict := response.Conflicts[0]
+	assert.Equal(t, "SEP-FLUOROQUINOLONE-CATION", conflict.RuleCode)
+	assert.Equal(t, []string{"o1", "o2"}, conflict.OrderIDs)
+	assert.Len(t, conflict.Times, 2)
+	assert.Contains(t, conflict.Message, "Ciprofloxacin must be given at least 2 h before or 6 h after Calcium carbonate")
+	assert.Equal(t, SeparationConflict, response.Separations[0].Status)
+
+	// Both orders are still placed
+	assert.Len(t, response.Schedule[0].Times, 2)
+	assert.Len(t, response.Schedule[1].Times, 6)
+}
+
+func TestPlanAdministrationSchedule_SequestrantSeparatesOtherOralDrugs(t *testing.T) {
+	rules := []AdministrationSeparationRule{
+		{RuleCode: "SEP-SEQUESTRANT-ANY", ATCClasses: models.StringArray{"C10AC01"}, SeparateFrom: SeparateFromAnyDrug,
+			MinHoursBefore: 4, MinHoursAfter: 1, Severity: models.SeverityModerate},
+	}
+	classes := map[string][]string{
+		"RxCUI:2551": {"J01MA02", "J01MA", "J01M", "J01", "J"},
+		"RxCUI:2447": {"C10AC01", "C10AC", "C10A", "C10", "C"},
+	}
+	orders := []models.MedicationOrder{
+		{OrderID: "chol", DrugCode: "RxCUI:2447", DrugName: "Colestyramine", Frequency: "BID"},
+		{OrderID: "warf", DrugCode: "RxCUI:11289", DrugName: "Warfarin", Frequency: "daily"},
+		{OrderID: "cipro-iv", DrugCode: "RxCUI:2551", DrugName: "Ciprofloxacin", Frequency: "q12h", Route: "IV"},
+	}
+	options, err := resolveScheduleOptions(AdministrationScheduleRequest{MedicationOrders: orders})
+	require.NoError(t, err)
+	response := planAdministrationSchedule(rules, orders, options, func(code string) []string { return classes[code] })
+
+	assert.True(t, response.Feasible)
+	// Intravenous doses are not separated
+	require.Len(t, response.Separations, 1)
+	assert.Equal(t, "warf", response.Separations[0].SeparatedOrderID)
+	assert.Empty(t, response.Schedule[2].Instructions)
+	assert.Equal(t, []string{"Give at least 1 h before or 4 h after Colestyramine"}, response.Schedule[1].Instructions)
+}
+
+func TestPlanAdministrationSchedule_PRNAndUnknownFrequency(t *testing.T) {
+	rules := []AdministrationSeparationRule{
+		{RuleCode: "SEP-FLUOROQUINOLONE-CATION", ATCClasses: models.StringArray{"J01MA"}, SeparateFrom: SeparateFromDrug,
+			SeparatedATCClasses: models.StringArray{"A02AA", "A12AA", "B03AA"}, MinHoursBefore: 2, MinHoursAfter: 6, Severity: models.SeverityMajor},
+	}
+	classes := map[string][]string{
+		"RxCUI:2551":  {"J01MA02", "J01MA", "J01M", "J01", "J"},
+		"RxCUI:1897":  {"A12AA04", "A12AA", "A12A", "A12", "A"},
+		"RxCUI:24947": {"B03AA07", "B03AA", "B03A", "B03", "B"},
+	}
+	orders := []models.MedicationOrder{
+		{OrderID: "o1", DrugCode: "RxCUI:2551", DrugName: "Ciprofloxacin", Frequency: "q12h"},
+		{OrderID: "o2", DrugCode: "RxCUI:1897", DrugName: "Calcium carbonate", Frequency: "q6h", PRN: true},
+		{OrderID: "o3", DrugCode: "RxCUI:24947", DrugName: "Ferrous sulfate", Frequency: "with lunch"},
+		{OrderID: "o4", DrugCode: "RxCUI:1897", Frequency: "daily", Status: models.OrderStatusDiscontinued},
+	}
+	options, err := resolveScheduleOptions(AdministrationScheduleRequest{MedicationOrders: orders})
+	require.NoError(t, err)
+	response := planAdministrationSchedule(rules, orders, options, func(code string) []string { return classes[code] })
+
+	assert.True(t, response.Feasible)
+	require.Len(t, response.Schedule, 3)
+	assert.Empty(t, response.Schedule[1].Times)
+	assert.Equal(t, []string{"When needed, give at least 6 h before or 2 h after Ciprofloxacin"}, response.Schedule[1].Instructions)
+	assert.Empty(t, response.Schedule[2].Times)
+	assert.Contains(t, response.Notes, `Ferrous sulfate: frequency "with lunch" not recognised; not scheduled`)
+
+	require.Len(t, response.Separations, 2)
+	assert.Equal(t, SeparationAsNeeded, response.Separations[0].Status)
+	assert.Equal(t, SeparationUnplaced, response.Separations[1].Status)
+}
+
+func TestResolveScheduleOptions(t *testing.T) {
+	options, err := resolveScheduleOptions(AdministrationScheduleRequest{MealTimes: []string{"19:00", "7:30"}, SleepTime: "23:00"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{450, 1140}, options.meals)
+	assert.Equal(t, 360, options.wake)
+	assert.Equal(t, 1380, options.sleep)
+
+	_, err = resolveScheduleOptions(AdministrationScheduleRequest{MealTimes: []string{"25:00"}})
+	var inputErr *ScheduleInputError
+	require.ErrorAs(t, err, &inputErr)
+	assert.Equal(t, "meal_times", inputErr.Field)
+
+	_, err = resolveScheduleOptions(AdministrationScheduleRequest{WakeTime: "07:00", SleepTime: "07:00"})
+	require.ErrorAs(t, err, &inputErr)
+}
+
+func TestValidateScheduleIntervals(t *testing.T) {
+	require.NoError(t, validateScheduleIntervals([]models.MedicationOrder{
+		{DrugCode: "RxCUI:2551", Frequency: "q12h"},
+		{DrugCode: "RxCUI:1897", IntervalHours: 0.5},
+		{DrugCode: "RxCUI:1897", IntervalHours: 0.001, PRN: true},
+		{DrugCode: "RxCUI:1897", IntervalHours: 0.001, Status: models.OrderStatusDiscontinued},
+	}))
+
+	err := validateScheduleIntervals([]models.MedicationOrder{
+		{DrugCode: "RxCUI:1897", DrugName: "Calcium carbonate", IntervalHours: 0.001},
+	})
+	var inputErr *ScheduleInputError
+	require.ErrorAs(t, err, &inputErr)
+	assert.Equal(t, "interval_hours", inputErr.Field)
+	assert.Contains(t, inputErr.Reason, "Calcium carbonate")
+}
+
+func TestSchedulePlanner_SkipsItemsWithoutCandidates(t *testing.T) {
+	items := []*scheduleItem{
+		{label: "Ciprofloxacin", scheduled: true, doses: 2, spacing: 720, candidates: []int{480}},
+		{label: "Calcium carbonate", scheduled: true, doses: 1, spacing: 1440},
+	}
+	planner := newSchedulePlanner(items, nil, scheduleOptions{})
+	assert.Equal(t, []int{0}, planner.order)
+
+	assert.NotPanics(t, planner.placeWithFewestConflicts)
+	assert.Equal(t, []int{480, 1200}, items[0].times)
+	assert.Nil(t, items[1].times)
+}
+
+func TestSeparationViolation(t *testing.T) {
+	// Calcium may not fall within (08:00 - 6 h, 08:00 + 2 h)
+	_, _, violated := separationViolation([]int{480}, []int{600}, 120, 360)
+	assert.False(t, violated)
+	_, _, violated = separationViolation([]int{480}, []int{570}, 120, 360)
+	assert.True(t, violated)
+	_, _, violated = separationViolation([]int{480}, []int{150}, 120, 360)
+	assert.True(t, violated)
+	// Around midnight: 23:00 is 9 h before 08:00
+	_, _, violated = separationViolation([]int{480}, []int{1380}, 120, 360)
+	assert.False(t, violated)
+	// Giving both together always violates
+	_, _, violated = separationViolation([]int{480}, []int{480}, 0, 60)
+	assert.True(t, violated)
+	assert.Equal(t, "30 min", formatSeparationHours(0.5))
+	assert.Equal(t, "1.5 h", formatSeparationHours(1.5))
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Allergy status values (PatientAllergy.Status)
+const (
+	AllergyStatusActive         = "active"
+	AllergyStatusInactive       = "inactive"
+	AllergyStatusResolved       = "resolved"
+	AllergyStatusRefuted        = "refuted"
+	AllergyStatusEnteredInError = "entered_in_error"
+)
+
+// AllergyAlertSuppressed is the alert level of a de-labeled allergy result
+const AllergyAlertSuppressed = "suppressed"
+
+// ToleratedExposure records a drug the patient has received without reaction,
+// e.g. a negative oral challenge or a completed therapeutic course
+type ToleratedExposure struct {
+	DrugCode string `json:"drug_code"`
+	DrugName string `json:"drug_name,omitempty"`
+	Date     string `json:"date,omitempty"`    // YYYY-MM-DD, YYYY-MM or YYYY
+	Context  string `json:"context,omitempty"` // challenge, therapeutic_course, skin_test
+}
+
+// applyDelabeling suppresses or downgrades a result for de-labeled allergies and
+// tolerated drugs. drugCode is the drug or product whose tolerance is checked.
+func applyDelabeling(result *AllergyCheckResult, drugCode string, allergy *PatientAllergy, tolerated []ToleratedExposure) {
+	level, reason := delabelAlertLevel(result.AlertLevel, drugCode, allergy, tolerated)
+	if reason == "" {
+		return
+	}
+	result.OriginalAlertLevel = result.AlertLevel
+	result.AlertLevel = level
+	result.DelabelReason = reason
+	if level == AllergyAlertSuppressed {
+		result.Suppressed = true
+		result.RequiresPharmacistReview = false
+		if result.Recommendation != "" {
+			result.Recommendation = AllergyRecommendProceed
+		}
+	}
+}
+
+// delabelAlertLevel applies allergy status and tolerance history to an alert
+// level. The reason is empty when the level is unchanged.
+func delabelAlertLevel(level, drugCode string, allergy *PatientAllergy, tolerated []ToleratedExposure) (string, string) {
+	if allergy == nil {
+		exposure, verified := findToleratedExposure(tolerated, drugCode, "", false)
+		if exposure == nil {
+			return level, ""
+		}
+		if verified {
+			return AllergyAlertSuppressed, toleranceReason(*exposure, "")
+		}
+		return downgradeAllergyAlertLevel(level), unverifiedToleranceReason(*exposure)
+	}
+
+	allergen := allergy.AllergenName
+	if allergen == "" {
+		allergen = allergy.AllergenCode
+	}
+
+	status := normalizeAllergyStatus(allergy.Status)
+	switch status {
+	case AllergyStatusRefuted:
+		return AllergyAlertSuppressed, fmt.Sprintf("Allergy to %s has been refuted (de-labeled)", allergen)
+	case AllergyStatusEnteredInError:
+		return AllergyAlertSuppressed, fmt.Sprintf("Allergy to %s was entered in error", allergen)
+	}
+
+	// The ordered drug itself was tolerated after the documented reaction
+	unverified, verified := findToleratedExposure(tolerated, drugCode, allergy.OnsetDate, true)
+	if verified {
+		return AllergyAlertSuppressed, toleranceReason(*unverified, "")
+	}
+	// The allergen was tolerated after the reaction, so the label is likely inaccurate
+	if !strings.EqualFold(normalizeATCDrugKey(drugCode), normalizeATCDrugKey(allergy.AllergenCode)) {
+		exposure, verified := findToleratedExposure(tolerated, allergy.AllergenCode, allergy.OnsetDate, true)
+		if verified {
+			return AllergyAlertSuppressed, toleranceReason(*exposure, "; the allergy label is likely inaccurate")
+		}
+		if unverified == nil {
+			unverified = exposure
+		}
+	}
+
+	if (status == AllergyStatusResolved || status == AllergyStatusInactive) && level != "low" && level != AllergyAlertSuppressed {
+		return "low", fmt.Sprintf("Allergy to %s is marked %s", allergen, status)
+	}
+	// Tolerance that cannot be placed after the reaction lowers the alert but never removes it
+	if unverified != nil {
+		return downgradeAllergyAlertLevel(level), unverifiedToleranceReason(*unverified)
+	}
+	return level, ""
+}
+
+// findToleratedExposure finds a tolerated exposure to a drug. The exposure is
+// verified when its date is known and on or after the allergy onset (any known
+// date when requireOnset is false); an unverified exposure is returned only when
+// no verified one exists. Exposures dated before the onset are ignored.
+func findToleratedExposure(tolerated []ToleratedExposure, drugCode, onsetDate string, requireOnset bool) (*ToleratedExposure, bool) {
+	key := normalizeATCDrugKey(drugCode)
+	onset, onsetKnown := parseClinicalDate(onsetDate)
+	var unverified *ToleratedExposure
+	for i, exposure := range tolerated {
+		if normalizeATCDrugKey(exposure.DrugCode) != key {
+			continue
+		}
+		date, dateKnown := parseClinicalDate(exposure.Date)
+		if dateKnown && onsetKnown && date.Before(onset) {
+			continue // Tolerated before the reaction; does not refute it
+		}
+		if dateKnown && (onsetKnown || !requireOnset) {
+			return &tolerated[i], true
+		}
+		if unverified == nil {
+			unverified = &tolerated[i]
+		}
+	}
+	return unverified, false
+}
+
+// downgradeAllergyAlertLevel lowers an alert level by one step, never below low
+func downgradeAllergyAlertLevel(level string) string {
+	switch level {
+	case "critical":
+		return "high"
+	case "high":
+		return "moderate"
+	}
+	return "low"
+}
+
+// unverifiedToleranceReason notes a tolerated exposure that cannot be placed
+// after the documented reaction
+func unverifiedToleranceReason(exposure ToleratedExposure) string {
+	missing := "the exposure date is unknown"
+	if _, ok := parseClinicalDate(exposure.Date); ok {
+		missing = "the reaction onset date is unknown"
+	}
+	return toleranceReason(exposure, "") + ", but " + missing + "; tolerance is unverified and the alert is kept"
+}
+
+// toleranceReason describes a tolerated exposure
+func toleranceReason(exposure ToleratedExposure, suffix string) string {
+	name := exposure.DrugName
+	if name == "" {
+		name = exposure.DrugCode
+	}
+	reason := fmt.Sprintf("Patient tolerated %s", name)
+	if exposure.Context != "" {
+		reason += fmt.Sprintf(" (%s)", strings.ReplaceAll(exposure.Context, "_", " "))
+	}
+	if exposure.Date != "" {
+		reason += fmt.Sprintf(" on %s", exposure.Date)
+	}
+	return reason + suffix
+}
+
+// normalizeAllergyStatus maps FHIR-style status values to allergy status constants
+func normalizeAllergyStatus(status string) string {
+	s := strings.ToLower(strings.TrimSpace(status))
+	s = strings.NewReplacer("-", "_", " ", "_").Replace(s)
+	switch s {
+	case "", AllergyStatusActive, "confirmed", "unconfirmed":
+		return AllergyStatusActive
+	case "error":
+		return AllergyStatusEnteredInError
+	}
+	return s
+}
+
+// parseClinicalDate parses a full or partial date
+func parseClinicalDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01", "2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
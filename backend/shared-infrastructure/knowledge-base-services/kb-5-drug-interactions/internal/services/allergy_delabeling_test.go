@@ -0,0 +1,121 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// ALLERGY DE-LABELING TESTS
+// ============================================================================
+
+func testPenicillinCefazolinRule() AllergyRule {
+	return AllergyRule{
+		AllergenCode:          "RXCUI:7984",
+		AllergenName:          "Penicillin",
+		CrossReactiveDrugCode: "RXCUI:2180",
+		CrossReactiveDrugName: "Cefazolin",
+		Severity:              models.SeverityMajor,
+	}
+}
+
+func TestDetermineAlertLevel_AllergyStatus(t *testing.T) {
+	ae := &AllergyEngine{}
+	rule := testPenicillinCefazolinRule()
+
+	level, reason := ae.determineAlertLevel(rule, &PatientAllergy{AllergenCode: "RXCUI:7984", AllergenName: "Penicillin"}, nil)
+	assert.Equal(t, "high", level)
+	assert.Empty(t, reason)
+
+	level, reason = ae.determineAlertLevel(rule, &PatientAllergy{AllergenCode: "RXCUI:7984", AllergenName: "Penicillin", Status: "refuted"}, nil)
+	assert.Equal(t, AllergyAlertSuppressed, level)
+	assert.Contains(t, reason, "refuted")
+
+	level, reason = ae.determineAlertLevel(rule, &PatientAllergy{AllergenCode: "RXCUI:7984", Status: "entered-in-error"}, nil)
+	assert.Equal(t, AllergyAlertSuppressed, level)
+	assert.Contains(t, reason, "entered in error")
+
+	level, reason = ae.determineAlertLevel(rule, &PatientAllergy{AllergenCode: "RXCUI:7984", AllergenName: "Penicillin", Status: "resolved"}, nil)
+	assert.Equal(t, "low", level, "resolved allergies are downgraded, not suppressed")
+	assert.Contains(t, reason, "resolved")
+}
+
+func TestDetermineAlertLevel_ToleratedExposure(t *testing.T) {
+	ae := &AllergyEngine{}
+	rule := testPenicillinCefazolinRule()
+	allergy := &PatientAllergy{AllergenCode: "RXCUI:7984", ReactionType: "anaphylaxis", OnsetDate: "2015-06-01"}
+
+	level, _ := ae.determineAlertLevel(rule, allergy, nil)
+	assert.Equal(t, "critical", level)
+
+	// Cefazolin tolerated after the reaction
+	tolerated := []ToleratedExposure{{DrugCode: "2180", DrugName: "Cefazolin", Date: "2024-03-10", Context: "therapeutic_course"}}
+	level, reason := ae.determineAlertLevel(rule, allergy, tolerated)
+	assert.Equal(t, AllergyAlertSuppressed, level)
+	assert.Equal(t, "Patient tolerated Cefazolin (therapeutic course) on 2024-03-10", reason)
+
+	// Tolerated before the reaction does not count
+	tolerated[0].Date = "2010"
+	level, reason = ae.determineAlertLevel(rule, allergy, tolerated)
+	assert.Equal(t, "critical", level)
+	assert.Empty(t, reason)
+
+	// A negative challenge to the allergen itself de-labels its cross-reactions
+	tolerated = []ToleratedExposure{{DrugCode: "RxCUI:7984", DrugName: "Penicillin V", Date: "2023-11", Context: "challenge"}}
+	level, reason = ae.determineAlertLevel(rule, allergy, tolerated)
+	assert.Equal(t, AllergyAlertSuppressed, level)
+	assert.Contains(t, reason, "label is likely inaccurate")
+}
+
+func TestDetermineAlertLevel_UnverifiedTolerance(t *testing.T) {
+	ae := &AllergyEngine{}
+	rule := testPenicillinCefazolinRule()
+	allergy := &PatientAllergy{AllergenCode: "RXCUI:7984", ReactionType: "anaphylaxis", OnsetDate: "2015-06-01"}
+
+	// An undated exposure cannot be placed after the reaction: downgraded, not suppressed
+	tolerated := []ToleratedExposure{{DrugCode: "2180", DrugName: "Cefazolin", Context: "challenge"}}
+	level, reason := ae.determineAlertLevel(rule, allergy, tolerated)
+	assert.Equal(t, "high", level)
+	assert.Contains(t, reason, "exposure date is unknown")
+
+	// Likewise when the reaction onset is unknown
+	allergy.OnsetDate = ""
+	tolerated[0].Date = "2024-03-10"
+	level, reason = ae.determineAlertLevel(rule, allergy, tolerated)
+	assert.Equal(t, "high", level)
+	assert.Contains(t, reason, "reaction onset date is unknown")
+
+	// A dated exposure after the onset still wins over an undated one
+	allergy.OnsetDate = "2015-06-01"
+	tolerated = append([]ToleratedExposure{{DrugCode: "RxCUI:7984", DrugName: "Penicillin V"}}, tolerated...)
+	level, _ = ae.determineAlertLevel(rule, allergy, tolerated)
+	assert.Equal(t, AllergyAlertSuppressed, level)
+
+	result := AllergyCheckResult{AlertLevel: "critical", RequiresPharmacistReview: true}
+	applyDelabeling(&result, "RxCUI:2180", nil, []ToleratedExposure{{DrugCode: "RxCUI:2180"}})
+	assert.False(t, result.Suppressed)
+	assert.Equal(t, "high", result.AlertLevel)
+	assert.Equal(t, "critical", result.OriginalAlertLevel)
+	assert.True(t, result.RequiresPharmacistReview)
+}
+
+func TestApplyDelabeling_SideChainResult(t *testing.T) {
+	allergy := PatientAllergy{AllergenCode: "RxCUI:723", AllergenName: "Amoxicillin", ReactionType: "urticaria", OnsetDate: "2019-05"}
+	amoxicillin := BetaLactamSideChain{DrugCode: "RxCUI:723", DrugName: "Amoxicillin", BetaLactamClass: BetaLactamPenicillin, R1Group: stringPtr("hydroxyaminobenzyl")}
+	cefadroxil := BetaLactamSideChain{DrugCode: "RxCUI:2176", DrugName: "Cefadroxil", BetaLactamClass: BetaLactamCephalosporin,
+		R1Group: stringPtr("hydroxyaminobenzyl"), R2Group: stringPtr("methyl")}
+	result := assessBetaLactamAllergy(amoxicillin, cefadroxil, allergy)
+	assert.Equal(t, AllergyRecommendAvoid, result.Recommendation)
+
+	applyDelabeling(&result, result.DrugCode, &allergy, []ToleratedExposure{{DrugCode: "RxCUI:723", Context: "challenge", Date: "2025-01-15"}})
+
+	assert.True(t, result.Suppressed)
+	assert.Equal(t, AllergyAlertSuppressed, result.AlertLevel)
+	assert.Equal(t, "high", result.OriginalAlertLevel)
+	assert.Equal(t, AllergyRecommendProceed, result.Recommendation)
+	assert.False(t, result.RequiresPharmacistReview)
+	assert.Contains(t, result.DelabelReason, "RxCUI:723 (challenge) on 2025-01-15")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"kb-drug-interactions/internal/database"
@@ -58,6 +59,14 @@ type AllergyCheckResult struct {
 	Confidence           float64             `json:"confidence"`
 	RequiresPharmacistReview bool            `json:"requires_pharmacist_review"`
 	AlertLevel           string              `json:"alert_level"` // critical, high, moderate, low
+	ReactionPhenotype    string              `json:"reaction_phenotype,omitempty"` // ige_mediated, benign_rash, severe_cutaneous, unknown
+	Recommendation       string              `json:"recommendation,omitempty"`     // avoid, test_dose, proceed
+	SideChainMatch       *SideChainMatch     `json:"side_chain_match,omitempty"`   // Beta-lactam side-chain assessment
+	Suppressed           bool                `json:"suppressed,omitempty"`         // De-labeled; not shown as an alert
+	OriginalAlertLevel   string              `json:"original_alert_level,omitempty"`
+	DelabelReason        string              `json:"delabel_reason,omitempty"`     // Why the alert was suppressed or downgraded
+	ProductCode          string              `json:"product_code,omitempty"`       // Specific product (excipient checks)
+	Excipient            string              `json:"excipient,omitempty"`          // Excipient that triggered the alert
 }
 
 // AllergyCheckRequest represents a request to check drug allergies
@@ -65,6 +74,8 @@ type AllergyCheckRequest struct {
 	DrugCodes        []string          `json:"drug_codes" binding:"required,min=1"`
 	PatientAllergies []PatientAllergy  `json:"patient_allergies" binding:"required,min=1"`
 	IncludePossible  bool              `json:"include_possible"` // Include possible (low probability) cross-reactions
+	ToleratedExposures []ToleratedExposure `json:"tolerated_exposures,omitempty"` // Drugs given without reaction
+	ProductCodes     []string          `json:"product_codes,omitempty"` // Specific products ordered (NDC/SPL), checked for excipients
 }
 
 // PatientAllergy represents a patient's documented allergy
@@ -76,6 +87,7 @@ type PatientAllergy struct {
 	Severity      string `json:"severity,omitempty"`        // mild, moderate, severe, life-threatening
 	OnsetDate     string `json:"onset_date,omitempty"`
 	Verified      bool   `json:"verified"`                  // Clinically verified allergy
+	Status        string `json:"status,omitempty"`          // active (default), inactive, resolved, refuted, entered_in_error
 }
 
 // CrossReactivityInfo provides detailed cross-reactivity information
@@ -95,6 +107,16 @@ type AllergyEngine struct {
 	ruleCache map[string][]AllergyRule
 	cacheTTL  time.Duration
 	lastLoad  time.Time
+
+	// Beta-lactam side chains keyed by normalized drug code
+	sideChains       map[string]BetaLactamSideChain
+	sideChainsLoaded time.Time
+	sideChainMu      sync.Mutex
+
+	// Excipients and the products that contain them
+	excipients       *excipientCatalog
+	excipientsLoaded time.Time
+	excipientMu      sync.Mutex
 }
 
 // NewAllergyEngine creates a new allergy cross-reactivity engine
@@ -107,14 +129,15 @@ func NewAllergyEngine(db *database.Database, metrics *metrics.Collector) *Allerg
 	}
 }
 
-// EvaluateAllergyRisk checks drugs against patient's documented allergies
+// EvaluateAllergyRisk checks drugs against patient's documented allergies. Notes
+// report checks that could not be performed.
 func (ae *AllergyEngine) EvaluateAllergyRisk(
 	ctx context.Context,
 	request AllergyCheckRequest,
 	datasetVersion string,
-) ([]AllergyCheckResult, error) {
+) ([]AllergyCheckResult, []string, error) {
 	if len(request.DrugCodes) == 0 || len(request.PatientAllergies) == 0 {
-		return []AllergyCheckResult{}, nil
+		return []AllergyCheckResult{}, nil, nil
 	}
 
 	timer := time.Now()
@@ -132,14 +155,55 @@ func (ae *AllergyEngine) EvaluateAllergyRisk(
 
 	rules, err := ae.loadAllergyRules(ctx, allergenCodes, datasetVersion)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load allergy rules: %w", err)
+		return nil, nil, fmt.Errorf("failed to load allergy rules: %w", err)
+	}
+
+	sideChains, err := ae.loadSideChains(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load beta-lactam side chains: %w", err)
+	}
+
+	// Excipient checks are supplementary; drug-level checks still run without them
+	var notes []string
+	catalog, err := ae.loadExcipientCatalog(ctx)
+	if err != nil {
+		notes = append(notes, fmt.Sprintf("Excipient allergy checks were not performed: failed to load excipient catalog: %v", err))
 	}
 
 	// Check each drug against each allergy rule
 	for _, drugCode := range request.DrugCodes {
 		normalizedDrug := strings.ToUpper(drugCode)
 
+		// Beta-lactam pairs use the side-chain model instead of flat rules
+		assessed := make(map[string]bool)
+		if drug, ok := sideChains[normalizeATCDrugKey(drugCode)]; ok {
+			for _, allergy := range request.PatientAllergies {
+				allergenKey := normalizeATCDrugKey(allergy.AllergenCode)
+				allergen, ok := sideChains[allergenKey]
+				if !ok || allergenKey == normalizeATCDrugKey(drugCode) {
+					continue
+				}
+				assessed[allergenKey] = true
+
+				result := assessBetaLactamAllergy(allergen, drug, allergy)
+				if result.Recommendation == AllergyRecommendProceed && !request.IncludePossible {
+					continue
+				}
+				applyDelabeling(&result, result.DrugCode, &allergy, request.ToleratedExposures)
+				if result.Recommendation != AllergyRecommendProceed {
+					result.AlternativeDrugs = betaLactamAlternatives(allergen, drug, sideChains)
+				}
+				results = append(results, result)
+				if !result.Suppressed {
+					ae.metrics.RecordAllergyInteraction(allergy.AllergenCode, normalizedDrug, string(result.Severity))
+				}
+			}
+		}
+
 		for _, rule := range rules {
+			if assessed[normalizeATCDrugKey(rule.AllergenCode)] {
+				continue
+			}
 			if ae.matchesDrug(rule, normalizedDrug) {
 				// Filter low probability cross-reactions if not requested
 				if !request.IncludePossible && rule.CrossReactivityRate != nil {
@@ -149,11 +213,13 @@ func (ae *AllergyEngine) EvaluateAllergyRisk(
 					}
 				}
 
-				result := ae.buildAllergyResult(rule, ae.findPatientAllergy(request.PatientAllergies, rule.AllergenCode))
+				result := ae.buildAllergyResult(rule, ae.findPatientAllergy(request.PatientAllergies, rule.AllergenCode), request.ToleratedExposures)
 				results = append(results, result)
 
 				// Record metric
-				ae.metrics.RecordAllergyInteraction(rule.AllergenCode, normalizedDrug, string(rule.Severity))
+				if !result.Suppressed {
+					ae.metrics.RecordAllergyInteraction(rule.AllergenCode, normalizedDrug, string(rule.Severity))
+				}
 			}
 		}
 
@@ -175,15 +241,24 @@ func (ae *AllergyEngine) EvaluateAllergyRisk(
 					RequiresPharmacistReview: true,
 					AlertLevel:           "critical",
 				}
+				applyDelabeling(&result, result.DrugCode, &allergy, request.ToleratedExposures)
 				results = append(results, result)
 			}
 		}
 	}
 
+	// Excipients in ordered products and in products of ordered drugs
+	for _, result := range evaluateExcipientAllergies(catalog, request) {
+		results = append(results, result)
+		if !result.Suppressed {
+			ae.metrics.RecordAllergyInteraction(result.AllergenCode, strings.ToUpper(result.DrugCode), string(result.Severity))
+		}
+	}
+
 	// Sort by alert level and severity
 	ae.sortByAlertLevel(results)
 
-	return results, nil
+	return results, notes, nil
 }
 
 // GetCrossReactivity returns cross-reactivity information for an allergen
@@ -246,7 +321,7 @@ func (ae *AllergyEngine) GetAllergyAlternatives(
 		IncludePossible:  false,
 	}
 
-	conflicts, err := ae.EvaluateAllergyRisk(ctx, checkRequest, datasetVersion)
+	conflicts, _, err := ae.EvaluateAllergyRisk(ctx, checkRequest, datasetVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -261,7 +336,7 @@ func (ae *AllergyEngine) GetAllergyAlternatives(
 				PatientAllergies: patientAllergies,
 				IncludePossible:  true,
 			}
-			altConflicts, _ := ae.EvaluateAllergyRisk(ctx, altCheckRequest, datasetVersion)
+			altConflicts, _, _ := ae.EvaluateAllergyRisk(ctx, altCheckRequest, datasetVersion)
 			if len(altConflicts) == 0 {
 				alternativesSet[alt] = true
 			}
@@ -289,7 +364,7 @@ func (ae *AllergyEngine) GetCommonCrossReactivities(
 			AllergenClass:       "Beta-lactam antibiotics",
 			RelatedDrugs:        []string{"Cephalexin", "Cefazolin", "Ceftriaxone", "Cefuroxime"},
 			CrossReactivityRate: 2.0, // ~2% cross-reactivity rate
-			ClinicalNote:        "Cross-reactivity between penicillins and cephalosporins is lower than historically believed (~2%). First-generation cephalosporins have slightly higher cross-reactivity with penicillins.",
+			ClinicalNote:        "Cross-reactivity between penicillins and cephalosporins is lower than historically believed (~2%) and is driven mainly by identical R1 side chains (e.g. amoxicillin/cefadroxil, ampicillin/cephalexin). Cephalosporins with dissimilar side chains (e.g. cefazolin) can usually be given.",
 		},
 		"penicillin-carbapenem": {
 			AllergenClass:       "Beta-lactam antibiotics",
@@ -387,7 +462,7 @@ func (ae *AllergyEngine) findPatientAllergy(allergies []PatientAllergy, allergen
 }
 
 // buildAllergyResult creates a result from an allergy rule
-func (ae *AllergyEngine) buildAllergyResult(rule AllergyRule, patientAllergy *PatientAllergy) AllergyCheckResult {
+func (ae *AllergyEngine) buildAllergyResult(rule AllergyRule, patientAllergy *PatientAllergy, tolerated []ToleratedExposure) AllergyCheckResult {
 	confidence := 0.80
 	if rule.Confidence != nil {
 		confidence, _ = rule.Confidence.Float64()
@@ -414,14 +489,27 @@ func (ae *AllergyEngine) buildAllergyResult(rule AllergyRule, patientAllergy *Pa
 		RequiresPharmacistReview: rule.Severity == models.SeverityContraindicated || rule.Severity == models.SeverityMajor,
 	}
 
-	// Determine alert level based on severity and cross-reactivity rate
-	result.AlertLevel = ae.determineAlertLevel(rule, patientAllergy)
+	// Determine alert level based on severity, cross-reactivity rate and de-labeling
+	result.AlertLevel, result.DelabelReason = ae.determineAlertLevel(rule, patientAllergy, tolerated)
+	if result.DelabelReason != "" {
+		result.OriginalAlertLevel = baseAllergyAlertLevel(rule, patientAllergy)
+		result.Suppressed = result.AlertLevel == AllergyAlertSuppressed
+		result.RequiresPharmacistReview = result.RequiresPharmacistReview && !result.Suppressed
+	}
 
 	return result
 }
 
-// determineAlertLevel determines the alert level based on multiple factors
-func (ae *AllergyEngine) determineAlertLevel(rule AllergyRule, patientAllergy *PatientAllergy) string {
+// determineAlertLevel determines the alert level based on multiple factors. Alerts
+// are suppressed or downgraded when the allergy has been de-labeled or the drug
+// has since been tolerated; the reason is returned alongside the level.
+func (ae *AllergyEngine) determineAlertLevel(rule AllergyRule, patientAllergy *PatientAllergy, tolerated []ToleratedExposure) (string, string) {
+	level := baseAllergyAlertLevel(rule, patientAllergy)
+	return delabelAlertLevel(level, rule.CrossReactiveDrugCode, patientAllergy, tolerated)
+}
+
+// baseAllergyAlertLevel determines the alert level from rule severity and reaction history
+func baseAllergyAlertLevel(rule AllergyRule, patientAllergy *PatientAllergy) string {
 	// Critical: Contraindicated or patient had severe reaction (anaphylaxis)
 	if rule.Severity == models.SeverityContraindicated {
 		return "critical"
@@ -454,6 +542,7 @@ func (ae *AllergyEngine) sortByAlertLevel(results []AllergyCheckResult) {
 		"high":     2,
 		"moderate": 3,
 		"low":      4,
+		AllergyAlertSuppressed: 5,
 	}
 
 	// Simple bubble sort for typical small result sets
@@ -470,4 +559,12 @@ func (ae *AllergyEngine) sortByAlertLevel(results []AllergyCheckResult) {
 func (ae *AllergyEngine) ClearCache() {
 	ae.ruleCache = make(map[string][]AllergyRule)
 	ae.lastLoad = time.Time{}
+
+	ae.sideChainMu.Lock()
+	ae.sideChains = nil
+	ae.sideChainMu.Unlock()
+
+	ae.excipientMu.Lock()
+	ae.excipients = nil
+	ae.excipientMu.Unlock()
 }
@@ -0,0 +1,445 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kb-drug-interactions/internal/database"
+	"kb-drug-interactions/internal/metrics"
+)
+
+// atcLevelLengths maps ATC hierarchy level to code length
+// Level 1: A (anatomical main group), 2: A10 (therapeutic), 3: A10B (pharmacological),
+// 4: A10BA (chemical subgroup), 5: A10BA02 (chemical substance)
+var atcLevelLengths = map[int]int{1: 1, 2: 3, 3: 4, 4: 5, 5: 7}
+
+// ATCConceptRow is an ATC class concept read from ohdsi_concept
+type ATCConceptRow struct {
+	ConceptCode string
+	ConceptName string
+}
+
+// ATCDrugMappingRow is an RxNorm drug to ATC class mapping read from ohdsi_concept_relationship
+type ATCDrugMappingRow struct {
+	DrugVocabularyID string
+	DrugCode         string
+	DrugName         string
+	ATCCode          string
+}
+
+// ATCIndexStats summarizes an ATC index build
+type ATCIndexStats struct {
+	ATCClasses  int       `json:"atc_classes"`
+	MappedDrugs int       `json:"mapped_drugs"`
+	Mappings    int       `json:"mappings"`
+	LoadedAt    time.Time `json:"loaded_at"`
+}
+
+// ATCClassIndex is an in-memory index of drug -> ATC class membership at all five
+// ATC levels, built from the OHDSI vocabulary ("RxNorm - ATC" relationships and
+// ATC concepts). Call Reload after the vocabulary tables are refreshed.
+type ATCClassIndex struct {
+	vocabularyDB *database.Database // Shared OHDSI vocabulary DB (optional)
+	metrics      *metrics.Collector
+
+	drugClasses map[string][]string // normalized drug code -> ATC codes (levels 1-5)
+	classNames  map[string]string   // ATC code -> class name
+	drugNames   map[string]string   // normalized drug code -> drug name
+	stats       ATCIndexStats
+	mu          sync.RWMutex
+}
+
+// NewATCClassIndex creates an empty ATC index. vocabularyDB may be nil, in which
+// case the index stays empty and drugs resolve through the built-in class map.
+func NewATCClassIndex(vocabularyDB *database.Database, metrics *metrics.Collector) *ATCClassIndex {
+	return &ATCClassIndex{
+		vocabularyDB: vocabularyDB,
+		metrics:      metrics,
+		drugClasses:  make(map[string][]string),
+		classNames:   make(map[string]string),
+		drugNames:    make(map[string]string),
+	}
+}
+
+// Reload rebuilds the index from the vocabulary tables. The previous index stays
+// in service until the new one is complete.
+func (idx *ATCClassIndex) Reload(ctx context.Context) (*ATCIndexStats, error) {
+	if idx.vocabularyDB == nil {
+		return nil, fmt.Errorf("vocabulary database not configured")
+	}
+
+	timer := time.Now()
+
+	var concepts []ATCConceptRow
+	err := idx.vocabularyDB.DB.WithContext(ctx).Raw(`
+		SELECT concept_code, concept_name
+		FROM ohdsi_concept
+		WHERE vocabulary_id = 'ATC'
+		  AND invalid_reason IS NULL`).
+		Scan(&concepts).Error
+	if err != nil {
+		idx.metrics.RecordDatabaseQuery("atc_index_load", "ohdsi_concept", "error", time.Since(timer))
+		return nil, fmt.Errorf("failed to load ATC concepts: %w", err)
+	}
+
+	// Mappings are published in both directions; either one is sufficient
+	var mappings []ATCDrugMappingRow
+	err = idx.vocabularyDB.DB.WithContext(ctx).Raw(`
+		SELECT d.vocabulary_id AS drug_vocabulary_id,
+		       d.concept_code  AS drug_code,
+		       d.concept_name  AS drug_name,
+		       a.concept_code  AS atc_code
+		FROM ohdsi_concept_relationship r
+		JOIN ohdsi_concept d ON d.concept_id = r.concept_id_1
+		JOIN ohdsi_concept a ON a.concept_id = r.concept_id_2
+		WHERE r.relationship_id = 'RxNorm - ATC'
+		  AND r.invalid_reason IS NULL
+		  AND a.vocabulary_id = 'ATC'
+		UNION
+		SELECT d.vocabulary_id, d.concept_code, d.concept_name, a.concept_code
+		FROM ohdsi_concept_relationship r
+		JOIN ohdsi_concept a ON a.concept_id = r.concept_id_1
+		JOIN ohdsi_concept d ON d.concept_id = r.concept_id_2
+		WHERE r.relationship_id = 'ATC - RxNorm'
+		  AND r.invalid_reason IS NULL
+		  AND a.vocabulary_id = 'ATC'`).
+		Scan(&mappings).Error
+	if err != nil {
+		idx.metrics.RecordDatabaseQuery("atc_index_load", "ohdsi_concept_relationship", "error", time.Since(timer))
+		return nil, fmt.Errorf("failed to load RxNorm-ATC mappings: %w", err)
+	}
+
+	stats := idx.Build(concepts, mappings)
+	idx.metrics.RecordDatabaseQuery("atc_index_load", "ohdsi_concept_relationship", "success", time.Since(timer))
+
+	return stats, nil
+}
+
+// Build replaces the index contents with the given concepts and mappings.
+// Each mapped ATC code is expanded to all of its ancestor levels.
+func (idx *ATCClassIndex) Build(concepts []ATCConceptRow, mappings []ATCDrugMappingRow) *ATCIndexStats {
+	classNames := make(map[string]string, len(concepts))
+	for _, c := range concepts {
+		code := NormalizeATCCode(c.ConceptCode)
+		if code != "" {
+			classNames[code] = c.ConceptName
+		}
+	}
+
+	classSets := make(map[string]map[string]bool)
+	drugNames := make(map[string]string)
+	for _, m := range mappings {
+		drugKey := normalizeATCDrugKey(formatConceptDrugCode(m.DrugVocabularyID, m.DrugCode))
+		atcCode := NormalizeATCCode(m.ATCCode)
+		if drugKey == "" || atcCode == "" {
+			continue
+		}
+
+		if classSets[drugKey] == nil {
+			classSets[drugKey] = make(map[string]bool)
+		}
+		for _, code := range ATCAncestorCodes(atcCode) {
+			classSets[drugKey][code] = true
+		}
+		if m.DrugName != "" {
+			drugNames[drugKey] = m.DrugName
+		}
+	}
+
+	drugClasses := make(map[string][]string, len(classSets))
+	for drugKey, set := range classSets {
+		codes := make([]string, 0, len(set))
+		for code := range set {
+			codes = append(codes, code)
+		}
+		// Most specific first, then alphabetical
+		sort.Slice(codes, func(i, j int) bool {
+			if len(codes[i]) != len(codes[j]) {
+				return len(codes[i]) > len(codes[j])
+			}
+			return codes[i] < codes[j]
+		})
+		drugClasses[drugKey] = codes
+	}
+
+	stats := ATCIndexStats{
+		ATCClasses:  len(classNames),
+		MappedDrugs: len(drugClasses),
+		Mappings:    len(mappings),
+		LoadedAt:    time.Now().UTC(),
+	}
+
+	idx.mu.Lock()
+	idx.drugClasses = drugClasses
+	idx.classNames = classNames
+	idx.drugNames = drugNames
+	idx.stats = stats
+	idx.mu.Unlock()
+
+	return &stats
+}
+
+// Loaded reports whether the index holds vocabulary mappings. Until it does,
+// lookups use the built-in class map.
+func (idx *ATCClassIndex) Loaded() bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.drugClasses) > 0
+}
+
+// ClassesForDrug returns the drug's ATC codes at every level, most specific first
+func (idx *ATCClassIndex) ClassesForDrug(drugCode string) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if len(idx.drugClasses) == 0 {
+		return staticATCClassesForDrug(drugCode)
+	}
+
+	classes := idx.drugClasses[normalizeATCDrugKey(drugCode)]
+	result := make([]string, len(classes))
+	copy(result, classes)
+	return result
+}
+
+// ClassName returns the vocabulary name for an ATC code
+func (idx *ATCClassIndex) ClassName(atcCode string) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if len(idx.classNames) == 0 {
+		name, exists := staticATCClassNames[NormalizeATCCode(atcCode)]
+		return name, exists
+	}
+
+	name, exists := idx.classNames[NormalizeATCCode(atcCode)]
+	return name, exists
+}
+
+// DrugName returns the vocabulary name for a mapped drug code
+func (idx *ATCClassIndex) DrugName(drugCode string) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if len(idx.drugNames) == 0 {
+		name, exists := staticATCDrugNames[normalizeATCDrugKey(drugCode)]
+		return name, exists
+	}
+
+	name, exists := idx.drugNames[normalizeATCDrugKey(drugCode)]
+	return name, exists
+}
+
+// Stats returns the statistics of the last build
+func (idx *ATCClassIndex) Stats() ATCIndexStats {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.stats
+}
+
+// NormalizeATCCode upper-cases an ATC code and strips an "ATC:" prefix
+func NormalizeATCCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.TrimPrefix(code, "ATC:")
+}
+
+// ATCLevel returns the ATC hierarchy level (1-5) of a code, or 0 if the length is invalid
+func ATCLevel(atcCode string) int {
+	length := len(NormalizeATCCode(atcCode))
+	for level, levelLength := range atcLevelLengths {
+		if levelLength == length {
+			return level
+		}
+	}
+	return 0
+}
+
+// TruncateATCCode truncates an ATC code to the specified level
+func TruncateATCCode(atcCode string, level int) string {
+	targetLen, exists := atcLevelLengths[level]
+	if !exists {
+		return atcCode
+	}
+
+	if len(atcCode) >= targetLen {
+		return atcCode[:targetLen]
+	}
+	return atcCode
+}
+
+// ATCAncestorCodes returns the code and its ancestors, most specific first
+// (e.g. "C09AA01" -> ["C09AA01", "C09AA", "C09A", "C09", "C"])
+func ATCAncestorCodes(atcCode string) []string {
+	atcCode = NormalizeATCCode(atcCode)
+	level := ATCLevel(atcCode)
+	if level == 0 {
+		return []string{atcCode}
+	}
+
+	codes := make([]string, 0, level)
+	for l := level; l >= 1; l-- {
+		codes = append(codes, TruncateATCCode(atcCode, l))
+	}
+	return codes
+}
+
+// ATCParentCode returns the parent class code, or "" for anatomical main groups
+func ATCParentCode(atcCode string) string {
+	level := ATCLevel(atcCode)
+	if level <= 1 {
+		return ""
+	}
+	return TruncateATCCode(NormalizeATCCode(atcCode), level-1)
+}
+
+// normalizeATCDrugKey upper-cases drug codes so "RxCUI:1998" and "RXCUI:1998" match;
+// bare numeric codes are treated as RxCUIs
+func normalizeATCDrugKey(drugCode string) string {
+	key := strings.ToUpper(strings.TrimSpace(drugCode))
+	if isAllDigits(key) {
+		key = "RXCUI:" + key
+	}
+	return key
+}
+
+// regimenDrug is a regimen drug with its ATC codes at every level
+type regimenDrug struct {
+	Code    string
+	Key     string
+	Classes []string
+}
+
+// regimenDrugMatches reports whether a drug is listed by code or belongs to a listed ATC class
+func regimenDrugMatches(drug regimenDrug, codes, classes []string) bool {
+	for _, code := range codes {
+		if normalizeATCDrugKey(code) == drug.Key {
+			return true
+		}
+	}
+	for _, class := range classes {
+		class = NormalizeATCCode(class)
+		for _, drugClass := range drug.Classes {
+			if drugClass == class {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// staticATCDrugClasses is the built-in drug -> ATC substance map used when the
+// vocabulary is not available
+var staticATCDrugClasses = map[string]string{
+	// Cardiovascular drugs
+	"RXCUI:1998":   "C09AA01", // Lisinopril - ACE inhibitor
+	"RXCUI:29046":  "C09AA02", // Enalapril - ACE inhibitor
+	"RXCUI:214354": "C09CA01", // Losartan - ARB
+	"RXCUI:69749":  "C07AB02", // Metoprolol - Beta blocker
+	"RXCUI:149":    "C07AB03", // Atenolol - Beta blocker
+	"RXCUI:4603":   "C03AA03", // Hydrochlorothiazide - Diuretic
+	"RXCUI:38413":  "C03CA01", // Furosemide - Loop diuretic
+	"RXCUI:11289":  "B01AA03", // Warfarin - Anticoagulant
+	"RXCUI:32968":  "C07AG02", // Carvedilol - Alpha/beta blocker
+
+	// NSAIDs
+	"RXCUI:5640":   "M01AE01", // Ibuprofen - NSAID
+	"RXCUI:7258":   "M01AE02", // Naproxen - NSAID
+	"RXCUI:2670":   "M01AE07", // Diclofenac - NSAID
+	"RXCUI:140587": "M01AH01", // Celecoxib - COX-2 inhibitor
+
+	// Antidepressants
+	"RXCUI:32937": "N06AB06", // Sertraline - SSRI
+	"RXCUI:36437": "N06AB10", // Escitalopram - SSRI
+	"RXCUI:30203": "N06AB05", // Paroxetine - SSRI
+	"RXCUI:31565": "N06AB04", // Citalopram - SSRI
+	"RXCUI:5781":  "N06AF03", // Phenelzine - MAOI
+
+	// Anticoagulants/Antiplatelets
+	"RXCUI:1154343": "B01AC04", // Clopidogrel - Antiplatelet
+	"RXCUI:1246289": "B01AC22", // Prasugrel - Antiplatelet
+	"RXCUI:855332":  "B01AC05", // Aspirin - Antiplatelet
+
+	// Statins
+	"RXCUI:36567":  "C10AA01", // Simvastatin - HMG CoA reductase inhibitor
+	"RXCUI:83367":  "C10AA05", // Atorvastatin - HMG CoA reductase inhibitor
+	"RXCUI:42463":  "C10AA03", // Pravastatin - HMG CoA reductase inhibitor
+	"RXCUI:446503": "C10AA07", // Rosuvastatin - HMG CoA reductase inhibitor
+}
+
+// staticATCClassNames names the classes of the built-in map
+var staticATCClassNames = map[string]string{
+	// Cardiovascular
+	"C09AA": "ACE Inhibitors",
+	"C09CA": "Angiotensin II Receptor Blockers",
+	"C07AB": "Beta Blocking Agents",
+	"C03AA": "Thiazide Diuretics",
+	"C03CA": "Loop Diuretics",
+	"B01AA": "Vitamin K Antagonists",
+	"B01AC": "Platelet Aggregation Inhibitors",
+
+	// Anti-inflammatory
+	"M01AE": "Propionic Acid Derivatives (NSAIDs)",
+	"M01AH": "COX-2 Inhibitors",
+	"M01A":  "Anti-inflammatory and Antirheumatic Products",
+
+	// CNS
+	"N06AB": "Selective Serotonin Reuptake Inhibitors",
+	"N06AF": "Monoamine Oxidase Inhibitors",
+	"N05BA": "Benzodiazepine Derivatives",
+	"N02AA": "Natural Opium Alkaloids",
+
+	// Lipid modifying
+	"C10AA": "HMG CoA Reductase Inhibitors",
+}
+
+// staticATCDrugNames names the drugs of the built-in map
+var staticATCDrugNames = map[string]string{
+	// Cardiovascular drugs
+	"RXCUI:1998":   "Lisinopril",
+	"RXCUI:29046":  "Enalapril",
+	"RXCUI:214354": "Losartan",
+	"RXCUI:69749":  "Metoprolol",
+	"RXCUI:149":    "Atenolol",
+	"RXCUI:4603":   "Hydrochlorothiazide",
+	"RXCUI:38413":  "Furosemide",
+	"RXCUI:11289":  "Warfarin",
+	"RXCUI:32968":  "Carvedilol",
+
+	// NSAIDs
+	"RXCUI:5640":   "Ibuprofen",
+	"RXCUI:7258":   "Naproxen",
+	"RXCUI:2670":   "Diclofenac",
+	"RXCUI:140587": "Celecoxib",
+
+	// Antidepressants
+	"RXCUI:32937": "Sertraline",
+	"RXCUI:36437": "Escitalopram",
+	"RXCUI:30203": "Paroxetine",
+	"RXCUI:31565": "Citalopram",
+	"RXCUI:5781":  "Phenelzine",
+
+	// Anticoagulants/Antiplatelets
+	"RXCUI:1154343": "Clopidogrel",
+	"RXCUI:1246289": "Prasugrel",
+	"RXCUI:855332":  "Aspirin",
+
+	// Statins
+	"RXCUI:36567":  "Simvastatin",
+	"RXCUI:83367":  "Atorvastatin",
+	"RXCUI:42463":  "Pravastatin",
+	"RXCUI:446503": "Rosuvastatin",
+}
+
+// staticATCClassesForDrug returns the built-in map's classes for a drug at every
+// level, most specific first
+func staticATCClassesForDrug(drugCode string) []string {
+	atcCode, exists := staticATCDrugClasses[normalizeATCDrugKey(drugCode)]
+	if !exists {
+		return []string{}
+	}
+	return ATCAncestorCodes(atcCode)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ============================================================================
+// ATC CLASS INDEX TESTS
+// ============================================================================
+
+func newTestATCIndex() *ATCClassIndex {
+	idx := NewATCClassIndex(nil, nil)
+	idx.Build(
+		[]ATCConceptRow{
+			{ConceptCode: "C", ConceptName: "CARDIOVASCULAR SYSTEM"},
+			{ConceptCode: "C09", ConceptName: "AGENTS ACTING ON THE RENIN-ANGIOTENSIN SYSTEM"},
+			{ConceptCode: "C09A", ConceptName: "ACE INHIBITORS, PLAIN"},
+			{ConceptCode: "C09AA", ConceptName: "ACE inhibitors, plain"},
+			{ConceptCode: "C09AA03", ConceptName: "lisinopril"},
+			{ConceptCode: "C09AA05", ConceptName: "ramipril"},
+			{ConceptCode: "M01AE", ConceptName: "Propionic acid derivatives"},
+		},
+		[]ATCDrugMappingRow{
+			{DrugVocabularyID: "RxNorm", DrugCode: "29046", DrugName: "lisinopril", ATCCode: "C09AA03"},
+			{DrugVocabularyID: "RxNorm", DrugCode: "35296", DrugName: "ramipril", ATCCode: "C09AA05"},
+			{DrugVocabularyID: "RxNorm", DrugCode: "5640", DrugName: "ibuprofen", ATCCode: "M01AE01"},
+		},
+	)
+	return idx
+}
+
+func TestATCClassIndex_ClassesAtAllLevels(t *testing.T) {
+	idx := newTestATCIndex()
+
+	classes := idx.ClassesForDrug("RxCUI:29046")
+	assert.Equal(t, []string{"C09AA03", "C09AA", "C09A", "C09", "C"}, classes)
+
+	// Drug code lookups are case-insensitive and accept bare RxCUIs
+	assert.Equal(t, classes, idx.ClassesForDrug("RXCUI:29046"))
+	assert.Equal(t, classes, idx.ClassesForDrug("29046"))
+
+	assert.Empty(t, idx.ClassesForDrug("RxCUI:999999"))
+
+	stats := idx.Stats()
+	assert.Equal(t, 7, stats.ATCClasses)
+	assert.Equal(t, 3, stats.MappedDrugs)
+}
+
+func TestATCClassIndex_Names(t *testing.T) {
+	idx := newTestATCIndex()
+
+	name, ok := idx.ClassName("ATC:C09AA")
+	assert.True(t, ok)
+	assert.Equal(t, "ACE inhibitors, plain", name)
+
+	name, ok = idx.DrugName("RxCUI:5640")
+	assert.True(t, ok)
+	assert.Equal(t, "ibuprofen", name)
+}
+
+func TestATCHierarchyHelpers(t *testing.T) {
+	assert.Equal(t, []string{"M01AE01", "M01AE", "M01A", "M01", "M"}, ATCAncestorCodes("atc:m01ae01"))
+	assert.Equal(t, 5, ATCLevel("M01AE01"))
+	assert.Equal(t, 4, ATCLevel("ATC:M01AE"))
+	assert.Equal(t, 0, ATCLevel("M01AE0"))
+	assert.Equal(t, "M01A", ATCParentCode("M01AE"))
+	assert.Equal(t, "", ATCParentCode("M"))
+	assert.Equal(t, "C09A", TruncateATCCode("C09AA03", 3))
+}
+
+func TestClassInteractionEngine_VocabularyResolution(t *testing.T) {
+	cie := &ClassInteractionEngine{atcIndex: newTestATCIndex()}
+
+	assert.Equal(t, "ACE inhibitors, plain", cie.getClassOrDrugName("ATC:C09AA", "class"))
+	assert.Equal(t, "lisinopril", cie.getClassOrDrugName("RxCUI:29046", "drug"))
+	assert.Equal(t, "RxCUI:1", cie.getClassOrDrugName("RxCUI:1", "drug"))
+
+	drugToClasses := map[string][]string{"RxCUI:29046": cie.atcIndex.ClassesForDrug("RxCUI:29046")}
+	assert.True(t, cie.hasDrugsInClass(drugToClasses, "ATC:C09A"))
+	assert.False(t, cie.hasDrugsInClass(drugToClasses, "ATC:M01A"))
+}
+
+func TestDuplicateTherapyEngine_VocabularyClassesGroupAtEachLevel(t *testing.T) {
+	dte := &DuplicateTherapyEngine{atcIndex: newTestATCIndex()}
+
+	var classes []DrugTherapeuticMapping
+	classes = append(classes, dte.vocabularyTherapeuticClasses("RXCUI:29046", "2025Q4")...)
+	classes = append(classes, dte.vocabularyTherapeuticClasses("RXCUI:35296", "2025Q4")...)
+	assert.Len(t, classes, 2)
+	assert.Equal(t, "C09AA03", classes[0].ATCCode)
+	assert.Equal(t, 5, classes[0].ATCLevel)
+	assert.Equal(t, "ACE inhibitors, plain", classes[0].TherapeuticClass)
+
+	// Two ACE inhibitors share a chemical subgroup but not a substance
+	assert.Len(t, dte.groupByTherapeuticClass(classes, 4)["C09AA"], 2)
+	assert.Len(t, dte.groupByTherapeuticClass(classes, 3)["C09A"], 2)
+	assert.Len(t, dte.groupByTherapeuticClass(classes, 5)["C09AA03"], 1)
+}
+
+func TestATCClassIndex_StaticFallbackWithoutVocabulary(t *testing.T) {
+	idx := NewATCClassIndex(nil, nil)
+	assert.False(t, idx.Loaded())
+
+	// Without vocabulary mappings the built-in class map applies at every level
+	assert.Equal(t, []string{"M01AE01", "M01AE", "M01A", "M01", "M"}, idx.ClassesForDrug("RxCUI:5640"))
+	name, ok := idx.ClassName("M01AE")
+	assert.True(t, ok)
+	assert.Equal(t, "Propionic Acid Derivatives (NSAIDs)", name)
+	assert.Empty(t, idx.ClassesForDrug("RxCUI:999999"))
+	name, ok = idx.DrugName("RxCUI:11289")
+	assert.True(t, ok)
+	assert.Equal(t, "Warfarin", name)
+
+	// Once loaded, only the vocabulary is used
+	loaded := newTestATCIndex()
+	assert.True(t, loaded.Loaded())
+	assert.Empty(t, loaded.ClassesForDrug("RxCUI:11289"))
+
+	cie := &ClassInteractionEngine{}
+	assert.Equal(t, "HMG CoA Reductase Inhibitors", cie.getATCClassName("C10AA"))
+	assert.Equal(t, "Lisinopril", cie.getDrugName("RxCUI:1998"))
+}
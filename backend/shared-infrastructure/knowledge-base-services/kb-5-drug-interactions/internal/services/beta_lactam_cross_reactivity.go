@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// Beta-lactam classes (beta_lactam_side_chains.beta_lactam_class)
+const (
+	BetaLactamPenicillin    = "penicillin"
+	BetaLactamCephalosporin = "cephalosporin"
+	BetaLactamCarbapenem    = "carbapenem"
+	BetaLactamMonobactam    = "monobactam"
+)
+
+// Reaction phenotypes of a documented allergy
+const (
+	ReactionPhenotypeIgE     = "ige_mediated"     // Anaphylaxis, urticaria, angioedema, bronchospasm
+	ReactionPhenotypeBenign  = "benign_rash"      // Maculopapular rash, GI upset, other low-risk histories
+	ReactionPhenotypeSCAR    = "severe_cutaneous" // SJS/TEN, DRESS, AGEP
+	ReactionPhenotypeUnknown = "unknown"
+)
+
+// Allergy recommendations for a cross-reactive drug
+const (
+	AllergyRecommendAvoid    = "avoid"
+	AllergyRecommendTestDose = "test_dose"
+	AllergyRecommendProceed  = "proceed"
+)
+
+// Beta-lactam relationships between an allergen and an ordered drug, with the
+// approximate cross-reactivity rates (%) used for each
+const (
+	betaLactamSharedR1   = "side_chain_r1"
+	betaLactamSameCore   = "beta_lactam_core"
+	betaLactamSharedR2   = "side_chain_r2"
+	betaLactamCarbapenem = "carbapenem"
+	betaLactamDissimilar = "dissimilar_side_chains"
+)
+
+var betaLactamCrossReactivityRates = map[string]float64{
+	betaLactamSharedR1:   15.0,
+	betaLactamSameCore:   10.0,
+	betaLactamSharedR2:   3.0,
+	betaLactamCarbapenem: 1.0,
+	betaLactamDissimilar: 0.5,
+}
+
+// BetaLactamSideChain gives a beta-lactam's class and R1/R2 side-chain groups
+type BetaLactamSideChain struct {
+	ID              int     `json:"id" gorm:"primaryKey"`
+	DrugCode        string  `json:"drug_code"`
+	DrugName        string  `json:"drug_name"`
+	BetaLactamClass string  `json:"beta_lactam_class"`
+	R1Group         *string `json:"r1_group,omitempty" gorm:"column:r1_group"`
+	R2Group         *string `json:"r2_group,omitempty" gorm:"column:r2_group"`
+	Source          string  `json:"source"`
+	Active          bool    `json:"active"`
+}
+
+// TableName specifies the database table for GORM
+func (BetaLactamSideChain) TableName() string {
+	return "beta_lactam_side_chains"
+}
+
+// SideChainMatch explains a beta-lactam cross-reactivity assessment
+type SideChainMatch struct {
+	AllergenClass string `json:"allergen_class"`
+	DrugClass     string `json:"drug_class"`
+	Relationship  string `json:"relationship"`
+	SharedR1      string `json:"shared_r1,omitempty"`
+	SharedR2      string `json:"shared_r2,omitempty"`
+	Explanation   string `json:"explanation"`
+}
+
+// ClassifyReactionPhenotype maps a documented reaction to a reaction phenotype
+func ClassifyReactionPhenotype(reactionType string) string {
+	fields := strings.FieldsFunc(strings.ToLower(reactionType), func(r rune) bool { return r < 'a' || r > 'z' })
+	text := " " + strings.Join(fields, " ") + " "
+	hasAny := func(terms ...string) bool {
+		for _, term := range terms {
+			if strings.Contains(text, " "+term+" ") {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case hasAny("sjs", "ten", "dress", "agep", "scar", "stevens johnson", "epidermal necrolysis", "exfoliative", "blistering"):
+		return ReactionPhenotypeSCAR
+	case hasAny("anaphylaxis", "anaphylactic", "urticaria", "hives", "angioedema", "bronchospasm", "wheezing", "hypotension", "laryngeal", "ige"):
+		return ReactionPhenotypeIgE
+	case hasAny("rash", "maculopapular", "exanthem", "pruritus", "itching", "nausea", "vomiting", "diarrhea", "headache", "gi", "intolerance"):
+		return ReactionPhenotypeBenign
+	}
+	return ReactionPhenotypeUnknown
+}
+
+// betaLactamRelationship finds how an ordered drug relates to an allergen beta-lactam
+func betaLactamRelationship(allergen, drug BetaLactamSideChain) (string, *SideChainMatch) {
+	match := &SideChainMatch{
+		AllergenClass: allergen.BetaLactamClass,
+		DrugClass:     drug.BetaLactamClass,
+	}
+	if sameSideChain(allergen.R1Group, drug.R1Group) {
+		match.SharedR1 = *drug.R1Group
+	}
+	if sameSideChain(allergen.R2Group, drug.R2Group) {
+		match.SharedR2 = *drug.R2Group
+	}
+
+	switch {
+	case match.SharedR1 != "":
+		match.Relationship = betaLactamSharedR1
+		match.Explanation = fmt.Sprintf("%s and %s share an identical R1 side chain (%s)",
+			allergen.DrugName, drug.DrugName, sideChainLabel(match.SharedR1))
+	case allergen.BetaLactamClass == drug.BetaLactamClass &&
+		(drug.BetaLactamClass == BetaLactamPenicillin || drug.BetaLactamClass == BetaLactamCarbapenem):
+		match.Relationship = betaLactamSameCore
+		match.Explanation = fmt.Sprintf("%s and %s are both %ss and share the same core ring structure",
+			allergen.DrugName, drug.DrugName, drug.BetaLactamClass)
+	case match.SharedR2 != "":
+		match.Relationship = betaLactamSharedR2
+		match.Explanation = fmt.Sprintf("%s and %s share an identical R2 side chain (%s); R1 side chains differ",
+			allergen.DrugName, drug.DrugName, sideChainLabel(match.SharedR2))
+	case allergen.BetaLactamClass == BetaLactamCarbapenem || drug.BetaLactamClass == BetaLactamCarbapenem:
+		match.Relationship = betaLactamCarbapenem
+		match.Explanation = fmt.Sprintf("%s and %s share no side chains; carbapenem cross-reactivity is about 1%%",
+			allergen.DrugName, drug.DrugName)
+	default:
+		match.Relationship = betaLactamDissimilar
+		match.Explanation = fmt.Sprintf("%s and %s share no R1 or R2 side chains", allergen.DrugName, drug.DrugName)
+	}
+	return match.Relationship, match
+}
+
+// assessBetaLactamAllergy builds the allergy result for a beta-lactam ordered in a
+// patient allergic to another beta-lactam. The reaction phenotype sets the
+// recommendation: shared R1 or core avoids for IgE/unknown histories and
+// test-doses for benign rash; severe cutaneous reactions avoid all beta-lactams.
+func assessBetaLactamAllergy(allergen, drug BetaLactamSideChain, allergy PatientAllergy) AllergyCheckResult {
+	relationship, match := betaLactamRelationship(allergen, drug)
+	phenotype := ClassifyReactionPhenotype(allergy.ReactionType)
+	anaphylaxis := strings.Contains(strings.ToLower(allergy.ReactionType), "anaphyla") ||
+		strings.EqualFold(allergy.Severity, "life-threatening")
+	closelyRelated := relationship == betaLactamSharedR1 || relationship == betaLactamSameCore
+
+	recommendation := AllergyRecommendProceed
+	switch phenotype {
+	case ReactionPhenotypeSCAR:
+		recommendation = AllergyRecommendAvoid
+	case ReactionPhenotypeIgE, ReactionPhenotypeUnknown:
+		switch {
+		case closelyRelated:
+			recommendation = AllergyRecommendAvoid
+		case relationship == betaLactamSharedR2 || anaphylaxis:
+			recommendation = AllergyRecommendTestDose
+		}
+	case ReactionPhenotypeBenign:
+		if closelyRelated {
+			recommendation = AllergyRecommendTestDose
+		}
+	}
+
+	result := AllergyCheckResult{
+		AllergenCode:        allergy.AllergenCode,
+		AllergenName:        allergen.DrugName,
+		DrugCode:            drug.DrugCode,
+		DrugName:            drug.DrugName,
+		CrossReactivityType: relationship,
+		CrossReactivityRate: betaLactamCrossReactivityRates[relationship],
+		ReactionType:        allergy.ReactionType,
+		ReactionPhenotype:   phenotype,
+		Recommendation:      recommendation,
+		SideChainMatch:      match,
+		Evidence:            models.EvidenceLevelA,
+		Confidence:          0.85,
+	}
+
+	switch recommendation {
+	case AllergyRecommendAvoid:
+		result.Severity = models.SeverityMajor
+		result.AlertLevel = "high"
+		if phenotype == ReactionPhenotypeSCAR || anaphylaxis {
+			result.Severity = models.SeverityContraindicated
+			result.AlertLevel = "critical"
+		}
+		result.RequiresPharmacistReview = true
+		if phenotype == ReactionPhenotypeSCAR {
+			result.ClinicalGuidance = fmt.Sprintf("%s. Prior severe cutaneous reaction: avoid all beta-lactams pending allergy specialist evaluation; do not test dose.", match.Explanation)
+		} else {
+			result.ClinicalGuidance = fmt.Sprintf("%s. Avoid; use a beta-lactam with dissimilar side chains or a non-beta-lactam alternative.", match.Explanation)
+		}
+	case AllergyRecommendTestDose:
+		result.Severity = models.SeverityModerate
+		result.AlertLevel = "moderate"
+		result.ClinicalGuidance = fmt.Sprintf("%s. May be given via a graded challenge (test dose) with monitoring.", match.Explanation)
+	default:
+		result.Severity = models.SeverityMinor
+		result.AlertLevel = "low"
+		result.ClinicalGuidance = fmt.Sprintf("%s. Cross-reactivity is low (about %g%%); may be given without a test dose.",
+			match.Explanation, betaLactamCrossReactivityRates[relationship])
+	}
+
+	return result
+}
+
+// betaLactamAlternatives lists drugs of the ordered drug's class that share no side
+// chain or core with the allergen
+func betaLactamAlternatives(allergen, drug BetaLactamSideChain, sideChains map[string]BetaLactamSideChain) []string {
+	var alternatives []string
+	for _, candidate := range sideChains {
+		if candidate.BetaLactamClass != drug.BetaLactamClass || candidate.DrugCode == drug.DrugCode ||
+			candidate.DrugCode == allergen.DrugCode {
+			continue
+		}
+		relationship, _ := betaLactamRelationship(allergen, candidate)
+		if relationship == betaLactamDissimilar || relationship == betaLactamCarbapenem {
+			alternatives = append(alternatives, candidate.DrugName)
+		}
+	}
+	sort.Strings(alternatives)
+	return alternatives[:minInt(len(alternatives), 5)]
+}
+
+// sameSideChain reports whether two side-chain groups are identical
+func sameSideChain(a, b *string) bool {
+	return a != nil && b != nil && *a != "" && strings.EqualFold(*a, *b)
+}
+
+// sideChainLabel formats a side-chain group for display
+func sideChainLabel(group string) string {
+	return strings.ReplaceAll(strings.ToLower(group), "_", " ")
+}
+
+// loadSideChains returns active beta-lactam side chains keyed by normalized drug code
+func (ae *AllergyEngine) loadSideChains(ctx context.Context) (map[string]BetaLactamSideChain, error) {
+	ae.sideChainMu.Lock()
+	defer ae.sideChainMu.Unlock()
+
+	if ae.sideChains != nil && time.Since(ae.sideChainsLoaded) < ae.cacheTTL {
+		return ae.sideChains, nil
+	}
+
+	var rows []BetaLactamSideChain
+	err := ae.db.DB.WithContext(ctx).
+		Where("active = TRUE").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sideChains := make(map[string]BetaLactamSideChain, len(rows))
+	for _, row := range rows {
+		sideChains[normalizeATCDrugKey(row.DrugCode)] = row
+	}
+
+	ae.sideChains = sideChains
+	ae.sideChainsLoaded = time.Now()
+
+	return sideChains, nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// BETA-LACTAM SIDE-CHAIN CROSS-REACTIVITY TESTS
+// ============================================================================
+
+func TestClassifyReactionPhenotype(t *testing.T) {
+	tests := map[string]string{
+		"anaphylaxis":                 ReactionPhenotypeIgE,
+		"Hives and throat swelling":   ReactionPhenotypeIgE,
+		"urticaria":                   ReactionPhenotypeIgE,
+		"Stevens-Johnson syndrome":    ReactionPhenotypeSCAR,
+		"SJS/TEN":                     ReactionPhenotypeSCAR,
+		"DRESS":                       ReactionPhenotypeSCAR,
+		"maculopapular rash":          ReactionPhenotypeBenign,
+		"GI upset":                    ReactionPhenotypeBenign,
+		"":                            ReactionPhenotypeUnknown,
+		"childhood reaction, unknown": ReactionPhenotypeUnknown,
+		"rash that was often itchy":   ReactionPhenotypeBenign, // "often" must not match TEN
+	}
+	for input, expected := range tests {
+		assert.Equal(t, expected, ClassifyReactionPhenotype(input), input)
+	}
+}
+
+func TestAssessBetaLactamAllergy_SharedR1(t *testing.T) {
+	amoxicillin := BetaLactamSideChain{DrugCode: "RxCUI:723", DrugName: "Amoxicillin", BetaLactamClass: BetaLactamPenicillin, R1Group: stringPtr("hydroxyaminobenzyl")}
+	cefadroxil := BetaLactamSideChain{DrugCode: "RxCUI:2176", DrugName: "Cefadroxil", BetaLactamClass: BetaLactamCephalosporin,
+		R1Group: stringPtr("hydroxyaminobenzyl"), R2Group: stringPtr("methyl")}
+
+	allergy := PatientAllergy{AllergenCode: "RxCUI:723", ReactionType: "urticaria"}
+	result := assessBetaLactamAllergy(amoxicillin, cefadroxil, allergy)
+
+	assert.Equal(t, AllergyRecommendAvoid, result.Recommendation)
+	assert.Equal(t, "high", result.AlertLevel)
+	assert.Equal(t, ReactionPhenotypeIgE, result.ReactionPhenotype)
+	require.NotNil(t, result.SideChainMatch)
+	assert.Equal(t, "hydroxyaminobenzyl", result.SideChainMatch.SharedR1)
+	assert.Contains(t, result.ClinicalGuidance, "identical R1 side chain")
+
+	// Benign rash history with the same shared R1 only needs a test dose
+	allergy.ReactionType = "maculopapular rash"
+	result = assessBetaLactamAllergy(amoxicillin, cefadroxil, allergy)
+	assert.Equal(t, AllergyRecommendTestDose, result.Recommendation)
+	assert.Equal(t, "moderate", result.AlertLevel)
+}
+
+func TestAssessBetaLactamAllergy_DissimilarSideChains(t *testing.T) {
+	amoxicillin := BetaLactamSideChain{DrugCode: "RxCUI:723", DrugName: "Amoxicillin", BetaLactamClass: BetaLactamPenicillin, R1Group: stringPtr("hydroxyaminobenzyl")}
+	cefazolin := BetaLactamSideChain{DrugCode: "RxCUI:2180", DrugName: "Cefazolin", BetaLactamClass: BetaLactamCephalosporin,
+		R1Group: stringPtr("tetrazolylmethyl"), R2Group: stringPtr("methylthiadiazolylthiomethyl")}
+	meropenem := BetaLactamSideChain{DrugCode: "RxCUI:29561", DrugName: "Meropenem", BetaLactamClass: BetaLactamCarbapenem}
+
+	result := assessBetaLactamAllergy(amoxicillin, cefazolin,
+		PatientAllergy{AllergenCode: "RxCUI:723", ReactionType: "hives"})
+	assert.Equal(t, AllergyRecommendProceed, result.Recommendation, "cefazolin shares no side chain with amoxicillin")
+	assert.Equal(t, "low", result.AlertLevel)
+	assert.Equal(t, betaLactamDissimilar, result.CrossReactivityType)
+
+	// Anaphylaxis history keeps a test dose even with dissimilar side chains
+	result = assessBetaLactamAllergy(amoxicillin, cefazolin,
+		PatientAllergy{AllergenCode: "RxCUI:723", ReactionType: "anaphylaxis"})
+	assert.Equal(t, AllergyRecommendTestDose, result.Recommendation)
+
+	result = assessBetaLactamAllergy(amoxicillin, meropenem,
+		PatientAllergy{AllergenCode: "RxCUI:723", ReactionType: "urticaria"})
+	assert.Equal(t, betaLactamCarbapenem, result.CrossReactivityType)
+	assert.Equal(t, AllergyRecommendProceed, result.Recommendation)
+}
+
+func TestAssessBetaLactamAllergy_PenicillinCore(t *testing.T) {
+	amoxicillin := BetaLactamSideChain{DrugCode: "RxCUI:723", DrugName: "Amoxicillin", BetaLactamClass: BetaLactamPenicillin, R1Group: stringPtr("hydroxyaminobenzyl")}
+	ampicillin := BetaLactamSideChain{DrugCode: "RxCUI:733", DrugName: "Ampicillin", BetaLactamClass: BetaLactamPenicillin, R1Group: stringPtr("aminobenzyl")}
+
+	result := assessBetaLactamAllergy(amoxicillin, ampicillin,
+		PatientAllergy{AllergenCode: "RxCUI:723", ReactionType: "angioedema"})
+	assert.Equal(t, betaLactamSameCore, result.CrossReactivityType)
+	assert.Equal(t, AllergyRecommendAvoid, result.Recommendation)
+}
+
+func TestAssessBetaLactamAllergy_AztreonamCeftazidime(t *testing.T) {
+	ceftazidime := BetaLactamSideChain{DrugCode: "RxCUI:2191", DrugName: "Ceftazidime", BetaLactamClass: BetaLactamCephalosporin,
+		R1Group: stringPtr("carboxypropyloxyimino_aminothiazolyl")}
+	aztreonam := BetaLactamSideChain{DrugCode: "RxCUI:1272", DrugName: "Aztreonam", BetaLactamClass: BetaLactamMonobactam,
+		R1Group: stringPtr("carboxypropyloxyimino_aminothiazolyl")}
+	amoxicillin := BetaLactamSideChain{DrugCode: "RxCUI:723", DrugName: "Amoxicillin", BetaLactamClass: BetaLactamPenicillin, R1Group: stringPtr("hydroxyaminobenzyl")}
+
+	result := assessBetaLactamAllergy(ceftazidime, aztreonam,
+		PatientAllergy{AllergenCode: "RxCUI:2191", ReactionType: "anaphylaxis"})
+	assert.Equal(t, betaLactamSharedR1, result.CrossReactivityType)
+	assert.Equal(t, AllergyRecommendAvoid, result.Recommendation)
+	assert.Equal(t, "critical", result.AlertLevel)
+	assert.Equal(t, models.SeverityContraindicated, result.Severity)
+
+	result = assessBetaLactamAllergy(amoxicillin, aztreonam,
+		PatientAllergy{AllergenCode: "RxCUI:723", ReactionType: "urticaria"})
+	assert.Equal(t, AllergyRecommendProceed, result.Recommendation, "aztreonam is safe with penicillin allergy")
+}
+
+func TestAssessBetaLactamAllergy_SevereCutaneous(t *testing.T) {
+	amoxicillin := BetaLactamSideChain{DrugCode: "RxCUI:723", DrugName: "Amoxicillin", BetaLactamClass: BetaLactamPenicillin, R1Group: stringPtr("hydroxyaminobenzyl")}
+	meropenem := BetaLactamSideChain{DrugCode: "RxCUI:29561", DrugName: "Meropenem", BetaLactamClass: BetaLactamCarbapenem}
+
+	result := assessBetaLactamAllergy(amoxicillin, meropenem,
+		PatientAllergy{AllergenCode: "RxCUI:723", ReactionType: "Stevens-Johnson syndrome"})
+	assert.Equal(t, AllergyRecommendAvoid, result.Recommendation)
+	assert.Equal(t, "critical", result.AlertLevel)
+	assert.Contains(t, result.ClinicalGuidance, "do not test dose")
+}
+
+func TestBetaLactamAlternatives(t *testing.T) {
+	sideChains := map[string]BetaLactamSideChain{
+		"RXCUI:723":  {DrugCode: "RxCUI:723", DrugName: "Amoxicillin", BetaLactamClass: BetaLactamPenicillin, R1Group: stringPtr("hydroxyaminobenzyl")},
+		"RXCUI:2176": {DrugCode: "RxCUI:2176", DrugName: "Cefadroxil", BetaLactamClass: BetaLactamCephalosporin, R1Group: stringPtr("hydroxyaminobenzyl"), R2Group: stringPtr("methyl")},
+		"RXCUI:2231": {DrugCode: "RxCUI:2231", DrugName: "Cephalexin", BetaLactamClass: BetaLactamCephalosporin, R1Group: stringPtr("aminobenzyl"), R2Group: stringPtr("methyl")},
+		"RXCUI:2180": {DrugCode: "RxCUI:2180", DrugName: "Cefazolin", BetaLactamClass: BetaLactamCephalosporin, R1Group: stringPtr("tetrazolylmethyl"), R2Group: stringPtr("methylthiadiazolylthiomethyl")},
+		"RXCUI:2193": {DrugCode: "RxCUI:2193", DrugName: "Ceftriaxone", BetaLactamClass: BetaLactamCephalosporin, R1Group: stringPtr("methoxyimino_aminothiazolyl")},
+	}
+
+	alternatives := betaLactamAlternatives(sideChains["RXCUI:723"], sideChains["RXCUI:2176"], sideChains)
+
+	assert.Contains(t, alternatives, "Cefazolin")
+	assert.Contains(t, alternatives, "Ceftriaxone")
+	assert.NotContains(t, alternatives, "Cefadroxil")
+	assert.Contains(t, alternatives, "Cephalexin", "cephalexin's aminobenzyl R1 differs from amoxicillin's")
+}
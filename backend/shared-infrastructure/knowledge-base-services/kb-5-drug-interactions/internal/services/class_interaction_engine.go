@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
 
 
 	"kb-drug-interactions/internal/database"
@@ -18,19 +20,24 @@ import (
 type ClassInteractionEngine struct {
 	db              *database.Database
 	metrics         *metrics.Collector
+	logger          *zap.Logger
+	atcIndex        *ATCClassIndex // Vocabulary-driven drug -> ATC class resolution
 	
 	// Cache for drug class mappings and rules
 	drugClassCache  map[string][]string // drug_code -> ATC classes
 	classRuleCache  map[string][]models.DDIClassRule
 	cacheTTL        time.Duration
 	lastCacheUpdate time.Time
+	cacheMutex      sync.RWMutex
 }
 
 // NewClassInteractionEngine creates a new class interaction evaluation engine
-func NewClassInteractionEngine(db *database.Database, metrics *metrics.Collector) *ClassInteractionEngine {
+func NewClassInteractionEngine(db *database.Database, atcIndex *ATCClassIndex, metrics *metrics.Collector, logger *zap.Logger) *ClassInteractionEngine {
 	return &ClassInteractionEngine{
 		db:              db,
 		metrics:         metrics,
+		logger:          logger,
+		atcIndex:        atcIndex,
 		drugClassCache:  make(map[string][]string),
 		classRuleCache:  make(map[string][]models.DDIClassRule),
 		cacheTTL:        15 * time.Minute,
@@ -83,9 +90,6 @@ func (cie *ClassInteractionEngine) GetDrugTherapeuticClasses(
 	ctx context.Context,
 	drugCode string,
 ) ([]TherapeuticClass, error) {
-	// TODO: Integrate with KB-7 Terminology service for comprehensive class mapping
-	// For now, use embedded ATC classification logic
-	
 	classes := cie.mapDrugToATCClasses(drugCode)
 	
 	var therapeuticClasses []TherapeuticClass
@@ -95,6 +99,7 @@ func (cie *ClassInteractionEngine) GetDrugTherapeuticClasses(
 			Name:        cie.getATCClassName(classCode),
 			Level:       cie.getATCLevel(classCode),
 			Description: cie.getATCDescription(classCode),
+			ParentCode:  ATCParentCode(classCode),
 		}
 		therapeuticClasses = append(therapeuticClasses, class)
 	}
@@ -181,71 +186,75 @@ func (cie *ClassInteractionEngine) resolveDrugClasses(
 	
 	for _, drugCode := range drugCodes {
 		// Check cache first
-		if classes, exists := cie.drugClassCache[drugCode]; exists && 
-			time.Since(cie.lastCacheUpdate) < cie.cacheTTL {
+		cie.cacheMutex.RLock()
+		classes, exists := cie.drugClassCache[drugCode]
+		fresh := time.Since(cie.lastCacheUpdate) < cie.cacheTTL
+		cie.cacheMutex.RUnlock()
+		if exists && fresh {
 			drugToClasses[drugCode] = classes
 			continue
 		}
 		
 		// Map drug to ATC classes
-		classes := cie.mapDrugToATCClasses(drugCode)
+		classes = cie.mapDrugToATCClasses(drugCode)
 		drugToClasses[drugCode] = classes
 		
 		// Update cache
+		cie.cacheMutex.Lock()
 		cie.drugClassCache[drugCode] = classes
+		cie.cacheMutex.Unlock()
 	}
 	
+	cie.cacheMutex.Lock()
 	cie.lastCacheUpdate = time.Now()
+	cie.cacheMutex.Unlock()
 	return drugToClasses, nil
 }
 
+// mapDrugToATCClasses resolves a drug to its ATC classes at all five levels
+// (e.g. lisinopril -> C09AA03, C09AA, C09A, C09, C) using the vocabulary index,
+// or the built-in class map when the vocabulary is not loaded
 func (cie *ClassInteractionEngine) mapDrugToATCClasses(drugCode string) []string {
-	// TODO: Replace with KB-7 Terminology service integration
-	// Static mapping for common drugs (temporary implementation)
-	
-	drugClassMapping := map[string][]string{
-		// Cardiovascular drugs
-		"RxCUI:1998":    {"C09AA01", "C09AA"},     // Lisinopril - ACE inhibitor
-		"RxCUI:29046":   {"C09AA02", "C09AA"},     // Enalapril - ACE inhibitor
-		"RxCUI:214354":  {"C09CA01", "C09CA"},     // Losartan - ARB
-		"RxCUI:69749":   {"C07AB02", "C07AB"},     // Metoprolol - Beta blocker
-		"RxCUI:149":     {"C07AB03", "C07AB"},     // Atenolol - Beta blocker
-		"RxCUI:4603":    {"C03AA03", "C03AA"},     // Hydrochlorothiazide - Diuretic
-		"RxCUI:38413":   {"C03CA01", "C03CA"},     // Furosemide - Loop diuretic
-		"RxCUI:11289":   {"B01AA03", "B01AA"},     // Warfarin - Anticoagulant
-		"RxCUI:32968":   {"C07AG02", "C07AG"},     // Carvedilol - Alpha/beta blocker
-		
-		// NSAIDs
-		"RxCUI:5640":    {"M01AE01", "M01AE"},     // Ibuprofen - NSAID
-		"RxCUI:7258":    {"M01AE02", "M01AE"},     // Naproxen - NSAID
-		"RxCUI:2670":    {"M01AE07", "M01AE"},     // Diclofenac - NSAID
-		"RxCUI:140587":  {"M01AH01", "M01AH"},     // Celecoxib - COX-2 inhibitor
-
-		// Antidepressants
-		"RxCUI:32937":   {"N06AB06", "N06AB"},     // Sertraline - SSRI
-		"RxCUI:36437":   {"N06AB10", "N06AB"},     // Escitalopram - SSRI
-		"RxCUI:30203":   {"N06AB05", "N06AB"},     // Paroxetine - SSRI (changed from duplicate 5640)
-		"RxCUI:31565":   {"N06AB04", "N06AB"},     // Citalopram - SSRI
-		"RxCUI:5781":    {"N06AF03", "N06AF"},     // Phenelzine - MAOI
-		
-		// Anticoagulants/Antiplatelets
-		"RxCUI:1154343": {"B01AC04", "B01AC"},     // Clopidogrel - Antiplatelet
-		"RxCUI:1246289": {"B01AC22", "B01AC"},     // Prasugrel - Antiplatelet  
-		"RxCUI:855332":  {"B01AC05", "B01AC"},     // Aspirin - Antiplatelet
-		
-		// Statins
-		"RxCUI:36567":   {"C10AA01", "C10AA"},     // Simvastatin - HMG CoA reductase inhibitor
-		"RxCUI:83367":   {"C10AA05", "C10AA"},     // Atorvastatin - HMG CoA reductase inhibitor
-		"RxCUI:42463":   {"C10AA03", "C10AA"},     // Pravastatin - HMG CoA reductase inhibitor
-		"RxCUI:446503":  {"C10AA07", "C10AA"},     // Rosuvastatin - HMG CoA reductase inhibitor
+	source := "vocabulary"
+	var classes []string
+	if cie.atcIndex != nil && cie.atcIndex.Loaded() {
+		classes = cie.atcIndex.ClassesForDrug(drugCode)
+	} else {
+		source = "static"
+		classes = staticATCClassesForDrug(drugCode)
+	}
+
+	if len(classes) == 0 {
+		// Class rules cannot fire for this drug - make the gap visible
+		cie.metrics.RecordATCClassResolution(source, "unresolved")
+		cie.logger.Debug("No ATC classes found for drug - class interaction rules not evaluated",
+			zap.String("drug_code", drugCode), zap.String("source", source))
+		return classes
 	}
 
-	if classes, exists := drugClassMapping[drugCode]; exists {
-		return classes
+	cie.metrics.RecordATCClassResolution(source, "resolved")
+	return classes
+}
+
+// ReloadATCIndex rebuilds the ATC class index from the vocabulary and drops
+// cached drug class resolutions
+func (cie *ClassInteractionEngine) ReloadATCIndex(ctx context.Context) (*ATCIndexStats, error) {
+	if cie.atcIndex == nil {
+		return nil, fmt.Errorf("ATC class index not configured")
 	}
-	
-	// If no specific mapping, try to infer from RxCUI patterns (basic fallback)
-	return []string{}
+
+	stats, err := cie.atcIndex.Reload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cie.cacheMutex.Lock()
+	cie.drugClassCache = make(map[string][]string)
+	cie.classRuleCache = make(map[string][]models.DDIClassRule)
+	cie.lastCacheUpdate = time.Time{}
+	cie.cacheMutex.Unlock()
+
+	return stats, nil
 }
 
 func (cie *ClassInteractionEngine) loadClassRules(
@@ -256,9 +265,21 @@ func (cie *ClassInteractionEngine) loadClassRules(
 ) ([]models.DDIClassRule, error) {
 	// Check cache first
 	cacheKey := fmt.Sprintf("%s:%v:%v", datasetVersion, drugCodes, classCodes)
-	if rules, exists := cie.classRuleCache[cacheKey]; exists && 
-		time.Since(cie.lastCacheUpdate) < cie.cacheTTL {
-		return rules, nil
+	cie.cacheMutex.RLock()
+	cached, exists := cie.classRuleCache[cacheKey]
+	fresh := time.Since(cie.lastCacheUpdate) < cie.cacheTTL
+	cie.cacheMutex.RUnlock()
+	if exists && fresh {
+		return cached, nil
+	}
+
+	// Class rules may store codes bare ("C09AA") or prefixed ("ATC:C09AA")
+	ruleClassCodes := make([]string, 0, len(classCodes)*2)
+	for _, code := range classCodes {
+		ruleClassCodes = append(ruleClassCodes, code, "ATC:"+code)
+	}
+	if len(ruleClassCodes) == 0 {
+		ruleClassCodes = []string{""}
 	}
 
 	var rules []models.DDIClassRule
@@ -270,7 +291,7 @@ func (cie *ClassInteractionEngine) loadClassRules(
 		) AND (
 			(subject_type = 'drug' AND subject_code IN ?) OR
 			(subject_type = 'class' AND subject_code IN ?)
-		)`, datasetVersion, drugCodes, classCodes, drugCodes, classCodes).
+		)`, datasetVersion, drugCodes, ruleClassCodes, drugCodes, ruleClassCodes).
 		Order("severity DESC, confidence DESC").
 		Find(&rules).Error
 
@@ -279,7 +300,9 @@ func (cie *ClassInteractionEngine) loadClassRules(
 	}
 
 	// Update cache
+	cie.cacheMutex.Lock()
 	cie.classRuleCache[cacheKey] = rules
+	cie.cacheMutex.Unlock()
 
 	return rules, nil
 }
@@ -320,7 +343,7 @@ func (cie *ClassInteractionEngine) evaluateDrugToClassInteractions(
 	for _, rule := range rules {
 		// Drug-to-class interactions
 		if rule.ObjectType == "drug" && rule.SubjectType == "class" {
-			if cie.containsString(drugCodes, rule.ObjectCode) && cie.containsString(classCodes, rule.SubjectCode) {
+			if cie.containsString(drugCodes, rule.ObjectCode) && cie.containsString(classCodes, NormalizeATCCode(rule.SubjectCode)) {
 				interaction := cie.convertClassRuleToInteraction(rule, "drug_to_class")
 				interactions = append(interactions, interaction)
 			}
@@ -328,7 +351,7 @@ func (cie *ClassInteractionEngine) evaluateDrugToClassInteractions(
 		
 		// Class-to-drug interactions  
 		if rule.ObjectType == "class" && rule.SubjectType == "drug" {
-			if cie.containsString(classCodes, rule.ObjectCode) && cie.containsString(drugCodes, rule.SubjectCode) {
+			if cie.containsString(classCodes, NormalizeATCCode(rule.ObjectCode)) && cie.containsString(drugCodes, rule.SubjectCode) {
 				interaction := cie.convertClassRuleToInteraction(rule, "class_to_drug")
 				interactions = append(interactions, interaction)
 			}
@@ -493,6 +516,7 @@ func (cie *ClassInteractionEngine) getAllUniqueClasses(drugToClasses map[string]
 }
 
 func (cie *ClassInteractionEngine) hasDrugsInClass(drugToClasses map[string][]string, targetClass string) bool {
+	targetClass = NormalizeATCCode(targetClass)
 	for _, classes := range drugToClasses {
 		for _, class := range classes {
 			if class == targetClass || strings.HasPrefix(class, targetClass) {
@@ -516,37 +540,15 @@ func (cie *ClassInteractionEngine) getClassOrDrugName(code, codeType string) str
 	if codeType == "class" {
 		return cie.getATCClassName(code)
 	}
-	return cie.getDrugName(code) // TODO: Integrate with KB-7 Terminology
+	return cie.getDrugName(code)
 }
 
 func (cie *ClassInteractionEngine) getATCClassName(atcCode string) string {
-	// ATC classification names (subset for common classes)
-	atcNames := map[string]string{
-		// Cardiovascular
-		"C09AA": "ACE Inhibitors",
-		"C09CA": "Angiotensin II Receptor Blockers",
-		"C07AB": "Beta Blocking Agents",
-		"C03AA": "Thiazide Diuretics",
-		"C03CA": "Loop Diuretics",
-		"B01AA": "Vitamin K Antagonists",
-		"B01AC": "Platelet Aggregation Inhibitors",
-		
-		// Anti-inflammatory
-		"M01AE": "Propionic Acid Derivatives (NSAIDs)",
-		"M01AH": "COX-2 Inhibitors",
-		"M01A":  "Anti-inflammatory and Antirheumatic Products",
-		
-		// CNS
-		"N06AB": "Selective Serotonin Reuptake Inhibitors",
-		"N06AF": "Monoamine Oxidase Inhibitors",
-		"N05BA": "Benzodiazepine Derivatives",
-		"N02AA": "Natural Opium Alkaloids",
-		
-		// Lipid modifying
-		"C10AA": "HMG CoA Reductase Inhibitors",
-	}
-
-	if name, exists := atcNames[atcCode]; exists {
+	if cie.atcIndex != nil {
+		if name, exists := cie.atcIndex.ClassName(atcCode); exists {
+			return name
+		}
+	} else if name, exists := staticATCClassNames[NormalizeATCCode(atcCode)]; exists {
 		return name
 	}
 	
@@ -555,7 +557,7 @@ func (cie *ClassInteractionEngine) getATCClassName(atcCode string) string {
 
 func (cie *ClassInteractionEngine) getATCLevel(atcCode string) int {
 	// ATC hierarchy levels: 1 (anatomical) -> 5 (chemical substance)
-	return len(atcCode)
+	return ATCLevel(atcCode)
 }
 
 func (cie *ClassInteractionEngine) getATCDescription(atcCode string) string {
@@ -579,23 +581,11 @@ func (cie *ClassInteractionEngine) getATCDescription(atcCode string) string {
 }
 
 func (cie *ClassInteractionEngine) getDrugName(drugCode string) string {
-	// TODO: Replace with KB-7 Terminology service integration
-	drugNames := map[string]string{
-		"RxCUI:1998":    "Lisinopril",
-		"RxCUI:29046":   "Enalapril", 
-		"RxCUI:214354":  "Losartan",
-		"RxCUI:69749":   "Metoprolol",
-		"RxCUI:149":     "Atenolol",
-		"RxCUI:4603":    "Hydrochlorothiazide",
-		"RxCUI:38413":   "Furosemide",
-		"RxCUI:11289":   "Warfarin",
-		"RxCUI:5640":    "Ibuprofen",
-		"RxCUI:32937":   "Sertraline",
-		"RxCUI:1154343": "Clopidogrel",
-		"RxCUI:36567":   "Simvastatin",
-	}
-
-	if name, exists := drugNames[drugCode]; exists {
+	if cie.atcIndex != nil {
+		if name, exists := cie.atcIndex.DrugName(drugCode); exists {
+			return name
+		}
+	} else if name, exists := staticATCDrugNames[normalizeATCDrugKey(drugCode)]; exists {
 		return name
 	}
 	
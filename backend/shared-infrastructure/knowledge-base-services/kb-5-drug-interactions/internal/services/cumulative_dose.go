@@ -0,0 +1,639 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kb-drug-interactions/internal/database"
+	"kb-drug-interactions/internal/metrics"
+	"kb-drug-interactions/internal/models"
+)
+
+// Cumulative dose result scopes
+const (
+	CumulativeScopeIngredient = "ingredient"
+	CumulativeScopeGroup      = "group" // Ingredients summed as a fraction of each one's maximum
+)
+
+// IngredientDoseLimit is the maximum daily dose of an ingredient for a patient context
+type IngredientDoseLimit struct {
+	ID               int                `json:"id" gorm:"primaryKey"`
+	LimitCode        string             `json:"limit_code"`
+	IngredientCode   string             `json:"ingredient_code"`
+	IngredientName   string             `json:"ingredient_name"`
+	CumulativeGroup  *string            `json:"cumulative_group,omitempty"`
+	MinAgeYears      *int               `json:"min_age_years,omitempty"` // Inclusive
+	MaxAgeYears      *int               `json:"max_age_years,omitempty"` // Exclusive
+	RenalMetric      *string            `json:"renal_metric,omitempty"`
+	RenalBelow       *float64           `json:"renal_below,omitempty"` // mL/min, exclusive
+	ChildPughClasses models.StringArray `json:"child_pugh_classes,omitempty" gorm:"type:text[]"`
+	DoseUnit         string             `json:"dose_unit"`
+	MaxDailyDose     float64            `json:"max_daily_dose"`
+	Rationale        string             `json:"rationale"`
+	Source           string             `json:"source"`
+	Active           bool               `json:"active"`
+}
+
+// TableName specifies the database table for GORM
+func (IngredientDoseLimit) TableName() string {
+	return "ingredient_daily_dose_limits"
+}
+
+// ProductIngredient is the strength of one active ingredient of a combination product
+type ProductIngredient struct {
+	ID             int     `json:"id" gorm:"primaryKey"`
+	ProductCode    string  `json:"product_code"`
+	ProductName    string  `json:"product_name"`
+	DoseFormUnit   string  `json:"dose_form_unit"` // "tablet", "capsule", "mL"
+	IngredientCode string  `json:"ingredient_code"`
+	IngredientName string  `json:"ingredient_name"`
+	Strength       float64 `json:"strength"` // Per dose form unit
+	StrengthUnit   string  `json:"strength_unit"`
+	Active         bool    `json:"active"`
+}
+
+// TableName specifies the database table for GORM
+func (ProductIngredient) TableName() string {
+	return "product_active_ingredients"
+}
+
+// CumulativeDosePatient is the age and organ function used to select daily dose limits
+type CumulativeDosePatient struct {
+	AgeYears *int                 `json:"age_years,omitempty"`
+	Function PatientOrganFunction `json:"function"`
+}
+
+// CumulativeDoseRequest checks the total daily dose of each ingredient across a regimen's orders
+type CumulativeDoseRequest struct {
+	MedicationOrders []models.MedicationOrder `json:"medication_orders" binding:"required,min=1"`
+	PatientContext   *models.PatientContext   `json:"patient_context,omitempty"`
+}
+
+// CumulativeDoseContribution is one order's share of an ingredient total, or one
+// ingredient's share of a group total
+type CumulativeDoseContribution struct {
+	OrderID      string   `json:"order_id,omitempty"`
+	DrugCode     string   `json:"drug_code"`
+	DrugName     string   `json:"drug_name,omitempty"`
+	ProductName  string   `json:"product_name,omitempty"` // Combination product the ingredient comes from
+	PRN          bool     `json:"prn,omitempty"`          // Counted at its maximum use
+	DailyDose    *float64 `json:"daily_dose,omitempty"`   // In the limit's unit
+	DoseUnit     string   `json:"dose_unit,omitempty"`
+	PercentOfMax *float64 `json:"percent_of_max,omitempty"` // Group contributions
+}
+
+// CumulativeDoseResult compares the summed daily dose of an ingredient, or of a
+// cumulative group, with its maximum
+type CumulativeDoseResult struct {
+	Scope           string                       `json:"scope"`
+	IngredientCode  string                       `json:"ingredient_code,omitempty"`
+	Name            string                       `json:"name"` // Ingredient or group name
+	CumulativeGroup string                       `json:"cumulative_group,omitempty"`
+	Status          string                       `json:"status"`
+	Severity        models.DDISeverity           `json:"severity"`
+	Message         string                       `json:"message"`
+	TotalDailyDose  *float64                     `json:"total_daily_dose,omitempty"`
+	MaxDailyDose    *float64                     `json:"max_daily_dose,omitempty"`
+	DoseUnit        string                       `json:"dose_unit,omitempty"`
+	PercentOfMax    float64                      `json:"percent_of_max"`
+	LimitCode       string                       `json:"limit_code,omitempty"`
+	Basis           string                       `json:"basis,omitempty"` // "age ≥ 60", "Child-Pugh B", "eGFR 38 mL/min/1.73m²"
+	Rationale       string                       `json:"rationale,omitempty"`
+	Source          string                       `json:"source,omitempty"`
+	Contributions   []CumulativeDoseContribution `json:"contributions"`
+	Issues          []string                     `json:"issues,omitempty"`
+}
+
+// CumulativeDoseResponse is the outcome of a cumulative dose check
+type CumulativeDoseResponse struct {
+	OrganFunction *models.OrganFunctionAssessment `json:"organ_function,omitempty"`
+	Patient       CumulativeDosePatient           `json:"patient"`
+	Results       []CumulativeDoseResult          `json:"results"`
+	Notes         []string                        `json:"notes,omitempty"`
+}
+
+// CumulativeDoseEngine sums ordered doses across products at ingredient level
+// and checks the totals against maximum daily doses
+type CumulativeDoseEngine struct {
+	db      *database.Database
+	metrics *metrics.Collector
+
+	// Limits keyed by normalized ingredient code; products by upper-case product code
+	limits      map[string][]IngredientDoseLimit
+	products    map[string][]ProductIngredient
+	rulesLoaded time.Time
+	cacheTTL    time.Duration
+	mu          sync.Mutex
+}
+
+// NewCumulativeDoseEngine creates a new cumulative daily dose engine
+func NewCumulativeDoseEngine(db *database.Database, metrics *metrics.Collector) *CumulativeDoseEngine {
+	return &CumulativeDoseEngine{
+		db:       db,
+		metrics:  metrics,
+		cacheTTL: 30 * time.Minute,
+	}
+}
+
+// CheckRegimen derives the patient's age and organ function and checks the
+// regimen's ingredient totals. Invalid labs are reported as *LabInputError and
+// invalid ages as *PediatricInputError.
+func (cde *CumulativeDoseEngine) CheckRegimen(ctx context.Context, request CumulativeDoseRequest) (*CumulativeDoseResponse, error) {
+	organFunction, err := ResolvePatientContext(request.PatientContext)
+	if err != nil {
+		return nil, err
+	}
+
+	patient, err := CumulativeDosePatientFromContext(request.PatientContext)
+	if err != nil {
+		return nil, err
+	}
+
+	results, notes, err := cde.CheckOrders(ctx, request.MedicationOrders, patient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CumulativeDoseResponse{
+		OrganFunction: organFunction,
+		Patient:       patient,
+		Results:       results,
+		Notes:         notes,
+	}, nil
+}
+
+// CheckOrders sums the daily dose of each ingredient across the active orders
+// and compares the totals with the limits for the patient
+func (cde *CumulativeDoseEngine) CheckOrders(ctx context.Context, orders []models.MedicationOrder, patient CumulativeDosePatient) ([]CumulativeDoseResult, []string, error) {
+	limits, products, err := cde.loadKnowledge(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load cumulative dose limits: %w", err)
+	}
+
+	results, notes := evaluateCumulativeDoses(limits, products, orders, patient)
+	for _, result := range results {
+		cde.metrics.RecordDoseEstimate("cumulative_" + result.Scope + "_" + result.Status)
+	}
+	return results, notes, nil
+}
+
+// CumulativeDosePatientFromContext reads age (from days, months or years) and
+// organ function from a patient context
+func CumulativeDosePatientFromContext(patientContext *models.PatientContext) (CumulativeDosePatient, error) {
+	pediatricPatient, err := PediatricPatientFromContext(patientContext)
+	if err != nil {
+		return CumulativeDosePatient{}, err
+	}
+	return CumulativeDosePatient{
+		AgeYears: pediatricPatient.AgeYears(),
+		Function: patientOrganFunction(patientContext),
+	}, nil
+}
+
+// ingredientDose is the amount of one ingredient in a single dose of an order
+type ingredientDose struct {
+	key         string
+	code        string
+	name        string
+	productName string
+	amount      float64
+	unit        string
+}
+
+// orderIngredientDoses splits an order into its active ingredients. Orders for a
+// combination product are dosed in the product's dose form unit; any other order
+// is a single ingredient dosed in a mass unit.
+func orderIngredientDoses(order models.MedicationOrder, products map[string][]ProductIngredient) ([]ingredientDose, string) {
+	components, isProduct := products[strings.ToUpper(strings.TrimSpace(order.DrugCode))]
+	if !isProduct {
+		return []ingredientDose{{
+			key:    normalizeATCDrugKey(order.DrugCode),
+			code:   order.DrugCode,
+			name:   order.DrugName,
+			amount: order.Dose,
+			unit:   order.DoseUnit,
+		}}, ""
+	}
+
+	formUnit := components[0].DoseFormUnit
+	if doseFormUnit(order.DoseUnit) != doseFormUnit(formUnit) {
+		return nil, fmt.Sprintf("%s: dose unit %q does not match product unit %q; ingredients not counted",
+			components[0].ProductName, order.DoseUnit, formUnit)
+	}
+
+	doses := make([]ingredientDose, 0, len(components))
+	for _, component := range components {
+		doses = append(doses, ingredientDose{
+			key:         normalizeATCDrugKey(component.IngredientCode),
+			code:        component.IngredientCode,
+			name:        component.IngredientName,
+			productName: component.ProductName,
+			amount:      order.Dose * component.Strength,
+			unit:        component.StrengthUnit,
+		})
+	}
+	return doses, ""
+}
+
+// doseFormUnit normalizes a dose form unit ("Tablets", "tab" -> "tablet")
+func doseFormUnit(unit string) string {
+	u := strings.ToLower(strings.TrimSpace(unit))
+	switch u {
+	case "tab", "tabs", "tablets", "caplet", "caplets":
+		return "tablet"
+	case "cap", "caps", "capsules":
+		return "capsule"
+	case "millilitre", "milliliter", "milliliters", "millilitres":
+		return "ml"
+	}
+	return u
+}
+
+// ingredientTotal accumulates the orders contributing to one ingredient
+type ingredientTotal struct {
+	key           string
+	code          string
+	name          string
+	contributions []CumulativeDoseContribution
+	doses         []ingredientDose
+	intervals     []float64 // 0 when the frequency is unknown
+}
+
+// evaluateCumulativeDoses sums each limited ingredient's daily dose across the
+// active orders (PRN orders at their maximum use), checks it against the lowest
+// applicable limit, and checks cumulative groups with two or more ingredients
+func evaluateCumulativeDoses(
+	limits map[string][]IngredientDoseLimit,
+	products map[string][]ProductIngredient,
+	orders []models.MedicationOrder,
+	patient CumulativeDosePatient,
+) ([]CumulativeDoseResult, []string) {
+	var notes []string
+	totals := make(map[string]*ingredientTotal)
+	var keys []string
+
+	for _, order := range orders {
+		if !order.IsActive() {
+			continue
+		}
+		doses, issue := orderIngredientDoses(order, products)
+		if issue != "" {
+			notes = append(notes, issue)
+			continue
+		}
+		interval, ok := orderIntervalHours(order)
+		if !ok {
+			interval = 0
+		}
+
+		for _, dose := range doses {
+			if len(limits[dose.key]) == 0 {
+				continue
+			}
+			total, exists := totals[dose.key]
+			if !exists {
+				total = &ingredientTotal{key: dose.key, code: dose.code, name: dose.name}
+				totals[dose.key] = total
+				keys = append(keys, dose.key)
+			}
+			if total.name == "" {
+				total.name = dose.name
+			}
+			total.contributions = append(total.contributions, CumulativeDoseContribution{
+				OrderID:     order.OrderID,
+				DrugCode:    order.DrugCode,
+				DrugName:    order.DrugName,
+				ProductName: dose.productName,
+				PRN:         order.PRN,
+			})
+			total.doses = append(total.doses, dose)
+			total.intervals = append(total.intervals, interval)
+		}
+	}
+
+	var results []CumulativeDoseResult
+	for _, key := range keys {
+		total := totals[key]
+		limit, basis := selectDoseLimit(limits[key], patient)
+		if limit == nil {
+			continue
+		}
+		if patient.AgeYears == nil && (limit.MinAgeYears != nil || limit.MaxAgeYears != nil) {
+			notes = append(notes, fmt.Sprintf("%s: patient age unknown; lowest age-specific limit applied (%s, max %g %s/day)",
+				limit.IngredientName, describeAgeYearsRange(limit.MinAgeYears, limit.MaxAgeYears), limit.MaxDailyDose, limit.DoseUnit))
+		}
+		results = append(results, checkIngredientTotal(total, *limit, basis))
+	}
+	results = append(results, cumulativeGroupResults(results, limits)...)
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return doseStatusRank(results[i].Status) < doseStatusRank(results[j].Status)
+	})
+	return results, notes
+}
+
+// selectDoseLimit picks the lowest maximum among the limits that apply to the
+// patient. Age bounds apply when the age is unknown; renal and hepatic
+// conditions need the patient's function.
+func selectDoseLimit(limits []IngredientDoseLimit, patient CumulativeDosePatient) (*IngredientDoseLimit, string) {
+	var selected *IngredientDoseLimit
+	var selectedBasis string
+	for i := range limits {
+		basis, applies := doseLimitApplies(limits[i], patient)
+		if !applies {
+			continue
+		}
+		if selected == nil || limits[i].MaxDailyDose < selected.MaxDailyDose {
+			selected = &limits[i]
+			selectedBasis = basis
+		}
+	}
+	return selected, selectedBasis
+}
+
+// doseLimitApplies reports whether a limit's conditions hold for the patient,
+// with the basis for using it
+func doseLimitApplies(limit IngredientDoseLimit, patient CumulativeDosePatient) (string, bool) {
+	var basis []string
+
+	if limit.MinAgeYears != nil || limit.MaxAgeYears != nil {
+		if patient.AgeYears != nil {
+			age := *patient.AgeYears
+			if limit.MinAgeYears != nil && age < *limit.MinAgeYears {
+				return "", false
+			}
+			if limit.MaxAgeYears != nil && age >= *limit.MaxAgeYears {
+				return "", false
+			}
+		}
+		ageRange := describeAgeYearsRange(limit.MinAgeYears, limit.MaxAgeYears)
+		if patient.AgeYears == nil {
+			ageRange += " (age not given)"
+		}
+		basis = append(basis, ageRange)
+	}
+
+	if limit.RenalMetric != nil && limit.RenalBelow != nil {
+		if patient.Function.DialysisModality != "" {
+			basis = append(basis, patient.Function.DialysisModality)
+		} else {
+			value, label, _ := renalMetricValue(*limit.RenalMetric, patient.Function)
+			if value == nil || *value >= *limit.RenalBelow {
+				return "", false
+			}
+			basis = append(basis, fmt.Sprintf("%s %g %s", label, *value, renalMetricUnit(label)))
+		}
+	}
+
+	if len(limit.ChildPughClasses) > 0 {
+		matched := false
+		for _, class := range limit.ChildPughClasses {
+			if strings.EqualFold(class, patient.Function.ChildPughClass) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return "", false
+		}
+		basis = append(basis, "Child-Pugh "+patient.Function.ChildPughClass)
+	}
+
+	if len(basis) == 0 {
+		return "all patients", true
+	}
+	return strings.Join(basis, ", "), true
+}
+
+// describeAgeYearsRange formats an age range in years, e.g. "age ≥ 60", "age 12-17"
+func describeAgeYearsRange(minAge, maxAge *int) string {
+	switch {
+	case minAge != nil && maxAge != nil:
+		return fmt.Sprintf("age %d-%d", *minAge, *maxAge-1)
+	case minAge != nil:
+		return fmt.Sprintf("age ≥ %d", *minAge)
+	case maxAge != nil:
+		return fmt.Sprintf("age < %d", *maxAge)
+	}
+	return ""
+}
+
+// checkIngredientTotal sums an ingredient's contributions in the limit's unit and
+// compares the total with the limit
+func checkIngredientTotal(total *ingredientTotal, limit IngredientDoseLimit, basis string) CumulativeDoseResult {
+	name := total.name
+	if name == "" {
+		name = limit.IngredientName
+	}
+	maxDaily := limit.MaxDailyDose
+	result := CumulativeDoseResult{
+		Scope:          CumulativeScopeIngredient,
+		IngredientCode: limit.IngredientCode,
+		Name:           name,
+		MaxDailyDose:   &maxDaily,
+		DoseUnit:       limit.DoseUnit,
+		LimitCode:      limit.LimitCode,
+		Basis:          basis,
+		Rationale:      limit.Rationale,
+		Source:         limit.Source,
+		Contributions:  total.contributions,
+	}
+	if limit.CumulativeGroup != nil {
+		result.CumulativeGroup = *limit.CumulativeGroup
+	}
+
+	sum := 0.0
+	for i, dose := range total.doses {
+		label := contributionLabel(total.contributions[i])
+		amount, unitOK := convertDose(dose.amount, dose.unit, limit.DoseUnit)
+		if !unitOK {
+			result.Issues = append(result.Issues, fmt.Sprintf("%s: unit %q cannot be compared with %s", label, dose.unit, limit.DoseUnit))
+			continue
+		}
+		if total.intervals[i] == 0 {
+			result.Issues = append(result.Issues, fmt.Sprintf("%s: frequency not recognized", label))
+			continue
+		}
+		daily := roundDose(amount * 24 / total.intervals[i])
+		result.Contributions[i].DailyDose = &daily
+		result.Contributions[i].DoseUnit = limit.DoseUnit
+		sum += daily
+	}
+
+	sum = roundDose(sum)
+	result.TotalDailyDose = &sum
+	result.PercentOfMax = percentOf(sum, maxDaily)
+
+	orderCount := fmt.Sprintf("%d order", len(total.contributions))
+	if len(total.contributions) != 1 {
+		orderCount += "s"
+	}
+	switch {
+	case sum > maxDaily+1e-9:
+		result.Status = DoseStatusExceedsMax
+		result.Severity = models.SeverityMajor
+		result.Message = fmt.Sprintf("Total %s %g %s/day from %s exceeds maximum %g %s/day (%s)",
+			name, sum, limit.DoseUnit, orderCount, maxDaily, limit.DoseUnit, basis)
+	case len(result.Issues) > 0:
+		result.Status = DoseStatusUnverified
+		result.Severity = models.SeverityModerate
+		result.Message = fmt.Sprintf("Total %s could not be fully verified against maximum %g %s/day (%s); counted %g %s/day from %s",
+			name, maxDaily, limit.DoseUnit, basis, sum, limit.DoseUnit, orderCount)
+	default:
+		result.Status = DoseStatusWithinLimits
+		result.Severity = models.SeverityMinor
+		result.Message = fmt.Sprintf("Total %s %g %s/day from %s is within maximum %g %s/day (%s)",
+			name, sum, limit.DoseUnit, orderCount, maxDaily, limit.DoseUnit, basis)
+	}
+	return result
+}
+
+// cumulativeGroupResults sums ingredients of the same cumulative group as a
+// fraction of each one's maximum
+func cumulativeGroupResults(ingredients []CumulativeDoseResult, limits map[string][]IngredientDoseLimit) []CumulativeDoseResult {
+	byGroup := make(map[string][]CumulativeDoseResult)
+	var groups []string
+	for _, ingredient := range ingredients {
+		if ingredient.CumulativeGroup == "" {
+			continue
+		}
+		if _, exists := byGroup[ingredient.CumulativeGroup]; !exists {
+			groups = append(groups, ingredient.CumulativeGroup)
+		}
+		byGroup[ingredient.CumulativeGroup] = append(byGroup[ingredient.CumulativeGroup], ingredient)
+	}
+
+	var results []CumulativeDoseResult
+	for _, group := range groups {
+		members := byGroup[group]
+		if len(members) < 2 {
+			continue
+		}
+
+		result := CumulativeDoseResult{
+			Scope:           CumulativeScopeGroup,
+			Name:            group,
+			CumulativeGroup: group,
+			Rationale:       members[0].Rationale,
+		}
+		total := 0.0
+		var shares, bases []string
+		for _, member := range members {
+			percent := member.PercentOfMax
+			total += percent
+			result.Contributions = append(result.Contributions, CumulativeDoseContribution{
+				DrugCode:     member.IngredientCode,
+				DrugName:     member.Name,
+				DailyDose:    member.TotalDailyDose,
+				DoseUnit:     member.DoseUnit,
+				PercentOfMax: &percent,
+			})
+			shares = append(shares, fmt.Sprintf("%s %g%%", member.Name, percent))
+			bases = append(bases, member.LimitCode)
+			if member.Status == DoseStatusUnverified {
+				result.Issues = append(result.Issues, fmt.Sprintf("%s total could not be fully verified", member.Name))
+			}
+		}
+		result.PercentOfMax = math.Round(total*10) / 10
+		result.Basis = strings.Join(bases, ", ")
+
+		switch {
+		case result.PercentOfMax > 100:
+			result.Status = DoseStatusExceedsMax
+			result.Severity = models.SeverityMajor
+			result.Message = fmt.Sprintf("Combined %s exposure is %g%% of the maximum daily dose: %s",
+				group, result.PercentOfMax, strings.Join(shares, ", "))
+		case len(result.Issues) > 0:
+			result.Status = DoseStatusUnverified
+			result.Severity = models.SeverityModerate
+			result.Message = fmt.Sprintf("Combined %s exposure could not be fully verified; counted %g%% of the maximum daily dose: %s",
+				group, result.PercentOfMax, strings.Join(shares, ", "))
+		default:
+			result.Status = DoseStatusWithinLimits
+			result.Severity = models.SeverityModerate // More than one agent of the group is itself a duplicate
+			result.Message = fmt.Sprintf("Combined %s exposure is %g%% of the maximum daily dose: %s",
+				group, result.PercentOfMax, strings.Join(shares, ", "))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// percentOf returns value as a percentage of max, to one decimal place
+func percentOf(value, max float64) float64 {
+	if max <= 0 {
+		return 0
+	}
+	return math.Round(value/max*1000) / 10
+}
+
+// contributionLabel names a contributing order in issues
+func contributionLabel(contribution CumulativeDoseContribution) string {
+	switch {
+	case contribution.OrderID != "":
+		return "order " + contribution.OrderID
+	case contribution.ProductName != "":
+		return contribution.ProductName
+	case contribution.DrugName != "":
+		return contribution.DrugName
+	}
+	return contribution.DrugCode
+}
+
+// GetIngredientLimits returns the active daily dose limits for an ingredient
+func (cde *CumulativeDoseEngine) GetIngredientLimits(ctx context.Context, ingredientCode string) ([]IngredientDoseLimit, error) {
+	limits, _, err := cde.loadKnowledge(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cumulative dose limits: %w", err)
+	}
+	return limits[normalizeATCDrugKey(ingredientCode)], nil
+}
+
+// loadKnowledge returns active limits keyed by normalized ingredient code and
+// combination product ingredients keyed by upper-case product code
+func (cde *CumulativeDoseEngine) loadKnowledge(ctx context.Context) (map[string][]IngredientDoseLimit, map[string][]ProductIngredient, error) {
+	cde.mu.Lock()
+	defer cde.mu.Unlock()
+
+	if cde.limits != nil && time.Since(cde.rulesLoaded) < cde.cacheTTL {
+		return cde.limits, cde.products, nil
+	}
+
+	var limitRows []IngredientDoseLimit
+	if err := cde.db.DB.WithContext(ctx).
+		Where("active = TRUE").
+		Order("ingredient_code, limit_code").
+		Find(&limitRows).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var productRows []ProductIngredient
+	if err := cde.db.DB.WithContext(ctx).
+		Where("active = TRUE").
+		Order("product_code, ingredient_code").
+		Find(&productRows).Error; err != nil {
+		return nil, nil, err
+	}
+
+	limits := make(map[string][]IngredientDoseLimit)
+	for _, row := range limitRows {
+		key := normalizeATCDrugKey(row.IngredientCode)
+		limits[key] = append(limits[key], row)
+	}
+	products := make(map[string][]ProductIngredient)
+	for _, row := range productRows {
+		key := strings.ToUpper(row.ProductCode)
+		products[key] = append(products[key], row)
+	}
+
+	cde.limits = limits
+	cde.products = products
+	cde.rulesLoaded = time.Now()
+
+	return limits, products, nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ImportIssue is an import file row that was skipped
+type ImportIssue struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// openDelimitedFile reads the header of a CSV or TSV file. format is "csv", "tsv"
+// or "" to detect from the header. Header names are matched through aliases;
+// the returned map gives the column index of each recognized field.
+func openDelimitedFile(r io.Reader, format, kind string, aliases map[string]string) (*csv.Reader, map[string]int, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read %s file: %w", kind, err)
+	}
+
+	text := strings.TrimPrefix(string(content), "\ufeff")
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	switch strings.ToLower(format) {
+	case "tsv":
+		reader.Comma = '\t'
+	case "csv":
+	case "":
+		header := text
+		if i := strings.IndexByte(header, '\n'); i >= 0 {
+			header = header[:i]
+		}
+		if strings.Contains(header, "\t") {
+			reader.Comma = '\t'
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported %s format %q (expected csv or tsv)", kind, format)
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read %s header: %w", kind, err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(name))
+		key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+		if field, ok := aliases[key]; ok {
+			columns[field] = i
+		}
+	}
+	return reader, columns, nil
+}
+
+// recordField returns a lookup of trimmed field values by name for one record
+func recordField(record []string, columns map[string]int) func(string) string {
+	return func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+}
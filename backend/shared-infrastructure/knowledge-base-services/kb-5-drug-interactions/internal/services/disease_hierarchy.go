@@ -0,0 +1,294 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Disease code systems as stored in ddi_drug_disease_rules.code_system
+const (
+	CodeSystemICD10  = "ICD10"
+	CodeSystemSNOMED = "SNOMED"
+	CodeSystemICD9   = "ICD9"
+)
+
+// Disease match types reported in DrugDiseaseResult.MatchType
+const (
+	DiseaseMatchExact      = "exact"
+	DiseaseMatchAncestor   = "ancestor"   // Patient code is a descendant of the rule code
+	DiseaseMatchDescendant = "descendant" // Rule code is more specific than the patient code (ICD-10 only)
+	DiseaseMatchCrosswalk  = "crosswalk"  // Matched through an ICD-10 <-> SNOMED mapping
+)
+
+// Relationship types accepted in the disease vocabulary file
+const (
+	diseaseRelIsA    = "is_a"    // source is a child of target (same code system)
+	diseaseRelMapsTo = "maps_to" // source is equivalent to target (cross code system)
+)
+
+// DiseaseCodeRef identifies a disease code within a code system
+type DiseaseCodeRef struct {
+	CodeSystem string `json:"code_system"`
+	Code       string `json:"code"`
+}
+
+func (r DiseaseCodeRef) key() string {
+	return r.CodeSystem + ":" + r.Code
+}
+
+// DiseaseMatch describes how a patient disease code satisfied a rule's disease code
+type DiseaseMatch struct {
+	PatientCode     DiseaseCodeRef
+	MatchedAncestor DiseaseCodeRef
+	MatchType       string
+}
+
+// DiseaseVocabularyStats summarizes a disease vocabulary load
+type DiseaseVocabularyStats struct {
+	IsARelationships  int `json:"is_a_relationships"`
+	CrosswalkMappings int `json:"crosswalk_mappings"`
+	SkippedRows       int `json:"skipped_rows"`
+}
+
+// DiseaseHierarchy indexes SNOMED "is a" relationships and an ICD-10 <-> SNOMED
+// crosswalk so disease rules match patient codes at any level of specificity.
+// ICD-10 ancestry is derived from the code itself (I50.21 -> I50.2 -> I50).
+type DiseaseHierarchy struct {
+	parents   map[string][]DiseaseCodeRef // child key -> parents (same system)
+	crosswalk map[string][]DiseaseCodeRef // code key -> equivalents (other system)
+	mu        sync.RWMutex
+}
+
+// NewDiseaseHierarchy creates an empty hierarchy (ICD-10 prefix matching only)
+func NewDiseaseHierarchy() *DiseaseHierarchy {
+	return &DiseaseHierarchy{
+		parents:   make(map[string][]DiseaseCodeRef),
+		crosswalk: make(map[string][]DiseaseCodeRef),
+	}
+}
+
+// LoadFile loads a tab-separated vocabulary file with the header
+//
+//	source_system  source_code  relationship  target_system  target_code
+//
+// where relationship is "is_a" (SNOMED child -> parent) or "maps_to"
+// (ICD-10 <-> SNOMED equivalence, indexed in both directions).
+// The loaded relationships replace any previously loaded vocabulary.
+func (dh *DiseaseHierarchy) LoadFile(path string) (*DiseaseVocabularyStats, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open disease vocabulary file: %w", err)
+	}
+	defer file.Close()
+
+	return dh.Load(file)
+}
+
+// Load reads vocabulary rows (see LoadFile for the format) from r
+func (dh *DiseaseHierarchy) Load(r io.Reader) (*DiseaseVocabularyStats, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = '\t'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	// Skip header
+	if _, err := reader.Read(); err != nil {
+		return nil, fmt.Errorf("cannot read header: %w", err)
+	}
+
+	parents := make(map[string][]DiseaseCodeRef)
+	crosswalk := make(map[string][]DiseaseCodeRef)
+	stats := &DiseaseVocabularyStats{}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil || len(record) < 5 {
+			stats.SkippedRows++
+			continue // Skip malformed rows
+		}
+
+		source := NormalizeDiseaseCode(record[0], record[1])
+		target := NormalizeDiseaseCode(record[3], record[4])
+		if source.Code == "" || target.Code == "" {
+			stats.SkippedRows++
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(record[2])) {
+		case diseaseRelIsA:
+			parents[source.key()] = append(parents[source.key()], target)
+			stats.IsARelationships++
+		case diseaseRelMapsTo:
+			crosswalk[source.key()] = append(crosswalk[source.key()], target)
+			crosswalk[target.key()] = append(crosswalk[target.key()], source)
+			stats.CrosswalkMappings++
+		default:
+			stats.SkippedRows++
+		}
+	}
+
+	dh.mu.Lock()
+	dh.parents = parents
+	dh.crosswalk = crosswalk
+	dh.mu.Unlock()
+
+	return stats, nil
+}
+
+// Ancestors returns the code itself followed by all ancestors in its own code system
+func (dh *DiseaseHierarchy) Ancestors(ref DiseaseCodeRef) []DiseaseCodeRef {
+	result := []DiseaseCodeRef{ref}
+	seen := map[string]bool{ref.key(): true}
+
+	if ref.CodeSystem == CodeSystemICD10 || ref.CodeSystem == CodeSystemICD9 {
+		for _, code := range icdAncestorCodes(ref.Code) {
+			ancestor := DiseaseCodeRef{CodeSystem: ref.CodeSystem, Code: code}
+			if !seen[ancestor.key()] {
+				seen[ancestor.key()] = true
+				result = append(result, ancestor)
+			}
+		}
+	}
+
+	dh.mu.RLock()
+	defer dh.mu.RUnlock()
+
+	// Breadth-first walk of "is a" relationships (also covers any explicit ICD parents)
+	for i := 0; i < len(result); i++ {
+		for _, parent := range dh.parents[result[i].key()] {
+			if !seen[parent.key()] {
+				seen[parent.key()] = true
+				result = append(result, parent)
+			}
+		}
+	}
+
+	return result
+}
+
+// Match reports whether a patient disease code satisfies a rule disease code,
+// checking (in order) exact match, ancestry within the patient's code system,
+// and ancestry of crosswalked equivalents. ICD-10 rules that are more specific
+// than the patient's code also match, so unspecified diagnoses stay conservative.
+func (dh *DiseaseHierarchy) Match(patient, rule DiseaseCodeRef) (DiseaseMatch, bool) {
+	match := DiseaseMatch{PatientCode: patient, MatchedAncestor: rule}
+
+	if patient == rule {
+		match.MatchType = DiseaseMatchExact
+		return match, true
+	}
+
+	if patient.CodeSystem == rule.CodeSystem {
+		for _, ancestor := range dh.Ancestors(patient) {
+			if ancestor == rule {
+				match.MatchType = DiseaseMatchAncestor
+				return match, true
+			}
+		}
+		if patient.CodeSystem == CodeSystemICD10 && strings.HasPrefix(rule.Code, patient.Code) {
+			match.MatchType = DiseaseMatchDescendant
+			return match, true
+		}
+		return match, false
+	}
+
+	// Cross code system: map the patient code (and its ancestors) into the rule's system
+	for _, ancestor := range dh.Ancestors(patient) {
+		for _, equivalent := range dh.equivalents(ancestor) {
+			if equivalent.CodeSystem != rule.CodeSystem {
+				continue
+			}
+			for _, mapped := range dh.Ancestors(equivalent) {
+				if mapped == rule {
+					match.MatchType = DiseaseMatchCrosswalk
+					return match, true
+				}
+			}
+		}
+	}
+
+	return match, false
+}
+
+// equivalents returns crosswalk mappings for a code
+func (dh *DiseaseHierarchy) equivalents(ref DiseaseCodeRef) []DiseaseCodeRef {
+	dh.mu.RLock()
+	defer dh.mu.RUnlock()
+	return dh.crosswalk[ref.key()]
+}
+
+// NormalizeDiseaseCode canonicalizes a code system name and disease code.
+// Codes may carry a system prefix (e.g. "SNOMED:84114007", "ICD-10:I50.9");
+// when no system is given it is inferred from the code format.
+func NormalizeDiseaseCode(codeSystem, code string) DiseaseCodeRef {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if idx := strings.Index(code, ":"); idx > 0 {
+		if prefixSystem := normalizeCodeSystem(code[:idx]); prefixSystem != "" {
+			codeSystem = prefixSystem
+			code = code[idx+1:]
+		}
+	}
+
+	system := normalizeCodeSystem(codeSystem)
+	if system == "" {
+		system = inferCodeSystem(code)
+	}
+
+	if system == CodeSystemICD10 || system == CodeSystemICD9 {
+		code = strings.ReplaceAll(code, ".", "")
+	}
+
+	return DiseaseCodeRef{CodeSystem: system, Code: code}
+}
+
+// normalizeCodeSystem maps code system aliases to the canonical names; "" if unknown
+func normalizeCodeSystem(system string) string {
+	switch strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(system), "_", "-")) {
+	case "ICD10", "ICD-10", "ICD-10-CM", "ICD10CM", "ICD-10-AM":
+		return CodeSystemICD10
+	case "SNOMED", "SNOMED-CT", "SNOMEDCT", "SCT":
+		return CodeSystemSNOMED
+	case "ICD9", "ICD-9", "ICD-9-CM", "ICD9CM":
+		return CodeSystemICD9
+	}
+	return ""
+}
+
+// inferCodeSystem guesses the code system from the code format
+func inferCodeSystem(code string) string {
+	// SNOMED concept IDs are numeric (6-18 digits)
+	if len(code) >= 6 && isAllDigits(code) {
+		return CodeSystemSNOMED
+	}
+	return CodeSystemICD10
+}
+
+// icdAncestorCodes returns the ICD category chain for a dotless code, most specific first
+// (e.g. "I5021" -> ["I502", "I50"]). Categories are 3 characters.
+func icdAncestorCodes(code string) []string {
+	var ancestors []string
+	for length := len(code) - 1; length >= 3; length-- {
+		ancestors = append(ancestors, code[:length])
+	}
+	return ancestors
+}
+
+// isAllDigits checks if a string is non-empty and all digits
+func isAllDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -60,13 +60,17 @@ type DrugDiseaseResult struct {
 	Evidence               models.EvidenceLevel `json:"evidence"`
 	Confidence             float64             `json:"confidence"`
 	RequiresPharmacistReview bool              `json:"requires_pharmacist_review"`
+	// Hierarchy/crosswalk match details: which patient code fired the rule and via which ancestor
+	PatientDiseaseCode     string              `json:"patient_disease_code,omitempty"` // e.g. "ICD10:I5021"
+	MatchedAncestor        string              `json:"matched_ancestor,omitempty"`     // Rule code matched, e.g. "ICD10:I50"
+	MatchType              string              `json:"match_type,omitempty"`           // exact, ancestor, descendant, crosswalk
 }
 
 // DrugDiseaseCheckRequest represents a request to check drug-disease contraindications
 type DrugDiseaseCheckRequest struct {
 	DrugCodes      []string `json:"drug_codes" binding:"required,min=1"`
 	DiseaseCodes   []string `json:"disease_codes" binding:"required,min=1"`   // ICD-10 or SNOMED codes
-	CodeSystem     string   `json:"code_system,omitempty"`                    // "ICD-10" or "SNOMED-CT", auto-detect if empty
+	CodeSystem     string   `json:"code_system,omitempty"`                    // "ICD-10" or "SNOMED-CT", auto-detect per code if empty (codes may also be prefixed, e.g. "SNOMED:84114007")
 	PatientContext *models.PatientContext `json:"patient_context,omitempty"`
 	IncludeCautions bool    `json:"include_cautions"`                         // Include "caution" level contraindications
 }
@@ -80,6 +84,9 @@ type DrugDiseaseEngine struct {
 	ruleCache map[string][]DrugDiseaseContraindication
 	cacheTTL  time.Duration
 	lastLoad  time.Time
+
+	// ICD-10 prefix hierarchy, SNOMED descendants and ICD-10 <-> SNOMED crosswalk
+	hierarchy *DiseaseHierarchy
 }
 
 // NewDrugDiseaseEngine creates a new drug-disease contraindication engine
@@ -89,9 +96,17 @@ func NewDrugDiseaseEngine(db *database.Database, metrics *metrics.Collector) *Dr
 		metrics:   metrics,
 		ruleCache: make(map[string][]DrugDiseaseContraindication),
 		cacheTTL:  30 * time.Minute,
+		hierarchy: NewDiseaseHierarchy(),
 	}
 }
 
+// LoadDiseaseVocabulary loads SNOMED "is a" relationships and the ICD-10 <-> SNOMED
+// crosswalk from a tab-separated vocabulary file (see DiseaseHierarchy.LoadFile).
+// Without a vocabulary, matching falls back to ICD-10 prefix hierarchy only.
+func (dde *DrugDiseaseEngine) LoadDiseaseVocabulary(path string) (*DiseaseVocabularyStats, error) {
+	return dde.hierarchy.LoadFile(path)
+}
+
 // EvaluateDrugDiseaseContraindications checks drugs against patient's disease conditions
 func (dde *DrugDiseaseEngine) EvaluateDrugDiseaseContraindications(
 	ctx context.Context,
@@ -107,41 +122,56 @@ func (dde *DrugDiseaseEngine) EvaluateDrugDiseaseContraindications(
 		dde.metrics.RecordDrugDiseaseCheck(time.Since(timer), len(request.DrugCodes)*len(request.DiseaseCodes))
 	}()
 
-	// Detect code system if not specified
-	codeSystem := request.CodeSystem
-	if codeSystem == "" {
-		codeSystem = dde.detectCodeSystem(request.DiseaseCodes)
+	// Normalize each disease code; the code system is detected per code unless specified
+	patientCodes := make([]DiseaseCodeRef, 0, len(request.DiseaseCodes))
+	for _, diseaseCode := range request.DiseaseCodes {
+		patientCodes = append(patientCodes, NormalizeDiseaseCode(request.CodeSystem, diseaseCode))
 	}
 
-	// Load contraindication rules
-	rules, err := dde.loadContraindicationRules(ctx, request.DrugCodes, codeSystem, datasetVersion)
+	// A CKD stage derived from labs counts as a renal diagnosis
+	organFunction, err := ResolvePatientContext(request.PatientContext)
+	if err != nil {
+		return nil, err
+	}
+	if organFunction != nil {
+		if code := ckdDiagnosisCode(organFunction.RenalStage); code != "" {
+			patientCodes = append(patientCodes, DiseaseCodeRef{CodeSystem: CodeSystemICD10, Code: code})
+		}
+	}
+
+	// Load contraindication rules for all code systems (the crosswalk bridges them)
+	rules, err := dde.loadContraindicationRules(ctx, request.DrugCodes, "", datasetVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load drug-disease rules: %w", err)
 	}
 
 	var results []DrugDiseaseResult
 
-	// Evaluate each drug against each disease
+	// Evaluate each rule against the patient's diseases, keeping the closest match
 	for _, rule := range rules {
-		for _, diseaseCode := range request.DiseaseCodes {
-			if dde.matchesDisease(rule, diseaseCode, codeSystem) {
-				// Skip cautions if not requested
-				if rule.ContraindicationType == "caution" && !request.IncludeCautions {
-					continue
-				}
-
-				// Check for exceptions based on patient context
-				if dde.hasException(rule, request.PatientContext) {
-					continue
-				}
-
-				result := dde.buildDrugDiseaseResult(rule, request.PatientContext)
-				results = append(results, result)
-
-				// Record metric
-				dde.metrics.RecordDrugDiseaseInteraction(rule.DrugCode, rule.DiseaseCode, string(rule.Severity))
-			}
+		match, matched := dde.bestDiseaseMatch(rule, patientCodes)
+		if !matched {
+			continue
+		}
+
+		// Skip cautions if not requested
+		if rule.ContraindicationType == "caution" && !request.IncludeCautions {
+			continue
+		}
+
+		// Check for exceptions based on patient context
+		if dde.hasException(rule, request.PatientContext) {
+			continue
 		}
+
+		result := dde.buildDrugDiseaseResult(rule, request.PatientContext)
+		result.PatientDiseaseCode = match.PatientCode.key()
+		result.MatchedAncestor = match.MatchedAncestor.key()
+		result.MatchType = match.MatchType
+		results = append(results, result)
+
+		// Record metric
+		dde.metrics.RecordDrugDiseaseInteraction(rule.DrugCode, rule.DiseaseCode, string(rule.Severity))
 	}
 
 	// Sort by severity (contraindicated > major > moderate > minor)
@@ -163,22 +193,29 @@ func (dde *DrugDiseaseEngine) CheckSingleDrugDisease(
 		dde.metrics.RecordDrugDiseaseCheck(time.Since(timer), 1)
 	}()
 
-	if codeSystem == "" {
-		codeSystem = dde.detectCodeSystem([]string{diseaseCode})
-	}
-
-	var rule DrugDiseaseContraindication
-	err := dde.db.DB.WithContext(ctx).
-		Where("drug_code = ? AND disease_code = ? AND code_system = ? AND dataset_version = ? AND active = true",
-			strings.ToUpper(drugCode), strings.ToUpper(diseaseCode), codeSystem, datasetVersion).
-		First(&rule).Error
+	patientCode := NormalizeDiseaseCode(codeSystem, diseaseCode)
 
+	rules, err := dde.loadContraindicationRules(ctx, []string{drugCode}, "", datasetVersion)
 	if err != nil {
-		return nil, nil // No contraindication found
+		return nil, fmt.Errorf("failed to load drug-disease rules: %w", err)
 	}
 
-	result := dde.buildDrugDiseaseResult(rule, nil)
-	return &result, nil
+	var best *DrugDiseaseResult
+	for _, rule := range rules {
+		match, matched := dde.bestDiseaseMatch(rule, []DiseaseCodeRef{patientCode})
+		if !matched {
+			continue
+		}
+		result := dde.buildDrugDiseaseResult(rule, nil)
+		result.PatientDiseaseCode = match.PatientCode.key()
+		result.MatchedAncestor = match.MatchedAncestor.key()
+		result.MatchType = match.MatchType
+		if best == nil || result.Severity.GetPriority() > best.Severity.GetPriority() {
+			best = &result
+		}
+	}
+
+	return best, nil // nil when no contraindication found
 }
 
 // GetContraindicatedDiseases returns all diseases contraindicated for a specific drug
@@ -209,15 +246,22 @@ func (dde *DrugDiseaseEngine) GetContraindicatedDrugs(
 	codeSystem string,
 	datasetVersion string,
 ) ([]DrugDiseaseContraindication, error) {
-	if codeSystem == "" {
-		codeSystem = dde.detectCodeSystem([]string{diseaseCode})
+	patientCode := NormalizeDiseaseCode(codeSystem, diseaseCode)
+
+	// Include rules written against any ancestor of the disease (e.g. I50 for I50.21)
+	var ancestorCodes []string
+	for _, ancestor := range dde.hierarchy.Ancestors(patientCode) {
+		ancestorCodes = append(ancestorCodes, ancestor.Code)
+		if ancestor.CodeSystem == CodeSystemICD10 && len(ancestor.Code) > 3 {
+			ancestorCodes = append(ancestorCodes, ancestor.Code[:3]+"."+ancestor.Code[3:])
+		}
 	}
 
 	var contraindications []DrugDiseaseContraindication
 
 	err := dde.db.DB.WithContext(ctx).
-		Where("disease_code = ? AND code_system = ? AND dataset_version = ? AND active = true",
-			strings.ToUpper(diseaseCode), codeSystem, datasetVersion).
+		Where("UPPER(disease_code) IN ? AND code_system IN ? AND dataset_version = ? AND active = true",
+			ancestorCodes, codeSystemAliases(patientCode.CodeSystem), datasetVersion).
 		Order("CASE severity WHEN 'contraindicated' THEN 1 WHEN 'major' THEN 2 WHEN 'moderate' THEN 3 WHEN 'minor' THEN 4 ELSE 5 END").
 		Find(&contraindications).Error
 
@@ -278,10 +322,13 @@ func (dde *DrugDiseaseEngine) loadContraindicationRules(
 		normalizedCodes[i] = strings.ToUpper(code)
 	}
 
-	err := dde.db.DB.WithContext(ctx).
-		Where("drug_code IN ? AND code_system = ? AND dataset_version = ? AND active = true",
-			normalizedCodes, codeSystem, datasetVersion).
-		Find(&rules).Error
+	query := dde.db.DB.WithContext(ctx).
+		Where("drug_code IN ? AND dataset_version = ? AND active = true", normalizedCodes, datasetVersion)
+	if codeSystem != "" {
+		query = query.Where("code_system IN ?", codeSystemAliases(codeSystem))
+	}
+
+	err := query.Find(&rules).Error
 
 	if err != nil {
 		return nil, err
@@ -294,60 +341,41 @@ func (dde *DrugDiseaseEngine) loadContraindicationRules(
 	return rules, nil
 }
 
-// detectCodeSystem determines the code system from the format of disease codes
-func (dde *DrugDiseaseEngine) detectCodeSystem(diseaseCodes []string) string {
-	if len(diseaseCodes) == 0 {
-		return "ICD10" // Match database format (no hyphen)
+// codeSystemAliases returns the code_system values stored for a canonical code system
+func codeSystemAliases(codeSystem string) []string {
+	switch normalizeCodeSystem(codeSystem) {
+	case CodeSystemSNOMED:
+		return []string{"SNOMED-CT", "SNOMED"}
+	case CodeSystemICD9:
+		return []string{"ICD9", "ICD-9"}
+	default:
+		return []string{"ICD10", "ICD-10"}
 	}
-
-	firstCode := diseaseCodes[0]
-
-	// SNOMED codes are typically numeric and longer
-	if len(firstCode) > 6 && dde.isNumeric(firstCode) {
-		return "SNOMED" // Match database format (no -CT suffix)
-	}
-
-	// ICD-10 codes follow pattern like A00.0, E11.9, etc.
-	if len(firstCode) >= 3 && firstCode[0] >= 'A' && firstCode[0] <= 'Z' {
-		return "ICD10" // Match database format (no hyphen)
-	}
-
-	return "ICD10" // Default
 }
 
-// isNumeric checks if a string is all digits
-func (dde *DrugDiseaseEngine) isNumeric(s string) bool {
-	for _, c := range s {
-		if c < '0' || c > '9' {
-			return false
-		}
+// bestDiseaseMatch returns the closest match between a rule's disease code and the
+// patient's disease codes (exact > ancestor > crosswalk > descendant)
+func (dde *DrugDiseaseEngine) bestDiseaseMatch(rule DrugDiseaseContraindication, patientCodes []DiseaseCodeRef) (DiseaseMatch, bool) {
+	matchRank := map[string]int{
+		DiseaseMatchExact:      4,
+		DiseaseMatchAncestor:   3,
+		DiseaseMatchCrosswalk:  2,
+		DiseaseMatchDescendant: 1,
 	}
-	return true
-}
-
-// matchesDisease checks if a rule matches a disease code
-func (dde *DrugDiseaseEngine) matchesDisease(rule DrugDiseaseContraindication, diseaseCode string, codeSystem string) bool {
-	normalizedCode := strings.ToUpper(diseaseCode)
-	normalizedRuleCode := strings.ToUpper(rule.DiseaseCode)
 
-	// Exact match
-	if normalizedRuleCode == normalizedCode {
-		return true
-	}
+	ruleCode := NormalizeDiseaseCode(rule.CodeSystem, rule.DiseaseCode)
 
-	// ICD-10 hierarchical matching (E11 matches E11.9, E11.65, etc.)
-	if codeSystem == "ICD10" || codeSystem == "ICD-10" { // Support both formats
-		// Check if disease code starts with rule code (parent category)
-		if strings.HasPrefix(normalizedCode, normalizedRuleCode) {
-			return true
-		}
-		// Check if rule code starts with disease code (child matches parent)
-		if strings.HasPrefix(normalizedRuleCode, normalizedCode) {
-			return true
+	var best DiseaseMatch
+	found := false
+	for _, patientCode := range patientCodes {
+		match, ok := dde.hierarchy.Match(patientCode, ruleCode)
+		if ok && (!found || matchRank[match.MatchType] > matchRank[best.MatchType]) {
+			best = match
+			found = true
 		}
 	}
 
-	return false
+	return best, found
 }
 
 // hasException checks if patient context triggers an exception to the contraindication
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kb-drug-interactions/internal/config"
+	"kb-drug-interactions/internal/database"
+	"kb-drug-interactions/internal/metrics"
+)
+
+// Match types reported by drug name search
+const (
+	DrugMatchExact  = "exact"
+	DrugMatchPrefix = "prefix"
+	DrugMatchFuzzy  = "fuzzy"
+)
+
+// Match sources reported by drug name search
+const (
+	DrugMatchSourceSynonym = "synonym"
+	DrugMatchSourceBrand   = "brand"
+	DrugMatchSourceOHDSI   = "ohdsi_concept"
+)
+
+// Result limits for drug name search; each result over-fetches
+// drugSearchOverfetch candidates from every source
+const (
+	defaultDrugSearchLimit = 10
+	maxDrugSearchLimit     = 50
+	drugSearchOverfetch    = 5
+)
+
+// DrugSearchResult is a ranked candidate coding for a free-text drug name
+type DrugSearchResult struct {
+	CanonicalCode string  `json:"canonical_code"`
+	MatchedName   string  `json:"matched_name"`
+	MatchedCode   string  `json:"matched_code,omitempty"`
+	MatchType     string  `json:"match_type"`   // exact, prefix, fuzzy
+	MatchSource   string  `json:"match_source"` // synonym, brand, ohdsi_concept
+	Score         float64 `json:"score"`        // 0-1, higher is better
+}
+
+// DrugNameResolution is the best coding for one free-text medication entry
+type DrugNameResolution struct {
+	Input      string             `json:"input"`
+	Resolved   bool               `json:"resolved"`
+	BestMatch  *DrugSearchResult  `json:"best_match,omitempty"`
+	Candidates []DrugSearchResult `json:"candidates,omitempty"`
+}
+
+// DrugSearchService ranks free-text drug names against synonyms, brand names and
+// OHDSI drug concepts so that uncoded medication lists can be checked
+type DrugSearchService struct {
+	db           *database.Database
+	vocabularyDB *database.Database // Shared OHDSI vocabulary DB (optional)
+	config       *config.Config
+	metrics      *metrics.Collector
+	logger       *zap.Logger
+	synonymRepo  *database.SynonymRepository
+}
+
+// NewDrugSearchService creates a new drug name search service.
+// vocabularyDB may be nil, in which case OHDSI concept names are not searched.
+func NewDrugSearchService(
+	db *database.Database,
+	vocabularyDB *database.Database,
+	cfg *config.Config,
+	metrics *metrics.Collector,
+	logger *zap.Logger,
+) *DrugSearchService {
+	return &DrugSearchService{
+		db:           db,
+		vocabularyDB: vocabularyDB,
+		config:       cfg,
+		metrics:      metrics,
+		logger:       logger,
+		synonymRepo:  database.NewSynonymRepository(db.DB),
+	}
+}
+
+// drugSearchLimit applies the default to a missing limit and caps large ones
+func drugSearchLimit(limit int) int {
+	if limit <= 0 {
+		return defaultDrugSearchLimit
+	}
+	if limit > maxDrugSearchLimit {
+		return maxDrugSearchLimit
+	}
+	return limit
+}
+
+// SearchDrugs returns candidates for a (possibly misspelled or partial) drug name,
+// one per canonical code, ranked by score. Candidates below minScore are dropped;
+// a minScore of 0 uses the configured SynonymMatchThreshold.
+func (dss *DrugSearchService) SearchDrugs(
+	ctx context.Context,
+	query string,
+	limit int,
+	minScore float64,
+) ([]DrugSearchResult, error) {
+	timer := time.Now()
+
+	normalized := normalizeDrugName(query)
+	if normalized == "" {
+		return []DrugSearchResult{}, nil
+	}
+	limit = drugSearchLimit(limit)
+	if minScore <= 0 {
+		minScore = dss.config.SynonymMatchThreshold
+	}
+
+	// Over-fetch candidates so re-scoring and de-duplication still fill the limit
+	candidateLimit := limit * drugSearchOverfetch
+
+	var results []DrugSearchResult
+
+	// Synonym and brand names are only consulted when synonym resolution is enabled
+	if dss.config.EnableSynonymResolution {
+		matches, err := dss.synonymRepo.SearchByName(normalized, candidateLimit)
+		if err != nil {
+			dss.metrics.RecordSynonymResolution("name_search", "error", time.Since(timer))
+			return nil, fmt.Errorf("synonym search failed: %w", err)
+		}
+		for _, m := range matches {
+			source := DrugMatchSourceSynonym
+			if m.SynonymType == "brand" {
+				source = DrugMatchSourceBrand
+			}
+			confidence, _ := m.MappingConfidence.Float64()
+			if confidence <= 0 {
+				confidence = 1.0
+			}
+			results = append(results, dss.scoreCandidate(normalized, m.CanonicalCode, m.MatchedName, m.MatchedCode, source, m.Similarity, confidence))
+		}
+	}
+
+	conceptResults, err := dss.searchConceptNames(ctx, normalized, candidateLimit)
+	if err != nil {
+		// Vocabulary is supplementary; synonym matches are still returned
+		dss.logger.Warn("OHDSI concept name search failed", zap.Error(err))
+	}
+	results = append(results, conceptResults...)
+
+	ranked := rankDrugSearchResults(results, minScore, limit)
+
+	status := "matched"
+	if len(ranked) == 0 {
+		status = "no_match"
+	}
+	dss.metrics.RecordSynonymResolution("name_search", status, time.Since(timer))
+
+	return ranked, nil
+}
+
+// ResolveDrugNames codes a list of free-text medication names (e.g. an admission
+// home-medication list), returning the best match for each entry
+func (dss *DrugSearchService) ResolveDrugNames(
+	ctx context.Context,
+	names []string,
+	candidatesPerName int,
+	minScore float64,
+) ([]DrugNameResolution, error) {
+	if candidatesPerName <= 0 {
+		candidatesPerName = 3
+	}
+
+	resolutions := make([]DrugNameResolution, 0, len(names))
+	for _, name := range names {
+		candidates, err := dss.SearchDrugs(ctx, name, candidatesPerName, minScore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %q: %w", name, err)
+		}
+
+		resolution := DrugNameResolution{
+			Input:      name,
+			Candidates: candidates,
+		}
+		if len(candidates) > 0 {
+			best := candidates[0]
+			resolution.BestMatch = &best
+			resolution.Resolved = true
+		}
+		resolutions = append(resolutions, resolution)
+	}
+
+	return resolutions, nil
+}
+
+// searchConceptNames searches standard OHDSI drug concepts (ingredients and brand names).
+// Brand names are mapped to their ingredient where the vocabulary carries the relationship.
+func (dss *DrugSearchService) searchConceptNames(
+	ctx context.Context,
+	query string,
+	limit int,
+) ([]DrugSearchResult, error) {
+	if dss.vocabularyDB == nil {
+		return nil, nil
+	}
+
+	var rows []struct {
+		ConceptName    string
+		ConceptCode    string
+		ConceptClassID string
+		VocabularyID   string
+		IngredientCode *string
+		Similarity     float64
+	}
+
+	err := dss.vocabularyDB.DB.WithContext(ctx).Raw(`
+		SELECT c.concept_name,
+		       c.concept_code,
+		       c.concept_class_id,
+		       c.vocabulary_id,
+		       ing.concept_code AS ingredient_code,
+		       similarity(LOWER(c.concept_name), LOWER(?)) AS similarity
+		FROM ohdsi_concept c
+		LEFT JOIN ohdsi_concept_relationship r
+		       ON r.concept_id_1 = c.concept_id
+		      AND r.relationship_id = 'Brand name of'
+		      AND r.invalid_reason IS NULL
+		LEFT JOIN ohdsi_concept ing
+		       ON ing.concept_id = r.concept_id_2
+		      AND ing.concept_class_id = 'Ingredient'
+		WHERE c.domain_id = 'Drug'
+		  AND c.invalid_reason IS NULL
+		  AND c.concept_class_id IN ('Ingredient', 'Brand Name')
+		  AND (LOWER(c.concept_name) % LOWER(?) OR LOWER(c.concept_name) LIKE LOWER(?) || '%')
+		ORDER BY similarity DESC
+		LIMIT ?`, query, query, query, limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]DrugSearchResult, 0, len(rows))
+	for _, row := range rows {
+		source := DrugMatchSourceOHDSI
+		canonical := formatConceptDrugCode(row.VocabularyID, row.ConceptCode)
+		if row.ConceptClassID == "Brand Name" {
+			source = DrugMatchSourceBrand
+			if row.IngredientCode != nil && *row.IngredientCode != "" {
+				canonical = formatConceptDrugCode(row.VocabularyID, *row.IngredientCode)
+			}
+		}
+		results = append(results, dss.scoreCandidate(query, canonical, row.ConceptName, row.ConceptCode, source, row.Similarity, 1.0))
+	}
+
+	return results, nil
+}
+
+// scoreCandidate classifies the match and combines trigram and edit-distance similarity
+func (dss *DrugSearchService) scoreCandidate(
+	query, canonicalCode, matchedName, matchedCode, source string,
+	trigramSimilarity, mappingConfidence float64,
+) DrugSearchResult {
+	matchType, score := scoreDrugNameMatch(query, matchedName, trigramSimilarity)
+	return DrugSearchResult{
+		CanonicalCode: canonicalCode,
+		MatchedName:   matchedName,
+		MatchedCode:   matchedCode,
+		MatchType:     matchType,
+		MatchSource:   source,
+		Score:         roundScore(score * mappingConfidence),
+	}
+}
+
+// scoreDrugNameMatch returns the match type and a 0-1 score for a candidate name.
+// Exact matches score 1.0; prefix matches (autocomplete) score at least 0.85;
+// anything else takes the better of trigram and normalized edit-distance similarity.
+func scoreDrugNameMatch(query, candidate string, trigramSimilarity float64) (string, float64) {
+	q := normalizeDrugName(query)
+	name := normalizeDrugName(candidate)
+
+	if q == name {
+		return DrugMatchExact, 1.0
+	}
+
+	if q != "" && strings.HasPrefix(name, q) {
+		coverage := float64(len(q)) / float64(len(name))
+		return DrugMatchPrefix, 0.85 + 0.14*coverage
+	}
+
+	score := trigramSimilarity
+	if editScore := editDistanceSimilarity(q, name); editScore > score {
+		score = editScore
+	}
+	return DrugMatchFuzzy, score
+}
+
+// editDistanceSimilarity converts Levenshtein distance into a 0-1 similarity
+func editDistanceSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1.0
+	}
+	return 1.0 - float64(levenshteinDistance(ra, rb))/float64(longest)
+}
+
+// levenshteinDistance computes the edit distance between two rune slices
+func levenshteinDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+// rankDrugSearchResults keeps the best candidate per canonical code, drops those below
+// minScore and returns at most limit results, best first
+func rankDrugSearchResults(results []DrugSearchResult, minScore float64, limit int) []DrugSearchResult {
+	best := make(map[string]DrugSearchResult)
+	for _, r := range results {
+		if r.Score < minScore || r.CanonicalCode == "" {
+			continue
+		}
+		if existing, ok := best[r.CanonicalCode]; !ok || r.Score > existing.Score {
+			best[r.CanonicalCode] = r
+		}
+	}
+
+	ranked := make([]DrugSearchResult, 0, len(best))
+	for _, r := range best {
+		ranked = append(ranked, r)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].MatchedName < ranked[j].MatchedName
+	})
+
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// normalizeDrugName lower-cases, trims and collapses whitespace; LIKE wildcards are removed
+func normalizeDrugName(name string) string {
+	name = strings.NewReplacer("%", " ", "_", " ").Replace(name)
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// formatConceptDrugCode maps an OHDSI concept code to the service's drug code format
+func formatConceptDrugCode(vocabularyID, conceptCode string) string {
+	if vocabularyID == "RxNorm" || vocabularyID == "RxNorm Extension" {
+		return "RxCUI:" + conceptCode
+	}
+	return vocabularyID + ":" + conceptCode
+}
+
+func roundScore(score float64) float64 {
+	return float64(int(score*1000+0.5)) / 1000
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
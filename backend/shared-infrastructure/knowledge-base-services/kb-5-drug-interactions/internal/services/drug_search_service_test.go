@@ -0,0 +1,79 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ============================================================================
+// DRUG NAME SEARCH TESTS
+// ============================================================================
+
+func TestScoreDrugNameMatch_Exact(t *testing.T) {
+	matchType, score := scoreDrugNameMatch("Warfarin", "warfarin ", 0)
+
+	assert.Equal(t, DrugMatchExact, matchType)
+	assert.Equal(t, 1.0, score)
+}
+
+func TestScoreDrugNameMatch_PrefixForAutocomplete(t *testing.T) {
+	matchType, score := scoreDrugNameMatch("atorv", "Atorvastatin", 0.25)
+
+	assert.Equal(t, DrugMatchPrefix, matchType)
+	assert.True(t, score >= 0.85, "prefix matches should pass the default threshold")
+	assert.True(t, score < 1.0)
+}
+
+func TestScoreDrugNameMatch_TypoTolerance(t *testing.T) {
+	// Single transposition / dropped letter should still score above the default 0.8
+	matchType, score := scoreDrugNameMatch("warfarn", "warfarin", 0.5)
+	assert.Equal(t, DrugMatchFuzzy, matchType)
+	assert.True(t, score >= 0.8)
+
+	_, unrelated := scoreDrugNameMatch("warfarin", "metformin", 0.1)
+	assert.True(t, unrelated < 0.8)
+}
+
+func TestEditDistanceSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, editDistanceSimilarity("", ""))
+	assert.Equal(t, 1.0, editDistanceSimilarity("aspirin", "aspirin"))
+	assert.InDelta(t, 0.857, editDistanceSimilarity("asprin", "aspirin"), 0.001)
+	assert.Equal(t, 0.0, editDistanceSimilarity("abc", "xyz"))
+}
+
+func TestRankDrugSearchResults_DeduplicatesByCanonicalCode(t *testing.T) {
+	results := []DrugSearchResult{
+		{CanonicalCode: "RxCUI:11289", MatchedName: "Coumadin", MatchType: DrugMatchFuzzy, MatchSource: DrugMatchSourceBrand, Score: 0.82},
+		{CanonicalCode: "RxCUI:11289", MatchedName: "warfarin", MatchType: DrugMatchExact, MatchSource: DrugMatchSourceSynonym, Score: 1.0},
+		{CanonicalCode: "RxCUI:5640", MatchedName: "ibuprofen", MatchType: DrugMatchFuzzy, MatchSource: DrugMatchSourceOHDSI, Score: 0.4},
+		{CanonicalCode: "RxCUI:1191", MatchedName: "aspirin", MatchType: DrugMatchFuzzy, MatchSource: DrugMatchSourceOHDSI, Score: 0.9},
+	}
+
+	ranked := rankDrugSearchResults(results, 0.8, 10)
+
+	assert.Len(t, ranked, 2)
+	assert.Equal(t, "RxCUI:11289", ranked[0].CanonicalCode)
+	assert.Equal(t, DrugMatchExact, ranked[0].MatchType)
+	assert.Equal(t, "RxCUI:1191", ranked[1].CanonicalCode)
+
+	limited := rankDrugSearchResults(results, 0.0, 1)
+	assert.Len(t, limited, 1)
+}
+
+func TestNormalizeDrugName(t *testing.T) {
+	assert.Equal(t, "metoprolol succinate", normalizeDrugName("  Metoprolol   SUCCINATE "))
+	assert.Equal(t, "war", normalizeDrugName("war%"))
+}
+
+func TestFormatConceptDrugCode(t *testing.T) {
+	assert.Equal(t, "RxCUI:11289", formatConceptDrugCode("RxNorm", "11289"))
+	assert.Equal(t, "ATC:B01AA03", formatConceptDrugCode("ATC", "B01AA03"))
+}
+
+func TestDrugSearchLimit(t *testing.T) {
+	assert.Equal(t, 10, drugSearchLimit(0))
+	assert.Equal(t, 10, drugSearchLimit(-3))
+	assert.Equal(t, 25, drugSearchLimit(25))
+	assert.Equal(t, 50, drugSearchLimit(1000000))
+}
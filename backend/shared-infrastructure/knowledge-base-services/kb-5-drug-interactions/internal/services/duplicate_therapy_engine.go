@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"kb-drug-interactions/internal/database"
@@ -68,6 +69,9 @@ type DuplicateTherapyResult struct {
 	AllowedCombination   bool                `json:"allowed_combination"`      // True if this is a clinically acceptable combination
 	Evidence             models.EvidenceLevel `json:"evidence"`
 	RequiresPharmacistReview bool            `json:"requires_pharmacist_review"`
+	AllowedPatterns      []DuplicatePatternMatch  `json:"allowed_patterns,omitempty"` // Order patterns that made the duplicate acceptable
+	Orders               []models.MedicationOrder `json:"orders,omitempty"`           // Active orders in the group, when orders were given
+	Notes                []string                 `json:"notes,omitempty"`            // Why covering patterns did not apply
 }
 
 // DuplicateDrugInfo represents information about a drug involved in duplication
@@ -78,50 +82,64 @@ type DuplicateDrugInfo struct {
 	TherapeuticClass string `json:"therapeutic_class"`
 }
 
-// DuplicateTherapyCheckRequest represents a request to check for duplicate therapy
+// DuplicateTherapyCheckRequest represents a request to check for duplicate therapy.
+// Drug codes are treated as active scheduled orders; medication orders add the
+// route, PRN flag, status and indication used by the allowed patterns.
 type DuplicateTherapyCheckRequest struct {
-	DrugCodes       []string               `json:"drug_codes" binding:"required,min=2"`
-	IncludeAllowed  bool                   `json:"include_allowed"`  // Include clinically allowed duplicates
-	CheckLevel      string                 `json:"check_level"`      // "strict" (ATC-5), "moderate" (ATC-4), "broad" (ATC-3)
-	PatientContext  *models.PatientContext `json:"patient_context,omitempty"`
+	DrugCodes        []string                 `json:"drug_codes"`
+	MedicationOrders []models.MedicationOrder `json:"medication_orders,omitempty"`
+	IncludeAllowed   bool                     `json:"include_allowed"` // Include clinically allowed duplicates
+	CheckLevel       string                   `json:"check_level"`     // "strict" (ATC-5), "moderate" (ATC-4), "broad" (ATC-3)
+	PatientContext   *models.PatientContext   `json:"patient_context,omitempty"`
 }
 
 // DuplicateTherapyEngine detects duplicate therapy situations
 type DuplicateTherapyEngine struct {
-	db      *database.Database
-	metrics *metrics.Collector
-
-	// Cache for therapeutic class mappings
-	classCache map[string][]DrugTherapeuticMapping
-	ruleCache  map[string][]DuplicateTherapyRule
-	cacheTTL   time.Duration
-	lastLoad   time.Time
+	db        *database.Database
+	metrics   *metrics.Collector
+	atcIndex  *ATCClassIndex    // Vocabulary classes for drugs without curated mappings
+	hierarchy *DiseaseHierarchy // Matches order indications for intentional combinations
+
+	// Cache for therapeutic class mappings, rules and allowed patterns
+	classCache   map[string][]DrugTherapeuticMapping
+	ruleCache    map[string][]DuplicateTherapyRule
+	patternCache map[string][]DuplicateAllowedPattern
+	cacheTTL     time.Duration
+	lastLoad     time.Time
+	cacheMutex   sync.RWMutex
 }
 
 // NewDuplicateTherapyEngine creates a new duplicate therapy detection engine
-func NewDuplicateTherapyEngine(db *database.Database, metrics *metrics.Collector) *DuplicateTherapyEngine {
+func NewDuplicateTherapyEngine(db *database.Database, atcIndex *ATCClassIndex, metrics *metrics.Collector) *DuplicateTherapyEngine {
 	return &DuplicateTherapyEngine{
-		db:         db,
-		metrics:    metrics,
-		classCache: make(map[string][]DrugTherapeuticMapping),
-		ruleCache:  make(map[string][]DuplicateTherapyRule),
-		cacheTTL:   30 * time.Minute,
+		db:           db,
+		metrics:      metrics,
+		atcIndex:     atcIndex,
+		hierarchy:    NewDiseaseHierarchy(),
+		classCache:   make(map[string][]DrugTherapeuticMapping),
+		ruleCache:    make(map[string][]DuplicateTherapyRule),
+		patternCache: make(map[string][]DuplicateAllowedPattern),
+		cacheTTL:     30 * time.Minute,
 	}
 }
 
-// CheckDuplicateTherapy evaluates a drug list for duplicate therapy
+// CheckDuplicateTherapy evaluates a drug list and medication orders for duplicate therapy.
+// Discontinued orders are ignored; duplicates that fit an allowed pattern (IV-to-PO
+// transition, scheduled + PRN, intentional combination) are marked allowed.
 func (dte *DuplicateTherapyEngine) CheckDuplicateTherapy(
 	ctx context.Context,
 	request DuplicateTherapyCheckRequest,
 	datasetVersion string,
 ) ([]DuplicateTherapyResult, error) {
-	if len(request.DrugCodes) < 2 {
+	orders := request.activeOrders()
+	if len(orders) < 2 {
 		return []DuplicateTherapyResult{}, nil
 	}
+	drugCodes := distinctOrderDrugCodes(orders)
 
 	timer := time.Now()
 	defer func() {
-		dte.metrics.RecordDuplicateTherapyCheck(time.Since(timer), len(request.DrugCodes))
+		dte.metrics.RecordDuplicateTherapyCheck(time.Since(timer), len(orders))
 	}()
 
 	// Determine check level (default to moderate)
@@ -132,7 +150,7 @@ func (dte *DuplicateTherapyEngine) CheckDuplicateTherapy(
 	atcLevel := dte.getATCLevelForCheckLevel(checkLevel)
 
 	// Load therapeutic classes for all drugs
-	drugClasses, err := dte.loadTherapeuticClasses(ctx, request.DrugCodes, datasetVersion)
+	drugClasses, err := dte.loadTherapeuticClasses(ctx, drugCodes, datasetVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load therapeutic classes: %w", err)
 	}
@@ -146,6 +164,23 @@ func (dte *DuplicateTherapyEngine) CheckDuplicateTherapy(
 		return nil, fmt.Errorf("failed to load duplicate therapy rules: %w", err)
 	}
 
+	patterns, err := dte.loadAllowedPatterns(ctx, datasetVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load allowed duplicate patterns: %w", err)
+	}
+
+	atcCodes := make(map[string][]string)
+	for _, class := range drugClasses {
+		code := strings.ToUpper(class.DrugCode)
+		atcCodes[code] = append(atcCodes[code], class.ATCCode)
+	}
+
+	var conditions []string
+	if request.PatientContext != nil {
+		conditions = request.PatientContext.Comorbidities
+	}
+	now := time.Now()
+
 	var results []DuplicateTherapyResult
 
 	// Check each class group for duplicates
@@ -160,12 +195,24 @@ func (dte *DuplicateTherapyEngine) CheckDuplicateTherapy(
 		// Check if this is an allowed combination
 		isAllowed := dte.isAllowedCombination(rule, drugs)
 
+		groupOrders := ordersForDrugs(orders, drugs)
+		var matches []DuplicatePatternMatch
+		var notes []string
+		if !isAllowed {
+			matches, notes, isAllowed = evaluateAllowedPatterns(groupOrders, atcCodes, conditions, patterns, dte.hierarchy, now)
+		}
+
 		// Skip allowed combinations if not requested
 		if isAllowed && !request.IncludeAllowed {
 			continue
 		}
 
 		result := dte.buildDuplicateResult(atcCode, drugs, rule, isAllowed)
+		result.AllowedPatterns = matches
+		result.Notes = notes
+		if len(request.MedicationOrders) > 0 {
+			result.Orders = groupOrders
+		}
 		results = append(results, result)
 
 		// Record metric
@@ -173,29 +220,103 @@ func (dte *DuplicateTherapyEngine) CheckDuplicateTherapy(
 	}
 
 	// Also check for exact duplicates (same drug code)
-	exactDuplicates := dte.findExactDuplicates(request.DrugCodes)
-	for drugCode, count := range exactDuplicates {
-		if count > 1 {
-			result := DuplicateTherapyResult{
-				DuplicateType:      "exact",
-				TherapeuticClass:   "Same medication",
-				DuplicateDrugs: []DuplicateDrugInfo{{
-					DrugCode: drugCode,
-					DrugName: drugCode,
-				}},
-				Severity:           models.SeverityMajor,
-				ClinicalRationale:  "The same medication appears multiple times in the drug list. This may indicate a prescribing error.",
-				ManagementStrategy: "Review medication list for duplicate entries. Reconcile orders from different prescribers or settings.",
-				Evidence:           models.EvidenceLevelA,
-				RequiresPharmacistReview: true,
+	exactDuplicates := dte.findExactDuplicates(orders)
+	for _, drugCode := range drugCodes {
+		drugOrders := exactDuplicates[drugCode]
+		if len(drugOrders) < 2 {
+			continue
+		}
+
+		matches, notes, isAllowed := evaluateAllowedPatterns(drugOrders, atcCodes, conditions, patterns, dte.hierarchy, now)
+		if isAllowed && !request.IncludeAllowed {
+			continue
+		}
+
+		drugName := drugCode
+		for _, order := range drugOrders {
+			if order.DrugName != "" {
+				drugName = order.DrugName
+				break
 			}
-			results = append(results, result)
 		}
+
+		result := DuplicateTherapyResult{
+			DuplicateType:      "exact",
+			TherapeuticClass:   "Same medication",
+			DuplicateDrugs: []DuplicateDrugInfo{{
+				DrugCode: drugCode,
+				DrugName: drugName,
+			}},
+			Severity:           models.SeverityMajor,
+			ClinicalRationale:  "The same medication appears multiple times in the drug list. This may indicate a prescribing error.",
+			ManagementStrategy: "Review medication list for duplicate entries. Reconcile orders from different prescribers or settings.",
+			AllowedCombination: isAllowed,
+			Evidence:           models.EvidenceLevelA,
+			RequiresPharmacistReview: !isAllowed,
+			AllowedPatterns:    matches,
+			Notes:              notes,
+		}
+		if len(request.MedicationOrders) > 0 {
+			result.Orders = drugOrders
+		}
+		results = append(results, result)
 	}
 
 	return results, nil
 }
 
+// activeOrders returns the request's medication orders that are, or will be,
+// administered, with each plain drug code as an active scheduled order. A drug
+// code with an order of its own is described by that order and is not added.
+func (r DuplicateTherapyCheckRequest) activeOrders() []models.MedicationOrder {
+	ordered := make(map[string]bool, len(r.MedicationOrders))
+	for _, order := range r.MedicationOrders {
+		ordered[normalizeATCDrugKey(order.DrugCode)] = true
+	}
+
+	orders := make([]models.MedicationOrder, 0, len(r.DrugCodes)+len(r.MedicationOrders))
+	for _, code := range r.DrugCodes {
+		if !ordered[normalizeATCDrugKey(code)] {
+			orders = append(orders, models.MedicationOrder{DrugCode: code})
+		}
+	}
+	for _, order := range r.MedicationOrders {
+		if order.IsActive() {
+			orders = append(orders, order)
+		}
+	}
+	return orders
+}
+
+// distinctOrderDrugCodes returns the upper-cased drug codes of the orders in order of first appearance
+func distinctOrderDrugCodes(orders []models.MedicationOrder) []string {
+	seen := make(map[string]bool)
+	var codes []string
+	for _, order := range orders {
+		code := strings.ToUpper(order.DrugCode)
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// ordersForDrugs returns the orders for the drugs of a class group
+func ordersForDrugs(orders []models.MedicationOrder, drugs []DuplicateDrugInfo) []models.MedicationOrder {
+	inGroup := make(map[string]bool)
+	for _, drug := range drugs {
+		inGroup[strings.ToUpper(drug.DrugCode)] = true
+	}
+	var groupOrders []models.MedicationOrder
+	for _, order := range orders {
+		if inGroup[strings.ToUpper(order.DrugCode)] {
+			groupOrders = append(groupOrders, order)
+		}
+	}
+	return groupOrders
+}
+
 // GetDrugTherapeuticClasses returns all therapeutic classifications for a drug
 func (dte *DuplicateTherapyEngine) GetDrugTherapeuticClasses(
 	ctx context.Context,
@@ -273,10 +394,12 @@ func (dte *DuplicateTherapyEngine) loadTherapeuticClasses(
 	cacheKey := fmt.Sprintf("%s:%s", strings.Join(normalizedCodes, ","), datasetVersion)
 
 	// Check cache
-	if time.Since(dte.lastLoad) < dte.cacheTTL {
-		if cached, exists := dte.classCache[cacheKey]; exists {
-			return cached, nil
-		}
+	dte.cacheMutex.RLock()
+	cached, exists := dte.classCache[cacheKey]
+	fresh := time.Since(dte.lastLoad) < dte.cacheTTL
+	dte.cacheMutex.RUnlock()
+	if fresh && exists {
+		return cached, nil
 	}
 
 	var classes []DrugTherapeuticMapping
@@ -289,13 +412,78 @@ func (dte *DuplicateTherapyEngine) loadTherapeuticClasses(
 		return nil, err
 	}
 
+	// Drugs without a curated mapping fall back to the vocabulary ATC classes
+	curated := make(map[string]bool)
+	for _, class := range classes {
+		curated[strings.ToUpper(class.DrugCode)] = true
+	}
+	for _, code := range normalizedCodes {
+		if !curated[code] {
+			classes = append(classes, dte.vocabularyTherapeuticClasses(code, datasetVersion)...)
+		}
+	}
+
 	// Update cache
+	dte.cacheMutex.Lock()
 	dte.classCache[cacheKey] = classes
 	dte.lastLoad = time.Now()
+	dte.cacheMutex.Unlock()
 
 	return classes, nil
 }
 
+// vocabularyTherapeuticClasses builds therapeutic class mappings for a drug from the
+// ATC vocabulary index, one per most-specific ATC code
+func (dte *DuplicateTherapyEngine) vocabularyTherapeuticClasses(
+	drugCode string,
+	datasetVersion string,
+) []DrugTherapeuticMapping {
+	if dte.atcIndex == nil {
+		return nil
+	}
+
+	atcCodes := dte.atcIndex.ClassesForDrug(drugCode)
+	drugName, exists := dte.atcIndex.DrugName(drugCode)
+	if !exists {
+		drugName = drugCode
+	}
+
+	var mappings []DrugTherapeuticMapping
+	for _, atcCode := range atcCodes {
+		if !isLeafATCCode(atcCode, atcCodes) {
+			continue
+		}
+
+		// Name the class at the chemical subgroup level where one exists
+		className, _ := dte.atcIndex.ClassName(TruncateATCCode(atcCode, 4))
+		if className == "" {
+			className, _ = dte.atcIndex.ClassName(atcCode)
+		}
+
+		mappings = append(mappings, DrugTherapeuticMapping{
+			DatasetVersion:   datasetVersion,
+			DrugCode:         drugCode,
+			DrugName:         drugName,
+			ATCCode:          atcCode,
+			ATCLevel:         ATCLevel(atcCode),
+			TherapeuticClass: className,
+			Active:           true,
+		})
+	}
+
+	return mappings
+}
+
+// isLeafATCCode reports whether no other code in the list is more specific than atcCode
+func isLeafATCCode(atcCode string, atcCodes []string) bool {
+	for _, other := range atcCodes {
+		if len(other) > len(atcCode) && strings.HasPrefix(other, atcCode) {
+			return false
+		}
+	}
+	return true
+}
+
 // loadDuplicateTherapyRules loads rules from database or cache
 func (dte *DuplicateTherapyEngine) loadDuplicateTherapyRules(
 	ctx context.Context,
@@ -304,10 +492,12 @@ func (dte *DuplicateTherapyEngine) loadDuplicateTherapyRules(
 	cacheKey := datasetVersion
 
 	// Check cache
-	if time.Since(dte.lastLoad) < dte.cacheTTL {
-		if cached, exists := dte.ruleCache[cacheKey]; exists {
-			return cached, nil
-		}
+	dte.cacheMutex.RLock()
+	cached, exists := dte.ruleCache[cacheKey]
+	fresh := time.Since(dte.lastLoad) < dte.cacheTTL
+	dte.cacheMutex.RUnlock()
+	if fresh && exists {
+		return cached, nil
 	}
 
 	var rules []DuplicateTherapyRule
@@ -320,7 +510,9 @@ func (dte *DuplicateTherapyEngine) loadDuplicateTherapyRules(
 	}
 
 	// Update cache
+	dte.cacheMutex.Lock()
 	dte.ruleCache[cacheKey] = rules
+	dte.cacheMutex.Unlock()
 
 	return rules, nil
 }
@@ -347,12 +539,13 @@ func (dte *DuplicateTherapyEngine) groupByTherapeuticClass(
 	groups := make(map[string][]DuplicateDrugInfo)
 
 	for _, class := range classes {
-		if class.ATCLevel > atcLevel {
+		// Codes coarser than the check level cannot be grouped at that level
+		if class.ATCLevel < atcLevel {
 			continue
 		}
 
 		// Truncate ATC code to specified level
-		atcKey := dte.truncateATCCode(class.ATCCode, atcLevel)
+		atcKey := TruncateATCCode(class.ATCCode, atcLevel)
 		if atcKey == "" {
 			continue
 		}
@@ -375,27 +568,6 @@ func (dte *DuplicateTherapyEngine) groupByTherapeuticClass(
 	return groups
 }
 
-// truncateATCCode truncates an ATC code to specified level
-func (dte *DuplicateTherapyEngine) truncateATCCode(atcCode string, level int) string {
-	// ATC code structure:
-	// Level 1: 1 char (A = Alimentary)
-	// Level 2: 3 chars (A10 = Drugs used in diabetes)
-	// Level 3: 4 chars (A10B = Blood glucose lowering drugs)
-	// Level 4: 5 chars (A10BA = Biguanides)
-	// Level 5: 7 chars (A10BA02 = Metformin)
-
-	lengths := map[int]int{1: 1, 2: 3, 3: 4, 4: 5, 5: 7}
-	targetLen, exists := lengths[level]
-	if !exists {
-		return atcCode
-	}
-
-	if len(atcCode) >= targetLen {
-		return atcCode[:targetLen]
-	}
-	return atcCode
-}
-
 // deduplicateDrugs removes duplicate drug entries
 func (dte *DuplicateTherapyEngine) deduplicateDrugs(drugs []DuplicateDrugInfo) []DuplicateDrugInfo {
 	seen := make(map[string]bool)
@@ -500,20 +672,23 @@ func (dte *DuplicateTherapyEngine) buildDuplicateResult(
 	return result
 }
 
-// findExactDuplicates finds drugs that appear multiple times
-func (dte *DuplicateTherapyEngine) findExactDuplicates(drugCodes []string) map[string]int {
-	counts := make(map[string]int)
-	for _, code := range drugCodes {
-		normalized := strings.ToUpper(code)
-		counts[normalized]++
+// findExactDuplicates groups orders by drug code; drugs with more than one order are duplicates
+func (dte *DuplicateTherapyEngine) findExactDuplicates(orders []models.MedicationOrder) map[string][]models.MedicationOrder {
+	byDrug := make(map[string][]models.MedicationOrder)
+	for _, order := range orders {
+		normalized := strings.ToUpper(order.DrugCode)
+		byDrug[normalized] = append(byDrug[normalized], order)
 	}
-	return counts
+	return byDrug
 }
 
 // ClearCache clears all caches
 func (dte *DuplicateTherapyEngine) ClearCache() {
+	dte.cacheMutex.Lock()
+	defer dte.cacheMutex.Unlock()
 	dte.classCache = make(map[string][]DrugTherapeuticMapping)
 	dte.ruleCache = make(map[string][]DuplicateTherapyRule)
+	dte.patternCache = make(map[string][]DuplicateAllowedPattern)
 	dte.lastLoad = time.Time{}
 }
 
@@ -545,7 +720,7 @@ func (dte *DuplicateTherapyEngine) GetATCHierarchy(
 
 	// Generate all levels from the ATC code
 	for level := 1; level <= 5; level++ {
-		truncated := dte.truncateATCCode(atcCode, level)
+		truncated := TruncateATCCode(atcCode, level)
 		if truncated != "" && len(truncated) >= level {
 			hierarchy[level] = truncated
 		}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"kb-drug-interactions/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Allowed duplicate pattern types (DuplicateAllowedPattern.PatternType)
+const (
+	DuplicatePatternRouteTransition        = "route_transition"
+	DuplicatePatternScheduledPRN           = "scheduled_prn"
+	DuplicatePatternIntentionalCombination = "intentional_combination"
+)
+
+// DuplicateAllowedPattern is an order pattern that is clinically acceptable
+// within an ATC class, such as scheduled plus PRN or an IV-to-PO transition
+type DuplicateAllowedPattern struct {
+	ID                  uuid.UUID            `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	DatasetVersion      string               `gorm:"not null;index" json:"dataset_version"`
+	PatternCode         string               `gorm:"not null" json:"pattern_code"`
+	PatternType         string               `gorm:"not null" json:"pattern_type"`
+	ATCCodePattern      string               `gorm:"not null;index" json:"atc_code_pattern"`
+	Description         string               `gorm:"type:text;not null" json:"description"`
+	MaxDailyDose        *float64             `json:"max_daily_dose,omitempty"`
+	DoseUnit            *string              `json:"dose_unit,omitempty"`
+	MaxOverlapHours     *float64             `json:"max_overlap_hours,omitempty"`
+	ComponentATCClasses models.StringArray   `gorm:"type:text[]" json:"component_atc_classes,omitempty"` // One component per entry, alternatives separated by "|"
+	IndicationCodes     models.StringArray   `gorm:"type:text[]" json:"indication_codes,omitempty"`
+	Rationale           string               `gorm:"type:text;not null" json:"rationale"`
+	Evidence            models.EvidenceLevel `gorm:"not null" json:"evidence"`
+	Active              bool                 `gorm:"default:true" json:"active"`
+	CreatedAt           time.Time            `json:"created_at"`
+	UpdatedAt           time.Time            `json:"updated_at"`
+}
+
+// TableName specifies the database table for GORM
+func (DuplicateAllowedPattern) TableName() string {
+	return "ddi_duplicate_allowed_patterns"
+}
+
+// DuplicatePatternMatch records an allowed pattern that accounted for orders in a duplicate group
+type DuplicatePatternMatch struct {
+	PatternCode string               `json:"pattern_code"`
+	PatternType string               `json:"pattern_type"`
+	Description string               `json:"description"`
+	Detail      string               `json:"detail"`
+	Rationale   string               `json:"rationale"`
+	Evidence    models.EvidenceLevel `json:"evidence"`
+}
+
+// loadAllowedPatterns loads allowed duplicate patterns from database or cache,
+// most specific ATC pattern first
+func (dte *DuplicateTherapyEngine) loadAllowedPatterns(
+	ctx context.Context,
+	datasetVersion string,
+) ([]DuplicateAllowedPattern, error) {
+	dte.cacheMutex.RLock()
+	cached, exists := dte.patternCache[datasetVersion]
+	fresh := time.Since(dte.lastLoad) < dte.cacheTTL
+	dte.cacheMutex.RUnlock()
+	if fresh && exists {
+		return cached, nil
+	}
+
+	var patterns []DuplicateAllowedPattern
+	err := dte.db.DB.WithContext(ctx).
+		Where("dataset_version = ? AND active = true", datasetVersion).
+		Order("length(atc_code_pattern) DESC, pattern_code").
+		Find(&patterns).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	dte.cacheMutex.Lock()
+	dte.patternCache[datasetVersion] = patterns
+	dte.cacheMutex.Unlock()
+
+	return patterns, nil
+}
+
+// evaluateAllowedPatterns checks the active orders of a duplicate group against
+// the allowed patterns. Route transitions are applied first and account for the
+// outgoing orders; whatever remains must then be a single order or fit one
+// scheduled + PRN or intentional combination pattern. atcCodes maps upper-cased
+// drug codes to their ATC codes; conditions are patient disease codes used with
+// order indications. Notes explain why a pattern that covers the group did not apply.
+func evaluateAllowedPatterns(
+	orders []models.MedicationOrder,
+	atcCodes map[string][]string,
+	conditions []string,
+	patterns []DuplicateAllowedPattern,
+	hierarchy *DiseaseHierarchy,
+	now time.Time,
+) ([]DuplicatePatternMatch, []string, bool) {
+	var matches []DuplicatePatternMatch
+	var notes []string
+	remaining := orders
+
+	for _, pattern := range patterns {
+		if pattern.PatternType != DuplicatePatternRouteTransition || !patternCoversOrders(pattern, remaining, atcCodes) {
+			continue
+		}
+		var detail string
+		var transitionNotes []string
+		remaining, detail, transitionNotes = applyRouteTransition(pattern, remaining, now)
+		notes = append(notes, transitionNotes...)
+		if detail != "" {
+			matches = append(matches, newDuplicatePatternMatch(pattern, detail))
+		}
+	}
+
+	if len(remaining) < 2 {
+		return matches, notes, len(matches) > 0
+	}
+
+	for _, pattern := range patterns {
+		if !patternCoversOrders(pattern, remaining, atcCodes) {
+			continue
+		}
+
+		var detail, note string
+		switch pattern.PatternType {
+		case DuplicatePatternScheduledPRN:
+			detail, note = matchScheduledPRN(pattern, remaining)
+		case DuplicatePatternIntentionalCombination:
+			detail, note = matchIntentionalCombination(pattern, remaining, atcCodes, conditions, hierarchy)
+		default:
+			continue
+		}
+
+		if detail != "" {
+			return append(matches, newDuplicatePatternMatch(pattern, detail)), notes, true
+		}
+		if note != "" {
+			notes = append(notes, note)
+		}
+	}
+
+	return nil, notes, false
+}
+
+// applyRouteTransition removes orders pending discontinuation that overlap a
+// replacement by a different route for no longer than the pattern allows
+func applyRouteTransition(
+	pattern DuplicateAllowedPattern,
+	orders []models.MedicationOrder,
+	now time.Time,
+) ([]models.MedicationOrder, string, []string) {
+	var continuing, outgoing []models.MedicationOrder
+	for _, order := range orders {
+		if order.Status == models.OrderStatusPendingDiscontinue {
+			outgoing = append(outgoing, order)
+		} else {
+			continuing = append(continuing, order)
+		}
+	}
+
+	remaining := continuing
+	var details, notes []string
+	for _, old := range outgoing {
+		replacement, found := findRouteReplacement(old, continuing)
+		if !found {
+			remaining = append(remaining, old)
+			continue
+		}
+		if old.StopTime == nil {
+			notes = append(notes, fmt.Sprintf("%s: %s has no stop time, so the transition overlap is unknown",
+				pattern.PatternCode, orderLabel(old)))
+			remaining = append(remaining, old)
+			continue
+		}
+
+		start := now
+		if replacement.StartTime != nil {
+			start = *replacement.StartTime
+		}
+		overlap := old.StopTime.Sub(start).Hours()
+		if overlap < 0 {
+			overlap = 0
+		}
+		if pattern.MaxOverlapHours != nil && overlap > *pattern.MaxOverlapHours {
+			notes = append(notes, fmt.Sprintf("%s: %s overlaps %s by %s h, more than %s h",
+				pattern.PatternCode, orderLabel(old), orderLabel(replacement),
+				formatHours(overlap), formatHours(*pattern.MaxOverlapHours)))
+			remaining = append(remaining, old)
+			continue
+		}
+
+		details = append(details, fmt.Sprintf("%s (%s) to %s (%s), overlap %s h",
+			orderLabel(old), old.Route, orderLabel(replacement), replacement.Route, formatHours(overlap)))
+	}
+
+	return remaining, strings.Join(details, "; "), notes
+}
+
+// findRouteReplacement finds a continuing order by a different route
+func findRouteReplacement(old models.MedicationOrder, continuing []models.MedicationOrder) (models.MedicationOrder, bool) {
+	if old.Route == "" {
+		return models.MedicationOrder{}, false
+	}
+	for _, order := range continuing {
+		if order.Route != "" && !strings.EqualFold(order.Route, old.Route) {
+			return order, true
+		}
+	}
+	return models.MedicationOrder{}, false
+}
+
+// matchScheduledPRN checks for one scheduled and one PRN order whose combined
+// maximum daily dose is within the pattern limit
+func matchScheduledPRN(pattern DuplicateAllowedPattern, orders []models.MedicationOrder) (string, string) {
+	if len(orders) != 2 || orders[0].PRN == orders[1].PRN {
+		return "", ""
+	}
+	scheduled, prn := orders[0], orders[1]
+	if scheduled.PRN {
+		scheduled, prn = prn, scheduled
+	}
+	detail := fmt.Sprintf("%s scheduled with %s PRN", orderLabel(scheduled), orderLabel(prn))
+
+	if pattern.MaxDailyDose == nil || pattern.DoseUnit == nil {
+		return detail, ""
+	}
+
+	total := 0.0
+	for _, order := range orders {
+		daily, ok := orderDailyDose(order, *pattern.DoseUnit)
+		if !ok {
+			return "", fmt.Sprintf("%s: daily dose of %s could not be determined", pattern.PatternCode, orderLabel(order))
+		}
+		total += daily
+	}
+	limit := fmt.Sprintf("%s %s", strconv.FormatFloat(*pattern.MaxDailyDose, 'f', -1, 64), *pattern.DoseUnit)
+	combined := fmt.Sprintf("%s %s", strconv.FormatFloat(roundDose(total), 'f', -1, 64), *pattern.DoseUnit)
+	if total > *pattern.MaxDailyDose {
+		return "", fmt.Sprintf("%s: combined maximum daily dose %s exceeds %s", pattern.PatternCode, combined, limit)
+	}
+
+	return fmt.Sprintf("%s; combined maximum daily dose %s within %s", detail, combined, limit), ""
+}
+
+// matchIntentionalCombination checks that the orders fill the pattern's
+// components one each and, when required, that an indication matches
+func matchIntentionalCombination(
+	pattern DuplicateAllowedPattern,
+	orders []models.MedicationOrder,
+	atcCodes map[string][]string,
+	conditions []string,
+	hierarchy *DiseaseHierarchy,
+) (string, string) {
+	if len(orders) != len(pattern.ComponentATCClasses) {
+		return "", ""
+	}
+	if !assignComponents(orders, pattern.ComponentATCClasses, atcCodes, make([]bool, len(orders)), 0) {
+		return "", ""
+	}
+
+	labels := make([]string, len(orders))
+	for i, order := range orders {
+		labels[i] = orderLabel(order)
+	}
+	detail := strings.Join(labels, " with ")
+
+	if len(pattern.IndicationCodes) == 0 {
+		return detail, ""
+	}
+
+	candidates := append([]string{}, conditions...)
+	for _, order := range orders {
+		if order.Indication != "" {
+			candidates = append(candidates, order.Indication)
+		}
+	}
+	for _, candidate := range candidates {
+		patientRef := NormalizeDiseaseCode("", candidate)
+		for _, code := range pattern.IndicationCodes {
+			if _, ok := hierarchy.Match(patientRef, NormalizeDiseaseCode("", code)); ok {
+				return fmt.Sprintf("%s for %s", detail, patientRef.Code), ""
+			}
+		}
+	}
+
+	return "", fmt.Sprintf("%s: no order indication or patient condition matches %s",
+		pattern.PatternCode, strings.Join(pattern.IndicationCodes, ", "))
+}
+
+// assignComponents reports whether each component can be filled by a distinct order
+func assignComponents(
+	orders []models.MedicationOrder,
+	components []string,
+	atcCodes map[string][]string,
+	used []bool,
+	component int,
+) bool {
+	if component == len(components) {
+		return true
+	}
+	alternatives := strings.Split(components[component], "|")
+	for i, order := range orders {
+		if used[i] || !atcCodesMatch(atcCodes[strings.ToUpper(order.DrugCode)], alternatives) {
+			continue
+		}
+		used[i] = true
+		if assignComponents(orders, components, atcCodes, used, component+1) {
+			return true
+		}
+		used[i] = false
+	}
+	return false
+}
+
+// patternCoversOrders reports whether every order's drug falls under the pattern's ATC class
+func patternCoversOrders(pattern DuplicateAllowedPattern, orders []models.MedicationOrder, atcCodes map[string][]string) bool {
+	prefix := []string{pattern.ATCCodePattern}
+	for _, order := range orders {
+		if !atcCodesMatch(atcCodes[strings.ToUpper(order.DrugCode)], prefix) {
+			return false
+		}
+	}
+	return len(orders) > 0
+}
+
+// atcCodesMatch reports whether any ATC code falls under any of the class prefixes
+func atcCodesMatch(atcCodes []string, prefixes []string) bool {
+	for _, code := range atcCodes {
+		for _, prefix := range prefixes {
+			prefix = strings.ToUpper(strings.TrimSpace(prefix))
+			if prefix != "" && strings.HasPrefix(strings.ToUpper(code), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newDuplicatePatternMatch records a pattern match with what it matched
+func newDuplicatePatternMatch(pattern DuplicateAllowedPattern, detail string) DuplicatePatternMatch {
+	return DuplicatePatternMatch{
+		PatternCode: pattern.PatternCode,
+		PatternType: pattern.PatternType,
+		Description: pattern.Description,
+		Detail:      detail,
+		Rationale:   pattern.Rationale,
+		Evidence:    pattern.Evidence,
+	}
+}
+
+// orderLabel names an order in notes: its ID, else the drug
+func orderLabel(order models.MedicationOrder) string {
+	switch {
+	case order.OrderID != "":
+		return "order " + order.OrderID
+	case order.DrugName != "":
+		return order.DrugName
+	}
+	return order.DrugCode
+}
+
+// formatHours formats a duration in hours to one decimal place
+func formatHours(hours float64) string {
+	return strconv.FormatFloat(math.Round(hours*10)/10, 'f', -1, 64)
+}
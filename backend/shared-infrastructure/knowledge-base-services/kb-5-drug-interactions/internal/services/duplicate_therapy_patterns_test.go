@@ -0,0 +1,170 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// ALLOWED DUPLICATE PATTERN TESTS
+// ============================================================================
+
+var duplicatePatternTestNow = time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+func TestEvaluateAllowedPatterns_ScheduledPRNWithinDailyMax(t *testing.T) {
+	patterns := []DuplicateAllowedPattern{
+		{PatternCode: "DUP-ALLOW-ACETAMINOPHEN-SCHED-PRN", PatternType: DuplicatePatternScheduledPRN, ATCCodePattern: "N02BE01",
+			Description: "Scheduled acetaminophen with PRN acetaminophen", MaxDailyDose: floatPtr(4000), DoseUnit: stringPtr("mg"),
+			Evidence: models.EvidenceLevelA},
+	}
+	atcCodes := map[string][]string{"RXCUI:161": {"N02BE01"}} // Acetaminophen
+
+	scheduled := models.MedicationOrder{OrderID: "A1", DrugCode: "RxCUI:161", Dose: 650, DoseUnit: "mg", Frequency: "q8h"}
+	prn := models.MedicationOrder{OrderID: "A2", DrugCode: "RxCUI:161", Dose: 500, DoseUnit: "mg", Frequency: "q6h", PRN: true}
+
+	matches, notes, allowed := evaluateAllowedPatterns([]models.MedicationOrder{scheduled, prn}, atcCodes, nil, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	require.True(t, allowed)
+	assert.Empty(t, notes)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "DUP-ALLOW-ACETAMINOPHEN-SCHED-PRN", matches[0].PatternCode)
+	assert.Equal(t, "order A1 scheduled with order A2 PRN; combined maximum daily dose 3950 mg within 4000 mg", matches[0].Detail)
+
+	// 650 mg q6h scheduled plus 500 mg q6h PRN can reach 4.6 g/day
+	scheduled.Frequency = "q6h"
+	matches, notes, allowed = evaluateAllowedPatterns([]models.MedicationOrder{scheduled, prn}, atcCodes, nil, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	assert.False(t, allowed)
+	assert.Empty(t, matches)
+	assert.Equal(t, []string{"DUP-ALLOW-ACETAMINOPHEN-SCHED-PRN: combined maximum daily dose 4600 mg exceeds 4000 mg"}, notes)
+
+	// Two scheduled orders are a duplicate regardless of dose
+	prn.PRN = false
+	_, _, allowed = evaluateAllowedPatterns([]models.MedicationOrder{scheduled, prn}, atcCodes, nil, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	assert.False(t, allowed)
+}
+
+func TestEvaluateAllowedPatterns_ScheduledPRNWithoutDoseLimit(t *testing.T) {
+	patterns := []DuplicateAllowedPattern{
+		{PatternCode: "DUP-ALLOW-OPIOID-SCHED-PRN", PatternType: DuplicatePatternScheduledPRN, ATCCodePattern: "N02A",
+			Evidence: models.EvidenceLevelB},
+	}
+	atcCodes := map[string][]string{
+		"RXCUI:7804": {"N02AA05"}, // Oxycodone
+		"RXCUI:7052": {"N02AA01"}, // Morphine
+	}
+
+	orders := []models.MedicationOrder{
+		{DrugCode: "RxCUI:7804", DrugName: "Oxycodone ER", Dose: 20, DoseUnit: "mg", Frequency: "q12h"},
+		{DrugCode: "RxCUI:7052", DrugName: "Morphine IR", Dose: 5, DoseUnit: "mg", Frequency: "q4h", PRN: true},
+	}
+
+	matches, _, allowed := evaluateAllowedPatterns(orders, atcCodes, nil, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	require.True(t, allowed)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "Oxycodone ER scheduled with Morphine IR PRN", matches[0].Detail)
+}
+
+func TestEvaluateAllowedPatterns_RouteTransition(t *testing.T) {
+	patterns := []DuplicateAllowedPattern{
+		{PatternCode: "DUP-ALLOW-ANTIBACTERIAL-IV-PO", PatternType: DuplicatePatternRouteTransition, ATCCodePattern: "J01",
+			MaxOverlapHours: floatPtr(24), Evidence: models.EvidenceLevelB},
+	}
+	atcCodes := map[string][]string{"RXCUI:82122": {"J01MA12"}} // Levofloxacin
+
+	stop := time.Date(2025, 3, 1, 20, 0, 0, 0, time.UTC)
+	iv := models.MedicationOrder{OrderID: "IV1", DrugCode: "RxCUI:82122", Route: "IV",
+		Status: models.OrderStatusPendingDiscontinue, StopTime: &stop}
+	po := models.MedicationOrder{OrderID: "PO1", DrugCode: "RxCUI:82122", Route: "PO"}
+
+	// The oral order starts now, 12 h before the IV order stops
+	matches, notes, allowed := evaluateAllowedPatterns([]models.MedicationOrder{iv, po}, atcCodes, nil, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	require.True(t, allowed)
+	assert.Empty(t, notes)
+	require.Len(t, matches, 1)
+	assert.Equal(t, DuplicatePatternRouteTransition, matches[0].PatternType)
+	assert.Equal(t, "order IV1 (IV) to order PO1 (PO), overlap 12 h", matches[0].Detail)
+
+	longStop := stop.Add(24 * time.Hour)
+	iv.StopTime = &longStop
+	_, notes, allowed = evaluateAllowedPatterns([]models.MedicationOrder{iv, po}, atcCodes, nil, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	assert.False(t, allowed)
+	assert.Equal(t, []string{"DUP-ALLOW-ANTIBACTERIAL-IV-PO: order IV1 overlaps order PO1 by 36 h, more than 24 h"}, notes)
+
+	iv.StopTime = nil
+	_, notes, allowed = evaluateAllowedPatterns([]models.MedicationOrder{iv, po}, atcCodes, nil, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	assert.False(t, allowed)
+	assert.Equal(t, []string{"DUP-ALLOW-ANTIBACTERIAL-IV-PO: order IV1 has no stop time, so the transition overlap is unknown"}, notes)
+
+	// Both orders continuing is not a transition
+	iv.Status, iv.StopTime = models.OrderStatusActive, &stop
+	_, _, allowed = evaluateAllowedPatterns([]models.MedicationOrder{iv, po}, atcCodes, nil, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	assert.False(t, allowed)
+}
+
+func TestEvaluateAllowedPatterns_IntentionalCombination(t *testing.T) {
+	patterns := []DuplicateAllowedPattern{
+		{PatternCode: "DUP-ALLOW-DUAL-ANTIPLATELET", PatternType: DuplicatePatternIntentionalCombination, ATCCodePattern: "B01AC",
+			ComponentATCClasses: models.StringArray{"B01AC06", "B01AC04|B01AC22|B01AC24"},
+			IndicationCodes:     models.StringArray{"I21", "Z955"}, Evidence: models.EvidenceLevelA},
+		{PatternCode: "DUP-ALLOW-INSULIN-BASAL-BOLUS", PatternType: DuplicatePatternIntentionalCombination, ATCCodePattern: "A10A",
+			ComponentATCClasses: models.StringArray{"A10AE|A10AC", "A10AB"}, Evidence: models.EvidenceLevelA},
+	}
+	atcCodes := map[string][]string{
+		"RXCUI:1191":   {"B01AC06"}, // Aspirin
+		"RXCUI:32968":  {"B01AC04"}, // Clopidogrel
+		"RXCUI:274783": {"A10AE04"}, // Insulin glargine
+		"RXCUI:86009":  {"A10AB04"}, // Insulin lispro
+	}
+
+	insulin := []models.MedicationOrder{{DrugCode: "RxCUI:274783"}, {DrugCode: "RxCUI:86009"}}
+	matches, _, allowed := evaluateAllowedPatterns(insulin, atcCodes, nil, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	require.True(t, allowed)
+	assert.Equal(t, "DUP-ALLOW-INSULIN-BASAL-BOLUS", matches[0].PatternCode)
+
+	// Two basal insulins do not fill both components
+	_, _, allowed = evaluateAllowedPatterns([]models.MedicationOrder{{DrugCode: "RxCUI:274783"}, {DrugCode: "RxCUI:274783"}}, atcCodes, nil, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	assert.False(t, allowed)
+
+	dapt := []models.MedicationOrder{{DrugCode: "RxCUI:1191"}, {DrugCode: "RxCUI:32968", Indication: "I21.4"}}
+	matches, _, allowed = evaluateAllowedPatterns(dapt, atcCodes, nil, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	require.True(t, allowed)
+	assert.Equal(t, "RxCUI:1191 with RxCUI:32968 for I214", matches[0].Detail)
+
+	// Patient conditions stand in for order indications
+	dapt[1].Indication = ""
+	_, _, allowed = evaluateAllowedPatterns(dapt, atcCodes, []string{"Z95.5"}, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	assert.True(t, allowed)
+
+	_, notes, allowed := evaluateAllowedPatterns(dapt, atcCodes, []string{"I48.0"}, patterns, NewDiseaseHierarchy(), duplicatePatternTestNow)
+	assert.False(t, allowed)
+	assert.Equal(t, []string{"DUP-ALLOW-DUAL-ANTIPLATELET: no order indication or patient condition matches I21, Z955"}, notes)
+}
+
+func TestDuplicateTherapyCheckRequest_ActiveOrders(t *testing.T) {
+	request := DuplicateTherapyCheckRequest{
+		DrugCodes: []string{"RxCUI:5640", "7646", "RxCUI:8640"},
+		MedicationOrders: []models.MedicationOrder{
+			{OrderID: "1", DrugCode: "rxcui:5640", Status: models.OrderStatusPending, PRN: true},
+			{OrderID: "2", DrugCode: "RxCUI:7646", Status: models.OrderStatusDiscontinued},
+			{OrderID: "3", DrugCode: "RxCUI:7646", Status: models.OrderStatusPendingDiscontinue},
+		},
+	}
+
+	// Drug codes with their own order are not added again as scheduled orders
+	orders := request.activeOrders()
+	require.Len(t, orders, 3)
+	assert.Equal(t, "RxCUI:8640", orders[0].DrugCode)
+	assert.Equal(t, "1", orders[1].OrderID)
+	assert.Equal(t, "3", orders[2].OrderID)
+	assert.Equal(t, []string{"RXCUI:8640", "RXCUI:5640", "RXCUI:7646"}, distinctOrderDrugCodes(orders))
+
+	// No false exact duplicate, and the PRN order is not paired with a phantom scheduled one
+	byDrug := (&DuplicateTherapyEngine{}).findExactDuplicates(orders)
+	require.Len(t, byDrug["RXCUI:5640"], 1)
+	assert.True(t, byDrug["RXCUI:5640"][0].PRN)
+	assert.Len(t, byDrug["RXCUI:7646"], 1)
+}
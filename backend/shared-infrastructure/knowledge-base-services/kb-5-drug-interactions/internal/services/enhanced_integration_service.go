@@ -20,6 +20,12 @@ type EnhancedIntegrationService struct {
 	classEngine        *ClassInteractionEngine
 	modifierEngine     *FoodAlcoholHerbalEngine
 	matrixEngine       *EnhancedInteractionMatrixService
+	doseAdjustmentEngine *OrganDoseAdjustmentEngine
+	cumulativeDoseEngine *CumulativeDoseEngine
+	reproductiveEngine *ReproductiveSafetyEngine
+	pimEngine          *PIMScreeningEngine
+	pediatricEngine    *PediatricSafetyEngine
+	drugDiseaseEngine  *DrugDiseaseEngine
 	logger             *zap.Logger
 	configProvider     models.ConfigProvider
 }
@@ -27,6 +33,7 @@ type EnhancedIntegrationService struct {
 // ComprehensiveInteractionRequest represents the complete clinical context for interaction analysis
 type ComprehensiveInteractionRequest struct {
 	DrugCodes        []string                    `json:"drug_codes"`
+	MedicationOrders []models.MedicationOrder    `json:"medication_orders,omitempty"` // Doses for renal/hepatic and cumulative dose checks
 	PatientContext   models.PatientContext       `json:"patient_context"`
 	ModifierContext  ModifierContext             `json:"modifier_context"`
 	DatasetVersion   string                      `json:"dataset_version"`
@@ -43,8 +50,19 @@ type ComprehensiveInteractionResponse struct {
 	// Core interaction results
 	DrugDrugInteractions   []models.EnhancedInteractionResult `json:"drug_drug_interactions"`
 	PGxInteractions        []models.EnhancedInteractionResult `json:"pgx_interactions"`
+	OrganFunction          *models.OrganFunctionAssessment    `json:"organ_function,omitempty"`
+	PGxTranslations        []GenotypeTranslation              `json:"pgx_translations,omitempty"`
+	PGxPhenoconversions    []PhenoconversionResult            `json:"pgx_phenoconversions,omitempty"`
+	PGxSafetyFindings      []models.EnhancedInteractionResult `json:"pgx_safety_findings"`
 	ClassInteractions      []models.EnhancedInteractionResult `json:"class_interactions"`
 	ModifierInteractions   []ModifierInteractionResult        `json:"modifier_interactions"`
+	DoseAdjustments        []DoseAdjustmentResult             `json:"dose_adjustments,omitempty"`
+	CumulativeDoses        []CumulativeDoseResult             `json:"cumulative_doses,omitempty"`
+	ReproductiveFindings   []models.EnhancedInteractionResult `json:"reproductive_findings,omitempty"`
+	PIMFindings            []models.EnhancedInteractionResult `json:"pim_findings,omitempty"`
+	PediatricFindings      []models.EnhancedInteractionResult `json:"pediatric_findings,omitempty"`
+	DrugDiseaseFindings    []DrugDiseaseResult                `json:"drug_disease_findings,omitempty"`
+	Notes                  []string                           `json:"notes,omitempty"` // Checks skipped or approximated
 	
 	// Clinical synthesis
 	OverallRiskScore       decimal.Decimal                    `json:"overall_risk_score"`
@@ -89,6 +107,12 @@ func NewEnhancedIntegrationService(
 	classEngine *ClassInteractionEngine,
 	modifierEngine *FoodAlcoholHerbalEngine,
 	matrixEngine *EnhancedInteractionMatrixService,
+	doseAdjustmentEngine *OrganDoseAdjustmentEngine,
+	cumulativeDoseEngine *CumulativeDoseEngine,
+	reproductiveEngine *ReproductiveSafetyEngine,
+	pimEngine *PIMScreeningEngine,
+	pediatricEngine *PediatricSafetyEngine,
+	drugDiseaseEngine *DrugDiseaseEngine,
 	logger *zap.Logger,
 	configProvider models.ConfigProvider,
 ) *EnhancedIntegrationService {
@@ -97,6 +121,12 @@ func NewEnhancedIntegrationService(
 		classEngine:      classEngine,
 		modifierEngine:   modifierEngine,
 		matrixEngine:     matrixEngine,
+		doseAdjustmentEngine: doseAdjustmentEngine,
+		cumulativeDoseEngine: cumulativeDoseEngine,
+		reproductiveEngine: reproductiveEngine,
+		pimEngine:        pimEngine,
+		pediatricEngine:  pediatricEngine,
+		drugDiseaseEngine: drugDiseaseEngine,
 		logger:           logger,
 		configProvider:   configProvider,
 	}
@@ -116,55 +146,191 @@ func (eis *EnhancedIntegrationService) PerformComprehensiveAnalysis(
 	
 	requestLogger.Info("Starting comprehensive interaction analysis")
 	
+	// Derive renal and hepatic function from raw labs before any engine runs
+	organFunction, err := ResolvePatientContext(&request.PatientContext)
+	if err != nil {
+		return nil, err
+	}
+	
+	organDosingFunction := patientOrganFunction(&request.PatientContext)
+	
+	reproductiveStatus, err := ResolveReproductiveStatus(request.PatientContext.Reproductive)
+	if err != nil {
+		return nil, err
+	}
+	
+	pimPatient := PIMPatientFromContext(&request.PatientContext)
+	
+	pediatricPatient, err := PediatricPatientFromContext(&request.PatientContext)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Translate lab genotypes to phenotypes before any engine runs
+	pgxMarkers, pgxTranslations, err := eis.pgxEngine.ResolvePatientPhenotypes(
+		request.PatientContext.PGXMarkers, request.PatientContext.PGXGenotypes)
+	if err != nil {
+		return nil, fmt.Errorf("genotype translation failed: %w", err)
+	}
+	
+	// Adjust phenotypes for inhibitors and inducers in the regimen (phenoconversion)
+	pgxMarkers, pgxPhenoconversions, err := eis.pgxEngine.ApplyPhenoconversion(
+		ctx, request.DrugCodes, pgxMarkers, pgxTranslations)
+	if err != nil {
+		requestLogger.Warn("Phenoconversion skipped - using genotype phenotypes", zap.Error(err))
+	}
+	
 	// Execute all interaction engines in parallel for performance
 	type engineResult struct {
 		name   string
 		result interface{}
 		error  error
+		notes  []string
 	}
 	
-	results := make(chan engineResult, 4)
+	const engineCount = 11
+	results := make(chan engineResult, engineCount)
 	
 	// Launch parallel engine evaluations
 	go func() {
+		patientContext := &models.PatientContextData{PGX: pgxMarkers, HepaticStage: request.PatientContext.HepaticFunction}
+		if organFunction != nil {
+			patientContext.RenalStage = organFunction.RenalStage
+			patientContext.AgeBand = organFunction.AgeBand
+		}
 		enhancedRequest := &models.EnhancedInteractionCheckRequest{
 			DrugCodes:       request.DrugCodes,
 			DatasetVersion:  request.DatasetVersion,
-			PatientContext:  &models.PatientContextData{PGX: request.PatientContext.PGXMarkers},
+			PatientContext:  patientContext,
 		}
 		ddiResults, err := eis.matrixEngine.CheckInteractionsEnhanced(ctx, enhancedRequest)
 		var interactionResults []models.EnhancedInteractionResult
 		if ddiResults != nil {
 			interactionResults = ddiResults.Interactions
 		}
-		results <- engineResult{"drug_drug", interactionResults, err}
+		results <- engineResult{"drug_drug", interactionResults, err, nil}
 	}()
 	
 	go func() {
 		pgxResults, err := eis.pgxEngine.EvaluatePatientPGXInteractions(
-			ctx, request.DrugCodes, request.PatientContext.PGXMarkers, request.DatasetVersion)
-		results <- engineResult{"pgx", pgxResults, err}
+			ctx, request.DrugCodes, pgxMarkers, request.DatasetVersion)
+		results <- engineResult{"pgx", pgxResults, err, nil}
+	}()
+	
+	go func() {
+		safetyResults, err := eis.pgxEngine.EvaluatePGXSafety(
+			ctx, request.DrugCodes, pgxMarkers, request.PatientContext.PGXRiskAlleles)
+		results <- engineResult{"pgx_safety", safetyResults, err, nil}
 	}()
 	
 	go func() {
 		classResults, err := eis.classEngine.EvaluateClassInteractions(
 			ctx, request.DrugCodes, request.DatasetVersion)
-		results <- engineResult{"class", classResults, err}
+		results <- engineResult{"class", classResults, err, nil}
 	}()
 	
 	go func() {
 		modifierResults, err := eis.modifierEngine.EvaluateModifierInteractions(
 			ctx, request.DrugCodes, request.ModifierContext, request.DatasetVersion)
-		results <- engineResult{"modifier", modifierResults, err}
+		results <- engineResult{"modifier", modifierResults, err, nil}
 	}()
 	
-	// Collect results
+	go func() {
+		// Avoid rules need no dose, so drug codes without an order are checked too
+		var doseResults []DoseAdjustmentResult
+		var doseNotes []string
+		var err error
+		if eis.doseAdjustmentEngine != nil {
+			doseResults, doseNotes, err = eis.doseAdjustmentEngine.CheckRegimen(
+				ctx, request.MedicationOrders, request.DrugCodes, organDosingFunction)
+		}
+		results <- engineResult{"dose_adjustment", doseResults, err, doseNotes}
+	}()
+	
+	go func() {
+		var cumulativeResults []CumulativeDoseResult
+		var cumulativeNotes []string
+		var err error
+		if eis.cumulativeDoseEngine != nil && len(request.MedicationOrders) > 0 {
+			patient := CumulativeDosePatient{AgeYears: pediatricPatient.AgeYears(), Function: organDosingFunction}
+			cumulativeResults, cumulativeNotes, err = eis.cumulativeDoseEngine.CheckOrders(ctx, request.MedicationOrders, patient)
+		}
+		results <- engineResult{"cumulative_dose", cumulativeResults, err, cumulativeNotes}
+	}()
+	
+	go func() {
+		reproductiveResults := []models.EnhancedInteractionResult{}
+		var err error
+		if eis.reproductiveEngine != nil && reproductiveStatus != nil {
+			reproductiveResults, _, err = eis.reproductiveEngine.EvaluateReproductiveSafety(
+				ctx, request.DrugCodes, reproductiveStatus)
+		}
+		results <- engineResult{"reproductive_safety", reproductiveResults, err, nil}
+	}()
+	
+	go func() {
+		pimResults := []models.EnhancedInteractionResult{}
+		var err error
+		if eis.pimEngine != nil && pimPatient.IsOlderAdult() {
+			var screen *PIMScreeningResponse
+			screen, err = eis.pimEngine.ScreenRegimen(ctx, request.DrugCodes, pimPatient, nil)
+			if screen != nil {
+				pimResults = screen.Findings
+			}
+		}
+		results <- engineResult{"pim_screening", pimResults, err, nil}
+	}()
+	
+	go func() {
+		pediatricResults := []models.EnhancedInteractionResult{}
+		var err error
+		if eis.pediatricEngine != nil && pediatricPatient.MayBePediatric() {
+			pediatricResults, _, err = eis.pediatricEngine.EvaluatePediatricSafety(
+				ctx, request.DrugCodes, request.MedicationOrders, pediatricPatient)
+		}
+		results <- engineResult{"pediatric_safety", pediatricResults, err, nil}
+	}()
+	
+	go func() {
+		// Labs were resolved above; the derived CKD stage counts as a renal diagnosis
+		drugDiseaseResults := []DrugDiseaseResult{}
+		var err error
+		if eis.drugDiseaseEngine != nil {
+			patientContext := request.PatientContext
+			patientContext.Labs = nil
+			diseaseCodes := append([]string(nil), patientContext.Comorbidities...)
+			if organFunction != nil {
+				if code := ckdDiagnosisCode(organFunction.RenalStage); code != "" {
+					diseaseCodes = append(diseaseCodes, CodeSystemICD10+":"+code)
+				}
+			}
+			drugDiseaseResults, err = eis.drugDiseaseEngine.EvaluateDrugDiseaseContraindications(ctx, DrugDiseaseCheckRequest{
+				DrugCodes:      request.DrugCodes,
+				DiseaseCodes:   diseaseCodes,
+				PatientContext: &patientContext,
+			}, request.DatasetVersion)
+		}
+		results <- engineResult{"drug_disease", drugDiseaseResults, err, nil}
+	}()
+	
+	// Collect results. Engines that catch contraindications and hard stops (PGx
+	// safety, reproductive, pediatric and drug-disease) fail closed: a missed
+	// contraindication is not a warning, so their errors fail the analysis.
+	// Advisory engines log the error and leave their section empty.
 	var drugDrugResults []models.EnhancedInteractionResult
 	var pgxResults []models.EnhancedInteractionResult
+	var pgxSafetyResults []models.EnhancedInteractionResult
 	var classResults []models.EnhancedInteractionResult
 	var modifierResults []ModifierInteractionResult
+	var doseResults []DoseAdjustmentResult
+	var cumulativeResults []CumulativeDoseResult
+	var reproductiveResults []models.EnhancedInteractionResult
+	var pimResults []models.EnhancedInteractionResult
+	var pediatricResults []models.EnhancedInteractionResult
+	var drugDiseaseResults []DrugDiseaseResult
+	var notes []string
 	
-	for i := 0; i < 4; i++ {
+	for i := 0; i < engineCount; i++ {
 		select {
 		case result := <-results:
 			switch result.name {
@@ -180,7 +346,15 @@ func (eis *EnhancedIntegrationService) PerformComprehensiveAnalysis(
 					requestLogger.Warn("PGx interaction analysis failed", zap.Error(result.error))
 				} else {
 					pgxResults = result.result.([]models.EnhancedInteractionResult)
+					AnnotatePhenoconversion(pgxResults, pgxPhenoconversions)
+				}
+				
+			case "pgx_safety":
+				if result.error != nil {
+					requestLogger.Error("PGx safety check failed", zap.Error(result.error))
+					return nil, fmt.Errorf("pgx safety check failed: %w", result.error)
 				}
+				pgxSafetyResults = result.result.([]models.EnhancedInteractionResult)
 				
 			case "class":
 				if result.error != nil {
@@ -192,9 +366,54 @@ func (eis *EnhancedIntegrationService) PerformComprehensiveAnalysis(
 			case "modifier":
 				if result.error != nil {
 					requestLogger.Warn("Modifier interaction analysis failed", zap.Error(result.error))
+					notes = append(notes, "Food, alcohol and herbal interactions not checked: modifier rules unavailable")
 				} else {
 					modifierResults = result.result.([]ModifierInteractionResult)
 				}
+				
+			case "dose_adjustment":
+				if result.error != nil {
+					requestLogger.Warn("Dose adjustment check failed", zap.Error(result.error))
+				} else {
+					doseResults = result.result.([]DoseAdjustmentResult)
+					notes = append(notes, result.notes...)
+				}
+				
+			case "cumulative_dose":
+				if result.error != nil {
+					requestLogger.Warn("Cumulative dose check failed", zap.Error(result.error))
+				} else {
+					cumulativeResults = result.result.([]CumulativeDoseResult)
+					notes = append(notes, result.notes...)
+				}
+				
+			case "reproductive_safety":
+				if result.error != nil {
+					requestLogger.Error("Reproductive safety check failed", zap.Error(result.error))
+					return nil, fmt.Errorf("reproductive safety check failed: %w", result.error)
+				}
+				reproductiveResults = result.result.([]models.EnhancedInteractionResult)
+				
+			case "pim_screening":
+				if result.error != nil {
+					requestLogger.Warn("PIM screening failed", zap.Error(result.error))
+				} else {
+					pimResults = result.result.([]models.EnhancedInteractionResult)
+				}
+				
+			case "pediatric_safety":
+				if result.error != nil {
+					requestLogger.Error("Pediatric safety check failed", zap.Error(result.error))
+					return nil, fmt.Errorf("pediatric safety check failed: %w", result.error)
+				}
+				pediatricResults = result.result.([]models.EnhancedInteractionResult)
+				
+			case "drug_disease":
+				if result.error != nil {
+					requestLogger.Error("Drug-disease check failed", zap.Error(result.error))
+					return nil, fmt.Errorf("drug-disease check failed: %w", result.error)
+				}
+				drugDiseaseResults = result.result.([]DrugDiseaseResult)
 			}
 			
 		case <-ctx.Done():
@@ -208,8 +427,19 @@ func (eis *EnhancedIntegrationService) PerformComprehensiveAnalysis(
 		AnalysisTimestamp:   time.Now(),
 		DrugDrugInteractions: drugDrugResults,
 		PGxInteractions:     pgxResults,
+		OrganFunction:       organFunction,
+		PGxTranslations:     pgxTranslations,
+		PGxPhenoconversions: pgxPhenoconversions,
+		PGxSafetyFindings:   pgxSafetyResults,
 		ClassInteractions:   classResults,
 		ModifierInteractions: modifierResults,
+		DoseAdjustments:     doseResults,
+		CumulativeDoses:     cumulativeResults,
+		ReproductiveFindings: reproductiveResults,
+		PIMFindings:         pimResults,
+		PediatricFindings:   pediatricResults,
+		DrugDiseaseFindings: drugDiseaseResults,
+		Notes:               notes,
 		DatasetVersion:     request.DatasetVersion,
 		ResponseTimeMs:     time.Since(startTime).Milliseconds(),
 	}
@@ -219,7 +449,7 @@ func (eis *EnhancedIntegrationService) PerformComprehensiveAnalysis(
 	
 	requestLogger.Info("Comprehensive analysis completed",
 		zap.Int64("response_time_ms", response.ResponseTimeMs),
-		zap.Int("total_interactions", len(drugDrugResults)+len(pgxResults)+len(classResults)+len(modifierResults)),
+		zap.Int("total_interactions", len(drugDrugResults)+len(pgxResults)+len(pgxSafetyResults)+len(classResults)+len(modifierResults)),
 		zap.Int("critical_alerts", len(response.CriticalAlerts)),
 	)
 	
@@ -278,6 +508,30 @@ func (eis *EnhancedIntegrationService) synthesizeClinicalResults(
 		severityScores = append(severityScores, eis.mapSeverityToScore(interaction.Severity))
 	}
 	
+	// Process PGx safety findings (HLA risk alleles, dose-critical genes)
+	for _, finding := range response.PGxSafetyFindings {
+		alertType := "monitoring_required"
+		urgency := eis.mapSeverityToUrgency(finding.Severity)
+		if models.GovernanceAction(finding.Qualifiers["governance_action"]).IsBlocking() {
+			alertType = "pgx_hard_stop"
+			urgency = "immediate"
+		}
+		alert := ClinicalAlert{
+			AlertID:         fmt.Sprintf("PGXSAFE-%s", finding.InteractionID),
+			AlertType:       alertType,
+			Severity:        finding.Severity,
+			Source:          "pgx_safety",
+			AffectedDrugs:   []string{finding.Drug1.Code},
+			ClinicalMessage: fmt.Sprintf("%s: %s", finding.Drug2.Name, finding.ClinicalEffects),
+			ActionRequired:  finding.ManagementStrategy,
+			Urgency:         urgency,
+			Evidence:        finding.Evidence,
+		}
+		allAlerts = append(allAlerts, alert)
+		
+		severityScores = append(severityScores, eis.mapSeverityToScore(finding.Severity))
+	}
+	
 	// Process class interactions
 	for _, interaction := range response.ClassInteractions {
 		if interaction.Severity == models.SeverityMajor ||
@@ -303,13 +557,23 @@ func (eis *EnhancedIntegrationService) synthesizeClinicalResults(
 	for _, interaction := range response.ModifierInteractions {
 		if interaction.Severity == models.SeverityMajor ||
 		   interaction.Severity == models.SeverityContraindicated {
+			// Say when the modifier stops mattering, e.g. 72 h after grapefruit
+			message := interaction.ClinicalEffect
+			if interaction.TimingGuidance != "" {
+				message = fmt.Sprintf("%s. %s", message, interaction.TimingGuidance)
+			}
+			alertID := fmt.Sprintf("MOD-%s-%s-%s", interaction.InteractionType, interaction.ModifierName,
+				strings.Join(interaction.AffectedDrugs, "-"))
+			if interaction.TriggerEvent != "" {
+				alertID += "-" + interaction.TriggerEvent
+			}
 			alert := ClinicalAlert{
-				AlertID:         fmt.Sprintf("MOD-%s-%s", interaction.InteractionType, interaction.ModifierName),
+				AlertID:         alertID,
 				AlertType:       "major_interaction", 
 				Severity:        interaction.Severity,
 				Source:          "modifier",
 				AffectedDrugs:   interaction.AffectedDrugs,
-				ClinicalMessage: interaction.ClinicalEffect,
+				ClinicalMessage: message,
 				ActionRequired:  interaction.Recommendation,
 				Urgency:         eis.mapSeverityToUrgency(interaction.Severity),
 				Evidence:        interaction.Evidence,
@@ -320,6 +584,189 @@ func (eis *EnhancedIntegrationService) synthesizeClinicalResults(
 		severityScores = append(severityScores, eis.mapSeverityToScore(interaction.Severity))
 	}
 	
+	// Process renal/hepatic dose adjustments
+	for _, adjustment := range response.DoseAdjustments {
+		if adjustment.Status != DoseStatusAvoid && adjustment.Status != DoseStatusExceedsMax {
+			continue
+		}
+		alertType := "dose_adjustment"
+		if adjustment.Status == DoseStatusAvoid {
+			alertType = "contraindication"
+		}
+		alert := ClinicalAlert{
+			AlertID:         fmt.Sprintf("DOSE-%s-%s", strings.ToUpper(adjustment.AdjustmentType), adjustment.DrugCode),
+			AlertType:       alertType,
+			Severity:        adjustment.Severity,
+			Source:          "dose_adjustment",
+			AffectedDrugs:   []string{adjustment.DrugCode},
+			ClinicalMessage: adjustment.Message,
+			ActionRequired:  adjustment.RecommendedRegimen,
+			Urgency:         eis.mapSeverityToUrgency(adjustment.Severity),
+			Evidence:        models.EvidenceLevelA,
+		}
+		allAlerts = append(allAlerts, alert)
+		
+		severityScores = append(severityScores, eis.mapSeverityToScore(adjustment.Severity))
+	}
+	
+	// Process cumulative daily doses across orders and combination products
+	for _, cumulative := range response.CumulativeDoses {
+		if cumulative.Status != DoseStatusExceedsMax {
+			continue
+		}
+		var affectedDrugs, contributors []string
+		seen := make(map[string]bool)
+		for _, contribution := range cumulative.Contributions {
+			if !seen[contribution.DrugCode] {
+				seen[contribution.DrugCode] = true
+				affectedDrugs = append(affectedDrugs, contribution.DrugCode)
+			}
+			contributors = append(contributors, contributionLabel(contribution))
+		}
+		alertKey := cumulative.IngredientCode
+		if cumulative.Scope == CumulativeScopeGroup {
+			alertKey = "GROUP-" + cumulative.CumulativeGroup
+		}
+		alert := ClinicalAlert{
+			AlertID:         fmt.Sprintf("CUMDOSE-%s", strings.ToUpper(alertKey)),
+			AlertType:       "cumulative_dose",
+			Severity:        cumulative.Severity,
+			Source:          "cumulative_dose",
+			AffectedDrugs:   affectedDrugs,
+			ClinicalMessage: cumulative.Message,
+			ActionRequired:  fmt.Sprintf("Reduce or discontinue contributing orders: %s", strings.Join(contributors, ", ")),
+			Urgency:         eis.mapSeverityToUrgency(cumulative.Severity),
+			Evidence:        models.EvidenceLevelA,
+		}
+		allAlerts = append(allAlerts, alert)
+		
+		severityScores = append(severityScores, eis.mapSeverityToScore(cumulative.Severity))
+	}
+	
+	// Process pregnancy and lactation findings
+	for _, finding := range response.ReproductiveFindings {
+		if finding.Severity != models.SeverityContraindicated && finding.Severity != models.SeverityMajor {
+			continue
+		}
+		alertType := "contraindication"
+		urgency := eis.mapSeverityToUrgency(finding.Severity)
+		if models.GovernanceAction(finding.Qualifiers["governance_action"]).IsBlocking() {
+			alertType = "reproductive_hard_stop"
+			urgency = "immediate"
+		}
+		action := finding.ManagementStrategy
+		if len(finding.AlternativeDrugs) > 0 {
+			action = fmt.Sprintf("%s Safer alternatives: %s.", action, strings.Join(finding.AlternativeDrugs, ", "))
+		}
+		alert := ClinicalAlert{
+			AlertID:         fmt.Sprintf("REPRO-%s", finding.InteractionID),
+			AlertType:       alertType,
+			Severity:        finding.Severity,
+			Source:          "reproductive_safety",
+			AffectedDrugs:   []string{finding.Drug1.Code},
+			ClinicalMessage: fmt.Sprintf("%s: %s", finding.Drug2.Name, finding.ClinicalEffects),
+			ActionRequired:  action,
+			Urgency:         urgency,
+			Evidence:        finding.Evidence,
+		}
+		allAlerts = append(allAlerts, alert)
+		
+		severityScores = append(severityScores, eis.mapSeverityToScore(finding.Severity))
+	}
+	
+	// Process potentially inappropriate medications for older adults
+	for _, finding := range response.PIMFindings {
+		if finding.Severity != models.SeverityContraindicated && finding.Severity != models.SeverityMajor {
+			continue
+		}
+		affected := []string{finding.Drug1.Code}
+		if finding.Qualifiers["criterion_type"] == PIMCriterionDrugDrug {
+			affected = append(affected, finding.Drug2.Code)
+		}
+		action := finding.ManagementStrategy
+		if len(finding.AlternativeDrugs) > 0 {
+			action = fmt.Sprintf("%s Alternatives: %s.", action, strings.Join(finding.AlternativeDrugs, ", "))
+		}
+		alert := ClinicalAlert{
+			AlertID:         fmt.Sprintf("PIM-%s", finding.InteractionID),
+			AlertType:       "potentially_inappropriate_medication",
+			Severity:        finding.Severity,
+			Source:          "pim_screening",
+			AffectedDrugs:   affected,
+			ClinicalMessage: fmt.Sprintf("%s (%s %s): %s", finding.Qualifiers["criterion_code"],
+				finding.Qualifiers["criteria_set"], finding.Qualifiers["criteria_version"], finding.ClinicalEffects),
+			ActionRequired:  action,
+			Urgency:         eis.mapSeverityToUrgency(finding.Severity),
+			Evidence:        finding.Evidence,
+		}
+		allAlerts = append(allAlerts, alert)
+		
+		severityScores = append(severityScores, eis.mapSeverityToScore(finding.Severity))
+	}
+	
+	// Process pediatric age- and weight-based findings
+	for _, finding := range response.PediatricFindings {
+		if finding.Severity != models.SeverityContraindicated && finding.Severity != models.SeverityMajor {
+			continue
+		}
+		alertType := "contraindication"
+		urgency := eis.mapSeverityToUrgency(finding.Severity)
+		switch {
+		case models.GovernanceAction(finding.Qualifiers["governance_action"]).IsBlocking():
+			alertType = "pediatric_hard_stop"
+			urgency = "immediate"
+		case finding.Qualifiers["rule_type"] == PediatricRuleDoseLimit:
+			alertType = "dose_adjustment"
+		}
+		affected := []string{finding.Drug1.Code}
+		if finding.Qualifiers["rule_type"] == PediatricRuleInteraction {
+			affected = append(affected, finding.Drug2.Code)
+		}
+		action := finding.ManagementStrategy
+		if len(finding.AlternativeDrugs) > 0 {
+			action = fmt.Sprintf("%s Alternatives: %s.", action, strings.Join(finding.AlternativeDrugs, ", "))
+		}
+		alert := ClinicalAlert{
+			AlertID:         fmt.Sprintf("PED-%s", finding.InteractionID),
+			AlertType:       alertType,
+			Severity:        finding.Severity,
+			Source:          "pediatric_safety",
+			AffectedDrugs:   affected,
+			ClinicalMessage: fmt.Sprintf("%s: %s", finding.Qualifiers["rule_code"], finding.ClinicalEffects),
+			ActionRequired:  action,
+			Urgency:         urgency,
+			Evidence:        finding.Evidence,
+		}
+		allAlerts = append(allAlerts, alert)
+		
+		severityScores = append(severityScores, eis.mapSeverityToScore(finding.Severity))
+	}
+	
+	// Process drug-disease contraindications
+	for _, finding := range response.DrugDiseaseFindings {
+		if finding.Severity != models.SeverityContraindicated && finding.Severity != models.SeverityMajor {
+			continue
+		}
+		action := finding.ManagementStrategy
+		if len(finding.AlternativeDrugs) > 0 {
+			action = fmt.Sprintf("%s Alternatives: %s.", action, strings.Join(finding.AlternativeDrugs, ", "))
+		}
+		alert := ClinicalAlert{
+			AlertID:         fmt.Sprintf("DDX-%s-%s", finding.DrugCode, finding.DiseaseCode),
+			AlertType:       "contraindication",
+			Severity:        finding.Severity,
+			Source:          "drug_disease",
+			AffectedDrugs:   []string{finding.DrugCode},
+			ClinicalMessage: fmt.Sprintf("%s: %s", finding.DiseaseName, finding.ClinicalEffects),
+			ActionRequired:  action,
+			Urgency:         eis.mapSeverityToUrgency(finding.Severity),
+			Evidence:        finding.Evidence,
+		}
+		allAlerts = append(allAlerts, alert)
+		
+		severityScores = append(severityScores, eis.mapSeverityToScore(finding.Severity))
+	}
+	
 	// Sort alerts by severity and urgency
 	sort.Slice(allAlerts, func(i, j int) bool {
 		return eis.mapSeverityToScore(allAlerts[i].Severity).GreaterThan(
@@ -339,6 +786,13 @@ func (eis *EnhancedIntegrationService) synthesizeClinicalResults(
 		"class_engine":    "1.0.0", 
 		"modifier_engine": "1.0.0",
 		"matrix_engine":   "2.0.0",
+		"dose_adjustment_engine": "1.0.0",
+		"reproductive_safety_engine": "1.0.0",
+		"pim_screening_engine": "1.0.0",
+		"pediatric_safety_engine": "1.0.0",
+	}
+	if version := eis.pgxEngine.TranslationVersion(); version != "" {
+		response.EngineVersions["pgx_translation_table"] = version
 	}
 }
 
@@ -535,19 +989,19 @@ func (eis *EnhancedIntegrationService) FastModifierLookup(
 	query := `
 		SELECT 
 			modifier_name,
-			mechanism,
-			clinical_effect,
+			COALESCE(mechanism, ''),
+			effect,
 			severity,
-			evidence_level,
-			recommendation,
-			timing_guidance,
+			evidence,
+			management_strategy,
 			confidence_score
 		FROM ddi_modifiers
 		WHERE drug_code = $1
 		AND modifier_type = $2
-		AND (LOWER(modifier_name) = $3 OR LOWER(modifier_aliases) ? $3)
+		AND (LOWER(modifier_name) = $3 OR $3 = ANY(modifier_aliases))
 		AND dataset_version = $4
-		AND is_active = true
+		AND active = true
+		ORDER BY (trigger_event = 'exposure') DESC
 		LIMIT 1
 	`
 	
@@ -562,7 +1016,6 @@ func (eis *EnhancedIntegrationService) FastModifierLookup(
 		&severityStr,
 		&evidenceStr,
 		&result.Recommendation,
-		&result.TimingGuidance,
 		&result.ConfidenceScore,
 	)
 	
@@ -0,0 +1,683 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// Excipient cross-reactivity types in allergy results
+const (
+	excipientDirect        = "excipient"
+	excipientCrossReactive = "excipient_cross_reactive"
+)
+
+// Excipient is an allergenic inactive ingredient
+type Excipient struct {
+	Code              string             `json:"code" gorm:"primaryKey"`
+	Name              string             `json:"name"`
+	Terms             models.StringArray `json:"terms" gorm:"type:text[]"`
+	CrossReactiveWith models.StringArray `json:"cross_reactive_with,omitempty" gorm:"type:text[]"`
+	ClinicalNote      string             `json:"clinical_note"`
+	Active            bool               `json:"active"`
+}
+
+// TableName specifies the database table for GORM
+func (Excipient) TableName() string {
+	return "excipients"
+}
+
+// FormulationExcipient links a drug product to an excipient it contains
+type FormulationExcipient struct {
+	FormulationID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	ExcipientCode  string    `gorm:"primaryKey"`
+	IngredientName string
+}
+
+// TableName specifies the database table for GORM
+func (FormulationExcipient) TableName() string {
+	return "drug_formulation_excipients"
+}
+
+// ExcipientProduct is a drug product with its allergenic excipients
+type ExcipientProduct struct {
+	ProductCode     string            `json:"product_code"`
+	ProductName     string            `json:"product_name"`
+	DrugCode        string            `json:"drug_code"`
+	FormulationType string            `json:"formulation_type"`
+	Route           string            `json:"route"`
+	Strength        string            `json:"strength,omitempty"`
+	Manufacturer    string            `json:"manufacturer,omitempty"`
+	Excipients      map[string]string `json:"excipients"`            // Excipient code -> label ingredient name
+	Ingredients     []string          `json:"ingredients,omitempty"` // Inactive ingredients as imported
+}
+
+// ExcipientImportResult summarizes a product ingredient file import
+type ExcipientImportResult struct {
+	RowsRead            int           `json:"rows_read"`
+	ProductsImported    int           `json:"products_imported"`
+	ExcipientLinks      int           `json:"excipient_links"`
+	UnmappedIngredients int           `json:"unmapped_ingredients"` // Inactive ingredients that are not catalogued allergens
+	Issues              []ImportIssue `json:"issues,omitempty"`
+}
+
+// excipientCatalog holds excipients and the products that contain them
+type excipientCatalog struct {
+	excipients map[string]Excipient           // By code
+	products   map[string]*ExcipientProduct   // By upper-case product code
+	byDrug     map[string][]*ExcipientProduct // By normalized drug code
+}
+
+// =============================================================================
+// Allergy checks
+// =============================================================================
+
+// evaluateExcipientAllergies flags ordered products that contain an excipient the
+// patient is allergic to (or a cross-reactive one), and ordered drugs whose
+// products on file contain it. Each result suggests excipient-free products.
+// Intolerances (e.g. lactose intolerance) are not allergies and are skipped.
+func evaluateExcipientAllergies(catalog *excipientCatalog, request AllergyCheckRequest) []AllergyCheckResult {
+	var results []AllergyCheckResult
+	if catalog == nil {
+		return results
+	}
+
+	for i := range request.PatientAllergies {
+		allergy := request.PatientAllergies[i]
+		if isIntolerance(allergy) {
+			continue
+		}
+		implicated := implicatedExcipients(catalog.excipients, allergy.AllergenCode+" "+allergy.AllergenName)
+		if len(implicated) == 0 {
+			continue
+		}
+
+		orderedDrugs := make(map[string]bool)
+		for _, code := range request.ProductCodes {
+			product, ok := catalog.products[strings.ToUpper(strings.TrimSpace(code))]
+			if !ok {
+				continue
+			}
+			orderedDrugs[normalizeATCDrugKey(product.DrugCode)] = true
+
+			for _, excipientCode := range sortedExcipientCodes(product) {
+				direct, ok := implicated[excipientCode]
+				if !ok {
+					continue
+				}
+				result := excipientProductResult(catalog, product, catalog.excipients[excipientCode], direct, allergy, implicated)
+				applyDelabeling(&result, product.ProductCode, &allergy, request.ToleratedExposures)
+				results = append(results, result)
+			}
+		}
+
+		// Ingredient-level orders: warn when products on file for the drug contain the excipient
+		for _, drugCode := range request.DrugCodes {
+			key := normalizeATCDrugKey(drugCode)
+			if orderedDrugs[key] {
+				continue
+			}
+			orderedDrugs[key] = true
+
+			result, ok := excipientIngredientResult(catalog, drugCode, allergy, implicated)
+			if !ok || (result.AlertLevel == "low" && !request.IncludePossible) {
+				continue
+			}
+			applyDelabeling(&result, drugCode, &allergy, request.ToleratedExposures)
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// isIntolerance reports whether a documented reaction is an intolerance rather
+// than an allergy
+func isIntolerance(allergy PatientAllergy) bool {
+	text := strings.ToLower(allergy.AllergenType + " " + allergy.AllergenName + " " + allergy.ReactionType)
+	return strings.Contains(text, "intoleran")
+}
+
+// implicatedExcipients finds excipients named by an allergy. The value is true for
+// excipients matched directly and false for cross-reactive ones.
+func implicatedExcipients(excipients map[string]Excipient, allergyText string) map[string]bool {
+	direct := matchExcipientTerms(excipients, allergyText)
+	implicated := make(map[string]bool)
+	for _, code := range direct {
+		implicated[code] = true
+	}
+	for _, code := range direct {
+		for _, related := range excipients[code].CrossReactiveWith {
+			if _, ok := implicated[related]; !ok {
+				implicated[related] = false
+			}
+		}
+	}
+	return implicated
+}
+
+// excipientProductResult builds the result for an ordered product containing an implicated excipient
+func excipientProductResult(
+	catalog *excipientCatalog,
+	product *ExcipientProduct,
+	excipient Excipient,
+	direct bool,
+	allergy PatientAllergy,
+	implicated map[string]bool,
+) AllergyCheckResult {
+	alternatives := excipientFreeProducts(catalog, product.DrugCode, product.Route, implicated)
+
+	guidance := fmt.Sprintf("%s contains %s (%s). %s", product.ProductName, excipient.Name,
+		product.Excipients[excipient.Code], excipient.ClinicalNote)
+	if !direct {
+		guidance = fmt.Sprintf("%s contains %s, which may cross-react with %s. %s", product.ProductName,
+			excipient.Name, allergenDisplayName(allergy), excipient.ClinicalNote)
+	}
+	guidance += excipientAlternativeSentence(alternatives)
+
+	result := AllergyCheckResult{
+		AllergenCode:        allergy.AllergenCode,
+		AllergenName:        allergenDisplayName(allergy),
+		DrugCode:            product.DrugCode,
+		DrugName:            product.ProductName,
+		ProductCode:         product.ProductCode,
+		Excipient:           excipient.Name,
+		CrossReactivityType: excipientDirect,
+		Severity:            models.SeverityMajor,
+		ReactionType:        allergy.ReactionType,
+		ReactionPhenotype:   ClassifyReactionPhenotype(allergy.ReactionType),
+		Recommendation:      AllergyRecommendAvoid,
+		ClinicalGuidance:    guidance,
+		AlternativeDrugs:    alternatives,
+		Evidence:            models.EvidenceLevelB,
+		Confidence:          0.90,
+		AlertLevel:          "high",
+	}
+	if direct {
+		result.RequiresPharmacistReview = true
+		if strings.Contains(strings.ToLower(allergy.ReactionType), "anaphyla") || strings.EqualFold(allergy.Severity, "life-threatening") {
+			result.Severity = models.SeverityContraindicated
+			result.AlertLevel = "critical"
+		}
+	} else {
+		result.CrossReactivityType = excipientCrossReactive
+		result.Severity = models.SeverityModerate
+		result.Recommendation = ""
+		result.Confidence = 0.60
+		result.AlertLevel = "moderate"
+	}
+	return result
+}
+
+// excipientIngredientResult summarizes which products of an ordered drug contain an
+// implicated excipient. Alert level is moderate when every product does and low
+// when an excipient-free product exists.
+func excipientIngredientResult(
+	catalog *excipientCatalog,
+	drugCode string,
+	allergy PatientAllergy,
+	implicated map[string]bool,
+) (AllergyCheckResult, bool) {
+	var containing []string
+	excipientNames := []string{}
+	for _, product := range catalog.byDrug[normalizeATCDrugKey(drugCode)] {
+		found := false
+		for _, code := range sortedExcipientCodes(product) {
+			if _, ok := implicated[code]; ok {
+				found = true
+				excipientNames = appendUnique(excipientNames, catalog.excipients[code].Name)
+			}
+		}
+		if found {
+			containing = append(containing, product.ProductName)
+		}
+	}
+	if len(containing) == 0 {
+		return AllergyCheckResult{}, false
+	}
+	sort.Strings(containing)
+
+	alternatives := excipientFreeProducts(catalog, drugCode, "", implicated)
+	result := AllergyCheckResult{
+		AllergenCode:        allergy.AllergenCode,
+		AllergenName:        allergenDisplayName(allergy),
+		DrugCode:            drugCode,
+		DrugName:            drugCode,
+		Excipient:           strings.Join(excipientNames, ", "),
+		CrossReactivityType: excipientDirect,
+		Severity:            models.SeverityModerate,
+		ReactionType:        allergy.ReactionType,
+		ReactionPhenotype:   ClassifyReactionPhenotype(allergy.ReactionType),
+		AlternativeDrugs:    alternatives,
+		Evidence:            models.EvidenceLevelB,
+		Confidence:          0.70,
+		AlertLevel:          "moderate",
+	}
+	if len(alternatives) == 0 {
+		result.ClinicalGuidance = fmt.Sprintf("All products on file for this drug contain %s: %s. No excipient-free product is available.",
+			result.Excipient, strings.Join(containing, "; "))
+	} else {
+		result.Severity = models.SeverityMinor
+		result.AlertLevel = "low"
+		result.ClinicalGuidance = fmt.Sprintf("Some products of this drug contain %s (%s); select an excipient-free product.%s",
+			result.Excipient, strings.Join(containing, "; "), excipientAlternativeSentence(alternatives))
+	}
+	return result, true
+}
+
+// excipientFreeProducts lists products of a drug without any implicated excipient,
+// same-route products first
+func excipientFreeProducts(catalog *excipientCatalog, drugCode, route string, implicated map[string]bool) []string {
+	var free []*ExcipientProduct
+	for _, product := range catalog.byDrug[normalizeATCDrugKey(drugCode)] {
+		clean := true
+		for code := range product.Excipients {
+			if _, ok := implicated[code]; ok {
+				clean = false
+				break
+			}
+		}
+		if clean {
+			free = append(free, product)
+		}
+	}
+	sort.SliceStable(free, func(i, j int) bool {
+		if (free[i].Route == route) != (free[j].Route == route) {
+			return free[i].Route == route
+		}
+		return free[i].ProductName < free[j].ProductName
+	})
+
+	names := make([]string, len(free))
+	for i, product := range free {
+		names[i] = product.ProductName
+	}
+	return names
+}
+
+// excipientAlternativeSentence describes excipient-free alternatives for guidance text
+func excipientAlternativeSentence(alternatives []string) string {
+	if len(alternatives) == 0 {
+		return " No excipient-free product of this drug is on file."
+	}
+	return fmt.Sprintf(" Excipient-free alternative: %s.", strings.Join(alternatives, "; "))
+}
+
+// allergenDisplayName returns the allergy's name, falling back to its code
+func allergenDisplayName(allergy PatientAllergy) string {
+	if allergy.AllergenName != "" {
+		return allergy.AllergenName
+	}
+	return allergy.AllergenCode
+}
+
+// sortedExcipientCodes returns a product's excipient codes in a stable order
+func sortedExcipientCodes(product *ExcipientProduct) []string {
+	codes := make([]string, 0, len(product.Excipients))
+	for code := range product.Excipients {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// matchExcipientTerms returns the codes of excipients whose code or terms appear
+// as whole words in text
+func matchExcipientTerms(excipients map[string]Excipient, text string) []string {
+	normalized := normalizeTermText(text)
+	var codes []string
+	for code, excipient := range excipients {
+		terms := append([]string{code}, excipient.Terms...)
+		for _, term := range terms {
+			term = strings.TrimSpace(normalizeTermText(term))
+			if containsExcipientTerm(normalized, term, numberedVariant(term, terms)) {
+				codes = append(codes, code)
+				break
+			}
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// containsExcipientTerm reports whether a normalized term appears as whole words
+// in normalized text. When the excipient is a numbered variant, a match followed
+// by a different number names another variant, so "polysorbate" does not match
+// "polysorbate 20" for polysorbate 80.
+func containsExcipientTerm(normalized, term string, variant bool) bool {
+	if term == "" {
+		return false
+	}
+	needle := " " + term + " "
+	for offset := 0; ; {
+		i := strings.Index(normalized[offset:], needle)
+		if i < 0 {
+			return false
+		}
+		rest := normalized[offset+i+len(needle):]
+		next := rest
+		if end := strings.IndexByte(rest, ' '); end >= 0 {
+			next = rest[:end]
+		}
+		if !variant || next == "" || !isAllDigits(next) {
+			return true
+		}
+		offset += i + len(needle) - 1
+	}
+}
+
+// numberedVariant reports whether another of the excipient's terms is term
+// followed by a number, e.g. "polysorbate 80" for "polysorbate"
+func numberedVariant(term string, terms []string) bool {
+	for _, other := range terms {
+		other = strings.TrimSpace(normalizeTermText(other))
+		if rest := strings.TrimPrefix(other, term+" "); rest != other && isAllDigits(rest) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTermText lower-cases text and reduces it to space-separated words,
+// padded with spaces for whole-word matching
+func normalizeTermText(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	return " " + strings.Join(fields, " ") + " "
+}
+
+// =============================================================================
+// Catalog
+// =============================================================================
+
+// GetProductExcipients returns a product and its allergenic excipients, or nil
+// when the product is not on file
+func (ae *AllergyEngine) GetProductExcipients(ctx context.Context, productCode string) (*ExcipientProduct, error) {
+	catalog, err := ae.loadExcipientCatalog(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load excipient catalog: %w", err)
+	}
+	return catalog.products[strings.ToUpper(strings.TrimSpace(productCode))], nil
+}
+
+// loadExcipientCatalog returns excipients and products, loading them when the
+// cache has expired
+func (ae *AllergyEngine) loadExcipientCatalog(ctx context.Context) (*excipientCatalog, error) {
+	ae.excipientMu.Lock()
+	defer ae.excipientMu.Unlock()
+
+	if ae.excipients != nil && time.Since(ae.excipientsLoaded) < ae.cacheTTL {
+		return ae.excipients, nil
+	}
+
+	var excipients []Excipient
+	if err := ae.db.DB.WithContext(ctx).Where("active = TRUE").Find(&excipients).Error; err != nil {
+		return nil, err
+	}
+	var formulations []models.DrugFormulation
+	if err := ae.db.DB.WithContext(ctx).Where("active = TRUE AND product_code IS NOT NULL").Find(&formulations).Error; err != nil {
+		return nil, err
+	}
+	var links []FormulationExcipient
+	if err := ae.db.DB.WithContext(ctx).Find(&links).Error; err != nil {
+		return nil, err
+	}
+
+	catalog := &excipientCatalog{
+		excipients: make(map[string]Excipient, len(excipients)),
+		products:   make(map[string]*ExcipientProduct, len(formulations)),
+		byDrug:     make(map[string][]*ExcipientProduct),
+	}
+	for _, e := range excipients {
+		catalog.excipients[e.Code] = e
+	}
+
+	byID := make(map[uuid.UUID]*ExcipientProduct, len(formulations))
+	for _, f := range formulations {
+		product := &ExcipientProduct{
+			ProductCode:     derefString(f.ProductCode),
+			ProductName:     derefString(f.ProductName),
+			DrugCode:        f.DrugCode,
+			FormulationType: f.FormulationType,
+			Route:           f.RouteOfAdministration,
+			Strength:        derefString(f.Strength),
+			Manufacturer:    derefString(f.Manufacturer),
+			Excipients:      make(map[string]string),
+		}
+		if product.ProductName == "" {
+			product.ProductName = product.ProductCode
+		}
+		byID[f.ID] = product
+		catalog.products[strings.ToUpper(product.ProductCode)] = product
+		key := normalizeATCDrugKey(product.DrugCode)
+		catalog.byDrug[key] = append(catalog.byDrug[key], product)
+	}
+	for _, link := range links {
+		if product, ok := byID[link.FormulationID]; ok {
+			product.Excipients[link.ExcipientCode] = link.IngredientName
+		}
+	}
+
+	ae.excipients = catalog
+	ae.excipientsLoaded = time.Now()
+
+	return catalog, nil
+}
+
+// derefString returns the string value or "" for nil
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// =============================================================================
+// Import
+// =============================================================================
+
+// excipientColumnAliases maps accepted header names to product file fields
+var excipientColumnAliases = map[string]string{
+	"product_code":         "product_code",
+	"ndc":                  "product_code",
+	"product_ndc":          "product_code",
+	"spl_id":               "product_code",
+	"product_name":         "product_name",
+	"proprietary_name":     "product_name",
+	"name":                 "product_name",
+	"drug_code":            "drug_code",
+	"rxcui":                "drug_code",
+	"ingredient_rxcui":     "drug_code",
+	"formulation_type":     "formulation_type",
+	"dosage_form":          "formulation_type",
+	"form":                 "formulation_type",
+	"route":                "route",
+	"route_of_admin":       "route",
+	"strength":             "strength",
+	"manufacturer":         "manufacturer",
+	"labeler":              "manufacturer",
+	"labeler_name":         "manufacturer",
+	"inactive_ingredients": "inactive_ingredients",
+	"inactive_ingredient":  "inactive_ingredients",
+	"excipients":           "inactive_ingredients",
+}
+
+// ParseExcipientFile parses a product ingredient list (CSV or TSV with a header
+// row): one product per row with its inactive ingredients separated by ";" or
+// "|" (or "," when neither is used). format is "csv", "tsv" or "" to detect.
+func ParseExcipientFile(r io.Reader, format string) ([]ExcipientProduct, []ImportIssue, error) {
+	reader, columns, err := openDelimitedFile(r, format, "product ingredient", excipientColumnAliases)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, required := range []string{"product_code", "drug_code", "inactive_ingredients"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("product ingredient file is missing required column %q", required)
+		}
+	}
+
+	var products []ExcipientProduct
+	var issues []ImportIssue
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			issues = append(issues, ImportIssue{Line: line, Reason: err.Error()})
+			continue
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		field := recordField(record, columns)
+		product := ExcipientProduct{
+			ProductCode:     field("product_code"),
+			ProductName:     field("product_name"),
+			DrugCode:        field("drug_code"),
+			FormulationType: strings.ToLower(field("formulation_type")),
+			Route:           strings.ToLower(field("route")),
+			Strength:        field("strength"),
+			Manufacturer:    field("manufacturer"),
+			Ingredients:     splitIngredientList(field("inactive_ingredients")),
+		}
+		if product.ProductCode == "" || product.DrugCode == "" {
+			issues = append(issues, ImportIssue{Line: line, Reason: "product_code and drug_code are required"})
+			continue
+		}
+		if isAllDigits(product.DrugCode) {
+			product.DrugCode = "RxCUI:" + product.DrugCode
+		}
+		if product.ProductName == "" {
+			product.ProductName = product.ProductCode
+		}
+		if product.FormulationType == "" {
+			product.FormulationType = "unknown"
+		}
+		if product.Route == "" {
+			product.Route = "unknown"
+		}
+		products = append(products, product)
+	}
+
+	return products, issues, nil
+}
+
+// splitIngredientList splits a label inactive-ingredient list
+func splitIngredientList(list string) []string {
+	separator := ","
+	switch {
+	case strings.Contains(list, ";"):
+		separator = ";"
+	case strings.Contains(list, "|"):
+		separator = "|"
+	}
+	var ingredients []string
+	for _, part := range strings.Split(list, separator) {
+		if ingredient := strings.TrimSpace(part); ingredient != "" {
+			ingredients = append(ingredients, ingredient)
+		}
+	}
+	return ingredients
+}
+
+// mapIngredients maps label ingredients to catalogued excipients. Returns the
+// excipient links and the number of ingredients that are not allergens.
+func mapIngredients(excipients map[string]Excipient, ingredients []string) (map[string]string, int) {
+	links := make(map[string]string)
+	unmapped := 0
+	for _, ingredient := range ingredients {
+		codes := matchExcipientTerms(excipients, ingredient)
+		if len(codes) == 0 {
+			unmapped++
+			continue
+		}
+		for _, code := range codes {
+			if _, ok := links[code]; !ok {
+				links[code] = ingredient
+			}
+		}
+	}
+	return links, unmapped
+}
+
+// ImportExcipientProducts upserts products by product code and replaces their
+// excipient links from the imported ingredient lists
+func (ae *AllergyEngine) ImportExcipientProducts(ctx context.Context, products []ExcipientProduct, source string) (ExcipientImportResult, error) {
+	result := ExcipientImportResult{}
+	if len(products) == 0 {
+		return result, nil
+	}
+
+	catalog, err := ae.loadExcipientCatalog(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to load excipient catalog: %w", err)
+	}
+
+	tx := ae.db.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return result, fmt.Errorf("failed to begin product import: %w", tx.Error)
+	}
+	defer tx.Rollback()
+
+	for _, p := range products {
+		links, unmapped := mapIngredients(catalog.excipients, p.Ingredients)
+		result.UnmappedIngredients += unmapped
+
+		var formulationID uuid.UUID
+		err := tx.Raw(`
+			INSERT INTO drug_formulations (drug_code, formulation_type, route_of_administration, strength,
+				product_code, product_name, manufacturer, source, active, updated_at)
+			VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, NULLIF(?, ''), ?, TRUE, NOW())
+			ON CONFLICT (product_code) WHERE product_code IS NOT NULL DO UPDATE SET
+				drug_code = EXCLUDED.drug_code,
+				formulation_type = EXCLUDED.formulation_type,
+				route_of_administration = EXCLUDED.route_of_administration,
+				strength = EXCLUDED.strength,
+				product_name = EXCLUDED.product_name,
+				manufacturer = EXCLUDED.manufacturer,
+				source = EXCLUDED.source,
+				active = TRUE,
+				updated_at = NOW()
+			RETURNING id`,
+			p.DrugCode, p.FormulationType, p.Route, p.Strength,
+			p.ProductCode, p.ProductName, p.Manufacturer, source).Scan(&formulationID).Error
+		if err != nil {
+			return result, fmt.Errorf("failed to import product %s: %w", p.ProductCode, err)
+		}
+
+		if err := tx.Exec(`DELETE FROM drug_formulation_excipients WHERE formulation_id = ?`, formulationID).Error; err != nil {
+			return result, fmt.Errorf("failed to replace excipients for %s: %w", p.ProductCode, err)
+		}
+		for code, ingredient := range links {
+			err := tx.Exec(`
+				INSERT INTO drug_formulation_excipients (formulation_id, excipient_code, ingredient_name)
+				VALUES (?, ?, ?)`, formulationID, code, ingredient).Error
+			if err != nil {
+				return result, fmt.Errorf("failed to link %s to %s: %w", p.ProductCode, code, err)
+			}
+			result.ExcipientLinks++
+		}
+		result.ProductsImported++
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return ExcipientImportResult{}, fmt.Errorf("failed to commit product import: %w", err)
+	}
+
+	ae.excipientMu.Lock()
+	ae.excipients = nil
+	ae.excipientMu.Unlock()
+
+	return result, nil
+}
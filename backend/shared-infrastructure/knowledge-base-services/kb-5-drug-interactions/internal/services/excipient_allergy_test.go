@@ -0,0 +1,229 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// EXCIPIENT ALLERGY TESTS
+// ============================================================================
+
+func TestImplicatedExcipients(t *testing.T) {
+	excipients := map[string]Excipient{
+		"PEG": {Code: "PEG", Name: "Polyethylene glycol (PEG)",
+			Terms: models.StringArray{"polyethylene glycol", "peg", "macrogol"}, CrossReactiveWith: models.StringArray{"POLYSORBATE_80"}},
+		"POLYSORBATE_80": {Code: "POLYSORBATE_80", Name: "Polysorbate 80",
+			Terms: models.StringArray{"polysorbate 80", "polysorbate"}, CrossReactiveWith: models.StringArray{"PEG"}},
+		"LACTOSE": {Code: "LACTOSE", Name: "Lactose",
+			Terms: models.StringArray{"lactose", "lactose monohydrate", "milk", "dairy"}},
+	}
+
+	implicated := implicatedExcipients(excipients, "Polyethylene glycol 3350")
+	assert.Equal(t, map[string]bool{"PEG": true, "POLYSORBATE_80": false}, implicated)
+
+	implicated = implicatedExcipients(excipients, "Cow's milk protein")
+	assert.Equal(t, map[string]bool{"LACTOSE": true}, implicated)
+
+	assert.Empty(t, implicatedExcipients(excipients, "Pegfilgrastim"), "terms match whole words only")
+	assert.Empty(t, implicatedExcipients(excipients, "Penicillin"))
+
+	// A bare "polysorbate" term does not stand in for a differently numbered polysorbate
+	assert.Empty(t, implicatedExcipients(excipients, "Polysorbate 20"))
+	assert.Contains(t, implicatedExcipients(excipients, "Polysorbate"), "POLYSORBATE_80")
+	links, _ := mapIngredients(excipients, []string{"polysorbate 20", "polysorbate 80"})
+	assert.Equal(t, map[string]string{"POLYSORBATE_80": "polysorbate 80"}, links)
+}
+
+func TestEvaluateExcipientAllergies_IntoleranceSkipped(t *testing.T) {
+	soluMedrol40 := &ExcipientProduct{ProductCode: "LOCAL:SOLU-MEDROL-40MG", ProductName: "Solu-Medrol 40 mg", DrugCode: "RxCUI:6902", Route: "iv",
+		Excipients: map[string]string{"LACTOSE": "lactose monohydrate"}}
+	soluMedrol125 := &ExcipientProduct{ProductCode: "LOCAL:SOLU-MEDROL-125MG", ProductName: "Solu-Medrol 125 mg", DrugCode: "RxCUI:6902", Route: "iv",
+		Excipients: map[string]string{}}
+	depoMedrol := &ExcipientProduct{ProductCode: "LOCAL:DEPO-MEDROL-40MG-ML", ProductName: "Depo-Medrol 40 mg/mL", DrugCode: "RxCUI:6902", Route: "im",
+		Excipients: map[string]string{"PEG": "polyethylene glycol 3350", "POLYSORBATE_80": "polysorbate 80"}}
+	catalog := &excipientCatalog{
+		excipients: map[string]Excipient{
+			"PEG": {Code: "PEG", Name: "Polyethylene glycol (PEG)",
+				Terms: models.StringArray{"polyethylene glycol", "peg", "macrogol"}, CrossReactiveWith: models.StringArray{"POLYSORBATE_80"}},
+			"POLYSORBATE_80": {Code: "POLYSORBATE_80", Name: "Polysorbate 80",
+				Terms: models.StringArray{"polysorbate 80", "polysorbate"}, CrossReactiveWith: models.StringArray{"PEG"}},
+			"LACTOSE": {Code: "LACTOSE", Name: "Lactose",
+				Terms: models.StringArray{"lactose", "lactose monohydrate", "milk", "dairy"}},
+		},
+		products: map[string]*ExcipientProduct{
+			"LOCAL:SOLU-MEDROL-40MG":    soluMedrol40,
+			"LOCAL:SOLU-MEDROL-125MG":   soluMedrol125,
+			"LOCAL:DEPO-MEDROL-40MG-ML": depoMedrol,
+		},
+		byDrug: map[string][]*ExcipientProduct{"RXCUI:6902": {soluMedrol40, soluMedrol125, depoMedrol}},
+	}
+
+	request := AllergyCheckRequest{
+		DrugCodes:        []string{"RxCUI:6902"},
+		ProductCodes:     []string{"LOCAL:SOLU-MEDROL-40MG"},
+		PatientAllergies: []PatientAllergy{{AllergenCode: "LACTOSE", AllergenName: "Lactose intolerance"}},
+		IncludePossible:  true,
+	}
+	assert.Empty(t, evaluateExcipientAllergies(catalog, request))
+
+	request.PatientAllergies[0] = PatientAllergy{AllergenCode: "LACTOSE", AllergenName: "Lactose", ReactionType: "intolerance"}
+	assert.Empty(t, evaluateExcipientAllergies(catalog, request))
+}
+
+func TestEvaluateExcipientAllergies_ProductOrder(t *testing.T) {
+	soluMedrol40 := &ExcipientProduct{ProductCode: "LOCAL:SOLU-MEDROL-40MG", ProductName: "Solu-Medrol 40 mg", DrugCode: "RxCUI:6902", Route: "iv",
+		Excipients: map[string]string{"LACTOSE": "lactose monohydrate"}}
+	soluMedrol125 := &ExcipientProduct{ProductCode: "LOCAL:SOLU-MEDROL-125MG", ProductName: "Solu-Medrol 125 mg", DrugCode: "RxCUI:6902", Route: "iv",
+		Excipients: map[string]string{}}
+	depoMedrol := &ExcipientProduct{ProductCode: "LOCAL:DEPO-MEDROL-40MG-ML", ProductName: "Depo-Medrol 40 mg/mL", DrugCode: "RxCUI:6902", Route: "im",
+		Excipients: map[string]string{"PEG": "polyethylene glycol 3350", "POLYSORBATE_80": "polysorbate 80"}}
+	catalog := &excipientCatalog{
+		excipients: map[string]Excipient{
+			"PEG": {Code: "PEG", Name: "Polyethylene glycol (PEG)",
+				Terms: models.StringArray{"polyethylene glycol", "peg", "macrogol"}, CrossReactiveWith: models.StringArray{"POLYSORBATE_80"}},
+			"POLYSORBATE_80": {Code: "POLYSORBATE_80", Name: "Polysorbate 80",
+				Terms: models.StringArray{"polysorbate 80", "polysorbate"}, CrossReactiveWith: models.StringArray{"PEG"}},
+			"LACTOSE": {Code: "LACTOSE", Name: "Lactose",
+				Terms: models.StringArray{"lactose", "lactose monohydrate", "milk", "dairy"}},
+		},
+		products: map[string]*ExcipientProduct{
+			"LOCAL:SOLU-MEDROL-40MG":    soluMedrol40,
+			"LOCAL:SOLU-MEDROL-125MG":   soluMedrol125,
+			"LOCAL:DEPO-MEDROL-40MG-ML": depoMedrol,
+		},
+		byDrug: map[string][]*ExcipientProduct{"RXCUI:6902": {soluMedrol40, soluMedrol125, depoMedrol}},
+	}
+
+	request := AllergyCheckRequest{
+		DrugCodes:        []string{"RxCUI:6902"},
+		ProductCodes:     []string{"local:solu-medrol-40mg"},
+		PatientAllergies: []PatientAllergy{{AllergenCode: "MILK", AllergenName: "Cow's milk", ReactionType: "anaphylaxis"}},
+	}
+
+	results := evaluateExcipientAllergies(catalog, request)
+	require.Len(t, results, 1, "the ingredient-level check is skipped when a product is ordered")
+
+	result := results[0]
+	assert.Equal(t, "LOCAL:SOLU-MEDROL-40MG", result.ProductCode)
+	assert.Equal(t, "Lactose", result.Excipient)
+	assert.Equal(t, "critical", result.AlertLevel)
+	assert.Equal(t, AllergyRecommendAvoid, result.Recommendation)
+	assert.Equal(t, []string{"Solu-Medrol 125 mg", "Depo-Medrol 40 mg/mL"}, result.AlternativeDrugs, "same-route products first")
+	assert.Contains(t, result.ClinicalGuidance, "lactose monohydrate")
+}
+
+func TestEvaluateExcipientAllergies_CrossReactive(t *testing.T) {
+	amiodarone := &ExcipientProduct{ProductCode: "LOCAL:AMIODARONE-50MG-ML", ProductName: "Amiodarone 50 mg/mL vial", DrugCode: "RxCUI:703", Route: "iv",
+		Excipients: map[string]string{"POLYSORBATE_80": "polysorbate 80", "BENZYL_ALCOHOL": "benzyl alcohol"}}
+	catalog := &excipientCatalog{
+		excipients: map[string]Excipient{
+			"PEG": {Code: "PEG", Name: "Polyethylene glycol (PEG)",
+				Terms: models.StringArray{"polyethylene glycol", "peg", "macrogol"}, CrossReactiveWith: models.StringArray{"POLYSORBATE_80"}},
+			"POLYSORBATE_80": {Code: "POLYSORBATE_80", Name: "Polysorbate 80",
+				Terms: models.StringArray{"polysorbate 80", "polysorbate"}, CrossReactiveWith: models.StringArray{"PEG"}},
+			"BENZYL_ALCOHOL": {Code: "BENZYL_ALCOHOL", Name: "Benzyl alcohol", Terms: models.StringArray{"benzyl alcohol"}},
+		},
+		products: map[string]*ExcipientProduct{"LOCAL:AMIODARONE-50MG-ML": amiodarone},
+		byDrug:   map[string][]*ExcipientProduct{"RXCUI:703": {amiodarone}},
+	}
+
+	request := AllergyCheckRequest{
+		DrugCodes:        []string{"RxCUI:703"},
+		ProductCodes:     []string{"LOCAL:AMIODARONE-50MG-ML"},
+		PatientAllergies: []PatientAllergy{{AllergenCode: "PEG", ReactionType: "urticaria"}},
+	}
+
+	results := evaluateExcipientAllergies(catalog, request)
+	require.Len(t, results, 1)
+	assert.Equal(t, excipientCrossReactive, results[0].CrossReactivityType)
+	assert.Equal(t, "moderate", results[0].AlertLevel)
+	assert.Empty(t, results[0].AlternativeDrugs)
+	assert.Contains(t, results[0].ClinicalGuidance, "No excipient-free product")
+}
+
+func TestEvaluateExcipientAllergies_IngredientOrder(t *testing.T) {
+	soluMedrol40 := &ExcipientProduct{ProductCode: "LOCAL:SOLU-MEDROL-40MG", ProductName: "Solu-Medrol 40 mg", DrugCode: "RxCUI:6902", Route: "iv",
+		Excipients: map[string]string{"LACTOSE": "lactose monohydrate"}}
+	soluMedrol125 := &ExcipientProduct{ProductCode: "LOCAL:SOLU-MEDROL-125MG", ProductName: "Solu-Medrol 125 mg", DrugCode: "RxCUI:6902", Route: "iv",
+		Excipients: map[string]string{}}
+	depoMedrol := &ExcipientProduct{ProductCode: "LOCAL:DEPO-MEDROL-40MG-ML", ProductName: "Depo-Medrol 40 mg/mL", DrugCode: "RxCUI:6902", Route: "im",
+		Excipients: map[string]string{"PEG": "polyethylene glycol 3350", "POLYSORBATE_80": "polysorbate 80"}}
+	catalog := &excipientCatalog{
+		excipients: map[string]Excipient{
+			"PEG": {Code: "PEG", Name: "Polyethylene glycol (PEG)",
+				Terms: models.StringArray{"polyethylene glycol", "peg", "macrogol"}, CrossReactiveWith: models.StringArray{"POLYSORBATE_80"}},
+			"POLYSORBATE_80": {Code: "POLYSORBATE_80", Name: "Polysorbate 80",
+				Terms: models.StringArray{"polysorbate 80", "polysorbate"}, CrossReactiveWith: models.StringArray{"PEG"}},
+			"LACTOSE": {Code: "LACTOSE", Name: "Lactose",
+				Terms: models.StringArray{"lactose", "lactose monohydrate", "milk", "dairy"}},
+		},
+		products: map[string]*ExcipientProduct{
+			"LOCAL:SOLU-MEDROL-40MG":    soluMedrol40,
+			"LOCAL:SOLU-MEDROL-125MG":   soluMedrol125,
+			"LOCAL:DEPO-MEDROL-40MG-ML": depoMedrol,
+		},
+		byDrug: map[string][]*ExcipientProduct{"RXCUI:6902": {soluMedrol40, soluMedrol125, depoMedrol}},
+	}
+
+	request := AllergyCheckRequest{
+		DrugCodes:        []string{"6902"},
+		PatientAllergies: []PatientAllergy{{AllergenCode: "LACTOSE", AllergenName: "Lactose"}},
+	}
+
+	// An excipient-free product exists, so this is a low alert shown only on request
+	assert.Empty(t, evaluateExcipientAllergies(catalog, request))
+
+	request.IncludePossible = true
+	results := evaluateExcipientAllergies(catalog, request)
+	require.Len(t, results, 1)
+	assert.Equal(t, "low", results[0].AlertLevel)
+	assert.Contains(t, results[0].AlternativeDrugs, "Solu-Medrol 125 mg")
+
+	// Refuted allergies are suppressed
+	request.PatientAllergies[0].Status = "refuted"
+	results = evaluateExcipientAllergies(catalog, request)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Suppressed)
+
+	// A documented tolerance of the ordered drug suppresses the ingredient-level alert
+	request.PatientAllergies[0] = PatientAllergy{AllergenCode: "LACTOSE", AllergenName: "Lactose", OnsetDate: "2018"}
+	request.ToleratedExposures = []ToleratedExposure{{DrugCode: "RxCUI:6902", Date: "2022-04-01"}}
+	results = evaluateExcipientAllergies(catalog, request)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Suppressed)
+}
+
+func TestParseExcipientFile(t *testing.T) {
+	data := "NDC,Proprietary Name,RXCUI,Dosage Form,Route,Labeler,Inactive Ingredients\n" +
+		"0009-0039-28,Solu-Medrol,6902,INJECTION,INTRAVENOUS,Pfizer,\"lactose monohydrate; sodium phosphate\"\n" +
+		",Missing,6902,TABLET,ORAL,,lactose\n"
+
+	products, issues, err := ParseExcipientFile(strings.NewReader(data), "")
+	require.NoError(t, err)
+	require.Len(t, products, 1)
+	require.Len(t, issues, 1)
+	assert.Equal(t, 3, issues[0].Line)
+
+	p := products[0]
+	assert.Equal(t, "RxCUI:6902", p.DrugCode)
+	assert.Equal(t, "injection", p.FormulationType)
+	assert.Equal(t, "intravenous", p.Route)
+	assert.Equal(t, []string{"lactose monohydrate", "sodium phosphate"}, p.Ingredients)
+
+	excipients := map[string]Excipient{
+		"LACTOSE": {Code: "LACTOSE", Name: "Lactose",
+			Terms: models.StringArray{"lactose", "lactose monohydrate", "milk", "dairy"}},
+	}
+	links, unmapped := mapIngredients(excipients, p.Ingredients)
+	assert.Equal(t, map[string]string{"LACTOSE": "lactose monohydrate"}, links)
+	assert.Equal(t, 1, unmapped)
+
+	_, _, err = ParseExcipientFile(strings.NewReader("product_code,drug_code\nX,1\n"), "csv")
+	assert.Error(t, err, "inactive_ingredients column is required")
+}
@@ -0,0 +1,494 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"kb-drug-interactions/internal/metrics"
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// GOVERNANCE OVERRIDE REQUESTS
+// Request → decision workflow for alerts governed as mandatory_escalation or
+// hard_block_governance_override. Approvals apply to one patient and drug pair
+// for a limited time. Requester and approver identities and roles are the
+// authenticated caller's, supplied by the API layer.
+// ============================================================================
+
+const (
+	// overrideDecisionWindow is how long a request may stay pending
+	overrideDecisionWindow = 24 * time.Hour
+	// overrideDefaultValidity applies when the approver does not set one
+	overrideDefaultValidity = 24 * time.Hour
+	// overrideMaxValidity bounds how long an approval may be honoured
+	overrideMaxValidity = 7 * 24 * time.Hour
+)
+
+// overrideLevelAuthority ranks the override levels from GetOverrideLevel.
+// Only senior_clinical and governance are decided through override requests.
+var overrideLevelAuthority = map[string]int{
+	"clinical":        1,
+	"senior_clinical": 2,
+	"governance":      3,
+}
+
+// overrideRoleAuthority ranks roles by the highest override level they may
+// decide; escalation targets (senior_pharmacist, p_and_t_committee) included
+var overrideRoleAuthority = map[string]int{
+	"physician":                      1,
+	"prescriber":                     1,
+	"nurse_practitioner":             1,
+	"pharmacist":                     1,
+	"attending_physician":            2,
+	"consultant":                     2,
+	"senior_pharmacist":              2,
+	"clinical_pharmacist_specialist": 2,
+	"medical_director":               3,
+	"chief_pharmacist":               3,
+	"governance_officer":             3,
+	"p_and_t_committee":              3,
+}
+
+// Override request workflow error codes
+const (
+	OverrideErrorNotFound       = "OVERRIDE_NOT_FOUND"
+	OverrideErrorNotPending     = "OVERRIDE_NOT_PENDING"
+	OverrideErrorSelfDecision   = "OVERRIDE_SELF_DECISION"
+	OverrideErrorUnauthorized   = "OVERRIDE_ROLE_NOT_AUTHORIZED"
+	OverrideErrorNotOverridable = "NOT_OVERRIDABLE"
+	OverrideErrorInvalid        = "INVALID_OVERRIDE_REQUEST"
+)
+
+// OverrideRequestError reports an override request the workflow does not allow
+type OverrideRequestError struct {
+	Code   string
+	Reason string
+}
+
+func (e *OverrideRequestError) Error() string {
+	return e.Reason
+}
+
+// OverrideRequestSubmission requests an override for an alert from a governed check
+type OverrideRequestSubmission struct {
+	TransactionID    string                  `json:"transaction_id" binding:"required"`
+	AuditID          *uuid.UUID              `json:"audit_id,omitempty"`
+	InteractionID    string                  `json:"interaction_id,omitempty"`
+	Drug1Code        string                  `json:"drug1_code" binding:"required"`
+	Drug2Code        string                  `json:"drug2_code,omitempty"`
+	GovernanceAction models.GovernanceAction `json:"governance_action" binding:"required"`
+	PolicyVersionID  string                  `json:"policy_version_id" binding:"required"`
+
+	PatientID     string `json:"patient_id" binding:"required"`
+	EncounterID   string `json:"encounter_id,omitempty"`
+	InstitutionID string `json:"institution_id,omitempty"`
+
+	RequesterID   string `json:"-"` // The caller
+	RequesterRole string `json:"-"` // The caller's role
+	Department    string `json:"department,omitempty"`
+
+	OverrideJustification     string `json:"override_justification" binding:"required"`
+	ClinicalRationale         string `json:"clinical_rationale,omitempty"`
+	AlternativeMonitoringPlan string `json:"alternative_monitoring_plan,omitempty"`
+}
+
+// OverrideDecision approves or denies a pending request
+type OverrideDecision struct {
+	ApproverID    string `json:"-"`                        // The caller
+	ApproverRole  string `json:"-"`                        // The caller's role
+	Notes         string `json:"notes,omitempty"`          // Required to deny
+	ValidityHours int    `json:"validity_hours,omitempty"` // Approval only; default 24, max 168
+}
+
+// OverrideRequestFilter selects override requests; empty fields match all
+type OverrideRequestFilter struct {
+	PatientID     string
+	InstitutionID string
+	Status        string
+}
+
+// GovernanceOverrideEngine manages override requests and applies approved
+// overrides to governed interaction results
+type GovernanceOverrideEngine struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	metrics *metrics.Collector
+}
+
+// NewGovernanceOverrideEngine creates a new override request engine
+func NewGovernanceOverrideEngine(db *gorm.DB, logger *zap.Logger, metricsCollector *metrics.Collector) *GovernanceOverrideEngine {
+	return &GovernanceOverrideEngine{
+		db:      db,
+		logger:  logger,
+		metrics: metricsCollector,
+	}
+}
+
+// SubmitOverrideRequest stores a pending request, due for decision within 24
+// hours. The policy version must be one a governed check could have applied,
+// and an audit_id must name a recorded attribution audit.
+func (goe *GovernanceOverrideEngine) SubmitOverrideRequest(ctx context.Context, submission OverrideRequestSubmission) (*models.GovernanceOverrideRequest, error) {
+	request, err := newOverrideRequest(submission, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	policy, err := goe.findPolicyVersion(ctx, request.PolicyVersionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOverridePolicyVersion(request, policy); err != nil {
+		return nil, err
+	}
+	if err := goe.db.WithContext(ctx).Create(request).Error; err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" && request.AuditID != nil {
+			return nil, &OverrideRequestError{Code: OverrideErrorInvalid,
+				Reason: fmt.Sprintf("audit_id %s is not a recorded attribution audit", request.AuditID)}
+		}
+		return nil, fmt.Errorf("failed to create override request: %w", err)
+	}
+
+	goe.metrics.RecordGovernanceOverride("submitted")
+	return request, nil
+}
+
+// ListOverrideRequests returns requests newest first, with expired requests marked
+func (goe *GovernanceOverrideEngine) ListOverrideRequests(ctx context.Context, filter OverrideRequestFilter) ([]models.GovernanceOverrideRequest, error) {
+	if err := goe.expireOverrideRequests(ctx); err != nil {
+		return nil, err
+	}
+
+	query := goe.db.WithContext(ctx)
+	if filter.PatientID != "" {
+		query = query.Where("patient_id = ?", filter.PatientID)
+	}
+	if filter.InstitutionID != "" {
+		query = query.Where("institution_id = ?", filter.InstitutionID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var requests []models.GovernanceOverrideRequest
+	if err := query.Order("created_at DESC").Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to load override requests: %w", err)
+	}
+	return requests, nil
+}
+
+// GetOverrideRequest returns one request, or nil if it does not exist
+func (goe *GovernanceOverrideEngine) GetOverrideRequest(ctx context.Context, id uuid.UUID) (*models.GovernanceOverrideRequest, error) {
+	if err := goe.expireOverrideRequests(ctx); err != nil {
+		return nil, err
+	}
+	return goe.findOverrideRequest(ctx, id)
+}
+
+// ApproveOverrideRequest approves a pending request. The approver's role must
+// have authority for the required override level and the approver must not be
+// the requester.
+func (goe *GovernanceOverrideEngine) ApproveOverrideRequest(ctx context.Context, id uuid.UUID, decision OverrideDecision) (*models.GovernanceOverrideRequest, error) {
+	return goe.decide(ctx, id, decision, true)
+}
+
+// DenyOverrideRequest denies a pending request, with the same role checks as approval
+func (goe *GovernanceOverrideEngine) DenyOverrideRequest(ctx context.Context, id uuid.UUID, decision OverrideDecision) (*models.GovernanceOverrideRequest, error) {
+	return goe.decide(ctx, id, decision, false)
+}
+
+func (goe *GovernanceOverrideEngine) decide(ctx context.Context, id uuid.UUID, decision OverrideDecision, approve bool) (*models.GovernanceOverrideRequest, error) {
+	if err := goe.expireOverrideRequests(ctx); err != nil {
+		return nil, err
+	}
+	request, err := goe.findOverrideRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, &OverrideRequestError{Code: OverrideErrorNotFound, Reason: fmt.Sprintf("override request %s not found", id)}
+	}
+	if err := decideOverrideRequest(request, decision, approve, time.Now()); err != nil {
+		return nil, err
+	}
+
+	result := goe.db.WithContext(ctx).Model(&models.GovernanceOverrideRequest{}).
+		Where("id = ? AND status = ?", id, models.OverrideStatusPending).
+		Updates(map[string]interface{}{
+			"status":               request.Status,
+			"approver_id":          request.ApproverID,
+			"approver_role":        request.ApproverRole,
+			"approval_decision_at": request.ApprovalDecisionAt,
+			"approval_notes":       request.ApprovalNotes,
+			"valid_from":           request.ValidFrom,
+			"valid_until":          request.ValidUntil,
+			"updated_at":           time.Now(),
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to record override decision: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, &OverrideRequestError{Code: OverrideErrorNotPending, Reason: "override request was decided by someone else"}
+	}
+
+	goe.logger.Info("Governance override request decided",
+		zap.String("override_id", id.String()),
+		zap.String("status", request.Status),
+		zap.String("approver_id", request.ApproverID),
+		zap.String("required_override_level", request.RequiredOverrideLevel))
+	goe.metrics.RecordGovernanceOverride(request.Status)
+	return request, nil
+}
+
+// ApplyApprovedOverrides lifts blocks and escalations on results for which the
+// patient has an approved override in force at the institution, returning how
+// many were lifted
+func (goe *GovernanceOverrideEngine) ApplyApprovedOverrides(ctx context.Context, patientID, institutionID string, results []models.GovernedInteractionResult) (int, error) {
+	if strings.TrimSpace(patientID) == "" || len(results) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	var overrides []models.GovernanceOverrideRequest
+	if err := goe.db.WithContext(ctx).
+		Where("patient_id = ? AND institution_id = ? AND status = ? AND valid_from <= ? AND valid_until > ?",
+			patientID, strings.TrimSpace(institutionID), models.OverrideStatusApproved, now, now).
+		Order("approval_decision_at DESC").
+		Find(&overrides).Error; err != nil {
+		return 0, fmt.Errorf("failed to load approved overrides: %w", err)
+	}
+
+	applied := applyApprovedOverrides(results, overrides, institutionID, now)
+	if applied > 0 {
+		goe.metrics.RecordGovernanceOverride("applied")
+	}
+	return applied, nil
+}
+
+// expireOverrideRequests writes back the expiry of pending requests past their
+// decision deadline and approvals past their validity
+func (goe *GovernanceOverrideEngine) expireOverrideRequests(ctx context.Context) error {
+	now := time.Now()
+	err := goe.db.WithContext(ctx).Model(&models.GovernanceOverrideRequest{}).
+		Where("(status = ? AND decision_due_at <= ?) OR (status = ? AND valid_until <= ?)",
+			models.OverrideStatusPending, now, models.OverrideStatusApproved, now).
+		Updates(map[string]interface{}{"status": models.OverrideStatusExpired, "updated_at": now}).Error
+	if err != nil {
+		return fmt.Errorf("failed to expire override requests: %w", err)
+	}
+	return nil
+}
+
+// findPolicyVersion loads the stored policy version a request names, or nil if
+// the ID is not a stored version
+func (goe *GovernanceOverrideEngine) findPolicyVersion(ctx context.Context, policyVersionID string) (*models.SeverityGovernanceMapping, error) {
+	id, err := uuid.Parse(policyVersionID)
+	if err != nil {
+		return nil, nil
+	}
+	var policy models.SeverityGovernanceMapping
+	err = goe.db.WithContext(ctx).Where("id = ?", id).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load governance policy version: %w", err)
+	}
+	return &policy, nil
+}
+
+func (goe *GovernanceOverrideEngine) findOverrideRequest(ctx context.Context, id uuid.UUID) (*models.GovernanceOverrideRequest, error) {
+	var request models.GovernanceOverrideRequest
+	err := goe.db.WithContext(ctx).Where("id = ?", id).First(&request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load override request: %w", err)
+	}
+	return &request, nil
+}
+
+// newOverrideRequest validates a submission and builds the pending request.
+// Only escalations and governance blocks are decided through requests; a
+// clinician acknowledges warnings directly and hard blocks cannot be overridden.
+func newOverrideRequest(submission OverrideRequestSubmission, now time.Time) (*models.GovernanceOverrideRequest, error) {
+	level := models.GetOverrideLevel(submission.GovernanceAction)
+	if overrideLevelAuthority[level] < overrideLevelAuthority["senior_clinical"] {
+		return nil, &OverrideRequestError{Code: OverrideErrorNotOverridable,
+			Reason: fmt.Sprintf("governance action %q (override level %s) is not decided through override requests", submission.GovernanceAction, level)}
+	}
+
+	role := normalizeOverrideRole(submission.RequesterRole)
+	if overrideRoleAuthority[role] == 0 {
+		return nil, &OverrideRequestError{Code: OverrideErrorUnauthorized,
+			Reason: fmt.Sprintf("requester role %q is not a recognised clinical role", submission.RequesterRole)}
+	}
+
+	request := &models.GovernanceOverrideRequest{
+		AuditID:                   submission.AuditID,
+		TransactionID:             strings.TrimSpace(submission.TransactionID),
+		RequesterID:               strings.TrimSpace(submission.RequesterID),
+		RequesterRole:             role,
+		Department:                strings.TrimSpace(submission.Department),
+		PatientID:                 strings.TrimSpace(submission.PatientID),
+		EncounterID:               strings.TrimSpace(submission.EncounterID),
+		InstitutionID:             strings.TrimSpace(submission.InstitutionID),
+		InteractionID:             strings.TrimSpace(submission.InteractionID),
+		Drug1Code:                 strings.TrimSpace(submission.Drug1Code),
+		Drug2Code:                 strings.TrimSpace(submission.Drug2Code),
+		GovernanceAction:          submission.GovernanceAction,
+		RequiredOverrideLevel:     level,
+		PolicyVersionID:           strings.TrimSpace(submission.PolicyVersionID),
+		OverrideJustification:     strings.TrimSpace(submission.OverrideJustification),
+		ClinicalRationale:         strings.TrimSpace(submission.ClinicalRationale),
+		AlternativeMonitoringPlan: strings.TrimSpace(submission.AlternativeMonitoringPlan),
+		Status:                    models.OverrideStatusPending,
+	}
+	required := []struct{ field, value string }{
+		{"transaction_id", request.TransactionID},
+		{"policy_version_id", request.PolicyVersionID},
+		{"requester_id", request.RequesterID},
+		{"patient_id", request.PatientID},
+		{"drug1_code", request.Drug1Code},
+		{"override_justification", request.OverrideJustification},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			return nil, &OverrideRequestError{Code: OverrideErrorInvalid, Reason: r.field + " is required"}
+		}
+	}
+
+	// Governed checks are not recorded, so only the form of the ID is checked
+	if _, err := uuid.Parse(request.TransactionID); err != nil {
+		return nil, &OverrideRequestError{Code: OverrideErrorInvalid,
+			Reason: fmt.Sprintf("transaction_id %q is not a UUID", request.TransactionID)}
+	}
+
+	due := now.Add(overrideDecisionWindow)
+	request.DecisionDueAt = &due
+	return request, nil
+}
+
+// checkOverridePolicyVersion checks that the request's policy version is one a
+// governed check at its institution could have applied: the built-in default,
+// or an approved version of the institution's or the global policy. Retired
+// versions are accepted since they may have applied when the check ran.
+func checkOverridePolicyVersion(request *models.GovernanceOverrideRequest, policy *models.SeverityGovernanceMapping) error {
+	if policy == nil {
+		if request.PolicyVersionID == models.DefaultGovernancePolicy().VersionID() {
+			return nil
+		}
+		return &OverrideRequestError{Code: OverrideErrorInvalid,
+			Reason: fmt.Sprintf("policy_version_id %q is not a governance policy version", request.PolicyVersionID)}
+	}
+	if policy.Status != models.PolicyStatusApproved {
+		return &OverrideRequestError{Code: OverrideErrorInvalid,
+			Reason: fmt.Sprintf("policy_version_id %s is a draft and has not been applied", request.PolicyVersionID)}
+	}
+	if policy.InstitutionID != "" && policy.InstitutionID != request.InstitutionID {
+		return &OverrideRequestError{Code: OverrideErrorInvalid,
+			Reason: fmt.Sprintf("policy_version_id %s belongs to institution %q", request.PolicyVersionID, policy.InstitutionID)}
+	}
+	return nil
+}
+
+// decideOverrideRequest records an approval or denial on a pending request at now
+func decideOverrideRequest(request *models.GovernanceOverrideRequest, decision OverrideDecision, approve bool, now time.Time) error {
+	if status := request.StatusAt(now); status != models.OverrideStatusPending {
+		return &OverrideRequestError{Code: OverrideErrorNotPending, Reason: fmt.Sprintf("override request is %s", status)}
+	}
+
+	approverID := strings.TrimSpace(decision.ApproverID)
+	if strings.EqualFold(approverID, strings.TrimSpace(request.RequesterID)) {
+		return &OverrideRequestError{Code: OverrideErrorSelfDecision, Reason: "the requester cannot decide their own override request"}
+	}
+	role := normalizeOverrideRole(decision.ApproverRole)
+	if overrideRoleAuthority[role] < overrideLevelAuthority[request.RequiredOverrideLevel] {
+		return &OverrideRequestError{Code: OverrideErrorUnauthorized,
+			Reason: fmt.Sprintf("role %q cannot decide %s overrides", decision.ApproverRole, request.RequiredOverrideLevel)}
+	}
+
+	notes := strings.TrimSpace(decision.Notes)
+	if !approve && notes == "" {
+		return &OverrideRequestError{Code: OverrideErrorInvalid, Reason: "notes are required to deny an override request"}
+	}
+	validity := overrideDefaultValidity
+	if decision.ValidityHours != 0 {
+		validity = time.Duration(decision.ValidityHours) * time.Hour
+	}
+	if validity <= 0 || validity > overrideMaxValidity {
+		return &OverrideRequestError{Code: OverrideErrorInvalid,
+			Reason: fmt.Sprintf("validity_hours must be between 1 and %d", int(overrideMaxValidity.Hours()))}
+	}
+
+	request.ApproverID = approverID
+	request.ApproverRole = role
+	request.ApprovalNotes = notes
+	request.ApprovalDecisionAt = &now
+	if !approve {
+		request.Status = models.OverrideStatusDenied
+		return nil
+	}
+
+	until := now.Add(validity)
+	request.Status = models.OverrideStatusApproved
+	request.ValidFrom = &now
+	request.ValidUntil = &until
+	return nil
+}
+
+// applyApprovedOverrides marks results covered by an override in force at now.
+// An override covers a result for the same drug pair, in either order, whose
+// governance action is decided through override requests and is no stricter
+// than the action approved. Overrides approved at another institution do not
+// apply.
+func applyApprovedOverrides(results []models.GovernedInteractionResult, overrides []models.GovernanceOverrideRequest, institutionID string, now time.Time) int {
+	institutionID = strings.TrimSpace(institutionID)
+	applied := 0
+	for i := range results {
+		result := &results[i]
+		if overrideLevelAuthority[models.GetOverrideLevel(result.GovernanceAction)] < overrideLevelAuthority["senior_clinical"] {
+			continue
+		}
+		pair := overridePairKey(result.Drug1.Code, result.Drug2.Code)
+		for j := range overrides {
+			override := &overrides[j]
+			if !override.ValidAt(now) || override.InstitutionID != institutionID ||
+				override.GovernanceAction.Priority() < result.GovernanceAction.Priority() ||
+				overridePairKey(override.Drug1Code, override.Drug2Code) != pair {
+				continue
+			}
+
+			result.RequiresOverride = false
+			result.EscalationRequired = false
+			result.Attribution.InstitutionalOverride = true
+			result.Attribution.OverrideReference = override.ID.String()
+			result.Attribution.OverrideApprover = override.ApproverID
+			applied++
+			break
+		}
+	}
+	return applied
+}
+
+// overridePairKey identifies a drug pair regardless of order
+func overridePairKey(code1, code2 string) string {
+	key1, key2 := normalizeATCDrugKey(code1), normalizeATCDrugKey(code2)
+	if key2 < key1 {
+		key1, key2 = key2, key1
+	}
+	return key1 + "|" + key2
+}
+
+func normalizeOverrideRole(role string) string {
+	return strings.ToLower(strings.TrimSpace(role))
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+var overrideTestNow = time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+
+func testOverrideSubmission(action models.GovernanceAction) OverrideRequestSubmission {
+	return OverrideRequestSubmission{
+		TransactionID:         "5b0e7c1e-3f7a-4d4b-9a53-2f1c8e6d9a10",
+		Drug1Code:             "RxCUI:11289",
+		Drug2Code:             "RxCUI:1191",
+		GovernanceAction:      action,
+		PolicyVersionID:       "8d3f1a52-6b1e-4c0f-a7d2-5e9b4c2f0d31",
+		PatientID:             "patient-7",
+		InstitutionID:         "general-hospital",
+		RequesterID:           "dr.khan",
+		RequesterRole:         "Physician",
+		OverrideJustification: "Post-PCI dual therapy; INR monitored daily",
+	}
+}
+
+func overrideErrorCode(t *testing.T, err error) string {
+	t.Helper()
+	var overrideErr *OverrideRequestError
+	require.True(t, errors.As(err, &overrideErr), "expected *OverrideRequestError, got %v", err)
+	return overrideErr.Code
+}
+
+func TestNewOverrideRequest_LevelFromGovernanceAction(t *testing.T) {
+	request, err := newOverrideRequest(testOverrideSubmission(models.GovernanceHardBlockOverride), overrideTestNow)
+	require.NoError(t, err)
+	assert.Equal(t, "governance", request.RequiredOverrideLevel)
+	assert.Equal(t, models.OverrideStatusPending, request.Status)
+	assert.Equal(t, "physician", request.RequesterRole)
+	assert.Equal(t, overrideTestNow.Add(overrideDecisionWindow), *request.DecisionDueAt)
+
+	request, err = newOverrideRequest(testOverrideSubmission(models.GovernanceMandatoryEscalation), overrideTestNow)
+	require.NoError(t, err)
+	assert.Equal(t, "senior_clinical", request.RequiredOverrideLevel)
+
+	// Hard blocks cannot be overridden; warnings are acknowledged without a request
+	for _, action := range []models.GovernanceAction{models.GovernanceHardBlock, models.GovernanceWarnAcknowledge, models.GovernanceNotify} {
+		_, err = newOverrideRequest(testOverrideSubmission(action), overrideTestNow)
+		assert.Equal(t, OverrideErrorNotOverridable, overrideErrorCode(t, err), string(action))
+	}
+
+	submission := testOverrideSubmission(models.GovernanceMandatoryEscalation)
+	submission.RequesterRole = "visitor"
+	_, err = newOverrideRequest(submission, overrideTestNow)
+	assert.Equal(t, OverrideErrorUnauthorized, overrideErrorCode(t, err))
+
+	// A request must name the governed check it overrides
+	submission = testOverrideSubmission(models.GovernanceMandatoryEscalation)
+	submission.PolicyVersionID = ""
+	_, err = newOverrideRequest(submission, overrideTestNow)
+	assert.Equal(t, OverrideErrorInvalid, overrideErrorCode(t, err))
+
+	submission = testOverrideSubmission(models.GovernanceMandatoryEscalation)
+	submission.TransactionID = "txn-1"
+	_, err = newOverrideRequest(submission, overrideTestNow)
+	assert.Equal(t, OverrideErrorInvalid, overrideErrorCode(t, err))
+}
+
+func TestCheckOverridePolicyVersion(t *testing.T) {
+	request, err := newOverrideRequest(testOverrideSubmission(models.GovernanceMandatoryEscalation), overrideTestNow)
+	require.NoError(t, err)
+
+	// An approved version of the institution's policy, or of the global policy
+	policy := models.DefaultGovernancePolicy()
+	policy.ID = uuid.MustParse(request.PolicyVersionID)
+	policy.PolicyName = "general_hospital_policy"
+	policy.InstitutionID = "general-hospital"
+	assert.NoError(t, checkOverridePolicyVersion(request, policy))
+	policy.InstitutionID = ""
+	assert.NoError(t, checkOverridePolicyVersion(request, policy))
+
+	// Retired versions may have applied when the check ran
+	policy.Active = false
+	assert.NoError(t, checkOverridePolicyVersion(request, policy))
+
+	policy.InstitutionID = "county-clinic"
+	assert.Equal(t, OverrideErrorInvalid, overrideErrorCode(t, checkOverridePolicyVersion(request, policy)))
+
+	policy.InstitutionID = "general-hospital"
+	policy.Status = models.PolicyStatusDraft
+	assert.Equal(t, OverrideErrorInvalid, overrideErrorCode(t, checkOverridePolicyVersion(request, policy)))
+
+	// Without a stored version only the built-in default is accepted
+	assert.Equal(t, OverrideErrorInvalid, overrideErrorCode(t, checkOverridePolicyVersion(request, nil)))
+	request.PolicyVersionID = "builtin:default_clinical_safety"
+	assert.NoError(t, checkOverridePolicyVersion(request, nil))
+	request.PolicyVersionID = "builtin:site_policy"
+	assert.Equal(t, OverrideErrorInvalid, overrideErrorCode(t, checkOverridePolicyVersion(request, nil)))
+}
+
+func TestDecideOverrideRequest_RoleChecks(t *testing.T) {
+	pending := func(action models.GovernanceAction) *models.GovernanceOverrideRequest {
+		request, err := newOverrideRequest(testOverrideSubmission(action), overrideTestNow)
+		require.NoError(t, err)
+		return request
+	}
+	decisionTime := overrideTestNow.Add(2 * time.Hour)
+
+	// A senior pharmacist may decide escalations but not governance blocks
+	err := decideOverrideRequest(pending(models.GovernanceHardBlockOverride),
+		OverrideDecision{ApproverID: "rph.ortiz", ApproverRole: "senior_pharmacist"}, true, decisionTime)
+	assert.Equal(t, OverrideErrorUnauthorized, overrideErrorCode(t, err))
+
+	request := pending(models.GovernanceMandatoryEscalation)
+	require.NoError(t, decideOverrideRequest(request,
+		OverrideDecision{ApproverID: "rph.ortiz", ApproverRole: "senior_pharmacist", ValidityHours: 12}, true, decisionTime))
+	assert.Equal(t, models.OverrideStatusApproved, request.Status)
+	assert.Equal(t, decisionTime, *request.ValidFrom)
+	assert.Equal(t, decisionTime.Add(12*time.Hour), *request.ValidUntil)
+
+	// Decided requests cannot be decided again
+	err = decideOverrideRequest(request, OverrideDecision{ApproverID: "dr.ng", ApproverRole: "medical_director"}, false, decisionTime)
+	assert.Equal(t, OverrideErrorNotPending, overrideErrorCode(t, err))
+
+	// Requesters cannot decide their own request, whatever their role
+	err = decideOverrideRequest(pending(models.GovernanceMandatoryEscalation),
+		OverrideDecision{ApproverID: "DR.KHAN", ApproverRole: "medical_director"}, true, decisionTime)
+	assert.Equal(t, OverrideErrorSelfDecision, overrideErrorCode(t, err))
+
+	// Denial needs a reason
+	err = decideOverrideRequest(pending(models.GovernanceHardBlockOverride),
+		OverrideDecision{ApproverID: "pt.committee", ApproverRole: "p_and_t_committee"}, false, decisionTime)
+	assert.Equal(t, OverrideErrorInvalid, overrideErrorCode(t, err))
+
+	request = pending(models.GovernanceHardBlockOverride)
+	require.NoError(t, decideOverrideRequest(request,
+		OverrideDecision{ApproverID: "pt.committee", ApproverRole: "p_and_t_committee", Notes: "Use apixaban instead"}, false, decisionTime))
+	assert.Equal(t, models.OverrideStatusDenied, request.Status)
+	assert.Nil(t, request.ValidUntil)
+
+	err = decideOverrideRequest(pending(models.GovernanceMandatoryEscalation),
+		OverrideDecision{ApproverID: "rph.ortiz", ApproverRole: "senior_pharmacist", ValidityHours: 200}, true, decisionTime)
+	assert.Equal(t, OverrideErrorInvalid, overrideErrorCode(t, err))
+}
+
+func TestOverrideRequest_AutomaticExpiry(t *testing.T) {
+	request, err := newOverrideRequest(testOverrideSubmission(models.GovernanceMandatoryEscalation), overrideTestNow)
+	require.NoError(t, err)
+
+	// Pending requests expire at their decision deadline
+	assert.Equal(t, models.OverrideStatusPending, request.StatusAt(overrideTestNow.Add(23*time.Hour)))
+	assert.Equal(t, models.OverrideStatusExpired, request.StatusAt(overrideTestNow.Add(overrideDecisionWindow)))
+	err = decideOverrideRequest(request, OverrideDecision{ApproverID: "rph.ortiz", ApproverRole: "senior_pharmacist"}, true,
+		overrideTestNow.Add(25*time.Hour))
+	assert.Equal(t, OverrideErrorNotPending, overrideErrorCode(t, err))
+
+	// Approvals expire at the end of their validity
+	require.NoError(t, decideOverrideRequest(request, OverrideDecision{ApproverID: "rph.ortiz", ApproverRole: "senior_pharmacist"}, true, overrideTestNow))
+	assert.True(t, request.ValidAt(overrideTestNow.Add(23*time.Hour)))
+	assert.False(t, request.ValidAt(overrideTestNow.Add(overrideDefaultValidity)))
+	assert.Equal(t, models.OverrideStatusExpired, request.StatusAt(overrideTestNow.Add(overrideDefaultValidity)))
+}
+
+func TestApplyApprovedOverrides(t *testing.T) {
+	validFrom, validUntil := overrideTestNow.Add(-time.Hour), overrideTestNow.Add(time.Hour)
+	escalationOverride := models.GovernanceOverrideRequest{
+		ID: uuid.New(), InstitutionID: "general-hospital", Drug1Code: "1191", Drug2Code: "RxCUI:11289", // Stored in the other order
+		GovernanceAction: models.GovernanceMandatoryEscalation, Status: models.OverrideStatusApproved,
+		ApproverID: "rph.ortiz", ValidFrom: &validFrom, ValidUntil: &validUntil,
+	}
+	governed := func(drug1, drug2 string, action models.GovernanceAction) models.GovernedInteractionResult {
+		return models.GovernedInteractionResult{
+			EnhancedInteractionResult: models.EnhancedInteractionResult{
+				Drug1: models.DrugInfo{Code: drug1}, Drug2: models.DrugInfo{Code: drug2}, Severity: models.SeverityMajor,
+			},
+			GovernanceAction:   action,
+			RequiresOverride:   true,
+			EscalationRequired: action == models.GovernanceMandatoryEscalation,
+		}
+	}
+
+	results := []models.GovernedInteractionResult{
+		governed("RxCUI:11289", "RxCUI:1191", models.GovernanceMandatoryEscalation),
+		governed("RxCUI:11289", "RxCUI:1191", models.GovernanceHardBlockOverride), // Stricter than approved
+		governed("RxCUI:11289", "RxCUI:5640", models.GovernanceMandatoryEscalation),
+	}
+	applied := applyApprovedOverrides(results, []models.GovernanceOverrideRequest{escalationOverride}, "general-hospital", overrideTestNow)
+	assert.Equal(t, 1, applied)
+	assert.True(t, results[0].Attribution.InstitutionalOverride)
+	assert.Equal(t, escalationOverride.ID.String(), results[0].Attribution.OverrideReference)
+	assert.Equal(t, "rph.ortiz", results[0].Attribution.OverrideApprover)
+	assert.False(t, results[0].EscalationRequired)
+	assert.False(t, results[1].Attribution.InstitutionalOverride)
+	assert.False(t, results[2].Attribution.InstitutionalOverride)
+
+	// The summary no longer counts the lifted escalation
+	summary := (&GovernancePolicyEngine{}).BuildGovernedSummary(results[:1])
+	assert.Equal(t, 1, summary.OverriddenCount)
+	assert.Equal(t, 0, summary.EscalationRequiredCount)
+	assert.Equal(t, models.GovernanceIgnore, summary.HighestGovernanceAction)
+
+	// Expired overrides are not honoured
+	results = []models.GovernedInteractionResult{governed("RxCUI:11289", "RxCUI:1191", models.GovernanceMandatoryEscalation)}
+	assert.Zero(t, applyApprovedOverrides(results, []models.GovernanceOverrideRequest{escalationOverride}, "general-hospital", validUntil))
+
+	// An override approved at one institution does not apply at another
+	assert.Zero(t, applyApprovedOverrides(results, []models.GovernanceOverrideRequest{escalationOverride}, "community-clinic", overrideTestNow))
+	assert.Zero(t, applyApprovedOverrides(results, []models.GovernanceOverrideRequest{escalationOverride}, "", overrideTestNow))
+	assert.False(t, results[0].Attribution.InstitutionalOverride)
+}
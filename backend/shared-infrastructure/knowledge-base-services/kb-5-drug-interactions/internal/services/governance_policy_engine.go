@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -29,8 +30,9 @@ type GovernancePolicyEngine struct {
 	logger          *zap.Logger
 	metrics         *metrics.Collector
 
-	// Cached policy
-	policyCache     map[string]*models.SeverityGovernanceMapping
+	// Cached approved policy versions; the one applied is chosen per request
+	policies        []models.SeverityGovernanceMapping
+	policiesLoaded  time.Time
 	defaultPolicy   *models.SeverityGovernanceMapping
 	cacheMutex      sync.RWMutex
 
@@ -47,7 +49,6 @@ func NewGovernancePolicyEngine(db *gorm.DB, logger *zap.Logger, metricsCollector
 		db:             db,
 		logger:         logger,
 		metrics:        metricsCollector,
-		policyCache:    make(map[string]*models.SeverityGovernanceMapping),
 		defaultPolicy:  models.DefaultGovernancePolicy(),
 		serviceVersion: "2.0.0",
 		apiVersion:     "v1",
@@ -61,39 +62,83 @@ func NewGovernancePolicyEngine(db *gorm.DB, logger *zap.Logger, metricsCollector
 	return engine
 }
 
-// loadPolicies loads governance policies from database into cache
+// governancePolicyRefreshInterval bounds how long a policy approved on another
+// instance takes to apply here. Activation and expiry of cached versions need
+// no refresh; they are evaluated against the request time.
+const governancePolicyRefreshInterval = 5 * time.Minute
+
+// loadPolicies loads approved governance policy versions from database into cache.
+// On failure the previously loaded versions (or the built-in default) stay in use.
 func (gpe *GovernancePolicyEngine) loadPolicies() {
 	gpe.cacheMutex.Lock()
 	defer gpe.cacheMutex.Unlock()
 
+	gpe.policiesLoaded = time.Now()
+
 	var policies []models.SeverityGovernanceMapping
-	if err := gpe.db.Where("active = ?", true).Find(&policies).Error; err != nil {
+	if err := gpe.db.Where("status = ? AND active = ?", models.PolicyStatusApproved, true).
+		Order("policy_name, version").Find(&policies).Error; err != nil {
 		gpe.logger.Warn("Failed to load governance policies, using defaults", zap.Error(err))
 		return
 	}
-
-	for _, policy := range policies {
-		p := policy // Create copy for pointer
-		gpe.policyCache[policy.InstitutionID] = &p
-		if policy.PolicyName == "default_clinical_safety" {
-			gpe.defaultPolicy = &p
-		}
-	}
+	gpe.policies = policies
 
 	gpe.logger.Info("Loaded governance policies", zap.Int("count", len(policies)))
 }
 
-// GetPolicy retrieves the governance policy for an institution
+// GetPolicy retrieves the governance policy version in effect now for an institution
 func (gpe *GovernancePolicyEngine) GetPolicy(institutionID string) *models.SeverityGovernanceMapping {
+	return gpe.PolicyAt(institutionID, time.Now())
+}
+
+// PolicyAt retrieves the governance policy version in effect at a time for an
+// institution, falling back to the global policy and then the built-in default
+func (gpe *GovernancePolicyEngine) PolicyAt(institutionID string, at time.Time) *models.SeverityGovernanceMapping {
+	gpe.cacheMutex.RLock()
+	stale := gpe.db != nil && time.Since(gpe.policiesLoaded) > governancePolicyRefreshInterval
+	gpe.cacheMutex.RUnlock()
+	if stale {
+		gpe.loadPolicies()
+	}
+
 	gpe.cacheMutex.RLock()
 	defer gpe.cacheMutex.RUnlock()
 
-	if policy, ok := gpe.policyCache[institutionID]; ok {
+	if policy := selectGovernancePolicy(gpe.policies, institutionID, at); policy != nil {
 		return policy
 	}
 	return gpe.defaultPolicy
 }
 
+// selectGovernancePolicy picks the version in effect at a time: the institution's
+// own versions first, then global ones (institution ''). Among versions in
+// effect, the latest effective date wins, then the higher version number.
+func selectGovernancePolicy(policies []models.SeverityGovernanceMapping, institutionID string, at time.Time) *models.SeverityGovernanceMapping {
+	scopes := []string{institutionID}
+	if institutionID != "" {
+		scopes = append(scopes, "")
+	}
+
+	for _, scope := range scopes {
+		var selected *models.SeverityGovernanceMapping
+		for i := range policies {
+			policy := &policies[i]
+			if policy.InstitutionID != scope || !policy.InEffectAt(at) {
+				continue
+			}
+			if selected == nil || policy.EffectiveDate.After(selected.EffectiveDate) ||
+				(policy.EffectiveDate.Equal(selected.EffectiveDate) && policy.Version > selected.Version) {
+				selected = policy
+			}
+		}
+		if selected != nil {
+			p := *selected // Copy so callers never share the cache entry
+			return &p
+		}
+	}
+	return nil
+}
+
 // ============================================================================
 // SEVERITY → GOVERNANCE TRANSLATION
 // ============================================================================
@@ -104,7 +149,16 @@ func (gpe *GovernancePolicyEngine) TranslateSeverity(
 	institutionID string,
 	patientContext *models.PatientContextData,
 ) models.GovernanceAction {
-	policy := gpe.GetPolicy(institutionID)
+	return gpe.TranslateSeverityWithPolicy(severity, gpe.GetPolicy(institutionID), patientContext)
+}
+
+// TranslateSeverityWithPolicy converts clinical severity to governance action
+// under a policy version already resolved for the request
+func (gpe *GovernancePolicyEngine) TranslateSeverityWithPolicy(
+	severity models.DDISeverity,
+	policy *models.SeverityGovernanceMapping,
+	patientContext *models.PatientContextData,
+) models.GovernanceAction {
 	baseAction := policy.MapSeverityToAction(severity)
 
 	// Apply context-based escalation
@@ -277,9 +331,25 @@ func (gpe *GovernancePolicyEngine) EnhanceWithGovernance(
 	interaction models.EnhancedInteractionResult,
 	institutionID string,
 	patientContext *models.PatientContextData,
+) models.GovernedInteractionResult {
+	return gpe.EnhanceWithPolicy(interaction, gpe.GetPolicy(institutionID), patientContext)
+}
+
+// EnhanceWithPolicy adds governance and attribution to interaction results under
+// a policy version already resolved for the request, so every result in a
+// response is governed by the same version
+func (gpe *GovernancePolicyEngine) EnhanceWithPolicy(
+	interaction models.EnhancedInteractionResult,
+	policy *models.SeverityGovernanceMapping,
+	patientContext *models.PatientContextData,
 ) models.GovernedInteractionResult {
 	// Translate severity to governance action
-	governanceAction := gpe.TranslateSeverity(interaction.Severity, institutionID, patientContext)
+	governanceAction := gpe.TranslateSeverityWithPolicy(interaction.Severity, policy, patientContext)
+
+	// Findings such as PGx safety checks carry a minimum action that policy cannot lower
+	if floor := models.GovernanceAction(interaction.Qualifiers["governance_action"]); floor.Priority() > governanceAction.Priority() {
+		governanceAction = floor
+	}
 
 	// Detect program flags based on interaction
 	programFlags := gpe.detectProgramFlags(interaction, patientContext)
@@ -293,6 +363,7 @@ func (gpe *GovernancePolicyEngine) EnhanceWithGovernance(
 		governanceAction,
 		programFlags,
 	)
+	attribution.PolicyReference = policy.VersionID()
 
 	return models.GovernedInteractionResult{
 		EnhancedInteractionResult: interaction,
@@ -339,6 +410,12 @@ func (gpe *GovernancePolicyEngine) detectProgramFlags(
 		if isHepaticImpaired(ctx.HepaticStage) {
 			flags = append(flags, models.ProgramFlagHepaticDosing)
 		}
+		if ctx.Reproductive.IsPregnant() {
+			flags = append(flags, models.ProgramFlagPregnancy)
+		}
+		if ctx.Reproductive.IsLactating() {
+			flags = append(flags, models.ProgramFlagLactation)
+		}
 	}
 
 	return flags
@@ -366,7 +443,7 @@ func (gpe *GovernancePolicyEngine) BuildResponseAttribution(
 	interactions []models.GovernedInteractionResult,
 	enginesUsed []string,
 	processingTimeMs float64,
-	institutionID string,
+	policy *models.SeverityGovernanceMapping,
 ) models.ResponseAttribution {
 	// Collect unique evidence sources
 	sourceSet := make(map[models.ClinicalSource]bool)
@@ -396,9 +473,6 @@ func (gpe *GovernancePolicyEngine) BuildResponseAttribution(
 		avgConfidence = &avg
 	}
 
-	// Get policy info
-	policy := gpe.GetPolicy(institutionID)
-
 	return models.ResponseAttribution{
 		ServiceName:         "kb-5-drug-interactions",
 		ServiceVersion:      gpe.serviceVersion,
@@ -412,6 +486,7 @@ func (gpe *GovernancePolicyEngine) BuildResponseAttribution(
 		EvidenceSources:     sources,
 		AverageConfidence:   avgConfidence,
 		GovernancePolicy:    policy.PolicyName,
+		PolicyVersionID:     policy.VersionID(),
 		PolicyEffectiveDate: policy.EffectiveDate,
 		AuditTrailID:        uuid.New().String(),
 	}
@@ -439,13 +514,19 @@ func (gpe *GovernancePolicyEngine) BuildGovernedSummary(
 		// Count severities
 		summary.SeverityCounts[string(i.Severity)]++
 
+		// An approved override request lifts the block or escalation
+		overridden := i.Attribution.InstitutionalOverride
+		if overridden {
+			summary.OverriddenCount++
+		}
+
 		// Track governance action counts
-		if i.GovernanceAction.IsBlocking() {
+		if i.GovernanceAction.IsBlocking() && !overridden {
 			summary.HardBlockCount++
 			blockingReasons = append(blockingReasons, fmt.Sprintf("%s + %s: %s",
 				i.Drug1.Name, i.Drug2.Name, i.ActionDescription))
 		}
-		if i.GovernanceAction.RequiresAcknowledgment() {
+		if i.GovernanceAction.RequiresAcknowledgment() && !overridden {
 			summary.RequiresAcknowledgment++
 		}
 		if i.EscalationRequired {
@@ -456,7 +537,7 @@ func (gpe *GovernancePolicyEngine) BuildGovernedSummary(
 		}
 
 		// Track highest action
-		if i.GovernanceAction.Priority() > highestAction.Priority() {
+		if !overridden && i.GovernanceAction.Priority() > highestAction.Priority() {
 			highestAction = i.GovernanceAction
 		}
 
@@ -507,13 +588,13 @@ func isRenalImpaired(stage string) bool {
 		"CKD_5":  true,
 		"ESRD":   true,
 	}
-	return impaired[stage]
+	return impaired[strings.ToUpper(strings.TrimSpace(stage))]
 }
 
 func isHepaticImpaired(stage string) bool {
 	impaired := map[string]bool{
-		"ChildPugh_B": true,
-		"ChildPugh_C": true,
+		"CHILDPUGH_B": true,
+		"CHILDPUGH_C": true,
 	}
-	return impaired[stage]
+	return impaired[strings.ToUpper(strings.TrimSpace(stage))]
 }
@@ -0,0 +1,530 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// GOVERNANCE POLICY VERSIONS
+// Draft → approved → retired workflow for severity-to-governance policies.
+// Each change is a new version of a named policy; approved versions are never
+// modified and apply from their effective date until their expiry date, a
+// later version, or retirement.
+// User identities are the authenticated caller's, supplied by the API layer.
+// ============================================================================
+
+// Governance policy workflow error codes
+const (
+	PolicyErrorNotFound     = "POLICY_NOT_FOUND"
+	PolicyErrorImmutable    = "POLICY_IMMUTABLE"
+	PolicyErrorSelfApproval = "SELF_APPROVAL"
+	PolicyErrorNotAuthor    = "NOT_POLICY_AUTHOR"
+	PolicyErrorInvalid      = "INVALID_POLICY"
+	PolicyErrorNotApproved  = "POLICY_NOT_APPROVED"
+	PolicyErrorRetired      = "POLICY_RETIRED"
+)
+
+// policyDraftAttempts bounds retries when a concurrent draft takes the same version number
+const policyDraftAttempts = 3
+
+// GovernancePolicyError reports a policy change the workflow does not allow
+type GovernancePolicyError struct {
+	Code   string
+	Reason string
+}
+
+func (e *GovernancePolicyError) Error() string {
+	return e.Reason
+}
+
+// GovernancePolicySettings are the editable fields of a policy version.
+// Context escalations default to enabled when omitted.
+type GovernancePolicySettings struct {
+	ContraindicatedAction models.GovernanceAction `json:"contraindicated_action" binding:"required"`
+	MajorAction           models.GovernanceAction `json:"major_action" binding:"required"`
+	ModerateAction        models.GovernanceAction `json:"moderate_action" binding:"required"`
+	MinorAction           models.GovernanceAction `json:"minor_action" binding:"required"`
+	UnknownAction         models.GovernanceAction `json:"unknown_action" binding:"required"`
+
+	PediatricEscalation      *bool `json:"pediatric_escalation,omitempty"`
+	GeriatricEscalation      *bool `json:"geriatric_escalation,omitempty"`
+	RenalImpairmentUpgrade   *bool `json:"renal_impairment_upgrade,omitempty"`
+	HepaticImpairmentUpgrade *bool `json:"hepatic_impairment_upgrade,omitempty"`
+
+	// Defaults to the time of approval; a date already passed at approval
+	// becomes the approval time
+	EffectiveDate *time.Time `json:"effective_date,omitempty"`
+	ExpiryDate    *time.Time `json:"expiry_date,omitempty"`
+	ChangeSummary string     `json:"change_summary" binding:"required"`
+}
+
+// GovernancePolicyDraft creates a new draft version of a named policy
+type GovernancePolicyDraft struct {
+	PolicyName    string `json:"policy_name" binding:"required"`
+	InstitutionID string `json:"institution_id,omitempty"` // '' for the global policy
+	CreatedBy     string `json:"-"`                        // The caller
+	GovernancePolicySettings
+}
+
+// GovernancePolicyDraftUpdate replaces the settings of a draft; only its author may edit it
+type GovernancePolicyDraftUpdate struct {
+	UpdatedBy string `json:"-"` // The caller
+	GovernancePolicySettings
+}
+
+// GovernancePolicyVersionView is a stored version with its lifecycle state
+type GovernancePolicyVersionView struct {
+	models.SeverityGovernanceMapping
+	PolicyVersionID string `json:"policy_version_id"`
+	LifecycleState  string `json:"lifecycle_state"`
+	Applied         bool   `json:"applied"` // Currently applied to its institution
+}
+
+// ListPolicyVersions returns stored policy versions, newest first per policy.
+// Empty filters match every policy and institution.
+func (gpe *GovernancePolicyEngine) ListPolicyVersions(ctx context.Context, policyName, institutionID string) ([]GovernancePolicyVersionView, error) {
+	query := gpe.db.WithContext(ctx)
+	if policyName != "" {
+		query = query.Where("policy_name = ?", policyName)
+	}
+	if institutionID != "" {
+		query = query.Where("institution_id = ?", institutionID)
+	}
+
+	var policies []models.SeverityGovernanceMapping
+	if err := query.Order("policy_name, version DESC").Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to load governance policies: %w", err)
+	}
+
+	now := time.Now()
+	views := make([]GovernancePolicyVersionView, 0, len(policies))
+	for _, policy := range policies {
+		views = append(views, gpe.policyVersionView(policy, now))
+	}
+	return views, nil
+}
+
+// GetPolicyVersion returns one stored policy version, or nil if it does not exist
+func (gpe *GovernancePolicyEngine) GetPolicyVersion(ctx context.Context, id uuid.UUID) (*GovernancePolicyVersionView, error) {
+	policy, err := gpe.findPolicyVersion(ctx, id)
+	if err != nil || policy == nil {
+		return nil, err
+	}
+	view := gpe.policyVersionView(*policy, time.Now())
+	return &view, nil
+}
+
+// CreatePolicyDraft stores a new draft version of a policy. The version
+// number follows the policy's latest version, and the draft records the latest
+// approved version it supersedes. The policy's versions are locked while the
+// number is chosen; a concurrent first version of a new policy is retried.
+func (gpe *GovernancePolicyEngine) CreatePolicyDraft(ctx context.Context, draft GovernancePolicyDraft) (*GovernancePolicyVersionView, error) {
+	var created *models.SeverityGovernanceMapping
+	var err error
+	for attempt := 0; attempt < policyDraftAttempts; attempt++ {
+		created, err = gpe.createPolicyDraft(ctx, draft)
+		if !isPolicyVersionConflict(err) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gpe.metrics.RecordGovernancePolicyChange("draft_created")
+	view := gpe.policyVersionView(*created, time.Now())
+	return &view, nil
+}
+
+// createPolicyDraft numbers and stores a draft in one transaction
+func (gpe *GovernancePolicyEngine) createPolicyDraft(ctx context.Context, draft GovernancePolicyDraft) (*models.SeverityGovernanceMapping, error) {
+	var created *models.SeverityGovernanceMapping
+	err := gpe.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []models.SeverityGovernanceMapping
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("policy_name = ?", strings.TrimSpace(draft.PolicyName)).Find(&existing).Error; err != nil {
+			return fmt.Errorf("failed to load policy versions: %w", err)
+		}
+
+		policy, err := newGovernancePolicyVersion(draft, existing, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(policy).Error; err != nil {
+			return fmt.Errorf("failed to create policy draft: %w", err)
+		}
+		created = policy
+		return nil
+	})
+	return created, err
+}
+
+// UpdatePolicyDraft replaces the settings of a draft version
+func (gpe *GovernancePolicyEngine) UpdatePolicyDraft(ctx context.Context, id uuid.UUID, update GovernancePolicyDraftUpdate) (*GovernancePolicyVersionView, error) {
+	policy, err := gpe.findDraft(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := requirePolicyAuthor(policy, update.UpdatedBy, "edit"); err != nil {
+		return nil, err
+	}
+	if err := applyGovernancePolicySettings(policy, update.GovernancePolicySettings, time.Now()); err != nil {
+		return nil, err
+	}
+
+	result := gpe.db.WithContext(ctx).Model(&models.SeverityGovernanceMapping{}).
+		Where("id = ? AND status = ?", id, models.PolicyStatusDraft).
+		Updates(policySettingsColumns(policy))
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update policy draft: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, policyImmutableError(policy)
+	}
+
+	gpe.metrics.RecordGovernancePolicyChange("draft_updated")
+	view := gpe.policyVersionView(*policy, time.Now())
+	return &view, nil
+}
+
+// DeletePolicyDraft discards a draft version; only its author may discard it
+func (gpe *GovernancePolicyEngine) DeletePolicyDraft(ctx context.Context, id uuid.UUID, deletedBy string) error {
+	policy, err := gpe.findDraft(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := requirePolicyAuthor(policy, deletedBy, "discard"); err != nil {
+		return err
+	}
+
+	result := gpe.db.WithContext(ctx).
+		Where("id = ? AND status = ?", id, models.PolicyStatusDraft).
+		Delete(&models.SeverityGovernanceMapping{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete policy draft: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return policyImmutableError(policy)
+	}
+
+	gpe.metrics.RecordGovernancePolicyChange("draft_deleted")
+	return nil
+}
+
+// ApprovePolicy approves a draft version. The approver must not be its author.
+// The version applies from its effective date, or from approval if that date
+// has passed, and the cache is reloaded so this instance applies it at once.
+func (gpe *GovernancePolicyEngine) ApprovePolicy(ctx context.Context, id uuid.UUID, approvedBy string) (*GovernancePolicyVersionView, error) {
+	policy, err := gpe.findDraft(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := approveGovernancePolicyVersion(policy, approvedBy, time.Now()); err != nil {
+		return nil, err
+	}
+
+	result := gpe.db.WithContext(ctx).Model(&models.SeverityGovernanceMapping{}).
+		Where("id = ? AND status = ?", id, models.PolicyStatusDraft).
+		Updates(map[string]interface{}{
+			"status":         policy.Status,
+			"approved_by":    policy.ApprovedBy,
+			"approved_at":    policy.ApprovedAt,
+			"effective_date": policy.EffectiveDate,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to approve policy: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, policyImmutableError(policy)
+	}
+
+	gpe.logger.Info("Approved governance policy version",
+		zap.String("policy_name", policy.PolicyName),
+		zap.Int("version", policy.Version),
+		zap.String("approved_by", policy.ApprovedBy),
+		zap.Time("effective_date", policy.EffectiveDate))
+	gpe.metrics.RecordGovernancePolicyChange("approved")
+	gpe.loadPolicies()
+
+	view := gpe.policyVersionView(*policy, time.Now())
+	return &view, nil
+}
+
+// RetirePolicy deactivates an approved version so it no longer applies; the
+// institution falls back to its previous approved version, or the global
+// policy. Retirement is the one change the immutability trigger allows, so who
+// retired the version is logged rather than stored.
+func (gpe *GovernancePolicyEngine) RetirePolicy(ctx context.Context, id uuid.UUID, retiredBy string) (*GovernancePolicyVersionView, error) {
+	policy, err := gpe.findPolicyVersion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, &GovernancePolicyError{Code: PolicyErrorNotFound, Reason: fmt.Sprintf("governance policy version %s not found", id)}
+	}
+	if err := retireGovernancePolicyVersion(policy, retiredBy); err != nil {
+		return nil, err
+	}
+
+	result := gpe.db.WithContext(ctx).Model(&models.SeverityGovernanceMapping{}).
+		Where("id = ? AND status = ? AND active", id, models.PolicyStatusApproved).
+		Update("active", false)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retire policy: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, policyRetiredError(policy)
+	}
+
+	gpe.logger.Info("Retired governance policy version",
+		zap.String("policy_name", policy.PolicyName),
+		zap.Int("version", policy.Version),
+		zap.String("retired_by", strings.TrimSpace(retiredBy)))
+	gpe.metrics.RecordGovernancePolicyChange("retired")
+	gpe.loadPolicies()
+
+	view := gpe.policyVersionView(*policy, time.Now())
+	return &view, nil
+}
+
+// findPolicyVersion loads a stored version, returning nil if it does not exist
+func (gpe *GovernancePolicyEngine) findPolicyVersion(ctx context.Context, id uuid.UUID) (*models.SeverityGovernanceMapping, error) {
+	var policy models.SeverityGovernanceMapping
+	err := gpe.db.WithContext(ctx).Where("id = ?", id).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load governance policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// findDraft loads a version that must exist and still be a draft
+func (gpe *GovernancePolicyEngine) findDraft(ctx context.Context, id uuid.UUID) (*models.SeverityGovernanceMapping, error) {
+	policy, err := gpe.findPolicyVersion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, &GovernancePolicyError{Code: PolicyErrorNotFound, Reason: fmt.Sprintf("governance policy version %s not found", id)}
+	}
+	if policy.Status != models.PolicyStatusDraft {
+		return nil, policyImmutableError(policy)
+	}
+	return policy, nil
+}
+
+func (gpe *GovernancePolicyEngine) policyVersionView(policy models.SeverityGovernanceMapping, now time.Time) GovernancePolicyVersionView {
+	return GovernancePolicyVersionView{
+		SeverityGovernanceMapping: policy,
+		PolicyVersionID:           policy.VersionID(),
+		LifecycleState:            policy.LifecycleState(now),
+		Applied:                   policy.Status == models.PolicyStatusApproved && gpe.PolicyAt(policy.InstitutionID, now).ID == policy.ID,
+	}
+}
+
+// newGovernancePolicyVersion builds a draft from a request and the policy's
+// existing versions. All versions of a policy belong to one institution.
+func newGovernancePolicyVersion(draft GovernancePolicyDraft, existing []models.SeverityGovernanceMapping, now time.Time) (*models.SeverityGovernanceMapping, error) {
+	policy := &models.SeverityGovernanceMapping{
+		PolicyName:    strings.TrimSpace(draft.PolicyName),
+		InstitutionID: strings.TrimSpace(draft.InstitutionID),
+		Version:       1,
+		Status:        models.PolicyStatusDraft,
+		Active:        true,
+		CreatedBy:     strings.TrimSpace(draft.CreatedBy),
+	}
+	if policy.PolicyName == "" {
+		return nil, &GovernancePolicyError{Code: PolicyErrorInvalid, Reason: "policy_name is required"}
+	}
+	if policy.CreatedBy == "" {
+		return nil, &GovernancePolicyError{Code: PolicyErrorInvalid, Reason: "created_by is required"}
+	}
+
+	var superseded *models.SeverityGovernanceMapping
+	for i := range existing {
+		version := &existing[i]
+		if version.InstitutionID != policy.InstitutionID {
+			return nil, &GovernancePolicyError{Code: PolicyErrorInvalid,
+				Reason: fmt.Sprintf("policy %s belongs to institution %q", policy.PolicyName, version.InstitutionID)}
+		}
+		if version.Version >= policy.Version {
+			policy.Version = version.Version + 1
+		}
+		if version.Status == models.PolicyStatusApproved && (superseded == nil || version.Version > superseded.Version) {
+			superseded = version
+		}
+	}
+	if superseded != nil {
+		policy.SupersedesID = &superseded.ID
+	}
+
+	if err := applyGovernancePolicySettings(policy, draft.GovernancePolicySettings, now); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// applyGovernancePolicySettings validates settings and copies them onto a draft
+func applyGovernancePolicySettings(policy *models.SeverityGovernanceMapping, settings GovernancePolicySettings, now time.Time) error {
+	actions := []struct {
+		field  string
+		action models.GovernanceAction
+	}{
+		{"contraindicated_action", settings.ContraindicatedAction},
+		{"major_action", settings.MajorAction},
+		{"moderate_action", settings.ModerateAction},
+		{"minor_action", settings.MinorAction},
+		{"unknown_action", settings.UnknownAction},
+	}
+	for _, a := range actions {
+		if !isGovernanceAction(a.action) {
+			return &GovernancePolicyError{Code: PolicyErrorInvalid, Reason: fmt.Sprintf("%s %q is not a governance action", a.field, a.action)}
+		}
+	}
+	if strings.TrimSpace(settings.ChangeSummary) == "" {
+		return &GovernancePolicyError{Code: PolicyErrorInvalid, Reason: "change_summary is required"}
+	}
+
+	policy.ContraindicatedAction = settings.ContraindicatedAction
+	policy.MajorAction = settings.MajorAction
+	policy.ModerateAction = settings.ModerateAction
+	policy.MinorAction = settings.MinorAction
+	policy.UnknownAction = settings.UnknownAction
+	policy.PediatricEscalation = boolOrTrue(settings.PediatricEscalation)
+	policy.GeriatricEscalation = boolOrTrue(settings.GeriatricEscalation)
+	policy.RenalImpairmentUpgrade = boolOrTrue(settings.RenalImpairmentUpgrade)
+	policy.HepaticImpairmentUpgrade = boolOrTrue(settings.HepaticImpairmentUpgrade)
+	policy.ChangeSummary = strings.TrimSpace(settings.ChangeSummary)
+
+	// Without an effective date the draft takes effect on approval, as any
+	// date already passed does
+	policy.EffectiveDate = now
+	if settings.EffectiveDate != nil {
+		policy.EffectiveDate = *settings.EffectiveDate
+	}
+	policy.ExpiryDate = settings.ExpiryDate
+	if policy.ExpiryDate != nil && !policy.ExpiryDate.After(policy.EffectiveDate) {
+		return &GovernancePolicyError{Code: PolicyErrorInvalid, Reason: "expiry_date must be after effective_date"}
+	}
+	return nil
+}
+
+// approveGovernancePolicyVersion approves a draft at now, moving an effective
+// date that has passed to the approval time
+func approveGovernancePolicyVersion(policy *models.SeverityGovernanceMapping, approvedBy string, now time.Time) error {
+	if policy.Status != models.PolicyStatusDraft {
+		return policyImmutableError(policy)
+	}
+	approvedBy = strings.TrimSpace(approvedBy)
+	if approvedBy == "" {
+		return &GovernancePolicyError{Code: PolicyErrorInvalid, Reason: "approved_by is required"}
+	}
+	if samePolicyUser(approvedBy, policy.CreatedBy) {
+		return &GovernancePolicyError{Code: PolicyErrorSelfApproval,
+			Reason: fmt.Sprintf("%s authored this draft; approval requires a different approver", policy.CreatedBy)}
+	}
+
+	if policy.EffectiveDate.Before(now) {
+		policy.EffectiveDate = now
+	}
+	if policy.ExpiryDate != nil && !policy.ExpiryDate.After(policy.EffectiveDate) {
+		return &GovernancePolicyError{Code: PolicyErrorInvalid,
+			Reason: fmt.Sprintf("expiry_date %s has passed; update the draft before approving", policy.ExpiryDate.Format(time.RFC3339))}
+	}
+
+	policy.Status = models.PolicyStatusApproved
+	policy.ApprovedBy = approvedBy
+	policy.ApprovedAt = &now
+	return nil
+}
+
+// retireGovernancePolicyVersion deactivates an approved, active version
+func retireGovernancePolicyVersion(policy *models.SeverityGovernanceMapping, retiredBy string) error {
+	if strings.TrimSpace(retiredBy) == "" {
+		return &GovernancePolicyError{Code: PolicyErrorInvalid, Reason: "retired_by is required"}
+	}
+	if policy.Status != models.PolicyStatusApproved {
+		return &GovernancePolicyError{Code: PolicyErrorNotApproved,
+			Reason: fmt.Sprintf("%s version %d is a draft; discard it instead of retiring it", policy.PolicyName, policy.Version)}
+	}
+	if !policy.Active {
+		return policyRetiredError(policy)
+	}
+	policy.Active = false
+	return nil
+}
+
+// policySettingsColumns maps a draft's editable fields to columns
+func policySettingsColumns(policy *models.SeverityGovernanceMapping) map[string]interface{} {
+	return map[string]interface{}{
+		"contraindicated_action":     policy.ContraindicatedAction,
+		"major_action":               policy.MajorAction,
+		"moderate_action":            policy.ModerateAction,
+		"minor_action":               policy.MinorAction,
+		"unknown_action":             policy.UnknownAction,
+		"pediatric_escalation":       policy.PediatricEscalation,
+		"geriatric_escalation":       policy.GeriatricEscalation,
+		"renal_impairment_upgrade":   policy.RenalImpairmentUpgrade,
+		"hepatic_impairment_upgrade": policy.HepaticImpairmentUpgrade,
+		"effective_date":             policy.EffectiveDate,
+		"expiry_date":                policy.ExpiryDate,
+		"change_summary":             policy.ChangeSummary,
+	}
+}
+
+// requirePolicyAuthor rejects a change to a draft by anyone but its author
+func requirePolicyAuthor(policy *models.SeverityGovernanceMapping, user, action string) error {
+	if samePolicyUser(user, policy.CreatedBy) {
+		return nil
+	}
+	return &GovernancePolicyError{Code: PolicyErrorNotAuthor,
+		Reason: fmt.Sprintf("only the author (%s) may %s this draft; create a new draft instead", policy.CreatedBy, action)}
+}
+
+// isPolicyVersionConflict reports whether another draft took the same version number
+func isPolicyVersionConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_governance_policy_version"
+}
+
+func policyImmutableError(policy *models.SeverityGovernanceMapping) error {
+	return &GovernancePolicyError{Code: PolicyErrorImmutable,
+		Reason: fmt.Sprintf("%s version %d is approved and cannot be changed; create a new draft version", policy.PolicyName, policy.Version)}
+}
+
+func policyRetiredError(policy *models.SeverityGovernanceMapping) error {
+	return &GovernancePolicyError{Code: PolicyErrorRetired,
+		Reason: fmt.Sprintf("%s version %d is already retired", policy.PolicyName, policy.Version)}
+}
+
+func isGovernanceAction(action models.GovernanceAction) bool {
+	switch action {
+	case models.GovernanceIgnore, models.GovernanceNotify, models.GovernanceWarnAcknowledge,
+		models.GovernanceMandatoryEscalation, models.GovernanceHardBlockOverride, models.GovernanceHardBlock:
+		return true
+	}
+	return false
+}
+
+// samePolicyUser compares user identifiers ignoring case and surrounding space
+func samePolicyUser(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+func boolOrTrue(value *bool) bool {
+	return value == nil || *value
+}
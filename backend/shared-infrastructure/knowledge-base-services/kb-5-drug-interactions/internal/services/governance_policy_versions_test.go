@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+var policyTestNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func approvedPolicyVersion(name, institutionID string, version int, effective time.Time, expiry *time.Time) models.SeverityGovernanceMapping {
+	policy := models.DefaultGovernancePolicy()
+	policy.ID = uuid.New()
+	policy.PolicyName = name
+	policy.InstitutionID = institutionID
+	policy.Version = version
+	policy.EffectiveDate = effective
+	policy.ExpiryDate = expiry
+	policy.CreatedBy = "author"
+	policy.ApprovedBy = "approver"
+	return *policy
+}
+
+func testPolicySettings() GovernancePolicySettings {
+	return GovernancePolicySettings{
+		ContraindicatedAction: models.GovernanceHardBlock,
+		MajorAction:           models.GovernanceMandatoryEscalation,
+		ModerateAction:        models.GovernanceNotify,
+		MinorAction:           models.GovernanceIgnore,
+		UnknownAction:         models.GovernanceNotify,
+		ChangeSummary:         "Escalate major interactions",
+	}
+}
+
+func TestSelectGovernancePolicy_EffectiveAndExpiryDates(t *testing.T) {
+	expiry := policyTestNow.Add(24 * time.Hour)
+	v1 := approvedPolicyVersion("global", "", 1, policyTestNow.AddDate(-1, 0, 0), nil)
+	v2 := approvedPolicyVersion("global", "", 2, policyTestNow.AddDate(0, -1, 0), &expiry)
+	v3 := approvedPolicyVersion("global", "", 3, policyTestNow.Add(7*24*time.Hour), nil)
+	policies := []models.SeverityGovernanceMapping{v1, v2, v3}
+
+	// v2 is the latest effective version; v3 is scheduled
+	assert.Equal(t, v2.ID, selectGovernancePolicy(policies, "", policyTestNow).ID)
+
+	// After v2 expires, v1 applies again until v3 takes effect
+	assert.Equal(t, v1.ID, selectGovernancePolicy(policies, "", expiry).ID)
+	assert.Equal(t, v3.ID, selectGovernancePolicy(policies, "", v3.EffectiveDate).ID)
+
+	// Nothing in effect before the first version
+	assert.Nil(t, selectGovernancePolicy(policies, "", v1.EffectiveDate.Add(-time.Second)))
+}
+
+func TestSelectGovernancePolicy_InstitutionFallback(t *testing.T) {
+	global := approvedPolicyVersion("global", "", 1, policyTestNow.AddDate(-1, 0, 0), nil)
+	local := approvedPolicyVersion("site_a_policy", "site-a", 1, policyTestNow.AddDate(0, 0, 1), nil)
+	draft := approvedPolicyVersion("site_b_policy", "site-b", 1, policyTestNow.AddDate(0, 0, -1), nil)
+	draft.Status = models.PolicyStatusDraft
+	policies := []models.SeverityGovernanceMapping{global, local, draft}
+
+	assert.Equal(t, global.ID, selectGovernancePolicy(policies, "site-a", policyTestNow).ID, "site policy not yet effective")
+	assert.Equal(t, local.ID, selectGovernancePolicy(policies, "site-a", policyTestNow.AddDate(0, 0, 2)).ID)
+	assert.Equal(t, global.ID, selectGovernancePolicy(policies, "site-b", policyTestNow).ID, "drafts never apply")
+
+	// The engine falls back to the built-in default when nothing applies
+	gpe := &GovernancePolicyEngine{defaultPolicy: models.DefaultGovernancePolicy()}
+	policy := gpe.PolicyAt("site-a", policyTestNow)
+	assert.Equal(t, "builtin:default_clinical_safety", policy.VersionID())
+}
+
+func TestEnhanceWithPolicy_ReportsVersionID(t *testing.T) {
+	policy := approvedPolicyVersion("global", "", 4, policyTestNow.AddDate(0, -1, 0), nil)
+	gpe := &GovernancePolicyEngine{defaultPolicy: models.DefaultGovernancePolicy()}
+
+	governed := gpe.EnhanceWithPolicy(models.EnhancedInteractionResult{Severity: models.SeverityMajor}, &policy, nil)
+	assert.Equal(t, policy.ID.String(), governed.Attribution.PolicyReference)
+
+	attribution := gpe.BuildResponseAttribution([]models.GovernedInteractionResult{governed}, []string{"core_ddi"}, 1, &policy)
+	assert.Equal(t, policy.ID.String(), attribution.PolicyVersionID)
+	assert.Equal(t, "4", policy.VersionLabel())
+}
+
+func TestNewGovernancePolicyVersion(t *testing.T) {
+	v1 := approvedPolicyVersion("site_a_policy", "site-a", 1, policyTestNow.AddDate(-1, 0, 0), nil)
+	v2 := approvedPolicyVersion("site_a_policy", "site-a", 2, policyTestNow.AddDate(0, -1, 0), nil)
+	pending := approvedPolicyVersion("site_a_policy", "site-a", 3, policyTestNow, nil)
+	pending.Status = models.PolicyStatusDraft
+
+	draft := GovernancePolicyDraft{PolicyName: "site_a_policy", InstitutionID: "site-a", CreatedBy: "dr.lee",
+		GovernancePolicySettings: testPolicySettings()}
+	policy, err := newGovernancePolicyVersion(draft, []models.SeverityGovernanceMapping{v1, v2, pending}, policyTestNow)
+	require.NoError(t, err)
+	assert.Equal(t, 4, policy.Version)
+	assert.Equal(t, models.PolicyStatusDraft, policy.Status)
+	assert.Equal(t, v2.ID, *policy.SupersedesID, "supersedes the latest approved version")
+	assert.Equal(t, policyTestNow, policy.EffectiveDate)
+	assert.True(t, policy.PediatricEscalation, "escalations default to enabled")
+
+	// All versions of a policy belong to one institution
+	draft.InstitutionID = "site-b"
+	_, err = newGovernancePolicyVersion(draft, []models.SeverityGovernanceMapping{v1}, policyTestNow)
+	var policyErr *GovernancePolicyError
+	require.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, PolicyErrorInvalid, policyErr.Code)
+
+	// Unknown actions and expiry before effect are rejected
+	draft.InstitutionID = "site-a"
+	draft.MajorAction = "block_everything"
+	_, err = newGovernancePolicyVersion(draft, nil, policyTestNow)
+	require.True(t, errors.As(err, &policyErr))
+
+	draft.GovernancePolicySettings = testPolicySettings()
+	expiry := policyTestNow.Add(-time.Hour)
+	draft.ExpiryDate = &expiry
+	_, err = newGovernancePolicyVersion(draft, nil, policyTestNow)
+	require.True(t, errors.As(err, &policyErr))
+	assert.Contains(t, policyErr.Reason, "expiry_date")
+}
+
+func TestApproveGovernancePolicyVersion(t *testing.T) {
+	newDraft := func(effective time.Time) *models.SeverityGovernanceMapping {
+		policy := approvedPolicyVersion("global", "", 2, effective, nil)
+		policy.Status = models.PolicyStatusDraft
+		policy.CreatedBy = "Dr.Lee"
+		policy.ApprovedBy = ""
+		return &policy
+	}
+	var policyErr *GovernancePolicyError
+
+	// The author cannot approve their own draft
+	err := approveGovernancePolicyVersion(newDraft(policyTestNow), " dr.lee ", policyTestNow)
+	require.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, PolicyErrorSelfApproval, policyErr.Code)
+
+	// A passed effective date moves to the approval time; a future one is kept
+	policy := newDraft(policyTestNow.AddDate(0, 0, -3))
+	require.NoError(t, approveGovernancePolicyVersion(policy, "pharmacy.chief", policyTestNow))
+	assert.Equal(t, models.PolicyStatusApproved, policy.Status)
+	assert.Equal(t, policyTestNow, policy.EffectiveDate)
+	assert.Equal(t, policyTestNow, *policy.ApprovedAt)
+	assert.Equal(t, models.PolicyStateInEffect, policy.LifecycleState(policyTestNow))
+
+	future := policyTestNow.AddDate(0, 1, 0)
+	policy = newDraft(future)
+	require.NoError(t, approveGovernancePolicyVersion(policy, "pharmacy.chief", policyTestNow))
+	assert.Equal(t, future, policy.EffectiveDate)
+	assert.Equal(t, models.PolicyStateScheduled, policy.LifecycleState(policyTestNow))
+
+	// Approved versions are immutable
+	err = approveGovernancePolicyVersion(policy, "someone.else", policyTestNow)
+	require.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, PolicyErrorImmutable, policyErr.Code)
+
+	// A draft whose expiry has passed cannot be approved
+	expired := policyTestNow.Add(-time.Hour)
+	policy = newDraft(policyTestNow.AddDate(0, 0, -3))
+	policy.ExpiryDate = &expired
+	err = approveGovernancePolicyVersion(policy, "pharmacy.chief", policyTestNow)
+	require.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, PolicyErrorInvalid, policyErr.Code)
+}
+
+func TestRetireGovernancePolicyVersion(t *testing.T) {
+	policy := approvedPolicyVersion("global", "", 2, policyTestNow.AddDate(0, 0, -3), nil)
+	var policyErr *GovernancePolicyError
+
+	// Retiring needs the caller
+	err := retireGovernancePolicyVersion(&policy, " ")
+	require.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, PolicyErrorInvalid, policyErr.Code)
+	assert.True(t, policy.Active)
+
+	require.NoError(t, retireGovernancePolicyVersion(&policy, "pharmacy.chief"))
+	assert.False(t, policy.Active)
+	assert.False(t, policy.InEffectAt(policyTestNow))
+	assert.Equal(t, models.PolicyStateRetired, policy.LifecycleState(policyTestNow))
+
+	// A retired version cannot be retired again
+	err = retireGovernancePolicyVersion(&policy, "pharmacy.chief")
+	require.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, PolicyErrorRetired, policyErr.Code)
+
+	// Drafts are discarded, not retired
+	draft := approvedPolicyVersion("global", "", 3, policyTestNow, nil)
+	draft.Status = models.PolicyStatusDraft
+	err = retireGovernancePolicyVersion(&draft, "pharmacy.chief")
+	require.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, PolicyErrorNotApproved, policyErr.Code)
+	assert.True(t, draft.Active)
+}
+
+func TestRequirePolicyAuthor(t *testing.T) {
+	draft := approvedPolicyVersion("global", "", 2, policyTestNow, nil)
+	draft.Status = models.PolicyStatusDraft
+	draft.CreatedBy = "Dr.Lee"
+
+	assert.NoError(t, requirePolicyAuthor(&draft, " dr.lee ", "discard"))
+
+	var policyErr *GovernancePolicyError
+	err := requirePolicyAuthor(&draft, "pharmacy.chief", "discard")
+	assert.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, PolicyErrorNotAuthor, policyErr.Code)
+	assert.Equal(t, "only the author (Dr.Lee) may discard this draft; create a new draft instead", policyErr.Reason)
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// Named dosing frequencies and their interval in hours
+var frequencyIntervalHours = map[string]float64{
+	"daily": 24, "oncedaily": 24, "qd": 24, "od": 24, "qday": 24, "qam": 24, "qpm": 24, "qhs": 24,
+	"bid": 12, "twicedaily": 12,
+	"tid": 8, "threetimesdaily": 8,
+	"qid": 6, "fourtimesdaily": 6,
+	"qod": 48, "everyotherday": 48,
+	"weekly": 168, "qweek": 168, "qwk": 168,
+}
+
+var intervalFrequencyPattern = regexp.MustCompile(`^(?:q|every)(\d+(?:\.\d+)?)(h|hr|hrs|hour|hours|d|day|days)$`)
+
+// parseFrequencyHours converts a dosing frequency ("q12h", "BID", "every 8 hours")
+// to an interval in hours
+func parseFrequencyHours(frequency string) (float64, bool) {
+	f := strings.ToLower(frequency)
+	f = strings.NewReplacer(" ", "", ".", "", "-", "", "_", "").Replace(f)
+	if hours, ok := frequencyIntervalHours[f]; ok {
+		return hours, true
+	}
+	if m := intervalFrequencyPattern.FindStringSubmatch(f); m != nil {
+		n, err := strconv.ParseFloat(m[1], 64)
+		if err != nil || n <= 0 {
+			return 0, false
+		}
+		if strings.HasPrefix(m[2], "d") {
+			n *= 24
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+// orderIntervalHours returns the dosing interval of an order
+func orderIntervalHours(order models.MedicationOrder) (float64, bool) {
+	if order.IntervalHours > 0 {
+		return order.IntervalHours, true
+	}
+	return parseFrequencyHours(order.Frequency)
+}
+
+// Mass units relative to mg
+var massUnitFactors = map[string]float64{
+	"mcg": 0.001, "ug": 0.001, "µg": 0.001, "microgram": 0.001, "micrograms": 0.001,
+	"mg": 1, "milligram": 1, "milligrams": 1,
+	"g": 1000, "gm": 1000, "gram": 1000, "grams": 1000,
+}
+
+// convertDose converts a dose between units. Mass units convert to one another;
+// other units must match.
+func convertDose(dose float64, from, to string) (float64, bool) {
+	from = strings.ToLower(strings.TrimSpace(from))
+	to = strings.ToLower(strings.TrimSpace(to))
+	if from == to {
+		return dose, true
+	}
+	fromFactor, fromMass := massUnitFactors[from]
+	toFactor, toMass := massUnitFactors[to]
+	if !fromMass || !toMass {
+		return 0, false
+	}
+	return dose * fromFactor / toFactor, true
+}
+
+// orderDailyDose returns the total daily dose of an order in the given unit
+func orderDailyDose(order models.MedicationOrder, unit string) (float64, bool) {
+	dose, ok := convertDose(order.Dose, order.DoseUnit, unit)
+	if !ok {
+		return 0, false
+	}
+	interval, ok := orderIntervalHours(order)
+	if !ok {
+		return 0, false
+	}
+	return dose * 24 / interval, true
+}
@@ -0,0 +1,697 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// Modifier exposure patterns (ddi_modifiers.exposure_pattern)
+const (
+	ModifierExposureIntermittent = "intermittent" // A meal or drink; the effect follows each intake
+	ModifierExposureContinuous   = "continuous"   // A product taken daily; the effect persists after stopping
+)
+
+// Modifier effect statuses at evaluation time
+const (
+	ModifierEffectActive        = "active"
+	ModifierEffectOnsetPending  = "onset_pending"  // Exposure started but the effect is still building
+	ModifierEffectResidual      = "residual"       // Exposure stopped but the effect persists
+	ModifierEffectTimingUnknown = "timing_unknown" // Intake time not given; the effect may have worn off
+)
+
+// Changes in exposure a rule fires on (ddi_modifiers.trigger_event)
+const (
+	ModifierTriggerExposure   = "exposure"   // While exposed
+	ModifierTriggerInitiation = "initiation" // Exposure started recently and continues
+	ModifierTriggerCessation  = "cessation"  // Exposure stopped recently or will stop
+)
+
+// Meal states a food rule can fire on (ddi_modifiers.food_state)
+const (
+	FoodStateFasting = "fasting"
+	FoodStateFed     = "fed"
+)
+
+const (
+	// defaultIntermittentOffsetHours is how long an intake matters when a rule gives no offset
+	defaultIntermittentOffsetHours = 24
+	// defaultMealStateHours is how long a patient counts as fed after a meal
+	defaultMealStateHours = 2
+	// defaultTransitionHours is how long a start or stop matters when a rule gives no offset
+	defaultTransitionHours = 336
+)
+
+// lifestyleQuantityConversions converts exposure quantities to the units rules use
+var lifestyleQuantityConversions = map[string]struct {
+	unit   string
+	factor float64
+}{
+	"packs/day": {"cigarettes/day", 20},
+	"cups/day":  {"mg/day", 95}, // Brewed coffee
+}
+
+// ModifierRule is a food, alcohol, herbal, supplement or lifestyle rule from ddi_modifiers
+type ModifierRule struct {
+	ModifierType       string
+	ModifierName       string
+	ModifierCode       string
+	Aliases            []string
+	FoodCategories     []string
+	FoodState          string // Fires on the meal state instead of a specific food
+	DrugCode           string
+	DrugName           string
+	Mechanism          string
+	Effect             string
+	ManagementStrategy string
+	Severity           models.DDISeverity
+	Evidence           models.EvidenceLevel
+	ExposurePattern    string
+	TriggerEvent       string
+	MinQuantity        *float64 // Lighter exposures, in QuantityUnit, do not fire
+	QuantityUnit       string
+	Routes             []string // Exposure routes the rule applies to; empty for any
+	OnsetHours         float64
+	OffsetHours        *float64
+	ConfidenceScore    decimal.Decimal
+	UpdatedAt          time.Time
+}
+
+// cachedModifierRules holds the rules loaded for one requested dataset version
+type cachedModifierRules struct {
+	rules    []ModifierRule
+	loadedAt time.Time
+}
+
+// modifierExposure is one reported intake or product matching a rule
+type modifierExposure struct {
+	source          string     // What the patient reported, e.g. "Grapefruit juice"
+	interactionType string     // food, alcohol, herbal, supplement, tobacco, cannabis, caffeine
+	start           *time.Time // Intake time or start date; nil when not given
+	stop            *time.Time // Continuous exposures: stop date; nil while taken
+	continuous      bool
+}
+
+// loadModifierRules returns the active modifier rules of a dataset version. A
+// version without modifier rules falls back to the latest version that has
+// them, so the built-in rules apply to any dataset.
+func (fahe *FoodAlcoholHerbalEngine) loadModifierRules(ctx context.Context, datasetVersion string) ([]ModifierRule, error) {
+	fahe.mu.Lock()
+	defer fahe.mu.Unlock()
+
+	if cached, exists := fahe.ruleCache[datasetVersion]; exists && time.Since(cached.loadedAt) < fahe.cacheTTL {
+		return cached.rules, nil
+	}
+
+	query := `
+		SELECT
+			dm.modifier_type,
+			dm.modifier_name,
+			COALESCE(dm.modifier_code, ''),
+			COALESCE(dm.modifier_aliases, '{}'),
+			COALESCE(dm.food_categories, '{}'),
+			COALESCE(dm.food_state, ''),
+			dm.drug_code,
+			COALESCE(dm.drug_name, ''),
+			COALESCE(dm.mechanism, ''),
+			dm.effect,
+			dm.management_strategy,
+			dm.severity,
+			dm.evidence,
+			dm.exposure_pattern,
+			dm.trigger_event,
+			dm.min_quantity,
+			COALESCE(dm.quantity_unit, ''),
+			COALESCE(dm.routes, '{}'),
+			dm.onset_hours,
+			dm.offset_hours,
+			dm.confidence_score,
+			COALESCE(dm.updated_at, dm.created_at, NOW())
+		FROM ddi_modifiers dm
+		WHERE dm.active = TRUE
+		AND dm.modifier_type <> 'disease'
+		AND dm.modifier_name IS NOT NULL
+		AND dm.dataset_version = COALESCE(
+			(SELECT dataset_version FROM ddi_modifiers
+			 WHERE dataset_version = $1 AND active = TRUE AND modifier_name IS NOT NULL LIMIT 1),
+			(SELECT MAX(dataset_version) FROM ddi_modifiers
+			 WHERE active = TRUE AND modifier_name IS NOT NULL))
+		ORDER BY dm.severity, dm.confidence_score DESC
+	`
+
+	rows, err := fahe.db.QueryContext(ctx, query, datasetVersion)
+	if err != nil {
+		return nil, fmt.Errorf("modifier rule query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []ModifierRule
+	for rows.Next() {
+		var rule ModifierRule
+		var aliases, categories, routes pq.StringArray
+		var severityStr, evidenceStr string
+		var minQuantity, offsetHours sql.NullFloat64
+
+		if err := rows.Scan(
+			&rule.ModifierType,
+			&rule.ModifierName,
+			&rule.ModifierCode,
+			&aliases,
+			&categories,
+			&rule.FoodState,
+			&rule.DrugCode,
+			&rule.DrugName,
+			&rule.Mechanism,
+			&rule.Effect,
+			&rule.ManagementStrategy,
+			&severityStr,
+			&evidenceStr,
+			&rule.ExposurePattern,
+			&rule.TriggerEvent,
+			&minQuantity,
+			&rule.QuantityUnit,
+			&routes,
+			&rule.OnsetHours,
+			&offsetHours,
+			&rule.ConfidenceScore,
+			&rule.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("modifier rule scan failed: %w", err)
+		}
+
+		rule.Aliases = aliases
+		rule.FoodCategories = categories
+		rule.Routes = routes
+		if minQuantity.Valid {
+			quantity := minQuantity.Float64
+			rule.MinQuantity = &quantity
+		}
+		rule.Severity = models.DDISeverity(severityStr)
+		rule.Evidence = models.EvidenceLevel(evidenceStr)
+		if offsetHours.Valid {
+			offset := offsetHours.Float64
+			rule.OffsetHours = &offset
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("modifier rule iteration failed: %w", err)
+	}
+
+	fahe.ruleCache[datasetVersion] = cachedModifierRules{rules: rules, loadedAt: time.Now()}
+	return rules, nil
+}
+
+// evaluateModifierRules matches rules against the regimen and the patient's
+// reported meals, alcohol and products, keeping those whose effect window
+// covers now
+func evaluateModifierRules(
+	rules []ModifierRule,
+	drugCodes []string,
+	modifierContext ModifierContext,
+	now time.Time,
+) []ModifierInteractionResult {
+	var results []ModifierInteractionResult
+
+	for _, rule := range rules {
+		for _, drugCode := range drugCodes {
+			if !modifierRuleMatchesDrug(rule, drugCode) {
+				continue
+			}
+
+			if rule.FoodState != "" {
+				if result, ok := evaluateFoodStateRule(rule, drugCode, modifierContext, now); ok {
+					results = append(results, result)
+				}
+				continue
+			}
+
+			var best *modifierWindow
+			for _, exposure := range modifierExposures(rule, modifierContext) {
+				window, ok := modifierEffectWindow(rule, exposure, now)
+				if !ok {
+					continue
+				}
+				if best == nil || window.outlasts(*best) {
+					w := window
+					best = &w
+				}
+			}
+			if best == nil {
+				continue
+			}
+
+			result := newModifierResult(rule, drugCode, best.exposure.interactionType)
+			result.EffectStatus = best.status
+			result.EffectStartsAt = best.startsAt
+			result.EffectEndsAt = best.endsAt
+			if rule.TriggerEvent == ModifierTriggerInitiation || rule.TriggerEvent == ModifierTriggerCessation {
+				result.TriggerEvent = rule.TriggerEvent
+			}
+			result.TimingGuidance = describeModifierTiming(rule, *best)
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// modifierRuleMatchesDrug reports whether a rule covers a drug, by code or, for
+// callers that pass drug names, by name
+func modifierRuleMatchesDrug(rule ModifierRule, drugCode string) bool {
+	if normalizeATCDrugKey(rule.DrugCode) == normalizeATCDrugKey(drugCode) {
+		return true
+	}
+	return rule.DrugName != "" && strings.EqualFold(strings.TrimSpace(drugCode), rule.DrugName)
+}
+
+// modifierExposures collects the patient's reported intakes and products that
+// match a rule
+func modifierExposures(rule ModifierRule, modifierContext ModifierContext) []modifierExposure {
+	var exposures []modifierExposure
+
+	switch rule.ModifierType {
+	case "food":
+		for _, meal := range modifierContext.RecentMeals {
+			if !foodMatchesRule(rule, meal) {
+				continue
+			}
+			exposures = append(exposures, modifierExposure{
+				source:          meal.Name,
+				interactionType: "food",
+				start:           mealTime(meal, modifierContext),
+			})
+		}
+
+	case "alcohol":
+		alcohol := modifierContext.AlcoholIntake
+		lastDrink := alcohol.LastConsumption
+		if lastDrink == nil {
+			lastDrink = modifierContext.AlcoholLastDose
+		}
+		if alcohol.CurrentUse {
+			exposures = append(exposures, modifierExposure{
+				source: "Current alcohol use", interactionType: "alcohol", continuous: true,
+			})
+		} else if lastDrink != nil {
+			exposures = append(exposures, modifierExposure{
+				source: "Alcohol", interactionType: "alcohol", start: lastDrink,
+			})
+		}
+
+	case "herbal", "supplement":
+		for _, herbal := range modifierContext.HerbalProducts {
+			if !modifierNameMatches(rule, herbal.Name, herbal.CommonName, herbal.ScientificName) {
+				continue
+			}
+			source := herbal.Name
+			if source == "" {
+				source = herbal.CommonName
+			}
+			exposures = append(exposures, modifierExposure{
+				source:          source,
+				interactionType: "herbal",
+				start:           knownTime(herbal.StartDate),
+				stop:            herbal.StopDate,
+				continuous:      true,
+			})
+		}
+		for _, supplement := range modifierContext.Supplements {
+			if !modifierNameMatches(rule, supplement.Name) {
+				continue
+			}
+			exposures = append(exposures, modifierExposure{
+				source:          supplement.Name,
+				interactionType: "supplement",
+				start:           knownTime(supplement.StartDate),
+				stop:            supplement.StopDate,
+				continuous:      true,
+			})
+		}
+
+	case LifestyleTobacco, LifestyleCannabis, LifestyleCaffeine:
+		for _, lifestyle := range modifierContext.LifestyleExposures {
+			if !strings.EqualFold(strings.TrimSpace(lifestyle.Category), rule.ModifierType) || !lifestyleMatchesRule(rule, lifestyle) {
+				continue
+			}
+			exposures = append(exposures, modifierExposure{
+				source:          describeLifestyleExposure(lifestyle),
+				interactionType: rule.ModifierType,
+				start:           lifestyle.StartDate,
+				stop:            lifestyle.StopDate,
+				continuous:      true,
+			})
+		}
+	}
+
+	return exposures
+}
+
+// lifestyleMatchesRule applies a rule's route and minimum quantity to an
+// exposure. Exposures without a route, without a quantity or in a unit that
+// cannot be compared are not excluded.
+func lifestyleMatchesRule(rule ModifierRule, lifestyle LifestyleExposure) bool {
+	if len(rule.Routes) > 0 && lifestyle.Route != "" {
+		matched := false
+		for _, route := range rule.Routes {
+			if strings.EqualFold(route, strings.TrimSpace(lifestyle.Route)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if rule.MinQuantity != nil && lifestyle.Quantity > 0 {
+		quantity, unit := lifestyle.Quantity, strings.ToLower(strings.TrimSpace(lifestyle.QuantityUnit))
+		if conversion, exists := lifestyleQuantityConversions[unit]; exists {
+			quantity, unit = quantity*conversion.factor, conversion.unit
+		}
+		if strings.EqualFold(unit, rule.QuantityUnit) && quantity < *rule.MinQuantity {
+			return false
+		}
+	}
+	return true
+}
+
+// describeLifestyleExposure labels an exposure, e.g. "Tobacco (cigarettes, 20 cigarettes/day)"
+func describeLifestyleExposure(lifestyle LifestyleExposure) string {
+	category := strings.ToLower(strings.TrimSpace(lifestyle.Category))
+	label := strings.ToUpper(category[:1]) + category[1:]
+
+	var details []string
+	if lifestyle.Product != "" {
+		details = append(details, lifestyle.Product)
+	}
+	if lifestyle.Quantity > 0 {
+		details = append(details, strings.TrimSpace(fmt.Sprintf("%g %s", lifestyle.Quantity, lifestyle.QuantityUnit)))
+	}
+	if len(details) == 0 {
+		return label
+	}
+	return fmt.Sprintf("%s (%s)", label, strings.Join(details, ", "))
+}
+
+// foodMatchesRule reports whether a meal contains a rule's modifier by name,
+// alias or category
+func foodMatchesRule(rule ModifierRule, meal FoodItem) bool {
+	if modifierNameMatches(rule, meal.Name) {
+		return true
+	}
+	for _, category := range rule.FoodCategories {
+		if strings.EqualFold(category, meal.Category) {
+			return true
+		}
+		if meal.GrapefruitContent && strings.EqualFold(category, "grapefruit") {
+			return true
+		}
+	}
+	return false
+}
+
+// modifierNameMatches reports whether any reported name contains the rule's
+// modifier name or an alias
+func modifierNameMatches(rule ModifierRule, names ...string) bool {
+	for _, name := range names {
+		normalized := normalizeModifierName(name)
+		if normalized == "" {
+			continue
+		}
+		for _, candidate := range append([]string{rule.ModifierName}, rule.Aliases...) {
+			candidate = normalizeModifierName(candidate)
+			if candidate != "" && strings.Contains(normalized, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeModifierName lower-cases a product name and drops punctuation, so
+// "St. John's Wort" matches "st johns wort"
+func normalizeModifierName(name string) string {
+	name = strings.NewReplacer("'", "", "’", "", ".", "", "-", " ", "_", " ").Replace(strings.ToLower(name))
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// mealTime returns when a meal was eaten, falling back to the last meal time
+func mealTime(meal FoodItem, modifierContext ModifierContext) *time.Time {
+	if t := knownTime(meal.ConsumedAt); t != nil {
+		return t
+	}
+	return modifierContext.LastMealTime
+}
+
+// knownTime returns nil for an unset time
+func knownTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// modifierWindow is when an exposure's effect applies
+type modifierWindow struct {
+	exposure modifierExposure
+	status   string
+	startsAt *time.Time // Known when the exposure start is known
+	endsAt   *time.Time // nil while a continuous exposure is taken, or when the intake time is unknown
+}
+
+// outlasts reports whether a window's effect ends later than another's; an
+// open-ended window outlasts any other, and a timed window outlasts one whose
+// timing is unknown
+func (w modifierWindow) outlasts(other modifierWindow) bool {
+	switch {
+	case w.status == ModifierEffectTimingUnknown:
+		return false
+	case other.status == ModifierEffectTimingUnknown:
+		return true
+	case other.endsAt == nil:
+		return false
+	case w.endsAt == nil:
+		return true
+	}
+	return w.endsAt.After(*other.endsAt)
+}
+
+// modifierEffectWindow places an exposure's effect in time: it starts
+// onset_hours after the intake or start date and ends offset_hours after the
+// intake or stop date. Initiation and cessation rules instead measure both from
+// the start or stop. Exposures whose effect has ended are not relevant, and an
+// intake without a time cannot be placed, so its status is timing_unknown.
+func modifierEffectWindow(rule ModifierRule, exposure modifierExposure, now time.Time) (modifierWindow, bool) {
+	window := modifierWindow{exposure: exposure, status: ModifierEffectActive}
+
+	switch rule.TriggerEvent {
+	case ModifierTriggerInitiation, ModifierTriggerCessation:
+		change := exposure.start
+		if rule.TriggerEvent == ModifierTriggerCessation {
+			change = exposure.stop
+		} else if exposure.stop != nil && !now.Before(*exposure.stop) {
+			return window, false // Stopped since; cessation rules take over
+		}
+		if change == nil {
+			return window, false
+		}
+		startsAt := change.Add(hoursDuration(rule.OnsetHours))
+		endsAt := change.Add(hoursDuration(rule.offsetHours(defaultTransitionHours)))
+		window.startsAt, window.endsAt = &startsAt, &endsAt
+		switch {
+		case !now.Before(endsAt):
+			return window, false
+		case now.Before(startsAt):
+			window.status = ModifierEffectOnsetPending
+		}
+		return window, true
+	}
+
+	if exposure.start != nil {
+		startsAt := exposure.start.Add(hoursDuration(rule.OnsetHours))
+		window.startsAt = &startsAt
+	}
+
+	if !exposure.continuous {
+		if exposure.start == nil {
+			window.status = ModifierEffectTimingUnknown
+			return window, true
+		}
+		endsAt := exposure.start.Add(hoursDuration(rule.offsetHours(defaultIntermittentOffsetHours)))
+		window.endsAt = &endsAt
+	} else if exposure.stop != nil {
+		endsAt := exposure.stop.Add(hoursDuration(rule.offsetHours(0)))
+		window.endsAt = &endsAt
+	}
+
+	switch {
+	case window.endsAt != nil && !now.Before(*window.endsAt):
+		return window, false
+	case window.startsAt != nil && now.Before(*window.startsAt):
+		window.status = ModifierEffectOnsetPending
+	case exposure.continuous && exposure.stop != nil && now.After(*exposure.stop):
+		window.status = ModifierEffectResidual
+	}
+	return window, true
+}
+
+// offsetHours returns the rule's offset or a default when it has none
+func (r ModifierRule) offsetHours(fallback float64) float64 {
+	if r.OffsetHours != nil {
+		return *r.OffsetHours
+	}
+	return fallback
+}
+
+// evaluateFoodStateRule fires a meal-state rule: 'fed' rules within the rule's
+// window after the last meal, 'fasting' rules outside it. Without meal times
+// the reported fasting status decides.
+func evaluateFoodStateRule(rule ModifierRule, drugCode string, modifierContext ModifierContext, now time.Time) (ModifierInteractionResult, bool) {
+	hours := rule.offsetHours(defaultMealStateHours)
+
+	var lastMeal *time.Time
+	for _, candidate := range append([]*time.Time{modifierContext.LastMealTime}, mealTimes(modifierContext)...) {
+		if candidate != nil && !candidate.After(now) && (lastMeal == nil || candidate.After(*lastMeal)) {
+			lastMeal = candidate
+		}
+	}
+
+	fed := !modifierContext.FastingStatus
+	var fedUntil *time.Time
+	if lastMeal != nil && !modifierContext.FastingStatus {
+		until := lastMeal.Add(hoursDuration(hours))
+		fed = now.Before(until)
+		fedUntil = &until
+	}
+	if fed != (rule.FoodState == FoodStateFed) {
+		return ModifierInteractionResult{}, false
+	}
+
+	result := newModifierResult(rule, drugCode, "food")
+	result.EffectStatus = ModifierEffectActive
+	switch {
+	case fed && fedUntil != nil:
+		result.EffectStartsAt = lastMeal
+		result.EffectEndsAt = fedUntil
+		result.TimingGuidance = fmt.Sprintf("Last meal %s; fed state until %s (%s after eating)",
+			formatModifierTime(*lastMeal), formatModifierTime(*fedUntil), formatModifierDuration(hours))
+	case fed:
+		result.TimingGuidance = "Patient is not fasting"
+	case lastMeal != nil && !modifierContext.FastingStatus:
+		result.TimingGuidance = fmt.Sprintf("Last meal %s; fasting since %s",
+			formatModifierTime(*lastMeal), formatModifierTime(*fedUntil))
+	default:
+		result.TimingGuidance = "Patient is fasting"
+	}
+	return result, true
+}
+
+// mealTimes returns the known intake times of the reported meals
+func mealTimes(modifierContext ModifierContext) []*time.Time {
+	var times []*time.Time
+	for _, meal := range modifierContext.RecentMeals {
+		times = append(times, knownTime(meal.ConsumedAt))
+	}
+	return times
+}
+
+// newModifierResult builds the finding for a rule and drug, without timing
+func newModifierResult(rule ModifierRule, drugCode, interactionType string) ModifierInteractionResult {
+	return ModifierInteractionResult{
+		InteractionType: interactionType,
+		ModifierName:    rule.ModifierName,
+		AffectedDrugs:   []string{drugCode},
+		Mechanism:       rule.Mechanism,
+		ClinicalEffect:  rule.Effect,
+		Severity:        rule.Severity,
+		Evidence:        rule.Evidence,
+		Recommendation:  rule.ManagementStrategy,
+		ConfidenceScore: rule.ConfidenceScore,
+		LastUpdated:     rule.UpdatedAt,
+	}
+}
+
+// describeModifierTiming says when the exposure began to matter and when it
+// will stop mattering
+func describeModifierTiming(rule ModifierRule, window modifierWindow) string {
+	exposure := window.exposure
+	onset := formatModifierDuration(rule.OnsetHours)
+
+	switch rule.TriggerEvent {
+	case ModifierTriggerInitiation:
+		return fmt.Sprintf("%s started %s; drug levels may change until %s",
+			exposure.source, formatModifierTime(*exposure.start), formatModifierTime(*window.endsAt))
+	case ModifierTriggerCessation:
+		if window.status == ModifierEffectOnsetPending {
+			return fmt.Sprintf("%s stops %s; drug levels expected to change from %s until %s",
+				exposure.source, formatModifierTime(*exposure.stop), formatModifierTime(*window.startsAt),
+				formatModifierTime(*window.endsAt))
+		}
+		return fmt.Sprintf("%s stopped %s; drug levels may change until %s",
+			exposure.source, formatModifierTime(*exposure.stop), formatModifierTime(*window.endsAt))
+	}
+
+	if !exposure.continuous {
+		offset := formatModifierDuration(rule.offsetHours(defaultIntermittentOffsetHours))
+		switch {
+		case exposure.start == nil:
+			return fmt.Sprintf("%s, time not given; effect lasts %s after intake and may have worn off", exposure.source, offset)
+		case window.status == ModifierEffectOnsetPending:
+			return fmt.Sprintf("%s at %s; effect expected from %s, lasting until %s",
+				exposure.source, formatModifierTime(*exposure.start), formatModifierTime(*window.startsAt),
+				formatModifierTime(*window.endsAt))
+		}
+		return fmt.Sprintf("%s at %s; effect lasts until %s (%s after intake)",
+			exposure.source, formatModifierTime(*exposure.start), formatModifierTime(*window.endsAt), offset)
+	}
+
+	afterStopping := "effect ends on stopping"
+	if hours := rule.offsetHours(0); hours > 0 {
+		afterStopping = fmt.Sprintf("effect persists %s after stopping", formatModifierDuration(hours))
+	}
+
+	switch {
+	case window.status == ModifierEffectResidual:
+		return fmt.Sprintf("%s stopped %s; effect persists until %s",
+			exposure.source, formatModifierTime(*exposure.stop), formatModifierTime(*window.endsAt))
+	case exposure.stop != nil:
+		prefix := fmt.Sprintf("%s until %s", exposure.source, formatModifierTime(*exposure.stop))
+		if window.status == ModifierEffectOnsetPending {
+			prefix += fmt.Sprintf("; effect expected from %s", formatModifierTime(*window.startsAt))
+		}
+		return fmt.Sprintf("%s; effect lasts until %s", prefix, formatModifierTime(*window.endsAt))
+	case window.status == ModifierEffectOnsetPending:
+		return fmt.Sprintf("%s since %s; effect expected from %s (%s after starting); %s",
+			exposure.source, formatModifierTime(*exposure.start), formatModifierTime(*window.startsAt), onset, afterStopping)
+	case exposure.start != nil:
+		return fmt.Sprintf("%s since %s; %s", exposure.source, formatModifierTime(*exposure.start), afterStopping)
+	}
+	return fmt.Sprintf("%s ongoing; %s", exposure.source, afterStopping)
+}
+
+// hoursDuration converts fractional hours to a duration
+func hoursDuration(hours float64) time.Duration {
+	return time.Duration(hours * float64(time.Hour))
+}
+
+// formatModifierTime formats a time in UTC, e.g. "2025-03-04 08:00 UTC"
+func formatModifierTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+// formatModifierDuration formats hours, using days from a week upward, e.g. "72 h", "14 days"
+func formatModifierDuration(hours float64) string {
+	if hours >= 168 && math.Mod(hours, 24) == 0 {
+		return fmt.Sprintf("%g days", hours/24)
+	}
+	return formatHours(hours) + " h"
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// TIME-AWARE MODIFIER RULE TESTS
+// ============================================================================
+
+var modifierTestNow = time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+func TestEvaluateModifierRules_GrapefruitWindow(t *testing.T) {
+	rules := []ModifierRule{{
+		ModifierType: "food", ModifierName: "grapefruit", Aliases: []string{"pomelo"}, FoodCategories: []string{"grapefruit"},
+		DrugCode: "RxCUI:36567", DrugName: "simvastatin", Effect: "Increased statin levels → severe myopathy risk",
+		Severity: models.SeverityMajor, Evidence: models.EvidenceLevelA, ExposurePattern: ModifierExposureIntermittent,
+		OffsetHours: floatPtr(72),
+	}}
+	meal := FoodItem{Name: "Grapefruit juice", ConsumedAt: modifierTestNow.Add(-24 * time.Hour)}
+
+	results := evaluateModifierRules(rules, []string{"rxcui:36567"}, ModifierContext{RecentMeals: []FoodItem{meal}}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "food", results[0].InteractionType)
+	assert.Equal(t, ModifierEffectActive, results[0].EffectStatus)
+	assert.Equal(t, time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC), *results[0].EffectEndsAt)
+	assert.Equal(t, "Grapefruit juice at 2025-03-09 12:00 UTC; effect lasts until 2025-03-12 12:00 UTC (72 h after intake)",
+		results[0].TimingGuidance)
+
+	// Drug names still match; the grapefruit flag counts as the grapefruit category
+	flagged := FoodItem{Name: "Breakfast smoothie", GrapefruitContent: true, ConsumedAt: modifierTestNow.Add(-2 * time.Hour)}
+	results = evaluateModifierRules(rules, []string{"Simvastatin"}, ModifierContext{RecentMeals: []FoodItem{flagged}}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, []string{"Simvastatin"}, results[0].AffectedDrugs)
+
+	// Inhibition has worn off 72 h after intake
+	meal.ConsumedAt = modifierTestNow.Add(-80 * time.Hour)
+	assert.Empty(t, evaluateModifierRules(rules, []string{"RxCUI:36567"}, ModifierContext{RecentMeals: []FoodItem{meal}}, modifierTestNow))
+
+	// A meal without its own time uses the last meal time
+	lastMeal := modifierTestNow.Add(-90 * time.Hour)
+	meal.ConsumedAt = time.Time{}
+	assert.Empty(t, evaluateModifierRules(rules, []string{"RxCUI:36567"},
+		ModifierContext{RecentMeals: []FoodItem{meal}, LastMealTime: &lastMeal}, modifierTestNow))
+
+	// With no time at all the effect cannot be placed
+	results = evaluateModifierRules(rules, []string{"RxCUI:36567"}, ModifierContext{RecentMeals: []FoodItem{meal}}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, ModifierEffectTimingUnknown, results[0].EffectStatus)
+	assert.Nil(t, results[0].EffectEndsAt)
+	assert.Equal(t, "Grapefruit juice, time not given; effect lasts 72 h after intake and may have worn off", results[0].TimingGuidance)
+
+	// A timed intake is reported over an untimed one
+	recent := FoodItem{Name: "Pomelo", ConsumedAt: modifierTestNow.Add(-2 * time.Hour)}
+	results = evaluateModifierRules(rules, []string{"RxCUI:36567"}, ModifierContext{RecentMeals: []FoodItem{meal, recent}}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, ModifierEffectActive, results[0].EffectStatus)
+	assert.Equal(t, time.Date(2025, 3, 13, 10, 0, 0, 0, time.UTC), *results[0].EffectEndsAt)
+}
+
+func TestEvaluateModifierRules_StJohnsWortInductionWindow(t *testing.T) {
+	rules := []ModifierRule{{
+		ModifierType: "herbal", ModifierName: "st johns wort", Aliases: []string{"hypericum"},
+		DrugCode: "RxCUI:11289", DrugName: "warfarin", Effect: "Reduced anticoagulation → thrombosis risk",
+		Severity: models.SeverityMajor, Evidence: models.EvidenceLevelA, ExposurePattern: ModifierExposureContinuous,
+		OnsetHours: 240, OffsetHours: floatPtr(336),
+	}}
+	herbal := HerbalItem{Name: "St. John's Wort", StartDate: modifierTestNow.Add(-72 * time.Hour)}
+
+	// Induction is still building three days in
+	results := evaluateModifierRules(rules, []string{"RxCUI:11289"}, ModifierContext{HerbalProducts: []HerbalItem{herbal}}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, ModifierEffectOnsetPending, results[0].EffectStatus)
+	assert.Equal(t, time.Date(2025, 3, 17, 12, 0, 0, 0, time.UTC), *results[0].EffectStartsAt)
+	assert.Nil(t, results[0].EffectEndsAt)
+	assert.Equal(t, "St. John's Wort since 2025-03-07 12:00 UTC; effect expected from 2025-03-17 12:00 UTC "+
+		"(10 days after starting); effect persists 14 days after stopping", results[0].TimingGuidance)
+
+	// Stopped five days ago: induction persists for two weeks after stopping
+	herbal.StartDate = modifierTestNow.AddDate(0, 0, -40)
+	stopped := modifierTestNow.AddDate(0, 0, -5)
+	herbal.StopDate = &stopped
+	results = evaluateModifierRules(rules, []string{"RxCUI:11289"}, ModifierContext{HerbalProducts: []HerbalItem{herbal}}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, ModifierEffectResidual, results[0].EffectStatus)
+	assert.Equal(t, time.Date(2025, 3, 19, 12, 0, 0, 0, time.UTC), *results[0].EffectEndsAt)
+	assert.Equal(t, "St. John's Wort stopped 2025-03-05 12:00 UTC; effect persists until 2025-03-19 12:00 UTC",
+		results[0].TimingGuidance)
+
+	stopped = modifierTestNow.AddDate(0, 0, -20)
+	assert.Empty(t, evaluateModifierRules(rules, []string{"RxCUI:11289"}, ModifierContext{HerbalProducts: []HerbalItem{herbal}}, modifierTestNow))
+
+	// Supplements match too
+	results = evaluateModifierRules(rules, []string{"RxCUI:11289"},
+		ModifierContext{Supplements: []Supplement{{Name: "Hypericum perforatum extract"}}}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "supplement", results[0].InteractionType)
+	assert.Equal(t, "Hypericum perforatum extract ongoing; effect persists 14 days after stopping", results[0].TimingGuidance)
+}
+
+func TestEvaluateModifierRules_Alcohol(t *testing.T) {
+	rules := []ModifierRule{{
+		ModifierType: "alcohol", ModifierName: "alcohol", DrugCode: "RxCUI:4493",
+		Severity: models.SeverityMajor, Evidence: models.EvidenceLevelA, ExposurePattern: ModifierExposureIntermittent,
+		OffsetHours: floatPtr(24),
+	}}
+
+	lastDrink := modifierTestNow.Add(-30 * time.Hour)
+	assert.Empty(t, evaluateModifierRules(rules, []string{"RxCUI:4493"},
+		ModifierContext{AlcoholIntake: AlcoholHistory{LastConsumption: &lastDrink}}, modifierTestNow))
+
+	lastDrink = modifierTestNow.Add(-6 * time.Hour)
+	results := evaluateModifierRules(rules, []string{"RxCUI:4493"}, ModifierContext{AlcoholLastDose: &lastDrink}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, time.Date(2025, 3, 11, 6, 0, 0, 0, time.UTC), *results[0].EffectEndsAt)
+
+	results = evaluateModifierRules(rules, []string{"RxCUI:4493"},
+		ModifierContext{AlcoholIntake: AlcoholHistory{CurrentUse: true}}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "Current alcohol use ongoing; effect persists 24 h after stopping", results[0].TimingGuidance)
+}
+
+func TestEvaluateModifierRules_MealState(t *testing.T) {
+	rules := []ModifierRule{{
+		ModifierType: "food", ModifierName: "food", FoodState: FoodStateFed, DrugCode: "RxCUI:10582",
+		Severity: models.SeverityModerate, Evidence: models.EvidenceLevelB, OffsetHours: floatPtr(2),
+	}}
+
+	lastMeal := modifierTestNow.Add(-1 * time.Hour)
+	results := evaluateModifierRules(rules, []string{"RxCUI:10582"}, ModifierContext{LastMealTime: &lastMeal}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "Last meal 2025-03-10 11:00 UTC; fed state until 2025-03-10 13:00 UTC (2 h after eating)", results[0].TimingGuidance)
+
+	lastMeal = modifierTestNow.Add(-3 * time.Hour)
+	assert.Empty(t, evaluateModifierRules(rules, []string{"RxCUI:10582"}, ModifierContext{LastMealTime: &lastMeal}, modifierTestNow))
+	assert.Empty(t, evaluateModifierRules(rules, []string{"RxCUI:10582"}, ModifierContext{FastingStatus: true}, modifierTestNow))
+
+	// Without meal times the reported fasting status decides
+	assert.Len(t, evaluateModifierRules(rules, []string{"RxCUI:10582"}, ModifierContext{}, modifierTestNow), 1)
+}
+
+func TestNormalizeModifierName(t *testing.T) {
+	assert.Equal(t, "st johns wort", normalizeModifierName("  St. John’s-Wort "))
+	assert.Equal(t, "ginkgo biloba", normalizeModifierName("Ginkgo_Biloba"))
+	assert.Equal(t, "14 days", formatModifierDuration(336))
+	assert.Equal(t, "72 h", formatModifierDuration(72))
+}
+
+// ============================================================================
+// LIFESTYLE MODIFIER TESTS
+// ============================================================================
+
+func TestEvaluateModifierRules_TobaccoCessation(t *testing.T) {
+	rules := []ModifierRule{{
+		ModifierType: LifestyleTobacco, ModifierName: "tobacco smoke", DrugCode: "RxCUI:2626", DrugName: "clozapine",
+		Severity: models.SeverityMajor, Evidence: models.EvidenceLevelA, ExposurePattern: ModifierExposureContinuous,
+		TriggerEvent: ModifierTriggerCessation, OffsetHours: floatPtr(336),
+		MinQuantity: floatPtr(7), QuantityUnit: "cigarettes/day", Routes: []string{"smoked"},
+	}}
+	started := modifierTestNow.AddDate(-10, 0, 0)
+	stopped := modifierTestNow.AddDate(0, 0, -3)
+	smoker := LifestyleExposure{Category: LifestyleTobacco, Product: "cigarettes", Route: "smoked",
+		Quantity: 20, QuantityUnit: "cigarettes/day", StartDate: &started, StopDate: &stopped}
+
+	results := evaluateModifierRules(rules, []string{"RxCUI:2626"}, ModifierContext{LifestyleExposures: []LifestyleExposure{smoker}}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, ModifierTriggerCessation, results[0].TriggerEvent)
+	assert.Equal(t, models.SeverityMajor, results[0].Severity)
+	assert.Equal(t, ModifierEffectActive, results[0].EffectStatus)
+	assert.Equal(t, time.Date(2025, 3, 21, 12, 0, 0, 0, time.UTC), *results[0].EffectEndsAt)
+	assert.Equal(t, "Tobacco (cigarettes, 20 cigarettes/day) stopped 2025-03-07 12:00 UTC; "+
+		"drug levels may change until 2025-03-21 12:00 UTC", results[0].TimingGuidance)
+
+	// A planned stop, e.g. a smoke-free admission, is flagged ahead of time
+	stopped = modifierTestNow.AddDate(0, 0, 2)
+	results = evaluateModifierRules(rules, []string{"clozapine"}, ModifierContext{LifestyleExposures: []LifestyleExposure{smoker}}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, ModifierEffectOnsetPending, results[0].EffectStatus)
+	assert.Equal(t, "Tobacco (cigarettes, 20 cigarettes/day) stops 2025-03-12 12:00 UTC; drug levels expected "+
+		"to change from 2025-03-12 12:00 UTC until 2025-03-26 12:00 UTC", results[0].TimingGuidance)
+
+	// Long after stopping the transition is over
+	stopped = modifierTestNow.AddDate(0, 0, -30)
+	assert.Empty(t, evaluateModifierRules(rules, []string{"RxCUI:2626"}, ModifierContext{LifestyleExposures: []LifestyleExposure{smoker}}, modifierTestNow))
+
+	// Steady smoking with no recent change does not fire
+	smoker.StopDate = nil
+	assert.Empty(t, evaluateModifierRules(rules, []string{"RxCUI:2626"}, ModifierContext{LifestyleExposures: []LifestyleExposure{smoker}}, modifierTestNow))
+}
+
+func TestEvaluateModifierRules_TobaccoInitiation(t *testing.T) {
+	rules := []ModifierRule{{
+		ModifierType: LifestyleTobacco, ModifierName: "tobacco smoke", DrugCode: "RxCUI:2626", DrugName: "clozapine",
+		Severity: models.SeverityModerate, Evidence: models.EvidenceLevelA, ExposurePattern: ModifierExposureContinuous,
+		TriggerEvent: ModifierTriggerInitiation, OffsetHours: floatPtr(336),
+		MinQuantity: floatPtr(7), QuantityUnit: "cigarettes/day", Routes: []string{"smoked"},
+	}}
+	started := modifierTestNow.AddDate(0, 0, -4)
+	smoker := LifestyleExposure{Category: "Tobacco", Route: "smoked", Quantity: 1, QuantityUnit: "packs/day", StartDate: &started}
+
+	results := evaluateModifierRules(rules, []string{"RxCUI:2626"}, ModifierContext{LifestyleExposures: []LifestyleExposure{smoker}}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, ModifierTriggerInitiation, results[0].TriggerEvent)
+	assert.Equal(t, models.SeverityModerate, results[0].Severity)
+	assert.Equal(t, "Tobacco (1 packs/day) started 2025-03-06 12:00 UTC; drug levels may change until 2025-03-20 12:00 UTC",
+		results[0].TimingGuidance)
+
+	// Below the threshold once packs are converted to cigarettes
+	smoker.Quantity = 0.25
+	assert.Empty(t, evaluateModifierRules(rules, []string{"RxCUI:2626"}, ModifierContext{LifestyleExposures: []LifestyleExposure{smoker}}, modifierTestNow))
+
+	// Unknown quantity is not excluded
+	smoker.Quantity = 0
+	assert.Len(t, evaluateModifierRules(rules, []string{"RxCUI:2626"}, ModifierContext{LifestyleExposures: []LifestyleExposure{smoker}}, modifierTestNow), 1)
+
+	// Vaping does not induce CYP1A2
+	smoker.Route = "vaped"
+	assert.Empty(t, evaluateModifierRules(rules, []string{"RxCUI:2626"}, ModifierContext{LifestyleExposures: []LifestyleExposure{smoker}}, modifierTestNow))
+
+	// An unknown start cannot place the transition
+	smoker.Route, smoker.StartDate = "", nil
+	assert.Empty(t, evaluateModifierRules(rules, []string{"RxCUI:2626"}, ModifierContext{LifestyleExposures: []LifestyleExposure{smoker}}, modifierTestNow))
+}
+
+func TestEvaluateModifierRules_CaffeineExposure(t *testing.T) {
+	rules := []ModifierRule{{
+		ModifierType: LifestyleCaffeine, ModifierName: "caffeine", DrugCode: "RxCUI:42355", DrugName: "fluvoxamine",
+		Severity: models.SeverityModerate, Evidence: models.EvidenceLevelB, ExposurePattern: ModifierExposureContinuous,
+		TriggerEvent: ModifierTriggerExposure, OffsetHours: floatPtr(72),
+	}}
+	coffee := LifestyleExposure{Category: LifestyleCaffeine, Product: "coffee", Quantity: 3, QuantityUnit: "cups/day"}
+
+	results := evaluateModifierRules(rules, []string{"RxCUI:42355"}, ModifierContext{LifestyleExposures: []LifestyleExposure{coffee}}, modifierTestNow)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "caffeine", results[0].InteractionType)
+	assert.Empty(t, results[0].TriggerEvent)
+	assert.Equal(t, "Caffeine (coffee, 3 cups/day) ongoing; effect persists 72 h after stopping", results[0].TimingGuidance)
+
+	// Other categories do not match caffeine rules
+	coffee.Category = LifestyleCannabis
+	assert.Empty(t, evaluateModifierRules(rules, []string{"RxCUI:42355"}, ModifierContext{LifestyleExposures: []LifestyleExposure{coffee}}, modifierTestNow))
+}
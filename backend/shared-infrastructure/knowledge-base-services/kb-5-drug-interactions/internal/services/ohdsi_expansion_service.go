@@ -138,8 +138,17 @@ func NewOHDSIExpansionService(db *database.Database, metricsCollector *metrics.C
 	}
 }
 
+// classHierarchyRelationships are the relationships kept for class expansion
+var classHierarchyRelationships = map[string]bool{
+	"Drug has drug class": true,
+	"Is a":                true,
+	"Subsumes":            true,
+}
+
 // LoadOHDSIVocabulary loads OHDSI concept and concept_relationship data from CSV files
-func (s *OHDSIExpansionService) LoadOHDSIVocabulary(ctx context.Context, conceptFile, relationshipFile string) error {
+// and refreshes the concept ancestor closure. ancestorFile is an (optionally filtered)
+// OHDSI CONCEPT_ANCESTOR file; when empty the closure is computed from the relationships.
+func (s *OHDSIExpansionService) LoadOHDSIVocabulary(ctx context.Context, conceptFile, relationshipFile, ancestorFile string) error {
 	start := time.Now()
 
 	// Load concepts
@@ -154,12 +163,121 @@ func (s *OHDSIExpansionService) LoadOHDSIVocabulary(ctx context.Context, concept
 		return fmt.Errorf("failed to load relationships: %w", err)
 	}
 
+	// Load or compute the class closure
+	var ancestorCount int64
+	if ancestorFile != "" {
+		ancestorCount, err = s.loadConceptAncestors(ctx, ancestorFile)
+	} else {
+		ancestorCount, err = s.RebuildConceptAncestors(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to build concept ancestors: %w", err)
+	}
+
+	s.ClearCache()
+
 	duration := time.Since(start)
-	fmt.Printf("Loaded %d concepts and %d relationships in %v\n", conceptCount, relationshipCount, duration)
+	fmt.Printf("Loaded %d concepts, %d relationships and %d ancestor rows in %v\n",
+		conceptCount, relationshipCount, ancestorCount, duration)
 
 	return nil
 }
 
+// RebuildConceptAncestors recomputes the ancestor closure from the loaded relationships
+func (s *OHDSIExpansionService) RebuildConceptAncestors(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.db.DB.WithContext(ctx).Raw(`SELECT rebuild_ohdsi_concept_ancestor()`).Scan(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// loadConceptAncestors replaces the ancestor closure with rows from an OHDSI CONCEPT_ANCESTOR file.
+// The table is cleared and reloaded in one transaction, so a failed load leaves the
+// previous closure in place.
+func (s *OHDSIExpansionService) loadConceptAncestors(ctx context.Context, filePath string) (int64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("cannot open ancestor file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = '\t'
+	reader.LazyQuotes = true
+
+	// Skip header
+	_, err = reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("cannot read header: %w", err)
+	}
+
+	sqlDB, err := s.db.DB.DB()
+	if err != nil {
+		return 0, err
+	}
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `TRUNCATE TABLE ohdsi_concept_ancestor`); err != nil {
+		return 0, fmt.Errorf("cannot clear ancestor table: %w", err)
+	}
+
+	var count int64
+	batch := make([][]interface{}, 0, 1000)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+
+		if len(record) < 4 {
+			continue
+		}
+
+		ancestorID, err1 := strconv.ParseInt(record[0], 10, 64)
+		descendantID, err2 := strconv.ParseInt(record[1], 10, 64)
+		minLevels, err3 := strconv.Atoi(record[2])
+		maxLevels, err4 := strconv.Atoi(record[3])
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			continue
+		}
+
+		batch = append(batch, []interface{}{ancestorID, descendantID, minLevels, maxLevels})
+
+		if len(batch) >= 1000 {
+			inserted, err := insertAncestorBatch(ctx, tx, batch)
+			if err != nil {
+				return 0, err
+			}
+			count += inserted
+			batch = batch[:0]
+		}
+	}
+
+	// Insert remaining
+	if len(batch) > 0 {
+		inserted, err := insertAncestorBatch(ctx, tx, batch)
+		if err != nil {
+			return 0, err
+		}
+		count += inserted
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("cannot commit ancestor load: %w", err)
+	}
+	return count, nil
+}
+
 // loadConcepts loads OHDSI concepts from CSV
 func (s *OHDSIExpansionService) loadConcepts(ctx context.Context, filePath string) (int, error) {
 	file, err := os.Open(filePath)
@@ -260,8 +378,8 @@ func (s *OHDSIExpansionService) loadRelationships(ctx context.Context, filePath
 			continue
 		}
 
-		// Only load "Drug has drug class" relationships
-		if record[2] != "Drug has drug class" {
+		// Only load drug -> class and class hierarchy relationships
+		if !classHierarchyRelationships[record[2]] {
 			continue
 		}
 
@@ -346,7 +464,38 @@ func (s *OHDSIExpansionService) insertRelationshipBatch(ctx context.Context, bat
 	return nil
 }
 
-// GetClassMembers returns all drugs belonging to a class
+// insertAncestorBatch inserts closure rows within the load transaction and returns
+// the number of rows written. The first failed row aborts the batch.
+func insertAncestorBatch(ctx context.Context, tx *sql.Tx, batch [][]interface{}) (int64, error) {
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO ohdsi_concept_ancestor (ancestor_concept_id, descendant_concept_id, min_levels_of_separation, max_levels_of_separation)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (ancestor_concept_id, descendant_concept_id) DO UPDATE
+		SET min_levels_of_separation = EXCLUDED.min_levels_of_separation,
+		    max_levels_of_separation = EXCLUDED.max_levels_of_separation
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var inserted int64
+	for _, row := range batch {
+		result, err := stmt.ExecContext(ctx, row...)
+		if err != nil {
+			return 0, fmt.Errorf("cannot insert ancestor row %v -> %v: %w", row[0], row[1], err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		inserted += affected
+	}
+
+	return inserted, nil
+}
+
+// GetClassMembers returns all drugs belonging to a class, including members of its sub-classes
 func (s *OHDSIExpansionService) GetClassMembers(ctx context.Context, classConceptID int64) ([]int64, error) {
 	// Check cache first
 	s.cacheMu.RLock()
@@ -356,14 +505,13 @@ func (s *OHDSIExpansionService) GetClassMembers(ctx context.Context, classConcep
 	}
 	s.cacheMu.RUnlock()
 
-	// Query database
+	// Single indexed lookup on the ancestor closure
 	var members []int64
 	err := s.db.DB.WithContext(ctx).Raw(`
-		SELECT cr.concept_id_1
-		FROM ohdsi_concept_relationship cr
-		JOIN ohdsi_concept c ON cr.concept_id_1 = c.concept_id
-		WHERE cr.concept_id_2 = ?
-		  AND cr.relationship_id = 'Drug has drug class'
+		SELECT ca.descendant_concept_id
+		FROM ohdsi_concept_ancestor ca
+		JOIN ohdsi_concept c ON ca.descendant_concept_id = c.concept_id
+		WHERE ca.ancestor_concept_id = ?
 		  AND c.standard_concept = 'S'
 	`, classConceptID).Scan(&members).Error
 
@@ -380,82 +528,69 @@ func (s *OHDSIExpansionService) GetClassMembers(ctx context.Context, classConcep
 	return members, nil
 }
 
-// ExpandRule expands a constitutional rule to drug pairs using OHDSI
+// ExpandRule expands a constitutional rule to drug pairs using OHDSI.
+// Both classes are expanded through the ancestor closure in a single join;
+// pairs are returned in canonical order (drug A < drug B) without duplicates.
 func (s *OHDSIExpansionService) ExpandRule(ctx context.Context, rule ConstitutionalRule) ([]DDIProjection, error) {
 	timer := time.Now()
 
-	// Get drugs in trigger class
-	triggerDrugs, err := s.GetClassMembers(ctx, rule.TriggerConceptID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get trigger class members: %w", err)
+	var pairs []struct {
+		DrugAConceptID int64
+		DrugAName      string
+		DrugBConceptID int64
+		DrugBName      string
 	}
 
-	// Get drugs in target class
-	targetDrugs, err := s.GetClassMembers(ctx, rule.TargetConceptID)
+	err := s.db.DB.WithContext(ctx).Raw(`
+		SELECT DISTINCT
+		       LEAST(t.descendant_concept_id, g.descendant_concept_id) AS drug_a_concept_id,
+		       CASE WHEN t.descendant_concept_id < g.descendant_concept_id THEN tc.concept_name ELSE gc.concept_name END AS drug_a_name,
+		       GREATEST(t.descendant_concept_id, g.descendant_concept_id) AS drug_b_concept_id,
+		       CASE WHEN t.descendant_concept_id < g.descendant_concept_id THEN gc.concept_name ELSE tc.concept_name END AS drug_b_name
+		FROM ohdsi_concept_ancestor t
+		JOIN ohdsi_concept tc ON tc.concept_id = t.descendant_concept_id AND tc.standard_concept = 'S'
+		JOIN ohdsi_concept_ancestor g ON g.ancestor_concept_id = ?
+		JOIN ohdsi_concept gc ON gc.concept_id = g.descendant_concept_id AND gc.standard_concept = 'S'
+		WHERE t.ancestor_concept_id = ?
+		  AND t.descendant_concept_id <> g.descendant_concept_id
+		ORDER BY drug_a_concept_id, drug_b_concept_id
+	`, rule.TargetConceptID, rule.TriggerConceptID).Scan(&pairs).Error
 	if err != nil {
-		return nil, fmt.Errorf("failed to get target class members: %w", err)
+		return nil, fmt.Errorf("failed to expand rule %d: %w", rule.RuleID, err)
 	}
 
-	// Special handling for self-class rules (QT + QT)
-	isSelfClassRule := rule.TriggerConceptID == rule.TargetConceptID
-
-	var projections []DDIProjection
-	seen := make(map[string]bool)
-
-	for _, d1 := range triggerDrugs {
-		for _, d2 := range targetDrugs {
-			// Skip self-pairs
-			if d1 == d2 {
-				continue
-			}
-
-			// Canonical ordering to prevent (A,B) and (B,A) duplicates
-			drugA, drugB := d1, d2
-			if d1 > d2 {
-				drugA, drugB = d2, d1
-			}
-
-			// Skip if already seen
-			key := fmt.Sprintf("%d-%d-%d", rule.RuleID, drugA, drugB)
-			if seen[key] {
-				continue
-			}
-			seen[key] = true
-
-			// For self-class rules, ensure we don't double-count
-			if isSelfClassRule && d1 > d2 {
-				continue
-			}
-
-			affectedRole := ""
-			if rule.AffectedDrugRole != nil {
-				affectedRole = *rule.AffectedDrugRole
-			}
-
-			projection := DDIProjection{
-				RuleID:               rule.RuleID,
-				DrugAConceptID:       drugA,
-				DrugAClassName:       rule.TriggerClassName,
-				DrugBConceptID:       drugB,
-				DrugBClassName:       rule.TargetClassName,
-				RiskLevel:            rule.RiskLevel,
-				AlertMessage:         rule.Description,
-				RuleAuthority:        rule.RuleAuthority,
-				RuleVersion:          rule.RuleVersion,
-				PrecedenceRank:       3, // Class-Class rules
-				RequiresContext:      rule.ContextLOINCID != nil,
-				ContextLOINCID:       rule.ContextLOINCID,
-				ContextThreshold:     rule.ContextThresholdVal,
-				ContextOperator:      rule.ContextLogicOperator,
-				ContextRequired:      rule.ContextRequired,
-				EvaluationTier:       rule.EvaluationTier,
-				InteractionDirection: rule.InteractionDirection,
-				AffectedDrugRole:     affectedRole,
-				LazyEvaluate:         rule.LazyEvaluate,
-			}
-
-			projections = append(projections, projection)
+	affectedRole := ""
+	if rule.AffectedDrugRole != nil {
+		affectedRole = *rule.AffectedDrugRole
+	}
+
+	projections := make([]DDIProjection, 0, len(pairs))
+	for _, pair := range pairs {
+		projection := DDIProjection{
+			RuleID:               rule.RuleID,
+			DrugAConceptID:       pair.DrugAConceptID,
+			DrugAName:            pair.DrugAName,
+			DrugAClassName:       rule.TriggerClassName,
+			DrugBConceptID:       pair.DrugBConceptID,
+			DrugBName:            pair.DrugBName,
+			DrugBClassName:       rule.TargetClassName,
+			RiskLevel:            rule.RiskLevel,
+			AlertMessage:         rule.Description,
+			RuleAuthority:        rule.RuleAuthority,
+			RuleVersion:          rule.RuleVersion,
+			PrecedenceRank:       3, // Class-Class rules
+			RequiresContext:      rule.ContextLOINCID != nil,
+			ContextLOINCID:       rule.ContextLOINCID,
+			ContextThreshold:     rule.ContextThresholdVal,
+			ContextOperator:      rule.ContextLogicOperator,
+			ContextRequired:      rule.ContextRequired,
+			EvaluationTier:       rule.EvaluationTier,
+			InteractionDirection: rule.InteractionDirection,
+			AffectedDrugRole:     affectedRole,
+			LazyEvaluate:         rule.LazyEvaluate,
 		}
+
+		projections = append(projections, projection)
 	}
 
 	s.metrics.RecordClassInteractionCheck("rule_expansion", time.Since(timer))
@@ -2,7 +2,22 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"kb-drug-interactions/internal/database"
+	"kb-drug-interactions/internal/metrics"
 )
 
 // TestWarfarinIbuprofenExpansion validates that Rule 6 (VKA + NSAID) correctly
@@ -302,3 +317,177 @@ func TestExpansionServiceIntegration(t *testing.T) {
 
 	_ = ctx // Use ctx when database is available
 }
+
+// ============================================================================
+// CONCEPT ANCESTOR CLOSURE TESTS (require KB5_TEST_DATABASE_URL)
+// ============================================================================
+
+// newOHDSITestService connects to the PostgreSQL database at the postgres:// URL in
+// KB5_TEST_DATABASE_URL and applies migrations 030 and 032 to a fresh schema,
+// dropped when the test ends
+func newOHDSITestService(t *testing.T) (*OHDSIExpansionService, *sql.DB) {
+	t.Helper()
+	dsn := os.Getenv("KB5_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("KB5_TEST_DATABASE_URL not set")
+	}
+
+	admin, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("kb5_ohdsi_test_%d", time.Now().UnixNano())
+	_, err = admin.Exec(`CREATE SCHEMA ` + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	db, err := gorm.Open(postgres.Open(u.String()), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	for _, migration := range []string{"030_onc_constitutional_class_expansion.sql", "032_ohdsi_concept_ancestor.sql"} {
+		script, err := os.ReadFile(filepath.Join("..", "..", "migrations", migration))
+		require.NoError(t, err)
+		_, err = sqlDB.Exec(string(script))
+		require.NoError(t, err, migration)
+	}
+
+	collector := &metrics.Collector{
+		InteractionChecksTotal: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "interaction_checks_total"},
+			[]string{"check_type", "status"}),
+		InteractionCheckDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "interaction_check_duration_seconds"},
+			[]string{"check_type"}),
+	}
+	return NewOHDSIExpansionService(&database.Database{DB: db}, collector), sqlDB
+}
+
+func TestRebuildConceptAncestors_Closure(t *testing.T) {
+	service, db := newOHDSITestService(t)
+	ctx := context.Background()
+
+	_, err := db.Exec(`
+		INSERT INTO ohdsi_concept (concept_id, concept_name, standard_concept) VALUES
+			(1001, 'Vitamin K antagonists', 'C'), (1002, 'Coumarins', 'C'), (2001, 'warfarin', 'S'),
+			(2003, 'phenprocoumon', 'S'),
+			(1101, 'NSAIDs', 'C'), (1102, 'Propionic acid derivatives', 'C'),
+			(2101, 'ibuprofen', 'S'), (2102, 'naproxen', 'S');
+		INSERT INTO ohdsi_concept_relationship (concept_id_1, concept_id_2, relationship_id, invalid_reason) VALUES
+			(1002, 1001, 'Is a', NULL),
+			(2001, 1002, 'Drug has drug class', NULL),
+			(2003, 1002, 'Drug has drug class', 'D'),
+			(1101, 1102, 'Subsumes', NULL),
+			(2101, 1102, 'Drug has drug class', NULL),
+			(2102, 1102, 'Drug has drug class', NULL);`)
+	require.NoError(t, err)
+
+	// 8 ancestor rows plus a self row for each of the 7 concepts on a valid edge
+	count, err := service.RebuildConceptAncestors(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), count)
+
+	// Warfarin reaches the VKA class through its sub-class
+	var minLevels, maxLevels int
+	require.NoError(t, db.QueryRow(`
+		SELECT min_levels_of_separation, max_levels_of_separation FROM ohdsi_concept_ancestor
+		WHERE ancestor_concept_id = 1001 AND descendant_concept_id = 2001`).Scan(&minLevels, &maxLevels))
+	assert.Equal(t, 2, minLevels)
+	assert.Equal(t, 2, maxLevels)
+
+	// Invalid relationships are not followed
+	var phenprocoumonRows int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM ohdsi_concept_ancestor WHERE descendant_concept_id = 2003`).Scan(&phenprocoumonRows))
+	assert.Zero(t, phenprocoumonRows)
+
+	// Rebuilding replaces the closure rather than adding to it
+	count, err = service.RebuildConceptAncestors(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), count)
+}
+
+func TestExpandRule_ThroughAncestorClosure(t *testing.T) {
+	service, db := newOHDSITestService(t)
+	ctx := context.Background()
+
+	_, err := db.Exec(`
+		INSERT INTO ohdsi_concept (concept_id, concept_name, standard_concept) VALUES
+			(1001, 'Vitamin K antagonists', 'C'), (1002, 'Coumarins', 'C'), (2001, 'warfarin', 'S'),
+			(1101, 'NSAIDs', 'C'), (1102, 'Propionic acid derivatives', 'C'),
+			(2101, 'ibuprofen', 'S'), (2102, 'naproxen', 'S'),
+			(1201, 'QT prolonging agents', 'C'), (2201, 'citalopram', 'S'), (2202, 'haloperidol', 'S');
+		INSERT INTO ohdsi_concept_relationship (concept_id_1, concept_id_2, relationship_id) VALUES
+			(1002, 1001, 'Is a'),
+			(2001, 1002, 'Drug has drug class'),
+			(1101, 1102, 'Subsumes'),
+			(2101, 1102, 'Drug has drug class'),
+			(2102, 1102, 'Drug has drug class'),
+			(2201, 1201, 'Drug has drug class'),
+			(2202, 1201, 'Drug has drug class');`)
+	require.NoError(t, err)
+	_, err = service.RebuildConceptAncestors(ctx)
+	require.NoError(t, err)
+
+	// Drugs attached to sub-classes are members of the parent class
+	projections, err := service.ExpandRule(ctx, ConstitutionalRule{
+		RuleID: 6, TriggerClassName: "Vitamin K Antagonists", TriggerConceptID: 1001,
+		TargetClassName: "NSAIDs", TargetConceptID: 1101, RiskLevel: "HIGH",
+	})
+	require.NoError(t, err)
+	require.Len(t, projections, 2)
+	assert.Equal(t, int64(2001), projections[0].DrugAConceptID)
+	assert.Equal(t, "warfarin", projections[0].DrugAName)
+	assert.Equal(t, int64(2101), projections[0].DrugBConceptID)
+	assert.Equal(t, "ibuprofen", projections[0].DrugBName)
+	assert.Equal(t, int64(2102), projections[1].DrugBConceptID)
+	assert.Equal(t, 3, projections[1].PrecedenceRank)
+
+	// Self-class rules yield each pair once and no self-pairs
+	projections, err = service.ExpandRule(ctx, ConstitutionalRule{
+		RuleID: 9, TriggerClassName: "QT Prolonging Agents", TriggerConceptID: 1201,
+		TargetClassName: "QT Prolonging Agents", TargetConceptID: 1201, RiskLevel: "WARNING",
+	})
+	require.NoError(t, err)
+	require.Len(t, projections, 1)
+	assert.Equal(t, int64(2201), projections[0].DrugAConceptID)
+	assert.Equal(t, "citalopram", projections[0].DrugAName)
+	assert.Equal(t, int64(2202), projections[0].DrugBConceptID)
+	assert.Equal(t, "haloperidol", projections[0].DrugBName)
+}
+
+func TestLoadConceptAncestors_FailedLoadKeepsClosure(t *testing.T) {
+	service, db := newOHDSITestService(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	header := "ancestor_concept_id\tdescendant_concept_id\tmin_levels_of_separation\tmax_levels_of_separation\n"
+	valid := filepath.Join(dir, "valid.tsv")
+	require.NoError(t, os.WriteFile(valid, []byte(header+
+		"1001\t2001\t2\t2\n"+
+		"1001\t1001\t0\t0\n"+
+		"not\ta\tnumber\trow\n"), 0o600))
+
+	// Malformed rows are skipped and not counted
+	count, err := service.loadConceptAncestors(ctx, valid)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// The database rejects the out-of-range level; nothing from this file is kept
+	rejected := filepath.Join(dir, "rejected.tsv")
+	require.NoError(t, os.WriteFile(rejected, []byte(header+
+		"1101\t2101\t1\t1\n"+
+		"1101\t2102\t3000000000\t1\n"), 0o600))
+
+	_, err = service.loadConceptAncestors(ctx, rejected)
+	assert.Error(t, err)
+
+	var rows int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM ohdsi_concept_ancestor`).Scan(&rows))
+	assert.Equal(t, 2, rows)
+}
@@ -0,0 +1,502 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kb-drug-interactions/internal/database"
+	"kb-drug-interactions/internal/metrics"
+	"kb-drug-interactions/internal/models"
+)
+
+// Dose adjustment types (drug_dose_adjustments.adjustment_type)
+const (
+	DoseAdjustmentRenal   = "renal"
+	DoseAdjustmentHepatic = "hepatic"
+)
+
+// Renal function metrics (drug_dose_adjustments.function_metric)
+const (
+	RenalMetricCrCl = "crcl"
+	RenalMetricEGFR = "egfr"
+)
+
+// Dose check statuses, most severe first
+const (
+	DoseStatusAvoid        = "avoid"
+	DoseStatusExceedsMax   = "exceeds_max"
+	DoseStatusUnverified   = "unverified" // Unit or frequency could not be compared
+	DoseStatusWithinLimits = "within_limits"
+)
+
+// DoseAdjustmentRule gives a drug's dosing limits in one band of renal or hepatic function
+type DoseAdjustmentRule struct {
+	ID                 int      `json:"id" gorm:"primaryKey"`
+	DrugCode           string   `json:"drug_code"`
+	DrugName           string   `json:"drug_name"`
+	AdjustmentType     string   `json:"adjustment_type"`
+	FunctionMetric     *string  `json:"function_metric,omitempty"`
+	MinValue           *float64 `json:"min_value,omitempty"` // Inclusive
+	MaxValue           *float64 `json:"max_value,omitempty"` // Exclusive
+	DialysisModality   *string  `json:"dialysis_modality,omitempty"`
+	ChildPughClass     *string  `json:"child_pugh_class,omitempty"`
+	DoseUnit           string   `json:"dose_unit"`
+	MaxSingleDose      *float64 `json:"max_single_dose,omitempty"`
+	MinIntervalHours   *float64 `json:"min_interval_hours,omitempty"`
+	MaxDailyDose       *float64 `json:"max_daily_dose,omitempty"`
+	Avoid              bool     `json:"avoid"`
+	RecommendedRegimen string   `json:"recommended_regimen"`
+	ClinicalNote       *string  `json:"clinical_note,omitempty"`
+	Source             string   `json:"source"`
+	Active             bool     `json:"active"`
+}
+
+// TableName specifies the database table for GORM
+func (DoseAdjustmentRule) TableName() string {
+	return "drug_dose_adjustments"
+}
+
+// PatientOrganFunction is the renal and hepatic function used to select dose limits
+type PatientOrganFunction struct {
+	CrCl             *float64 `json:"crcl,omitempty"` // mL/min
+	EGFR             *float64 `json:"egfr,omitempty"` // mL/min/1.73m²
+	DialysisModality string   `json:"dialysis_modality,omitempty"`
+	ChildPughClass   string   `json:"child_pugh_class,omitempty"` // "A", "B", "C"
+}
+
+// DoseAdjustmentRequest checks medication orders against the patient's organ function
+type DoseAdjustmentRequest struct {
+	Orders         []models.MedicationOrder `json:"orders" binding:"required,min=1"`
+	PatientContext *models.PatientContext   `json:"patient_context" binding:"required"`
+}
+
+// DoseAdjustmentResult compares one order with the dose limits for the patient's function
+type DoseAdjustmentResult struct {
+	OrderID            string             `json:"order_id,omitempty"`
+	DrugCode           string             `json:"drug_code"`
+	DrugName           string             `json:"drug_name"`
+	AdjustmentType     string             `json:"adjustment_type"`
+	Basis              string             `json:"basis"` // "CrCl 25 mL/min", "HD", "Child-Pugh C"
+	Status             string             `json:"status"`
+	Message            string             `json:"message"`
+	Issues             []string           `json:"issues,omitempty"`
+	OrderedDose        float64            `json:"ordered_dose"`
+	OrderedUnit        string             `json:"ordered_unit"`
+	OrderedFrequency   string             `json:"ordered_frequency,omitempty"`
+	OrderedDailyDose   *float64           `json:"ordered_daily_dose,omitempty"` // In the rule's unit
+	RecommendedRegimen string             `json:"recommended_regimen"`
+	MaxSingleDose      *float64           `json:"max_single_dose,omitempty"`
+	MinIntervalHours   *float64           `json:"min_interval_hours,omitempty"`
+	MaxDailyDose       *float64           `json:"max_daily_dose,omitempty"`
+	DoseUnit           string             `json:"dose_unit"`
+	Severity           models.DDISeverity `json:"severity"`
+	ClinicalNote       string             `json:"clinical_note,omitempty"`
+	Source             string             `json:"source"`
+}
+
+// DoseAdjustmentResponse is the outcome of a dose adjustment check
+type DoseAdjustmentResponse struct {
+	OrganFunction *models.OrganFunctionAssessment `json:"organ_function,omitempty"`
+	Function      PatientOrganFunction            `json:"function"`
+	Results       []DoseAdjustmentResult          `json:"results"`
+	Notes         []string                        `json:"notes,omitempty"`
+}
+
+// OrganDoseAdjustmentEngine checks ordered doses against renal and hepatic dosing limits
+type OrganDoseAdjustmentEngine struct {
+	db      *database.Database
+	metrics *metrics.Collector
+
+	// Rules keyed by normalized drug code
+	rules       map[string][]DoseAdjustmentRule
+	rulesLoaded time.Time
+	cacheTTL    time.Duration
+	mu          sync.Mutex
+}
+
+// NewOrganDoseAdjustmentEngine creates a new renal/hepatic dose adjustment engine
+func NewOrganDoseAdjustmentEngine(db *database.Database, metrics *metrics.Collector) *OrganDoseAdjustmentEngine {
+	return &OrganDoseAdjustmentEngine{
+		db:       db,
+		metrics:  metrics,
+		cacheTTL: 30 * time.Minute,
+	}
+}
+
+// CheckDoses derives the patient's organ function (from labs when supplied) and
+// checks each order against the matching dose limits. Invalid labs are reported
+// as *LabInputError.
+func (ode *OrganDoseAdjustmentEngine) CheckDoses(ctx context.Context, request DoseAdjustmentRequest) (*DoseAdjustmentResponse, error) {
+	organFunction, err := ResolvePatientContext(request.PatientContext)
+	if err != nil {
+		return nil, err
+	}
+
+	function := patientOrganFunction(request.PatientContext)
+	results, notes, err := ode.CheckOrders(ctx, request.Orders, function)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DoseAdjustmentResponse{
+		OrganFunction: organFunction,
+		Function:      function,
+		Results:       results,
+		Notes:         notes,
+	}, nil
+}
+
+// CheckOrders checks each order against the dose limits for the given organ function
+func (ode *OrganDoseAdjustmentEngine) CheckOrders(ctx context.Context, orders []models.MedicationOrder, function PatientOrganFunction) ([]DoseAdjustmentResult, []string, error) {
+	rules, err := ode.loadRules(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load dose adjustment rules: %w", err)
+	}
+
+	results, notes := evaluateDoseAdjustments(rules, orders, function)
+	for _, result := range results {
+		ode.metrics.RecordDoseEstimate(result.AdjustmentType + "_" + result.Status)
+	}
+	return results, notes, nil
+}
+
+// CheckRegimen checks the orders against their dose limits and the drug codes
+// without an order against avoid rules, which need no dose to fire
+func (ode *OrganDoseAdjustmentEngine) CheckRegimen(ctx context.Context, orders []models.MedicationOrder, drugCodes []string, function PatientOrganFunction) ([]DoseAdjustmentResult, []string, error) {
+	rules, err := ode.loadRules(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load dose adjustment rules: %w", err)
+	}
+
+	results, notes := evaluateDoseAdjustments(rules, orders, function)
+	results = append(results, evaluateAvoidRules(rules, orders, drugCodes, function)...)
+	sort.SliceStable(results, func(i, j int) bool {
+		return doseStatusRank(results[i].Status) < doseStatusRank(results[j].Status)
+	})
+	for _, result := range results {
+		ode.metrics.RecordDoseEstimate(result.AdjustmentType + "_" + result.Status)
+	}
+	return results, notes, nil
+}
+
+// evaluateAvoidRules applies the avoid rules for the patient's function to drug
+// codes that have no order; ordered drugs are checked by evaluateDoseAdjustments
+func evaluateAvoidRules(rules map[string][]DoseAdjustmentRule, orders []models.MedicationOrder, drugCodes []string, function PatientOrganFunction) []DoseAdjustmentResult {
+	checked := make(map[string]bool, len(orders)+len(drugCodes))
+	for _, order := range orders {
+		checked[normalizeATCDrugKey(order.DrugCode)] = true
+	}
+
+	var results []DoseAdjustmentResult
+	for _, drugCode := range drugCodes {
+		key := normalizeATCDrugKey(drugCode)
+		drugRules := rules[key]
+		if checked[key] || len(drugRules) == 0 {
+			continue
+		}
+		checked[key] = true
+
+		order := models.MedicationOrder{DrugCode: drugCode}
+		if rule, basis, _ := selectRenalRule(drugRules, function); rule != nil && rule.Avoid {
+			results = append(results, checkOrderDose(order, *rule, basis))
+		}
+		if rule, basis := selectHepaticRule(drugRules, function); rule != nil && rule.Avoid {
+			results = append(results, checkOrderDose(order, *rule, basis))
+		}
+	}
+	return results
+}
+
+// patientOrganFunction reads the dosing-relevant function from a resolved patient context
+func patientOrganFunction(patientContext *models.PatientContext) PatientOrganFunction {
+	var function PatientOrganFunction
+	if patientContext == nil {
+		return function
+	}
+	if patientContext.CreatinineClearance != nil {
+		crcl, _ := patientContext.CreatinineClearance.Float64()
+		function.CrCl = &crcl
+	}
+	if patientContext.RenalFunction != nil {
+		egfr, _ := patientContext.RenalFunction.Float64()
+		function.EGFR = &egfr
+	}
+	function.DialysisModality = strings.ToUpper(strings.TrimSpace(patientContext.DialysisModality))
+	function.ChildPughClass = childPughClass(patientContext.HepaticFunction)
+	return function
+}
+
+// childPughClass extracts the class letter from "ChildPugh_B", "Child-Pugh B" or "B"
+func childPughClass(stage string) string {
+	s := strings.ToUpper(strings.NewReplacer("-", "", "_", "", " ", "").Replace(stage))
+	s = strings.TrimPrefix(s, "CHILDPUGH")
+	switch s {
+	case "A", "B", "C":
+		return s
+	}
+	return ""
+}
+
+// evaluateDoseAdjustments checks each order against the renal and hepatic rule
+// for the patient's function
+func evaluateDoseAdjustments(rules map[string][]DoseAdjustmentRule, orders []models.MedicationOrder, function PatientOrganFunction) ([]DoseAdjustmentResult, []string) {
+	var results []DoseAdjustmentResult
+	var notes []string
+
+	for _, order := range orders {
+		drugRules := rules[normalizeATCDrugKey(order.DrugCode)]
+		if len(drugRules) == 0 {
+			continue
+		}
+
+		rule, basis, note := selectRenalRule(drugRules, function)
+		if note != "" {
+			notes = append(notes, fmt.Sprintf("%s: %s", ruleDrugName(drugRules, order), note))
+		}
+		if rule != nil {
+			results = append(results, checkOrderDose(order, *rule, basis))
+		}
+
+		if rule, basis := selectHepaticRule(drugRules, function); rule != nil {
+			results = append(results, checkOrderDose(order, *rule, basis))
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return doseStatusRank(results[i].Status) < doseStatusRank(results[j].Status)
+	})
+	return results, notes
+}
+
+// selectRenalRule picks the renal rule for the patient's dialysis modality or
+// CrCl/eGFR band. A rule's metric falls back to the other metric when it is
+// unknown; dialysis without a modality-specific rule uses the lowest band.
+func selectRenalRule(rules []DoseAdjustmentRule, function PatientOrganFunction) (*DoseAdjustmentRule, string, string) {
+	if function.DialysisModality != "" {
+		var lowest *DoseAdjustmentRule
+		for i, rule := range rules {
+			if rule.AdjustmentType != DoseAdjustmentRenal {
+				continue
+			}
+			if rule.DialysisModality != nil && strings.EqualFold(*rule.DialysisModality, function.DialysisModality) {
+				return &rules[i], function.DialysisModality, ""
+			}
+			if rule.FunctionMetric != nil && rule.MinValue == nil {
+				lowest = &rules[i]
+			}
+		}
+		if lowest != nil {
+			return lowest, function.DialysisModality, fmt.Sprintf(
+				"no %s-specific dosing; lowest renal function band applied", function.DialysisModality)
+		}
+		return nil, "", ""
+	}
+
+	for i, rule := range rules {
+		if rule.AdjustmentType != DoseAdjustmentRenal || rule.FunctionMetric == nil {
+			continue
+		}
+		value, label, substituted := renalMetricValue(*rule.FunctionMetric, function)
+		if value == nil {
+			continue
+		}
+		if rule.MinValue != nil && *value < *rule.MinValue {
+			continue
+		}
+		if rule.MaxValue != nil && *value >= *rule.MaxValue {
+			continue
+		}
+		note := ""
+		if substituted {
+			note = fmt.Sprintf("%s used in place of %s", label, strings.ToUpper(*rule.FunctionMetric))
+		}
+		return &rules[i], fmt.Sprintf("%s %g %s", label, *value, renalMetricUnit(label)), note
+	}
+	return nil, "", ""
+}
+
+// renalMetricValue returns the value of a renal metric, substituting the other
+// metric when it is unknown
+func renalMetricValue(metric string, function PatientOrganFunction) (*float64, string, bool) {
+	if metric == RenalMetricEGFR {
+		if function.EGFR != nil {
+			return function.EGFR, "eGFR", false
+		}
+		if function.CrCl != nil {
+			return function.CrCl, "CrCl", true
+		}
+		return nil, "", false
+	}
+	if function.CrCl != nil {
+		return function.CrCl, "CrCl", false
+	}
+	if function.EGFR != nil {
+		return function.EGFR, "eGFR", true
+	}
+	return nil, "", false
+}
+
+func renalMetricUnit(label string) string {
+	if label == "eGFR" {
+		return "mL/min/1.73m²"
+	}
+	return "mL/min"
+}
+
+// selectHepaticRule picks the hepatic rule for the patient's Child-Pugh class
+func selectHepaticRule(rules []DoseAdjustmentRule, function PatientOrganFunction) (*DoseAdjustmentRule, string) {
+	if function.ChildPughClass == "" {
+		return nil, ""
+	}
+	for i, rule := range rules {
+		if rule.AdjustmentType == DoseAdjustmentHepatic && rule.ChildPughClass != nil &&
+			strings.EqualFold(*rule.ChildPughClass, function.ChildPughClass) {
+			return &rules[i], "Child-Pugh " + function.ChildPughClass
+		}
+	}
+	return nil, ""
+}
+
+// checkOrderDose compares an order's dose, interval and daily dose with a rule's limits
+func checkOrderDose(order models.MedicationOrder, rule DoseAdjustmentRule, basis string) DoseAdjustmentResult {
+	drugName := order.DrugName
+	if drugName == "" {
+		drugName = rule.DrugName
+	}
+	result := DoseAdjustmentResult{
+		OrderID:            order.OrderID,
+		DrugCode:           order.DrugCode,
+		DrugName:           drugName,
+		AdjustmentType:     rule.AdjustmentType,
+		Basis:              basis,
+		OrderedDose:        order.Dose,
+		OrderedUnit:        order.DoseUnit,
+		OrderedFrequency:   order.Frequency,
+		RecommendedRegimen: rule.RecommendedRegimen,
+		MaxSingleDose:      rule.MaxSingleDose,
+		MinIntervalHours:   rule.MinIntervalHours,
+		MaxDailyDose:       rule.MaxDailyDose,
+		DoseUnit:           rule.DoseUnit,
+		ClinicalNote:       derefString(rule.ClinicalNote),
+		Source:             rule.Source,
+	}
+
+	if rule.Avoid {
+		result.Status = DoseStatusAvoid
+		result.Severity = models.SeverityContraindicated
+		result.Message = fmt.Sprintf("Avoid %s for %s", drugName, basis)
+		if result.ClinicalNote != "" {
+			result.Message += ": " + result.ClinicalNote
+		}
+		return result
+	}
+
+	dose, unitOK := convertDose(order.Dose, order.DoseUnit, rule.DoseUnit)
+	interval, intervalOK := orderIntervalHours(order)
+	if !unitOK {
+		result.Issues = append(result.Issues, fmt.Sprintf("Ordered unit %q cannot be compared with %s", order.DoseUnit, rule.DoseUnit))
+	}
+	if !intervalOK {
+		result.Issues = append(result.Issues, fmt.Sprintf("Frequency %q not recognized", order.Frequency))
+	}
+
+	exceeded := false
+	if unitOK && rule.MaxSingleDose != nil && dose > *rule.MaxSingleDose+1e-9 {
+		exceeded = true
+		result.Issues = append(result.Issues, fmt.Sprintf("Dose %g %s exceeds maximum single dose %g %s",
+			dose, rule.DoseUnit, *rule.MaxSingleDose, rule.DoseUnit))
+	}
+	if intervalOK && rule.MinIntervalHours != nil && interval < *rule.MinIntervalHours-1e-9 {
+		exceeded = true
+		result.Issues = append(result.Issues, fmt.Sprintf("Interval %gh is shorter than minimum %gh",
+			interval, *rule.MinIntervalHours))
+	}
+	if unitOK && intervalOK {
+		daily := roundDose(dose * 24 / interval)
+		result.OrderedDailyDose = &daily
+		if rule.MaxDailyDose != nil && daily > *rule.MaxDailyDose+1e-9 {
+			exceeded = true
+			result.Issues = append(result.Issues, fmt.Sprintf("Daily dose %g %s exceeds maximum %g %s/day",
+				daily, rule.DoseUnit, *rule.MaxDailyDose, rule.DoseUnit))
+		}
+	}
+
+	switch {
+	case exceeded:
+		result.Status = DoseStatusExceedsMax
+		result.Severity = models.SeverityMajor
+		result.Message = fmt.Sprintf("%s exceeds max for %s: use %s", drugName, basis, rule.RecommendedRegimen)
+	case !unitOK || !intervalOK:
+		result.Status = DoseStatusUnverified
+		result.Severity = models.SeverityModerate
+		result.Message = fmt.Sprintf("%s dose could not be verified for %s: recommended %s", drugName, basis, rule.RecommendedRegimen)
+	default:
+		result.Status = DoseStatusWithinLimits
+		result.Severity = models.SeverityMinor
+		result.Message = fmt.Sprintf("%s dose is within limits for %s", drugName, basis)
+	}
+	return result
+}
+
+// doseStatusRank orders dose statuses from most to least severe
+func doseStatusRank(status string) int {
+	switch status {
+	case DoseStatusAvoid:
+		return 0
+	case DoseStatusExceedsMax:
+		return 1
+	case DoseStatusUnverified:
+		return 2
+	}
+	return 3
+}
+
+func ruleDrugName(rules []DoseAdjustmentRule, order models.MedicationOrder) string {
+	if order.DrugName != "" {
+		return order.DrugName
+	}
+	return rules[0].DrugName
+}
+
+// GetDrugAdjustments returns the active dose adjustment rules for a drug
+func (ode *OrganDoseAdjustmentEngine) GetDrugAdjustments(ctx context.Context, drugCode string) ([]DoseAdjustmentRule, error) {
+	rules, err := ode.loadRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dose adjustment rules: %w", err)
+	}
+	return rules[normalizeATCDrugKey(drugCode)], nil
+}
+
+// loadRules returns active dose adjustment rules keyed by normalized drug code
+func (ode *OrganDoseAdjustmentEngine) loadRules(ctx context.Context) (map[string][]DoseAdjustmentRule, error) {
+	ode.mu.Lock()
+	defer ode.mu.Unlock()
+
+	if ode.rules != nil && time.Since(ode.rulesLoaded) < ode.cacheTTL {
+		return ode.rules, nil
+	}
+
+	var rows []DoseAdjustmentRule
+	err := ode.db.DB.WithContext(ctx).
+		Where("active = TRUE").
+		Order("drug_code, adjustment_type, min_value DESC NULLS LAST").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make(map[string][]DoseAdjustmentRule)
+	for _, row := range rows {
+		key := normalizeATCDrugKey(row.DrugCode)
+		rules[key] = append(rules[key], row)
+	}
+
+	ode.rules = rules
+	ode.rulesLoaded = time.Now()
+
+	return rules, nil
+}
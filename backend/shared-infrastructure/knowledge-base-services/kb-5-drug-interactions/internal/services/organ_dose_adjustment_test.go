@@ -0,0 +1,204 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// RENAL/HEPATIC DOSE ADJUSTMENT TESTS
+// ============================================================================
+
+func TestParseFrequencyHours(t *testing.T) {
+	cases := map[string]float64{"q12h": 12, "Q 8 H": 8, "BID": 12, "tid": 8, "daily": 24, "every 6 hours": 6, "q2d": 48, "weekly": 168}
+	for frequency, expected := range cases {
+		hours, ok := parseFrequencyHours(frequency)
+		require.True(t, ok, frequency)
+		assert.Equal(t, expected, hours, frequency)
+	}
+	_, ok := parseFrequencyHours("prn")
+	assert.False(t, ok)
+
+	dose, ok := convertDose(1, "g", "mg")
+	require.True(t, ok)
+	assert.Equal(t, 1000.0, dose)
+	_, ok = convertDose(1, "mL", "mg")
+	assert.False(t, ok)
+}
+
+func TestEvaluateDoseAdjustments_RenalExceedsMax(t *testing.T) {
+	rules := map[string][]DoseAdjustmentRule{
+		"RXCUI:114477": {
+			{DrugCode: "RxCUI:114477", DrugName: "Levetiracetam", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricCrCl),
+				MinValue: floatPtr(30), MaxValue: floatPtr(50), DoseUnit: "mg", MaxSingleDose: floatPtr(750),
+				MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(1500), RecommendedRegimen: "250-750 mg q12h"},
+			{DrugCode: "RxCUI:114477", DrugName: "Levetiracetam", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricCrCl),
+				MaxValue: floatPtr(30), DoseUnit: "mg", MaxSingleDose: floatPtr(500),
+				MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(1000), RecommendedRegimen: "250-500 mg q12h"},
+		},
+	}
+
+	orders := []models.MedicationOrder{{DrugCode: "114477", Dose: 1, DoseUnit: "g", Frequency: "q12h"}}
+	results, notes := evaluateDoseAdjustments(rules, orders, PatientOrganFunction{CrCl: floatPtr(25)})
+
+	require.Len(t, results, 1)
+	assert.Empty(t, notes)
+	result := results[0]
+	assert.Equal(t, DoseStatusExceedsMax, result.Status)
+	assert.Equal(t, "CrCl 25 mL/min", result.Basis)
+	assert.Equal(t, "Levetiracetam exceeds max for CrCl 25 mL/min: use 250-500 mg q12h", result.Message)
+	assert.Equal(t, 2000.0, *result.OrderedDailyDose)
+	assert.Len(t, result.Issues, 2) // Single dose and daily dose
+	assert.Equal(t, models.SeverityMajor, result.Severity)
+}
+
+func TestEvaluateDoseAdjustments_WithinLimits(t *testing.T) {
+	rules := map[string][]DoseAdjustmentRule{
+		"RXCUI:114477": {
+			{DrugCode: "RxCUI:114477", DrugName: "Levetiracetam", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricCrCl),
+				MinValue: floatPtr(50), MaxValue: floatPtr(80), DoseUnit: "mg", MaxSingleDose: floatPtr(1000),
+				MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(2000), RecommendedRegimen: "500-1000 mg q12h"},
+			{DrugCode: "RxCUI:114477", DrugName: "Levetiracetam", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricCrCl),
+				MinValue: floatPtr(30), MaxValue: floatPtr(50), DoseUnit: "mg", MaxSingleDose: floatPtr(750),
+				MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(1500), RecommendedRegimen: "250-750 mg q12h"},
+			{DrugCode: "RxCUI:114477", DrugName: "Levetiracetam", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricCrCl),
+				MaxValue: floatPtr(30), DoseUnit: "mg", MaxSingleDose: floatPtr(500),
+				MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(1000), RecommendedRegimen: "250-500 mg q12h"},
+		},
+	}
+
+	orders := []models.MedicationOrder{{DrugCode: "RxCUI:114477", Dose: 500, DoseUnit: "mg", Frequency: "BID"}}
+	results, _ := evaluateDoseAdjustments(rules, orders, PatientOrganFunction{CrCl: floatPtr(40)})
+	require.Len(t, results, 1)
+	assert.Equal(t, DoseStatusWithinLimits, results[0].Status)
+
+	// Normal renal function: no band applies
+	results, _ = evaluateDoseAdjustments(rules, orders, PatientOrganFunction{CrCl: floatPtr(95)})
+	assert.Empty(t, results)
+}
+
+func TestEvaluateDoseAdjustments_FrequencyAndUnverified(t *testing.T) {
+	rules := map[string][]DoseAdjustmentRule{
+		"RXCUI:114477": {
+			{DrugCode: "RxCUI:114477", DrugName: "Levetiracetam", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricCrCl),
+				MinValue: floatPtr(30), MaxValue: floatPtr(50), DoseUnit: "mg", MaxSingleDose: floatPtr(750),
+				MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(1500), RecommendedRegimen: "250-750 mg q12h"},
+			{DrugCode: "RxCUI:114477", DrugName: "Levetiracetam", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricCrCl),
+				MaxValue: floatPtr(30), DoseUnit: "mg", MaxSingleDose: floatPtr(500),
+				MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(1000), RecommendedRegimen: "250-500 mg q12h"},
+		},
+	}
+
+	orders := []models.MedicationOrder{
+		{DrugCode: "RxCUI:114477", Dose: 250, DoseUnit: "mg", Frequency: "q6h"},
+		{DrugCode: "RxCUI:114477", OrderID: "2", Dose: 250, DoseUnit: "mg", Frequency: "as directed"},
+	}
+	results, _ := evaluateDoseAdjustments(rules, orders, PatientOrganFunction{CrCl: floatPtr(20)})
+	require.Len(t, results, 2)
+	assert.Equal(t, DoseStatusExceedsMax, results[0].Status)
+	assert.Contains(t, results[0].Issues[0], "shorter than minimum 12h")
+	assert.Equal(t, DoseStatusUnverified, results[1].Status)
+}
+
+func TestEvaluateDoseAdjustments_DialysisAndEGFR(t *testing.T) {
+	rules := map[string][]DoseAdjustmentRule{
+		"RXCUI:20481": {
+			{DrugCode: "RxCUI:20481", DrugName: "Cefepime", AdjustmentType: DoseAdjustmentRenal, DialysisModality: stringPtr("HD"),
+				DoseUnit: "mg", MaxSingleDose: floatPtr(1000), MinIntervalHours: floatPtr(24), MaxDailyDose: floatPtr(1000),
+				RecommendedRegimen: "1 g q24h"},
+		},
+		"RXCUI:114477": {
+			{DrugCode: "RxCUI:114477", DrugName: "Levetiracetam", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricCrCl),
+				MinValue: floatPtr(50), MaxValue: floatPtr(80), DoseUnit: "mg", MaxSingleDose: floatPtr(1000),
+				MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(2000), RecommendedRegimen: "500-1000 mg q12h"},
+			{DrugCode: "RxCUI:114477", DrugName: "Levetiracetam", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricCrCl),
+				MinValue: floatPtr(30), MaxValue: floatPtr(50), DoseUnit: "mg", MaxSingleDose: floatPtr(750),
+				MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(1500), RecommendedRegimen: "250-750 mg q12h"},
+			{DrugCode: "RxCUI:114477", DrugName: "Levetiracetam", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricCrCl),
+				MaxValue: floatPtr(30), DoseUnit: "mg", MaxSingleDose: floatPtr(500),
+				MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(1000), RecommendedRegimen: "250-500 mg q12h"},
+		},
+		"RXCUI:6809": {
+			{DrugCode: "RxCUI:6809", DrugName: "Metformin", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricEGFR),
+				MaxValue: floatPtr(30), Avoid: true, RecommendedRegimen: "Contraindicated", ClinicalNote: stringPtr("Risk of lactic acidosis.")},
+		},
+	}
+
+	orders := []models.MedicationOrder{
+		{DrugCode: "RxCUI:20481", Dose: 2, DoseUnit: "g", Frequency: "q8h"},
+		{DrugCode: "RxCUI:114477", Dose: 500, DoseUnit: "mg", Frequency: "q12h"},
+		{DrugCode: "RxCUI:6809", Dose: 500, DoseUnit: "mg", Frequency: "BID"},
+	}
+	results, notes := evaluateDoseAdjustments(rules, orders, PatientOrganFunction{DialysisModality: "HD"})
+	require.Len(t, results, 3)
+	assert.Equal(t, DoseStatusAvoid, results[0].Status) // Metformin: lowest eGFR band
+	assert.Equal(t, "Avoid Metformin for HD: Risk of lactic acidosis.", results[0].Message)
+	assert.Equal(t, "Cefepime", results[1].DrugName)
+	assert.Equal(t, DoseStatusExceedsMax, results[1].Status)
+	assert.Equal(t, "Levetiracetam", results[2].DrugName)
+	assert.Equal(t, DoseStatusWithinLimits, results[2].Status)
+	require.Len(t, notes, 2)
+	assert.Equal(t, "Levetiracetam: no HD-specific dosing; lowest renal function band applied", notes[0])
+
+	// eGFR rule with only eGFR known
+	results, _ = evaluateDoseAdjustments(rules, orders[2:], PatientOrganFunction{EGFR: floatPtr(25)})
+	require.Len(t, results, 1)
+	assert.Equal(t, DoseStatusAvoid, results[0].Status)
+	assert.Equal(t, "Avoid Metformin for eGFR 25 mL/min/1.73m²: Risk of lactic acidosis.", results[0].Message)
+}
+
+func TestEvaluateDoseAdjustments_Hepatic(t *testing.T) {
+	rules := map[string][]DoseAdjustmentRule{
+		"RXCUI:10689": {
+			{DrugCode: "RxCUI:10689", DrugName: "Tramadol", AdjustmentType: DoseAdjustmentHepatic, ChildPughClass: stringPtr("C"),
+				DoseUnit: "mg", MaxSingleDose: floatPtr(50), MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(100),
+				RecommendedRegimen: "50 mg q12h"},
+		},
+	}
+
+	orders := []models.MedicationOrder{{DrugCode: "RxCUI:10689", Dose: 50, DoseUnit: "mg", Frequency: "q6h"}}
+	function := patientOrganFunction(&models.PatientContext{HepaticFunction: "ChildPugh_C"})
+	assert.Equal(t, "C", function.ChildPughClass)
+
+	results, _ := evaluateDoseAdjustments(rules, orders, function)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Child-Pugh C", results[0].Basis)
+	assert.Equal(t, DoseStatusExceedsMax, results[0].Status)
+	assert.Equal(t, "Tramadol exceeds max for Child-Pugh C: use 50 mg q12h", results[0].Message)
+}
+
+func TestEvaluateAvoidRules_DrugCodesWithoutOrders(t *testing.T) {
+	rules := map[string][]DoseAdjustmentRule{
+		"RXCUI:114477": {
+			{DrugCode: "RxCUI:114477", DrugName: "Levetiracetam", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricCrCl),
+				MaxValue: floatPtr(30), DoseUnit: "mg", MaxSingleDose: floatPtr(500),
+				MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(1000), RecommendedRegimen: "250-500 mg q12h"},
+		},
+		"RXCUI:6809": {
+			{DrugCode: "RxCUI:6809", DrugName: "Metformin", AdjustmentType: DoseAdjustmentRenal, FunctionMetric: stringPtr(RenalMetricEGFR),
+				MaxValue: floatPtr(30), Avoid: true, RecommendedRegimen: "Contraindicated", ClinicalNote: stringPtr("Risk of lactic acidosis.")},
+		},
+		"RXCUI:10689": {
+			{DrugCode: "RxCUI:10689", DrugName: "Tramadol", AdjustmentType: DoseAdjustmentHepatic, ChildPughClass: stringPtr("C"),
+				DoseUnit: "mg", MaxSingleDose: floatPtr(50), MinIntervalHours: floatPtr(12), MaxDailyDose: floatPtr(100),
+				RecommendedRegimen: "50 mg q12h"},
+		},
+	}
+
+	orders := []models.MedicationOrder{{DrugCode: "RxCUI:114477", Dose: 500, DoseUnit: "mg", Frequency: "q12h"}}
+	drugCodes := []string{"RxCUI:6809", "RxCUI:114477", "RxCUI:10689", "6809"}
+	function := PatientOrganFunction{EGFR: floatPtr(25), CrCl: floatPtr(25), ChildPughClass: "C"}
+
+	// Only avoid rules fire without a dose; ordered drugs and repeats are skipped
+	results := evaluateAvoidRules(rules, orders, drugCodes, function)
+	require.Len(t, results, 1)
+	assert.Equal(t, DoseStatusAvoid, results[0].Status)
+	assert.Equal(t, "RxCUI:6809", results[0].DrugCode)
+	assert.Equal(t, "Avoid Metformin for eGFR 25 mL/min/1.73m²: Risk of lactic acidosis.", results[0].Message)
+
+	assert.Empty(t, evaluateAvoidRules(rules, nil, drugCodes, PatientOrganFunction{EGFR: floatPtr(45)}))
+}
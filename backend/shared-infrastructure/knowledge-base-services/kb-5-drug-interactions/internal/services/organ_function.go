@@ -0,0 +1,472 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// Formulas used to derive organ function from labs
+const (
+	FormulaCKDEPI2021Creatinine         = "CKD-EPI 2021 creatinine (race-free)"
+	FormulaCKDEPI2021CreatinineCystatin = "CKD-EPI 2021 creatinine-cystatin C (race-free)"
+	FormulaCKDEPI2012Cystatin           = "CKD-EPI 2012 cystatin C"
+	FormulaCockcroftGault               = "Cockcroft-Gault (actual body weight)"
+	FormulaChildPugh                    = "Child-Pugh (Child-Turcotte-Pugh)"
+)
+
+// Sources of a stage in an OrganFunctionAssessment
+const (
+	StageSourceProvided = "provided"
+	StageSourceDerived  = "derived"
+)
+
+// LabInputError reports a patient lab value that cannot be used
+type LabInputError struct {
+	Field  string
+	Reason string
+}
+
+func (e *LabInputError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// ResolvePatientContextData resolves a governance patient context through
+// ResolvePatientContext. Stages supplied by the caller take precedence; derived
+// values fill the empty ones. Returns nil when no labs were supplied.
+func ResolvePatientContextData(patientContext *models.PatientContextData) (*models.OrganFunctionAssessment, error) {
+	if patientContext == nil {
+		return nil, nil
+	}
+
+	resolved := &models.PatientContext{
+		AgeMonths:       patientContext.AgeMonths,
+		AgeDays:         patientContext.AgeDays,
+		HepaticFunction: patientContext.HepaticStage,
+		Labs:            patientContext.Labs,
+	}
+	assessment, err := ResolvePatientContext(resolved)
+	if assessment == nil || err != nil {
+		return nil, err
+	}
+
+	patientContext.RenalStage = reconcileStage(assessment, "renal", patientContext.RenalStage,
+		&assessment.RenalStage, &assessment.RenalStageSource)
+	patientContext.HepaticStage = resolved.HepaticFunction
+	if patientContext.AgeBand == "" {
+		patientContext.AgeBand = assessment.AgeBand
+	}
+	return assessment, nil
+}
+
+// ResolvePatientContext derives renal function (eGFR and CrCl) and hepatic
+// function (Child-Pugh class) from the patient's labs when the caller left them
+// empty. Age and weight fall back to the patient context. Returns nil when no
+// labs were supplied.
+func ResolvePatientContext(patientContext *models.PatientContext) (*models.OrganFunctionAssessment, error) {
+	if patientContext == nil || patientContext.Labs == nil {
+		return nil, nil
+	}
+
+	labs := *patientContext.Labs
+	if labs.AgeYears == nil && patientContext.Age > 0 {
+		age := patientContext.Age
+		labs.AgeYears = &age
+	}
+	if labs.WeightKg == nil && patientContext.Weight != nil {
+		weight, _ := patientContext.Weight.Float64()
+		labs.WeightKg = &weight
+	}
+	if patientContext.DialysisModality != "" {
+		labs.OnDialysis = true
+	}
+
+	assessment, err := AssessOrganFunction(labs)
+	if err != nil {
+		return nil, err
+	}
+
+	if patientContext.RenalFunction == nil {
+		if assessment.EGFR != nil {
+			egfr := decimal.NewFromFloat(assessment.EGFR.Value)
+			patientContext.RenalFunction = &egfr
+		}
+		if patientContext.CreatinineClearance == nil && assessment.CrCl != nil {
+			crcl := decimal.NewFromFloat(assessment.CrCl.Value)
+			patientContext.CreatinineClearance = &crcl
+		}
+		if assessment.RenalStage != "" {
+			assessment.RenalStageSource = StageSourceDerived
+		}
+	} else {
+		assessment.Notes = append(assessment.Notes, "Provided renal function used; derived values are informational")
+		if !labs.OnDialysis {
+			egfr, _ := patientContext.RenalFunction.Float64()
+			reconcileStage(assessment, "renal", CKDStage(egfr), &assessment.RenalStage, &assessment.RenalStageSource)
+		} else {
+			assessment.RenalStageSource = StageSourceProvided
+		}
+	}
+	patientContext.HepaticFunction = reconcileStage(assessment, "hepatic", patientContext.HepaticFunction,
+		&assessment.HepaticStage, &assessment.HepaticStageSource)
+	return assessment, nil
+}
+
+// reconcileStage returns the stage to use: the provided stage when set (noting a
+// disagreement with the derived stage), otherwise the derived stage
+func reconcileStage(assessment *models.OrganFunctionAssessment, organ, provided string, derived, source *string) string {
+	if provided == "" {
+		if *derived != "" {
+			*source = StageSourceDerived
+		}
+		return *derived
+	}
+	if *derived != "" && !strings.EqualFold(provided, *derived) {
+		assessment.Notes = append(assessment.Notes, fmt.Sprintf(
+			"Provided %s stage %s differs from derived %s; provided stage used", organ, provided, *derived))
+	}
+	*derived = provided
+	*source = StageSourceProvided
+	return provided
+}
+
+// AssessOrganFunction calculates eGFR, creatinine clearance and Child-Pugh class
+// from raw labs. Calculations whose inputs are missing are skipped with a note;
+// implausible values are reported as *LabInputError.
+func AssessOrganFunction(labs models.PatientLabs) (*models.OrganFunctionAssessment, error) {
+	if err := validatePatientLabs(labs); err != nil {
+		return nil, err
+	}
+
+	assessment := &models.OrganFunctionAssessment{}
+	female, sexKnown := labSex(labs.Sex)
+	if labs.AgeYears != nil {
+		assessment.AgeBand = ageBand(*labs.AgeYears)
+	}
+
+	switch {
+	case labs.OnDialysis:
+		assessment.RenalStage = "ESRD"
+		assessment.Notes = append(assessment.Notes, "Patient on dialysis: staged as ESRD; eGFR and CrCl are not valid")
+	case labs.SerumCreatinine == nil && labs.CystatinC == nil:
+		// No renal labs
+	case labs.AgeYears == nil || !sexKnown:
+		assessment.Notes = append(assessment.Notes, "eGFR and CrCl require age_years and sex")
+	case *labs.AgeYears < 18:
+		assessment.Notes = append(assessment.Notes, "CKD-EPI and Cockcroft-Gault are validated for adults only; renal function not derived")
+	default:
+		assessment.EGFR = estimateGFR(labs, *labs.AgeYears, female)
+		if labs.SerumCreatinine != nil {
+			if labs.WeightKg != nil {
+				assessment.CrCl = creatinineClearance(*labs.SerumCreatinine, *labs.AgeYears, *labs.WeightKg, female)
+			} else {
+				assessment.Notes = append(assessment.Notes, "CrCl requires weight_kg")
+			}
+		}
+		switch {
+		case assessment.EGFR != nil:
+			assessment.RenalStage = assessment.EGFR.Stage
+		case assessment.CrCl != nil:
+			assessment.RenalStage = assessment.CrCl.Stage
+			assessment.Notes = append(assessment.Notes, "Renal stage derived from CrCl; no eGFR available")
+		}
+	}
+
+	childPugh, missing := childPughScore(labs)
+	if childPugh != nil {
+		assessment.ChildPugh = childPugh
+		assessment.HepaticStage = childPugh.Stage
+	} else if len(missing) > 0 && len(missing) < 5 {
+		assessment.Notes = append(assessment.Notes,
+			fmt.Sprintf("Child-Pugh class not derived; missing %s", strings.Join(missing, ", ")))
+	}
+
+	return assessment, nil
+}
+
+// validatePatientLabs rejects values outside physiologically plausible ranges
+func validatePatientLabs(labs models.PatientLabs) error {
+	ranges := []struct {
+		field    string
+		value    *float64
+		min, max float64
+	}{
+		{"serum_creatinine_mg_dl", labs.SerumCreatinine, 0.1, 25},
+		{"cystatin_c_mg_l", labs.CystatinC, 0.2, 10},
+		{"weight_kg", labs.WeightKg, 0.5, 400},
+		{"total_bilirubin_mg_dl", labs.TotalBilirubin, 0, 60},
+		{"albumin_g_dl", labs.Albumin, 0.5, 7},
+		{"inr", labs.INR, 0.5, 15},
+	}
+	for _, r := range ranges {
+		if r.value != nil && (*r.value < r.min || *r.value > r.max) {
+			return &LabInputError{Field: r.field, Reason: fmt.Sprintf("must be between %g and %g", r.min, r.max)}
+		}
+	}
+	if labs.AgeYears != nil && (*labs.AgeYears < 0 || *labs.AgeYears > 120) {
+		return &LabInputError{Field: "age_years", Reason: "must be between 0 and 120"}
+	}
+	if _, known := labSex(labs.Sex); !known && labs.Sex != "" {
+		return &LabInputError{Field: "sex", Reason: "must be male or female"}
+	}
+	if labs.Encephalopathy != nil && (*labs.Encephalopathy < 0 || *labs.Encephalopathy > 4) {
+		return &LabInputError{Field: "encephalopathy_grade", Reason: "must be a West Haven grade 0-4"}
+	}
+	if _, ok := ascitesPoints(labs.Ascites); !ok && labs.Ascites != "" {
+		return &LabInputError{Field: "ascites", Reason: "must be none, mild or moderate_severe"}
+	}
+	return nil
+}
+
+// estimateGFR applies the most specific CKD-EPI equation the labs allow
+func estimateGFR(labs models.PatientLabs, age int, female bool) *models.LabDerivation {
+	inputs := map[string]interface{}{"age_years": age, "sex": sexLabel(female)}
+	var value float64
+	var formula string
+
+	switch {
+	case labs.SerumCreatinine != nil && labs.CystatinC != nil:
+		value = CKDEPI2021CreatinineCystatin(*labs.SerumCreatinine, *labs.CystatinC, age, female)
+		formula = FormulaCKDEPI2021CreatinineCystatin
+		inputs["serum_creatinine_mg_dl"] = *labs.SerumCreatinine
+		inputs["cystatin_c_mg_l"] = *labs.CystatinC
+	case labs.SerumCreatinine != nil:
+		value = CKDEPI2021Creatinine(*labs.SerumCreatinine, age, female)
+		formula = FormulaCKDEPI2021Creatinine
+		inputs["serum_creatinine_mg_dl"] = *labs.SerumCreatinine
+	case labs.CystatinC != nil:
+		value = CKDEPI2012Cystatin(*labs.CystatinC, age, female)
+		formula = FormulaCKDEPI2012Cystatin
+		inputs["cystatin_c_mg_l"] = *labs.CystatinC
+	default:
+		return nil
+	}
+
+	value = math.Round(value)
+	return &models.LabDerivation{
+		Value:   value,
+		Unit:    "mL/min/1.73m²",
+		Formula: formula,
+		Inputs:  inputs,
+		Stage:   CKDStage(value),
+	}
+}
+
+// creatinineClearance applies Cockcroft-Gault
+func creatinineClearance(scr float64, age int, weightKg float64, female bool) *models.LabDerivation {
+	value := math.Round(CockcroftGault(scr, age, weightKg, female)*10) / 10
+	return &models.LabDerivation{
+		Value:   value,
+		Unit:    "mL/min",
+		Formula: FormulaCockcroftGault,
+		Inputs: map[string]interface{}{
+			"serum_creatinine_mg_dl": scr,
+			"age_years":              age,
+			"weight_kg":              weightKg,
+			"sex":                    sexLabel(female),
+		},
+		Stage: CKDStage(value),
+	}
+}
+
+// CKDEPI2021Creatinine estimates GFR (mL/min/1.73m²) from serum creatinine (mg/dL):
+// 142 × min(Scr/κ,1)^α × max(Scr/κ,1)^-1.200 × 0.9938^age [× 1.012 female]
+func CKDEPI2021Creatinine(scr float64, age int, female bool) float64 {
+	kappa, alpha, sexFactor := 0.9, -0.302, 1.0
+	if female {
+		kappa, alpha, sexFactor = 0.7, -0.241, 1.012
+	}
+	ratio := scr / kappa
+	return 142 * math.Pow(math.Min(ratio, 1), alpha) * math.Pow(math.Max(ratio, 1), -1.200) *
+		math.Pow(0.9938, float64(age)) * sexFactor
+}
+
+// CKDEPI2021CreatinineCystatin estimates GFR from serum creatinine (mg/dL) and
+// cystatin C (mg/L):
+// 135 × min(Scr/κ,1)^α × max(Scr/κ,1)^-0.544 × min(Scys/0.8,1)^-0.323 ×
+// max(Scys/0.8,1)^-0.778 × 0.9961^age [× 0.963 female]
+func CKDEPI2021CreatinineCystatin(scr, scys float64, age int, female bool) float64 {
+	kappa, alpha, sexFactor := 0.9, -0.144, 1.0
+	if female {
+		kappa, alpha, sexFactor = 0.7, -0.219, 0.963
+	}
+	ratio := scr / kappa
+	cys := scys / 0.8
+	return 135 * math.Pow(math.Min(ratio, 1), alpha) * math.Pow(math.Max(ratio, 1), -0.544) *
+		math.Pow(math.Min(cys, 1), -0.323) * math.Pow(math.Max(cys, 1), -0.778) *
+		math.Pow(0.9961, float64(age)) * sexFactor
+}
+
+// CKDEPI2012Cystatin estimates GFR from cystatin C (mg/L):
+// 133 × min(Scys/0.8,1)^-0.499 × max(Scys/0.8,1)^-1.328 × 0.996^age [× 0.932 female]
+func CKDEPI2012Cystatin(scys float64, age int, female bool) float64 {
+	sexFactor := 1.0
+	if female {
+		sexFactor = 0.932
+	}
+	cys := scys / 0.8
+	return 133 * math.Pow(math.Min(cys, 1), -0.499) * math.Pow(math.Max(cys, 1), -1.328) *
+		math.Pow(0.996, float64(age)) * sexFactor
+}
+
+// CockcroftGault estimates creatinine clearance (mL/min):
+// (140 - age) × weight / (72 × Scr) [× 0.85 female]
+func CockcroftGault(scr float64, age int, weightKg float64, female bool) float64 {
+	crcl := float64(140-age) * weightKg / (72 * scr)
+	if female {
+		crcl *= 0.85
+	}
+	return crcl
+}
+
+// CKDStage maps a GFR to a KDIGO stage
+func CKDStage(gfr float64) string {
+	switch {
+	case gfr >= 90:
+		return "CKD_1"
+	case gfr >= 60:
+		return "CKD_2"
+	case gfr >= 45:
+		return "CKD_3a"
+	case gfr >= 30:
+		return "CKD_3b"
+	case gfr >= 15:
+		return "CKD_4"
+	}
+	return "CKD_5"
+}
+
+// ckdDiagnosisCode maps a CKD stage to its ICD-10-CM code. Stages 1-2 need
+// evidence of kidney damage beyond eGFR and are not mapped.
+func ckdDiagnosisCode(stage string) string {
+	codes := map[string]string{
+		"CKD_3":  "N1830",
+		"CKD_3A": "N1831",
+		"CKD_3B": "N1832",
+		"CKD_4":  "N184",
+		"CKD_5":  "N185",
+		"ESRD":   "N186",
+	}
+	return codes[strings.ToUpper(stage)]
+}
+
+// childPughScore scores the five Child-Pugh criteria. It returns the missing
+// criteria when the score cannot be completed.
+func childPughScore(labs models.PatientLabs) (*models.LabDerivation, []string) {
+	components := make(map[string]int, 5)
+	inputs := make(map[string]interface{}, 5)
+	var missing []string
+
+	if labs.TotalBilirubin != nil {
+		inputs["total_bilirubin_mg_dl"] = *labs.TotalBilirubin
+		components["bilirubin"] = graded(*labs.TotalBilirubin < 2, *labs.TotalBilirubin <= 3)
+	} else {
+		missing = append(missing, "total_bilirubin_mg_dl")
+	}
+	if labs.Albumin != nil {
+		inputs["albumin_g_dl"] = *labs.Albumin
+		components["albumin"] = graded(*labs.Albumin > 3.5, *labs.Albumin >= 2.8)
+	} else {
+		missing = append(missing, "albumin_g_dl")
+	}
+	if labs.INR != nil {
+		inputs["inr"] = *labs.INR
+		components["inr"] = graded(*labs.INR < 1.7, *labs.INR <= 2.3)
+	} else {
+		missing = append(missing, "inr")
+	}
+	if points, ok := ascitesPoints(labs.Ascites); ok {
+		inputs["ascites"] = labs.Ascites
+		components["ascites"] = points
+	} else {
+		missing = append(missing, "ascites")
+	}
+	if labs.Encephalopathy != nil {
+		inputs["encephalopathy_grade"] = *labs.Encephalopathy
+		components["encephalopathy"] = graded(*labs.Encephalopathy == 0, *labs.Encephalopathy <= 2)
+	} else {
+		missing = append(missing, "encephalopathy_grade")
+	}
+
+	if len(missing) > 0 {
+		return nil, missing
+	}
+
+	total := 0
+	for _, points := range components {
+		total += points
+	}
+	stage := "ChildPugh_C"
+	switch {
+	case total <= 6:
+		stage = "ChildPugh_A"
+	case total <= 9:
+		stage = "ChildPugh_B"
+	}
+
+	return &models.LabDerivation{
+		Value:      float64(total),
+		Unit:       "points",
+		Formula:    FormulaChildPugh,
+		Inputs:     inputs,
+		Components: components,
+		Stage:      stage,
+	}, nil
+}
+
+// graded returns 1 point for the best band, 2 for the middle band and 3 otherwise
+func graded(best, middle bool) int {
+	switch {
+	case best:
+		return 1
+	case middle:
+		return 2
+	}
+	return 3
+}
+
+// ascitesPoints scores an ascites grade
+func ascitesPoints(grade string) (int, bool) {
+	switch strings.ToLower(strings.NewReplacer("-", "_", " ", "_").Replace(strings.TrimSpace(grade))) {
+	case "none", "absent":
+		return 1, true
+	case "mild", "slight", "controlled":
+		return 2, true
+	case "moderate_severe", "moderate", "severe", "refractory":
+		return 3, true
+	}
+	return 0, false
+}
+
+// labSex parses sex, reporting whether it is known
+func labSex(sex string) (female, known bool) {
+	switch strings.ToLower(strings.TrimSpace(sex)) {
+	case "female", "f":
+		return true, true
+	case "male", "m":
+		return false, true
+	}
+	return false, false
+}
+
+func sexLabel(female bool) string {
+	if female {
+		return "female"
+	}
+	return "male"
+}
+
+// ageBand maps an age to the governance age band
+func ageBand(age int) string {
+	switch {
+	case age < 18:
+		return "pediatric"
+	case age >= 65:
+		return "older_adult"
+	}
+	return "adult"
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// ORGAN FUNCTION DERIVATION TESTS
+// ============================================================================
+
+func TestCKDEPIEquations(t *testing.T) {
+	assert.InDelta(t, 64.5, CKDEPI2021Creatinine(1.0, 60, true), 0.1)
+	assert.InDelta(t, 86.2, CKDEPI2021Creatinine(1.0, 60, false), 0.1)
+	assert.InDelta(t, 35.2, CKDEPI2021Creatinine(2.0, 70, false), 0.1)
+	assert.InDelta(t, 71.2, CKDEPI2021CreatinineCystatin(1.0, 1.0, 60, true), 0.1)
+	assert.InDelta(t, 35.4, CKDEPI2021CreatinineCystatin(2.0, 1.8, 70, false), 0.1)
+	assert.InDelta(t, 38.9, CockcroftGault(2.0, 70, 80, false), 0.1)
+	assert.InDelta(t, 56.7, CockcroftGault(1.0, 60, 60, true), 0.1)
+}
+
+func TestCKDStage(t *testing.T) {
+	assert.Equal(t, "CKD_1", CKDStage(95))
+	assert.Equal(t, "CKD_2", CKDStage(60))
+	assert.Equal(t, "CKD_3a", CKDStage(59))
+	assert.Equal(t, "CKD_3b", CKDStage(35))
+	assert.Equal(t, "CKD_4", CKDStage(15))
+	assert.Equal(t, "CKD_5", CKDStage(14.9))
+}
+
+func TestAssessOrganFunction_Renal(t *testing.T) {
+	assessment, err := AssessOrganFunction(models.PatientLabs{
+		SerumCreatinine: floatPtr(2.0),
+		AgeYears:        intPtr(70),
+		Sex:             "M",
+		WeightKg:        floatPtr(80),
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, assessment.EGFR)
+	assert.Equal(t, 35.0, assessment.EGFR.Value)
+	assert.Equal(t, FormulaCKDEPI2021Creatinine, assessment.EGFR.Formula)
+	assert.Equal(t, 2.0, assessment.EGFR.Inputs["serum_creatinine_mg_dl"])
+	require.NotNil(t, assessment.CrCl)
+	assert.Equal(t, 38.9, assessment.CrCl.Value)
+	assert.Equal(t, "CKD_3b", assessment.RenalStage)
+	assert.Equal(t, "older_adult", assessment.AgeBand)
+	assert.Nil(t, assessment.ChildPugh)
+	assert.Empty(t, assessment.Notes)
+}
+
+func TestAssessOrganFunction_RenalNotDerived(t *testing.T) {
+	assessment, err := AssessOrganFunction(models.PatientLabs{SerumCreatinine: floatPtr(1.0), AgeYears: intPtr(50)})
+	require.NoError(t, err)
+	assert.Nil(t, assessment.EGFR)
+	assert.Empty(t, assessment.RenalStage)
+	assert.Contains(t, assessment.Notes, "eGFR and CrCl require age_years and sex")
+
+	assessment, err = AssessOrganFunction(models.PatientLabs{SerumCreatinine: floatPtr(6.0), OnDialysis: true})
+	require.NoError(t, err)
+	assert.Equal(t, "ESRD", assessment.RenalStage)
+	assert.Nil(t, assessment.EGFR)
+}
+
+func TestAssessOrganFunction_ChildPugh(t *testing.T) {
+	assessment, err := AssessOrganFunction(models.PatientLabs{
+		TotalBilirubin: floatPtr(2.5), // 2
+		Albumin:        floatPtr(3.0), // 2
+		INR:            floatPtr(1.5), // 1
+		Ascites:        "mild",        // 2
+		Encephalopathy: intPtr(0),     // 1
+	})
+	require.NoError(t, err)
+	require.NotNil(t, assessment.ChildPugh)
+	assert.Equal(t, 8.0, assessment.ChildPugh.Value)
+	assert.Equal(t, "ChildPugh_B", assessment.HepaticStage)
+	assert.Equal(t, 2, assessment.ChildPugh.Components["bilirubin"])
+
+	assessment, err = AssessOrganFunction(models.PatientLabs{TotalBilirubin: floatPtr(4), Albumin: floatPtr(2.5)})
+	require.NoError(t, err)
+	assert.Nil(t, assessment.ChildPugh)
+	assert.Empty(t, assessment.HepaticStage)
+	require.Len(t, assessment.Notes, 1)
+	assert.Contains(t, assessment.Notes[0], "inr, ascites, encephalopathy_grade")
+}
+
+func TestAssessOrganFunction_InvalidInput(t *testing.T) {
+	_, err := AssessOrganFunction(models.PatientLabs{SerumCreatinine: floatPtr(-1)})
+	var inputErr *LabInputError
+	require.True(t, errors.As(err, &inputErr))
+	assert.Equal(t, "serum_creatinine_mg_dl", inputErr.Field)
+
+	_, err = AssessOrganFunction(models.PatientLabs{Ascites: "large"})
+	require.True(t, errors.As(err, &inputErr))
+	assert.Equal(t, "ascites", inputErr.Field)
+}
+
+func TestResolvePatientContextData(t *testing.T) {
+	patientContext := &models.PatientContextData{
+		HepaticStage: "ChildPugh_A",
+		Labs: &models.PatientLabs{
+			SerumCreatinine: floatPtr(2.0), AgeYears: intPtr(70), Sex: "male",
+			TotalBilirubin: floatPtr(4), Albumin: floatPtr(2.5), INR: floatPtr(2.5),
+			Ascites: "moderate_severe", Encephalopathy: intPtr(3),
+		},
+	}
+	assessment, err := ResolvePatientContextData(patientContext)
+	require.NoError(t, err)
+
+	assert.Equal(t, "CKD_3b", patientContext.RenalStage)
+	assert.Equal(t, StageSourceDerived, assessment.RenalStageSource)
+	assert.Equal(t, "ChildPugh_A", patientContext.HepaticStage)
+	assert.Equal(t, StageSourceProvided, assessment.HepaticStageSource)
+	assert.Equal(t, "older_adult", patientContext.AgeBand)
+	assert.Contains(t, assessment.Notes, "Provided hepatic stage ChildPugh_A differs from derived ChildPugh_C; provided stage used")
+
+	assessment, err = ResolvePatientContextData(&models.PatientContextData{RenalStage: "CKD_2"})
+	assert.NoError(t, err)
+	assert.Nil(t, assessment)
+}
+
+func TestResolvePatientContext(t *testing.T) {
+	weight := decimal.NewFromInt(80)
+	patientContext := &models.PatientContext{
+		Age:    70,
+		Weight: &weight,
+		Labs:   &models.PatientLabs{SerumCreatinine: floatPtr(2.0), Sex: "male"},
+	}
+	assessment, err := ResolvePatientContext(patientContext)
+	require.NoError(t, err)
+
+	require.NotNil(t, patientContext.RenalFunction)
+	assert.Equal(t, "35", patientContext.RenalFunction.String())
+	require.NotNil(t, assessment.CrCl)
+	assert.Equal(t, 38.9, assessment.CrCl.Value)
+	assert.Equal(t, "N1832", ckdDiagnosisCode(assessment.RenalStage))
+	assert.Empty(t, ckdDiagnosisCode("CKD_2"))
+}
+
+func TestResolvePatientContext_ProvidedRenalFunction(t *testing.T) {
+	// Provided eGFR of 65 is CKD_2; the labs alone would stage CKD_3b
+	egfr := decimal.NewFromInt(65)
+	patientContext := &models.PatientContext{
+		Age:           70,
+		RenalFunction: &egfr,
+		Labs:          &models.PatientLabs{SerumCreatinine: floatPtr(2.0), Sex: "male"},
+	}
+	assessment, err := ResolvePatientContext(patientContext)
+	require.NoError(t, err)
+
+	assert.Equal(t, "CKD_2", assessment.RenalStage)
+	assert.Equal(t, StageSourceProvided, assessment.RenalStageSource)
+	assert.Equal(t, "65", patientContext.RenalFunction.String())
+	require.NotNil(t, assessment.EGFR)
+	assert.Equal(t, "CKD_3b", assessment.EGFR.Stage, "derived values stay informational")
+	assert.Contains(t, assessment.Notes, "Provided renal stage CKD_2 differs from derived CKD_3b; provided stage used")
+}
+
+func TestGovernanceEscalation_DerivedStages(t *testing.T) {
+	assert.True(t, isRenalImpaired("CKD_3b"))
+	assert.True(t, isRenalImpaired("ckd_4"))
+	assert.False(t, isRenalImpaired("CKD_2"))
+	assert.True(t, isHepaticImpaired("childpugh_b"))
+	assert.False(t, isHepaticImpaired("ChildPugh_A"))
+}
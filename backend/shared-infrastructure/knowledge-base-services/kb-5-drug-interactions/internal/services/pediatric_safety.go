@@ -0,0 +1,697 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kb-drug-interactions/internal/database"
+	"kb-drug-interactions/internal/metrics"
+	"kb-drug-interactions/internal/models"
+)
+
+// Pediatric rule types (pediatric_safety_rules.rule_type)
+const (
+	PediatricRuleContraindication = "contraindication" // Drug in the age/weight range
+	PediatricRuleInteraction      = "interaction"      // Drug with an interacting drug in the age/weight range
+	PediatricRuleDoseLimit        = "dose_limit"       // mg/kg and absolute maximums, checked against orders
+)
+
+// Pediatric age bands (PediatricPatient.AgeBand when the exact age is known)
+const (
+	PediatricBandNeonate    = "neonate"    // Under 28 days
+	PediatricBandInfant     = "infant"     // Under 1 year
+	PediatricBandChild      = "child"      // Under 12 years
+	PediatricBandAdolescent = "adolescent" // Under 18 years
+)
+
+// Age conversions to days, matching pediatric_safety_rules age bounds
+const (
+	daysPerYear  = 365.25
+	daysPerMonth = 30.4375
+)
+
+// PediatricSafetyRule gives a drug's pediatric contraindication, interaction or
+// dose limit in one age and weight range
+type PediatricSafetyRule struct {
+	ID                     int                  `json:"id" gorm:"primaryKey"`
+	RuleCode               string               `json:"rule_code"`
+	DrugCode               string               `json:"drug_code"`
+	DrugName               string               `json:"drug_name"`
+	RuleType               string               `json:"rule_type"`
+	MinAgeDays             *int                 `json:"min_age_days,omitempty"`  // Inclusive
+	MaxAgeDays             *int                 `json:"max_age_days,omitempty"`  // Exclusive
+	MinWeightKg            *float64             `json:"min_weight_kg,omitempty"` // Inclusive
+	MaxWeightKg            *float64             `json:"max_weight_kg,omitempty"` // Exclusive
+	InteractingDescription *string              `json:"interacting_description,omitempty"`
+	InteractingDrugCodes   models.StringArray   `json:"interacting_drug_codes,omitempty" gorm:"type:text[]"`
+	InteractingATCClasses  models.StringArray   `json:"interacting_atc_classes,omitempty" gorm:"column:interacting_atc_classes;type:text[]"`
+	MaxSingleDoseMgPerKg   *float64             `json:"max_single_dose_mg_per_kg,omitempty"`
+	MaxDailyDoseMgPerKg    *float64             `json:"max_daily_dose_mg_per_kg,omitempty"`
+	MaxSingleDoseMg        *float64             `json:"max_single_dose_mg,omitempty"`
+	MaxDailyDoseMg         *float64             `json:"max_daily_dose_mg,omitempty"`
+	Severity               models.DDISeverity   `json:"severity"`
+	GovernanceAction       *string              `json:"governance_action,omitempty"`
+	RiskSummary            string               `json:"risk_summary"`
+	Recommendation         string               `json:"recommendation"`
+	Alternatives           models.StringArray   `json:"alternatives,omitempty" gorm:"type:text[]"`
+	Evidence               models.EvidenceLevel `json:"evidence"`
+	Source                 string               `json:"source"`
+	Active                 bool                 `json:"active"`
+}
+
+// TableName specifies the database table for GORM
+func (PediatricSafetyRule) TableName() string {
+	return "pediatric_safety_rules"
+}
+
+// PediatricInputError reports an invalid patient age or weight
+type PediatricInputError struct {
+	Field  string
+	Reason string
+}
+
+func (e *PediatricInputError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// PediatricPatient is the exact age and weight pediatric rules are matched against
+type PediatricPatient struct {
+	AgeDays  *int     `json:"age_days,omitempty"`
+	AgeBand  string   `json:"age_band,omitempty"` // neonate, infant, child, adolescent, adult; "pediatric" when only the band is known
+	WeightKg *float64 `json:"weight_kg,omitempty"`
+}
+
+// PediatricSafetyRequest checks a regimen and its orders against pediatric rules
+type PediatricSafetyRequest struct {
+	DrugCodes        []string                 `json:"drug_codes,omitempty"`
+	MedicationOrders []models.MedicationOrder `json:"medication_orders,omitempty"` // Doses for weight-based limits
+	PatientContext   *models.PatientContext   `json:"patient_context" binding:"required"`
+}
+
+// PediatricSafetyResponse is the outcome of a pediatric safety check
+type PediatricSafetyResponse struct {
+	Patient  PediatricPatient                   `json:"patient"`
+	Findings []models.EnhancedInteractionResult `json:"findings"`
+	Notes    []string                           `json:"notes,omitempty"`
+}
+
+// PediatricSafetyEngine checks regimens against age- and weight-specific pediatric rules
+type PediatricSafetyEngine struct {
+	db       *database.Database
+	atcIndex *ATCClassIndex
+	metrics  *metrics.Collector
+
+	// Rules keyed by normalized drug code
+	rules       map[string][]PediatricSafetyRule
+	rulesLoaded time.Time
+	cacheTTL    time.Duration
+	mu          sync.Mutex
+}
+
+// NewPediatricSafetyEngine creates a new pediatric contraindication engine.
+// Interacting drug classes match through the ATC index; without it only listed
+// drug codes match.
+func NewPediatricSafetyEngine(db *database.Database, atcIndex *ATCClassIndex, metrics *metrics.Collector) *PediatricSafetyEngine {
+	return &PediatricSafetyEngine{
+		db:       db,
+		atcIndex: atcIndex,
+		metrics:  metrics,
+		cacheTTL: 30 * time.Minute,
+	}
+}
+
+// CheckRegimen resolves the patient's age and weight and evaluates the regimen.
+// Invalid age or weight is reported as *PediatricInputError.
+func (pse *PediatricSafetyEngine) CheckRegimen(ctx context.Context, request PediatricSafetyRequest) (*PediatricSafetyResponse, error) {
+	if len(request.DrugCodes) == 0 && len(request.MedicationOrders) == 0 {
+		return nil, &PediatricInputError{Field: "drug_codes", Reason: "drug_codes or medication_orders required"}
+	}
+	patient, err := PediatricPatientFromContext(request.PatientContext)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PediatricSafetyResponse{Patient: patient, Findings: []models.EnhancedInteractionResult{}}
+	if !patient.MayBePediatric() {
+		response.Notes = []string{"Patient is not pediatric; pediatric rules not applied"}
+		return response, nil
+	}
+
+	response.Findings, response.Notes, err = pse.EvaluatePediatricSafety(ctx, request.DrugCodes, request.MedicationOrders, patient)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// EvaluatePediatricSafety checks the regimen and orders against pediatric rules for
+// the patient's age and weight. Findings carry a "governance_action" qualifier, when
+// the rule sets one, that the governance policy engine treats as the minimum action.
+func (pse *PediatricSafetyEngine) EvaluatePediatricSafety(
+	ctx context.Context,
+	drugCodes []string,
+	orders []models.MedicationOrder,
+	patient PediatricPatient,
+) ([]models.EnhancedInteractionResult, []string, error) {
+	if !patient.MayBePediatric() || (len(drugCodes) == 0 && len(orders) == 0) {
+		return []models.EnhancedInteractionResult{}, nil, nil
+	}
+
+	rules, err := pse.loadRules(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load pediatric safety rules: %w", err)
+	}
+
+	findings, notes := evaluatePediatricRules(rules, drugCodes, orders, patient, pse.drugClasses)
+	for _, f := range findings {
+		pse.metrics.RecordPediatricSafetyMatch(f.Qualifiers["rule_type"], f.Qualifiers["governance_action"])
+	}
+	return findings, notes, nil
+}
+
+// ResolvePediatricPatient converts the most precise age given (days, then months,
+// then years) to days and validates age and weight. Invalid values are reported
+// as *PediatricInputError.
+func ResolvePediatricPatient(ageDays, ageMonths, ageYears *int, weightKg *float64) (PediatricPatient, error) {
+	var patient PediatricPatient
+	switch {
+	case ageDays != nil:
+		if *ageDays < 0 || *ageDays > 120*daysPerYear {
+			return patient, &PediatricInputError{Field: "age_days", Reason: "must be between 0 and 43830"}
+		}
+		days := *ageDays
+		patient.AgeDays = &days
+	case ageMonths != nil:
+		if *ageMonths < 0 || *ageMonths > 1440 {
+			return patient, &PediatricInputError{Field: "age_months", Reason: "must be between 0 and 1440"}
+		}
+		days := int(float64(*ageMonths) * daysPerMonth)
+		patient.AgeDays = &days
+	case ageYears != nil:
+		if *ageYears < 0 || *ageYears > 120 {
+			return patient, &PediatricInputError{Field: "age_years", Reason: "must be between 0 and 120"}
+		}
+		days := yearsToDays(*ageYears)
+		patient.AgeDays = &days
+	}
+	if patient.AgeDays != nil {
+		patient.AgeBand = PediatricAgeBand(*patient.AgeDays)
+	}
+
+	if weightKg != nil {
+		if *weightKg < 0.2 || *weightKg > 400 {
+			return patient, &PediatricInputError{Field: "weight_kg", Reason: "must be between 0.2 and 400"}
+		}
+		weight := *weightKg
+		patient.WeightKg = &weight
+	}
+	return patient, nil
+}
+
+// PediatricPatientFromContext reads age and weight from a patient context, falling
+// back to the age and weight in its labs
+func PediatricPatientFromContext(patientContext *models.PatientContext) (PediatricPatient, error) {
+	if patientContext == nil {
+		return PediatricPatient{}, nil
+	}
+
+	var ageYears *int
+	var weightKg *float64
+	if patientContext.Labs != nil {
+		ageYears = patientContext.Labs.AgeYears
+		weightKg = patientContext.Labs.WeightKg
+	}
+	if patientContext.Age > 0 {
+		age := patientContext.Age
+		ageYears = &age
+	}
+	if patientContext.Weight != nil {
+		weight, _ := patientContext.Weight.Float64()
+		weightKg = &weight
+	}
+	return ResolvePediatricPatient(patientContext.AgeDays, patientContext.AgeMonths, ageYears, weightKg)
+}
+
+// PediatricPatientFromContextData reads age and weight from a governance patient
+// context. Without an exact age, an age band of "pediatric" is kept.
+func PediatricPatientFromContextData(patientContext *models.PatientContextData) (PediatricPatient, error) {
+	if patientContext == nil {
+		return PediatricPatient{}, nil
+	}
+
+	var ageYears *int
+	weightKg := patientContext.WeightKg
+	if patientContext.Labs != nil {
+		ageYears = patientContext.Labs.AgeYears
+		if weightKg == nil {
+			weightKg = patientContext.Labs.WeightKg
+		}
+	}
+	patient, err := ResolvePediatricPatient(patientContext.AgeDays, patientContext.AgeMonths, ageYears, weightKg)
+	if err != nil {
+		return patient, err
+	}
+	if patient.AgeDays == nil && patientContext.AgeBand == "pediatric" {
+		patient.AgeBand = "pediatric"
+	}
+	return patient, nil
+}
+
+// PediatricAgeBand returns the pediatric age band for an age in days
+func PediatricAgeBand(ageDays int) string {
+	switch {
+	case ageDays < 28:
+		return PediatricBandNeonate
+	case ageDays < yearsToDays(1):
+		return PediatricBandInfant
+	case ageDays < yearsToDays(12):
+		return PediatricBandChild
+	case ageDays < yearsToDays(18):
+		return PediatricBandAdolescent
+	}
+	return "adult"
+}
+
+// yearsToDays converts completed years to days
+func yearsToDays(years int) int {
+	return int(float64(years) * daysPerYear)
+}
+
+// IsPediatric reports whether the patient is under 18
+func (p PediatricPatient) IsPediatric() bool {
+	if p.AgeDays != nil {
+		return *p.AgeDays < yearsToDays(18)
+	}
+	return p.AgeBand == "pediatric"
+}
+
+// MayBePediatric reports whether pediatric rules can apply: the patient is under
+// 18 or their age is unknown. A context with age 0 and no age in days or months
+// is indistinguishable from one without an age, and may be a neonate.
+func (p PediatricPatient) MayBePediatric() bool {
+	return p.IsPediatric() || p.AgeDays == nil
+}
+
+// AgeYears returns the patient's completed years of age, or nil when unknown
+func (p PediatricPatient) AgeYears() *int {
+	if p.AgeDays == nil {
+		return nil
+	}
+	years := completedUnits(*p.AgeDays, daysPerYear)
+	return &years
+}
+
+// describe summarizes the patient's age and weight, e.g. "Neonate (12 days, 3.4 kg)"
+func (p PediatricPatient) describe() string {
+	var parts []string
+	if p.AgeDays != nil {
+		parts = append(parts, describeAgeDays(*p.AgeDays, false))
+	}
+	if p.WeightKg != nil {
+		parts = append(parts, fmt.Sprintf("%g kg", *p.WeightKg))
+	}
+	band := p.AgeBand
+	if band == "" {
+		band = "pediatric"
+	}
+	band = strings.ToUpper(band[:1]) + band[1:]
+	if len(parts) == 0 {
+		return band
+	}
+	return fmt.Sprintf("%s (%s)", band, strings.Join(parts, ", "))
+}
+
+// describeAgeDays formats an age in days, months or years. Patient ages are
+// completed units; rule bounds round back to the unit they were written in.
+func describeAgeDays(days int, bound bool) string {
+	convert := completedUnits
+	if bound {
+		convert = func(days int, unitDays float64) int { return int(math.Round(float64(days) / unitDays)) }
+	}
+	switch {
+	case days < 60:
+		return fmt.Sprintf("%d days", days)
+	case days < yearsToDays(2):
+		return fmt.Sprintf("%d months", convert(days, daysPerMonth))
+	}
+	return fmt.Sprintf("%d years", convert(days, daysPerYear))
+}
+
+// completedUnits returns the completed months or years in an age in days,
+// the inverse of the truncating conversion to days
+func completedUnits(days int, unitDays float64) int {
+	n := int(float64(days) / unitDays)
+	if int(float64(n+1)*unitDays) <= days {
+		n++
+	}
+	return n
+}
+
+// evaluatePediatricRules matches rules (keyed by normalized drug code) against the
+// regimen, its orders and the patient's age and weight. Rules whose bounds need an
+// unknown age or weight are skipped with a note. classesFor returns a drug's ATC
+// codes at every level.
+func evaluatePediatricRules(
+	rules map[string][]PediatricSafetyRule,
+	drugCodes []string,
+	orders []models.MedicationOrder,
+	patient PediatricPatient,
+	classesFor func(drugCode string) []string,
+) ([]models.EnhancedInteractionResult, []string) {
+	findings := []models.EnhancedInteractionResult{}
+	var notes []string
+
+	var drugs []regimenDrug
+	seenDrugs := make(map[string]bool)
+	addDrug := func(code string) {
+		key := normalizeATCDrugKey(code)
+		if code == "" || seenDrugs[key] {
+			return
+		}
+		seenDrugs[key] = true
+		drugs = append(drugs, regimenDrug{Code: code, Key: key, Classes: classesFor(code)})
+	}
+	for _, code := range drugCodes {
+		addDrug(code)
+	}
+	for _, order := range orders {
+		addDrug(order.DrugCode)
+	}
+
+	noted := make(map[string]bool)
+	note := func(text string) {
+		if !noted[text] {
+			noted[text] = true
+			notes = append(notes, text)
+		}
+	}
+
+	for _, drug := range drugs {
+		for _, rule := range rules[drug.Key] {
+			applies, missing := rule.appliesTo(patient)
+			// Without an age the patient may be a neonate: rules that apply from birth
+			// are reported at warning level rather than skipped
+			ageUnknown := missing == "age" && rule.appliesFromBirth() && rule.RuleType != PediatricRuleDoseLimit
+			if missing != "" && !ageUnknown {
+				note(fmt.Sprintf("%s not evaluated: %s unknown", rule.RuleCode, missing))
+				continue
+			}
+			if !applies && !ageUnknown {
+				continue
+			}
+
+			switch rule.RuleType {
+			case PediatricRuleContraindication:
+				finding := pediatricFinding(rule, drug.Code, patient)
+				finding.InteractionID = fmt.Sprintf("PED_%s_%s", rule.RuleCode, drug.Key)
+				if ageUnknown {
+					unknownAgeFinding(&finding, rule)
+				}
+				findings = append(findings, finding)
+
+			case PediatricRuleInteraction:
+				for _, other := range drugs {
+					if other.Key == drug.Key || !regimenDrugMatches(other, rule.InteractingDrugCodes, rule.InteractingATCClasses) {
+						continue
+					}
+					finding := pediatricFinding(rule, drug.Code, patient)
+					finding.InteractionID = fmt.Sprintf("PED_%s_%s_%s", rule.RuleCode, drug.Key, other.Key)
+					finding.Drug2 = models.DrugInfo{Code: other.Code, Name: derefString(rule.InteractingDescription)}
+					if ageUnknown {
+						unknownAgeFinding(&finding, rule)
+					}
+					findings = append(findings, finding)
+				}
+
+			case PediatricRuleDoseLimit:
+				for i, order := range orders {
+					if normalizeATCDrugKey(order.DrugCode) != drug.Key {
+						continue
+					}
+					finding, issues := checkPediatricDose(rule, order, patient)
+					for _, issue := range issues {
+						note(issue)
+					}
+					if finding != nil {
+						orderRef := order.OrderID
+						if orderRef == "" {
+							orderRef = strconv.Itoa(i + 1)
+						}
+						finding.InteractionID = fmt.Sprintf("PED_%s_%s_%s", rule.RuleCode, drug.Key, orderRef)
+						findings = append(findings, *finding)
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return pediatricFindingRank(findings[i]) > pediatricFindingRank(findings[j])
+	})
+	return findings, notes
+}
+
+// pediatricFindingRank orders findings by governance floor, then severity
+func pediatricFindingRank(finding models.EnhancedInteractionResult) int {
+	return 10*models.GovernanceAction(finding.Qualifiers["governance_action"]).Priority() +
+		finding.Severity.GetPriority()
+}
+
+// unknownAgeFinding downgrades a finding for a patient of unknown age to a
+// warning asking for the age to be confirmed
+func unknownAgeFinding(finding *models.EnhancedInteractionResult, rule PediatricSafetyRule) {
+	finding.InteractionID += "_AGE_UNKNOWN"
+	finding.Severity = models.SeverityModerate
+	finding.ClinicalEffects = fmt.Sprintf("Patient age unknown; rule applies %s: %s", rule.ageRange(), rule.RiskSummary)
+	finding.ManagementStrategy = "Confirm the patient's age. " + rule.Recommendation
+	finding.Qualifiers["age_status"] = "unknown"
+	delete(finding.Qualifiers, "governance_action")
+}
+
+// appliesTo reports whether the patient falls in the rule's age and weight range.
+// missing names the measurement ("age", "weight") a bound needs but the patient lacks.
+func (r PediatricSafetyRule) appliesTo(patient PediatricPatient) (applies bool, missing string) {
+	if r.MinAgeDays != nil || r.MaxAgeDays != nil {
+		if patient.AgeDays == nil {
+			return false, "age"
+		}
+		if (r.MinAgeDays != nil && *patient.AgeDays < *r.MinAgeDays) || (r.MaxAgeDays != nil && *patient.AgeDays >= *r.MaxAgeDays) {
+			return false, ""
+		}
+	}
+	if r.MinWeightKg != nil || r.MaxWeightKg != nil {
+		if patient.WeightKg == nil {
+			return false, "weight"
+		}
+		if (r.MinWeightKg != nil && *patient.WeightKg < *r.MinWeightKg) || (r.MaxWeightKg != nil && *patient.WeightKg >= *r.MaxWeightKg) {
+			return false, ""
+		}
+	}
+	return true, ""
+}
+
+// appliesFromBirth reports whether the rule's age range includes neonates
+func (r PediatricSafetyRule) appliesFromBirth() bool {
+	return r.MaxAgeDays != nil && (r.MinAgeDays == nil || *r.MinAgeDays == 0)
+}
+
+// ageRange describes the rule's age bounds, e.g. "under 12 years", "6 months to 18 years"
+func (r PediatricSafetyRule) ageRange() string {
+	switch {
+	case r.MinAgeDays != nil && *r.MinAgeDays > 0 && r.MaxAgeDays != nil:
+		return fmt.Sprintf("%s to %s", describeAgeDays(*r.MinAgeDays, true), describeAgeDays(*r.MaxAgeDays, true))
+	case r.MaxAgeDays != nil:
+		return "under " + describeAgeDays(*r.MaxAgeDays, true)
+	case r.MinAgeDays != nil && *r.MinAgeDays > 0:
+		return "from " + describeAgeDays(*r.MinAgeDays, true)
+	}
+	return ""
+}
+
+// weightRange describes the rule's weight bounds, e.g. "under 50 kg"
+func (r PediatricSafetyRule) weightRange() string {
+	switch {
+	case r.MinWeightKg != nil && r.MaxWeightKg != nil:
+		return fmt.Sprintf("%g-%g kg", *r.MinWeightKg, *r.MaxWeightKg)
+	case r.MaxWeightKg != nil:
+		return fmt.Sprintf("under %g kg", *r.MaxWeightKg)
+	case r.MinWeightKg != nil:
+		return fmt.Sprintf("%g kg and over", *r.MinWeightKg)
+	}
+	return ""
+}
+
+// checkPediatricDose compares an order with the rule's weight-based and absolute
+// maximums. It returns a finding when a maximum is exceeded, and issues for limits
+// that could not be checked.
+func checkPediatricDose(rule PediatricSafetyRule, order models.MedicationOrder, patient PediatricPatient) (*models.EnhancedInteractionResult, []string) {
+	var issues []string
+	dose, ok := convertDose(order.Dose, order.DoseUnit, "mg")
+	if !ok {
+		return nil, []string{fmt.Sprintf("%s not evaluated: unit %q cannot be compared with mg", rule.RuleCode, order.DoseUnit)}
+	}
+
+	maxSingle := pediatricDoseLimit(rule.MaxSingleDoseMgPerKg, rule.MaxSingleDoseMg, patient.WeightKg)
+	maxDaily := pediatricDoseLimit(rule.MaxDailyDoseMgPerKg, rule.MaxDailyDoseMg, patient.WeightKg)
+	if patient.WeightKg == nil && (rule.MaxSingleDoseMgPerKg != nil || rule.MaxDailyDoseMgPerKg != nil) {
+		issues = append(issues, fmt.Sprintf("%s: weight unknown; mg/kg limits not checked", rule.RuleCode))
+	}
+
+	var exceeded []string
+	if maxSingle != nil && dose > *maxSingle+1e-9 {
+		exceeded = append(exceeded, fmt.Sprintf("Dose %g mg exceeds maximum single dose %g mg", roundDose(dose), *maxSingle))
+	}
+	var daily *float64
+	if interval, ok := orderIntervalHours(order); ok {
+		d := roundDose(dose * 24 / interval)
+		daily = &d
+		if maxDaily != nil && d > *maxDaily+1e-9 {
+			exceeded = append(exceeded, fmt.Sprintf("Daily dose %g mg exceeds maximum %g mg/day", d, *maxDaily))
+		}
+	} else if maxDaily != nil {
+		issues = append(issues, fmt.Sprintf("%s: frequency %q not recognized; daily maximum not checked", rule.RuleCode, order.Frequency))
+	}
+	if len(exceeded) == 0 {
+		return nil, issues
+	}
+
+	finding := pediatricFinding(rule, order.DrugCode, patient)
+	finding.Mechanism = models.MechanismPK
+	finding.Drug2 = models.DrugInfo{Code: "PEDIATRIC_DOSE", Name: patient.describe()}
+	finding.ClinicalEffects = fmt.Sprintf("%s. %s", strings.Join(exceeded, "; "), rule.RiskSummary)
+	finding.Qualifiers["ordered_dose_mg"] = strconv.FormatFloat(roundDose(dose), 'f', -1, 64)
+	if daily != nil {
+		finding.Qualifiers["ordered_daily_dose_mg"] = strconv.FormatFloat(*daily, 'f', -1, 64)
+	}
+	if maxSingle != nil {
+		finding.Qualifiers["max_single_dose_mg"] = strconv.FormatFloat(*maxSingle, 'f', -1, 64)
+	}
+	if maxDaily != nil {
+		finding.Qualifiers["max_daily_dose_mg"] = strconv.FormatFloat(*maxDaily, 'f', -1, 64)
+	}
+	if order.OrderID != "" {
+		finding.Qualifiers["order_id"] = order.OrderID
+	}
+	return &finding, issues
+}
+
+// pediatricDoseLimit returns the lower of the weight-based and absolute limits (mg)
+func pediatricDoseLimit(mgPerKg, absolute, weightKg *float64) *float64 {
+	var limit *float64
+	if mgPerKg != nil && weightKg != nil {
+		l := roundDose(*mgPerKg * *weightKg)
+		limit = &l
+	}
+	if absolute != nil && (limit == nil || *absolute < *limit) {
+		l := *absolute
+		limit = &l
+	}
+	return limit
+}
+
+// pediatricFinding builds the interaction result for a matched pediatric rule
+func pediatricFinding(rule PediatricSafetyRule, drugCode string, patient PediatricPatient) models.EnhancedInteractionResult {
+	contextCode := "PEDIATRIC_AGE"
+	if rule.MinAgeDays == nil && rule.MaxAgeDays == nil {
+		contextCode = "PEDIATRIC_WEIGHT"
+	}
+	finding := models.EnhancedInteractionResult{
+		Severity:           rule.Severity,
+		Mechanism:          models.MechanismPD,
+		ClinicalEffects:    rule.RiskSummary,
+		ManagementStrategy: rule.Recommendation,
+		Evidence:           rule.Evidence,
+		Sources:            []string{rule.Source},
+		AlternativeDrugs:   []string(rule.Alternatives),
+		Drug1: models.DrugInfo{
+			Code: drugCode,
+			Name: rule.DrugName,
+		},
+		Drug2: models.DrugInfo{
+			Code: contextCode,
+			Name: patient.describe(),
+		},
+		Qualifiers: map[string]string{
+			"type":      "pediatric_safety",
+			"rule_type": rule.RuleType,
+			"rule_code": rule.RuleCode,
+		},
+	}
+	if rule.GovernanceAction != nil {
+		finding.Qualifiers["governance_action"] = *rule.GovernanceAction
+	}
+	if ageRange := rule.ageRange(); ageRange != "" {
+		finding.Qualifiers["age_range"] = ageRange
+	}
+	if weightRange := rule.weightRange(); weightRange != "" {
+		finding.Qualifiers["weight_range"] = weightRange
+	}
+	if patient.AgeDays != nil {
+		finding.Qualifiers["age_days"] = strconv.Itoa(*patient.AgeDays)
+	}
+	if patient.AgeBand != "" {
+		finding.Qualifiers["age_band"] = patient.AgeBand
+	}
+	if patient.WeightKg != nil {
+		finding.Qualifiers["weight_kg"] = strconv.FormatFloat(*patient.WeightKg, 'f', -1, 64)
+	}
+	return finding
+}
+
+// drugClasses returns a drug's ATC codes at every level
+func (pse *PediatricSafetyEngine) drugClasses(drugCode string) []string {
+	if pse.atcIndex == nil {
+		return nil
+	}
+	return pse.atcIndex.ClassesForDrug(drugCode)
+}
+
+// GetDrugRules returns the active pediatric rules for a drug
+func (pse *PediatricSafetyEngine) GetDrugRules(ctx context.Context, drugCode string) ([]PediatricSafetyRule, error) {
+	rules, err := pse.loadRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pediatric safety rules: %w", err)
+	}
+	drugRules := rules[normalizeATCDrugKey(drugCode)]
+	if drugRules == nil {
+		drugRules = []PediatricSafetyRule{}
+	}
+	return drugRules, nil
+}
+
+// loadRules returns active rules keyed by normalized drug code, youngest age range first
+func (pse *PediatricSafetyEngine) loadRules(ctx context.Context) (map[string][]PediatricSafetyRule, error) {
+	pse.mu.Lock()
+	defer pse.mu.Unlock()
+
+	if pse.rules != nil && time.Since(pse.rulesLoaded) < pse.cacheTTL {
+		return pse.rules, nil
+	}
+
+	var rows []PediatricSafetyRule
+	err := pse.db.DB.WithContext(ctx).
+		Where("active = TRUE").
+		Order("drug_code, min_age_days NULLS FIRST, min_weight_kg NULLS FIRST").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make(map[string][]PediatricSafetyRule)
+	for _, row := range rows {
+		row.RuleType = strings.ToLower(row.RuleType)
+		key := normalizeATCDrugKey(row.DrugCode)
+		rules[key] = append(rules[key], row)
+	}
+
+	pse.rules = rules
+	pse.rulesLoaded = time.Now()
+
+	return rules, nil
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// PEDIATRIC AGE AND WEIGHT SAFETY TESTS
+// ============================================================================
+
+func TestResolvePediatricPatient(t *testing.T) {
+	patient, err := ResolvePediatricPatient(intPtr(12), intPtr(5), intPtr(3), floatPtr(3.4))
+	require.NoError(t, err)
+	assert.Equal(t, 12, *patient.AgeDays) // Days take precedence
+	assert.Equal(t, PediatricBandNeonate, patient.AgeBand)
+	assert.Equal(t, "Neonate (12 days, 3.4 kg)", patient.describe())
+
+	patient, _ = ResolvePediatricPatient(nil, intPtr(6), nil, nil)
+	assert.Equal(t, 182, *patient.AgeDays)
+	assert.Equal(t, PediatricBandInfant, patient.AgeBand)
+
+	patient, _ = ResolvePediatricPatient(nil, nil, intPtr(12), nil)
+	assert.Equal(t, 4383, *patient.AgeDays)
+	assert.Equal(t, PediatricBandAdolescent, patient.AgeBand)
+	assert.True(t, patient.IsPediatric())
+
+	patient, _ = ResolvePediatricPatient(nil, nil, intPtr(18), nil)
+	assert.False(t, patient.IsPediatric())
+
+	var inputErr *PediatricInputError
+	_, err = ResolvePediatricPatient(intPtr(-1), nil, nil, nil)
+	require.True(t, errors.As(err, &inputErr))
+	assert.Equal(t, "age_days", inputErr.Field)
+
+	_, err = ResolvePediatricPatient(nil, nil, nil, floatPtr(0))
+	require.True(t, errors.As(err, &inputErr))
+	assert.Equal(t, "weight_kg", inputErr.Field)
+}
+
+func TestPediatricPatientFromContext(t *testing.T) {
+	weight := decimal.NewFromFloat(18.5)
+	patient, err := PediatricPatientFromContext(&models.PatientContext{Age: 5, AgeMonths: intPtr(62), Weight: &weight})
+	require.NoError(t, err)
+	assert.Equal(t, 1887, *patient.AgeDays)
+	assert.Equal(t, 18.5, *patient.WeightKg)
+
+	patient, err = PediatricPatientFromContextData(&models.PatientContextData{AgeBand: "pediatric"})
+	require.NoError(t, err)
+	assert.True(t, patient.IsPediatric())
+	assert.Nil(t, patient.AgeDays)
+
+	patient, _ = PediatricPatientFromContextData(&models.PatientContextData{
+		WeightKg: floatPtr(9),
+		Labs:     &models.PatientLabs{AgeYears: intPtr(1), WeightKg: floatPtr(10)},
+	})
+	assert.Equal(t, 365, *patient.AgeDays)
+	assert.Equal(t, 9.0, *patient.WeightKg)
+}
+
+func TestPediatricPatient_AgeYears(t *testing.T) {
+	patient, err := ResolvePediatricPatient(nil, intPtr(30), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, *patient.AgeYears())
+
+	patient, err = ResolvePediatricPatient(nil, nil, intPtr(65), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 65, *patient.AgeYears())
+
+	assert.Nil(t, PediatricPatient{}.AgeYears())
+}
+
+func TestEvaluatePediatricRules_AgeRanges(t *testing.T) {
+	rules := map[string][]PediatricSafetyRule{
+		"RXCUI:2670": {
+			{RuleCode: "PED-CODEINE-UNDER-12", DrugCode: "RxCUI:2670", DrugName: "Codeine",
+				RuleType: PediatricRuleContraindication, MinAgeDays: intPtr(0), MaxAgeDays: intPtr(4383),
+				Severity: models.SeverityContraindicated, GovernanceAction: stringPtr("hard_block_governance_override"),
+				RiskSummary: "Respiratory depression", Recommendation: "Do not use under 12 years."},
+		},
+		"RXCUI:10395": {
+			{RuleCode: "PED-TETRACYCLINE-UNDER-8", DrugCode: "RxCUI:10395", DrugName: "Tetracycline",
+				RuleType: PediatricRuleContraindication, MinAgeDays: intPtr(0), MaxAgeDays: intPtr(2922),
+				Severity: models.SeverityMajor, GovernanceAction: stringPtr("mandatory_escalation"),
+				RiskSummary: "Tooth discoloration", Recommendation: "Avoid under 8 years."},
+		},
+		"RXCUI:161": {
+			{RuleCode: "PED-ACETAMINOPHEN-DOSE", DrugCode: "RxCUI:161", DrugName: "Acetaminophen",
+				RuleType: PediatricRuleDoseLimit, MinAgeDays: intPtr(29), MaxAgeDays: intPtr(6574), MaxWeightKg: floatPtr(50),
+				MaxSingleDoseMgPerKg: floatPtr(15), MaxDailyDoseMgPerKg: floatPtr(75), MaxSingleDoseMg: floatPtr(1000), MaxDailyDoseMg: floatPtr(4000),
+				Severity: models.SeverityMajor, RiskSummary: "Hepatotoxicity", Recommendation: "Up to 15 mg/kg per dose."},
+		},
+	}
+	noClasses := func(string) []string { return nil }
+
+	child := PediatricPatient{AgeDays: intPtr(yearsToDays(6)), AgeBand: PediatricBandChild}
+	findings, notes := evaluatePediatricRules(rules, []string{"2670", "RxCUI:10395"}, nil, child, noClasses)
+
+	require.Len(t, findings, 2)
+	assert.Empty(t, notes)
+	codeine := findings[0]
+	assert.Equal(t, "PED_PED-CODEINE-UNDER-12_RXCUI:2670", codeine.InteractionID)
+	assert.Equal(t, "PEDIATRIC_AGE", codeine.Drug2.Code)
+	assert.Equal(t, "Child (6 years)", codeine.Drug2.Name)
+	assert.Equal(t, "under 12 years", codeine.Qualifiers["age_range"])
+	assert.Equal(t, "hard_block_governance_override", codeine.Qualifiers["governance_action"])
+	assert.Equal(t, "Tetracycline", findings[1].Drug1.Name)
+	assert.Equal(t, "under 8 years", findings[1].Qualifiers["age_range"])
+
+	// Upper bound is exclusive
+	findings, _ = evaluatePediatricRules(rules, []string{"2670", "10395"}, nil, PediatricPatient{AgeDays: intPtr(2922)}, noClasses)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "PED-CODEINE-UNDER-12", findings[0].Qualifiers["rule_code"])
+
+	// Age unknown: rules that apply from birth become warnings to confirm the age
+	findings, notes = evaluatePediatricRules(rules, []string{"2670"}, nil, PediatricPatient{AgeBand: "pediatric"}, noClasses)
+	assert.Empty(t, notes)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "PED_PED-CODEINE-UNDER-12_RXCUI:2670_AGE_UNKNOWN", findings[0].InteractionID)
+	assert.Equal(t, models.SeverityModerate, findings[0].Severity)
+	assert.Equal(t, "unknown", findings[0].Qualifiers["age_status"])
+	assert.Empty(t, findings[0].Qualifiers["governance_action"])
+
+	// Dose limits still need the age
+	orders := []models.MedicationOrder{{DrugCode: "161", Dose: 160, DoseUnit: "mg", Frequency: "q6h"}}
+	findings, notes = evaluatePediatricRules(rules, nil, orders, PediatricPatient{WeightKg: floatPtr(10)}, noClasses)
+	assert.Empty(t, findings)
+	assert.Contains(t, notes, "PED-ACETAMINOPHEN-DOSE not evaluated: age unknown")
+}
+
+func TestEvaluatePediatricRules_AgeZeroWithoutDaysOrMonths(t *testing.T) {
+	rules := map[string][]PediatricSafetyRule{
+		"RXCUI:2193": {
+			{RuleCode: "PED-CEFTRIAXONE-CALCIUM-NEONATE", DrugCode: "RxCUI:2193", DrugName: "Ceftriaxone",
+				RuleType: PediatricRuleInteraction, MinAgeDays: intPtr(0), MaxAgeDays: intPtr(29),
+				InteractingDescription: stringPtr("Intravenous calcium"), InteractingATCClasses: models.StringArray{"A12AA"},
+				Severity: models.SeverityContraindicated, GovernanceAction: stringPtr("hard_block_governance_override"),
+				RiskSummary: "Fatal precipitates", Recommendation: "Do not co-administer.", Alternatives: models.StringArray{"Cefotaxime"}},
+		},
+	}
+	classes := map[string][]string{
+		"RXCUI:42638": {"A", "A12", "A12A", "A12AA", "A12AA03"}, // Calcium gluconate
+	}
+	classesFor := func(drugCode string) []string { return classes[normalizeATCDrugKey(drugCode)] }
+
+	// Age 0 with no days or months cannot rule out a neonate
+	patient, err := PediatricPatientFromContext(&models.PatientContext{Age: 0})
+	require.NoError(t, err)
+	assert.False(t, patient.IsPediatric())
+	assert.True(t, patient.MayBePediatric())
+
+	findings, _ := evaluatePediatricRules(rules, []string{"2193", "42638"}, nil, patient, classesFor)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "PED_PED-CEFTRIAXONE-CALCIUM-NEONATE_RXCUI:2193_RXCUI:42638_AGE_UNKNOWN", findings[0].InteractionID)
+	assert.Equal(t, models.SeverityModerate, findings[0].Severity)
+	assert.Contains(t, findings[0].ClinicalEffects, "Patient age unknown; rule applies under 29 days")
+
+	adult, _ := PediatricPatientFromContext(&models.PatientContext{Age: 40})
+	assert.False(t, adult.MayBePediatric())
+}
+
+func TestEvaluatePediatricRules_NeonatalInteraction(t *testing.T) {
+	rules := map[string][]PediatricSafetyRule{
+		"RXCUI:2193": {
+			{RuleCode: "PED-CEFTRIAXONE-CALCIUM-NEONATE", DrugCode: "RxCUI:2193", DrugName: "Ceftriaxone",
+				RuleType: PediatricRuleInteraction, MinAgeDays: intPtr(0), MaxAgeDays: intPtr(29),
+				InteractingDescription: stringPtr("Intravenous calcium"), InteractingATCClasses: models.StringArray{"A12AA"},
+				Severity: models.SeverityContraindicated, GovernanceAction: stringPtr("hard_block_governance_override"),
+				RiskSummary: "Fatal precipitates", Recommendation: "Do not co-administer.", Alternatives: models.StringArray{"Cefotaxime"}},
+		},
+	}
+	classes := map[string][]string{
+		"RXCUI:42638": {"A", "A12", "A12A", "A12AA", "A12AA03"}, // Calcium gluconate
+	}
+	classesFor := func(drugCode string) []string { return classes[normalizeATCDrugKey(drugCode)] }
+
+	neonate := PediatricPatient{AgeDays: intPtr(10), AgeBand: PediatricBandNeonate}
+	findings, _ := evaluatePediatricRules(rules, []string{"2193", "42638"}, nil, neonate, classesFor)
+
+	require.Len(t, findings, 1)
+	assert.Equal(t, "PED_PED-CEFTRIAXONE-CALCIUM-NEONATE_RXCUI:2193_RXCUI:42638", findings[0].InteractionID)
+	assert.Equal(t, "42638", findings[0].Drug2.Code)
+	assert.Equal(t, "Intravenous calcium", findings[0].Drug2.Name)
+	assert.Equal(t, []string{"Cefotaxime"}, findings[0].AlternativeDrugs)
+
+	// Ceftriaxone alone, or after the neonatal period, does not fire
+	findings, _ = evaluatePediatricRules(rules, []string{"2193"}, nil, neonate, classesFor)
+	assert.Empty(t, findings)
+	findings, _ = evaluatePediatricRules(rules, []string{"2193", "42638"}, nil, PediatricPatient{AgeDays: intPtr(40)}, classesFor)
+	assert.Empty(t, findings)
+}
+
+func TestEvaluatePediatricRules_WeightBasedDoseLimits(t *testing.T) {
+	rules := map[string][]PediatricSafetyRule{
+		"RXCUI:161": {
+			{RuleCode: "PED-ACETAMINOPHEN-DOSE", DrugCode: "RxCUI:161", DrugName: "Acetaminophen",
+				RuleType: PediatricRuleDoseLimit, MinAgeDays: intPtr(29), MaxAgeDays: intPtr(6574), MaxWeightKg: floatPtr(50),
+				MaxSingleDoseMgPerKg: floatPtr(15), MaxDailyDoseMgPerKg: floatPtr(75), MaxSingleDoseMg: floatPtr(1000), MaxDailyDoseMg: floatPtr(4000),
+				Severity: models.SeverityMajor, RiskSummary: "Hepatotoxicity", Recommendation: "Up to 15 mg/kg per dose."},
+			{RuleCode: "PED-ACETAMINOPHEN-DOSE-50KG", DrugCode: "RxCUI:161", DrugName: "Acetaminophen",
+				RuleType: PediatricRuleDoseLimit, MinAgeDays: intPtr(29), MaxAgeDays: intPtr(6574), MinWeightKg: floatPtr(50),
+				MaxSingleDoseMg: floatPtr(1000), MaxDailyDoseMg: floatPtr(4000),
+				Severity: models.SeverityMajor, RiskSummary: "Hepatotoxicity", Recommendation: "Up to 1 g per dose."},
+		},
+	}
+	noClasses := func(string) []string { return nil }
+
+	patient := PediatricPatient{AgeDays: intPtr(yearsToDays(4)), AgeBand: PediatricBandChild, WeightKg: floatPtr(16)}
+	orders := []models.MedicationOrder{
+		{OrderID: "o1", DrugCode: "161", Dose: 320, DoseUnit: "mg", Frequency: "q4h"},
+	}
+	findings, notes := evaluatePediatricRules(rules, nil, orders, patient, noClasses)
+
+	require.Len(t, findings, 1)
+	assert.Empty(t, notes)
+	dose := findings[0]
+	assert.Equal(t, "PED_PED-ACETAMINOPHEN-DOSE_RXCUI:161_o1", dose.InteractionID)
+	assert.Equal(t, "PEDIATRIC_DOSE", dose.Drug2.Code)
+	assert.Equal(t, "240", dose.Qualifiers["max_single_dose_mg"])
+	assert.Equal(t, "1200", dose.Qualifiers["max_daily_dose_mg"])
+	assert.Equal(t, "1920", dose.Qualifiers["ordered_daily_dose_mg"])
+	assert.Equal(t, "under 50 kg", dose.Qualifiers["weight_range"])
+	assert.Contains(t, dose.ClinicalEffects, "Dose 320 mg exceeds maximum single dose 240 mg")
+	assert.Equal(t, models.MechanismPK, dose.Mechanism)
+
+	// Within limits
+	orders[0].Dose, orders[0].Frequency = 240, "q6h"
+	findings, _ = evaluatePediatricRules(rules, nil, orders, patient, noClasses)
+	assert.Empty(t, findings)
+
+	// 50 kg and over: absolute caps from the weight band
+	orders[0].Dose = 1.5
+	orders[0].DoseUnit = "g"
+	findings, _ = evaluatePediatricRules(rules, nil, orders, PediatricPatient{AgeDays: intPtr(yearsToDays(15)), WeightKg: floatPtr(62)}, noClasses)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "PED-ACETAMINOPHEN-DOSE-50KG", findings[0].Qualifiers["rule_code"])
+	assert.Equal(t, "1000", findings[0].Qualifiers["max_single_dose_mg"])
+
+	// Weight unknown: weight-banded rules cannot be selected
+	findings, notes = evaluatePediatricRules(rules, nil, orders, PediatricPatient{AgeDays: intPtr(yearsToDays(4))}, noClasses)
+	assert.Empty(t, findings)
+	assert.Contains(t, notes, "PED-ACETAMINOPHEN-DOSE not evaluated: weight unknown")
+}
+
+func TestPediatricDoseLimit(t *testing.T) {
+	assert.Equal(t, 150.0, *pediatricDoseLimit(floatPtr(15), floatPtr(1000), floatPtr(10)))
+	assert.Equal(t, 1000.0, *pediatricDoseLimit(floatPtr(15), floatPtr(1000), floatPtr(80)))
+	assert.Equal(t, 1000.0, *pediatricDoseLimit(floatPtr(15), floatPtr(1000), nil))
+	assert.Nil(t, pediatricDoseLimit(floatPtr(15), nil, nil))
+}
@@ -3,7 +3,9 @@ package services
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"kb-drug-interactions/internal/database"
@@ -13,22 +15,39 @@ import (
 
 // PharmacogenomicEngine evaluates patient-specific genetic interactions
 type PharmacogenomicEngine struct {
-	db      *database.Database
-	metrics *metrics.Collector
+	db         *database.Database
+	translator *GenotypeTranslator // Genotype -> phenotype (optional)
+	metrics    *metrics.Collector
 	
 	// Cache for PGx rules to avoid repeated database queries
 	ruleCache map[string][]models.DDIPharmacogenomicRule
 	cacheTTL  time.Duration
 	lastLoad  time.Time
+
+	// Phenoconversion perpetrators keyed by normalized drug code
+	perpetrators       map[string][]EnzymePerpetrator
+	perpetratorsLoaded time.Time
+	perpetratorMu      sync.Mutex
+
+	// Gene-phenotype-drug guidance keyed by normalized drug code
+	guidelines       map[string][]PGXGuideline
+	guidelinesLoaded time.Time
+	guidelineMu      sync.Mutex
+
+	// HLA risk-allele and dose-critical gene rules keyed by normalized drug code
+	safetyRules       map[string][]PGXSafetyRule
+	safetyRulesLoaded time.Time
+	safetyMu          sync.Mutex
 }
 
 // NewPharmacogenomicEngine creates a new PGx evaluation engine
-func NewPharmacogenomicEngine(db *database.Database, metrics *metrics.Collector) *PharmacogenomicEngine {
+func NewPharmacogenomicEngine(db *database.Database, translator *GenotypeTranslator, metrics *metrics.Collector) *PharmacogenomicEngine {
 	return &PharmacogenomicEngine{
-		db:        db,
-		metrics:   metrics,
-		ruleCache: make(map[string][]models.DDIPharmacogenomicRule),
-		cacheTTL:  30 * time.Minute,
+		db:         db,
+		translator: translator,
+		metrics:    metrics,
+		ruleCache:  make(map[string][]models.DDIPharmacogenomicRule),
+		cacheTTL:   30 * time.Minute,
 	}
 }
 
@@ -67,6 +86,71 @@ func (pge *PharmacogenomicEngine) EvaluatePatientPGXInteractions(
 	return interactions, nil
 }
 
+// ResolvePatientPhenotypes merges reported phenotypes with phenotypes derived from
+// lab genotypes. A genotype takes precedence over a reported phenotype for the same
+// gene; indeterminate translations leave the gene out of the returned markers.
+func (pge *PharmacogenomicEngine) ResolvePatientPhenotypes(
+	phenotypes map[string]string,
+	genotypes map[string]models.PGXGenotype,
+) (map[string]string, []GenotypeTranslation, error) {
+	if len(genotypes) == 0 {
+		return phenotypes, nil, nil
+	}
+	if pge.translator == nil {
+		return nil, nil, fmt.Errorf("genotype translation not available")
+	}
+
+	markers := make(map[string]string, len(phenotypes)+len(genotypes))
+	for gene, phenotype := range phenotypes {
+		markers[strings.ToUpper(gene)] = phenotype
+	}
+
+	genes := make([]string, 0, len(genotypes))
+	for gene := range genotypes {
+		genes = append(genes, gene)
+	}
+	sort.Strings(genes)
+
+	translations := make([]GenotypeTranslation, 0, len(genes))
+	for _, gene := range genes {
+		translation, err := pge.translator.Translate(gene, genotypes[gene])
+		if err != nil {
+			pge.metrics.RecordPGXTranslation(strings.ToUpper(gene), "error")
+			return nil, nil, err
+		}
+		pge.metrics.RecordPGXTranslation(translation.Gene, translation.Phenotype)
+
+		if reported, exists := markers[translation.Gene]; exists && !strings.EqualFold(reported, translation.Phenotype) {
+			translation.Notes = append(translation.Notes,
+				fmt.Sprintf("reported phenotype %q replaced by genotype-derived phenotype", reported))
+		}
+		if translation.Phenotype == PGXPhenotypeIndeterminate {
+			delete(markers, translation.Gene)
+		} else {
+			markers[translation.Gene] = translation.Phenotype
+		}
+		translations = append(translations, *translation)
+	}
+
+	return markers, translations, nil
+}
+
+// TranslationVersion returns the genotype translation table version in use ("" if none)
+func (pge *PharmacogenomicEngine) TranslationVersion() string {
+	if pge.translator == nil {
+		return ""
+	}
+	return pge.translator.Version()
+}
+
+// ReloadTranslationTables reloads the current genotype translation table version
+func (pge *PharmacogenomicEngine) ReloadTranslationTables(ctx context.Context) (*PGXTranslationStats, error) {
+	if pge.translator == nil {
+		return nil, fmt.Errorf("genotype translation not available")
+	}
+	return pge.translator.Reload(ctx)
+}
+
 // EvaluateDrugMetabolism provides drug metabolism assessment based on patient PGx
 func (pge *PharmacogenomicEngine) EvaluateDrugMetabolism(
 	ctx context.Context,
@@ -80,6 +164,12 @@ func (pge *PharmacogenomicEngine) EvaluateDrugMetabolism(
 		return nil, err
 	}
 
+	guidelines, err := pge.loadGuidelines(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PGx guidelines: %w", err)
+	}
+	drugGuidelines := guidelines[normalizeATCDrugKey(drugCode)]
+
 	assessment := &PharmacogenomicMetabolismAssessment{
 		DrugCode:           drugCode,
 		PatientPGX:         patientPGX,
@@ -93,8 +183,8 @@ func (pge *PharmacogenomicEngine) EvaluateDrugMetabolism(
 	// Evaluate each relevant genetic marker
 	for _, rule := range pgxRules {
 		if patientPhenotype, exists := patientPGX[rule.Gene]; exists {
-			metabolismImpact := pge.assessMetabolismImpact(rule, patientPhenotype)
-			pge.incorporateMetabolismFindings(assessment, metabolismImpact, rule)
+			metabolismImpact := pge.assessMetabolismImpact(rule, patientPhenotype, drugGuidelines)
+			pge.incorporateMetabolismFindings(assessment, metabolismImpact, rule, drugGuidelines)
 		}
 	}
 
@@ -234,6 +324,7 @@ func (pge *PharmacogenomicEngine) evaluatePGXRule(
 func (pge *PharmacogenomicEngine) assessMetabolismImpact(
 	rule models.DDIPharmacogenomicRule,
 	patientPhenotype string,
+	guidelines []PGXGuideline,
 ) PharmacogenomicMetabolismImpact {
 	impact := PharmacogenomicMetabolismImpact{
 		Gene:      rule.Gene,
@@ -262,114 +353,12 @@ func (pge *PharmacogenomicEngine) assessMetabolismImpact(
 		impact.RiskLevel = "low"
 	}
 
-	// Add gene-specific assessments
-	switch rule.Gene {
-	case "CYP2D6":
-		impact = pge.assessCYP2D6Impact(impact, rule)
-	case "CYP2C19":
-		impact = pge.assessCYP2C19Impact(impact, rule)
-	case "SLCO1B1":
-		impact = pge.assessSLCO1B1Impact(impact, rule)
-	case "CYP3A5":
-		impact = pge.assessCYP3A5Impact(impact, rule)
-	}
-
-	return impact
-}
-
-func (pge *PharmacogenomicEngine) assessCYP2D6Impact(
-	impact PharmacogenomicMetabolismImpact,
-	rule models.DDIPharmacogenomicRule,
-) PharmacogenomicMetabolismImpact {
-	// CYP2D6-specific logic for common substrates
-	drugSpecificGuidance := map[string]map[string]string{
-		"RxCUI:2670": { // Codeine
-			"ultrarapid": "AVOID: Toxic morphine levels - use morphine/oxycodone instead",
-			"poor":       "AVOID: Inadequate analgesia - use non-CYP2D6 opioid",
-		},
-		"RxCUI:4493": { // Metoprolol
-			"ultrarapid": "May need higher doses - monitor BP/HR closely",
-			"poor":       "Reduce dose 50% - monitor for bradycardia",
-		},
-		"RxCUI:5640": { // Paroxetine
-			"ultrarapid": "Standard dosing usually adequate",
-			"poor":       "Consider dose reduction or alternative SSRI",
-		},
-	}
-
-	if guidance, exists := drugSpecificGuidance[rule.DrugCode]; exists {
-		if specific, found := guidance[impact.Phenotype]; found {
-			impact.SpecificRecommendation = specific
-		}
-	}
-
-	return impact
-}
-
-func (pge *PharmacogenomicEngine) assessCYP2C19Impact(
-	impact PharmacogenomicMetabolismImpact,
-	rule models.DDIPharmacogenomicRule,
-) PharmacogenomicMetabolismImpact {
-	// CYP2C19-specific logic for antiplatelet drugs
-	drugSpecificGuidance := map[string]map[string]string{
-		"RxCUI:1154343": { // Clopidogrel
-			"poor":       "AVOID: Inadequate antiplatelet effect - use prasugrel/ticagrelor",
-			"ultrarapid": "Standard dosing adequate - enhanced antiplatelet effect",
-		},
-		"RxCUI:197696": { // Omeprazole
-			"poor":       "Consider H2 blocker alternative - prolonged acid suppression",
-			"ultrarapid": "Standard dosing adequate",
-		},
-	}
-
-	if guidance, exists := drugSpecificGuidance[rule.DrugCode]; exists {
-		if specific, found := guidance[impact.Phenotype]; found {
-			impact.SpecificRecommendation = specific
-		}
-	}
-
-	return impact
-}
-
-func (pge *PharmacogenomicEngine) assessSLCO1B1Impact(
-	impact PharmacogenomicMetabolismImpact,
-	rule models.DDIPharmacogenomicRule,
-) PharmacogenomicMetabolismImpact {
-	// SLCO1B1-specific logic for statins
-	drugSpecificGuidance := map[string]map[string]string{
-		"RxCUI:36567": { // Simvastatin
-			"poor": "Reduce dose 50% or use pravastatin/rosuvastatin - myopathy risk",
-		},
-		"RxCUI:83367": { // Atorvastatin
-			"poor": "Consider dose reduction - monitor for muscle symptoms",
-		},
-	}
-
-	if guidance, exists := drugSpecificGuidance[rule.DrugCode]; exists {
-		if specific, found := guidance[impact.Phenotype]; found {
-			impact.SpecificRecommendation = specific
-		}
-	}
-
-	return impact
-}
-
-func (pge *PharmacogenomicEngine) assessCYP3A5Impact(
-	impact PharmacogenomicMetabolismImpact,
-	rule models.DDIPharmacogenomicRule,
-) PharmacogenomicMetabolismImpact {
-	// CYP3A5-specific logic for immunosuppressants
-	drugSpecificGuidance := map[string]map[string]string{
-		"RxCUI:42316": { // Tacrolimus
-			"expresser": "May need higher doses - monitor trough levels closely",
-			"non_expresser": "Standard dosing - monitor trough levels",
-		},
-	}
-
-	if guidance, exists := drugSpecificGuidance[rule.DrugCode]; exists {
-		if specific, found := guidance[impact.Phenotype]; found {
-			impact.SpecificRecommendation = specific
-		}
+	// Add guideline recommendation for this gene, phenotype and drug
+	if guideline := selectGuideline(guidelines, rule.Gene, patientPhenotype); guideline != nil {
+		impact.SpecificRecommendation = guideline.Recommendation
+		impact.DosingAction = guideline.DosingAction
+		impact.Classification = guideline.Classification
+		impact.GuidelineSource = guideline.SourceVersion
 	}
 
 	return impact
@@ -379,6 +368,7 @@ func (pge *PharmacogenomicEngine) incorporateMetabolismFindings(
 	assessment *PharmacogenomicMetabolismAssessment,
 	impact PharmacogenomicMetabolismImpact,
 	rule models.DDIPharmacogenomicRule,
+	guidelines []PGXGuideline,
 ) {
 	// Update overall metabolism status
 	if impact.RiskLevel == "high" {
@@ -398,40 +388,14 @@ func (pge *PharmacogenomicEngine) incorporateMetabolismFindings(
 		assessment.Recommendations = append(assessment.Recommendations, impact.SpecificRecommendation)
 	}
 
-	// Add alternative drugs if contraindicated or high risk
-	if rule.Severity == models.SeverityContraindicated || impact.RiskLevel == "high" {
-		alternatives := pge.getAlternativeDrugsForPGX(rule.DrugCode, rule.Gene)
+	// Add alternative drugs if contraindicated, high risk or the guideline advises against the drug
+	if rule.Severity == models.SeverityContraindicated || impact.RiskLevel == "high" ||
+		impact.DosingAction == DosingActionAvoid || impact.DosingAction == DosingActionConsiderAlternative {
+		alternatives := guidelineAlternatives(guidelines, rule.Gene)
 		assessment.AlternativeDrugs = append(assessment.AlternativeDrugs, alternatives...)
 	}
 }
 
-func (pge *PharmacogenomicEngine) getAlternativeDrugsForPGX(drugCode, gene string) []string {
-	// Clinical alternatives based on PGx considerations
-	alternatives := map[string]map[string][]string{
-		"CYP2D6": {
-			"RxCUI:2670":  {"RxCUI:7052", "RxCUI:7804"},    // Codeine -> Morphine, Oxycodone
-			"RxCUI:4493":  {"RxCUI:149", "RxCUI:32968"},     // Metoprolol -> Atenolol, Carvedilol
-			"RxCUI:5640":  {"RxCUI:32937", "RxCUI:36437"},   // Paroxetine -> Sertraline, Escitalopram
-		},
-		"CYP2C19": {
-			"RxCUI:1154343": {"RxCUI:1246289", "RxCUI:1156738"}, // Clopidogrel -> Prasugrel, Ticagrelor
-			"RxCUI:197696":  {"RxCUI:1927851", "RxCUI:2047"},     // Omeprazole -> Pantoprazole, Ranitidine
-		},
-		"SLCO1B1": {
-			"RxCUI:36567": {"RxCUI:42463", "RxCUI:446503"},   // Simvastatin -> Pravastatin, Rosuvastatin
-			"RxCUI:83367": {"RxCUI:42463", "RxCUI:446503"},   // Atorvastatin -> Pravastatin, Rosuvastatin
-		},
-	}
-
-	if geneAlts, exists := alternatives[gene]; exists {
-		if drugAlts, found := geneAlts[drugCode]; found {
-			return drugAlts
-		}
-	}
-
-	return []string{}
-}
-
 func (pge *PharmacogenomicEngine) getPGXMonitoringParameters(rule models.DDIPharmacogenomicRule) map[string]interface{} {
 	// Gene-specific monitoring parameters
 	monitoring := map[string]map[string]interface{}{
@@ -533,49 +497,39 @@ type PharmacogenomicMetabolismImpact struct {
 	ExposureChange       string                  `json:"exposure_change"`
 	RiskLevel           string                  `json:"risk_level"`
 	SpecificRecommendation string                `json:"specific_recommendation"`
+	DosingAction         string                  `json:"dosing_action,omitempty"`
+	Classification       string                  `json:"classification,omitempty"`
+	GuidelineSource      string                  `json:"guideline_source,omitempty"`
 }
 
-// ValidatePGXData validates patient pharmacogenomic data format
-func (pge *PharmacogenomicEngine) ValidatePGXData(patientPGX map[string]string) error {
-	supportedGenes := []string{"CYP2D6", "CYP2C19", "CYP2C9", "SLCO1B1", "CYP3A5", "VKORC1"}
-	validPhenotypes := map[string][]string{
-		"CYP2D6":   {"poor", "intermediate", "normal", "ultrarapid"},
-		"CYP2C19":  {"poor", "intermediate", "normal", "ultrarapid"},
-		"CYP2C9":   {"poor", "intermediate", "normal"},
-		"SLCO1B1":  {"poor", "normal"},
-		"CYP3A5":   {"expresser", "non_expresser"},
-		"VKORC1":   {"low", "intermediate", "high"},
+// ValidatePGXData validates patient pharmacogenomic data against the genes and
+// phenotypes with guidelines or translation tables
+func (pge *PharmacogenomicEngine) ValidatePGXData(ctx context.Context, patientPGX map[string]string) error {
+	validPhenotypes, err := pge.SupportedPGXPhenotypes(ctx)
+	if err != nil {
+		return err
 	}
 
 	for gene, phenotype := range patientPGX {
 		// Check if gene is supported
-		geneSupported := false
-		for _, supportedGene := range supportedGenes {
-			if gene == supportedGene {
-				geneSupported = true
-				break
-			}
-		}
-		
+		phenotypes, geneSupported := validPhenotypes[gene]
 		if !geneSupported {
 			return fmt.Errorf("unsupported gene: %s", gene)
 		}
 
 		// Check if phenotype is valid for this gene
-		if validPhenotypes, exists := validPhenotypes[gene]; exists {
-			phenotypeValid := false
-			for _, validPhenotype := range validPhenotypes {
-				if strings.ToLower(phenotype) == validPhenotype {
-					phenotypeValid = true
-					break
-				}
-			}
-			
-			if !phenotypeValid {
-				return fmt.Errorf("invalid phenotype %s for gene %s", phenotype, gene)
+		phenotypeValid := false
+		for _, validPhenotype := range phenotypes {
+			if strings.ToLower(phenotype) == validPhenotype {
+				phenotypeValid = true
+				break
 			}
 		}
+
+		if !phenotypeValid {
+			return fmt.Errorf("invalid phenotype %s for gene %s", phenotype, gene)
+		}
 	}
 
 	return nil
-}
\ No newline at end of file
+}
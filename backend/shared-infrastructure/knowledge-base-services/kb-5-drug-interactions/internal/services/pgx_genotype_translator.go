@@ -0,0 +1,586 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kb-drug-interactions/internal/database"
+	"kb-drug-interactions/internal/metrics"
+	"kb-drug-interactions/internal/models"
+)
+
+// Phenotype translation methods (pgx_phenotype_rules.method)
+const (
+	PGXMethodActivityScore = "activity_score"
+	PGXMethodFunctionPair  = "function_pair"
+)
+
+// PGXPhenotypeIndeterminate is reported when a genotype cannot be assigned a single phenotype
+const PGXPhenotypeIndeterminate = "indeterminate"
+
+// pgxDeletionAlleles are star alleles that denote a whole-gene deletion (no gene copy)
+var pgxDeletionAlleles = map[string]string{
+	"CYP2D6": "*5",
+}
+
+// pgxUnresolvedDuplicationCopies are the copy counts tried for an "xN" duplication
+// when the lab did not report a copy number
+var pgxUnresolvedDuplicationCopies = []int{2, 3}
+
+// PGXAlleleFunctionRow is a star allele function read from pgx_allele_functions
+type PGXAlleleFunctionRow struct {
+	Gene           string
+	Allele         string
+	FunctionStatus string
+	ActivityValue  *float64
+}
+
+// PGXPhenotypeRuleRow is a phenotype assignment rule read from pgx_phenotype_rules
+type PGXPhenotypeRuleRow struct {
+	Gene             string
+	Method           string
+	MinActivityScore *float64
+	MaxActivityScore *float64
+	Function1        *string
+	Function2        *string
+	Phenotype        string
+}
+
+// PGXTranslationStats summarizes a translation table load
+type PGXTranslationStats struct {
+	TableVersion string    `json:"table_version"`
+	Genes        int       `json:"genes"`
+	Alleles      int       `json:"alleles"`
+	Rules        int       `json:"rules"`
+	LoadedAt     time.Time `json:"loaded_at"`
+}
+
+// AlleleCall is one allele of a diplotype after translation
+type AlleleCall struct {
+	Allele        string   `json:"allele"`
+	Copies        int      `json:"copies"`
+	Function      string   `json:"function"`
+	ActivityValue *float64 `json:"activity_value,omitempty"`
+
+	unresolved bool // "xN" duplication with unknown copy count
+}
+
+// GenotypeTranslation is the phenotype derived from a lab-reported genotype
+type GenotypeTranslation struct {
+	Gene          string       `json:"gene"`
+	Diplotype     string       `json:"diplotype"`
+	CopyNumber    *int         `json:"copy_number,omitempty"`
+	Alleles       []AlleleCall `json:"alleles"`
+	Method        string       `json:"method"`
+	ActivityScore *float64     `json:"activity_score,omitempty"`
+	Phenotype     string       `json:"phenotype"`
+	TableVersion  string       `json:"table_version"`
+	Notes         []string     `json:"notes,omitempty"`
+}
+
+// GenotypeError reports a genotype that cannot be translated (unsupported gene,
+// unparseable diplotype, unknown allele or inconsistent copy number)
+type GenotypeError struct {
+	Gene      string
+	Diplotype string
+	Reason    string
+}
+
+func (e *GenotypeError) Error() string {
+	return fmt.Sprintf("cannot translate %s genotype %q: %s", e.Gene, e.Diplotype, e.Reason)
+}
+
+// pgxGeneTable holds the translation tables for one gene
+type pgxGeneTable struct {
+	method  string
+	alleles map[string]PGXAlleleFunctionRow // star allele -> function
+	rules   []PGXPhenotypeRuleRow
+}
+
+// GenotypeTranslator translates star-allele diplotypes into the phenotype labels
+// used by ddi_pharmacogenomic_rules, using the current version of the
+// pgx_allele_functions and pgx_phenotype_rules tables. Call Reload after the
+// tables are updated.
+type GenotypeTranslator struct {
+	db      *database.Database
+	metrics *metrics.Collector
+
+	version string
+	genes   map[string]*pgxGeneTable
+	stats   PGXTranslationStats
+	mu      sync.RWMutex
+}
+
+// NewGenotypeTranslator creates an empty translator. Translate fails until
+// Reload or Build has loaded a table version.
+func NewGenotypeTranslator(db *database.Database, metrics *metrics.Collector) *GenotypeTranslator {
+	return &GenotypeTranslator{
+		db:      db,
+		metrics: metrics,
+		genes:   make(map[string]*pgxGeneTable),
+	}
+}
+
+// Reload loads the current translation table version. The previous tables stay
+// in service until the new ones are complete.
+func (gt *GenotypeTranslator) Reload(ctx context.Context) (*PGXTranslationStats, error) {
+	if gt.db == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+
+	timer := time.Now()
+
+	var version string
+	err := gt.db.DB.WithContext(ctx).Raw(`
+		SELECT table_version FROM pgx_translation_versions
+		WHERE is_current = TRUE
+		LIMIT 1`).
+		Row().Scan(&version)
+	if err != nil {
+		gt.metrics.RecordDatabaseQuery("pgx_translation_load", "pgx_translation_versions", "error", time.Since(timer))
+		return nil, fmt.Errorf("failed to find current PGx translation table: %w", err)
+	}
+
+	var alleles []PGXAlleleFunctionRow
+	err = gt.db.DB.WithContext(ctx).Raw(`
+		SELECT gene, allele, function_status, activity_value
+		FROM pgx_allele_functions
+		WHERE table_version = ?`, version).
+		Scan(&alleles).Error
+	if err != nil {
+		gt.metrics.RecordDatabaseQuery("pgx_translation_load", "pgx_allele_functions", "error", time.Since(timer))
+		return nil, fmt.Errorf("failed to load allele functions: %w", err)
+	}
+
+	var rules []PGXPhenotypeRuleRow
+	err = gt.db.DB.WithContext(ctx).Raw(`
+		SELECT gene, method, min_activity_score, max_activity_score,
+		       function_1, function_2, phenotype
+		FROM pgx_phenotype_rules
+		WHERE table_version = ?
+		ORDER BY gene, id`, version).
+		Scan(&rules).Error
+	if err != nil {
+		gt.metrics.RecordDatabaseQuery("pgx_translation_load", "pgx_phenotype_rules", "error", time.Since(timer))
+		return nil, fmt.Errorf("failed to load phenotype rules: %w", err)
+	}
+
+	stats := gt.Build(version, alleles, rules)
+	gt.metrics.RecordDatabaseQuery("pgx_translation_load", "pgx_phenotype_rules", "success", time.Since(timer))
+
+	return stats, nil
+}
+
+// Build replaces the translation tables with the given allele functions and
+// phenotype rules for version
+func (gt *GenotypeTranslator) Build(version string, alleles []PGXAlleleFunctionRow, rules []PGXPhenotypeRuleRow) *PGXTranslationStats {
+	genes := make(map[string]*pgxGeneTable)
+	geneTable := func(gene string) *pgxGeneTable {
+		gene = strings.ToUpper(strings.TrimSpace(gene))
+		if genes[gene] == nil {
+			genes[gene] = &pgxGeneTable{alleles: make(map[string]PGXAlleleFunctionRow)}
+		}
+		return genes[gene]
+	}
+
+	for _, a := range alleles {
+		geneTable(a.Gene).alleles[normalizeStarAllele(a.Allele)] = a
+	}
+	for _, r := range rules {
+		table := geneTable(r.Gene)
+		table.method = r.Method
+		table.rules = append(table.rules, r)
+	}
+
+	stats := PGXTranslationStats{
+		TableVersion: version,
+		Genes:        len(genes),
+		Alleles:      len(alleles),
+		Rules:        len(rules),
+		LoadedAt:     time.Now().UTC(),
+	}
+
+	gt.mu.Lock()
+	gt.version = version
+	gt.genes = genes
+	gt.stats = stats
+	gt.mu.Unlock()
+
+	return &stats
+}
+
+// Version returns the loaded translation table version ("" if none)
+func (gt *GenotypeTranslator) Version() string {
+	gt.mu.RLock()
+	defer gt.mu.RUnlock()
+	return gt.version
+}
+
+// Stats returns statistics for the loaded tables
+func (gt *GenotypeTranslator) Stats() PGXTranslationStats {
+	gt.mu.RLock()
+	defer gt.mu.RUnlock()
+	return gt.stats
+}
+
+// SupportedGenes returns the genes with translation tables, sorted
+func (gt *GenotypeTranslator) SupportedGenes() []string {
+	gt.mu.RLock()
+	defer gt.mu.RUnlock()
+
+	genes := make([]string, 0, len(gt.genes))
+	for gene, table := range gt.genes {
+		if len(table.rules) > 0 {
+			genes = append(genes, gene)
+		}
+	}
+	sort.Strings(genes)
+	return genes
+}
+
+// Phenotypes returns the phenotypes each gene's rules can assign
+func (gt *GenotypeTranslator) Phenotypes() map[string][]string {
+	gt.mu.RLock()
+	defer gt.mu.RUnlock()
+
+	phenotypes := make(map[string][]string, len(gt.genes))
+	for gene, table := range gt.genes {
+		for _, rule := range table.rules {
+			phenotypes[gene] = appendUnique(phenotypes[gene], rule.Phenotype)
+		}
+	}
+	return phenotypes
+}
+
+// PhenotypeForActivityScore returns the phenotype for an activity score on an
+// activity-score gene
+func (gt *GenotypeTranslator) PhenotypeForActivityScore(gene string, score float64) (string, bool) {
+	gt.mu.RLock()
+	table := gt.genes[strings.ToUpper(gene)]
+	gt.mu.RUnlock()
+
+	if table == nil || table.method != PGXMethodActivityScore {
+		return "", false
+	}
+	phenotype := activityScorePhenotype(table.rules, score)
+	return phenotype, phenotype != PGXPhenotypeIndeterminate
+}
+
+// Translate derives the phenotype for a gene from a lab-reported diplotype.
+// Genotypes that are valid but cannot be assigned a single phenotype (uncertain
+// function alleles, ambiguous copy number) are returned with phenotype
+// "indeterminate" and an explanatory note.
+func (gt *GenotypeTranslator) Translate(gene string, genotype models.PGXGenotype) (*GenotypeTranslation, error) {
+	gene = strings.ToUpper(strings.TrimSpace(gene))
+
+	gt.mu.RLock()
+	version := gt.version
+	table := gt.genes[gene]
+	gt.mu.RUnlock()
+
+	if version == "" {
+		return nil, fmt.Errorf("PGx translation tables not loaded")
+	}
+	if table == nil || len(table.rules) == 0 {
+		return nil, &GenotypeError{Gene: gene, Diplotype: genotype.Diplotype,
+			Reason: fmt.Sprintf("gene not covered by translation table %s", version)}
+	}
+
+	calls, err := parseDiplotype(gene, genotype.Diplotype)
+	if err != nil {
+		return nil, &GenotypeError{Gene: gene, Diplotype: genotype.Diplotype, Reason: err.Error()}
+	}
+
+	for i := range calls {
+		allele, exists := table.alleles[calls[i].Allele]
+		if !exists {
+			return nil, &GenotypeError{Gene: gene, Diplotype: genotype.Diplotype,
+				Reason: fmt.Sprintf("allele %s not in translation table %s", calls[i].Allele, version)}
+		}
+		calls[i].Function = allele.FunctionStatus
+		calls[i].ActivityValue = allele.ActivityValue
+	}
+
+	translation := &GenotypeTranslation{
+		Gene:         gene,
+		Diplotype:    genotype.Diplotype,
+		CopyNumber:   genotype.CopyNumber,
+		Method:       table.method,
+		TableVersion: version,
+	}
+
+	switch table.method {
+	case PGXMethodActivityScore:
+		if err := translateActivityScore(table, calls, genotype.CopyNumber, translation); err != nil {
+			return nil, &GenotypeError{Gene: gene, Diplotype: genotype.Diplotype, Reason: err.Error()}
+		}
+	default:
+		translateFunctionPair(table, calls, translation)
+	}
+
+	return translation, nil
+}
+
+// translateActivityScore sums allele activity values, resolving gene duplications
+// and deletions against the reported copy number
+func translateActivityScore(table *pgxGeneTable, calls []AlleleCall, copyNumber *int, translation *GenotypeTranslation) error {
+	for _, call := range calls {
+		if call.ActivityValue == nil {
+			translation.Alleles = calls
+			translation.Phenotype = PGXPhenotypeIndeterminate
+			translation.Notes = append(translation.Notes,
+				fmt.Sprintf("%s has %s function and no activity value", call.Allele, call.Function))
+			return nil
+		}
+	}
+
+	candidates, notes, err := resolveAlleleCopies(translation.Gene, calls, copyNumber)
+	if err != nil {
+		return err
+	}
+	translation.Notes = append(translation.Notes, notes...)
+
+	// Every plausible copy assignment must agree on the phenotype
+	phenotypes := make(map[string]bool)
+	minScore, maxScore := math.Inf(1), math.Inf(-1)
+	for _, copies := range candidates {
+		score := 0.0
+		for i, call := range calls {
+			score += *call.ActivityValue * float64(copies[i])
+		}
+		minScore = math.Min(minScore, score)
+		maxScore = math.Max(maxScore, score)
+		phenotypes[activityScorePhenotype(table.rules, score)] = true
+	}
+
+	if len(candidates) == 1 {
+		for i := range calls {
+			calls[i].Copies = candidates[0][i]
+		}
+	}
+	translation.Alleles = calls
+
+	if minScore == maxScore {
+		score := minScore
+		translation.ActivityScore = &score
+	} else {
+		translation.Notes = append(translation.Notes,
+			fmt.Sprintf("activity score between %s and %s", formatActivityScore(minScore), formatActivityScore(maxScore)))
+	}
+
+	if len(phenotypes) == 1 {
+		for phenotype := range phenotypes {
+			translation.Phenotype = phenotype
+		}
+	} else {
+		translation.Phenotype = PGXPhenotypeIndeterminate
+		translation.Notes = append(translation.Notes, "possible copy numbers map to different phenotypes")
+	}
+	if translation.Phenotype == PGXPhenotypeIndeterminate && len(phenotypes) == 1 {
+		translation.Notes = append(translation.Notes, "activity score not covered by phenotype rules")
+	}
+
+	return nil
+}
+
+// resolveAlleleCopies returns every plausible per-allele copy assignment.
+// Deletion alleles count as zero copies. The reported copy number is the total
+// number of gene copies across both chromosomes.
+func resolveAlleleCopies(gene string, calls []AlleleCall, copyNumber *int) ([][]int, []string, error) {
+	var notes []string
+	base := make([]int, len(calls))
+	present := 0
+	var unresolved, expandable []int
+
+	for i, call := range calls {
+		if call.Allele == pgxDeletionAlleles[gene] {
+			base[i] = 0
+			continue
+		}
+		if call.unresolved {
+			unresolved = append(unresolved, i)
+			continue
+		}
+		base[i] = call.Copies
+		present += call.Copies
+		expandable = append(expandable, i)
+	}
+
+	if copyNumber == nil {
+		if len(unresolved) == 0 {
+			return [][]int{base}, notes, nil
+		}
+		notes = append(notes, "duplication copy count not reported")
+		var candidates [][]int
+		for _, n := range pgxUnresolvedDuplicationCopies {
+			candidate := append([]int(nil), base...)
+			for _, i := range unresolved {
+				candidate[i] = n
+			}
+			candidates = append(candidates, candidate)
+		}
+		return candidates, notes, nil
+	}
+
+	total := *copyNumber
+	if total < 0 {
+		return nil, nil, fmt.Errorf("copy number must not be negative")
+	}
+	extra := total - present
+
+	switch {
+	case len(unresolved) > 0:
+		// Split the remaining copies across the "xN" alleles (at least two each)
+		if extra < 2*len(unresolved) {
+			return nil, nil, fmt.Errorf("copy number %d is too low for the duplications in the diplotype", total)
+		}
+		if len(unresolved) == 1 {
+			candidate := append([]int(nil), base...)
+			candidate[unresolved[0]] = extra
+			return [][]int{candidate}, notes, nil
+		}
+		var candidates [][]int
+		for n := 2; n <= extra-2; n++ {
+			candidate := append([]int(nil), base...)
+			candidate[unresolved[0]] = n
+			candidate[unresolved[1]] = extra - n
+			candidates = append(candidates, candidate)
+		}
+		notes = append(notes, "duplicated copies could not be assigned to an allele")
+		return candidates, notes, nil
+
+	case extra == 0:
+		return [][]int{base}, notes, nil
+
+	case extra < 0:
+		return nil, nil, fmt.Errorf("copy number %d is lower than the %d gene copies implied by the diplotype", total, present)
+
+	default:
+		if len(expandable) == 0 {
+			return nil, nil, fmt.Errorf("copy number %d but the diplotype has no gene copies to duplicate", total)
+		}
+
+		// Extra copies belong to one allele; which one is unknown unless both are the same
+		var candidates [][]int
+		seen := make(map[string]bool)
+		for _, i := range expandable {
+			if seen[calls[i].Allele] {
+				continue
+			}
+			seen[calls[i].Allele] = true
+			candidate := append([]int(nil), base...)
+			candidate[i] += extra
+			candidates = append(candidates, candidate)
+		}
+		if len(candidates) > 1 {
+			notes = append(notes, fmt.Sprintf("copy number %d implies a duplication of an unspecified allele", total))
+		}
+		return candidates, notes, nil
+	}
+}
+
+// activityScorePhenotype returns the phenotype whose inclusive score range contains score
+func activityScorePhenotype(rules []PGXPhenotypeRuleRow, score float64) string {
+	const epsilon = 1e-9
+	for _, rule := range rules {
+		if rule.Method != PGXMethodActivityScore {
+			continue
+		}
+		if rule.MinActivityScore != nil && score < *rule.MinActivityScore-epsilon {
+			continue
+		}
+		if rule.MaxActivityScore != nil && score > *rule.MaxActivityScore+epsilon {
+			continue
+		}
+		return rule.Phenotype
+	}
+	return PGXPhenotypeIndeterminate
+}
+
+// translateFunctionPair looks up the unordered pair of allele function statuses
+func translateFunctionPair(table *pgxGeneTable, calls []AlleleCall, translation *GenotypeTranslation) {
+	translation.Alleles = calls
+	translation.Phenotype = PGXPhenotypeIndeterminate
+
+	for _, call := range calls {
+		if call.Copies != 1 || call.unresolved {
+			translation.Notes = append(translation.Notes,
+				fmt.Sprintf("%s duplication ignored - phenotype is assigned from allele function only", call.Allele))
+		}
+	}
+
+	f1, f2 := calls[0].Function, calls[1].Function
+	for _, rule := range table.rules {
+		if rule.Method != PGXMethodFunctionPair || rule.Function1 == nil || rule.Function2 == nil {
+			continue
+		}
+		if (*rule.Function1 == f1 && *rule.Function2 == f2) || (*rule.Function1 == f2 && *rule.Function2 == f1) {
+			translation.Phenotype = rule.Phenotype
+			return
+		}
+	}
+
+	translation.Notes = append(translation.Notes,
+		fmt.Sprintf("no phenotype rule for %s/%s function", f1, f2))
+}
+
+// parseDiplotype parses "*1/*4", "CYP2D6*1x2/*4" or "*2xN/*41" into two allele calls
+func parseDiplotype(gene, diplotype string) ([]AlleleCall, error) {
+	value := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(diplotype), " ", ""))
+	value = strings.ReplaceAll(value, gene+"*", "*")
+	if value == "" {
+		return nil, fmt.Errorf("diplotype is empty")
+	}
+
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("diplotype must have two alleles separated by '/'")
+	}
+
+	calls := make([]AlleleCall, 0, 2)
+	for _, part := range parts {
+		call := AlleleCall{Copies: 1}
+
+		if idx := strings.Index(part, "X"); idx > 0 {
+			multiplier := part[idx+1:]
+			part = part[:idx]
+			if multiplier == "N" {
+				call.unresolved = true
+				call.Copies = 0
+			} else {
+				n, err := strconv.Atoi(multiplier)
+				if err != nil || n < 1 {
+					return nil, fmt.Errorf("invalid duplication %q", "x"+multiplier)
+				}
+				call.Copies = n
+			}
+		}
+
+		call.Allele = normalizeStarAllele(part)
+		if call.Allele == "*" {
+			return nil, fmt.Errorf("allele is empty")
+		}
+		calls = append(calls, call)
+	}
+
+	return calls, nil
+}
+
+// normalizeStarAllele formats an allele as "*<n>" (accepts "4" or "*4")
+func normalizeStarAllele(allele string) string {
+	allele = strings.ToUpper(strings.TrimSpace(allele))
+	if !strings.HasPrefix(allele, "*") {
+		allele = "*" + allele
+	}
+	return allele
+}
+
+func formatActivityScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
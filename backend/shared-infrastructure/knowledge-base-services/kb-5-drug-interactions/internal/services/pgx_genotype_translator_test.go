@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// GENOTYPE TRANSLATOR TESTS
+// ============================================================================
+
+func floatPtr(v float64) *float64 { return &v }
+func stringPtr(v string) *string  { return &v }
+func intPtr(v int) *int           { return &v }
+func boolPtr(v bool) *bool        { return &v }
+
+func newTestGenotypeTranslator() *GenotypeTranslator {
+	gt := NewGenotypeTranslator(nil, nil)
+	gt.Build("TEST-1",
+		[]PGXAlleleFunctionRow{
+			{Gene: "CYP2D6", Allele: "*1", FunctionStatus: "normal", ActivityValue: floatPtr(1)},
+			{Gene: "CYP2D6", Allele: "*2", FunctionStatus: "normal", ActivityValue: floatPtr(1)},
+			{Gene: "CYP2D6", Allele: "*4", FunctionStatus: "no", ActivityValue: floatPtr(0)},
+			{Gene: "CYP2D6", Allele: "*5", FunctionStatus: "no", ActivityValue: floatPtr(0)},
+			{Gene: "CYP2D6", Allele: "*10", FunctionStatus: "decreased", ActivityValue: floatPtr(0.25)},
+			{Gene: "CYP2D6", Allele: "*41", FunctionStatus: "decreased", ActivityValue: floatPtr(0.5)},
+			{Gene: "CYP2D6", Allele: "*22", FunctionStatus: "uncertain"},
+			{Gene: "CYP2C19", Allele: "*1", FunctionStatus: "normal"},
+			{Gene: "CYP2C19", Allele: "*2", FunctionStatus: "no"},
+			{Gene: "CYP2C19", Allele: "*17", FunctionStatus: "increased"},
+		},
+		[]PGXPhenotypeRuleRow{
+			{Gene: "CYP2D6", Method: PGXMethodActivityScore, MinActivityScore: floatPtr(0), MaxActivityScore: floatPtr(0), Phenotype: "poor"},
+			{Gene: "CYP2D6", Method: PGXMethodActivityScore, MinActivityScore: floatPtr(0.25), MaxActivityScore: floatPtr(1), Phenotype: "intermediate"},
+			{Gene: "CYP2D6", Method: PGXMethodActivityScore, MinActivityScore: floatPtr(1.25), MaxActivityScore: floatPtr(2.25), Phenotype: "normal"},
+			{Gene: "CYP2D6", Method: PGXMethodActivityScore, MinActivityScore: floatPtr(2.5), Phenotype: "ultrarapid"},
+			{Gene: "CYP2C19", Method: PGXMethodFunctionPair, Function1: stringPtr("normal"), Function2: stringPtr("normal"), Phenotype: "normal"},
+			{Gene: "CYP2C19", Method: PGXMethodFunctionPair, Function1: stringPtr("normal"), Function2: stringPtr("increased"), Phenotype: "rapid"},
+			{Gene: "CYP2C19", Method: PGXMethodFunctionPair, Function1: stringPtr("increased"), Function2: stringPtr("no"), Phenotype: "intermediate"},
+		},
+	)
+	return gt
+}
+
+func TestGenotypeTranslator_CYP2D6ActivityScore(t *testing.T) {
+	gt := newTestGenotypeTranslator()
+
+	tests := []struct {
+		diplotype string
+		score     float64
+		phenotype string
+	}{
+		{"*1/*4", 1, "intermediate"},
+		{"*1/*1", 2, "normal"},
+		{"*1/*10", 1.25, "normal"},
+		{"*4/*5", 0, "poor"},
+		{"*1x2/*41", 2.5, "ultrarapid"},
+		{"CYP2D6*1/*4", 1, "intermediate"},
+		{"1/4", 1, "intermediate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.diplotype, func(t *testing.T) {
+			translation, err := gt.Translate("cyp2d6", models.PGXGenotype{Diplotype: tt.diplotype})
+			require.NoError(t, err)
+			require.NotNil(t, translation.ActivityScore)
+			assert.InDelta(t, tt.score, *translation.ActivityScore, 1e-9)
+			assert.Equal(t, tt.phenotype, translation.Phenotype)
+			assert.Equal(t, "TEST-1", translation.TableVersion)
+			assert.Equal(t, PGXMethodActivityScore, translation.Method)
+		})
+	}
+}
+
+func TestGenotypeTranslator_CYP2D6CopyNumber(t *testing.T) {
+	gt := newTestGenotypeTranslator()
+
+	// Both alleles identical - the extra copy is a *1 either way
+	translation, err := gt.Translate("CYP2D6", models.PGXGenotype{Diplotype: "*1/*1", CopyNumber: intPtr(3)})
+	require.NoError(t, err)
+	assert.Equal(t, "ultrarapid", translation.Phenotype)
+	assert.InDelta(t, 3, *translation.ActivityScore, 1e-9)
+
+	// Unknown duplicated allele: *1x2/*4 (normal) or *1/*4x2 (intermediate)
+	translation, err = gt.Translate("CYP2D6", models.PGXGenotype{Diplotype: "*1/*4", CopyNumber: intPtr(3)})
+	require.NoError(t, err)
+	assert.Equal(t, PGXPhenotypeIndeterminate, translation.Phenotype)
+	assert.Nil(t, translation.ActivityScore)
+	assert.NotEmpty(t, translation.Notes)
+
+	// "xN" resolved by copy number
+	translation, err = gt.Translate("CYP2D6", models.PGXGenotype{Diplotype: "*2xN/*4", CopyNumber: intPtr(3)})
+	require.NoError(t, err)
+	assert.Equal(t, "normal", translation.Phenotype)
+	assert.Equal(t, 2, translation.Alleles[0].Copies)
+
+	// "xN" without copy number: x2 (normal) or x3 (ultrarapid)
+	translation, err = gt.Translate("CYP2D6", models.PGXGenotype{Diplotype: "*2xN/*4"})
+	require.NoError(t, err)
+	assert.Equal(t, PGXPhenotypeIndeterminate, translation.Phenotype)
+
+	// "xN" of a no-function allele is poor regardless of count
+	translation, err = gt.Translate("CYP2D6", models.PGXGenotype{Diplotype: "*4xN/*4"})
+	require.NoError(t, err)
+	assert.Equal(t, "poor", translation.Phenotype)
+
+	// Deletion allele counts as zero copies
+	translation, err = gt.Translate("CYP2D6", models.PGXGenotype{Diplotype: "*1/*5", CopyNumber: intPtr(1)})
+	require.NoError(t, err)
+	assert.Equal(t, "intermediate", translation.Phenotype)
+
+	// Copy number below the diplotype is inconsistent
+	_, err = gt.Translate("CYP2D6", models.PGXGenotype{Diplotype: "*1x2/*4", CopyNumber: intPtr(2)})
+	var genotypeErr *GenotypeError
+	assert.True(t, errors.As(err, &genotypeErr))
+}
+
+func TestGenotypeTranslator_UncertainFunction(t *testing.T) {
+	gt := newTestGenotypeTranslator()
+
+	translation, err := gt.Translate("CYP2D6", models.PGXGenotype{Diplotype: "*1/*22"})
+	require.NoError(t, err)
+	assert.Equal(t, PGXPhenotypeIndeterminate, translation.Phenotype)
+	assert.NotEmpty(t, translation.Notes)
+}
+
+func TestGenotypeTranslator_CYP2C19FunctionPair(t *testing.T) {
+	gt := newTestGenotypeTranslator()
+
+	tests := map[string]string{
+		"*1/*1":  "normal",
+		"*17/*1": "rapid",
+		"*2/*17": "intermediate",
+		"*2/*2":  PGXPhenotypeIndeterminate, // No rule in the test table
+	}
+
+	for diplotype, phenotype := range tests {
+		translation, err := gt.Translate("CYP2C19", models.PGXGenotype{Diplotype: diplotype})
+		require.NoError(t, err, diplotype)
+		assert.Equal(t, phenotype, translation.Phenotype, diplotype)
+		assert.Nil(t, translation.ActivityScore, diplotype)
+	}
+}
+
+func TestGenotypeTranslator_InvalidGenotypes(t *testing.T) {
+	gt := newTestGenotypeTranslator()
+
+	invalid := []struct {
+		gene      string
+		diplotype string
+	}{
+		{"CYP2D6", "*1/*99"},   // Unknown allele
+		{"CYP2D6", "*1"},       // Single allele
+		{"CYP2D6", "*1/*4/*5"}, // Three alleles
+		{"CYP2D6", "*1x0/*4"},  // Invalid duplication
+		{"TPMT", "*1/*3A"},     // Gene without tables
+		{"CYP2C19", ""},        // Empty
+	}
+
+	for _, tt := range invalid {
+		_, err := gt.Translate(tt.gene, models.PGXGenotype{Diplotype: tt.diplotype})
+		var genotypeErr *GenotypeError
+		assert.True(t, errors.As(err, &genotypeErr), "%s %s", tt.gene, tt.diplotype)
+	}
+
+	// No tables loaded is not a genotype error
+	_, err := NewGenotypeTranslator(nil, nil).Translate("CYP2D6", models.PGXGenotype{Diplotype: "*1/*1"})
+	var genotypeErr *GenotypeError
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &genotypeErr))
+}
+
+func TestGenotypeTranslator_SupportedGenes(t *testing.T) {
+	gt := newTestGenotypeTranslator()
+	assert.Equal(t, []string{"CYP2C19", "CYP2D6"}, gt.SupportedGenes())
+	assert.Equal(t, "TEST-1", gt.Version())
+}
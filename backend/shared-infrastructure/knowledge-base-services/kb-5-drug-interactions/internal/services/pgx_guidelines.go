@@ -0,0 +1,550 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// Guideline classification strengths (pgx_guidelines.classification)
+const (
+	GuidelineClassificationStrong           = "strong"
+	GuidelineClassificationModerate         = "moderate"
+	GuidelineClassificationOptional         = "optional"
+	GuidelineClassificationNoRecommendation = "no_recommendation"
+)
+
+// Guideline dosing actions (pgx_guidelines.dosing_action)
+const (
+	DosingActionAvoid               = "avoid"
+	DosingActionConsiderAlternative = "consider_alternative"
+	DosingActionReduceDose          = "reduce_dose"
+	DosingActionIncreaseDose        = "increase_dose"
+	DosingActionUseStandard         = "use_standard"
+	DosingActionMonitor             = "monitor"
+)
+
+var guidelineClassificationRank = map[string]int{
+	GuidelineClassificationStrong:           3,
+	GuidelineClassificationModerate:         2,
+	GuidelineClassificationOptional:         1,
+	GuidelineClassificationNoRecommendation: 0,
+}
+
+var validDosingActions = map[string]bool{
+	DosingActionAvoid:               true,
+	DosingActionConsiderAlternative: true,
+	DosingActionReduceDose:          true,
+	DosingActionIncreaseDose:        true,
+	DosingActionUseStandard:         true,
+	DosingActionMonitor:             true,
+}
+
+// PGXGuideline is gene-phenotype-drug guidance from a CPIC/DPWG guideline
+type PGXGuideline struct {
+	ID             int64              `json:"id" gorm:"primaryKey"`
+	Gene           string             `json:"gene"`
+	Phenotype      string             `json:"phenotype"`
+	DrugCode       string             `json:"drug_code"`
+	DrugName       string             `json:"drug_name"`
+	Recommendation string             `json:"recommendation"`
+	Implications   *string            `json:"implications,omitempty"`
+	Classification string             `json:"classification"`
+	DosingAction   string             `json:"dosing_action"`
+	Alternatives   models.StringArray `json:"alternatives" gorm:"type:text[]"`
+	Source         string             `json:"source"`
+	SourceVersion  string             `json:"source_version"`
+	Active         bool               `json:"active"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (PGXGuideline) TableName() string {
+	return "pgx_guidelines"
+}
+
+// GuidelineImportResult summarizes a guideline file import
+type GuidelineImportResult struct {
+	RowsRead     int           `json:"rows_read"`
+	RowsImported int           `json:"rows_imported"`
+	Genes        []string      `json:"genes"`
+	Issues       []ImportIssue `json:"issues,omitempty"`
+}
+
+// =============================================================================
+// Lookup
+// =============================================================================
+
+// LookupGuidelines returns active guidelines matching the given filters. Empty
+// filters match everything; phenotypes may be given as CPIC labels.
+func (pge *PharmacogenomicEngine) LookupGuidelines(
+	ctx context.Context,
+	gene string,
+	phenotype string,
+	drugCode string,
+) ([]PGXGuideline, error) {
+	guidelines, err := pge.loadGuidelines(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PGx guidelines: %w", err)
+	}
+
+	gene = strings.ToUpper(strings.TrimSpace(gene))
+	if phenotype != "" {
+		if normalized, ok := NormalizePGXPhenotype(phenotype); ok {
+			phenotype = normalized
+		}
+	}
+
+	var candidates []PGXGuideline
+	if drugCode != "" {
+		candidates = guidelines[normalizeATCDrugKey(drugCode)]
+	} else {
+		for _, rows := range guidelines {
+			candidates = append(candidates, rows...)
+		}
+	}
+
+	matches := []PGXGuideline{}
+	for _, g := range candidates {
+		if gene != "" && g.Gene != gene {
+			continue
+		}
+		if phenotype != "" && g.Phenotype != strings.ToLower(phenotype) {
+			continue
+		}
+		matches = append(matches, g)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Gene != matches[j].Gene {
+			return matches[i].Gene < matches[j].Gene
+		}
+		if matches[i].DrugCode != matches[j].DrugCode {
+			return matches[i].DrugCode < matches[j].DrugCode
+		}
+		if matches[i].Phenotype != matches[j].Phenotype {
+			return matches[i].Phenotype < matches[j].Phenotype
+		}
+		return matches[i].Source < matches[j].Source
+	})
+
+	return matches, nil
+}
+
+// SupportedPGXPhenotypes returns the genes the service can act on and their valid
+// phenotypes: every gene with guidelines, dose-critical safety rules or
+// translation tables, plus the phenoconversion activity scales.
+func (pge *PharmacogenomicEngine) SupportedPGXPhenotypes(ctx context.Context) (map[string][]string, error) {
+	guidelines, err := pge.loadGuidelines(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PGx guidelines: %w", err)
+	}
+
+	supported := make(map[string][]string)
+	for _, rows := range guidelines {
+		for _, g := range rows {
+			supported[g.Gene] = appendUnique(supported[g.Gene], g.Phenotype)
+		}
+	}
+	safetyRules, err := pge.loadSafetyRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PGx safety rules: %w", err)
+	}
+	for _, rows := range safetyRules {
+		for _, r := range rows {
+			if r.Phenotype != nil {
+				supported[r.Gene] = appendUnique(supported[r.Gene], strings.ToLower(*r.Phenotype))
+			}
+		}
+	}
+	if pge.translator != nil {
+		for gene, phenotypes := range pge.translator.Phenotypes() {
+			for _, p := range phenotypes {
+				if p != PGXPhenotypeIndeterminate {
+					supported[gene] = appendUnique(supported[gene], p)
+				}
+			}
+		}
+	}
+	for gene, scale := range pgxPhenotypeScales {
+		if _, exists := supported[gene]; !exists {
+			continue // Only genes that have guidance or translation tables
+		}
+		for _, p := range scale {
+			supported[gene] = appendUnique(supported[gene], p)
+		}
+	}
+
+	for gene := range supported {
+		sort.Strings(supported[gene])
+	}
+	return supported, nil
+}
+
+// selectGuideline picks the guideline for a gene/phenotype/drug, preferring the
+// strongest classification when several sources cover the same pair
+func selectGuideline(guidelines []PGXGuideline, gene, phenotype string) *PGXGuideline {
+	var best *PGXGuideline
+	for i := range guidelines {
+		g := &guidelines[i]
+		if g.Gene != strings.ToUpper(gene) || g.Phenotype != strings.ToLower(phenotype) {
+			continue
+		}
+		if best == nil || guidelineClassificationRank[g.Classification] > guidelineClassificationRank[best.Classification] {
+			best = g
+		}
+	}
+	return best
+}
+
+// guidelineAlternatives returns the alternatives listed for a drug-gene pair
+// across all phenotypes, in listed order
+func guidelineAlternatives(guidelines []PGXGuideline, gene string) []string {
+	alternatives := []string{}
+	for _, g := range guidelines {
+		if g.Gene != strings.ToUpper(gene) {
+			continue
+		}
+		for _, alt := range g.Alternatives {
+			alternatives = appendUnique(alternatives, alt)
+		}
+	}
+	return alternatives
+}
+
+// loadGuidelines returns active guidelines keyed by normalized drug code
+func (pge *PharmacogenomicEngine) loadGuidelines(ctx context.Context) (map[string][]PGXGuideline, error) {
+	pge.guidelineMu.Lock()
+	defer pge.guidelineMu.Unlock()
+
+	if pge.guidelines != nil && time.Since(pge.guidelinesLoaded) < pge.cacheTTL {
+		return pge.guidelines, nil
+	}
+
+	var rows []PGXGuideline
+	err := pge.db.DB.WithContext(ctx).
+		Where("active = TRUE").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	guidelines := make(map[string][]PGXGuideline)
+	for _, row := range rows {
+		row.Gene = strings.ToUpper(row.Gene)
+		row.Phenotype = strings.ToLower(row.Phenotype)
+		key := normalizeATCDrugKey(row.DrugCode)
+		guidelines[key] = append(guidelines[key], row)
+	}
+
+	pge.guidelines = guidelines
+	pge.guidelinesLoaded = time.Now()
+
+	return guidelines, nil
+}
+
+// =============================================================================
+// Import
+// =============================================================================
+
+// ImportGuidelines upserts parsed guidelines. A row replaces the existing
+// guidance for the same gene, phenotype, drug and source, so importing a newer
+// guideline version updates recommendations in place.
+func (pge *PharmacogenomicEngine) ImportGuidelines(ctx context.Context, guidelines []PGXGuideline) (int, error) {
+	if len(guidelines) == 0 {
+		return 0, nil
+	}
+
+	tx := pge.db.DB.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return 0, fmt.Errorf("failed to begin guideline import: %w", tx.Error)
+	}
+	defer tx.Rollback()
+
+	for _, g := range guidelines {
+		err := tx.Exec(`
+			INSERT INTO pgx_guidelines (gene, phenotype, drug_code, drug_name, recommendation, implications,
+				classification, dosing_action, alternatives, source, source_version, active)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, TRUE)
+			ON CONFLICT (gene, phenotype, drug_code, source) DO UPDATE SET
+				drug_name = EXCLUDED.drug_name,
+				recommendation = EXCLUDED.recommendation,
+				implications = EXCLUDED.implications,
+				classification = EXCLUDED.classification,
+				dosing_action = EXCLUDED.dosing_action,
+				alternatives = EXCLUDED.alternatives,
+				source_version = EXCLUDED.source_version,
+				active = TRUE,
+				updated_at = NOW()`,
+			g.Gene, g.Phenotype, g.DrugCode, g.DrugName, g.Recommendation, g.Implications,
+			g.Classification, g.DosingAction, g.Alternatives, g.Source, g.SourceVersion).Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to import %s %s guideline for %s: %w", g.Gene, g.Phenotype, g.DrugCode, err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return 0, fmt.Errorf("failed to commit guideline import: %w", err)
+	}
+
+	pge.guidelineMu.Lock()
+	pge.guidelines = nil
+	pge.guidelineMu.Unlock()
+
+	return len(guidelines), nil
+}
+
+// guidelineColumnAliases maps accepted header names to guideline fields
+var guidelineColumnAliases = map[string]string{
+	"gene":                             "gene",
+	"phenotype":                        "phenotype",
+	"drug_code":                        "drug_code",
+	"rxcui":                            "drug_code",
+	"rxnorm":                           "drug_code",
+	"drug_name":                        "drug_name",
+	"drug":                             "drug_name",
+	"recommendation":                   "recommendation",
+	"therapeutic_recommendation":       "recommendation",
+	"implications":                     "implications",
+	"classification":                   "classification",
+	"classification_of_recommendation": "classification",
+	"strength":                         "classification",
+	"dosing_action":                    "dosing_action",
+	"action":                           "dosing_action",
+	"alternatives":                     "alternatives",
+	"source":                           "source",
+	"source_version":                   "source_version",
+	"guideline_version":                "source_version",
+}
+
+// ParseGuidelineFile parses a CPIC-style guideline table (CSV or TSV with a
+// header row). format is "csv", "tsv" or "" to detect from the header.
+// sourceVersion is used for rows without a source_version column. Rows that
+// cannot be used are reported as issues rather than failing the file.
+func ParseGuidelineFile(r io.Reader, format string, sourceVersion string) ([]PGXGuideline, []ImportIssue, error) {
+	reader, columns, err := openDelimitedFile(r, format, "guideline", guidelineColumnAliases)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, required := range []string{"gene", "phenotype", "drug_code", "recommendation"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("guideline file is missing required column %q", required)
+		}
+	}
+	if _, ok := columns["source_version"]; !ok && sourceVersion == "" {
+		return nil, nil, fmt.Errorf("guideline file has no source_version column and no source version was given")
+	}
+
+	var guidelines []PGXGuideline
+	var issues []ImportIssue
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			issues = append(issues, ImportIssue{Line: line, Reason: err.Error()})
+			continue
+		}
+
+		field := recordField(record, columns)
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		guideline, reason := buildGuideline(field, sourceVersion)
+		if reason != "" {
+			issues = append(issues, ImportIssue{Line: line, Reason: reason})
+			continue
+		}
+		guidelines = append(guidelines, guideline)
+	}
+
+	return guidelines, issues, nil
+}
+
+// buildGuideline validates and normalizes one guideline row. Returns a reason
+// when the row cannot be imported.
+func buildGuideline(field func(string) string, defaultSourceVersion string) (PGXGuideline, string) {
+	g := PGXGuideline{
+		Gene:           strings.ToUpper(field("gene")),
+		DrugCode:       field("drug_code"),
+		DrugName:       field("drug_name"),
+		Recommendation: field("recommendation"),
+		Source:         field("source"),
+		SourceVersion:  field("source_version"),
+		Active:         true,
+	}
+	if g.Gene == "" || g.DrugCode == "" || g.Recommendation == "" {
+		return g, "gene, drug_code and recommendation are required"
+	}
+	if isAllDigits(g.DrugCode) {
+		g.DrugCode = "RxCUI:" + g.DrugCode
+	}
+	if g.DrugName == "" {
+		g.DrugName = g.DrugCode
+	}
+	if g.Source == "" {
+		g.Source = "CPIC"
+	}
+	if g.SourceVersion == "" {
+		g.SourceVersion = defaultSourceVersion
+	}
+	if implications := field("implications"); implications != "" {
+		g.Implications = &implications
+	}
+
+	phenotype, ok := NormalizePGXPhenotype(field("phenotype"))
+	if !ok {
+		return g, fmt.Sprintf("unrecognized phenotype %q", field("phenotype"))
+	}
+	g.Phenotype = phenotype
+
+	classification, ok := normalizeGuidelineClassification(field("classification"))
+	if !ok {
+		return g, fmt.Sprintf("unrecognized classification %q", field("classification"))
+	}
+	g.Classification = classification
+
+	if action := field("dosing_action"); action != "" {
+		action = strings.ToLower(strings.NewReplacer(" ", "_", "-", "_").Replace(action))
+		if !validDosingActions[action] {
+			return g, fmt.Sprintf("unrecognized dosing action %q", field("dosing_action"))
+		}
+		g.DosingAction = action
+	} else {
+		g.DosingAction = inferDosingAction(g.Recommendation)
+	}
+
+	for _, alt := range strings.FieldsFunc(field("alternatives"), func(r rune) bool { return r == ';' || r == '|' }) {
+		alt = strings.TrimSpace(alt)
+		if isAllDigits(alt) {
+			alt = "RxCUI:" + alt
+		}
+		if alt != "" {
+			g.Alternatives = append(g.Alternatives, alt)
+		}
+	}
+
+	return g, ""
+}
+
+// NormalizePGXPhenotype converts a CPIC/DPWG phenotype label ("Poor Metabolizer",
+// "CYP2C19 Ultrarapid Metabolizer", "Decreased Function", "CYP3A5 Nonexpresser",
+// "PM") to the phenotype labels used by the rule tables
+func NormalizePGXPhenotype(label string) (string, bool) {
+	p := strings.ToLower(strings.TrimSpace(label))
+	if p == "" {
+		return "", false
+	}
+
+	// Expresser status is often given in parentheses after a metabolizer label
+	compact := strings.NewReplacer("-", "", "_", "", " ", "").Replace(p)
+	switch {
+	case strings.Contains(compact, "nonexpresser"):
+		return "non_expresser", true
+	case strings.Contains(compact, "expresser"):
+		return "expresser", true
+	}
+
+	switch p {
+	case "pm":
+		return "poor", true
+	case "im":
+		return "intermediate", true
+	case "nm", "em":
+		return "normal", true
+	case "rm":
+		return "rapid", true
+	case "um":
+		return "ultrarapid", true
+	}
+
+	if i := strings.IndexByte(p, '('); i > 0 {
+		p = p[:i] // "Poor Metabolizer (PM)"
+	}
+	words := strings.Fields(strings.NewReplacer("-", "", "_", " ").Replace(p))
+	var kept []string
+	for _, w := range words {
+		switch {
+		case strings.HasPrefix(w, "cyp"), strings.HasPrefix(w, "slco"), w == "vkorc1":
+		case w == "likely", w == "metabolizer", w == "metaboliser", w == "function", w == "phenotype":
+		case w == "sensitivity", w == "warfarin":
+		default:
+			kept = append(kept, w)
+		}
+	}
+
+	switch strings.Join(kept, " ") {
+	case "poor":
+		return "poor", true
+	case "intermediate":
+		return "intermediate", true
+	case "normal", "extensive":
+		return "normal", true
+	case "rapid":
+		return "rapid", true
+	case "ultrarapid":
+		return "ultrarapid", true
+	case "decreased":
+		return "decreased", true
+	case "increased":
+		return "increased", true
+	case "low":
+		return "low", true
+	case "high":
+		return "high", true
+	}
+	return "", false
+}
+
+// normalizeGuidelineClassification maps CPIC recommendation strengths; blank is optional
+func normalizeGuidelineClassification(label string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "strong":
+		return GuidelineClassificationStrong, true
+	case "moderate":
+		return GuidelineClassificationModerate, true
+	case "optional", "":
+		return GuidelineClassificationOptional, true
+	case "no recommendation", "no_recommendation", "none", "n/a":
+		return GuidelineClassificationNoRecommendation, true
+	}
+	return "", false
+}
+
+// inferDosingAction derives a dosing action from recommendation text for files
+// without a dosing_action column
+func inferDosingAction(recommendation string) string {
+	r := strings.ToLower(recommendation)
+	switch {
+	case strings.Contains(r, "avoid"):
+		return DosingActionAvoid
+	case strings.Contains(r, "alternative"):
+		return DosingActionConsiderAlternative
+	case strings.Contains(r, "reduc"), strings.Contains(r, "lower"), strings.Contains(r, "decrease"):
+		return DosingActionReduceDose
+	case strings.Contains(r, "increase"), strings.Contains(r, "higher"):
+		return DosingActionIncreaseDose
+	case strings.Contains(r, "monitor"):
+		return DosingActionMonitor
+	}
+	return DosingActionUseStandard
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
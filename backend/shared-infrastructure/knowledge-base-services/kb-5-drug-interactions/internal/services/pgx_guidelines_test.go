@@ -0,0 +1,124 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================================
+// PGX GUIDELINE TESTS
+// ============================================================================
+
+func TestNormalizePGXPhenotype(t *testing.T) {
+	tests := []struct {
+		label    string
+		expected string
+	}{
+		{"Poor Metabolizer", "poor"},
+		{"CYP2C19 Ultrarapid Metabolizer", "ultrarapid"},
+		{"Likely Intermediate Metabolizer", "intermediate"},
+		{"Normal Metabolizer (NM)", "normal"},
+		{"Decreased Function", "decreased"},
+		{"Poor Function", "poor"},
+		{"CYP3A5 Nonexpresser", "non_expresser"},
+		{"Extensive Metabolizer (CYP3A5 expresser)", "expresser"},
+		{"UM", "ultrarapid"},
+		{"rapid", "rapid"},
+		{"High warfarin sensitivity", "high"},
+	}
+
+	for _, tt := range tests {
+		phenotype, ok := NormalizePGXPhenotype(tt.label)
+		assert.True(t, ok, tt.label)
+		assert.Equal(t, tt.expected, phenotype, tt.label)
+	}
+
+	_, ok := NormalizePGXPhenotype("Indeterminate")
+	assert.False(t, ok)
+}
+
+func TestParseGuidelineFile_CSV(t *testing.T) {
+	file := `Gene,Phenotype,RxCUI,Drug,Therapeutic Recommendation,Classification of Recommendation,Alternatives,Implications
+CYP2C19,Poor Metabolizer,32968,Clopidogrel,Avoid standard dose clopidogrel if possible,Strong,1246289;1156738,Reduced active metabolite
+CYP2C19,Intermediate Metabolizer,32968,Clopidogrel,"Use an alternative antiplatelet agent, if possible",Moderate,,
+CYP2C19,Unknown,32968,Clopidogrel,Use standard dose,Strong,,
+`
+	guidelines, issues, err := ParseGuidelineFile(strings.NewReader(file), "", "CPIC CYP2C19-clopidogrel 2022")
+	require.NoError(t, err)
+	require.Len(t, guidelines, 2)
+	require.Len(t, issues, 1)
+	assert.Equal(t, 4, issues[0].Line)
+
+	poor := guidelines[0]
+	assert.Equal(t, "CYP2C19", poor.Gene)
+	assert.Equal(t, "poor", poor.Phenotype)
+	assert.Equal(t, "RxCUI:32968", poor.DrugCode)
+	assert.Equal(t, GuidelineClassificationStrong, poor.Classification)
+	assert.Equal(t, DosingActionAvoid, poor.DosingAction)
+	assert.Equal(t, []string{"RxCUI:1246289", "RxCUI:1156738"}, []string(poor.Alternatives))
+	assert.Equal(t, "CPIC", poor.Source)
+	assert.Equal(t, "CPIC CYP2C19-clopidogrel 2022", poor.SourceVersion)
+	require.NotNil(t, poor.Implications)
+
+	intermediate := guidelines[1]
+	assert.Equal(t, DosingActionConsiderAlternative, intermediate.DosingAction)
+	assert.Nil(t, intermediate.Implications)
+}
+
+func TestParseGuidelineFile_TSVWithDosingAction(t *testing.T) {
+	file := "gene\tphenotype\tdrug_code\trecommendation\tdosing_action\tsource\tsource_version\n" +
+		"SLCO1B1\tDecreased Function\tRxCUI:36567\tPrescribe a lower dose\treduce-dose\tCPIC\tCPIC SLCO1B1-statins 2022\n" +
+		"SLCO1B1\tPoor Function\tRxCUI:36567\tPrescribe an alternative statin\tstop\tCPIC\tCPIC SLCO1B1-statins 2022\n"
+
+	guidelines, issues, err := ParseGuidelineFile(strings.NewReader(file), "tsv", "")
+	require.NoError(t, err)
+	require.Len(t, guidelines, 1)
+	require.Len(t, issues, 1)
+	assert.Contains(t, issues[0].Reason, "dosing action")
+
+	assert.Equal(t, "decreased", guidelines[0].Phenotype)
+	assert.Equal(t, DosingActionReduceDose, guidelines[0].DosingAction)
+	assert.Equal(t, GuidelineClassificationOptional, guidelines[0].Classification)
+	assert.Equal(t, "RxCUI:36567", guidelines[0].DrugName)
+}
+
+func TestParseGuidelineFile_MissingColumns(t *testing.T) {
+	_, _, err := ParseGuidelineFile(strings.NewReader("gene,phenotype,recommendation\n"), "csv", "v1")
+	assert.Error(t, err)
+
+	_, _, err = ParseGuidelineFile(strings.NewReader("gene,phenotype,drug_code,recommendation\n"), "csv", "")
+	assert.Error(t, err, "source version is required when the file has none")
+
+	_, _, err = ParseGuidelineFile(strings.NewReader("gene\n"), "xml", "v1")
+	assert.Error(t, err)
+}
+
+func TestSelectGuidelineAndAlternatives(t *testing.T) {
+	guidelines := []PGXGuideline{
+		{Gene: "CYP2D6", Phenotype: "poor", Recommendation: "DPWG advice", Classification: GuidelineClassificationOptional, Source: "DPWG",
+			Alternatives: []string{"RxCUI:7052"}},
+		{Gene: "CYP2D6", Phenotype: "poor", Recommendation: "CPIC advice", Classification: GuidelineClassificationStrong, Source: "CPIC",
+			Alternatives: []string{"RxCUI:7804"}},
+		{Gene: "CYP2D6", Phenotype: "ultrarapid", Recommendation: "Avoid", Classification: GuidelineClassificationStrong,
+			Alternatives: []string{"RxCUI:7052", "RxCUI:7804"}},
+	}
+
+	selected := selectGuideline(guidelines, "CYP2D6", "Poor")
+	require.NotNil(t, selected)
+	assert.Equal(t, "CPIC advice", selected.Recommendation)
+	assert.Nil(t, selectGuideline(guidelines, "CYP2D6", "normal"))
+
+	assert.Equal(t, []string{"RxCUI:7052", "RxCUI:7804"}, guidelineAlternatives(guidelines, "cyp2d6"))
+	assert.Empty(t, guidelineAlternatives(guidelines, "CYP2C19"))
+}
+
+func TestInferDosingAction(t *testing.T) {
+	assert.Equal(t, DosingActionAvoid, inferDosingAction("Avoid codeine use"))
+	assert.Equal(t, DosingActionReduceDose, inferDosingAction("Reduce starting dose by 50%"))
+	assert.Equal(t, DosingActionIncreaseDose, inferDosingAction("Increase starting dose 1.5-2 times"))
+	assert.Equal(t, DosingActionMonitor, inferDosingAction("Monitor trough levels"))
+	assert.Equal(t, DosingActionUseStandard, inferDosingAction("Initiate therapy with recommended starting dose"))
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// pgxPhenotypeScales orders each gene's phenotypes from lowest to highest activity
+var pgxPhenotypeScales = map[string][]string{
+	"CYP2D6":  {"poor", "intermediate", "normal", "ultrarapid"},
+	"CYP2C19": {"poor", "intermediate", "normal", "rapid", "ultrarapid"},
+	"CYP2C9":  {"poor", "intermediate", "normal"},
+	"SLCO1B1": {"poor", "decreased", "normal", "increased"},
+}
+
+// moderateInhibitorActivityFactor scales the activity score under a moderate inhibitor
+const moderateInhibitorActivityFactor = 0.5
+
+// Perpetrator effects and strengths (pgx_enzyme_perpetrators)
+const (
+	PerpetratorInhibitor = "inhibitor"
+	PerpetratorInducer   = "inducer"
+
+	PerpetratorStrong   = "strong"
+	PerpetratorModerate = "moderate"
+	PerpetratorWeak     = "weak"
+)
+
+// EnzymePerpetrator is a drug that inhibits or induces a PGx gene product
+type EnzymePerpetrator struct {
+	DrugCode string `json:"drug_code"`
+	DrugName string `json:"drug_name"`
+	Gene     string `json:"gene"`
+	Effect   string `json:"effect"`   // inhibitor, inducer
+	Strength string `json:"strength"` // strong, moderate, weak
+}
+
+// TableName specifies the table name for GORM
+func (EnzymePerpetrator) TableName() string {
+	return "pgx_enzyme_perpetrators"
+}
+
+// PhenoconversionResult explains how co-medications change a gene's phenotype
+type PhenoconversionResult struct {
+	Gene                   string              `json:"gene"`
+	GenotypePhenotype      string              `json:"genotype_phenotype"`
+	EffectivePhenotype     string              `json:"effective_phenotype"`
+	GenotypeActivityScore  *float64            `json:"genotype_activity_score,omitempty"`
+	EffectiveActivityScore *float64            `json:"effective_activity_score,omitempty"`
+	Perpetrators           []EnzymePerpetrator `json:"perpetrators"`
+	Converted              bool                `json:"converted"`
+	Explanation            string              `json:"explanation"`
+}
+
+// ApplyPhenoconversion converts each patient phenotype to the effective phenotype
+// given the inhibitors and inducers in the regimen. PGx rules should be evaluated
+// against the returned markers. On error the input phenotypes are returned unchanged.
+func (pge *PharmacogenomicEngine) ApplyPhenoconversion(
+	ctx context.Context,
+	drugCodes []string,
+	phenotypes map[string]string,
+	translations []GenotypeTranslation,
+) (map[string]string, []PhenoconversionResult, error) {
+	if len(phenotypes) == 0 || len(drugCodes) == 0 {
+		return phenotypes, nil, nil
+	}
+
+	perpetrators, err := pge.loadPerpetrators(ctx)
+	if err != nil {
+		return phenotypes, nil, fmt.Errorf("failed to load phenoconversion perpetrators: %w", err)
+	}
+
+	byGene := make(map[string][]EnzymePerpetrator)
+	for _, code := range drugCodes {
+		for _, p := range perpetrators[normalizeATCDrugKey(code)] {
+			byGene[p.Gene] = append(byGene[p.Gene], p)
+		}
+	}
+	if len(byGene) == 0 {
+		return phenotypes, nil, nil
+	}
+
+	scores := make(map[string]*float64)
+	for _, t := range translations {
+		scores[t.Gene] = t.ActivityScore
+	}
+
+	genes := make([]string, 0, len(phenotypes))
+	for gene := range phenotypes {
+		genes = append(genes, gene)
+	}
+	sort.Strings(genes)
+
+	effective := make(map[string]string, len(phenotypes))
+	var results []PhenoconversionResult
+	for _, gene := range genes {
+		phenotype := phenotypes[gene]
+		effective[gene] = phenotype
+
+		geneKey := strings.ToUpper(gene)
+		result := convertPhenotype(geneKey, phenotype, scores[geneKey], byGene[geneKey], pge.translator)
+		if result == nil {
+			continue
+		}
+		if result.Converted {
+			effective[gene] = result.EffectivePhenotype
+			pge.metrics.RecordPGXPhenoconversion(geneKey, result.EffectivePhenotype)
+		}
+		results = append(results, *result)
+	}
+
+	return effective, results, nil
+}
+
+// AnnotatePhenoconversion marks PGx interactions that fired on a converted phenotype
+func AnnotatePhenoconversion(interactions []models.EnhancedInteractionResult, conversions []PhenoconversionResult) {
+	converted := make(map[string]PhenoconversionResult)
+	for _, c := range conversions {
+		if c.Converted {
+			converted[c.Gene] = c
+		}
+	}
+	if len(converted) == 0 {
+		return
+	}
+
+	for i := range interactions {
+		c, exists := converted[strings.ToUpper(interactions[i].Qualifiers["gene"])]
+		if !exists {
+			continue
+		}
+		interactions[i].Qualifiers["phenoconversion"] = "true"
+		interactions[i].Qualifiers["genotype_phenotype"] = c.GenotypePhenotype
+		interactions[i].Qualifiers["phenoconversion_explanation"] = c.Explanation
+	}
+}
+
+// convertPhenotype applies the strongest inhibitor, or else the strongest inducer,
+// to a phenotype. Returns nil when no perpetrator is strong enough to matter.
+func convertPhenotype(
+	gene string,
+	phenotype string,
+	activityScore *float64,
+	perpetrators []EnzymePerpetrator,
+	translator *GenotypeTranslator,
+) *PhenoconversionResult {
+	var inhibitor, inducer *EnzymePerpetrator
+	var relevant []EnzymePerpetrator
+	for i := range perpetrators {
+		p := &perpetrators[i]
+		if p.Strength != PerpetratorStrong && p.Strength != PerpetratorModerate {
+			continue
+		}
+		relevant = append(relevant, *p)
+		switch p.Effect {
+		case PerpetratorInhibitor:
+			if inhibitor == nil || (p.Strength == PerpetratorStrong && inhibitor.Strength != PerpetratorStrong) {
+				inhibitor = p
+			}
+		case PerpetratorInducer:
+			if inducer == nil || (p.Strength == PerpetratorStrong && inducer.Strength != PerpetratorStrong) {
+				inducer = p
+			}
+		}
+	}
+	if inhibitor == nil && inducer == nil {
+		return nil
+	}
+
+	result := &PhenoconversionResult{
+		Gene:                  gene,
+		GenotypePhenotype:     phenotype,
+		EffectivePhenotype:    phenotype,
+		GenotypeActivityScore: activityScore,
+		Perpetrators:          relevant,
+	}
+
+	scale := pgxPhenotypeScales[gene]
+	position := -1
+	for i, p := range scale {
+		if p == strings.ToLower(phenotype) {
+			position = i
+		}
+	}
+	if position < 0 {
+		result.Explanation = fmt.Sprintf("%s phenotype %q has no activity ordering; phenoconversion not applied",
+			gene, phenotype)
+		return result
+	}
+
+	perpetrator := inhibitor
+	switch {
+	case inhibitor != nil && inhibitor.Strength == PerpetratorStrong:
+		result.EffectivePhenotype = scale[0]
+		if activityScore != nil {
+			zero := 0.0
+			result.EffectiveActivityScore = &zero
+		}
+
+	case inhibitor != nil:
+		result.EffectivePhenotype = scale[maxInt(position-1, 0)]
+		if activityScore != nil && translator != nil {
+			score := *activityScore * moderateInhibitorActivityFactor
+			if converted, ok := translator.PhenotypeForActivityScore(gene, score); ok {
+				result.EffectivePhenotype = converted
+				result.EffectiveActivityScore = &score
+			}
+		}
+
+	default:
+		perpetrator = inducer
+		result.EffectivePhenotype = scale[minInt(position+1, len(scale)-1)]
+	}
+
+	result.Converted = result.EffectivePhenotype != strings.ToLower(phenotype)
+
+	explanation := fmt.Sprintf("%s is a %s %s %s", perpetrator.DrugName, perpetrator.Strength, gene, perpetrator.Effect)
+	if result.Converted {
+		explanation += fmt.Sprintf(": genotype-predicted %s phenotype behaves as %s", phenotype, result.EffectivePhenotype)
+		if result.GenotypeActivityScore != nil && result.EffectiveActivityScore != nil {
+			explanation += fmt.Sprintf(" (activity score %s -> %s)",
+				formatActivityScore(*result.GenotypeActivityScore), formatActivityScore(*result.EffectiveActivityScore))
+		}
+	} else {
+		explanation += fmt.Sprintf(": %s phenotype unchanged", phenotype)
+	}
+	if inhibitor != nil && inducer != nil {
+		explanation += fmt.Sprintf("; %s induction not applied while an inhibitor is present", inducer.DrugName)
+	}
+	result.Explanation = explanation
+
+	return result
+}
+
+// loadPerpetrators returns active perpetrators keyed by normalized drug code
+func (pge *PharmacogenomicEngine) loadPerpetrators(ctx context.Context) (map[string][]EnzymePerpetrator, error) {
+	pge.perpetratorMu.Lock()
+	defer pge.perpetratorMu.Unlock()
+
+	if pge.perpetrators != nil && time.Since(pge.perpetratorsLoaded) < pge.cacheTTL {
+		return pge.perpetrators, nil
+	}
+
+	var rows []EnzymePerpetrator
+	err := pge.db.DB.WithContext(ctx).
+		Where("active = TRUE").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	perpetrators := make(map[string][]EnzymePerpetrator)
+	for _, row := range rows {
+		row.Gene = strings.ToUpper(row.Gene)
+		key := normalizeATCDrugKey(row.DrugCode)
+		perpetrators[key] = append(perpetrators[key], row)
+	}
+
+	pge.perpetrators = perpetrators
+	pge.perpetratorsLoaded = time.Now()
+
+	return perpetrators, nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
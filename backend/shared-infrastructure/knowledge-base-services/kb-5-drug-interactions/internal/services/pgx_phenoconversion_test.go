@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// PHENOCONVERSION TESTS
+// ============================================================================
+
+func TestConvertPhenotype_StrongInhibitor(t *testing.T) {
+	gt := newTestGenotypeTranslator()
+	paroxetine := EnzymePerpetrator{DrugCode: "RxCUI:32937", DrugName: "Paroxetine", Gene: "CYP2D6",
+		Effect: PerpetratorInhibitor, Strength: PerpetratorStrong}
+
+	result := convertPhenotype("CYP2D6", "normal", floatPtr(2), []EnzymePerpetrator{paroxetine}, gt)
+	require.NotNil(t, result)
+	assert.True(t, result.Converted)
+	assert.Equal(t, "poor", result.EffectivePhenotype)
+	assert.Equal(t, "normal", result.GenotypePhenotype)
+	assert.InDelta(t, 0, *result.EffectiveActivityScore, 1e-9)
+	assert.Contains(t, result.Explanation, "Paroxetine is a strong CYP2D6 inhibitor")
+}
+
+func TestConvertPhenotype_ModerateInhibitorActivityScore(t *testing.T) {
+	gt := newTestGenotypeTranslator()
+	duloxetine := EnzymePerpetrator{DrugName: "Duloxetine", Gene: "CYP2D6",
+		Effect: PerpetratorInhibitor, Strength: PerpetratorModerate}
+
+	// Activity score 2 -> 1: normal behaves as intermediate
+	result := convertPhenotype("CYP2D6", "normal", floatPtr(2), []EnzymePerpetrator{duloxetine}, gt)
+	require.NotNil(t, result)
+	assert.Equal(t, "intermediate", result.EffectivePhenotype)
+	assert.InDelta(t, 1, *result.EffectiveActivityScore, 1e-9)
+
+	// Without a genotype score the phenotype steps down one level
+	result = convertPhenotype("CYP2D6", "ultrarapid", nil, []EnzymePerpetrator{duloxetine}, gt)
+	require.NotNil(t, result)
+	assert.Equal(t, "normal", result.EffectivePhenotype)
+	assert.Nil(t, result.EffectiveActivityScore)
+}
+
+func TestConvertPhenotype_InducerAndInhibitor(t *testing.T) {
+	rifampin := EnzymePerpetrator{DrugName: "Rifampin", Gene: "CYP2C19",
+		Effect: PerpetratorInducer, Strength: PerpetratorStrong}
+	omeprazole := EnzymePerpetrator{DrugName: "Omeprazole", Gene: "CYP2C19",
+		Effect: PerpetratorInhibitor, Strength: PerpetratorModerate}
+
+	result := convertPhenotype("CYP2C19", "normal", nil, []EnzymePerpetrator{rifampin}, nil)
+	require.NotNil(t, result)
+	assert.Equal(t, "rapid", result.EffectivePhenotype)
+
+	// Inhibition takes precedence over induction
+	result = convertPhenotype("CYP2C19", "normal", nil, []EnzymePerpetrator{rifampin, omeprazole}, nil)
+	require.NotNil(t, result)
+	assert.Equal(t, "intermediate", result.EffectivePhenotype)
+	assert.Contains(t, result.Explanation, "Rifampin induction not applied")
+}
+
+func TestConvertPhenotype_NoConversion(t *testing.T) {
+	sertraline := EnzymePerpetrator{DrugName: "Sertraline", Gene: "CYP2D6",
+		Effect: PerpetratorInhibitor, Strength: PerpetratorWeak}
+	paroxetine := EnzymePerpetrator{DrugName: "Paroxetine", Gene: "CYP2D6",
+		Effect: PerpetratorInhibitor, Strength: PerpetratorStrong}
+
+	// Weak perpetrators are ignored
+	assert.Nil(t, convertPhenotype("CYP2D6", "normal", nil, []EnzymePerpetrator{sertraline}, nil))
+
+	// Already poor
+	result := convertPhenotype("CYP2D6", "poor", nil, []EnzymePerpetrator{paroxetine}, nil)
+	require.NotNil(t, result)
+	assert.False(t, result.Converted)
+	assert.Contains(t, result.Explanation, "unchanged")
+}
+
+func TestAnnotatePhenoconversion(t *testing.T) {
+	interactions := []models.EnhancedInteractionResult{
+		{InteractionID: "PGX_codeine_CYP2D6_poor", Qualifiers: map[string]string{"gene": "CYP2D6"}},
+		{InteractionID: "PGX_clopidogrel_CYP2C19_poor", Qualifiers: map[string]string{"gene": "CYP2C19"}},
+	}
+	AnnotatePhenoconversion(interactions, []PhenoconversionResult{
+		{Gene: "CYP2D6", GenotypePhenotype: "normal", EffectivePhenotype: "poor", Converted: true, Explanation: "converted"},
+	})
+
+	assert.Equal(t, "true", interactions[0].Qualifiers["phenoconversion"])
+	assert.Equal(t, "normal", interactions[0].Qualifiers["genotype_phenotype"])
+	assert.Empty(t, interactions[1].Qualifiers["phenoconversion"])
+}
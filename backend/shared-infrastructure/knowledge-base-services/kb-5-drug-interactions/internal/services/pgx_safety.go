@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// PGx safety check types (pgx_safety_rules.check_type)
+const (
+	PGXSafetyRiskAllele   = "risk_allele"
+	PGXSafetyDoseCritical = "dose_critical"
+)
+
+// PGXSafetyRule is a hard-stop PGx check: carriage of a risk allele, or a
+// dose-critical gene phenotype, with a drug
+type PGXSafetyRule struct {
+	ID                  int                  `json:"id" gorm:"primaryKey"`
+	CheckType           string               `json:"check_type"`
+	Gene                string               `json:"gene"`
+	Allele              *string              `json:"allele,omitempty"`
+	Phenotype           *string              `json:"phenotype,omitempty"`
+	DrugCode            string               `json:"drug_code"`
+	DrugName            string               `json:"drug_name"`
+	Severity            models.DDISeverity   `json:"severity"`
+	GovernanceAction    string               `json:"governance_action"`
+	ClinicalEffect      string               `json:"clinical_effect"`
+	Recommendation      string               `json:"recommendation"`
+	StartingDosePercent *decimal.Decimal     `json:"starting_dose_percent,omitempty"`
+	Evidence            models.EvidenceLevel `json:"evidence"`
+	Source              string               `json:"source"`
+	Active              bool                 `json:"active"`
+}
+
+// TableName specifies the table name for GORM
+func (PGXSafetyRule) TableName() string {
+	return "pgx_safety_rules"
+}
+
+// EvaluatePGXSafety checks the regimen against HLA risk alleles and dose-critical
+// gene phenotypes. Findings carry a "governance_action" qualifier that the
+// governance policy engine treats as the minimum action for the finding.
+// Drugs with a risk allele rule are checked even without results: an allele
+// that was not screened is reported as a required screening result.
+func (pge *PharmacogenomicEngine) EvaluatePGXSafety(
+	ctx context.Context,
+	drugCodes []string,
+	phenotypes map[string]string,
+	riskAlleles map[string]string,
+) ([]models.EnhancedInteractionResult, error) {
+	if len(drugCodes) == 0 {
+		return []models.EnhancedInteractionResult{}, nil
+	}
+
+	rules, err := pge.loadSafetyRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PGx safety rules: %w", err)
+	}
+
+	findings := evaluateSafetyRules(rules, drugCodes, phenotypes, riskAlleles)
+	for _, f := range findings {
+		pge.metrics.RecordPGXSafetyMatch(f.Qualifiers["check_type"], f.Qualifiers["governance_action"])
+	}
+	return findings, nil
+}
+
+// evaluateSafetyRules matches safety rules (keyed by normalized drug code) against
+// the patient's phenotypes and risk allele results
+func evaluateSafetyRules(
+	rules map[string][]PGXSafetyRule,
+	drugCodes []string,
+	phenotypes map[string]string,
+	riskAlleles map[string]string,
+) []models.EnhancedInteractionResult {
+	carried := make(map[string]string)    // normalized allele -> reported result
+	unverified := make(map[string]string) // normalized allele -> uninterpretable result
+	screened := make(map[string]bool)     // normalized allele -> any result reported
+	for allele, result := range riskAlleles {
+		screened[NormalizeRiskAllele(allele)] = true
+		carrier, known := riskAlleleCarrier(result)
+		switch {
+		case carrier:
+			carried[NormalizeRiskAllele(allele)] = result
+		case !known:
+			unverified[NormalizeRiskAllele(allele)] = result
+		}
+	}
+	genePhenotypes := make(map[string]string)
+	for gene, phenotype := range phenotypes {
+		genePhenotypes[strings.ToUpper(gene)] = strings.ToLower(phenotype)
+	}
+
+	findings := []models.EnhancedInteractionResult{}
+	seen := make(map[string]bool)
+	for _, code := range drugCodes {
+		for _, rule := range rules[normalizeATCDrugKey(code)] {
+			var marker, result string
+			switch rule.CheckType {
+			case PGXSafetyRiskAllele:
+				if rule.Allele == nil {
+					continue
+				}
+				allele := NormalizeRiskAllele(*rule.Allele)
+				reported, ok := carried[allele]
+				if !ok {
+					// A missing or indeterminate result cannot clear a hard-stop check
+					status := ""
+					if !screened[allele] {
+						status = "untested"
+					} else if reported, ok = unverified[allele]; ok {
+						status = "unverified"
+					}
+					if status != "" {
+						id := fmt.Sprintf("PGXSAFE_%s_%s_%s", rule.DrugCode, allele, status)
+						if !seen[id] {
+							seen[id] = true
+							findings = append(findings, unverifiedSafetyFinding(rule, code, allele, reported, id))
+						}
+					}
+					continue
+				}
+				marker, result = allele, reported
+			case PGXSafetyDoseCritical:
+				if rule.Phenotype == nil || genePhenotypes[rule.Gene] != strings.ToLower(*rule.Phenotype) {
+					continue
+				}
+				marker, result = rule.Gene, strings.ToLower(*rule.Phenotype)
+			default:
+				continue
+			}
+
+			id := fmt.Sprintf("PGXSAFE_%s_%s_%s", rule.DrugCode, marker, result)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			findings = append(findings, safetyFinding(rule, code, marker, result, id))
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return models.GovernanceAction(findings[i].Qualifiers["governance_action"]).Priority() >
+			models.GovernanceAction(findings[j].Qualifiers["governance_action"]).Priority()
+	})
+	return findings
+}
+
+// safetyFinding builds the interaction result for a matched safety rule
+func safetyFinding(rule PGXSafetyRule, drugCode, marker, result, id string) models.EnhancedInteractionResult {
+	markerName := fmt.Sprintf("%s %s", marker, result)
+	if rule.CheckType == PGXSafetyRiskAllele {
+		markerName = fmt.Sprintf("%s carrier", marker)
+	}
+
+	finding := models.EnhancedInteractionResult{
+		InteractionID:      id,
+		Severity:           rule.Severity,
+		Mechanism:          models.MechanismPK,
+		ClinicalEffects:    rule.ClinicalEffect,
+		ManagementStrategy: rule.Recommendation,
+		Evidence:           rule.Evidence,
+		Sources:            []string{rule.Source},
+		PGXApplicable:      true,
+		Drug1: models.DrugInfo{
+			Code: drugCode,
+			Name: rule.DrugName,
+		},
+		Drug2: models.DrugInfo{
+			Code: fmt.Sprintf("PGX_%s", strings.ReplaceAll(marker, " ", "_")),
+			Name: markerName,
+		},
+		Qualifiers: map[string]string{
+			"type":              "pgx_safety",
+			"check_type":        rule.CheckType,
+			"gene":              rule.Gene,
+			"governance_action": rule.GovernanceAction,
+		},
+	}
+	if rule.CheckType == PGXSafetyRiskAllele {
+		finding.Mechanism = models.MechanismPD // Immune-mediated, not exposure-driven
+		finding.Qualifiers["allele"] = marker
+		finding.Qualifiers["allele_result"] = result
+	} else {
+		finding.Qualifiers["phenotype"] = result
+	}
+	if rule.StartingDosePercent != nil {
+		finding.DoseAdjustmentRequired = true
+		finding.Qualifiers["starting_dose_percent"] = rule.StartingDosePercent.String()
+	}
+	return finding
+}
+
+// unverifiedSafetyFinding reports a risk allele rule whose result is missing
+// (reported is empty) or could not be interpreted. Carrier status must be
+// confirmed before prescribing, so the finding escalates rather than blocking outright.
+func unverifiedSafetyFinding(rule PGXSafetyRule, drugCode, allele, reported, id string) models.EnhancedInteractionResult {
+	finding := safetyFinding(rule, drugCode, allele, reported, id)
+	if reported == "" {
+		finding.Drug2.Name = fmt.Sprintf("%s screening result required", allele)
+		finding.ManagementStrategy = fmt.Sprintf("No %s result reported; screening result required before prescribing. %s",
+			allele, rule.Recommendation)
+		finding.Qualifiers["allele_status"] = "untested"
+	} else {
+		finding.Drug2.Name = fmt.Sprintf("%s result not interpretable", allele)
+		finding.ManagementStrategy = fmt.Sprintf("%s result %q could not be interpreted; confirm carrier status before prescribing. %s",
+			allele, reported, rule.Recommendation)
+		finding.Qualifiers["allele_status"] = "unverified"
+	}
+
+	if rule.Severity.GetPriority() > models.SeverityMajor.GetPriority() {
+		finding.Severity = models.SeverityMajor
+	}
+	if reported == "" || models.GovernanceAction(rule.GovernanceAction).Priority() > models.GovernanceMandatoryEscalation.Priority() {
+		finding.Qualifiers["governance_action"] = string(models.GovernanceMandatoryEscalation)
+	}
+	return finding
+}
+
+var riskAllelePattern = regexp.MustCompile(`^(HLA-)?([A-Z]+[0-9]*)\*?(\d{2}):?(\d{2})$`)
+
+// NormalizeRiskAllele puts an HLA allele name in "HLA-B*57:01" form, accepting
+// "B*57:01", "HLA-B*5701" and "hla-b 57:01". Other names are upper-cased.
+func NormalizeRiskAllele(name string) string {
+	n := strings.ToUpper(strings.TrimSpace(name))
+	n = strings.NewReplacer(" ", "", "_", "").Replace(n)
+	if m := riskAllelePattern.FindStringSubmatch(n); m != nil {
+		return fmt.Sprintf("HLA-%s*%s:%s", m[2], m[3], m[4])
+	}
+	return n
+}
+
+// riskAlleleCarrier interprets a reported risk allele result. known is false for
+// results that cannot be interpreted, which are reported as unverified.
+func riskAlleleCarrier(result string) (carrier bool, known bool) {
+	switch strings.ToLower(strings.TrimSpace(result)) {
+	case "positive", "present", "detected", "carrier", "heterozygous", "homozygous", "true", "yes":
+		return true, true
+	case "negative", "absent", "not detected", "not_detected", "non-carrier", "noncarrier", "false", "no":
+		return false, true
+	}
+	return false, false
+}
+
+// loadSafetyRules returns active safety rules keyed by normalized drug code
+func (pge *PharmacogenomicEngine) loadSafetyRules(ctx context.Context) (map[string][]PGXSafetyRule, error) {
+	pge.safetyMu.Lock()
+	defer pge.safetyMu.Unlock()
+
+	if pge.safetyRules != nil && time.Since(pge.safetyRulesLoaded) < pge.cacheTTL {
+		return pge.safetyRules, nil
+	}
+
+	var rows []PGXSafetyRule
+	err := pge.db.DB.WithContext(ctx).
+		Where("active = TRUE").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make(map[string][]PGXSafetyRule)
+	for _, row := range rows {
+		row.Gene = strings.ToUpper(row.Gene)
+		key := normalizeATCDrugKey(row.DrugCode)
+		rules[key] = append(rules[key], row)
+	}
+
+	pge.safetyRules = rules
+	pge.safetyRulesLoaded = time.Now()
+
+	return rules, nil
+}
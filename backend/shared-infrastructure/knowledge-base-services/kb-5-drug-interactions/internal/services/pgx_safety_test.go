@@ -0,0 +1,129 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// PGX SAFETY CHECK TESTS
+// ============================================================================
+
+func TestNormalizeRiskAllele(t *testing.T) {
+	for _, name := range []string{"HLA-B*57:01", "B*57:01", "HLA-B*5701", "hla-b 57:01", "HLA-B57:01"} {
+		assert.Equal(t, "HLA-B*57:01", NormalizeRiskAllele(name), name)
+	}
+	assert.Equal(t, "HLA-A*31:01", NormalizeRiskAllele("A*31:01"))
+}
+
+func TestEvaluateSafetyRules_RiskAlleles(t *testing.T) {
+	rules := map[string][]PGXSafetyRule{
+		"RXCUI:190521": {
+			{CheckType: PGXSafetyRiskAllele, Gene: "HLA-B", Allele: stringPtr("HLA-B*57:01"), DrugCode: "RxCUI:190521", DrugName: "Abacavir",
+				Severity: models.SeverityContraindicated, GovernanceAction: string(models.GovernanceHardBlock), Source: "CPIC"},
+		},
+		"RXCUI:2002": {
+			{CheckType: PGXSafetyRiskAllele, Gene: "HLA-B", Allele: stringPtr("HLA-B*15:02"), DrugCode: "RxCUI:2002", DrugName: "Carbamazepine",
+				Severity: models.SeverityContraindicated, GovernanceAction: string(models.GovernanceHardBlock), Source: "CPIC"},
+			{CheckType: PGXSafetyRiskAllele, Gene: "HLA-A", Allele: stringPtr("HLA-A*31:01"), DrugCode: "RxCUI:2002", DrugName: "Carbamazepine",
+				Severity: models.SeverityMajor, GovernanceAction: string(models.GovernanceHardBlockOverride), Source: "CPIC"},
+		},
+	}
+
+	findings := evaluateSafetyRules(rules,
+		[]string{"RxCUI:2002", "190521"},
+		nil,
+		map[string]string{"B*15:02": "Positive", "HLA-A*31:01": "detected", "HLA-B*57:01": "negative"})
+
+	assert.Equal(t, 2, len(findings))
+	assert.Equal(t, string(models.GovernanceHardBlock), findings[0].Qualifiers["governance_action"])
+	assert.Equal(t, "HLA-B*15:02", findings[0].Qualifiers["allele"])
+	assert.Equal(t, models.MechanismPD, findings[0].Mechanism)
+	assert.Equal(t, "HLA-A*31:01", findings[1].Qualifiers["allele"])
+	assert.True(t, findings[1].PGXApplicable)
+
+	// Uninterpretable results escalate instead of passing as non-carrier
+	findings = evaluateSafetyRules(rules,
+		[]string{"RxCUI:190521", "RxCUI:2002"},
+		nil,
+		map[string]string{"HLA-B*57:01": "indeterminate", "HLA-B*15:02": "negative", "HLA-A*31:01": "??"})
+
+	assert.Equal(t, 2, len(findings))
+	assert.Equal(t, "HLA-B*57:01", findings[0].Qualifiers["allele"])
+	assert.Equal(t, "unverified", findings[0].Qualifiers["allele_status"])
+	assert.Equal(t, "indeterminate", findings[0].Qualifiers["allele_result"])
+	assert.Equal(t, string(models.GovernanceMandatoryEscalation), findings[0].Qualifiers["governance_action"])
+	assert.Equal(t, models.SeverityMajor, findings[0].Severity)
+	assert.Contains(t, findings[0].ManagementStrategy, "confirm carrier status")
+	assert.Equal(t, "HLA-A*31:01", findings[1].Qualifiers["allele"])
+	assert.Equal(t, "unverified", findings[1].Qualifiers["allele_status"])
+
+	// Without a result the check is not skipped; screening is required
+	findings = evaluateSafetyRules(rules, []string{"190521"}, nil, nil)
+
+	assert.Equal(t, 1, len(findings))
+	assert.Equal(t, "PGXSAFE_RxCUI:190521_HLA-B*57:01_untested", findings[0].InteractionID)
+	assert.Equal(t, "untested", findings[0].Qualifiers["allele_status"])
+	assert.Equal(t, string(models.GovernanceMandatoryEscalation), findings[0].Qualifiers["governance_action"])
+	assert.Equal(t, models.SeverityMajor, findings[0].Severity)
+	assert.Equal(t, "HLA-B*57:01 screening result required", findings[0].Drug2.Name)
+	assert.Contains(t, findings[0].ManagementStrategy, "screening result required")
+
+	// A result for one allele does not stand in for another
+	findings = evaluateSafetyRules(rules, []string{"RxCUI:2002"}, nil, map[string]string{"HLA-B*15:02": "negative"})
+
+	assert.Equal(t, 1, len(findings))
+	assert.Equal(t, "HLA-A*31:01", findings[0].Qualifiers["allele"])
+	assert.Equal(t, "untested", findings[0].Qualifiers["allele_status"])
+}
+
+func TestEvaluateSafetyRules_DoseCritical(t *testing.T) {
+	fifty := decimal.NewFromInt(50)
+	rules := map[string][]PGXSafetyRule{
+		"RXCUI:194000": {
+			{CheckType: PGXSafetyDoseCritical, Gene: "DPYD", Phenotype: stringPtr("intermediate"), DrugCode: "RxCUI:194000", DrugName: "Capecitabine",
+				Severity: models.SeverityMajor, GovernanceAction: string(models.GovernanceMandatoryEscalation), StartingDosePercent: &fifty, Source: "CPIC"},
+			{CheckType: PGXSafetyDoseCritical, Gene: "DPYD", Phenotype: stringPtr("poor"), DrugCode: "RxCUI:194000", DrugName: "Capecitabine",
+				Severity: models.SeverityContraindicated, GovernanceAction: string(models.GovernanceHardBlock), Source: "CPIC"},
+		},
+	}
+
+	findings := evaluateSafetyRules(rules,
+		[]string{"RxCUI:194000"},
+		map[string]string{"dpyd": "Intermediate"},
+		nil)
+
+	assert.Equal(t, 1, len(findings))
+	assert.Equal(t, models.SeverityMajor, findings[0].Severity)
+	assert.True(t, findings[0].DoseAdjustmentRequired)
+	assert.Equal(t, "50", findings[0].Qualifiers["starting_dose_percent"])
+	assert.Equal(t, "intermediate", findings[0].Qualifiers["phenotype"])
+
+	assert.Empty(t, evaluateSafetyRules(rules, []string{"RxCUI:194000"}, map[string]string{"DPYD": "normal"}, nil))
+}
+
+func TestEnhanceWithGovernance_SafetyFloor(t *testing.T) {
+	policy := models.DefaultGovernancePolicy()
+	policy.ContraindicatedAction = models.GovernanceWarnAcknowledge // Permissive institution
+	gpe := &GovernancePolicyEngine{
+		defaultPolicy: policy,
+	}
+
+	finding := models.EnhancedInteractionResult{
+		Severity:   models.SeverityContraindicated,
+		Qualifiers: map[string]string{"governance_action": string(models.GovernanceHardBlock)},
+	}
+
+	governed := gpe.EnhanceWithGovernance(finding, "", nil)
+	assert.Equal(t, models.GovernanceHardBlock, governed.GovernanceAction)
+	assert.Equal(t, "not_overridable", governed.OverrideLevel)
+
+	// Without the qualifier the policy mapping applies
+	delete(finding.Qualifiers, "governance_action")
+	governed = gpe.EnhanceWithGovernance(finding, "", nil)
+	assert.Equal(t, models.GovernanceWarnAcknowledge, governed.GovernanceAction)
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -370,3 +371,233 @@ func TestCrossReactivityInfo_Structure(t *testing.T) {
 	assert.Equal(t, 2, len(info.RelatedDrugs))
 	assert.Equal(t, float64(2.0), info.CrossReactivityRate)
 }
+
+// ============================================================================
+// DISEASE HIERARCHY TESTS
+// ============================================================================
+
+const testDiseaseVocabulary = "source_system\tsource_code\trelationship\ttarget_system\ttarget_code\n" +
+	"SNOMED\t417996009\tis_a\tSNOMED\t84114007\n" +
+	"ICD10\tI50\tmaps_to\tSNOMED\t84114007\n" +
+	"ICD10\tI50.2\tmaps_to\tSNOMED\t417996009\n"
+
+func TestNormalizeDiseaseCode(t *testing.T) {
+	assert.Equal(t, DiseaseCodeRef{CodeSystem: CodeSystemICD10, Code: "I5021"}, NormalizeDiseaseCode("", "i50.21"))
+	assert.Equal(t, DiseaseCodeRef{CodeSystem: CodeSystemICD10, Code: "I509"}, NormalizeDiseaseCode("ICD-10", "I50.9"))
+	assert.Equal(t, DiseaseCodeRef{CodeSystem: CodeSystemSNOMED, Code: "84114007"}, NormalizeDiseaseCode("", "84114007"))
+	assert.Equal(t, DiseaseCodeRef{CodeSystem: CodeSystemSNOMED, Code: "84114007"}, NormalizeDiseaseCode("SNOMED-CT", "84114007"))
+	assert.Equal(t, DiseaseCodeRef{CodeSystem: CodeSystemSNOMED, Code: "84114007"}, NormalizeDiseaseCode("ICD10", "SNOMED:84114007"))
+}
+
+func TestDiseaseHierarchy_ICD10Prefix(t *testing.T) {
+	h := NewDiseaseHierarchy()
+
+	// Rule on heart failure (I50) fires for a specific HF subtype (I50.21)
+	match, ok := h.Match(NormalizeDiseaseCode("", "I50.21"), NormalizeDiseaseCode("ICD10", "I50"))
+	assert.True(t, ok)
+	assert.Equal(t, DiseaseMatchAncestor, match.MatchType)
+	assert.Equal(t, "ICD10:I50", match.MatchedAncestor.key())
+
+	// Legacy behavior retained: unspecified CKD still triggers stage-specific rules
+	match, ok = h.Match(NormalizeDiseaseCode("", "N18"), NormalizeDiseaseCode("ICD10", "N18.5"))
+	assert.True(t, ok)
+	assert.Equal(t, DiseaseMatchDescendant, match.MatchType)
+
+	_, ok = h.Match(NormalizeDiseaseCode("", "I48"), NormalizeDiseaseCode("ICD10", "I50"))
+	assert.False(t, ok)
+}
+
+func TestDiseaseHierarchy_SNOMEDDescendantsAndCrosswalk(t *testing.T) {
+	h := NewDiseaseHierarchy()
+	stats, err := h.Load(strings.NewReader(testDiseaseVocabulary))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.IsARelationships)
+	assert.Equal(t, 2, stats.CrosswalkMappings)
+
+	systolicHF := NormalizeDiseaseCode("SNOMED", "417996009")
+
+	// SNOMED descendant matches SNOMED ancestor rule
+	match, ok := h.Match(systolicHF, NormalizeDiseaseCode("SNOMED-CT", "84114007"))
+	assert.True(t, ok)
+	assert.Equal(t, DiseaseMatchAncestor, match.MatchType)
+
+	// SNOMED-coded problem list matches ICD-10-coded rule
+	match, ok = h.Match(systolicHF, NormalizeDiseaseCode("ICD10", "I50"))
+	assert.True(t, ok)
+	assert.Equal(t, DiseaseMatchCrosswalk, match.MatchType)
+	assert.Equal(t, "ICD10:I50", match.MatchedAncestor.key())
+
+	// ICD-10-coded problem list matches SNOMED-coded rule via crosswalk + SNOMED ancestry
+	match, ok = h.Match(NormalizeDiseaseCode("", "I50.22"), NormalizeDiseaseCode("SNOMED-CT", "84114007"))
+	assert.True(t, ok)
+	assert.Equal(t, DiseaseMatchCrosswalk, match.MatchType)
+}
+
+// ============================================================================
+// CUMULATIVE DAILY DOSE TESTS
+// ============================================================================
+
+func TestEvaluateCumulativeDoses_AcetaminophenAcrossCombinationProducts(t *testing.T) {
+	limits := map[string][]IngredientDoseLimit{
+		"RXCUI:161": {
+			{LimitCode: "APAP-MAX", IngredientCode: "RxCUI:161", IngredientName: "Acetaminophen", MinAgeYears: intPtr(12), DoseUnit: "mg", MaxDailyDose: 4000},
+			{LimitCode: "APAP-HEPATIC", IngredientCode: "RxCUI:161", IngredientName: "Acetaminophen", MinAgeYears: intPtr(12),
+				ChildPughClasses: models.StringArray{"A", "B", "C"}, DoseUnit: "mg", MaxDailyDose: 2000},
+		},
+	}
+	products := map[string][]ProductIngredient{
+		"LOCAL:OXYCODONE-APAP-5-325-TAB": {
+			{ProductCode: "LOCAL:OXYCODONE-APAP-5-325-TAB", ProductName: "Oxycodone/acetaminophen 5/325", DoseFormUnit: "tablet",
+				IngredientCode: "RxCUI:7804", IngredientName: "Oxycodone", Strength: 5, StrengthUnit: "mg"},
+			{ProductCode: "LOCAL:OXYCODONE-APAP-5-325-TAB", ProductName: "Oxycodone/acetaminophen 5/325", DoseFormUnit: "tablet",
+				IngredientCode: "RxCUI:161", IngredientName: "Acetaminophen", Strength: 325, StrengthUnit: "mg"},
+		},
+		"LOCAL:DIPHENHYDRAMINE-APAP-25-500-TAB": {
+			{ProductCode: "LOCAL:DIPHENHYDRAMINE-APAP-25-500-TAB", ProductName: "Acetaminophen/diphenhydramine 500/25", DoseFormUnit: "tablet",
+				IngredientCode: "RxCUI:161", IngredientName: "Acetaminophen", Strength: 500, StrengthUnit: "mg"},
+		},
+	}
+	orders := []models.MedicationOrder{
+		{OrderID: "1", DrugCode: "LOCAL:OXYCODONE-APAP-5-325-TAB", Dose: 2, DoseUnit: "tablets", Frequency: "q6h", PRN: true},
+		{OrderID: "2", DrugCode: "LOCAL:DIPHENHYDRAMINE-APAP-25-500-TAB", Dose: 2, DoseUnit: "tab", Frequency: "qhs"},
+		{OrderID: "3", DrugCode: "RxCUI:161", DrugName: "Acetaminophen", Dose: 650, DoseUnit: "mg", Frequency: "q8h"},
+		{OrderID: "4", DrugCode: "RxCUI:161", Dose: 1000, DoseUnit: "mg", Frequency: "q6h", Status: models.OrderStatusDiscontinued},
+	}
+
+	results, notes := evaluateCumulativeDoses(limits, products, orders, CumulativeDosePatient{AgeYears: intPtr(45)})
+	assert.Empty(t, notes)
+	assert.Equal(t, 1, len(results))
+	result := results[0]
+	assert.Equal(t, CumulativeScopeIngredient, result.Scope)
+	assert.Equal(t, DoseStatusExceedsMax, result.Status)
+	assert.Equal(t, models.SeverityMajor, result.Severity)
+	assert.Equal(t, "APAP-MAX", result.LimitCode)
+	assert.Equal(t, 5550.0, *result.TotalDailyDose) // 2600 + 1000 + 1950
+	assert.Equal(t, 138.8, result.PercentOfMax)
+	assert.Equal(t, 3, len(result.Contributions))
+	assert.Equal(t, 2600.0, *result.Contributions[0].DailyDose)
+	assert.True(t, result.Contributions[0].PRN)
+	assert.Equal(t, "Oxycodone/acetaminophen 5/325", result.Contributions[0].ProductName)
+	assert.Equal(t, "Total Acetaminophen 5550 mg/day from 3 orders exceeds maximum 4000 mg/day (age ≥ 12)", result.Message)
+
+	// Cirrhosis lowers the maximum to 2 g/day
+	results, _ = evaluateCumulativeDoses(limits, products, orders[2:3], CumulativeDosePatient{AgeYears: intPtr(45),
+		Function: PatientOrganFunction{ChildPughClass: "B"}})
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, DoseStatusWithinLimits, results[0].Status)
+	assert.Equal(t, "APAP-HEPATIC", results[0].LimitCode)
+	assert.Equal(t, "age ≥ 12, Child-Pugh B", results[0].Basis)
+	assert.Equal(t, 97.5, results[0].PercentOfMax)
+}
+
+func TestEvaluateCumulativeDoses_NSAIDGroup(t *testing.T) {
+	limits := map[string][]IngredientDoseLimit{
+		"RXCUI:5640": {{LimitCode: "IBUPROFEN-MAX", IngredientCode: "RxCUI:5640", IngredientName: "Ibuprofen",
+			CumulativeGroup: stringPtr("NSAID"), MinAgeYears: intPtr(18), DoseUnit: "mg", MaxDailyDose: 3200}},
+		"RXCUI:7258": {{LimitCode: "NAPROXEN-MAX", IngredientCode: "RxCUI:7258", IngredientName: "Naproxen",
+			CumulativeGroup: stringPtr("NSAID"), MinAgeYears: intPtr(18), DoseUnit: "mg", MaxDailyDose: 1500}},
+	}
+	orders := []models.MedicationOrder{
+		{OrderID: "1", DrugCode: "RxCUI:5640", DrugName: "Ibuprofen", Dose: 800, DoseUnit: "mg", Frequency: "TID"},
+		{OrderID: "2", DrugCode: "RxCUI:7258", DrugName: "Naproxen", Dose: 500, DoseUnit: "mg", Frequency: "BID"},
+	}
+
+	results, _ := evaluateCumulativeDoses(limits, nil, orders, CumulativeDosePatient{AgeYears: intPtr(50)})
+	assert.Equal(t, 3, len(results))
+	group := results[0]
+	assert.Equal(t, CumulativeScopeGroup, group.Scope)
+	assert.Equal(t, DoseStatusExceedsMax, group.Status)
+	assert.Equal(t, 141.7, group.PercentOfMax) // 75% + 66.7%
+	assert.Equal(t, "Combined NSAID exposure is 141.7% of the maximum daily dose: Ibuprofen 75%, Naproxen 66.7%", group.Message)
+	assert.Equal(t, DoseStatusWithinLimits, results[1].Status)
+	assert.Equal(t, DoseStatusWithinLimits, results[2].Status)
+
+	// One NSAID alone has no group result
+	results, _ = evaluateCumulativeDoses(limits, nil, orders[:1], CumulativeDosePatient{AgeYears: intPtr(50)})
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, CumulativeScopeIngredient, results[0].Scope)
+}
+
+func TestEvaluateCumulativeDoses_AgeAndRenalLimits(t *testing.T) {
+	limits := map[string][]IngredientDoseLimit{
+		"RXCUI:2556": {
+			{LimitCode: "CITALOPRAM-MAX", IngredientCode: "RxCUI:2556", IngredientName: "Citalopram", MinAgeYears: intPtr(18), DoseUnit: "mg", MaxDailyDose: 40},
+			{LimitCode: "CITALOPRAM-AGE-60", IngredientCode: "RxCUI:2556", IngredientName: "Citalopram", MinAgeYears: intPtr(60), DoseUnit: "mg", MaxDailyDose: 20},
+		},
+		"RXCUI:6809": {
+			{LimitCode: "METFORMIN-MAX", IngredientCode: "RxCUI:6809", IngredientName: "Metformin", MinAgeYears: intPtr(18), DoseUnit: "mg", MaxDailyDose: 2550},
+			{LimitCode: "METFORMIN-EGFR-45", IngredientCode: "RxCUI:6809", IngredientName: "Metformin", MinAgeYears: intPtr(18),
+				RenalMetric: stringPtr(RenalMetricEGFR), RenalBelow: floatPtr(45), DoseUnit: "mg", MaxDailyDose: 1000},
+		},
+	}
+	products := map[string][]ProductIngredient{
+		"LOCAL:SITAGLIPTIN-METFORMIN-50-1000-TAB": {
+			{ProductCode: "LOCAL:SITAGLIPTIN-METFORMIN-50-1000-TAB", ProductName: "Sitagliptin/metformin 50/1000", DoseFormUnit: "tablet",
+				IngredientCode: "RxCUI:6809", IngredientName: "Metformin", Strength: 1000, StrengthUnit: "mg"},
+		},
+	}
+	citalopram := []models.MedicationOrder{{DrugCode: "RxCUI:2556", DrugName: "Citalopram", Dose: 30, DoseUnit: "mg", Frequency: "daily"}}
+
+	results, notes := evaluateCumulativeDoses(limits, products, citalopram, CumulativeDosePatient{AgeYears: intPtr(72)})
+	assert.Empty(t, notes)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, DoseStatusExceedsMax, results[0].Status)
+	assert.Equal(t, "CITALOPRAM-AGE-60", results[0].LimitCode)
+
+	results, _ = evaluateCumulativeDoses(limits, products, citalopram, CumulativeDosePatient{AgeYears: intPtr(40)})
+	assert.Equal(t, DoseStatusWithinLimits, results[0].Status)
+	assert.Equal(t, "CITALOPRAM-MAX", results[0].LimitCode)
+
+	// Unknown age applies the lowest age-bounded limit and says so
+	results, notes = evaluateCumulativeDoses(limits, products, citalopram, CumulativeDosePatient{})
+	assert.Equal(t, "CITALOPRAM-AGE-60", results[0].LimitCode)
+	assert.Equal(t, "age ≥ 60 (age not given)", results[0].Basis)
+	assert.Equal(t, []string{"Citalopram: patient age unknown; lowest age-specific limit applied (age ≥ 60, max 20 mg/day)"}, notes)
+
+	metformin := []models.MedicationOrder{
+		{OrderID: "1", DrugCode: "LOCAL:SITAGLIPTIN-METFORMIN-50-1000-TAB", Dose: 1, DoseUnit: "tablet", Frequency: "BID"},
+	}
+	results, _ = evaluateCumulativeDoses(limits, products, metformin, CumulativeDosePatient{AgeYears: intPtr(66),
+		Function: PatientOrganFunction{EGFR: floatPtr(38)}})
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, DoseStatusExceedsMax, results[0].Status)
+	assert.Equal(t, "METFORMIN-EGFR-45", results[0].LimitCode)
+	assert.Equal(t, "age ≥ 18, eGFR 38 mL/min/1.73m²", results[0].Basis)
+
+	results, _ = evaluateCumulativeDoses(limits, products, metformin, CumulativeDosePatient{AgeYears: intPtr(66),
+		Function: PatientOrganFunction{EGFR: floatPtr(60)}})
+	assert.Equal(t, "METFORMIN-MAX", results[0].LimitCode)
+	assert.Equal(t, DoseStatusWithinLimits, results[0].Status)
+}
+
+func TestEvaluateCumulativeDoses_Unverified(t *testing.T) {
+	limits := map[string][]IngredientDoseLimit{
+		"RXCUI:161": {{LimitCode: "APAP-MAX", IngredientCode: "RxCUI:161", IngredientName: "Acetaminophen", MinAgeYears: intPtr(12), DoseUnit: "mg", MaxDailyDose: 4000}},
+	}
+	products := map[string][]ProductIngredient{
+		"LOCAL:APAP-160MG-5ML-SOLN": {
+			{ProductCode: "LOCAL:APAP-160MG-5ML-SOLN", ProductName: "Acetaminophen 160 mg/5 mL", DoseFormUnit: "mL",
+				IngredientCode: "RxCUI:161", IngredientName: "Acetaminophen", Strength: 32, StrengthUnit: "mg"},
+		},
+		"LOCAL:OXYCODONE-APAP-5-325-TAB": {
+			{ProductCode: "LOCAL:OXYCODONE-APAP-5-325-TAB", ProductName: "Oxycodone/acetaminophen 5/325", DoseFormUnit: "tablet",
+				IngredientCode: "RxCUI:161", IngredientName: "Acetaminophen", Strength: 325, StrengthUnit: "mg"},
+		},
+	}
+	orders := []models.MedicationOrder{
+		{OrderID: "1", DrugCode: "LOCAL:APAP-160MG-5ML-SOLN", Dose: 20, DoseUnit: "mL", Frequency: "q6h"},
+		{OrderID: "2", DrugCode: "RxCUI:161", Dose: 1, DoseUnit: "tablet", Frequency: "q6h"},
+		{OrderID: "3", DrugCode: "RxCUI:161", Dose: 500, DoseUnit: "mg", Frequency: "with meals"},
+		{OrderID: "4", DrugCode: "LOCAL:OXYCODONE-APAP-5-325-TAB", Dose: 5, DoseUnit: "mg", Frequency: "q6h"},
+	}
+
+	results, notes := evaluateCumulativeDoses(limits, products, orders, CumulativeDosePatient{AgeYears: intPtr(30)})
+	assert.Equal(t, []string{`Oxycodone/acetaminophen 5/325: dose unit "mg" does not match product unit "tablet"; ingredients not counted`}, notes)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, DoseStatusUnverified, results[0].Status)
+	assert.Equal(t, 2560.0, *results[0].TotalDailyDose) // 640 mg q6h of solution
+	assert.Equal(t, []string{
+		`order 2: unit "tablet" cannot be compared with mg`,
+		"order 3: frequency not recognized",
+	}, results[0].Issues)
+}
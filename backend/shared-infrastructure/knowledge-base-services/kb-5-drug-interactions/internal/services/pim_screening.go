@@ -0,0 +1,584 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kb-drug-interactions/internal/database"
+	"kb-drug-interactions/internal/metrics"
+	"kb-drug-interactions/internal/models"
+)
+
+// PIM criterion types (pim_criteria.criterion_type)
+const (
+	PIMCriterionAvoid       = "avoid"        // Drug to avoid in older adults
+	PIMCriterionDrugDisease = "drug_disease" // Drug to avoid with a condition
+	PIMCriterionDrugDrug    = "drug_drug"    // Combination to avoid
+	PIMCriterionRenal       = "renal"        // Drug to avoid or reduce below a renal threshold
+)
+
+// PIMCriteriaSet is one published version of an explicit criteria set
+type PIMCriteriaSet struct {
+	ID            int       `json:"id" gorm:"primaryKey"`
+	SetCode       string    `json:"set_code"`
+	Version       string    `json:"version"`
+	Title         string    `json:"title"`
+	PublishedDate time.Time `json:"published_date"`
+	MinAge        int       `json:"min_age"`
+	IsCurrent     bool      `json:"is_current"`
+	Source        string    `json:"source"`
+}
+
+// TableName specifies the database table for GORM
+func (PIMCriteriaSet) TableName() string {
+	return "pim_criteria_sets"
+}
+
+// Key identifies the set version, e.g. "BEERS:2023"
+func (s PIMCriteriaSet) Key() string {
+	return s.SetCode + ":" + s.Version
+}
+
+// PIMCriterion is one potentially inappropriate medication criterion
+type PIMCriterion struct {
+	ID                       int                `json:"id" gorm:"primaryKey"`
+	SetCode                  string             `json:"set_code"`
+	Version                  string             `json:"version"`
+	CriterionCode            string             `json:"criterion_code"`
+	CriterionType            string             `json:"criterion_type"`
+	DrugDescription          string             `json:"drug_description"`
+	DrugCodes                models.StringArray `json:"drug_codes,omitempty" gorm:"type:text[]"`
+	ATCClasses               models.StringArray `json:"atc_classes,omitempty" gorm:"column:atc_classes;type:text[]"`
+	ConditionDescription     *string            `json:"condition_description,omitempty"`
+	ConditionCodes           models.StringArray `json:"condition_codes,omitempty" gorm:"type:text[]"`
+	ExceptionConditionCodes  models.StringArray `json:"exception_condition_codes,omitempty" gorm:"type:text[]"`
+	InteractingDescription   *string            `json:"interacting_description,omitempty"`
+	InteractingDrugCodes     models.StringArray `json:"interacting_drug_codes,omitempty" gorm:"type:text[]"`
+	InteractingATCClasses    models.StringArray `json:"interacting_atc_classes,omitempty" gorm:"column:interacting_atc_classes;type:text[]"`
+	RenalMetric              *string            `json:"renal_metric,omitempty"`
+	RenalThreshold           *float64           `json:"renal_threshold,omitempty"` // Applies below this value
+	Severity                 models.DDISeverity `json:"severity"`
+	Rationale                string             `json:"rationale"`
+	Recommendation           string             `json:"recommendation"`
+	Alternatives             models.StringArray `json:"alternatives,omitempty" gorm:"type:text[]"`
+	QualityOfEvidence        *string            `json:"quality_of_evidence,omitempty"`
+	StrengthOfRecommendation *string            `json:"strength_of_recommendation,omitempty"`
+	Active                   bool               `json:"active"`
+}
+
+// TableName specifies the database table for GORM
+func (PIMCriterion) TableName() string {
+	return "pim_criteria"
+}
+
+// PIMPatient is the patient information PIM criteria are screened against
+type PIMPatient struct {
+	AgeYears   *int                 `json:"age_years,omitempty"`
+	AgeBand    string               `json:"age_band,omitempty"`
+	Conditions []string             `json:"conditions,omitempty"` // ICD-10 or SNOMED, optionally system-prefixed
+	Function   PatientOrganFunction `json:"function"`
+}
+
+// PIMScreeningRequest screens a regimen against explicit criteria for older adults
+type PIMScreeningRequest struct {
+	DrugCodes      []string               `json:"drug_codes" binding:"required,min=1"`
+	PatientContext *models.PatientContext `json:"patient_context" binding:"required"`
+	CriteriaSets   []string               `json:"criteria_sets,omitempty"` // "BEERS", "STOPP:v3"; default all current
+}
+
+// PIMScreeningResponse is the outcome of a PIM screen
+type PIMScreeningResponse struct {
+	CriteriaApplied []PIMCriteriaSet                   `json:"criteria_applied"`
+	OrganFunction   *models.OrganFunctionAssessment    `json:"organ_function,omitempty"`
+	Findings        []models.EnhancedInteractionResult `json:"findings"`
+	Notes           []string                           `json:"notes,omitempty"`
+}
+
+// pimCriteriaVersion is a criteria set version with its active criteria
+type pimCriteriaVersion struct {
+	Set      PIMCriteriaSet
+	Criteria []PIMCriterion
+}
+
+// PIMScreeningEngine screens regimens against Beers/STOPP-style criteria for older adults
+type PIMScreeningEngine struct {
+	db        *database.Database
+	atcIndex  *ATCClassIndex
+	hierarchy *DiseaseHierarchy
+	metrics   *metrics.Collector
+
+	// Criteria keyed by set version ("BEERS:2023")
+	versions       map[string]pimCriteriaVersion
+	versionsLoaded time.Time
+	cacheTTL       time.Duration
+	mu             sync.Mutex
+}
+
+// NewPIMScreeningEngine creates a new potentially inappropriate medication screening engine.
+// Drugs match class criteria through the ATC index; without it only listed drug codes match.
+func NewPIMScreeningEngine(db *database.Database, atcIndex *ATCClassIndex, metrics *metrics.Collector) *PIMScreeningEngine {
+	return &PIMScreeningEngine{
+		db:        db,
+		atcIndex:  atcIndex,
+		hierarchy: NewDiseaseHierarchy(),
+		metrics:   metrics,
+		cacheTTL:  30 * time.Minute,
+	}
+}
+
+// Screen derives the patient's organ function (from labs when supplied) and
+// screens the regimen. Invalid labs are reported as *LabInputError.
+func (pse *PIMScreeningEngine) Screen(ctx context.Context, request PIMScreeningRequest) (*PIMScreeningResponse, error) {
+	organFunction, err := ResolvePatientContext(request.PatientContext)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := pse.ScreenRegimen(ctx, request.DrugCodes, PIMPatientFromContext(request.PatientContext), request.CriteriaSets)
+	if err != nil {
+		return nil, err
+	}
+	response.OrganFunction = organFunction
+	return response, nil
+}
+
+// ScreenRegimen screens the regimen against the selected criteria set versions
+// (all current versions when none are named)
+func (pse *PIMScreeningEngine) ScreenRegimen(
+	ctx context.Context,
+	drugCodes []string,
+	patient PIMPatient,
+	criteriaSets []string,
+) (*PIMScreeningResponse, error) {
+	versions, err := pse.loadCriteria(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PIM criteria: %w", err)
+	}
+
+	selected, notes := selectPIMCriteriaVersions(versions, criteriaSets)
+	response := &PIMScreeningResponse{
+		CriteriaApplied: []PIMCriteriaSet{},
+		Findings:        []models.EnhancedInteractionResult{},
+		Notes:           notes,
+	}
+	for _, version := range selected {
+		response.CriteriaApplied = append(response.CriteriaApplied, version.Set)
+	}
+
+	findings, screenNotes := screenPIMCriteria(selected, drugCodes, patient, pse.drugClasses, pse.hierarchy)
+	response.Findings = findings
+	response.Notes = append(response.Notes, screenNotes...)
+
+	for _, f := range findings {
+		pse.metrics.RecordPIMMatch(strings.ToLower(f.Qualifiers["criteria_set"]), f.Qualifiers["criterion_type"])
+	}
+	return response, nil
+}
+
+// GetCriteriaSets returns all criteria set versions, current versions first
+func (pse *PIMScreeningEngine) GetCriteriaSets(ctx context.Context) ([]PIMCriteriaSet, error) {
+	versions, err := pse.loadCriteria(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PIM criteria: %w", err)
+	}
+
+	sets := make([]PIMCriteriaSet, 0, len(versions))
+	for _, version := range versions {
+		sets = append(sets, version.Set)
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		if sets[i].IsCurrent != sets[j].IsCurrent {
+			return sets[i].IsCurrent
+		}
+		if sets[i].SetCode != sets[j].SetCode {
+			return sets[i].SetCode < sets[j].SetCode
+		}
+		return sets[i].PublishedDate.After(sets[j].PublishedDate)
+	})
+	return sets, nil
+}
+
+// GetCriteria returns the active criteria of a set version ("BEERS" for the current version)
+func (pse *PIMScreeningEngine) GetCriteria(ctx context.Context, criteriaSet string) (*PIMCriteriaSet, []PIMCriterion, error) {
+	versions, err := pse.loadCriteria(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load PIM criteria: %w", err)
+	}
+
+	selected, _ := selectPIMCriteriaVersions(versions, []string{criteriaSet})
+	if len(selected) == 0 {
+		return nil, nil, nil
+	}
+	return &selected[0].Set, selected[0].Criteria, nil
+}
+
+// PIMPatientFromContext reads the screening inputs from a resolved patient context
+func PIMPatientFromContext(patientContext *models.PatientContext) PIMPatient {
+	var patient PIMPatient
+	if patientContext == nil {
+		return patient
+	}
+	if patientContext.Age > 0 {
+		age := patientContext.Age
+		patient.AgeYears = &age
+		patient.AgeBand = ageBand(age)
+	} else if patientContext.Labs != nil && patientContext.Labs.AgeYears != nil {
+		patient.AgeYears = patientContext.Labs.AgeYears
+		patient.AgeBand = ageBand(*patientContext.Labs.AgeYears)
+	}
+	patient.Conditions = patientContext.Comorbidities
+	patient.Function = patientOrganFunction(patientContext)
+	return patient
+}
+
+// PIMPatientFromContextData reads the screening inputs from a governance patient
+// context and the organ function derived from its labs
+func PIMPatientFromContextData(patientContext *models.PatientContextData, organFunction *models.OrganFunctionAssessment) PIMPatient {
+	var patient PIMPatient
+	if patientContext == nil {
+		return patient
+	}
+	patient.AgeBand = patientContext.AgeBand
+	if patientContext.Labs != nil {
+		patient.AgeYears = patientContext.Labs.AgeYears
+	}
+	patient.Conditions = patientContext.Comorbidities
+	if organFunction != nil {
+		if organFunction.CrCl != nil {
+			crcl := organFunction.CrCl.Value
+			patient.Function.CrCl = &crcl
+		}
+		if organFunction.EGFR != nil {
+			egfr := organFunction.EGFR.Value
+			patient.Function.EGFR = &egfr
+		}
+	}
+	return patient
+}
+
+// IsOlderAdult reports whether the patient falls in the older adult age band
+func (p PIMPatient) IsOlderAdult() bool {
+	if p.AgeYears != nil {
+		return ageBand(*p.AgeYears) == "older_adult"
+	}
+	return p.AgeBand == "older_adult"
+}
+
+// selectPIMCriteriaVersions resolves set selectors ("BEERS" = current version,
+// "BEERS:2019" = that version) to loaded versions. No selectors selects every
+// current version. Unknown selectors are reported as notes.
+func selectPIMCriteriaVersions(versions map[string]pimCriteriaVersion, selectors []string) ([]pimCriteriaVersion, []string) {
+	var selected []pimCriteriaVersion
+	var notes []string
+	seen := make(map[string]bool)
+	add := func(version pimCriteriaVersion) {
+		if !seen[version.Set.Key()] {
+			seen[version.Set.Key()] = true
+			selected = append(selected, version)
+		}
+	}
+
+	if len(selectors) == 0 {
+		for _, version := range versions {
+			if version.Set.IsCurrent {
+				add(version)
+			}
+		}
+	}
+
+	for _, selector := range selectors {
+		setCode, version, hasVersion := strings.Cut(strings.TrimSpace(selector), ":")
+		setCode = strings.ToUpper(setCode)
+		found := false
+		for _, candidate := range versions {
+			if candidate.Set.SetCode != setCode {
+				continue
+			}
+			if (hasVersion && strings.EqualFold(candidate.Set.Version, version)) || (!hasVersion && candidate.Set.IsCurrent) {
+				add(candidate)
+				found = true
+			}
+		}
+		if !found {
+			notes = append(notes, fmt.Sprintf("Criteria set %q not found", selector))
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Set.Key() < selected[j].Set.Key() })
+	return selected, notes
+}
+
+// screenPIMCriteria evaluates each criterion against the regimen and patient.
+// classesFor returns a drug's ATC codes at every level.
+func screenPIMCriteria(
+	versions []pimCriteriaVersion,
+	drugCodes []string,
+	patient PIMPatient,
+	classesFor func(drugCode string) []string,
+	hierarchy *DiseaseHierarchy,
+) ([]models.EnhancedInteractionResult, []string) {
+	findings := []models.EnhancedInteractionResult{}
+	var notes []string
+
+	drugs := make([]regimenDrug, 0, len(drugCodes))
+	seenDrugs := make(map[string]bool)
+	for _, code := range drugCodes {
+		key := normalizeATCDrugKey(code)
+		if seenDrugs[key] {
+			continue
+		}
+		seenDrugs[key] = true
+		drugs = append(drugs, regimenDrug{Code: code, Key: key, Classes: classesFor(code)})
+	}
+
+	conditions := make([]DiseaseCodeRef, 0, len(patient.Conditions))
+	for _, condition := range patient.Conditions {
+		conditions = append(conditions, NormalizeDiseaseCode("", condition))
+	}
+
+	seen := make(map[string]bool)
+	renalNoted := make(map[string]bool)
+	for _, version := range versions {
+		if patient.AgeYears != nil && *patient.AgeYears < version.Set.MinAge {
+			notes = append(notes, fmt.Sprintf("%s applies from age %d; patient is %d",
+				version.Set.Title, version.Set.MinAge, *patient.AgeYears))
+			continue
+		}
+		// Without an age the set's minimum age cannot be checked; an older adult age band will do
+		if patient.AgeYears == nil && version.Set.MinAge > 0 && !patient.IsOlderAdult() {
+			notes = append(notes, fmt.Sprintf("%s applies from age %d; patient age is missing, criteria not screened",
+				version.Set.Title, version.Set.MinAge))
+			continue
+		}
+
+		for _, criterion := range version.Criteria {
+			for _, drug := range drugs {
+				if !regimenDrugMatches(drug, criterion.DrugCodes, criterion.ATCClasses) {
+					continue
+				}
+
+				condition, ok := pimConditionMatch(criterion, conditions, hierarchy)
+				if !ok {
+					continue
+				}
+
+				renalBasis := ""
+				if criterion.RenalMetric != nil && criterion.RenalThreshold != nil {
+					value, label, _ := renalMetricValue(*criterion.RenalMetric, patient.Function)
+					if value == nil {
+						if !renalNoted[criterion.CriterionCode] {
+							renalNoted[criterion.CriterionCode] = true
+							notes = append(notes, fmt.Sprintf("%s not evaluated: no CrCl or eGFR", criterion.CriterionCode))
+						}
+						continue
+					}
+					if *value >= *criterion.RenalThreshold {
+						continue
+					}
+					renalBasis = fmt.Sprintf("%s %g %s", label, *value, renalMetricUnit(label))
+				}
+
+				var partners []regimenDrug
+				if len(criterion.InteractingDrugCodes) > 0 || len(criterion.InteractingATCClasses) > 0 {
+					for _, other := range drugs {
+						if other.Key != drug.Key && regimenDrugMatches(other, criterion.InteractingDrugCodes, criterion.InteractingATCClasses) {
+							partners = append(partners, other)
+						}
+					}
+					if len(partners) == 0 {
+						continue
+					}
+				} else {
+					partners = []regimenDrug{{}}
+				}
+
+				for _, partner := range partners {
+					id := pimFindingID(version.Set, criterion, drug, partner)
+					if seen[id] {
+						continue
+					}
+					seen[id] = true
+					findings = append(findings, pimFinding(version.Set, criterion, drug, partner, patient, condition, renalBasis, id))
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity.GetPriority() > findings[j].Severity.GetPriority()
+	})
+	return findings, notes
+}
+
+// pimConditionMatch checks a criterion's condition and exception codes. It returns
+// the matched patient condition ("" for criteria without conditions).
+func pimConditionMatch(criterion PIMCriterion, conditions []DiseaseCodeRef, hierarchy *DiseaseHierarchy) (string, bool) {
+	for _, exception := range criterion.ExceptionConditionCodes {
+		rule := NormalizeDiseaseCode(CodeSystemICD10, exception)
+		for _, condition := range conditions {
+			if _, ok := hierarchy.Match(condition, rule); ok {
+				return "", false
+			}
+		}
+	}
+
+	if len(criterion.ConditionCodes) == 0 {
+		return "", true
+	}
+	for _, code := range criterion.ConditionCodes {
+		rule := NormalizeDiseaseCode(CodeSystemICD10, code)
+		for _, condition := range conditions {
+			if match, ok := hierarchy.Match(condition, rule); ok && match.MatchType != DiseaseMatchDescendant {
+				return condition.key(), true
+			}
+		}
+	}
+	return "", false
+}
+
+func pimFindingID(set PIMCriteriaSet, criterion PIMCriterion, drug, partner regimenDrug) string {
+	id := fmt.Sprintf("PIM_%s_%s_%s", set.Key(), criterion.CriterionCode, drug.Key)
+	if partner.Key == "" {
+		return id
+	}
+	// Combinations are reported once whichever drug matched first
+	first, second := drug.Key, partner.Key
+	if second < first {
+		first, second = second, first
+	}
+	return fmt.Sprintf("PIM_%s_%s_%s_%s", set.Key(), criterion.CriterionCode, first, second)
+}
+
+// pimFinding builds the interaction result for a matched criterion
+func pimFinding(
+	set PIMCriteriaSet,
+	criterion PIMCriterion,
+	drug, partner regimenDrug,
+	patient PIMPatient,
+	condition, renalBasis, id string,
+) models.EnhancedInteractionResult {
+	finding := models.EnhancedInteractionResult{
+		InteractionID:      id,
+		Severity:           criterion.Severity,
+		Mechanism:          models.MechanismPD,
+		ClinicalEffects:    criterion.Rationale,
+		ManagementStrategy: criterion.Recommendation,
+		Evidence:           pimEvidenceLevel(criterion.QualityOfEvidence),
+		Sources:            []string{set.Title},
+		AlternativeDrugs:   []string(criterion.Alternatives),
+		Drug1: models.DrugInfo{
+			Code: drug.Code,
+			Name: criterion.DrugDescription,
+		},
+		Qualifiers: map[string]string{
+			"type":             "pim",
+			"criteria_set":     set.SetCode,
+			"criteria_version": set.Version,
+			"criterion_code":   criterion.CriterionCode,
+			"criterion_type":   criterion.CriterionType,
+		},
+	}
+	if criterion.QualityOfEvidence != nil {
+		finding.Qualifiers["quality_of_evidence"] = *criterion.QualityOfEvidence
+	}
+	if criterion.StrengthOfRecommendation != nil {
+		finding.Qualifiers["strength_of_recommendation"] = *criterion.StrengthOfRecommendation
+	}
+
+	switch {
+	case partner.Key != "":
+		finding.Drug2 = models.DrugInfo{Code: partner.Code, Name: derefString(criterion.InteractingDescription)}
+	case condition != "":
+		finding.Drug2 = models.DrugInfo{Code: condition, Name: derefString(criterion.ConditionDescription)}
+	case renalBasis != "":
+		finding.Mechanism = models.MechanismPK
+		finding.Drug2 = models.DrugInfo{Code: "RENAL_FUNCTION", Name: renalBasis}
+	default:
+		name := "Older adult"
+		if patient.AgeYears != nil {
+			name = fmt.Sprintf("Older adult (age %d)", *patient.AgeYears)
+		}
+		finding.Drug2 = models.DrugInfo{Code: "OLDER_ADULT", Name: name}
+	}
+	if condition != "" {
+		finding.Qualifiers["matched_condition"] = condition
+	}
+	if renalBasis != "" {
+		finding.Qualifiers["renal_basis"] = renalBasis
+		finding.Qualifiers["renal_threshold"] = strconv.FormatFloat(*criterion.RenalThreshold, 'f', -1, 64)
+	}
+	return finding
+}
+
+// pimEvidenceLevel maps a criterion's quality of evidence to an evidence level
+func pimEvidenceLevel(quality *string) models.EvidenceLevel {
+	if quality == nil {
+		return models.EvidenceLevelExpertOpinion
+	}
+	switch *quality {
+	case "high":
+		return models.EvidenceLevelA
+	case "moderate":
+		return models.EvidenceLevelB
+	case "low":
+		return models.EvidenceLevelC
+	}
+	return models.EvidenceLevelUnknown
+}
+
+// drugClasses returns a drug's ATC codes at every level
+func (pse *PIMScreeningEngine) drugClasses(drugCode string) []string {
+	if pse.atcIndex == nil {
+		return nil
+	}
+	return pse.atcIndex.ClassesForDrug(drugCode)
+}
+
+// loadCriteria returns every criteria set version with its active criteria
+func (pse *PIMScreeningEngine) loadCriteria(ctx context.Context) (map[string]pimCriteriaVersion, error) {
+	pse.mu.Lock()
+	defer pse.mu.Unlock()
+
+	if pse.versions != nil && time.Since(pse.versionsLoaded) < pse.cacheTTL {
+		return pse.versions, nil
+	}
+
+	var sets []PIMCriteriaSet
+	if err := pse.db.DB.WithContext(ctx).Find(&sets).Error; err != nil {
+		return nil, err
+	}
+	var rows []PIMCriterion
+	err := pse.db.DB.WithContext(ctx).
+		Where("active = TRUE").
+		Order("set_code, version, criterion_code").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]pimCriteriaVersion, len(sets))
+	for _, set := range sets {
+		set.SetCode = strings.ToUpper(set.SetCode)
+		versions[set.Key()] = pimCriteriaVersion{Set: set}
+	}
+	for _, row := range rows {
+		row.SetCode = strings.ToUpper(row.SetCode)
+		key := row.SetCode + ":" + row.Version
+		version, ok := versions[key]
+		if !ok {
+			continue
+		}
+		version.Criteria = append(version.Criteria, row)
+		versions[key] = version
+	}
+
+	pse.versions = versions
+	pse.versionsLoaded = time.Now()
+
+	return versions, nil
+}
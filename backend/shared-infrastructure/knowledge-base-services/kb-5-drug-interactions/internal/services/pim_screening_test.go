@@ -0,0 +1,252 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// OLDER ADULT PIM SCREENING TESTS
+// ============================================================================
+
+func TestSelectPIMCriteriaVersions(t *testing.T) {
+	beers := PIMCriteriaSet{SetCode: "BEERS", Version: "2023", Title: "AGS Beers Criteria 2023", MinAge: 65, IsCurrent: true}
+	stopp := PIMCriteriaSet{SetCode: "STOPP", Version: "v3", Title: "STOPP/START v3", MinAge: 65, IsCurrent: true}
+	oldBeers := PIMCriteriaSet{SetCode: "BEERS", Version: "2019", Title: "AGS Beers Criteria 2019", MinAge: 65}
+	versions := map[string]pimCriteriaVersion{
+		beers.Key():    {Set: beers},
+		stopp.Key():    {Set: stopp},
+		oldBeers.Key(): {Set: oldBeers},
+	}
+
+	selected, notes := selectPIMCriteriaVersions(versions, nil)
+	require.Len(t, selected, 2)
+	assert.Empty(t, notes)
+	assert.Equal(t, "BEERS:2023", selected[0].Set.Key())
+	assert.Equal(t, "STOPP:v3", selected[1].Set.Key())
+
+	selected, _ = selectPIMCriteriaVersions(versions, []string{"beers:2019"})
+	require.Len(t, selected, 1)
+	assert.Equal(t, "2019", selected[0].Set.Version)
+
+	selected, notes = selectPIMCriteriaVersions(versions, []string{"BEERS", "FORTA"})
+	require.Len(t, selected, 1)
+	assert.Equal(t, "2023", selected[0].Set.Version)
+	assert.Equal(t, []string{`Criteria set "FORTA" not found`}, notes)
+}
+
+func TestScreenPIMCriteria_AvoidAndDrugDisease(t *testing.T) {
+	versions := []pimCriteriaVersion{{
+		Set: PIMCriteriaSet{SetCode: "BEERS", Version: "2023", Title: "AGS Beers Criteria 2023", MinAge: 65, IsCurrent: true},
+		Criteria: []PIMCriterion{
+			{SetCode: "BEERS", Version: "2023", CriterionCode: "BEERS-T2-BENZODIAZEPINE", CriterionType: PIMCriterionAvoid,
+				DrugDescription: "Benzodiazepines", ATCClasses: models.StringArray{"N05BA"}, Severity: models.SeverityMajor,
+				Rationale: "Falls and cognitive impairment", Recommendation: "Avoid.", QualityOfEvidence: stringPtr("moderate"),
+				StrengthOfRecommendation: stringPtr("strong")},
+			{SetCode: "BEERS", Version: "2023", CriterionCode: "BEERS-T3-HF-NSAID", CriterionType: PIMCriterionDrugDisease,
+				DrugDescription: "NSAIDs", ATCClasses: models.StringArray{"M01A"}, ConditionDescription: stringPtr("Heart failure"),
+				ConditionCodes: models.StringArray{"I50"}, Severity: models.SeverityMajor, Rationale: "Fluid retention",
+				Recommendation: "Avoid.", Alternatives: models.StringArray{"Acetaminophen"}, QualityOfEvidence: stringPtr("moderate")},
+		},
+	}}
+	classes := map[string][]string{
+		"RXCUI:596":  {"N", "N05", "N05B", "N05BA", "N05BA12"}, // Alprazolam
+		"RXCUI:5640": {"M", "M01", "M01A", "M01AE", "M01AE01"}, // Ibuprofen
+	}
+	classesFor := func(drugCode string) []string { return classes[normalizeATCDrugKey(drugCode)] }
+
+	patient := PIMPatient{AgeYears: intPtr(78), Conditions: []string{"I50.22"}}
+	findings, notes := screenPIMCriteria(versions, []string{"596", "5640"}, patient, classesFor, NewDiseaseHierarchy())
+
+	require.Len(t, findings, 2)
+	assert.Empty(t, notes)
+
+	benzo := findings[0]
+	assert.Equal(t, "PIM_BEERS:2023_BEERS-T2-BENZODIAZEPINE_RXCUI:596", benzo.InteractionID)
+	assert.Equal(t, "OLDER_ADULT", benzo.Drug2.Code)
+	assert.Equal(t, "Older adult (age 78)", benzo.Drug2.Name)
+	assert.Equal(t, "pim", benzo.Qualifiers["type"])
+	assert.Equal(t, "2023", benzo.Qualifiers["criteria_version"])
+	assert.Equal(t, "strong", benzo.Qualifiers["strength_of_recommendation"])
+	assert.Equal(t, models.EvidenceLevelB, benzo.Evidence)
+
+	// I50.22 falls under the I50 heart failure category
+	nsaid := findings[1]
+	assert.Equal(t, "BEERS-T3-HF-NSAID", nsaid.Qualifiers["criterion_code"])
+	assert.Equal(t, "ICD10:I5022", nsaid.Drug2.Code)
+	assert.Equal(t, "ICD10:I5022", nsaid.Qualifiers["matched_condition"])
+	assert.Equal(t, []string{"Acetaminophen"}, nsaid.AlternativeDrugs)
+
+	// Without heart failure the NSAID criterion does not apply
+	findings, _ = screenPIMCriteria(versions, []string{"5640"}, PIMPatient{AgeYears: intPtr(78)}, classesFor, NewDiseaseHierarchy())
+	assert.Empty(t, findings)
+}
+
+func TestScreenPIMCriteria_DrugDrugReportedOncePerPair(t *testing.T) {
+	versions := []pimCriteriaVersion{
+		{
+			Set: PIMCriteriaSet{SetCode: "BEERS", Version: "2023", Title: "AGS Beers Criteria 2023", MinAge: 65, IsCurrent: true},
+			Criteria: []PIMCriterion{
+				{SetCode: "BEERS", Version: "2023", CriterionCode: "BEERS-T5-OPIOID-BENZODIAZEPINE", CriterionType: PIMCriterionDrugDrug,
+					DrugDescription: "Opioids", ATCClasses: models.StringArray{"N02A"}, InteractingDescription: stringPtr("Benzodiazepines"),
+					InteractingATCClasses: models.StringArray{"N05BA"}, Severity: models.SeverityMajor,
+					Rationale: "Overdose risk", Recommendation: "Avoid.", QualityOfEvidence: stringPtr("moderate")},
+			},
+		},
+		{
+			Set: PIMCriteriaSet{SetCode: "STOPP", Version: "v3", Title: "STOPP/START v3", MinAge: 65, IsCurrent: true},
+			Criteria: []PIMCriterion{
+				{SetCode: "STOPP", Version: "v3", CriterionCode: "STOPP-M-DUAL-ANTICHOLINERGIC", CriterionType: PIMCriterionDrugDrug,
+					DrugDescription: "Anticholinergics", ATCClasses: models.StringArray{"N05BB", "A03B"},
+					InteractingDescription: stringPtr("Anticholinergics"), InteractingATCClasses: models.StringArray{"N05BB", "A03B"},
+					Severity: models.SeverityModerate, Rationale: "Anticholinergic burden", Recommendation: "Stop one.",
+					QualityOfEvidence: stringPtr("high")},
+			},
+		},
+	}
+	classes := map[string][]string{
+		"RXCUI:596":  {"N", "N05", "N05B", "N05BA", "N05BA12"}, // Alprazolam
+		"RXCUI:7804": {"N", "N02", "N02A", "N02AA", "N02AA05"}, // Oxycodone
+		"RXCUI:5553": {"N", "N05", "N05B", "N05BB", "N05BB01"}, // Hydroxyzine
+		"RXCUI:1223": {"A", "A03", "A03B", "A03BA", "A03BA01"}, // Atropine
+	}
+	classesFor := func(drugCode string) []string { return classes[normalizeATCDrugKey(drugCode)] }
+
+	patient := PIMPatient{AgeYears: intPtr(70)}
+	findings, _ := screenPIMCriteria(versions, []string{"7804", "596", "5553", "1223"}, patient, classesFor, NewDiseaseHierarchy())
+
+	byCode := make(map[string][]models.EnhancedInteractionResult)
+	for _, f := range findings {
+		byCode[f.Qualifiers["criterion_code"]] = append(byCode[f.Qualifiers["criterion_code"]], f)
+	}
+
+	require.Len(t, byCode["BEERS-T5-OPIOID-BENZODIAZEPINE"], 1)
+	combo := byCode["BEERS-T5-OPIOID-BENZODIAZEPINE"][0]
+	assert.Equal(t, "7804", combo.Drug1.Code)
+	assert.Equal(t, "596", combo.Drug2.Code)
+
+	// Both sides of a symmetric criterion match; the pair is reported once
+	require.Len(t, byCode["STOPP-M-DUAL-ANTICHOLINERGIC"], 1)
+	assert.Equal(t, "PIM_STOPP:v3_STOPP-M-DUAL-ANTICHOLINERGIC_RXCUI:1223_RXCUI:5553",
+		byCode["STOPP-M-DUAL-ANTICHOLINERGIC"][0].InteractionID)
+	assert.Equal(t, models.EvidenceLevelA, byCode["STOPP-M-DUAL-ANTICHOLINERGIC"][0].Evidence)
+}
+
+func TestScreenPIMCriteria_RenalThreshold(t *testing.T) {
+	versions := []pimCriteriaVersion{{
+		Set: PIMCriteriaSet{SetCode: "BEERS", Version: "2023", Title: "AGS Beers Criteria 2023", MinAge: 65, IsCurrent: true},
+		Criteria: []PIMCriterion{
+			{SetCode: "BEERS", Version: "2023", CriterionCode: "BEERS-T6-NITROFURANTOIN", CriterionType: PIMCriterionRenal,
+				DrugDescription: "Nitrofurantoin", DrugCodes: models.StringArray{"RxCUI:7454"}, RenalMetric: stringPtr(RenalMetricCrCl),
+				RenalThreshold: floatPtr(30), Severity: models.SeverityMajor, Rationale: "Toxicity and inefficacy",
+				Recommendation: "Avoid.", QualityOfEvidence: stringPtr("low")},
+		},
+	}}
+	noClasses := func(string) []string { return nil }
+
+	patient := PIMPatient{AgeYears: intPtr(82), Function: PatientOrganFunction{CrCl: floatPtr(24)}}
+	findings, _ := screenPIMCriteria(versions, []string{"7454"}, patient, noClasses, NewDiseaseHierarchy())
+
+	require.Len(t, findings, 1)
+	assert.Equal(t, "RENAL_FUNCTION", findings[0].Drug2.Code)
+	assert.Equal(t, "CrCl 24 mL/min", findings[0].Qualifiers["renal_basis"])
+	assert.Equal(t, "30", findings[0].Qualifiers["renal_threshold"])
+	assert.Equal(t, models.MechanismPK, findings[0].Mechanism)
+	assert.Equal(t, models.EvidenceLevelC, findings[0].Evidence)
+
+	// Threshold is exclusive
+	patient.Function.CrCl = floatPtr(30)
+	findings, _ = screenPIMCriteria(versions, []string{"7454"}, patient, noClasses, NewDiseaseHierarchy())
+	assert.Empty(t, findings)
+
+	// eGFR stands in for CrCl when only eGFR is known
+	findings, _ = screenPIMCriteria(versions, []string{"7454"},
+		PIMPatient{AgeYears: intPtr(82), Function: PatientOrganFunction{EGFR: floatPtr(20)}}, noClasses, NewDiseaseHierarchy())
+	require.Len(t, findings, 1)
+	assert.Equal(t, "eGFR 20 mL/min/1.73m²", findings[0].Qualifiers["renal_basis"])
+
+	findings, notes := screenPIMCriteria(versions, []string{"7454"}, PIMPatient{AgeYears: intPtr(82)}, noClasses, NewDiseaseHierarchy())
+	assert.Empty(t, findings)
+	assert.Equal(t, []string{"BEERS-T6-NITROFURANTOIN not evaluated: no CrCl or eGFR"}, notes)
+}
+
+func TestScreenPIMCriteria_BelowMinimumAge(t *testing.T) {
+	versions := []pimCriteriaVersion{
+		{
+			Set: PIMCriteriaSet{SetCode: "BEERS", Version: "2023", Title: "AGS Beers Criteria 2023", MinAge: 65, IsCurrent: true},
+			Criteria: []PIMCriterion{
+				{SetCode: "BEERS", Version: "2023", CriterionCode: "BEERS-T2-BENZODIAZEPINE", CriterionType: PIMCriterionAvoid,
+					DrugDescription: "Benzodiazepines", ATCClasses: models.StringArray{"N05BA"}, Severity: models.SeverityMajor,
+					Rationale: "Falls and cognitive impairment", Recommendation: "Avoid."},
+			},
+		},
+		{Set: PIMCriteriaSet{SetCode: "STOPP", Version: "v3", Title: "STOPP/START v3", MinAge: 65, IsCurrent: true}},
+	}
+	classesFor := func(drugCode string) []string {
+		if normalizeATCDrugKey(drugCode) == "RXCUI:596" { // Alprazolam
+			return []string{"N", "N05", "N05B", "N05BA", "N05BA12"}
+		}
+		return nil
+	}
+
+	findings, notes := screenPIMCriteria(versions, []string{"596"}, PIMPatient{AgeYears: intPtr(50)}, classesFor, NewDiseaseHierarchy())
+	assert.Empty(t, findings)
+	assert.Equal(t, []string{
+		"AGS Beers Criteria 2023 applies from age 65; patient is 50",
+		"STOPP/START v3 applies from age 65; patient is 50",
+	}, notes)
+}
+
+func TestScreenPIMCriteria_MissingAge(t *testing.T) {
+	versions := []pimCriteriaVersion{
+		{
+			Set: PIMCriteriaSet{SetCode: "BEERS", Version: "2023", Title: "AGS Beers Criteria 2023", MinAge: 65, IsCurrent: true},
+			Criteria: []PIMCriterion{
+				{SetCode: "BEERS", Version: "2023", CriterionCode: "BEERS-T2-BENZODIAZEPINE", CriterionType: PIMCriterionAvoid,
+					DrugDescription: "Benzodiazepines", ATCClasses: models.StringArray{"N05BA"}, Severity: models.SeverityMajor,
+					Rationale: "Falls and cognitive impairment", Recommendation: "Avoid."},
+			},
+		},
+		{Set: PIMCriteriaSet{SetCode: "STOPP", Version: "v3", Title: "STOPP/START v3", MinAge: 65, IsCurrent: true}},
+	}
+	classesFor := func(drugCode string) []string {
+		if normalizeATCDrugKey(drugCode) == "RXCUI:596" { // Alprazolam
+			return []string{"N", "N05", "N05B", "N05BA", "N05BA12"}
+		}
+		return nil
+	}
+
+	findings, notes := screenPIMCriteria(versions, []string{"596"}, PIMPatient{}, classesFor, NewDiseaseHierarchy())
+	assert.Empty(t, findings)
+	assert.Equal(t, []string{
+		"AGS Beers Criteria 2023 applies from age 65; patient age is missing, criteria not screened",
+		"STOPP/START v3 applies from age 65; patient age is missing, criteria not screened",
+	}, notes)
+
+	// An older adult age band stands in for the age
+	findings, notes = screenPIMCriteria(versions, []string{"596"}, PIMPatient{AgeBand: "older_adult"}, classesFor, NewDiseaseHierarchy())
+	require.Len(t, findings, 1)
+	assert.Empty(t, notes)
+}
+
+func TestPIMPatientFromContext(t *testing.T) {
+	patient := PIMPatientFromContext(&models.PatientContext{Age: 72, Comorbidities: []string{"I50.9"}})
+	assert.True(t, patient.IsOlderAdult())
+	assert.Equal(t, "older_adult", patient.AgeBand)
+	assert.Equal(t, []string{"I50.9"}, patient.Conditions)
+
+	patient = PIMPatientFromContextData(&models.PatientContextData{AgeBand: "older_adult"}, nil)
+	assert.True(t, patient.IsOlderAdult())
+
+	patient = PIMPatientFromContextData(&models.PatientContextData{
+		AgeBand: "older_adult",
+		Labs:    &models.PatientLabs{AgeYears: intPtr(40)},
+	}, &models.OrganFunctionAssessment{EGFR: &models.LabDerivation{Value: 45}})
+	assert.False(t, patient.IsOlderAdult())
+	require.NotNil(t, patient.Function.EGFR)
+	assert.Equal(t, 45.0, *patient.Function.EGFR)
+}
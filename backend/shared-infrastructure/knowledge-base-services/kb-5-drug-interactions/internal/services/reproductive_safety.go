@@ -0,0 +1,425 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kb-drug-interactions/internal/database"
+	"kb-drug-interactions/internal/metrics"
+	"kb-drug-interactions/internal/models"
+)
+
+// Reproductive safety contexts (reproductive_safety_rules.context)
+const (
+	ReproductiveContextPregnancy = "pregnancy"
+	ReproductiveContextLactation = "lactation"
+)
+
+// First completed gestational weeks of the second and third trimesters. Possible,
+// unrecognized pregnancy is screened against first-trimester risk only.
+const (
+	secondTrimesterStartWeek = 14
+	thirdTrimesterStartWeek  = 28
+)
+
+// ReproductiveSafetyRule gives a drug's risk in one gestational window or in lactation
+type ReproductiveSafetyRule struct {
+	ID                 int                  `json:"id" gorm:"primaryKey"`
+	DrugCode           string               `json:"drug_code"`
+	DrugName           string               `json:"drug_name"`
+	Context            string               `json:"context"`
+	WeekStart          int                  `json:"week_start"`         // Inclusive
+	WeekEnd            *int                 `json:"week_end,omitempty"` // Exclusive; nil = until delivery
+	InfantExposure     *string              `json:"infant_exposure,omitempty"`
+	RelativeInfantDose *float64             `json:"relative_infant_dose,omitempty"` // RID %
+	Severity           models.DDISeverity   `json:"severity"`
+	GovernanceAction   *string              `json:"governance_action,omitempty"`
+	RiskSummary        string               `json:"risk_summary"`
+	Recommendation     string               `json:"recommendation"`
+	Alternatives       models.StringArray   `json:"alternatives,omitempty" gorm:"type:text[]"`
+	Evidence           models.EvidenceLevel `json:"evidence"`
+	Source             string               `json:"source"`
+	Active             bool                 `json:"active"`
+}
+
+// TableName specifies the database table for GORM
+func (ReproductiveSafetyRule) TableName() string {
+	return "reproductive_safety_rules"
+}
+
+// ReproductiveInputError reports an invalid pregnancy or lactation status
+type ReproductiveInputError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ReproductiveInputError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// ReproductiveSafetyRequest checks a regimen against the patient's pregnancy and lactation status
+type ReproductiveSafetyRequest struct {
+	DrugCodes    []string                   `json:"drug_codes" binding:"required,min=1"`
+	Reproductive *models.ReproductiveStatus `json:"reproductive" binding:"required"`
+}
+
+// ReproductiveSafetyResponse is the outcome of a reproductive safety check
+type ReproductiveSafetyResponse struct {
+	Status    models.ReproductiveStatus          `json:"status"`
+	Trimester int                                `json:"trimester,omitempty"`
+	Findings  []models.EnhancedInteractionResult `json:"findings"`
+	Notes     []string                           `json:"notes,omitempty"`
+}
+
+// ReproductiveSafetyEngine checks drugs against trimester-specific pregnancy risk
+// and breast milk infant exposure
+type ReproductiveSafetyEngine struct {
+	db      *database.Database
+	metrics *metrics.Collector
+
+	// Rules keyed by normalized drug code
+	rules       map[string][]ReproductiveSafetyRule
+	rulesLoaded time.Time
+	cacheTTL    time.Duration
+	mu          sync.Mutex
+}
+
+// NewReproductiveSafetyEngine creates a new pregnancy and lactation safety engine
+func NewReproductiveSafetyEngine(db *database.Database, metrics *metrics.Collector) *ReproductiveSafetyEngine {
+	return &ReproductiveSafetyEngine{
+		db:       db,
+		metrics:  metrics,
+		cacheTTL: 30 * time.Minute,
+	}
+}
+
+// CheckRegimen validates the reproductive status and evaluates the regimen against it.
+// Invalid status is reported as *ReproductiveInputError.
+func (rse *ReproductiveSafetyEngine) CheckRegimen(ctx context.Context, request ReproductiveSafetyRequest) (*ReproductiveSafetyResponse, error) {
+	findings, notes, err := rse.EvaluateReproductiveSafety(ctx, request.DrugCodes, request.Reproductive)
+	if err != nil {
+		return nil, err
+	}
+
+	status := normalizeReproductiveStatus(request.Reproductive)
+	response := &ReproductiveSafetyResponse{
+		Status:   *status,
+		Findings: findings,
+		Notes:    notes,
+	}
+	if status.IsPregnant() && status.GestationalWeeks != nil {
+		response.Trimester = Trimester(*status.GestationalWeeks)
+	}
+	return response, nil
+}
+
+// EvaluateReproductiveSafety checks the regimen against pregnancy and lactation
+// rules. Findings carry a "governance_action" qualifier, when the rule sets one,
+// that the governance policy engine treats as the minimum action for the finding.
+func (rse *ReproductiveSafetyEngine) EvaluateReproductiveSafety(
+	ctx context.Context,
+	drugCodes []string,
+	status *models.ReproductiveStatus,
+) ([]models.EnhancedInteractionResult, []string, error) {
+	status, err := ResolveReproductiveStatus(status)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(drugCodes) == 0 || !reproductiveChecksApply(status) {
+		return []models.EnhancedInteractionResult{}, nil, nil
+	}
+
+	rules, err := rse.loadRules(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load reproductive safety rules: %w", err)
+	}
+
+	findings, notes := evaluateReproductiveRules(rules, drugCodes, status)
+	for _, f := range findings {
+		rse.metrics.RecordReproductiveSafetyMatch(f.Qualifiers["context"], f.Qualifiers["governance_action"])
+	}
+	return findings, notes, nil
+}
+
+// ResolveReproductiveStatus validates a reproductive status and returns a
+// normalized copy. Invalid status is reported as *ReproductiveInputError.
+func ResolveReproductiveStatus(status *models.ReproductiveStatus) (*models.ReproductiveStatus, error) {
+	if err := validateReproductiveStatus(status); err != nil {
+		return nil, err
+	}
+	return normalizeReproductiveStatus(status), nil
+}
+
+// validateReproductiveStatus checks the pregnancy status and week values
+func validateReproductiveStatus(status *models.ReproductiveStatus) error {
+	if status == nil {
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSpace(status.PregnancyStatus)) {
+	case "", models.PregnancyStatusPregnant, models.PregnancyStatusNotPregnant,
+		models.PregnancyStatusPossible, models.PregnancyStatusUnknown:
+	default:
+		return &ReproductiveInputError{Field: "pregnancy_status",
+			Reason: "must be pregnant, not_pregnant, possible or unknown"}
+	}
+	if weeks := status.GestationalWeeks; weeks != nil {
+		if *weeks < 0 || *weeks > 45 {
+			return &ReproductiveInputError{Field: "gestational_weeks", Reason: "must be between 0 and 45"}
+		}
+		if s := strings.ToLower(strings.TrimSpace(status.PregnancyStatus)); s != "" && s != models.PregnancyStatusPregnant {
+			return &ReproductiveInputError{Field: "gestational_weeks",
+				Reason: "requires pregnancy_status pregnant"}
+		}
+	}
+	if weeks := status.InfantAgeWeeks; weeks != nil && *weeks < 0 {
+		return &ReproductiveInputError{Field: "infant_age_weeks", Reason: "must not be negative"}
+	}
+	return nil
+}
+
+// normalizeReproductiveStatus returns a copy of the status in which a gestational
+// age without a pregnancy status implies pregnancy
+func normalizeReproductiveStatus(status *models.ReproductiveStatus) *models.ReproductiveStatus {
+	if status == nil {
+		return nil
+	}
+	normalized := *status
+	normalized.PregnancyStatus = strings.ToLower(strings.TrimSpace(normalized.PregnancyStatus))
+	if normalized.PregnancyStatus == "" && normalized.GestationalWeeks != nil {
+		normalized.PregnancyStatus = models.PregnancyStatusPregnant
+	}
+	return &normalized
+}
+
+// reproductiveChecksApply reports whether the status calls for any reproductive check
+func reproductiveChecksApply(status *models.ReproductiveStatus) bool {
+	if status == nil {
+		return false
+	}
+	return status.IsPregnant() || status.PregnancyStatus == models.PregnancyStatusPossible || status.IsLactating()
+}
+
+// Trimester returns the trimester for completed weeks of gestation
+func Trimester(weeks int) int {
+	switch {
+	case weeks < secondTrimesterStartWeek:
+		return 1
+	case weeks < thirdTrimesterStartWeek:
+		return 2
+	}
+	return 3
+}
+
+// evaluateReproductiveRules matches rules (keyed by normalized drug code) against
+// the patient's pregnancy and lactation status. Without a gestational age, and for
+// possible pregnancy, the highest-risk window per drug is reported (first trimester
+// only for possible pregnancy).
+func evaluateReproductiveRules(
+	rules map[string][]ReproductiveSafetyRule,
+	drugCodes []string,
+	status *models.ReproductiveStatus,
+) ([]models.EnhancedInteractionResult, []string) {
+	findings := []models.EnhancedInteractionResult{}
+	var notes []string
+	if status == nil {
+		return findings, notes
+	}
+
+	weekKnown := status.GestationalWeeks != nil
+	possible := status.PregnancyStatus == models.PregnancyStatusPossible
+	if status.IsPregnant() && !weekKnown {
+		notes = append(notes, "Gestational age unknown; highest-risk window applied for each drug")
+	}
+	if possible {
+		notes = append(notes, "Pregnancy not excluded; first-trimester risk applied")
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range drugCodes {
+		key := normalizeATCDrugKey(code)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		var worstPregnancy *ReproductiveSafetyRule
+		for i, rule := range rules[key] {
+			switch rule.Context {
+			case ReproductiveContextPregnancy:
+				if !status.IsPregnant() && !possible {
+					continue
+				}
+				if status.IsPregnant() && weekKnown {
+					if rule.coversWeek(*status.GestationalWeeks) {
+						findings = append(findings, pregnancyFinding(rule, code, status))
+					}
+					continue
+				}
+				if possible && rule.WeekStart >= secondTrimesterStartWeek {
+					continue
+				}
+				if worstPregnancy == nil || reproductiveRuleRank(rule) > reproductiveRuleRank(*worstPregnancy) {
+					worstPregnancy = &rules[key][i]
+				}
+			case ReproductiveContextLactation:
+				if status.IsLactating() {
+					findings = append(findings, lactationFinding(rule, code, status))
+				}
+			}
+		}
+		if worstPregnancy != nil {
+			findings = append(findings, pregnancyFinding(*worstPregnancy, code, status))
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return reproductiveFindingRank(findings[i]) > reproductiveFindingRank(findings[j])
+	})
+	return findings, notes
+}
+
+// coversWeek reports whether a pregnancy rule's window contains the gestational week
+func (r ReproductiveSafetyRule) coversWeek(weeks int) bool {
+	return weeks >= r.WeekStart && (r.WeekEnd == nil || weeks < *r.WeekEnd)
+}
+
+// window describes a pregnancy rule's gestational window, e.g. "20-30 weeks"
+func (r ReproductiveSafetyRule) window() string {
+	if r.WeekEnd == nil {
+		if r.WeekStart == 0 {
+			return "entire pregnancy"
+		}
+		return fmt.Sprintf("from %d weeks", r.WeekStart)
+	}
+	return fmt.Sprintf("%d-%d weeks", r.WeekStart, *r.WeekEnd)
+}
+
+// pregnancyFinding builds the interaction result for a matched pregnancy rule
+func pregnancyFinding(rule ReproductiveSafetyRule, drugCode string, status *models.ReproductiveStatus) models.EnhancedInteractionResult {
+	name := "Pregnancy (gestational age unknown)"
+	switch {
+	case status.PregnancyStatus == models.PregnancyStatusPossible:
+		name = "Possible pregnancy"
+	case status.GestationalWeeks != nil:
+		name = fmt.Sprintf("Pregnancy (week %d, trimester %d)", *status.GestationalWeeks, Trimester(*status.GestationalWeeks))
+	}
+
+	finding := reproductiveFinding(rule, drugCode, "PREGNANCY", name)
+	finding.InteractionID = fmt.Sprintf("REPRO_PREGNANCY_%s_W%d", normalizeATCDrugKey(rule.DrugCode), rule.WeekStart)
+	finding.Mechanism = models.MechanismPD
+	finding.Qualifiers["pregnancy_status"] = status.PregnancyStatus
+	finding.Qualifiers["risk_window"] = rule.window()
+	if status.GestationalWeeks != nil {
+		finding.Qualifiers["gestational_weeks"] = strconv.Itoa(*status.GestationalWeeks)
+		finding.Qualifiers["trimester"] = strconv.Itoa(Trimester(*status.GestationalWeeks))
+	}
+	return finding
+}
+
+// lactationFinding builds the interaction result for a matched lactation rule
+func lactationFinding(rule ReproductiveSafetyRule, drugCode string, status *models.ReproductiveStatus) models.EnhancedInteractionResult {
+	finding := reproductiveFinding(rule, drugCode, "LACTATION", "Breastfeeding")
+	finding.InteractionID = fmt.Sprintf("REPRO_LACTATION_%s", normalizeATCDrugKey(rule.DrugCode))
+	finding.Mechanism = models.MechanismPK // Transfer into breast milk
+	if rule.InfantExposure != nil {
+		finding.Qualifiers["infant_exposure"] = *rule.InfantExposure
+	}
+	if rule.RelativeInfantDose != nil {
+		finding.Qualifiers["relative_infant_dose_percent"] = strconv.FormatFloat(*rule.RelativeInfantDose, 'f', -1, 64)
+	}
+	if status.InfantAgeWeeks != nil {
+		finding.Qualifiers["infant_age_weeks"] = strconv.Itoa(*status.InfantAgeWeeks)
+	}
+	return finding
+}
+
+func reproductiveFinding(rule ReproductiveSafetyRule, drugCode, contextCode, contextName string) models.EnhancedInteractionResult {
+	finding := models.EnhancedInteractionResult{
+		Severity:           rule.Severity,
+		ClinicalEffects:    rule.RiskSummary,
+		ManagementStrategy: rule.Recommendation,
+		Evidence:           rule.Evidence,
+		Sources:            []string{rule.Source},
+		AlternativeDrugs:   []string(rule.Alternatives),
+		Drug1: models.DrugInfo{
+			Code: drugCode,
+			Name: rule.DrugName,
+		},
+		Drug2: models.DrugInfo{
+			Code: contextCode,
+			Name: contextName,
+		},
+		Qualifiers: map[string]string{
+			"type":    "reproductive_safety",
+			"context": rule.Context,
+		},
+	}
+	if rule.GovernanceAction != nil {
+		finding.Qualifiers["governance_action"] = *rule.GovernanceAction
+	}
+	return finding
+}
+
+// reproductiveRuleRank orders rules by governance floor, then severity
+func reproductiveRuleRank(rule ReproductiveSafetyRule) int {
+	rank := rule.Severity.GetPriority()
+	if rule.GovernanceAction != nil {
+		rank += 10 * models.GovernanceAction(*rule.GovernanceAction).Priority()
+	}
+	return rank
+}
+
+func reproductiveFindingRank(finding models.EnhancedInteractionResult) int {
+	return 10*models.GovernanceAction(finding.Qualifiers["governance_action"]).Priority() +
+		finding.Severity.GetPriority()
+}
+
+// GetDrugRules returns the active pregnancy and lactation rules for a drug
+func (rse *ReproductiveSafetyEngine) GetDrugRules(ctx context.Context, drugCode string) ([]ReproductiveSafetyRule, error) {
+	rules, err := rse.loadRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reproductive safety rules: %w", err)
+	}
+	drugRules := rules[normalizeATCDrugKey(drugCode)]
+	if drugRules == nil {
+		drugRules = []ReproductiveSafetyRule{}
+	}
+	return drugRules, nil
+}
+
+// loadRules returns active rules keyed by normalized drug code, pregnancy
+// windows in gestational order
+func (rse *ReproductiveSafetyEngine) loadRules(ctx context.Context) (map[string][]ReproductiveSafetyRule, error) {
+	rse.mu.Lock()
+	defer rse.mu.Unlock()
+
+	if rse.rules != nil && time.Since(rse.rulesLoaded) < rse.cacheTTL {
+		return rse.rules, nil
+	}
+
+	var rows []ReproductiveSafetyRule
+	err := rse.db.DB.WithContext(ctx).
+		Where("active = TRUE").
+		Order("drug_code, context DESC, week_start").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make(map[string][]ReproductiveSafetyRule)
+	for _, row := range rows {
+		row.Context = strings.ToLower(row.Context)
+		key := normalizeATCDrugKey(row.DrugCode)
+		rules[key] = append(rules[key], row)
+	}
+
+	rse.rules = rules
+	rse.rulesLoaded = time.Now()
+
+	return rules, nil
+}
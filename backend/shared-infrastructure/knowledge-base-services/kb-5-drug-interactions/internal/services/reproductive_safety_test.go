@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kb-drug-interactions/internal/models"
+)
+
+// ============================================================================
+// PREGNANCY AND LACTATION SAFETY TESTS
+// ============================================================================
+
+func TestTrimester(t *testing.T) {
+	assert.Equal(t, 1, Trimester(0))
+	assert.Equal(t, 1, Trimester(13))
+	assert.Equal(t, 2, Trimester(14))
+	assert.Equal(t, 2, Trimester(27))
+	assert.Equal(t, 3, Trimester(28))
+}
+
+func TestEvaluateReproductiveRules_GestationalWindows(t *testing.T) {
+	escalate, overrideBlock := stringPtr("mandatory_escalation"), stringPtr("hard_block_governance_override")
+	rules := map[string][]ReproductiveSafetyRule{
+		"RXCUI:5640": {
+			{DrugCode: "RxCUI:5640", DrugName: "Ibuprofen", Context: ReproductiveContextPregnancy, WeekStart: 20, WeekEnd: intPtr(30),
+				Severity: models.SeverityMajor, GovernanceAction: escalate, RiskSummary: "Oligohydramnios",
+				Recommendation: "Avoid NSAIDs from 20 weeks.", Alternatives: models.StringArray{"Acetaminophen"}},
+			{DrugCode: "RxCUI:5640", DrugName: "Ibuprofen", Context: ReproductiveContextPregnancy, WeekStart: 30,
+				Severity: models.SeverityContraindicated, GovernanceAction: overrideBlock, RiskSummary: "Ductus arteriosus closure",
+				Recommendation: "Do not use NSAIDs from 30 weeks.", Alternatives: models.StringArray{"Acetaminophen"}},
+		},
+		"RXCUI:11289": {
+			{DrugCode: "RxCUI:11289", DrugName: "Warfarin", Context: ReproductiveContextPregnancy, WeekStart: 0, WeekEnd: intPtr(6),
+				Severity: models.SeverityMajor, GovernanceAction: escalate, RiskSummary: "Embryopathy if continued",
+				Recommendation: "Switch to LMWH before 6 weeks.", Alternatives: models.StringArray{"Enoxaparin"}},
+			{DrugCode: "RxCUI:11289", DrugName: "Warfarin", Context: ReproductiveContextPregnancy, WeekStart: 6, WeekEnd: intPtr(13),
+				Severity: models.SeverityContraindicated, GovernanceAction: overrideBlock, RiskSummary: "Warfarin embryopathy",
+				Recommendation: "Do not use in the first trimester.", Alternatives: models.StringArray{"Enoxaparin"}},
+			{DrugCode: "RxCUI:11289", DrugName: "Warfarin", Context: ReproductiveContextPregnancy, WeekStart: 13,
+				Severity: models.SeverityMajor, GovernanceAction: escalate, RiskSummary: "Fetal hemorrhage",
+				Recommendation: "Specialist care only.", Alternatives: models.StringArray{"Enoxaparin"}},
+		},
+	}
+	status := &models.ReproductiveStatus{PregnancyStatus: models.PregnancyStatusPregnant, GestationalWeeks: intPtr(24)}
+	findings, notes := evaluateReproductiveRules(rules, []string{"5640", "RxCUI:11289"}, status)
+
+	require.Len(t, findings, 2)
+	assert.Empty(t, notes)
+	nsaid := findings[0]
+	assert.Equal(t, "RxCUI:11289", findings[1].Drug1.Code)
+	assert.Equal(t, "Ibuprofen", nsaid.Drug1.Name)
+	assert.Equal(t, "PREGNANCY", nsaid.Drug2.Code)
+	assert.Equal(t, "Pregnancy (week 24, trimester 2)", nsaid.Drug2.Name)
+	assert.Equal(t, models.SeverityMajor, nsaid.Severity)
+	assert.Equal(t, "mandatory_escalation", nsaid.Qualifiers["governance_action"])
+	assert.Equal(t, "20-30 weeks", nsaid.Qualifiers["risk_window"])
+	assert.Equal(t, "2", nsaid.Qualifiers["trimester"])
+	assert.Equal(t, []string{"Acetaminophen"}, nsaid.AlternativeDrugs)
+
+	// Warfarin in weeks 6-12 is a hard stop; ibuprofen has no first-trimester rule
+	status.GestationalWeeks = intPtr(8)
+	findings, _ = evaluateReproductiveRules(rules, []string{"5640", "11289"}, status)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "REPRO_PREGNANCY_RXCUI:11289_W6", findings[0].InteractionID)
+	assert.Equal(t, models.SeverityContraindicated, findings[0].Severity)
+	assert.Equal(t, "hard_block_governance_override", findings[0].Qualifiers["governance_action"])
+
+	// Window end is exclusive
+	status.GestationalWeeks = intPtr(30)
+	findings, _ = evaluateReproductiveRules(rules, []string{"5640"}, status)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "from 30 weeks", findings[0].Qualifiers["risk_window"])
+}
+
+func TestEvaluateReproductiveRules_UnknownAndPossiblePregnancy(t *testing.T) {
+	escalate, overrideBlock := stringPtr("mandatory_escalation"), stringPtr("hard_block_governance_override")
+	rules := map[string][]ReproductiveSafetyRule{
+		"RXCUI:5640": {
+			{DrugCode: "RxCUI:5640", DrugName: "Ibuprofen", Context: ReproductiveContextPregnancy, WeekStart: 20, WeekEnd: intPtr(30),
+				Severity: models.SeverityMajor, GovernanceAction: escalate, RiskSummary: "Oligohydramnios",
+				Recommendation: "Avoid NSAIDs from 20 weeks.", Alternatives: models.StringArray{"Acetaminophen"}},
+			{DrugCode: "RxCUI:5640", DrugName: "Ibuprofen", Context: ReproductiveContextPregnancy, WeekStart: 30,
+				Severity: models.SeverityContraindicated, GovernanceAction: overrideBlock, RiskSummary: "Ductus arteriosus closure",
+				Recommendation: "Do not use NSAIDs from 30 weeks.", Alternatives: models.StringArray{"Acetaminophen"}},
+		},
+		"RXCUI:11289": {
+			{DrugCode: "RxCUI:11289", DrugName: "Warfarin", Context: ReproductiveContextPregnancy, WeekStart: 0, WeekEnd: intPtr(6),
+				Severity: models.SeverityMajor, GovernanceAction: escalate, RiskSummary: "Embryopathy if continued",
+				Recommendation: "Switch to LMWH before 6 weeks.", Alternatives: models.StringArray{"Enoxaparin"}},
+			{DrugCode: "RxCUI:11289", DrugName: "Warfarin", Context: ReproductiveContextPregnancy, WeekStart: 6, WeekEnd: intPtr(13),
+				Severity: models.SeverityContraindicated, GovernanceAction: overrideBlock, RiskSummary: "Warfarin embryopathy",
+				Recommendation: "Do not use in the first trimester.", Alternatives: models.StringArray{"Enoxaparin"}},
+			{DrugCode: "RxCUI:11289", DrugName: "Warfarin", Context: ReproductiveContextPregnancy, WeekStart: 13,
+				Severity: models.SeverityMajor, GovernanceAction: escalate, RiskSummary: "Fetal hemorrhage",
+				Recommendation: "Specialist care only.", Alternatives: models.StringArray{"Enoxaparin"}},
+		},
+	}
+	status := &models.ReproductiveStatus{PregnancyStatus: models.PregnancyStatusPregnant}
+	findings, notes := evaluateReproductiveRules(rules, []string{"5640", "11289"}, status)
+	require.Len(t, findings, 2) // Highest-risk window per drug
+	assert.Equal(t, models.SeverityContraindicated, findings[0].Severity)
+	assert.Equal(t, models.SeverityContraindicated, findings[1].Severity)
+	assert.Equal(t, "Pregnancy (gestational age unknown)", findings[0].Drug2.Name)
+	assert.Equal(t, []string{"Gestational age unknown; highest-risk window applied for each drug"}, notes)
+
+	status = &models.ReproductiveStatus{PregnancyStatus: models.PregnancyStatusPossible}
+	findings, notes = evaluateReproductiveRules(rules, []string{"5640", "11289"}, status)
+	require.Len(t, findings, 1) // NSAID risk starts at 20 weeks
+	assert.Equal(t, "Warfarin", findings[0].Drug1.Name)
+	assert.Equal(t, "6-13 weeks", findings[0].Qualifiers["risk_window"])
+	assert.Equal(t, "Possible pregnancy", findings[0].Drug2.Name)
+	assert.Equal(t, []string{"Pregnancy not excluded; first-trimester risk applied"}, notes)
+
+	// Week 13 is still the first trimester
+	lateFirstTrimester := map[string][]ReproductiveSafetyRule{"RXCUI:11289": {rules["RXCUI:11289"][2]}}
+	findings, _ = evaluateReproductiveRules(lateFirstTrimester, []string{"11289"}, status)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "from 13 weeks", findings[0].Qualifiers["risk_window"])
+
+	findings, _ = evaluateReproductiveRules(rules, []string{"5640", "11289"},
+		&models.ReproductiveStatus{PregnancyStatus: models.PregnancyStatusNotPregnant})
+	assert.Empty(t, findings)
+}
+
+func TestEvaluateReproductiveRules_Lactation(t *testing.T) {
+	rules := map[string][]ReproductiveSafetyRule{
+		"RXCUI:5640": {
+			{DrugCode: "RxCUI:5640", DrugName: "Ibuprofen", Context: ReproductiveContextLactation, InfantExposure: stringPtr("minimal"),
+				RelativeInfantDose: floatPtr(0.6), Severity: models.SeverityMinor, RiskSummary: "Very low milk transfer",
+				Recommendation: "Compatible with breastfeeding."},
+		},
+		"RXCUI:2670": {
+			{DrugCode: "RxCUI:2670", DrugName: "Codeine", Context: ReproductiveContextLactation, InfantExposure: stringPtr("high"),
+				RelativeInfantDose: floatPtr(8.1), Severity: models.SeverityContraindicated, GovernanceAction: stringPtr("hard_block_governance_override"),
+				RiskSummary: "Infant morphine toxicity", Recommendation: "Do not use while breastfeeding.",
+				Alternatives: models.StringArray{"Acetaminophen", "Ibuprofen"}},
+		},
+		"RXCUI:11289": {
+			{DrugCode: "RxCUI:11289", DrugName: "Warfarin", Context: ReproductiveContextPregnancy, WeekStart: 13,
+				Severity: models.SeverityMajor, RiskSummary: "Fetal hemorrhage", Recommendation: "Specialist care only."},
+		},
+	}
+	status := &models.ReproductiveStatus{Lactating: true, InfantAgeWeeks: intPtr(6)}
+	findings, _ := evaluateReproductiveRules(rules, []string{"5640", "2670", "11289"}, status)
+
+	require.Len(t, findings, 2)
+	codeine := findings[0]
+	assert.Equal(t, "LACTATION", codeine.Drug2.Code)
+	assert.Equal(t, "high", codeine.Qualifiers["infant_exposure"])
+	assert.Equal(t, "8.1", codeine.Qualifiers["relative_infant_dose_percent"])
+	assert.Equal(t, "6", codeine.Qualifiers["infant_age_weeks"])
+	assert.Equal(t, []string{"Acetaminophen", "Ibuprofen"}, codeine.AlternativeDrugs)
+	assert.Equal(t, models.MechanismPK, codeine.Mechanism)
+
+	ibuprofen := findings[1]
+	assert.Equal(t, models.SeverityMinor, ibuprofen.Severity)
+	assert.Equal(t, "minimal", ibuprofen.Qualifiers["infant_exposure"])
+	assert.NotContains(t, ibuprofen.Qualifiers, "governance_action")
+}
+
+func TestResolveReproductiveStatus(t *testing.T) {
+	status, err := ResolveReproductiveStatus(&models.ReproductiveStatus{GestationalWeeks: intPtr(22)})
+	require.NoError(t, err)
+	assert.True(t, status.IsPregnant())
+
+	status, err = ResolveReproductiveStatus(nil)
+	assert.NoError(t, err)
+	assert.False(t, status.IsPregnant())
+	assert.False(t, status.IsLactating())
+
+	var inputErr *ReproductiveInputError
+	_, err = ResolveReproductiveStatus(&models.ReproductiveStatus{PregnancyStatus: "expecting"})
+	require.True(t, errors.As(err, &inputErr))
+	assert.Equal(t, "pregnancy_status", inputErr.Field)
+
+	_, err = ResolveReproductiveStatus(&models.ReproductiveStatus{PregnancyStatus: "not_pregnant", GestationalWeeks: intPtr(10)})
+	require.True(t, errors.As(err, &inputErr))
+	assert.Equal(t, "gestational_weeks", inputErr.Field)
+
+	_, err = ResolveReproductiveStatus(&models.ReproductiveStatus{PregnancyStatus: "pregnant", GestationalWeeks: intPtr(50)})
+	require.True(t, errors.As(err, &inputErr))
+}
+
+func TestGovernance_ReproductiveFlagsAndFloor(t *testing.T) {
+	engine := &GovernancePolicyEngine{}
+	patientContext := &models.PatientContextData{
+		Reproductive: &models.ReproductiveStatus{PregnancyStatus: models.PregnancyStatusPregnant, Lactating: true},
+	}
+	flags := engine.detectProgramFlags(models.EnhancedInteractionResult{Severity: models.SeverityMinor}, patientContext)
+	assert.Contains(t, flags, models.ProgramFlagPregnancy)
+	assert.Contains(t, flags, models.ProgramFlagLactation)
+
+	flags = engine.detectProgramFlags(models.EnhancedInteractionResult{Severity: models.SeverityMinor}, &models.PatientContextData{})
+	assert.NotContains(t, flags, models.ProgramFlagPregnancy)
+}
@@ -0,0 +1,421 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kb-drug-interactions/internal/database"
+	"kb-drug-interactions/internal/metrics"
+)
+
+// IWPCAlgorithm identifies the warfarin dosing algorithm
+const IWPCAlgorithm = "IWPC pharmacogenetic (NEJM 2009;360:753-64)"
+
+// IWPC covariate flags (warfarin_dose_modifiers.iwpc_covariate)
+const (
+	IWPCCovariateAmiodarone    = "amiodarone"
+	IWPCCovariateEnzymeInducer = "enzyme_inducer"
+)
+
+// iwpcCYP2C9Coefficients are the IWPC terms for CYP2C9 genotype (*1/*1 = 0)
+var iwpcCYP2C9Coefficients = map[string]float64{
+	"*1/*1":   0,
+	"*1/*2":   -0.5211,
+	"*1/*3":   -0.9357,
+	"*2/*2":   -1.0616,
+	"*2/*3":   -1.9206,
+	"*3/*3":   -2.3312,
+	"unknown": -0.2188,
+}
+
+// iwpcVKORC1Coefficients are the IWPC terms for VKORC1 -1639G>A genotype (G/G = 0)
+var iwpcVKORC1Coefficients = map[string]float64{
+	"G/G":     0,
+	"A/G":     -0.8677,
+	"A/A":     -1.6974,
+	"unknown": -0.4854,
+}
+
+// iwpcRaceCoefficients are the IWPC terms for race (White = 0)
+var iwpcRaceCoefficients = map[string]float64{
+	"white":            0,
+	"asian":            -0.1092,
+	"black":            -0.2760,
+	"missing_or_mixed": -0.1032,
+}
+
+// DosingInputError reports a dosing request field that cannot be used
+type DosingInputError struct {
+	Field  string
+	Reason string
+}
+
+func (e *DosingInputError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// WarfarinDoseModifier is a co-medication that changes the warfarin dose requirement
+type WarfarinDoseModifier struct {
+	ID                   int     `json:"id" gorm:"primaryKey"`
+	DrugCode             string  `json:"drug_code"`
+	DrugName             string  `json:"drug_name"`
+	InteractionID        *string `json:"interaction_id,omitempty"`
+	IWPCCovariate        *string `json:"iwpc_covariate,omitempty" gorm:"column:iwpc_covariate"`
+	AdjustmentMinPercent float64 `json:"adjustment_min_percent"`
+	AdjustmentMaxPercent float64 `json:"adjustment_max_percent"`
+	Recommendation       string  `json:"recommendation"`
+	Active               bool    `json:"active"`
+}
+
+// TableName specifies the table name for GORM
+func (WarfarinDoseModifier) TableName() string {
+	return "warfarin_dose_modifiers"
+}
+
+// WarfarinDosingRequest holds the patient inputs to the IWPC algorithm
+type WarfarinDosingRequest struct {
+	AgeYears       int      `json:"age_years"`
+	HeightCm       float64  `json:"height_cm"`
+	WeightKg       float64  `json:"weight_kg"`
+	Race           string   `json:"race,omitempty"`            // white, asian, black, other/unknown
+	CYP2C9Genotype string   `json:"cyp2c9_genotype,omitempty"` // e.g. "*1/*3"; empty = not genotyped
+	VKORC1Genotype string   `json:"vkorc1_genotype,omitempty"` // -1639G>A: "G/G", "A/G", "A/A"; empty = not genotyped
+	DrugCodes      []string `json:"drug_codes,omitempty"`      // Current regimen
+	Amiodarone     *bool    `json:"amiodarone,omitempty"`      // Overrides detection from drug_codes
+	EnzymeInducer  *bool    `json:"enzyme_inducer,omitempty"`  // Overrides detection from drug_codes
+}
+
+// IWPCCovariates are the normalized algorithm inputs
+type IWPCCovariates struct {
+	AgeDecades    int     `json:"age_decades"`
+	HeightCm      float64 `json:"height_cm"`
+	WeightKg      float64 `json:"weight_kg"`
+	Race          string  `json:"race"`
+	CYP2C9        string  `json:"cyp2c9"`
+	VKORC1        string  `json:"vkorc1"`
+	Amiodarone    bool    `json:"amiodarone"`
+	EnzymeInducer bool    `json:"enzyme_inducer"`
+}
+
+// WarfarinDoseAdjustment is the effect of one regimen drug on the dose estimate
+type WarfarinDoseAdjustment struct {
+	DrugCode            string  `json:"drug_code"`
+	DrugName            string  `json:"drug_name"`
+	InteractionID       string  `json:"interaction_id,omitempty"`
+	MinPercent          float64 `json:"min_percent"`
+	MaxPercent          float64 `json:"max_percent"`
+	AppliedPercent      float64 `json:"applied_percent"`
+	IncludedInAlgorithm bool    `json:"included_in_algorithm"` // IWPC covariate; not applied again
+	Recommendation      string  `json:"recommendation"`
+}
+
+// WarfarinDoseEstimate is the genotype-guided dose and its co-medication adjustments
+type WarfarinDoseEstimate struct {
+	Algorithm            string                   `json:"algorithm"`
+	Covariates           IWPCCovariates           `json:"covariates"`
+	BaseWeeklyDoseMg     float64                  `json:"base_weekly_dose_mg"`
+	BaseDailyDoseMg      float64                  `json:"base_daily_dose_mg"`
+	AdjustedWeeklyDoseMg float64                  `json:"adjusted_weekly_dose_mg"`
+	AdjustedDailyDoseMg  float64                  `json:"adjusted_daily_dose_mg"`
+	AdjustedWeeklyMinMg  float64                  `json:"adjusted_weekly_min_mg"`
+	AdjustedWeeklyMaxMg  float64                  `json:"adjusted_weekly_max_mg"`
+	Adjustments          []WarfarinDoseAdjustment `json:"adjustments"`
+	Notes                []string                 `json:"notes,omitempty"`
+}
+
+// WarfarinDosingEngine estimates genotype-guided warfarin maintenance doses
+type WarfarinDosingEngine struct {
+	db      *database.Database
+	metrics *metrics.Collector
+
+	// Dose modifiers keyed by normalized drug code
+	modifiers       map[string]WarfarinDoseModifier
+	modifiersLoaded time.Time
+	cacheTTL        time.Duration
+	mu              sync.Mutex
+}
+
+// NewWarfarinDosingEngine creates a new warfarin dosing engine
+func NewWarfarinDosingEngine(db *database.Database, metrics *metrics.Collector) *WarfarinDosingEngine {
+	return &WarfarinDosingEngine{
+		db:       db,
+		metrics:  metrics,
+		cacheTTL: 30 * time.Minute,
+	}
+}
+
+// EstimateDose applies the IWPC algorithm to the patient inputs, then adjusts the
+// estimate for interacting drugs in the regimen. Invalid inputs are reported as
+// *DosingInputError.
+func (wde *WarfarinDosingEngine) EstimateDose(ctx context.Context, request WarfarinDosingRequest) (*WarfarinDoseEstimate, error) {
+	modifiers, err := wde.loadModifiers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load warfarin dose modifiers: %w", err)
+	}
+
+	estimate, err := estimateWarfarinDose(request, modifiers)
+	if err != nil {
+		return nil, err
+	}
+
+	wde.metrics.RecordDoseEstimate("warfarin_iwpc")
+	return estimate, nil
+}
+
+// estimateWarfarinDose runs the algorithm against modifiers keyed by normalized drug code
+func estimateWarfarinDose(request WarfarinDosingRequest, modifiers map[string]WarfarinDoseModifier) (*WarfarinDoseEstimate, error) {
+	covariates, notes, err := buildIWPCCovariates(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Regimen drugs that are IWPC covariates set the flags unless given explicitly
+	var regimen []WarfarinDoseModifier
+	seen := make(map[string]bool)
+	for _, code := range request.DrugCodes {
+		key := normalizeATCDrugKey(code)
+		modifier, ok := modifiers[key]
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		regimen = append(regimen, modifier)
+		if modifier.IWPCCovariate == nil {
+			continue
+		}
+		switch *modifier.IWPCCovariate {
+		case IWPCCovariateAmiodarone:
+			if request.Amiodarone == nil {
+				covariates.Amiodarone = true
+			}
+		case IWPCCovariateEnzymeInducer:
+			if request.EnzymeInducer == nil {
+				covariates.EnzymeInducer = true
+			}
+		}
+	}
+
+	base := IWPCWeeklyDose(covariates)
+	estimate := &WarfarinDoseEstimate{
+		Algorithm:        IWPCAlgorithm,
+		Covariates:       covariates,
+		BaseWeeklyDoseMg: roundDose(base),
+		BaseDailyDoseMg:  roundDose(base / 7),
+		Adjustments:      []WarfarinDoseAdjustment{},
+		Notes:            notes,
+	}
+
+	adjusted, minDose, maxDose := base, base, base
+	for _, m := range regimen {
+		adjustment := WarfarinDoseAdjustment{
+			DrugCode:       m.DrugCode,
+			DrugName:       m.DrugName,
+			MinPercent:     m.AdjustmentMinPercent,
+			MaxPercent:     m.AdjustmentMaxPercent,
+			Recommendation: m.Recommendation,
+		}
+		if m.InteractionID != nil {
+			adjustment.InteractionID = *m.InteractionID
+		}
+
+		if covariateApplied(m, covariates) {
+			adjustment.IncludedInAlgorithm = true
+		} else {
+			adjustment.AppliedPercent = (m.AdjustmentMinPercent + m.AdjustmentMaxPercent) / 2
+			adjusted *= 1 + adjustment.AppliedPercent/100
+			minDose *= 1 + m.AdjustmentMinPercent/100
+			maxDose *= 1 + m.AdjustmentMaxPercent/100
+		}
+		estimate.Adjustments = append(estimate.Adjustments, adjustment)
+	}
+
+	sort.SliceStable(estimate.Adjustments, func(i, j int) bool {
+		return math.Abs(estimate.Adjustments[i].AppliedPercent) > math.Abs(estimate.Adjustments[j].AppliedPercent)
+	})
+
+	estimate.AdjustedWeeklyDoseMg = roundDose(adjusted)
+	estimate.AdjustedDailyDoseMg = roundDose(adjusted / 7)
+	estimate.AdjustedWeeklyMinMg = roundDose(minDose)
+	estimate.AdjustedWeeklyMaxMg = roundDose(maxDose)
+	estimate.Notes = append(estimate.Notes,
+		"Estimated maintenance dose; titrate to INR. Co-medication adjustments use the midpoint of each interaction's recommended range.")
+
+	return estimate, nil
+}
+
+// covariateApplied reports whether a modifier's effect is already in the IWPC estimate
+func covariateApplied(m WarfarinDoseModifier, covariates IWPCCovariates) bool {
+	if m.IWPCCovariate == nil {
+		return false
+	}
+	switch *m.IWPCCovariate {
+	case IWPCCovariateAmiodarone:
+		return covariates.Amiodarone
+	case IWPCCovariateEnzymeInducer:
+		return covariates.EnzymeInducer
+	}
+	return false
+}
+
+// IWPCWeeklyDose returns the IWPC pharmacogenetic weekly warfarin dose in mg
+func IWPCWeeklyDose(c IWPCCovariates) float64 {
+	sqrtDose := 5.6044 -
+		0.2614*float64(c.AgeDecades) +
+		0.0087*c.HeightCm +
+		0.0128*c.WeightKg +
+		iwpcVKORC1Coefficients[c.VKORC1] +
+		iwpcCYP2C9Coefficients[c.CYP2C9] +
+		iwpcRaceCoefficients[c.Race]
+	if c.EnzymeInducer {
+		sqrtDose += 1.1816
+	}
+	if c.Amiodarone {
+		sqrtDose -= 0.5503
+	}
+	if sqrtDose < 0 {
+		return 0
+	}
+	return sqrtDose * sqrtDose
+}
+
+// buildIWPCCovariates validates and normalizes the request inputs
+func buildIWPCCovariates(request WarfarinDosingRequest) (IWPCCovariates, []string, error) {
+	var notes []string
+
+	if request.AgeYears < 18 || request.AgeYears > 110 {
+		return IWPCCovariates{}, nil, &DosingInputError{Field: "age_years", Reason: "IWPC algorithm is validated for adults 18-110 years"}
+	}
+	if request.HeightCm < 100 || request.HeightCm > 250 {
+		return IWPCCovariates{}, nil, &DosingInputError{Field: "height_cm", Reason: "must be between 100 and 250 cm"}
+	}
+	if request.WeightKg < 30 || request.WeightKg > 300 {
+		return IWPCCovariates{}, nil, &DosingInputError{Field: "weight_kg", Reason: "must be between 30 and 300 kg"}
+	}
+
+	cyp2c9, note, err := normalizeIWPCCYP2C9(request.CYP2C9Genotype)
+	if err != nil {
+		return IWPCCovariates{}, nil, err
+	}
+	if note != "" {
+		notes = append(notes, note)
+	}
+
+	vkorc1, err := normalizeIWPCVKORC1(request.VKORC1Genotype)
+	if err != nil {
+		return IWPCCovariates{}, nil, err
+	}
+	if vkorc1 == "unknown" {
+		notes = append(notes, "VKORC1 not genotyped; IWPC unknown-genotype term used")
+	}
+
+	covariates := IWPCCovariates{
+		AgeDecades: request.AgeYears / 10,
+		HeightCm:   request.HeightCm,
+		WeightKg:   request.WeightKg,
+		Race:       normalizeIWPCRace(request.Race),
+		CYP2C9:     cyp2c9,
+		VKORC1:     vkorc1,
+	}
+	if request.Amiodarone != nil {
+		covariates.Amiodarone = *request.Amiodarone
+	}
+	if request.EnzymeInducer != nil {
+		covariates.EnzymeInducer = *request.EnzymeInducer
+	}
+	return covariates, notes, nil
+}
+
+// normalizeIWPCCYP2C9 maps a CYP2C9 diplotype to an IWPC genotype term. Alleles
+// outside *1/*2/*3 are not in the algorithm and use the unknown term.
+func normalizeIWPCCYP2C9(diplotype string) (string, string, error) {
+	value := strings.TrimSpace(diplotype)
+	if value == "" || strings.EqualFold(value, "unknown") {
+		return "unknown", "CYP2C9 not genotyped; IWPC unknown-genotype term used", nil
+	}
+
+	calls, err := parseDiplotype("CYP2C9", value)
+	if err != nil {
+		return "", "", &DosingInputError{Field: "cyp2c9_genotype", Reason: err.Error()}
+	}
+	if len(calls) != 2 || calls[0].Copies != 1 || calls[1].Copies != 1 {
+		return "", "", &DosingInputError{Field: "cyp2c9_genotype", Reason: "expected two alleles, e.g. *1/*3"}
+	}
+
+	alleles := []string{calls[0].Allele, calls[1].Allele}
+	sort.Strings(alleles)
+	genotype := alleles[0] + "/" + alleles[1]
+	if _, ok := iwpcCYP2C9Coefficients[genotype]; !ok {
+		return "unknown", fmt.Sprintf("CYP2C9 %s is not covered by the IWPC algorithm (*1, *2, *3 only); unknown-genotype term used", genotype), nil
+	}
+	return genotype, "", nil
+}
+
+// normalizeIWPCVKORC1 maps a VKORC1 -1639G>A genotype ("GA", "A/G", "g/g") to an IWPC term
+func normalizeIWPCVKORC1(genotype string) (string, error) {
+	value := strings.ToUpper(strings.TrimSpace(genotype))
+	if value == "" || value == "UNKNOWN" {
+		return "unknown", nil
+	}
+	value = strings.NewReplacer("/", "", " ", "", "-1639", "", "G>A", "", ":", "").Replace(value)
+	switch value {
+	case "GG":
+		return "G/G", nil
+	case "AG", "GA":
+		return "A/G", nil
+	case "AA":
+		return "A/A", nil
+	}
+	return "", &DosingInputError{Field: "vkorc1_genotype", Reason: fmt.Sprintf("%q is not a -1639G>A genotype (G/G, A/G or A/A)", genotype)}
+}
+
+// normalizeIWPCRace maps a reported race to an IWPC race term
+func normalizeIWPCRace(race string) string {
+	r := strings.ToLower(strings.TrimSpace(race))
+	switch {
+	case r == "white", r == "caucasian":
+		return "white"
+	case r == "asian":
+		return "asian"
+	case r == "black", strings.Contains(r, "african"):
+		return "black"
+	}
+	return "missing_or_mixed"
+}
+
+// roundDose rounds a dose to 0.1 mg
+func roundDose(mg float64) float64 {
+	return math.Round(mg*10) / 10
+}
+
+// loadModifiers returns active dose modifiers keyed by normalized drug code
+func (wde *WarfarinDosingEngine) loadModifiers(ctx context.Context) (map[string]WarfarinDoseModifier, error) {
+	wde.mu.Lock()
+	defer wde.mu.Unlock()
+
+	if wde.modifiers != nil && time.Since(wde.modifiersLoaded) < wde.cacheTTL {
+		return wde.modifiers, nil
+	}
+
+	var rows []WarfarinDoseModifier
+	err := wde.db.DB.WithContext(ctx).
+		Where("active = TRUE").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	modifiers := make(map[string]WarfarinDoseModifier, len(rows))
+	for _, row := range rows {
+		modifiers[normalizeATCDrugKey(row.DrugCode)] = row
+	}
+
+	wde.modifiers = modifiers
+	wde.modifiersLoaded = time.Now()
+
+	return modifiers, nil
+}
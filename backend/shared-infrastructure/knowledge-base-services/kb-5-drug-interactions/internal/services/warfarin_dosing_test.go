@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ============================================================================
+// WARFARIN DOSING TESTS
+// ============================================================================
+
+func TestIWPCWeeklyDose(t *testing.T) {
+	covariates := IWPCCovariates{AgeDecades: 6, HeightCm: 170, WeightKg: 80, Race: "white", CYP2C9: "*1/*1", VKORC1: "G/G"}
+	// sqrt dose = 5.6044 - 1.5684 + 1.479 + 1.024 = 6.539
+	assert.InDelta(t, 42.76, IWPCWeeklyDose(covariates), 0.01)
+
+	covariates.CYP2C9 = "*3/*3"
+	covariates.VKORC1 = "A/A"
+	assert.InDelta(t, 6.30, IWPCWeeklyDose(covariates), 0.01, "poor metabolizer with sensitive VKORC1 needs far less")
+
+	covariates.CYP2C9 = "*1/*1"
+	covariates.VKORC1 = "G/G"
+	covariates.EnzymeInducer = true
+	assert.Greater(t, IWPCWeeklyDose(covariates), 42.76)
+}
+
+func TestEstimateWarfarinDose_Baseline(t *testing.T) {
+	request := WarfarinDosingRequest{
+		AgeYears:       65,
+		HeightCm:       170,
+		WeightKg:       80,
+		Race:           "White",
+		CYP2C9Genotype: "*1/*1",
+		VKORC1Genotype: "G/G",
+	}
+
+	estimate, err := estimateWarfarinDose(request, map[string]WarfarinDoseModifier{})
+	require.NoError(t, err)
+
+	assert.Equal(t, IWPCAlgorithm, estimate.Algorithm)
+	assert.Equal(t, 6, estimate.Covariates.AgeDecades)
+	assert.Equal(t, 42.8, estimate.BaseWeeklyDoseMg)
+	assert.Equal(t, 6.1, estimate.BaseDailyDoseMg)
+	assert.Equal(t, estimate.BaseWeeklyDoseMg, estimate.AdjustedWeeklyDoseMg)
+	assert.Empty(t, estimate.Adjustments)
+}
+
+func TestEstimateWarfarinDose_InhibitorAdjustment(t *testing.T) {
+	modifiers := map[string]WarfarinDoseModifier{
+		"RXCUI:4450": {DrugCode: "RxCUI:4450", DrugName: "Fluconazole", InteractionID: stringPtr("WARFARIN_FLUCONAZOLE_001"),
+			AdjustmentMinPercent: -50, AdjustmentMaxPercent: -25},
+		"RXCUI:2551": {DrugCode: "RxCUI:2551", DrugName: "Ciprofloxacin", AdjustmentMinPercent: -20, AdjustmentMaxPercent: -10},
+	}
+	request := WarfarinDosingRequest{
+		AgeYears:       65,
+		HeightCm:       170,
+		WeightKg:       80,
+		Race:           "White",
+		CYP2C9Genotype: "*1/*1",
+		VKORC1Genotype: "G/G",
+		DrugCodes:      []string{"4450", "RxCUI:4450", "RxCUI:99999"},
+	}
+
+	estimate, err := estimateWarfarinDose(request, modifiers)
+	require.NoError(t, err)
+
+	require.Len(t, estimate.Adjustments, 1, "duplicate codes count once; unknown drugs are ignored")
+	adj := estimate.Adjustments[0]
+	assert.Equal(t, "Fluconazole", adj.DrugName)
+	assert.Equal(t, "WARFARIN_FLUCONAZOLE_001", adj.InteractionID)
+	assert.Equal(t, -37.5, adj.AppliedPercent)
+	assert.False(t, adj.IncludedInAlgorithm)
+
+	assert.Equal(t, 26.7, estimate.AdjustedWeeklyDoseMg)
+	assert.Equal(t, 21.4, estimate.AdjustedWeeklyMinMg)
+	assert.Equal(t, 32.1, estimate.AdjustedWeeklyMaxMg)
+}
+
+func TestEstimateWarfarinDose_CovariateNotAppliedTwice(t *testing.T) {
+	modifiers := map[string]WarfarinDoseModifier{
+		"RXCUI:703": {DrugCode: "RxCUI:703", DrugName: "Amiodarone", InteractionID: stringPtr("WARFARIN_AMIODARONE_001"),
+			IWPCCovariate: stringPtr(IWPCCovariateAmiodarone), AdjustmentMinPercent: -50, AdjustmentMaxPercent: -30},
+		"RXCUI:9384": {DrugCode: "RxCUI:9384", DrugName: "Rifampin", IWPCCovariate: stringPtr(IWPCCovariateEnzymeInducer),
+			AdjustmentMinPercent: 100, AdjustmentMaxPercent: 200},
+	}
+	request := WarfarinDosingRequest{
+		AgeYears:       65,
+		HeightCm:       170,
+		WeightKg:       80,
+		Race:           "White",
+		CYP2C9Genotype: "*1/*1",
+		VKORC1Genotype: "G/G",
+		DrugCodes:      []string{"RxCUI:703"},
+	}
+
+	estimate, err := estimateWarfarinDose(request, modifiers)
+	require.NoError(t, err)
+
+	assert.True(t, estimate.Covariates.Amiodarone, "amiodarone in the regimen sets the IWPC covariate")
+	assert.Equal(t, 35.9, estimate.BaseWeeklyDoseMg)
+	assert.Equal(t, estimate.BaseWeeklyDoseMg, estimate.AdjustedWeeklyDoseMg)
+	require.Len(t, estimate.Adjustments, 1)
+	assert.True(t, estimate.Adjustments[0].IncludedInAlgorithm)
+	assert.Zero(t, estimate.Adjustments[0].AppliedPercent)
+
+	// An explicit false keeps it out of the algorithm, so the interaction range applies instead
+	request.Amiodarone = boolPtr(false)
+	estimate, err = estimateWarfarinDose(request, modifiers)
+	require.NoError(t, err)
+	assert.False(t, estimate.Covariates.Amiodarone)
+	assert.Equal(t, 42.8, estimate.BaseWeeklyDoseMg)
+	assert.Equal(t, 25.7, estimate.AdjustedWeeklyDoseMg)
+	assert.False(t, estimate.Adjustments[0].IncludedInAlgorithm)
+}
+
+func TestEstimateWarfarinDose_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*WarfarinDosingRequest)
+		field  string
+	}{
+		{"pediatric", func(r *WarfarinDosingRequest) { r.AgeYears = 12 }, "age_years"},
+		{"missing height", func(r *WarfarinDosingRequest) { r.HeightCm = 0 }, "height_cm"},
+		{"implausible weight", func(r *WarfarinDosingRequest) { r.WeightKg = 500 }, "weight_kg"},
+		{"bad CYP2C9", func(r *WarfarinDosingRequest) { r.CYP2C9Genotype = "*1" }, "cyp2c9_genotype"},
+		{"bad VKORC1", func(r *WarfarinDosingRequest) { r.VKORC1Genotype = "C/T" }, "vkorc1_genotype"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := WarfarinDosingRequest{
+				AgeYears:       65,
+				HeightCm:       170,
+				WeightKg:       80,
+				Race:           "White",
+				CYP2C9Genotype: "*1/*1",
+				VKORC1Genotype: "G/G",
+			}
+			tt.modify(&request)
+
+			_, err := estimateWarfarinDose(request, map[string]WarfarinDoseModifier{})
+			var inputErr *DosingInputError
+			require.True(t, errors.As(err, &inputErr), "expected DosingInputError, got %v", err)
+			assert.Equal(t, tt.field, inputErr.Field)
+		})
+	}
+}
+
+func TestNormalizeIWPCGenotypes(t *testing.T) {
+	genotype, note, err := normalizeIWPCCYP2C9("*3/*1")
+	require.NoError(t, err)
+	assert.Equal(t, "*1/*3", genotype)
+	assert.Empty(t, note)
+
+	genotype, note, err = normalizeIWPCCYP2C9("*1/*8")
+	require.NoError(t, err)
+	assert.Equal(t, "unknown", genotype, "alleles outside the algorithm use the unknown term")
+	assert.NotEmpty(t, note)
+
+	genotype, _, err = normalizeIWPCCYP2C9("")
+	require.NoError(t, err)
+	assert.Equal(t, "unknown", genotype)
+
+	for input, expected := range map[string]string{"GA": "A/G", "a/g": "A/G", "-1639 G/G": "G/G", "AA": "A/A", "": "unknown"} {
+		got, err := normalizeIWPCVKORC1(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, got, input)
+	}
+
+	assert.Equal(t, "black", normalizeIWPCRace("Black or African American"))
+	assert.Equal(t, "missing_or_mixed", normalizeIWPCRace(""))
+}
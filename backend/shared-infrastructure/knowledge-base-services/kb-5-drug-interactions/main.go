@@ -64,6 +64,28 @@ func main() {
 	
 	// Initialize cache manager
 	cacheManager := models.NewCacheManager(cacheClient)
+
+	// Shared OHDSI vocabulary database (canonical_facts) - optional
+	logger.Info("Connecting to shared vocabulary database...")
+	var vocabularyDB *database.Database
+	sharedDB, sharedDBErr := database.NewSharedConnection(cfg)
+	if sharedDBErr == nil {
+		defer sharedDB.Close()
+		vocabularyDB = sharedDB
+	}
+
+	// ATC class index (drug -> ATC classes at all five levels, from OHDSI vocabulary)
+	atcIndex := services.NewATCClassIndex(vocabularyDB, metricsCollector)
+	if vocabularyDB != nil {
+		if stats, err := atcIndex.Reload(context.Background()); err != nil {
+			logger.Warn("ATC class index not loaded - using built-in ATC class map",
+				zap.Error(err))
+		} else {
+			logger.Info("ATC class index loaded",
+				zap.Int("atc_classes", stats.ATCClasses),
+				zap.Int("mapped_drugs", stats.MappedDrugs))
+		}
+	}
 	
 	// Initialize enhanced engines
 	logger.Info("Initializing enhanced interaction engines...")
@@ -72,11 +94,22 @@ func main() {
 	matrixService := services.NewEnhancedInteractionMatrixService(
 		db, cacheClient, cfg, metricsCollector)
 
+	// Pharmacogenomic genotype -> phenotype translation (versioned CPIC tables)
+	genotypeTranslator := services.NewGenotypeTranslator(db, metricsCollector)
+	if stats, err := genotypeTranslator.Reload(context.Background()); err != nil {
+		logger.Warn("PGx translation tables not loaded - genotype submission unavailable", zap.Error(err))
+	} else {
+		logger.Info("PGx translation tables loaded",
+			zap.String("table_version", stats.TableVersion),
+			zap.Int("genes", stats.Genes),
+			zap.Int("alleles", stats.Alleles))
+	}
+
 	// Pharmacogenomic interaction engine
-	pgxEngine := services.NewPharmacogenomicEngine(db, metricsCollector)
+	pgxEngine := services.NewPharmacogenomicEngine(db, genotypeTranslator, metricsCollector)
 
 	// Drug class interaction engine
-	classEngine := services.NewClassInteractionEngine(db, metricsCollector)
+	classEngine := services.NewClassInteractionEngine(db, atcIndex, metricsCollector, logger)
 
 	// Food/alcohol/herbal modifier engine (requires *sql.DB)
 	sqlDB, err := db.DB.DB()
@@ -89,41 +122,74 @@ func main() {
 	hotCacheService := services.NewHotCacheService(
 		hotCacheClient, warmCacheClient, logger, cfg)
 	
-	// Enhanced integration service (orchestrates all engines)
-	integrationService := services.NewEnhancedIntegrationService(
-		pgxEngine, classEngine, modifierEngine, matrixService, logger, cfg)
-
+	// Renal/hepatic dose adjustment engine
+	organDosingEngine := services.NewOrganDoseAdjustmentEngine(db, metricsCollector)
+	
+	// Cumulative daily dose engine (ingredient totals across combination products)
+	cumulativeDoseEngine := services.NewCumulativeDoseEngine(db, metricsCollector)
+	
+	// Pregnancy and lactation safety engine
+	reproductiveEngine := services.NewReproductiveSafetyEngine(db, metricsCollector)
+	
+	// Older adult potentially inappropriate medication screening (Beers/STOPP)
+	pimEngine := services.NewPIMScreeningEngine(db, atcIndex, metricsCollector)
+	
+	// Pediatric age- and weight-based safety engine
+	pediatricEngine := services.NewPediatricSafetyEngine(db, atcIndex, metricsCollector)
+	
+	// Administration-timing separation scheduler (chelation, food, sequestrants)
+	scheduleEngine := services.NewAdministrationScheduleEngine(db, atcIndex, metricsCollector)
+	
 	// Phase 3 engines: Drug-Disease, Allergy, Duplicate Therapy
 	logger.Info("Initializing Phase 3 clinical safety engines...")
 
 	// Drug-disease contraindication engine
 	drugDiseaseEngine := services.NewDrugDiseaseEngine(db, metricsCollector)
+	if cfg.DiseaseVocabularyPath != "" {
+		if stats, err := drugDiseaseEngine.LoadDiseaseVocabulary(cfg.DiseaseVocabularyPath); err != nil {
+			logger.Warn("Disease vocabulary not loaded - using ICD-10 prefix matching only",
+				zap.String("path", cfg.DiseaseVocabularyPath), zap.Error(err))
+		} else {
+			logger.Info("Disease vocabulary loaded",
+				zap.Int("is_a_relationships", stats.IsARelationships),
+				zap.Int("crosswalk_mappings", stats.CrosswalkMappings))
+		}
+	}
+
+	// Enhanced integration service (orchestrates all engines)
+	integrationService := services.NewEnhancedIntegrationService(
+		pgxEngine, classEngine, modifierEngine, matrixService, organDosingEngine, cumulativeDoseEngine, reproductiveEngine, pimEngine, pediatricEngine, drugDiseaseEngine, logger, cfg)
 
 	// Allergy cross-reactivity engine
 	allergyEngine := services.NewAllergyEngine(db, metricsCollector)
 
 	// Duplicate therapy detection engine
-	duplicateTherapyEngine := services.NewDuplicateTherapyEngine(db, metricsCollector)
+	duplicateTherapyEngine := services.NewDuplicateTherapyEngine(db, atcIndex, metricsCollector)
 
 	// Phase 4: Governance Policy Engine (Severity → Governance + Attribution)
 	logger.Info("Initializing Phase 4 governance and attribution engine...")
 	governanceEngine := services.NewGovernancePolicyEngine(db.DB, logger, metricsCollector)
+	overrideEngine := services.NewGovernanceOverrideEngine(db.DB, logger, metricsCollector)
 
 	// Phase 5: OHDSI Constitutional DDI Service (connects to shared database)
 	logger.Info("Initializing OHDSI Constitutional DDI Service...")
 	var ohdsiEnabled bool
-	sharedDB, sharedDBErr := database.NewSharedConnection(cfg)
 	if sharedDBErr != nil {
 		logger.Warn("Shared database not available - OHDSI Constitutional DDI disabled",
 			zap.Error(sharedDBErr))
 		ohdsiEnabled = false
 	} else {
-		defer sharedDB.Close()
 		_ = services.NewOHDSIExpansionService(sharedDB, metricsCollector)
 		ohdsiEnabled = true
 		logger.Info("OHDSI Constitutional DDI Service initialized (25 ONC rules)")
 	}
 
+	// Drug name search (synonyms, brand names, OHDSI concept names)
+	drugSearchService := services.NewDrugSearchService(db, vocabularyDB, cfg, metricsCollector, logger)
+
+	// Genotype-guided warfarin dosing (IWPC)
+	warfarinDosingEngine := services.NewWarfarinDosingEngine(db, metricsCollector)
+
 	// Legacy interaction service (for backward compatibility)
 	interactionService := services.NewInteractionService(
 		db,
@@ -153,6 +219,22 @@ func main() {
 		duplicateTherapyEngine,
 		// Phase 4: Governance
 		governanceEngine,
+		// Terminology
+		drugSearchService,
+		// Dosing
+		warfarinDosingEngine,
+		organDosingEngine,
+		cumulativeDoseEngine,
+		// Pregnancy and lactation safety
+		reproductiveEngine,
+		// Older adult PIM screening
+		pimEngine,
+		// Pediatric safety
+		pediatricEngine,
+		// Administration scheduling
+		scheduleEngine,
+		// Governance override requests
+		overrideEngine,
 	)
 	
 	// Start HTTP server with enhanced engines
@@ -247,6 +329,8 @@ Enhanced HTTP REST Endpoints:
 - Batch Check: POST /api/v1/interactions/batch-check
 - Quick Check: GET /api/v1/interactions/quick-check
 - Comprehensive Check: POST /api/v1/interactions/comprehensive
+- Drug Name Search: GET /api/v1/drugs/search?q=
+- Resolve Drug Names: POST /api/v1/drugs/resolve
 
 Enhanced Features Now Available:
 ✅ Pharmacogenomic (PGx) interaction detection
@@ -265,13 +349,42 @@ Enhanced Features Now Available:
 Phase 3 Endpoints:
 - Drug-Disease: POST /api/v1/contraindications/disease
 - Allergy Check: POST /api/v1/allergy/check
+- Product Excipients: GET /api/v1/allergy/products/:product_code/excipients
 - Duplicate Therapy: POST /api/v1/duplicates/check
+- Genotype Translation: POST /api/v1/cyp/genotype/translate
+- PGx Guidelines: GET /api/v1/cyp/guidelines
+- PGx Safety Check: POST /api/v1/cyp/safety/check
+- Warfarin Dosing: POST /api/v1/dosing/warfarin
+- Organ Function (eGFR/CrCl/Child-Pugh): POST /api/v1/dosing/organ-function
+- Renal/Hepatic Dose Adjustment: POST /api/v1/dosing/organ-adjustment
+- Drug Dose Adjustments: GET /api/v1/dosing/organ-adjustment/:drug_code
+- Cumulative Daily Dose: POST /api/v1/dosing/cumulative
+- Ingredient Dose Limits: GET /api/v1/dosing/cumulative/:ingredient_code
+- Pregnancy/Lactation Safety: POST /api/v1/reproductive/check
+- Drug Reproductive Safety Rules: GET /api/v1/reproductive/drug/:drug_code
+- Older Adult PIM Screening (Beers/STOPP): POST /api/v1/geriatric/pim-screen
+- PIM Criteria Sets: GET /api/v1/geriatric/criteria
+- PIM Criteria: GET /api/v1/geriatric/criteria/:set_code
+- Pediatric Safety (age/weight): POST /api/v1/pediatric/check
+- Drug Pediatric Rules: GET /api/v1/pediatric/drug/:drug_code
+- Administration Schedule (dose separation): POST /api/v1/administration/schedule
+- Drug Separation Rules: GET /api/v1/administration/separations/:drug_code
+- Governance Policy Versions: GET/POST /api/v1/governance/policies
+- Governance Policy Draft: GET/PUT/DELETE /api/v1/governance/policies/:policy_id
+- Approve Governance Policy: POST /api/v1/governance/policies/:policy_id/approve
+- Governance Override Requests: GET/POST /api/v1/governance/overrides
+- Override Request: GET /api/v1/governance/overrides/:override_id
+- Decide Override Request: POST /api/v1/governance/overrides/:override_id/approve|deny
 
 Admin Endpoints:
 - Engine Health: GET /api/v1/admin/engines/health
 - Cache Statistics: GET /api/v1/admin/cache/stats
 - Performance Metrics: GET /api/v1/admin/performance
 - Dataset Management: POST /api/v1/admin/dataset/update
+- Vocabulary Reload: POST /api/v1/admin/vocabulary/reload
+- PGx Translation Reload: POST /api/v1/admin/pgx/translation/reload
+- PGx Guideline Import: POST /api/v1/admin/pgx/guidelines/import
+- Excipient Product Import: POST /api/v1/admin/excipients/import
 
 Note: gRPC endpoints available after protoc compilation
 ========================================
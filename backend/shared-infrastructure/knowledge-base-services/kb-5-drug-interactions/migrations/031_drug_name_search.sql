@@ -0,0 +1,22 @@
+-- =============================================================================
+-- Migration 031: Fuzzy Drug Name Search
+-- =============================================================================
+-- Trigram indexes backing GET /api/v1/drugs/search. Free-text medication
+-- names (admission home-medication lists, typed orders) are ranked against
+-- drug_synonyms (generic + brand names) and OHDSI concept names.
+-- =============================================================================
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Synonym and brand names (drug_synonyms.synonym_type IN ('brand','generic',...))
+CREATE INDEX IF NOT EXISTS idx_drug_synonyms_name_trgm
+    ON drug_synonyms USING GIN (LOWER(synonym_name) gin_trgm_ops)
+    WHERE active = TRUE;
+
+-- OHDSI drug concept names (ingredients, brand names)
+CREATE INDEX IF NOT EXISTS idx_ohdsi_concept_name_trgm
+    ON ohdsi_concept USING GIN (LOWER(concept_name) gin_trgm_ops)
+    WHERE domain_id = 'Drug' AND invalid_reason IS NULL;
+
+COMMENT ON INDEX idx_drug_synonyms_name_trgm IS 'Trigram index for fuzzy drug name search and autocomplete';
+COMMENT ON INDEX idx_ohdsi_concept_name_trgm IS 'Trigram index for fuzzy OHDSI drug concept name search';
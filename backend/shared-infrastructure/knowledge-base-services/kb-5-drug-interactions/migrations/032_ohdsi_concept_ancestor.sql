@@ -0,0 +1,155 @@
+-- =============================================================================
+-- Migration 032: OHDSI Concept Ancestor Closure
+-- =============================================================================
+-- Class expansion previously walked 'Drug has drug class' edges per request,
+-- which misses drugs attached to sub-classes and makes first-hit latency
+-- depend on hierarchy depth (MED-RT / VA Class).
+--
+-- ohdsi_concept_ancestor holds the transitive closure of the class hierarchy:
+-- - Imported from OHDSI CONCEPT_ANCESTOR (filtered by cmd/ohdsi-loader), or
+-- - Computed by rebuild_ohdsi_concept_ancestor() from 'Is a', 'Subsumes'
+--   and 'Drug has drug class' relationships
+--
+-- Class membership is then a single indexed lookup on ancestor_concept_id.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS ohdsi_concept_ancestor (
+    ancestor_concept_id BIGINT NOT NULL,
+    descendant_concept_id BIGINT NOT NULL,
+    min_levels_of_separation INTEGER NOT NULL,
+    max_levels_of_separation INTEGER NOT NULL,
+
+    PRIMARY KEY (ancestor_concept_id, descendant_concept_id)
+);
+
+-- Descendant -> ancestors lookup (drug -> all classes)
+CREATE INDEX IF NOT EXISTS idx_oca_descendant ON ohdsi_concept_ancestor(descendant_concept_id);
+
+-- =============================================================================
+-- 1. Closure Computation (when CONCEPT_ANCESTOR is not imported)
+-- =============================================================================
+
+CREATE OR REPLACE FUNCTION rebuild_ohdsi_concept_ancestor()
+RETURNS BIGINT AS $$
+DECLARE
+    inserted BIGINT;
+BEGIN
+    TRUNCATE TABLE ohdsi_concept_ancestor;
+
+    INSERT INTO ohdsi_concept_ancestor (
+        ancestor_concept_id, descendant_concept_id,
+        min_levels_of_separation, max_levels_of_separation
+    )
+    WITH RECURSIVE edges (child_id, parent_id) AS (
+        SELECT concept_id_1, concept_id_2
+        FROM ohdsi_concept_relationship
+        WHERE relationship_id IN ('Is a', 'Drug has drug class')
+          AND invalid_reason IS NULL
+        UNION
+        SELECT concept_id_2, concept_id_1
+        FROM ohdsi_concept_relationship
+        WHERE relationship_id = 'Subsumes'
+          AND invalid_reason IS NULL
+    ),
+    closure (ancestor_id, descendant_id, depth) AS (
+        SELECT parent_id, child_id, 1 FROM edges
+        UNION
+        SELECT e.parent_id, c.descendant_id, c.depth + 1
+        FROM closure c
+        JOIN edges e ON e.child_id = c.ancestor_id
+        WHERE c.depth < 20  -- Guard against cycles in malformed vocabularies
+    )
+    SELECT ancestor_id, descendant_id, MIN(depth), MAX(depth)
+    FROM closure
+    WHERE ancestor_id <> descendant_id
+    GROUP BY ancestor_id, descendant_id
+    UNION ALL
+    -- Self rows (level 0), as in OHDSI CONCEPT_ANCESTOR
+    SELECT n.node_id, n.node_id, 0, 0
+    FROM (SELECT parent_id AS node_id FROM edges UNION SELECT child_id FROM edges) n;
+
+    GET DIAGNOSTICS inserted = ROW_COUNT;
+    RETURN inserted;
+END;
+$$ LANGUAGE plpgsql;
+
+-- =============================================================================
+-- 2. Class Expansion Views (closure-based)
+-- =============================================================================
+
+CREATE OR REPLACE VIEW v_active_ddi_definitions AS
+SELECT
+    r.rule_id,
+    r.description AS alert_message,
+    r.risk_level,
+
+    -- TRIGGER SIDE (e.g., Warfarin)
+    r.trigger_class_name,
+    c1.concept_name AS trigger_drug_name,
+    c1.concept_id AS trigger_drug_id,
+
+    -- TARGET SIDE (e.g., Ibuprofen)
+    r.target_class_name,
+    c2.concept_name AS target_drug_name,
+    c2.concept_id AS target_drug_id,
+
+    -- CONTEXT (LOINC reference)
+    r.context_loinc_id,
+    r.context_loinc_name,
+    r.context_threshold_val,
+    r.context_logic_operator,
+    r.context_required,
+
+    -- GOVERNANCE
+    r.rule_authority,
+    r.rule_version
+
+FROM ddi_constitutional_rules r
+
+-- Expand Trigger Class to all descendant drugs
+JOIN ohdsi_concept_ancestor ca1
+    ON ca1.ancestor_concept_id = r.trigger_concept_id
+JOIN ohdsi_concept c1
+    ON ca1.descendant_concept_id = c1.concept_id
+    AND c1.standard_concept = 'S'
+
+-- Expand Target Class to all descendant drugs
+JOIN ohdsi_concept_ancestor ca2
+    ON ca2.ancestor_concept_id = r.target_concept_id
+JOIN ohdsi_concept c2
+    ON ca2.descendant_concept_id = c2.concept_id
+    AND c2.standard_concept = 'S'
+
+WHERE r.active = TRUE
+    AND r.frozen = TRUE  -- Only constitutional rules
+    AND c1.concept_id != c2.concept_id;
+
+CREATE OR REPLACE VIEW v_ddi_expansion_stats AS
+SELECT
+    r.rule_id,
+    r.trigger_class_name,
+    r.target_class_name,
+    r.risk_level,
+    r.rule_authority,
+    COUNT(DISTINCT ca1.descendant_concept_id) AS trigger_drug_count,
+    COUNT(DISTINCT ca2.descendant_concept_id) AS target_drug_count,
+    COUNT(*) AS total_pairs_covered
+FROM ddi_constitutional_rules r
+LEFT JOIN ohdsi_concept_ancestor ca1
+    ON ca1.ancestor_concept_id = r.trigger_concept_id
+    AND ca1.min_levels_of_separation > 0
+LEFT JOIN ohdsi_concept_ancestor ca2
+    ON ca2.ancestor_concept_id = r.target_concept_id
+    AND ca2.min_levels_of_separation > 0
+WHERE r.active = TRUE
+GROUP BY r.rule_id, r.trigger_class_name, r.target_class_name, r.risk_level, r.rule_authority
+ORDER BY total_pairs_covered DESC;
+
+-- =============================================================================
+-- 3. Initial Population (from relationships already loaded)
+-- =============================================================================
+
+SELECT rebuild_ohdsi_concept_ancestor();
+
+COMMENT ON TABLE ohdsi_concept_ancestor IS 'Transitive closure of OHDSI class hierarchy (imported CONCEPT_ANCESTOR or computed from Is a/Subsumes/Drug has drug class).';
+COMMENT ON FUNCTION rebuild_ohdsi_concept_ancestor IS 'Recomputes ohdsi_concept_ancestor from ohdsi_concept_relationship. Returns rows inserted.';
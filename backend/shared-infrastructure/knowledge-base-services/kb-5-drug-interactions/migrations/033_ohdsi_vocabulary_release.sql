@@ -0,0 +1,36 @@
+-- =============================================================================
+-- Migration 033: OHDSI Vocabulary Release Tracking
+-- =============================================================================
+-- cmd/ohdsi-loader -load records every vocabulary release it loads, together
+-- with the delta against the previously loaded release and the impact report
+-- for active constitutional rules. The latest row is the release in service.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS ohdsi_vocabulary_release (
+    release_id SERIAL PRIMARY KEY,
+    vocabulary_version VARCHAR(100) NOT NULL,
+    previous_version VARCHAR(100),
+    source_path TEXT,
+
+    -- Loaded row counts
+    concept_count BIGINT NOT NULL DEFAULT 0,
+    relationship_count BIGINT NOT NULL DEFAULT 0,
+    ancestor_count BIGINT NOT NULL DEFAULT 0,
+    ancestor_source VARCHAR(20) NOT NULL DEFAULT 'computed'
+        CHECK (ancestor_source IN ('imported', 'computed')),
+
+    -- Delta against previous release
+    concepts_added BIGINT NOT NULL DEFAULT 0,
+    concepts_deprecated BIGINT NOT NULL DEFAULT 0,
+    concepts_remapped BIGINT NOT NULL DEFAULT 0,
+    affected_rule_count INTEGER NOT NULL DEFAULT 0,
+
+    -- Full validation report (JSON, as written by the loader)
+    report JSONB,
+
+    loaded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ovr_loaded_at ON ohdsi_vocabulary_release(loaded_at DESC);
+
+COMMENT ON TABLE ohdsi_vocabulary_release IS 'OHDSI vocabulary releases loaded by cmd/ohdsi-loader, with delta and constitutional rule impact report.';
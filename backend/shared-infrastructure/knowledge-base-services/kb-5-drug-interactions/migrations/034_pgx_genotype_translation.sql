@@ -0,0 +1,165 @@
+-- =============================================================================
+-- Migration 034: Pharmacogenomic Genotype-to-Phenotype Translation Tables
+-- =============================================================================
+-- Lab reports give diplotypes (CYP2D6 *1/*4, CYP2C19 *2/*17), not phenotypes.
+-- These versioned tables translate a diplotype into the phenotype labels used by
+-- ddi_pharmacogenomic_rules:
+--   * pgx_allele_functions   - star allele -> function status (+ activity value)
+--   * pgx_phenotype_rules    - activity score range or function pair -> phenotype
+--
+-- Activity-score genes (CYP2D6, CYP2C9) sum allele activity values, including
+-- gene duplications (*1x2) and deletions (*5). Function-pair genes (CYP2C19,
+-- SLCO1B1, CYP3A5) look up the unordered pair of allele function statuses.
+--
+-- Exactly one table version is current; the service echoes it with every
+-- translated phenotype so results can be reproduced after a table update.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS pgx_translation_versions (
+    table_version VARCHAR(50) PRIMARY KEY,
+    source VARCHAR(200) NOT NULL,
+    source_url TEXT,
+    is_current BOOLEAN NOT NULL DEFAULT FALSE,
+    notes TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pgx_translation_current
+    ON pgx_translation_versions(is_current) WHERE is_current;
+
+CREATE TABLE IF NOT EXISTS pgx_allele_functions (
+    table_version VARCHAR(50) NOT NULL REFERENCES pgx_translation_versions(table_version),
+    gene VARCHAR(20) NOT NULL,
+    allele VARCHAR(30) NOT NULL,
+    function_status VARCHAR(30) NOT NULL
+        CHECK (function_status IN ('normal', 'decreased', 'no', 'increased', 'uncertain')),
+    activity_value NUMERIC(4,2),  -- Activity-score genes only
+    PRIMARY KEY (table_version, gene, allele)
+);
+
+CREATE TABLE IF NOT EXISTS pgx_phenotype_rules (
+    id SERIAL PRIMARY KEY,
+    table_version VARCHAR(50) NOT NULL REFERENCES pgx_translation_versions(table_version),
+    gene VARCHAR(20) NOT NULL,
+    method VARCHAR(20) NOT NULL CHECK (method IN ('activity_score', 'function_pair')),
+    -- activity_score: inclusive range, NULL = unbounded
+    min_activity_score NUMERIC(4,2),
+    max_activity_score NUMERIC(4,2),
+    -- function_pair: unordered pair of allele function statuses
+    function_1 VARCHAR(30),
+    function_2 VARCHAR(30),
+    phenotype VARCHAR(30) NOT NULL,
+    CHECK (method <> 'function_pair' OR (function_1 IS NOT NULL AND function_2 IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_pgx_phenotype_rules_gene ON pgx_phenotype_rules(table_version, gene);
+
+COMMENT ON TABLE pgx_allele_functions IS 'Star allele function status and activity value per translation table version.';
+COMMENT ON TABLE pgx_phenotype_rules IS 'Activity score ranges and allele function pairs mapped to ddi_pharmacogenomic_rules phenotype labels.';
+
+-- =============================================================================
+-- Seed: CPIC allele functionality and diplotype-phenotype assignments
+-- =============================================================================
+
+INSERT INTO pgx_translation_versions (table_version, source, source_url, is_current, notes) VALUES
+('CPIC-2024.1', 'CPIC allele functionality and diplotype-to-phenotype tables',
+ 'https://cpicpgx.org/guidelines/', TRUE,
+ 'CYP2D6 activity score per 2019 consensus (*10 = 0.25). Phenotypes use ddi_pharmacogenomic_rules labels.')
+ON CONFLICT (table_version) DO NOTHING;
+
+INSERT INTO pgx_allele_functions (table_version, gene, allele, function_status, activity_value) VALUES
+-- CYP2D6
+('CPIC-2024.1', 'CYP2D6', '*1',  'normal',    1.00),
+('CPIC-2024.1', 'CYP2D6', '*2',  'normal',    1.00),
+('CPIC-2024.1', 'CYP2D6', '*35', 'normal',    1.00),
+('CPIC-2024.1', 'CYP2D6', '*9',  'decreased', 0.50),
+('CPIC-2024.1', 'CYP2D6', '*10', 'decreased', 0.25),
+('CPIC-2024.1', 'CYP2D6', '*14', 'decreased', 0.50),
+('CPIC-2024.1', 'CYP2D6', '*17', 'decreased', 0.50),
+('CPIC-2024.1', 'CYP2D6', '*29', 'decreased', 0.50),
+('CPIC-2024.1', 'CYP2D6', '*41', 'decreased', 0.50),
+('CPIC-2024.1', 'CYP2D6', '*3',  'no',        0.00),
+('CPIC-2024.1', 'CYP2D6', '*4',  'no',        0.00),
+('CPIC-2024.1', 'CYP2D6', '*5',  'no',        0.00),  -- Gene deletion
+('CPIC-2024.1', 'CYP2D6', '*6',  'no',        0.00),
+('CPIC-2024.1', 'CYP2D6', '*7',  'no',        0.00),
+('CPIC-2024.1', 'CYP2D6', '*8',  'no',        0.00),
+('CPIC-2024.1', 'CYP2D6', '*11', 'no',        0.00),
+('CPIC-2024.1', 'CYP2D6', '*15', 'no',        0.00),
+('CPIC-2024.1', 'CYP2D6', '*36', 'no',        0.00),
+('CPIC-2024.1', 'CYP2D6', '*40', 'no',        0.00),
+('CPIC-2024.1', 'CYP2D6', '*42', 'no',        0.00),
+-- CYP2C9
+('CPIC-2024.1', 'CYP2C9', '*1',  'normal',    1.00),
+('CPIC-2024.1', 'CYP2C9', '*2',  'decreased', 0.50),
+('CPIC-2024.1', 'CYP2C9', '*5',  'decreased', 0.50),
+('CPIC-2024.1', 'CYP2C9', '*8',  'decreased', 0.50),
+('CPIC-2024.1', 'CYP2C9', '*11', 'decreased', 0.50),
+('CPIC-2024.1', 'CYP2C9', '*3',  'no',        0.00),
+('CPIC-2024.1', 'CYP2C9', '*6',  'no',        0.00),
+('CPIC-2024.1', 'CYP2C9', '*13', 'no',        0.00),
+-- CYP2C19
+('CPIC-2024.1', 'CYP2C19', '*1',  'normal',    NULL),
+('CPIC-2024.1', 'CYP2C19', '*9',  'decreased', NULL),
+('CPIC-2024.1', 'CYP2C19', '*2',  'no',        NULL),
+('CPIC-2024.1', 'CYP2C19', '*3',  'no',        NULL),
+('CPIC-2024.1', 'CYP2C19', '*4',  'no',        NULL),
+('CPIC-2024.1', 'CYP2C19', '*8',  'no',        NULL),
+('CPIC-2024.1', 'CYP2C19', '*17', 'increased', NULL),
+-- SLCO1B1
+('CPIC-2024.1', 'SLCO1B1', '*1',  'normal',    NULL),
+('CPIC-2024.1', 'SLCO1B1', '*37', 'normal',    NULL),
+('CPIC-2024.1', 'SLCO1B1', '*14', 'increased', NULL),
+('CPIC-2024.1', 'SLCO1B1', '*20', 'increased', NULL),
+('CPIC-2024.1', 'SLCO1B1', '*9',  'decreased', NULL),
+('CPIC-2024.1', 'SLCO1B1', '*5',  'no',        NULL),
+('CPIC-2024.1', 'SLCO1B1', '*15', 'no',        NULL),
+-- CYP3A5
+('CPIC-2024.1', 'CYP3A5', '*1',  'normal',    NULL),
+('CPIC-2024.1', 'CYP3A5', '*3',  'no',        NULL),
+('CPIC-2024.1', 'CYP3A5', '*6',  'no',        NULL),
+('CPIC-2024.1', 'CYP3A5', '*7',  'no',        NULL)
+ON CONFLICT (table_version, gene, allele) DO NOTHING;
+
+INSERT INTO pgx_phenotype_rules (table_version, gene, method, min_activity_score, max_activity_score, function_1, function_2, phenotype)
+SELECT v.table_version, v.gene, v.method, v.min_score::NUMERIC, v.max_score::NUMERIC, v.function_1, v.function_2, v.phenotype
+FROM (VALUES
+-- CYP2D6 (activity score)
+('CPIC-2024.1', 'CYP2D6', 'activity_score', 0.00, 0.00, NULL, NULL, 'poor'),
+('CPIC-2024.1', 'CYP2D6', 'activity_score', 0.25, 1.00, NULL, NULL, 'intermediate'),
+('CPIC-2024.1', 'CYP2D6', 'activity_score', 1.25, 2.25, NULL, NULL, 'normal'),
+('CPIC-2024.1', 'CYP2D6', 'activity_score', 2.50, NULL, NULL, NULL, 'ultrarapid'),
+-- CYP2C9 (activity score)
+('CPIC-2024.1', 'CYP2C9', 'activity_score', 0.00, 0.50, NULL, NULL, 'poor'),
+('CPIC-2024.1', 'CYP2C9', 'activity_score', 1.00, 1.50, NULL, NULL, 'intermediate'),
+('CPIC-2024.1', 'CYP2C9', 'activity_score', 2.00, NULL, NULL, NULL, 'normal'),
+-- CYP2C19 (function pair)
+('CPIC-2024.1', 'CYP2C19', 'function_pair', NULL, NULL, 'normal',    'normal',    'normal'),
+('CPIC-2024.1', 'CYP2C19', 'function_pair', NULL, NULL, 'normal',    'increased', 'rapid'),
+('CPIC-2024.1', 'CYP2C19', 'function_pair', NULL, NULL, 'increased', 'increased', 'ultrarapid'),
+('CPIC-2024.1', 'CYP2C19', 'function_pair', NULL, NULL, 'normal',    'no',        'intermediate'),
+('CPIC-2024.1', 'CYP2C19', 'function_pair', NULL, NULL, 'increased', 'no',        'intermediate'),
+('CPIC-2024.1', 'CYP2C19', 'function_pair', NULL, NULL, 'normal',    'decreased', 'intermediate'),
+('CPIC-2024.1', 'CYP2C19', 'function_pair', NULL, NULL, 'increased', 'decreased', 'intermediate'),
+('CPIC-2024.1', 'CYP2C19', 'function_pair', NULL, NULL, 'decreased', 'decreased', 'intermediate'),
+('CPIC-2024.1', 'CYP2C19', 'function_pair', NULL, NULL, 'decreased', 'no',        'poor'),
+('CPIC-2024.1', 'CYP2C19', 'function_pair', NULL, NULL, 'no',        'no',        'poor'),
+-- SLCO1B1 (function pair; transporter function labels)
+('CPIC-2024.1', 'SLCO1B1', 'function_pair', NULL, NULL, 'normal',    'normal',    'normal'),
+('CPIC-2024.1', 'SLCO1B1', 'function_pair', NULL, NULL, 'normal',    'increased', 'normal'),
+('CPIC-2024.1', 'SLCO1B1', 'function_pair', NULL, NULL, 'increased', 'increased', 'increased'),
+('CPIC-2024.1', 'SLCO1B1', 'function_pair', NULL, NULL, 'normal',    'decreased', 'decreased'),
+('CPIC-2024.1', 'SLCO1B1', 'function_pair', NULL, NULL, 'normal',    'no',        'decreased'),
+('CPIC-2024.1', 'SLCO1B1', 'function_pair', NULL, NULL, 'increased', 'decreased', 'decreased'),
+('CPIC-2024.1', 'SLCO1B1', 'function_pair', NULL, NULL, 'increased', 'no',        'decreased'),
+('CPIC-2024.1', 'SLCO1B1', 'function_pair', NULL, NULL, 'decreased', 'decreased', 'poor'),
+('CPIC-2024.1', 'SLCO1B1', 'function_pair', NULL, NULL, 'decreased', 'no',        'poor'),
+('CPIC-2024.1', 'SLCO1B1', 'function_pair', NULL, NULL, 'no',        'no',        'poor'),
+-- CYP3A5 (function pair; expresser status)
+('CPIC-2024.1', 'CYP3A5', 'function_pair', NULL, NULL, 'normal',    'normal',    'expresser'),
+('CPIC-2024.1', 'CYP3A5', 'function_pair', NULL, NULL, 'normal',    'no',        'expresser'),
+('CPIC-2024.1', 'CYP3A5', 'function_pair', NULL, NULL, 'no',        'no',        'non_expresser')
+) AS v(table_version, gene, method, min_score, max_score, function_1, function_2, phenotype)
+WHERE NOT EXISTS (
+    SELECT 1 FROM pgx_phenotype_rules r WHERE r.table_version = v.table_version AND r.gene = v.gene
+);
@@ -0,0 +1,56 @@
+-- =============================================================================
+-- Migration 035: Phenoconversion Perpetrators
+-- =============================================================================
+-- A CYP2D6 normal metabolizer taking paroxetine behaves like a poor metabolizer.
+-- Before PGx rules run, the PGx engine converts each genotype phenotype to an
+-- effective phenotype using the inhibitors and inducers in the regimen:
+--   * strong inhibitor   -> poor (activity score 0)
+--   * moderate inhibitor -> one step lower (activity score x 0.5)
+--   * strong/moderate inducer -> one step higher (ignored when an inhibitor is present)
+-- Weak perpetrators are listed for completeness but do not convert phenotypes.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS pgx_enzyme_perpetrators (
+    id SERIAL PRIMARY KEY,
+    drug_code VARCHAR(50) NOT NULL,
+    drug_name VARCHAR(200) NOT NULL,
+    gene VARCHAR(20) NOT NULL,
+    effect VARCHAR(20) NOT NULL CHECK (effect IN ('inhibitor', 'inducer')),
+    strength VARCHAR(20) NOT NULL CHECK (strength IN ('strong', 'moderate', 'weak')),
+    source VARCHAR(100) NOT NULL DEFAULT 'FDA_DDI_TABLE',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (drug_code, gene, effect)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pgx_perpetrators_drug ON pgx_enzyme_perpetrators(drug_code) WHERE active;
+
+COMMENT ON TABLE pgx_enzyme_perpetrators IS 'Enzyme/transporter inhibitors and inducers used for PGx phenoconversion.';
+
+INSERT INTO pgx_enzyme_perpetrators (drug_code, drug_name, gene, effect, strength, source) VALUES
+-- CYP2D6 inhibitors (FDA clinical index inhibitors)
+('RxCUI:32937',  'Paroxetine',   'CYP2D6', 'inhibitor', 'strong',   'FDA_DDI_TABLE'),
+('RxCUI:4493',   'Fluoxetine',   'CYP2D6', 'inhibitor', 'strong',   'FDA_DDI_TABLE'),
+('RxCUI:42347',  'Bupropion',    'CYP2D6', 'inhibitor', 'strong',   'FDA_DDI_TABLE'),
+('RxCUI:9068',   'Quinidine',    'CYP2D6', 'inhibitor', 'strong',   'FDA_DDI_TABLE'),
+('RxCUI:37801',  'Terbinafine',  'CYP2D6', 'inhibitor', 'strong',   'FDA_DDI_TABLE'),
+('RxCUI:72625',  'Duloxetine',   'CYP2D6', 'inhibitor', 'moderate', 'FDA_DDI_TABLE'),
+('RxCUI:1300786','Mirabegron',   'CYP2D6', 'inhibitor', 'moderate', 'FDA_DDI_TABLE'),
+('RxCUI:36437',  'Sertraline',   'CYP2D6', 'inhibitor', 'weak',     'FDA_DDI_TABLE'),
+-- CYP2C19
+('RxCUI:42355',  'Fluvoxamine',  'CYP2C19', 'inhibitor', 'strong',   'FDA_DDI_TABLE'),
+('RxCUI:4450',   'Fluconazole',  'CYP2C19', 'inhibitor', 'strong',   'FDA_DDI_TABLE'),
+('RxCUI:10594',  'Ticlopidine',  'CYP2C19', 'inhibitor', 'strong',   'FDA_DDI_TABLE'),
+('RxCUI:7646',   'Omeprazole',   'CYP2C19', 'inhibitor', 'moderate', 'FDA_DDI_TABLE'),
+('RxCUI:283742', 'Esomeprazole', 'CYP2C19', 'inhibitor', 'moderate', 'FDA_DDI_TABLE'),
+('RxCUI:121243', 'Voriconazole', 'CYP2C19', 'inhibitor', 'moderate', 'FDA_DDI_TABLE'),
+('RxCUI:9384',   'Rifampin',     'CYP2C19', 'inducer',   'strong',   'FDA_DDI_TABLE'),
+('RxCUI:2002',   'Carbamazepine','CYP2C19', 'inducer',   'moderate', 'FDA_DDI_TABLE'),
+-- CYP2C9
+('RxCUI:4450',   'Fluconazole',  'CYP2C9', 'inhibitor', 'moderate', 'FDA_DDI_TABLE'),
+('RxCUI:703',    'Amiodarone',   'CYP2C9', 'inhibitor', 'moderate', 'FDA_DDI_TABLE'),
+('RxCUI:9384',   'Rifampin',     'CYP2C9', 'inducer',   'moderate', 'FDA_DDI_TABLE'),
+-- SLCO1B1 (OATP1B1 transporter)
+('RxCUI:3008',   'Cyclosporine', 'SLCO1B1', 'inhibitor', 'strong',   'FDA_DDI_TABLE'),
+('RxCUI:4719',   'Gemfibrozil',  'SLCO1B1', 'inhibitor', 'moderate', 'FDA_DDI_TABLE')
+ON CONFLICT (drug_code, gene, effect) DO NOTHING;
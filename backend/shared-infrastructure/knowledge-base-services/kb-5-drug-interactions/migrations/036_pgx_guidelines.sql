@@ -0,0 +1,113 @@
+-- =============================================================================
+-- Migration 036: Pharmacogenomic Guideline Table
+-- =============================================================================
+-- Drug-gene guidance keyed by gene, phenotype and drug, replacing the Go map
+-- literals previously embedded in the PGx engine. New drug-gene pairs are added
+-- by importing a CPIC-style guideline file (POST /api/v1/admin/pgx/guidelines/import)
+-- rather than by a code release.
+--
+-- Phenotypes use the ddi_pharmacogenomic_rules labels (poor, intermediate,
+-- normal, rapid, ultrarapid, decreased, increased, expresser, non_expresser,
+-- and VKORC1 sensitivity low/intermediate/high).
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS pgx_guidelines (
+    id BIGSERIAL PRIMARY KEY,
+    gene VARCHAR(20) NOT NULL,
+    phenotype VARCHAR(30) NOT NULL,
+    drug_code VARCHAR(50) NOT NULL,
+    drug_name VARCHAR(200) NOT NULL,
+
+    recommendation TEXT NOT NULL,
+    implications TEXT,
+    classification VARCHAR(20) NOT NULL DEFAULT 'optional'
+        CHECK (classification IN ('strong', 'moderate', 'optional', 'no_recommendation')),
+    dosing_action VARCHAR(30) NOT NULL
+        CHECK (dosing_action IN ('avoid', 'consider_alternative', 'reduce_dose', 'increase_dose',
+                                 'use_standard', 'monitor')),
+    alternatives TEXT[],  -- Alternative drug codes
+
+    source VARCHAR(50) NOT NULL DEFAULT 'CPIC',
+    source_version VARCHAR(100) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    UNIQUE (gene, phenotype, drug_code, source)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pgx_guidelines_drug ON pgx_guidelines(drug_code) WHERE active;
+CREATE INDEX IF NOT EXISTS idx_pgx_guidelines_gene ON pgx_guidelines(gene, phenotype) WHERE active;
+
+COMMENT ON TABLE pgx_guidelines IS 'Gene-phenotype-drug guidance (recommendation, strength, dosing action, alternatives) with source version.';
+
+-- =============================================================================
+-- Seed: guidance previously hardcoded in pgx_engine.go, plus warfarin/celecoxib
+-- so CYP2C9 and VKORC1 remain supported genes
+-- =============================================================================
+
+INSERT INTO pgx_guidelines (gene, phenotype, drug_code, drug_name, recommendation, classification, dosing_action, alternatives, source, source_version) VALUES
+-- CYP2D6
+('CYP2D6', 'ultrarapid', 'RxCUI:2670', 'Codeine',
+ 'AVOID: Toxic morphine levels - use morphine/oxycodone instead',
+ 'strong', 'avoid', ARRAY['RxCUI:7052', 'RxCUI:7804'], 'CPIC', 'CPIC CYP2D6-opioids 2021'),
+('CYP2D6', 'poor', 'RxCUI:2670', 'Codeine',
+ 'AVOID: Inadequate analgesia - use non-CYP2D6 opioid',
+ 'strong', 'avoid', ARRAY['RxCUI:7052', 'RxCUI:7804'], 'CPIC', 'CPIC CYP2D6-opioids 2021'),
+('CYP2D6', 'ultrarapid', 'RxCUI:4493', 'Metoprolol',
+ 'May need higher doses - monitor BP/HR closely',
+ 'optional', 'monitor', ARRAY['RxCUI:149', 'RxCUI:32968'], 'DPWG', 'DPWG metoprolol'),
+('CYP2D6', 'poor', 'RxCUI:4493', 'Metoprolol',
+ 'Reduce dose 50% - monitor for bradycardia',
+ 'optional', 'reduce_dose', ARRAY['RxCUI:149', 'RxCUI:32968'], 'DPWG', 'DPWG metoprolol'),
+('CYP2D6', 'ultrarapid', 'RxCUI:5640', 'Paroxetine',
+ 'Standard dosing usually adequate',
+ 'optional', 'use_standard', ARRAY['RxCUI:32937', 'RxCUI:36437'], 'CPIC', 'CPIC SSRI 2023'),
+('CYP2D6', 'poor', 'RxCUI:5640', 'Paroxetine',
+ 'Consider dose reduction or alternative SSRI',
+ 'optional', 'consider_alternative', ARRAY['RxCUI:32937', 'RxCUI:36437'], 'CPIC', 'CPIC SSRI 2023'),
+-- CYP2C19
+('CYP2C19', 'poor', 'RxCUI:1154343', 'Clopidogrel',
+ 'AVOID: Inadequate antiplatelet effect - use prasugrel/ticagrelor',
+ 'strong', 'avoid', ARRAY['RxCUI:1246289', 'RxCUI:1156738'], 'CPIC', 'CPIC CYP2C19-clopidogrel 2022'),
+('CYP2C19', 'ultrarapid', 'RxCUI:1154343', 'Clopidogrel',
+ 'Standard dosing adequate - enhanced antiplatelet effect',
+ 'strong', 'use_standard', ARRAY['RxCUI:1246289', 'RxCUI:1156738'], 'CPIC', 'CPIC CYP2C19-clopidogrel 2022'),
+('CYP2C19', 'poor', 'RxCUI:197696', 'Omeprazole',
+ 'Consider H2 blocker alternative - prolonged acid suppression',
+ 'optional', 'consider_alternative', ARRAY['RxCUI:1927851', 'RxCUI:2047'], 'CPIC', 'CPIC PPI 2020'),
+('CYP2C19', 'ultrarapid', 'RxCUI:197696', 'Omeprazole',
+ 'Standard dosing adequate',
+ 'optional', 'use_standard', ARRAY['RxCUI:1927851', 'RxCUI:2047'], 'CPIC', 'CPIC PPI 2020'),
+-- SLCO1B1
+('SLCO1B1', 'poor', 'RxCUI:36567', 'Simvastatin',
+ 'Reduce dose 50% or use pravastatin/rosuvastatin - myopathy risk',
+ 'strong', 'reduce_dose', ARRAY['RxCUI:42463', 'RxCUI:446503'], 'CPIC', 'CPIC SLCO1B1-statins 2022'),
+('SLCO1B1', 'poor', 'RxCUI:83367', 'Atorvastatin',
+ 'Consider dose reduction - monitor for muscle symptoms',
+ 'moderate', 'reduce_dose', ARRAY['RxCUI:42463', 'RxCUI:446503'], 'CPIC', 'CPIC SLCO1B1-statins 2022'),
+-- CYP3A5
+('CYP3A5', 'expresser', 'RxCUI:42316', 'Tacrolimus',
+ 'May need higher doses - monitor trough levels closely',
+ 'strong', 'increase_dose', NULL, 'CPIC', 'CPIC CYP3A5-tacrolimus 2015'),
+('CYP3A5', 'non_expresser', 'RxCUI:42316', 'Tacrolimus',
+ 'Standard dosing - monitor trough levels',
+ 'strong', 'use_standard', NULL, 'CPIC', 'CPIC CYP3A5-tacrolimus 2015'),
+-- CYP2C9
+('CYP2C9', 'poor', 'RxCUI:140587', 'Celecoxib',
+ 'Initiate at 25-50% of the lowest recommended starting dose',
+ 'moderate', 'reduce_dose', NULL, 'CPIC', 'CPIC CYP2C9-NSAIDs 2020'),
+('CYP2C9', 'intermediate', 'RxCUI:140587', 'Celecoxib',
+ 'Initiate at the lowest recommended starting dose',
+ 'optional', 'reduce_dose', NULL, 'CPIC', 'CPIC CYP2C9-NSAIDs 2020'),
+-- VKORC1 (warfarin sensitivity)
+('VKORC1', 'high', 'RxCUI:11289', 'Warfarin',
+ 'Increased warfarin sensitivity - use genotype-guided dosing with a lower initial dose',
+ 'strong', 'reduce_dose', NULL, 'CPIC', 'CPIC warfarin 2017'),
+('VKORC1', 'intermediate', 'RxCUI:11289', 'Warfarin',
+ 'Use genotype-guided dosing',
+ 'strong', 'monitor', NULL, 'CPIC', 'CPIC warfarin 2017'),
+('VKORC1', 'low', 'RxCUI:11289', 'Warfarin',
+ 'Standard dosing - use genotype-guided dosing where available',
+ 'strong', 'use_standard', NULL, 'CPIC', 'CPIC warfarin 2017')
+ON CONFLICT (gene, phenotype, drug_code, source) DO NOTHING;
@@ -0,0 +1,150 @@
+-- =============================================================================
+-- Migration 037: High-Risk Pharmacogenomic Safety Rules
+-- =============================================================================
+-- Two kinds of PGx hard-stop checks that metabolizer-phenotype rules cannot
+-- express:
+--   * risk_allele   - carrier status of a single allele (HLA-B*57:01, HLA-B*15:02,
+--                     HLA-B*58:01, HLA-A*31:01). Matched against the patient's
+--                     reported risk alleles ("HLA-B*57:01": "positive").
+--   * dose_critical - a phenotype of a gene whose poor/deficient status makes
+--                     standard dosing dangerous (TPMT, NUDT15, DPYD, G6PD, and
+--                     CYP2C9 with phenytoin). Matched against pgx_markers.
+--
+-- governance_action is the minimum governance action for a match; the governance
+-- policy engine never maps a PGx safety finding below it, whatever the
+-- institution's severity policy says.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS pgx_safety_rules (
+    id SERIAL PRIMARY KEY,
+    check_type VARCHAR(20) NOT NULL CHECK (check_type IN ('risk_allele', 'dose_critical')),
+    gene VARCHAR(20) NOT NULL,
+    allele VARCHAR(30),      -- risk_allele: normalized allele name, e.g. HLA-B*57:01
+    phenotype VARCHAR(30),   -- dose_critical: phenotype label
+    drug_code VARCHAR(50) NOT NULL,
+    drug_name VARCHAR(200) NOT NULL,
+
+    severity VARCHAR(20) NOT NULL CHECK (severity IN ('contraindicated', 'major', 'moderate', 'minor')),
+    governance_action VARCHAR(40) NOT NULL
+        CHECK (governance_action IN ('hard_block', 'hard_block_governance_override',
+                                     'mandatory_escalation', 'warn_acknowledge')),
+    clinical_effect TEXT NOT NULL,
+    recommendation TEXT NOT NULL,
+    starting_dose_percent NUMERIC(5,1), -- Percent of standard starting dose, when dosing is still allowed
+    evidence VARCHAR(20) NOT NULL DEFAULT 'A',
+    source VARCHAR(100) NOT NULL DEFAULT 'CPIC',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CHECK ((check_type = 'risk_allele' AND allele IS NOT NULL AND phenotype IS NULL) OR
+           (check_type = 'dose_critical' AND phenotype IS NOT NULL AND allele IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pgx_safety_rules_key
+    ON pgx_safety_rules(gene, COALESCE(allele, ''), COALESCE(phenotype, ''), drug_code);
+CREATE INDEX IF NOT EXISTS idx_pgx_safety_rules_drug ON pgx_safety_rules(drug_code) WHERE active;
+
+COMMENT ON TABLE pgx_safety_rules IS 'HLA risk-allele and dose-critical gene checks that feed governance hard-block actions.';
+
+-- =============================================================================
+-- Seed: CPIC HLA and dose-critical gene guidance
+-- =============================================================================
+
+INSERT INTO pgx_safety_rules (check_type, gene, allele, phenotype, drug_code, drug_name, severity, governance_action,
+                              clinical_effect, recommendation, starting_dose_percent, source) VALUES
+-- HLA risk alleles
+('risk_allele', 'HLA-B', 'HLA-B*57:01', NULL, 'RxCUI:190521', 'Abacavir', 'contraindicated', 'hard_block',
+ 'Abacavir hypersensitivity reaction, potentially fatal on rechallenge',
+ 'Do not prescribe abacavir. Use an alternative antiretroviral.', NULL, 'CPIC HLA-B-abacavir 2014'),
+('risk_allele', 'HLA-B', 'HLA-B*15:02', NULL, 'RxCUI:2002', 'Carbamazepine', 'contraindicated', 'hard_block',
+ 'Stevens-Johnson syndrome / toxic epidermal necrolysis',
+ 'Do not use carbamazepine in carbamazepine-naive patients. Avoid oxcarbazepine and phenytoin as alternatives.', NULL, 'CPIC HLA-carbamazepine 2017'),
+('risk_allele', 'HLA-B', 'HLA-B*15:02', NULL, 'RxCUI:32624', 'Oxcarbazepine', 'contraindicated', 'hard_block_governance_override',
+ 'Stevens-Johnson syndrome / toxic epidermal necrolysis',
+ 'Do not use oxcarbazepine in oxcarbazepine-naive patients.', NULL, 'CPIC HLA-carbamazepine 2017'),
+('risk_allele', 'HLA-B', 'HLA-B*15:02', NULL, 'RxCUI:8183', 'Phenytoin', 'contraindicated', 'hard_block_governance_override',
+ 'Stevens-Johnson syndrome / toxic epidermal necrolysis',
+ 'Do not use phenytoin or fosphenytoin in phenytoin-naive patients. Avoid carbamazepine and oxcarbazepine as alternatives.', NULL, 'CPIC CYP2C9-HLA-B-phenytoin 2020'),
+('risk_allele', 'HLA-B', 'HLA-B*15:02', NULL, 'RxCUI:72236', 'Fosphenytoin', 'contraindicated', 'hard_block_governance_override',
+ 'Stevens-Johnson syndrome / toxic epidermal necrolysis',
+ 'Do not use phenytoin or fosphenytoin in phenytoin-naive patients.', NULL, 'CPIC CYP2C9-HLA-B-phenytoin 2020'),
+('risk_allele', 'HLA-B', 'HLA-B*58:01', NULL, 'RxCUI:519', 'Allopurinol', 'contraindicated', 'hard_block',
+ 'Severe cutaneous adverse reactions (SJS/TEN, DRESS)',
+ 'Allopurinol is contraindicated. Use an alternative urate-lowering agent such as febuxostat.', NULL, 'CPIC HLA-B-allopurinol 2015'),
+('risk_allele', 'HLA-A', 'HLA-A*31:01', NULL, 'RxCUI:2002', 'Carbamazepine', 'major', 'hard_block_governance_override',
+ 'DRESS, maculopapular exanthema and SJS/TEN',
+ 'If carbamazepine-naive and alternatives are available, do not use carbamazepine.', NULL, 'CPIC HLA-carbamazepine 2017'),
+-- TPMT / NUDT15 with thiopurines
+('dose_critical', 'TPMT', NULL, 'poor', 'RxCUI:103', 'Mercaptopurine', 'major', 'hard_block_governance_override',
+ 'Life-threatening myelosuppression at standard doses',
+ 'Reduce daily dose 10-fold and dose three times weekly, or use an alternative for non-malignant conditions.', 10, 'CPIC TPMT-NUDT15-thiopurines 2018'),
+('dose_critical', 'TPMT', NULL, 'intermediate', 'RxCUI:103', 'Mercaptopurine', 'moderate', 'mandatory_escalation',
+ 'Increased risk of myelosuppression',
+ 'Start at 30-80% of the normal dose and adjust based on myelosuppression.', 50, 'CPIC TPMT-NUDT15-thiopurines 2018'),
+('dose_critical', 'TPMT', NULL, 'poor', 'RxCUI:1256', 'Azathioprine', 'major', 'hard_block_governance_override',
+ 'Life-threatening myelosuppression at standard doses',
+ 'For non-malignant conditions use an alternative agent; for malignancy reduce daily dose 10-fold, three times weekly.', 10, 'CPIC TPMT-NUDT15-thiopurines 2018'),
+('dose_critical', 'TPMT', NULL, 'intermediate', 'RxCUI:1256', 'Azathioprine', 'moderate', 'mandatory_escalation',
+ 'Increased risk of myelosuppression',
+ 'Start at 30-80% of the normal dose and adjust based on myelosuppression.', 50, 'CPIC TPMT-NUDT15-thiopurines 2018'),
+('dose_critical', 'TPMT', NULL, 'poor', 'RxCUI:10485', 'Thioguanine', 'major', 'hard_block_governance_override',
+ 'Life-threatening myelosuppression at standard doses',
+ 'Reduce daily dose 10-fold and dose three times weekly.', 10, 'CPIC TPMT-NUDT15-thiopurines 2018'),
+('dose_critical', 'TPMT', NULL, 'intermediate', 'RxCUI:10485', 'Thioguanine', 'moderate', 'mandatory_escalation',
+ 'Increased risk of myelosuppression',
+ 'Start at 50-80% of the normal dose.', 50, 'CPIC TPMT-NUDT15-thiopurines 2018'),
+('dose_critical', 'NUDT15', NULL, 'poor', 'RxCUI:103', 'Mercaptopurine', 'major', 'hard_block_governance_override',
+ 'Life-threatening myelosuppression at standard doses',
+ 'Start at 10 mg/m2/day and adjust based on myelosuppression, or use an alternative for non-malignant conditions.', 10, 'CPIC TPMT-NUDT15-thiopurines 2018'),
+('dose_critical', 'NUDT15', NULL, 'intermediate', 'RxCUI:103', 'Mercaptopurine', 'moderate', 'mandatory_escalation',
+ 'Increased risk of myelosuppression',
+ 'Start at 30-80% of the normal dose and adjust based on myelosuppression.', 50, 'CPIC TPMT-NUDT15-thiopurines 2018'),
+('dose_critical', 'NUDT15', NULL, 'poor', 'RxCUI:1256', 'Azathioprine', 'major', 'hard_block_governance_override',
+ 'Life-threatening myelosuppression at standard doses',
+ 'For non-malignant conditions use an alternative agent; for malignancy reduce daily dose 10-fold.', 10, 'CPIC TPMT-NUDT15-thiopurines 2018'),
+('dose_critical', 'NUDT15', NULL, 'intermediate', 'RxCUI:1256', 'Azathioprine', 'moderate', 'mandatory_escalation',
+ 'Increased risk of myelosuppression',
+ 'Start at 30-80% of the normal dose and adjust based on myelosuppression.', 50, 'CPIC TPMT-NUDT15-thiopurines 2018'),
+('dose_critical', 'NUDT15', NULL, 'poor', 'RxCUI:10485', 'Thioguanine', 'major', 'hard_block_governance_override',
+ 'Life-threatening myelosuppression at standard doses',
+ 'Reduce to 25% of the normal dose and adjust based on myelosuppression.', 25, 'CPIC TPMT-NUDT15-thiopurines 2018'),
+-- DPYD with fluoropyrimidines
+('dose_critical', 'DPYD', NULL, 'poor', 'RxCUI:4492', 'Fluorouracil', 'contraindicated', 'hard_block',
+ 'Severe or fatal fluoropyrimidine toxicity',
+ 'Avoid fluorouracil. Use a non-fluoropyrimidine regimen.', NULL, 'CPIC DPYD-fluoropyrimidines 2017'),
+('dose_critical', 'DPYD', NULL, 'intermediate', 'RxCUI:4492', 'Fluorouracil', 'major', 'mandatory_escalation',
+ 'Increased risk of severe fluoropyrimidine toxicity',
+ 'Reduce starting dose by 50% and titrate based on toxicity.', 50, 'CPIC DPYD-fluoropyrimidines 2017'),
+('dose_critical', 'DPYD', NULL, 'poor', 'RxCUI:194000', 'Capecitabine', 'contraindicated', 'hard_block',
+ 'Severe or fatal fluoropyrimidine toxicity',
+ 'Avoid capecitabine. Use a non-fluoropyrimidine regimen.', NULL, 'CPIC DPYD-fluoropyrimidines 2017'),
+('dose_critical', 'DPYD', NULL, 'intermediate', 'RxCUI:194000', 'Capecitabine', 'major', 'mandatory_escalation',
+ 'Increased risk of severe fluoropyrimidine toxicity',
+ 'Reduce starting dose by 50% and titrate based on toxicity.', 50, 'CPIC DPYD-fluoropyrimidines 2017'),
+-- G6PD
+('dose_critical', 'G6PD', NULL, 'deficient', 'RxCUI:283821', 'Rasburicase', 'contraindicated', 'hard_block',
+ 'Acute hemolytic anemia and methemoglobinemia',
+ 'Rasburicase is contraindicated. Use an alternative such as allopurinol.', NULL, 'CPIC G6PD 2022'),
+('dose_critical', 'G6PD', NULL, 'variable', 'RxCUI:283821', 'Rasburicase', 'major', 'hard_block_governance_override',
+ 'Risk of acute hemolytic anemia',
+ 'Confirm G6PD activity by enzyme assay before use.', NULL, 'CPIC G6PD 2022'),
+('dose_critical', 'G6PD', NULL, 'deficient', 'RxCUI:8687', 'Primaquine', 'contraindicated', 'hard_block',
+ 'Acute hemolytic anemia',
+ 'Avoid standard primaquine dosing. Weekly dosing only under specialist supervision with hemolysis monitoring.', NULL, 'CPIC G6PD 2022'),
+('dose_critical', 'G6PD', NULL, 'variable', 'RxCUI:8687', 'Primaquine', 'major', 'mandatory_escalation',
+ 'Risk of acute hemolytic anemia',
+ 'Confirm G6PD activity by enzyme assay; monitor for hemolysis.', NULL, 'CPIC G6PD 2022'),
+-- CYP2C9 with phenytoin
+('dose_critical', 'CYP2C9', NULL, 'poor', 'RxCUI:8183', 'Phenytoin', 'major', 'mandatory_escalation',
+ 'Increased phenytoin exposure and concentration-dependent toxicity',
+ 'Reduce starting maintenance dose by 50%; use therapeutic drug monitoring for subsequent doses.', 50, 'CPIC CYP2C9-HLA-B-phenytoin 2020'),
+('dose_critical', 'CYP2C9', NULL, 'intermediate', 'RxCUI:8183', 'Phenytoin', 'moderate', 'warn_acknowledge',
+ 'Increased phenytoin exposure',
+ 'Reduce starting maintenance dose by 25%; use therapeutic drug monitoring for subsequent doses.', 75, 'CPIC CYP2C9-HLA-B-phenytoin 2020'),
+('dose_critical', 'CYP2C9', NULL, 'poor', 'RxCUI:72236', 'Fosphenytoin', 'major', 'mandatory_escalation',
+ 'Increased phenytoin exposure and concentration-dependent toxicity',
+ 'Reduce starting maintenance dose by 50%; use therapeutic drug monitoring for subsequent doses.', 50, 'CPIC CYP2C9-HLA-B-phenytoin 2020'),
+('dose_critical', 'CYP2C9', NULL, 'intermediate', 'RxCUI:72236', 'Fosphenytoin', 'moderate', 'warn_acknowledge',
+ 'Increased phenytoin exposure',
+ 'Reduce starting maintenance dose by 25%; use therapeutic drug monitoring for subsequent doses.', 75, 'CPIC CYP2C9-HLA-B-phenytoin 2020')
+ON CONFLICT DO NOTHING;
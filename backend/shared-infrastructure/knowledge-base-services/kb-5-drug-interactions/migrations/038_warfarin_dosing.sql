@@ -0,0 +1,62 @@
+-- =============================================================================
+-- Migration 038: Genotype-Guided Warfarin Dosing Modifiers
+-- =============================================================================
+-- The warfarin dosing endpoint (POST /api/v1/dosing/warfarin) estimates the
+-- weekly maintenance dose with the IWPC pharmacogenetic algorithm (NEJM 2009;
+-- 360:753-64), then adjusts it for interacting drugs in the regimen.
+--
+-- Each row links a co-medication to its warfarin interaction (migration 015) and
+-- gives the dose change range from that interaction's management strategy.
+-- Drugs that are IWPC covariates (amiodarone; enzyme inducers carbamazepine,
+-- phenytoin, rifampin) are already counted by the algorithm; iwpc_covariate
+-- marks them so their adjustment is not applied twice.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS warfarin_dose_modifiers (
+    id SERIAL PRIMARY KEY,
+    drug_code VARCHAR(50) NOT NULL UNIQUE,
+    drug_name VARCHAR(200) NOT NULL,
+    interaction_id VARCHAR(100),              -- drug_interactions.interaction_id
+    iwpc_covariate VARCHAR(20) CHECK (iwpc_covariate IN ('amiodarone', 'enzyme_inducer')),
+    adjustment_min_percent NUMERIC(5,1) NOT NULL, -- Negative = dose reduction
+    adjustment_max_percent NUMERIC(5,1) NOT NULL,
+    recommendation TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (adjustment_min_percent <= adjustment_max_percent)
+);
+
+COMMENT ON TABLE warfarin_dose_modifiers IS 'Co-medication adjustments to the genotype-guided warfarin dose estimate.';
+
+INSERT INTO warfarin_dose_modifiers (drug_code, drug_name, interaction_id, iwpc_covariate,
+                                     adjustment_min_percent, adjustment_max_percent, recommendation) VALUES
+-- IWPC covariates
+('RxCUI:703',    'Amiodarone',    'WARFARIN_AMIODARONE_001',    'amiodarone',      -50,  -30,
+ 'Reduce warfarin dose by 30-50% when starting amiodarone. Monitor INR weekly x 4 weeks.'),
+('RxCUI:2002',   'Carbamazepine', 'WARFARIN_CARBAMAZEPINE_002', 'enzyme_inducer',    50,  100,
+ 'Increase warfarin dose as needed (often 50-100%). Monitor INR weekly during initiation.'),
+('RxCUI:8183',   'Phenytoin',     'WARFARIN_PHENYTOIN_002',     'enzyme_inducer',  100,  200,
+ 'May need 2-3x warfarin dose long-term. Monitor INR and phenytoin levels closely.'),
+('RxCUI:9384',   'Rifampin',      'WARFARIN_RIFAMPIN_001',      'enzyme_inducer',  100,  200,
+ 'Warfarin doses often need to be doubled or tripled. Monitor INR twice weekly.'),
+-- CYP2C9 / CYP3A4 inhibitors
+('RxCUI:4450',   'Fluconazole',   'WARFARIN_FLUCONAZOLE_001',   NULL,  -50,  -25,
+ 'Reduce warfarin dose by 25-50% when starting fluconazole. Check INR within 3-5 days.'),
+('RxCUI:6922',   'Metronidazole', 'WARFARIN_METRONIDAZOLE_001', NULL,  -35,  -25,
+ 'Reduce warfarin dose by 25-35%. Monitor INR within 5-7 days.'),
+('RxCUI:10180',  'Sulfamethoxazole', 'WARFARIN_TMPSMX_001',     NULL,  -50,  -25,
+ 'Avoid if alternative exists; otherwise reduce warfarin by 25-50% and check INR in 3-4 days.'),
+('RxCUI:2551',   'Ciprofloxacin', 'WARFARIN_CIPROFLOXACIN_001', NULL,  -20,  -10,
+ 'Consider 10-20% warfarin dose reduction. Monitor INR within 3-5 days.'),
+('RxCUI:21212',  'Clarithromycin','WARFARIN_CLARITHROMYCIN_001',NULL,  -25,  -15,
+ 'Reduce warfarin 15-25% and monitor INR, or use azithromycin.'),
+('RxCUI:4053',   'Erythromycin',  'WARFARIN_ERYTHROMYCIN_001',  NULL,  -15,  -10,
+ 'May need warfarin dose reduction 10-15%. Monitor INR within 5 days.'),
+('RxCUI:121243', 'Voriconazole',  'WARFARIN_VORICONAZOLE_001',  NULL,  -50,  -50,
+ 'Reduce warfarin dose by 50% when starting voriconazole. Check INR within 3 days.'),
+('RxCUI:140587', 'Celecoxib',     'WARFARIN_CELECOXIB_001',     NULL,  -20,  -10,
+ 'Monitor INR; may need warfarin reduction.'),
+-- Inducers that are not IWPC covariates
+('RxCUI:75207',  'Bosentan',      'WARFARIN_BOSENTAN_001',      NULL,   30,   40,
+ 'Increase warfarin dose as needed. Monitor INR weekly x 4 weeks.')
+ON CONFLICT (drug_code) DO NOTHING;
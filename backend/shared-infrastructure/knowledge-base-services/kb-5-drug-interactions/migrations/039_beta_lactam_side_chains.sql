@@ -0,0 +1,69 @@
+-- =============================================================================
+-- Migration 039: Beta-Lactam Side-Chain Cross-Reactivity
+-- =============================================================================
+-- Flat ddi_allergy_rules rows give one cross-reactivity rate per allergen/drug
+-- pair, so a penicillin allergy flags every cephalosporin. Cross-reactivity
+-- between beta-lactams is driven mainly by identical R1 side chains (and, for
+-- cephalosporins, R2 side chains), not by the shared beta-lactam ring.
+--
+-- The allergy engine uses this table when both the allergen and the ordered
+-- drug are listed: identical side chains and the reaction phenotype (IgE,
+-- benign rash, severe cutaneous) decide avoid / test dose / proceed. Pairs with
+-- a row here take precedence over ddi_allergy_rules.
+--
+-- Groups name identical side chains; NULL = unique to that drug or not
+-- applicable (penicillin R2 is part of the thiazolidine core; carbapenem side
+-- chains are not shared with other classes).
+-- Sources: Zagursky RJ, Pichichero ME. J Allergy Clin Immunol Pract 2018;
+-- 6:72-81. Khan DA et al. J Allergy Clin Immunol 2022;150:1333-93.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS beta_lactam_side_chains (
+    id SERIAL PRIMARY KEY,
+    drug_code VARCHAR(50) NOT NULL UNIQUE,
+    drug_name VARCHAR(200) NOT NULL,
+    beta_lactam_class VARCHAR(20) NOT NULL
+        CHECK (beta_lactam_class IN ('penicillin', 'cephalosporin', 'carbapenem', 'monobactam')),
+    r1_group VARCHAR(60),
+    r2_group VARCHAR(60),
+    source VARCHAR(100) NOT NULL DEFAULT 'JACI 2022 drug allergy practice parameter',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_beta_lactam_r1 ON beta_lactam_side_chains(r1_group) WHERE r1_group IS NOT NULL;
+
+COMMENT ON TABLE beta_lactam_side_chains IS 'R1/R2 side-chain groups for beta-lactam allergy cross-reactivity.';
+
+INSERT INTO beta_lactam_side_chains (drug_code, drug_name, beta_lactam_class, r1_group, r2_group) VALUES
+-- Penicillins
+('RxCUI:7980',   'Penicillin G',  'penicillin',    'benzyl',                               NULL),
+('RxCUI:7984',   'Penicillin V',  'penicillin',    'phenoxymethyl',                        NULL),
+('RxCUI:733',    'Ampicillin',    'penicillin',    'aminobenzyl',                          NULL),
+('RxCUI:723',    'Amoxicillin',   'penicillin',    'hydroxyaminobenzyl',                   NULL),
+('RxCUI:8339',   'Piperacillin',  'penicillin',    'ureido',                               NULL),
+('RxCUI:7233',   'Nafcillin',     'penicillin',    'ethoxynaphthyl',                       NULL),
+('RxCUI:7773',   'Oxacillin',     'penicillin',    'isoxazolyl',                           NULL),
+('RxCUI:3356',   'Dicloxacillin', 'penicillin',    'isoxazolyl',                           NULL),
+-- Cephalosporins
+('RxCUI:2231',   'Cephalexin',    'cephalosporin', 'aminobenzyl',                          'methyl'),
+('RxCUI:2176',   'Cefadroxil',    'cephalosporin', 'hydroxyaminobenzyl',                   'methyl'),
+('RxCUI:19552',  'Cefprozil',     'cephalosporin', 'hydroxyaminobenzyl',                   'propenyl'),
+('RxCUI:2180',   'Cefazolin',     'cephalosporin', 'tetrazolylmethyl',                     'methylthiadiazolylthiomethyl'),
+('RxCUI:2194',   'Cefuroxime',    'cephalosporin', 'methoxyimino_furyl',                   'carbamoyloxymethyl'),
+('RxCUI:2189',   'Cefoxitin',     'cephalosporin', 'thienyl',                              'carbamoyloxymethyl'),
+('RxCUI:2187',   'Cefotetan',     'cephalosporin', NULL,                                   'methyltetrazolylthiomethyl'),
+('RxCUI:2186',   'Cefotaxime',    'cephalosporin', 'methoxyimino_aminothiazolyl',          'acetoxymethyl'),
+('RxCUI:2193',   'Ceftriaxone',   'cephalosporin', 'methoxyimino_aminothiazolyl',          NULL),
+('RxCUI:20489',  'Cefpodoxime',   'cephalosporin', 'methoxyimino_aminothiazolyl',          'methoxymethyl'),
+('RxCUI:20481',  'Cefepime',      'cephalosporin', 'methoxyimino_aminothiazolyl',          NULL),
+('RxCUI:2191',   'Ceftazidime',   'cephalosporin', 'carboxypropyloxyimino_aminothiazolyl', NULL),
+('RxCUI:25037',  'Cefdinir',      'cephalosporin', NULL,                                   'vinyl'),
+('RxCUI:25033',  'Cefixime',      'cephalosporin', NULL,                                   'vinyl'),
+-- Carbapenems
+('RxCUI:29561',  'Meropenem',     'carbapenem',    NULL,                                   NULL),
+('RxCUI:5690',   'Imipenem',      'carbapenem',    NULL,                                   NULL),
+('RxCUI:325642', 'Ertapenem',     'carbapenem',    NULL,                                   NULL),
+-- Monobactams (aztreonam shares its R1 with ceftazidime)
+('RxCUI:1272',   'Aztreonam',     'monobactam',    'carboxypropyloxyimino_aminothiazolyl', NULL)
+ON CONFLICT (drug_code) DO NOTHING;
@@ -0,0 +1,111 @@
+-- =============================================================================
+-- Migration 040: Excipient (Inactive Ingredient) Allergy Model
+-- =============================================================================
+-- ddi_allergy_rules allows allergen_type 'excipient', but nothing linked drug
+-- products to their excipients. This migration:
+--   * gives drug_formulations a product identity (product_code, product_name,
+--     manufacturer) so rows describe specific marketed products
+--   * adds an excipient catalog with the label/allergy terms that identify each
+--     excipient and its cross-reactive excipients (PEG <-> polysorbate 80)
+--   * links products to excipients (drug_formulation_excipients)
+--
+-- Product ingredient lists are loaded with POST /api/v1/admin/excipients/import.
+-- Seeded products use local product codes; imported products use NDC or SPL codes.
+-- =============================================================================
+
+ALTER TABLE drug_formulations
+    ADD COLUMN IF NOT EXISTS product_code VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS product_name VARCHAR(300),
+    ADD COLUMN IF NOT EXISTS manufacturer VARCHAR(200),
+    ADD COLUMN IF NOT EXISTS source VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT NOW();
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_drug_formulations_product
+    ON drug_formulations(product_code) WHERE product_code IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS excipients (
+    code VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    terms TEXT[] NOT NULL,                         -- Label ingredient and allergy terms (lower case)
+    cross_reactive_with TEXT[] NOT NULL DEFAULT '{}', -- Excipient codes
+    clinical_note TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE IF NOT EXISTS drug_formulation_excipients (
+    formulation_id UUID NOT NULL REFERENCES drug_formulations(id) ON DELETE CASCADE,
+    excipient_code VARCHAR(50) NOT NULL REFERENCES excipients(code),
+    ingredient_name VARCHAR(200) NOT NULL,         -- As listed on the product label
+    PRIMARY KEY (formulation_id, excipient_code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_formulation_excipients_code ON drug_formulation_excipients(excipient_code);
+
+COMMENT ON TABLE excipients IS 'Allergenic excipients and the label/allergy terms that identify them.';
+COMMENT ON TABLE drug_formulation_excipients IS 'Allergenic excipients contained in each drug product.';
+
+INSERT INTO excipients (code, name, terms, cross_reactive_with, clinical_note) VALUES
+('PEG', 'Polyethylene glycol (PEG)',
+ ARRAY['polyethylene glycol', 'peg', 'macrogol', 'macrogols'], ARRAY['POLYSORBATE_80'],
+ 'PEG allergy can cause anaphylaxis, including from injectables, bowel preparations and depot formulations. Polysorbate 80 may cross-react.'),
+('POLYSORBATE_80', 'Polysorbate 80',
+ ARRAY['polysorbate 80', 'polysorbate', 'tween 80'], ARRAY['PEG'],
+ 'Polysorbate 80 shares polyether structure with PEG; patients allergic to either may react to both.'),
+('GELATIN', 'Gelatin',
+ ARRAY['gelatin', 'gelatine'], '{}',
+ 'Gelatin allergy (often with alpha-gal or meat allergy) can cause anaphylaxis from capsules, vaccines and hemostatic agents.'),
+('LACTOSE', 'Lactose',
+ ARRAY['lactose', 'lactose monohydrate', 'anhydrous lactose', 'milk', 'cow milk', 'dairy'], '{}',
+ 'Pharmaceutical lactose can carry trace milk protein; relevant for cow''s milk protein allergy (notably injectable methylprednisolone), not lactose intolerance.'),
+('PEANUT_OIL', 'Peanut (arachis) oil',
+ ARRAY['peanut oil', 'arachis oil', 'peanut', 'groundnut'], '{}',
+ 'Refined peanut oil is usually tolerated, but products containing it are contraindicated in peanut allergy per labeling.'),
+('SOY_OIL', 'Soybean oil / soy lecithin',
+ ARRAY['soybean oil', 'soya oil', 'soy lecithin', 'soya lecithin', 'soy', 'soya', 'soybean'], '{}',
+ 'Lipid emulsions and some capsules contain soybean oil; labeling lists soy allergy as a contraindication.'),
+('BENZYL_ALCOHOL', 'Benzyl alcohol',
+ ARRAY['benzyl alcohol'], '{}',
+ 'Preservative in multiple-dose vials; hypersensitivity is rare. Avoid in neonates (gasping syndrome).'),
+('SULFITES', 'Sulfites',
+ ARRAY['sulfite', 'sulfites', 'sulphite', 'sulphites', 'sodium bisulfite', 'sodium metabisulfite', 'metabisulfite', 'bisulfite'], '{}',
+ 'Sulfite preservatives can trigger bronchospasm in sulfite-sensitive asthmatics. Epinephrine should not be withheld in anaphylaxis.')
+ON CONFLICT (code) DO NOTHING;
+
+-- Seed products: (product_code, drug_code, product_name, formulation, route, strength, manufacturer)
+INSERT INTO drug_formulations (drug_code, formulation_type, route_of_administration, strength,
+                               product_code, product_name, manufacturer, source) VALUES
+('RxCUI:6902',  'injection', 'iv',   '40 mg',            'LOCAL:SOLU-MEDROL-40MG',      'Solu-Medrol 40 mg powder for injection',          'Pfizer',  'seed'),
+('RxCUI:6902',  'injection', 'iv',   '125 mg',           'LOCAL:SOLU-MEDROL-125MG',     'Solu-Medrol 125 mg powder for injection',         'Pfizer',  'seed'),
+('RxCUI:6902',  'injection', 'im',   '40 mg/mL',         'LOCAL:DEPO-MEDROL-40MG-ML',   'Depo-Medrol 40 mg/mL injectable suspension',      'Pfizer',  'seed'),
+('RxCUI:6691',  'injection', 'im',   '150 mg/mL',        'LOCAL:DEPO-PROVERA-150MG-ML', 'Depo-Provera CI 150 mg/mL injectable suspension', 'Pfizer',  'seed'),
+('RxCUI:8782',  'injection', 'iv',   '10 mg/mL',         'LOCAL:DIPRIVAN-10MG-ML',      'Diprivan 1% injectable emulsion',                 'Fresenius Kabi', 'seed'),
+('RxCUI:703',   'injection', 'iv',   '50 mg/mL',         'LOCAL:AMIODARONE-50MG-ML',    'Amiodarone HCl 50 mg/mL injection (vial)',        NULL,      'seed'),
+('RxCUI:703',   'injection', 'iv',   '150 mg/100 mL',    'LOCAL:NEXTERONE-150MG-100ML', 'Nexterone 150 mg/100 mL premixed injection',      'Baxter',  'seed'),
+('RxCUI:8727',  'capsule',   'oral', '100 mg',           'LOCAL:PROMETRIUM-100MG',      'Prometrium 100 mg capsule',                       'Virtus',  'seed'),
+('RxCUI:3992',  'injection', 'im',   '0.3 mg',           'LOCAL:EPINEPHRINE-AI-0.3MG',  'Epinephrine 0.3 mg auto-injector',                NULL,      'seed'),
+('RxCUI:10582', 'tablet',    'oral', '100 mcg',          'LOCAL:SYNTHROID-100MCG',      'Synthroid 100 mcg tablet',                        'AbbVie',  'seed'),
+('RxCUI:10582', 'capsule',   'oral', '100 mcg',          'LOCAL:TIROSINT-100MCG',       'Tirosint 100 mcg capsule',                        'IBSA',    'seed'),
+('RxCUI:5224',  'injection', 'sc',   '5,000 units/mL',   'LOCAL:HEPARIN-5000U-ML-MDV',  'Heparin 5,000 units/mL multiple-dose vial',       NULL,      'seed'),
+('RxCUI:5224',  'injection', 'sc',   '5,000 units/0.5 mL', 'LOCAL:HEPARIN-5000U-PF',    'Heparin 5,000 units/0.5 mL preservative-free syringe', NULL, 'seed')
+ON CONFLICT (product_code) WHERE product_code IS NOT NULL DO NOTHING;
+
+INSERT INTO drug_formulation_excipients (formulation_id, excipient_code, ingredient_name)
+SELECT f.id, v.excipient_code, v.ingredient_name
+FROM (VALUES
+    ('LOCAL:SOLU-MEDROL-40MG',      'LACTOSE',        'lactose monohydrate'),
+    ('LOCAL:DEPO-MEDROL-40MG-ML',   'PEG',            'polyethylene glycol 3350'),
+    ('LOCAL:DEPO-MEDROL-40MG-ML',   'POLYSORBATE_80', 'polysorbate 80'),
+    ('LOCAL:DEPO-PROVERA-150MG-ML', 'PEG',            'polyethylene glycol 3350'),
+    ('LOCAL:DEPO-PROVERA-150MG-ML', 'POLYSORBATE_80', 'polysorbate 80'),
+    ('LOCAL:DIPRIVAN-10MG-ML',      'SOY_OIL',        'soybean oil'),
+    ('LOCAL:AMIODARONE-50MG-ML',    'POLYSORBATE_80', 'polysorbate 80'),
+    ('LOCAL:AMIODARONE-50MG-ML',    'BENZYL_ALCOHOL', 'benzyl alcohol'),
+    ('LOCAL:PROMETRIUM-100MG',      'PEANUT_OIL',     'peanut oil'),
+    ('LOCAL:PROMETRIUM-100MG',      'GELATIN',        'gelatin'),
+    ('LOCAL:EPINEPHRINE-AI-0.3MG',  'SULFITES',       'sodium bisulfite'),
+    ('LOCAL:SYNTHROID-100MCG',      'LACTOSE',        'lactose monohydrate'),
+    ('LOCAL:TIROSINT-100MCG',       'GELATIN',        'gelatin'),
+    ('LOCAL:HEPARIN-5000U-ML-MDV',  'BENZYL_ALCOHOL', 'benzyl alcohol')
+) AS v(product_code, excipient_code, ingredient_name)
+JOIN drug_formulations f ON f.product_code = v.product_code
+ON CONFLICT (formulation_id, excipient_code) DO NOTHING;
@@ -0,0 +1,162 @@
+-- =============================================================================
+-- Migration 041: Renal and Hepatic Dose Adjustments
+-- =============================================================================
+-- Governance escalates alerts for renal/hepatic impairment but gave no adjusted
+-- dose. Each row gives the dosing limits for a drug in one band of organ function:
+--   * renal rows: a CrCl or eGFR band [min_value, max_value) in mL/min
+--     (NULL bound = open), or a dialysis modality (HD, PD, CRRT)
+--   * hepatic rows: a Child-Pugh class
+--
+-- The organ dose adjustment engine (POST /api/v1/dosing/organ-adjustment and
+-- the comprehensive check) compares ordered dose and frequency with these limits.
+-- avoid = TRUE means the drug should not be used in that band.
+-- Regimens are for usual adult indications; see each source for indication detail.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS drug_dose_adjustments (
+    id SERIAL PRIMARY KEY,
+    drug_code VARCHAR(50) NOT NULL,
+    drug_name VARCHAR(200) NOT NULL,
+    adjustment_type VARCHAR(10) NOT NULL CHECK (adjustment_type IN ('renal', 'hepatic')),
+    function_metric VARCHAR(10) CHECK (function_metric IN ('crcl', 'egfr')),
+    min_value NUMERIC(6,1),                     -- mL/min, inclusive
+    max_value NUMERIC(6,1),                     -- mL/min, exclusive
+    dialysis_modality VARCHAR(10) CHECK (dialysis_modality IN ('HD', 'PD', 'CRRT')),
+    child_pugh_class CHAR(1) CHECK (child_pugh_class IN ('A', 'B', 'C')),
+    dose_unit VARCHAR(20) NOT NULL DEFAULT 'mg',
+    max_single_dose NUMERIC(10,2),
+    min_interval_hours NUMERIC(6,1),
+    max_daily_dose NUMERIC(10,2),
+    avoid BOOLEAN NOT NULL DEFAULT FALSE,
+    recommended_regimen TEXT NOT NULL,          -- e.g. '250-500 mg q12h'
+    clinical_note TEXT,
+    source VARCHAR(200) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (
+        (adjustment_type = 'renal' AND child_pugh_class IS NULL AND
+            ((dialysis_modality IS NOT NULL AND function_metric IS NULL) OR
+             (dialysis_modality IS NULL AND function_metric IS NOT NULL))) OR
+        (adjustment_type = 'hepatic' AND child_pugh_class IS NOT NULL AND
+            function_metric IS NULL AND dialysis_modality IS NULL)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_drug_dose_adjustments_drug ON drug_dose_adjustments(drug_code) WHERE active;
+
+COMMENT ON TABLE drug_dose_adjustments IS 'Dose limits by CrCl/eGFR band, dialysis modality or Child-Pugh class.';
+
+INSERT INTO drug_dose_adjustments (drug_code, drug_name, adjustment_type, function_metric, min_value, max_value,
+                                   dialysis_modality, child_pugh_class, max_single_dose, min_interval_hours,
+                                   max_daily_dose, avoid, recommended_regimen, clinical_note, source) VALUES
+-- Levetiracetam
+('RxCUI:114477', 'Levetiracetam', 'renal', 'crcl', 50, 80, NULL, NULL, 1000, 12, 2000, FALSE,
+ '500-1000 mg q12h', NULL, 'Keppra prescribing information'),
+('RxCUI:114477', 'Levetiracetam', 'renal', 'crcl', 30, 50, NULL, NULL, 750, 12, 1500, FALSE,
+ '250-750 mg q12h', NULL, 'Keppra prescribing information'),
+('RxCUI:114477', 'Levetiracetam', 'renal', 'crcl', NULL, 30, NULL, NULL, 500, 12, 1000, FALSE,
+ '250-500 mg q12h', NULL, 'Keppra prescribing information'),
+('RxCUI:114477', 'Levetiracetam', 'renal', NULL, NULL, NULL, 'HD', NULL, 1000, 24, 1000, FALSE,
+ '500-1000 mg q24h', 'Give a 250-500 mg supplemental dose after each dialysis session.', 'Keppra prescribing information'),
+-- Gabapentin
+('RxCUI:25480', 'Gabapentin', 'renal', 'crcl', 30, 60, NULL, NULL, 700, 12, 1400, FALSE,
+ '200-700 mg q12h', NULL, 'Neurontin prescribing information'),
+('RxCUI:25480', 'Gabapentin', 'renal', 'crcl', 15, 30, NULL, NULL, 700, 24, 700, FALSE,
+ '200-700 mg q24h', NULL, 'Neurontin prescribing information'),
+('RxCUI:25480', 'Gabapentin', 'renal', 'crcl', NULL, 15, NULL, NULL, 300, 24, 300, FALSE,
+ '100-300 mg q24h', NULL, 'Neurontin prescribing information'),
+('RxCUI:25480', 'Gabapentin', 'renal', NULL, NULL, NULL, 'HD', NULL, 300, 24, 300, FALSE,
+ '100-300 mg q24h', 'Give a 125-350 mg supplemental dose after each 4-hour dialysis session.', 'Neurontin prescribing information'),
+-- Cefepime
+('RxCUI:20481', 'Cefepime', 'renal', 'crcl', 30, 60, NULL, NULL, 2000, 12, 4000, FALSE,
+ '2 g q12h', 'Accumulation causes encephalopathy and seizures.', 'Maxipime prescribing information'),
+('RxCUI:20481', 'Cefepime', 'renal', 'crcl', 11, 30, NULL, NULL, 2000, 24, 2000, FALSE,
+ '2 g q24h', 'Accumulation causes encephalopathy and seizures.', 'Maxipime prescribing information'),
+('RxCUI:20481', 'Cefepime', 'renal', 'crcl', NULL, 11, NULL, NULL, 1000, 24, 1000, FALSE,
+ '1 g q24h', 'Accumulation causes encephalopathy and seizures.', 'Maxipime prescribing information'),
+('RxCUI:20481', 'Cefepime', 'renal', NULL, NULL, NULL, 'HD', NULL, 1000, 24, 1000, FALSE,
+ '1 g q24h', 'Dose after dialysis on dialysis days.', 'Maxipime prescribing information'),
+('RxCUI:20481', 'Cefepime', 'renal', NULL, NULL, NULL, 'PD', NULL, 2000, 48, 1000, FALSE,
+ '1-2 g q48h', NULL, 'Maxipime prescribing information'),
+('RxCUI:20481', 'Cefepime', 'renal', NULL, NULL, NULL, 'CRRT', NULL, 2000, 12, 4000, FALSE,
+ '2 g q12h', 'CRRT clearance depends on effluent rate.', 'Sanford Guide'),
+-- Meropenem
+('RxCUI:29561', 'Meropenem', 'renal', 'crcl', 26, 51, NULL, NULL, 1000, 12, 2000, FALSE,
+ '1 g q12h', NULL, 'Merrem prescribing information'),
+('RxCUI:29561', 'Meropenem', 'renal', 'crcl', 10, 26, NULL, NULL, 500, 12, 1000, FALSE,
+ '500 mg q12h', NULL, 'Merrem prescribing information'),
+('RxCUI:29561', 'Meropenem', 'renal', 'crcl', NULL, 10, NULL, NULL, 500, 24, 500, FALSE,
+ '500 mg q24h', NULL, 'Merrem prescribing information'),
+('RxCUI:29561', 'Meropenem', 'renal', NULL, NULL, NULL, 'HD', NULL, 500, 24, 500, FALSE,
+ '500 mg q24h', 'Dose after dialysis on dialysis days.', 'Sanford Guide'),
+('RxCUI:29561', 'Meropenem', 'renal', NULL, NULL, NULL, 'CRRT', NULL, 1000, 12, 2000, FALSE,
+ '1 g q12h', NULL, 'Sanford Guide'),
+-- Ciprofloxacin (oral)
+('RxCUI:2551', 'Ciprofloxacin', 'renal', 'crcl', 30, 50, NULL, NULL, 500, 12, 1000, FALSE,
+ '250-500 mg q12h', NULL, 'Cipro prescribing information'),
+('RxCUI:2551', 'Ciprofloxacin', 'renal', 'crcl', NULL, 30, NULL, NULL, 500, 18, 667, FALSE,
+ '250-500 mg q18h', NULL, 'Cipro prescribing information'),
+('RxCUI:2551', 'Ciprofloxacin', 'renal', NULL, NULL, NULL, 'HD', NULL, 500, 24, 500, FALSE,
+ '250-500 mg q24h', 'Dose after dialysis.', 'Cipro prescribing information'),
+('RxCUI:2551', 'Ciprofloxacin', 'renal', NULL, NULL, NULL, 'PD', NULL, 500, 24, 500, FALSE,
+ '250-500 mg q24h', NULL, 'Cipro prescribing information'),
+-- Valacyclovir (herpes zoster)
+('RxCUI:73645', 'Valacyclovir', 'renal', 'crcl', 30, 50, NULL, NULL, 1000, 12, 2000, FALSE,
+ '1 g q12h', NULL, 'Valtrex prescribing information'),
+('RxCUI:73645', 'Valacyclovir', 'renal', 'crcl', 10, 30, NULL, NULL, 1000, 24, 1000, FALSE,
+ '1 g q24h', NULL, 'Valtrex prescribing information'),
+('RxCUI:73645', 'Valacyclovir', 'renal', 'crcl', NULL, 10, NULL, NULL, 500, 24, 500, FALSE,
+ '500 mg q24h', 'Neurotoxicity risk in renal failure.', 'Valtrex prescribing information'),
+('RxCUI:73645', 'Valacyclovir', 'renal', NULL, NULL, NULL, 'HD', NULL, 500, 24, 500, FALSE,
+ '500 mg q24h', 'Dose after dialysis.', 'Valtrex prescribing information'),
+-- Famotidine
+('RxCUI:4278', 'Famotidine', 'renal', 'crcl', NULL, 60, NULL, NULL, 20, 24, 20, FALSE,
+ '20 mg q24h', 'CNS adverse effects (confusion, delirium) accumulate in renal impairment.', 'Pepcid prescribing information'),
+-- Allopurinol
+('RxCUI:519', 'Allopurinol', 'renal', 'crcl', 10, 20, NULL, NULL, 200, 24, 200, FALSE,
+ '200 mg q24h', 'Titrate to urate target; higher doses need close monitoring.', 'Zyloprim prescribing information'),
+('RxCUI:519', 'Allopurinol', 'renal', 'crcl', NULL, 10, NULL, NULL, 100, 24, 100, FALSE,
+ '100 mg q24h or less often', NULL, 'Zyloprim prescribing information'),
+-- Metformin (eGFR based)
+('RxCUI:6809', 'Metformin', 'renal', 'egfr', 30, 45, NULL, NULL, 1000, 12, 1000, FALSE,
+ 'Maximum 1000 mg/day', 'Do not start metformin at eGFR 30-45; reassess benefit if already taking.', 'FDA Drug Safety Communication 2016'),
+('RxCUI:6809', 'Metformin', 'renal', 'egfr', NULL, 30, NULL, NULL, NULL, NULL, NULL, TRUE,
+ 'Contraindicated', 'Risk of lactic acidosis.', 'FDA Drug Safety Communication 2016'),
+('RxCUI:6809', 'Metformin', 'renal', NULL, NULL, NULL, 'HD', NULL, NULL, NULL, NULL, TRUE,
+ 'Contraindicated', 'Risk of lactic acidosis.', 'FDA Drug Safety Communication 2016'),
+-- Nitrofurantoin
+('RxCUI:7454', 'Nitrofurantoin', 'renal', 'crcl', NULL, 30, NULL, NULL, NULL, NULL, NULL, TRUE,
+ 'Avoid', 'Ineffective urinary concentrations and increased toxicity.', 'AGS Beers Criteria 2023'),
+-- Dabigatran (nonvalvular AF)
+('RxCUI:1037042', 'Dabigatran', 'renal', 'crcl', 15, 30, NULL, NULL, 75, 12, 150, FALSE,
+ '75 mg q12h', NULL, 'Pradaxa prescribing information'),
+('RxCUI:1037042', 'Dabigatran', 'renal', 'crcl', NULL, 15, NULL, NULL, NULL, NULL, NULL, TRUE,
+ 'Avoid', 'Accumulation with major bleeding risk.', 'Pradaxa prescribing information'),
+('RxCUI:1037042', 'Dabigatran', 'renal', NULL, NULL, NULL, 'HD', NULL, NULL, NULL, NULL, TRUE,
+ 'Avoid', 'Accumulation with major bleeding risk.', 'Pradaxa prescribing information'),
+-- Rivaroxaban (nonvalvular AF)
+('RxCUI:1114195', 'Rivaroxaban', 'renal', 'crcl', 15, 51, NULL, NULL, 15, 24, 15, FALSE,
+ '15 mg q24h with the evening meal', NULL, 'Xarelto prescribing information'),
+('RxCUI:1114195', 'Rivaroxaban', 'renal', 'crcl', NULL, 15, NULL, NULL, NULL, NULL, NULL, TRUE,
+ 'Avoid', NULL, 'Xarelto prescribing information'),
+('RxCUI:1114195', 'Rivaroxaban', 'hepatic', NULL, NULL, NULL, NULL, 'B', NULL, NULL, NULL, TRUE,
+ 'Avoid', 'Increased exposure and bleeding risk with coagulopathy.', 'Xarelto prescribing information'),
+('RxCUI:1114195', 'Rivaroxaban', 'hepatic', NULL, NULL, NULL, NULL, 'C', NULL, NULL, NULL, TRUE,
+ 'Avoid', 'Increased exposure and bleeding risk with coagulopathy.', 'Xarelto prescribing information'),
+-- Tramadol
+('RxCUI:10689', 'Tramadol', 'renal', 'crcl', NULL, 30, NULL, NULL, 100, 12, 200, FALSE,
+ '50-100 mg q12h (max 200 mg/day)', 'Do not use extended-release formulations.', 'Ultram prescribing information'),
+('RxCUI:10689', 'Tramadol', 'hepatic', NULL, NULL, NULL, NULL, 'C', 50, 12, 100, FALSE,
+ '50 mg q12h', 'Do not use extended-release formulations.', 'Ultram prescribing information'),
+-- Duloxetine
+('RxCUI:72625', 'Duloxetine', 'renal', 'crcl', NULL, 30, NULL, NULL, NULL, NULL, NULL, TRUE,
+ 'Avoid', NULL, 'Cymbalta prescribing information'),
+('RxCUI:72625', 'Duloxetine', 'hepatic', NULL, NULL, NULL, NULL, 'A', NULL, NULL, NULL, TRUE,
+ 'Avoid', 'Hepatotoxicity; avoid in any chronic liver disease.', 'Cymbalta prescribing information'),
+('RxCUI:72625', 'Duloxetine', 'hepatic', NULL, NULL, NULL, NULL, 'B', NULL, NULL, NULL, TRUE,
+ 'Avoid', 'Hepatotoxicity; avoid in any chronic liver disease.', 'Cymbalta prescribing information'),
+('RxCUI:72625', 'Duloxetine', 'hepatic', NULL, NULL, NULL, NULL, 'C', NULL, NULL, NULL, TRUE,
+ 'Avoid', 'Hepatotoxicity; avoid in any chronic liver disease.', 'Cymbalta prescribing information'),
+-- Metronidazole
+('RxCUI:6922', 'Metronidazole', 'hepatic', NULL, NULL, NULL, NULL, 'C', 500, 12, 1000, FALSE,
+ '500 mg q12h (50% dose reduction)', NULL, 'Flagyl prescribing information');
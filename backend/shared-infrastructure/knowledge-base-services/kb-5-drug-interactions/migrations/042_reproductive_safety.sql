@@ -0,0 +1,199 @@
+-- =============================================================================
+-- Migration 042: Pregnancy and Lactation Safety Rules
+-- =============================================================================
+-- Pregnancy was only reachable as a comorbidity code, with no gestational age
+-- and no lactation status. Each row gives the risk of a drug in one context:
+--   * pregnancy rows: a gestational window [week_start, week_end) in completed
+--     weeks (NULL week_end = until delivery), so risk can differ by trimester
+--     (NSAIDs from 20 weeks, warfarin embryopathy in weeks 6-12)
+--   * lactation rows: the infant's exposure through breast milk, with the
+--     relative infant dose (RID, % of weight-adjusted maternal dose) when known
+--
+-- governance_action is the minimum governance action for a match, as for
+-- pgx_safety_rules; NULL leaves the action to the institution's severity policy.
+-- alternatives are safer options for the same indication.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS reproductive_safety_rules (
+    id SERIAL PRIMARY KEY,
+    drug_code VARCHAR(50) NOT NULL,
+    drug_name VARCHAR(200) NOT NULL,
+    context VARCHAR(20) NOT NULL CHECK (context IN ('pregnancy', 'lactation')),
+    week_start INT NOT NULL DEFAULT 0,      -- pregnancy: completed weeks, inclusive
+    week_end INT,                           -- pregnancy: completed weeks, exclusive
+    infant_exposure VARCHAR(20) CHECK (infant_exposure IN ('minimal', 'low', 'moderate', 'high')),
+    relative_infant_dose NUMERIC(5,1),      -- lactation: RID %
+
+    severity VARCHAR(20) NOT NULL CHECK (severity IN ('contraindicated', 'major', 'moderate', 'minor')),
+    governance_action VARCHAR(40)
+        CHECK (governance_action IN ('hard_block', 'hard_block_governance_override',
+                                     'mandatory_escalation', 'warn_acknowledge')),
+    risk_summary TEXT NOT NULL,
+    recommendation TEXT NOT NULL,
+    alternatives TEXT[],
+    evidence VARCHAR(20) NOT NULL DEFAULT 'B',
+    source VARCHAR(200) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CHECK ((context = 'pregnancy' AND infant_exposure IS NULL AND relative_infant_dose IS NULL) OR
+           (context = 'lactation' AND infant_exposure IS NOT NULL AND week_start = 0 AND week_end IS NULL)),
+    CHECK (week_end IS NULL OR week_end > week_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reproductive_safety_rules_drug ON reproductive_safety_rules(drug_code) WHERE active;
+
+COMMENT ON TABLE reproductive_safety_rules IS 'Gestational-age-specific pregnancy risk and breast milk infant exposure, with safer alternatives.';
+
+-- =============================================================================
+-- Seed: pregnancy
+-- =============================================================================
+
+INSERT INTO reproductive_safety_rules (drug_code, drug_name, context, week_start, week_end, severity, governance_action,
+                                       risk_summary, recommendation, alternatives, evidence, source) VALUES
+-- NSAIDs: oligohydramnios and fetal renal dysfunction from 20 weeks, ductus closure from 30 weeks
+('RxCUI:5640', 'Ibuprofen', 'pregnancy', 20, 30, 'major', 'mandatory_escalation',
+ 'Fetal renal dysfunction and oligohydramnios with use from 20 weeks',
+ 'Avoid NSAIDs from 20 weeks. If unavoidable, use the lowest dose for no more than 48 hours and monitor amniotic fluid.',
+ ARRAY['Acetaminophen'], 'A', 'FDA NSAID pregnancy labeling 2020'),
+('RxCUI:5640', 'Ibuprofen', 'pregnancy', 30, NULL, 'contraindicated', 'hard_block_governance_override',
+ 'Premature closure of the fetal ductus arteriosus and oligohydramnios',
+ 'Do not use NSAIDs from 30 weeks of gestation.',
+ ARRAY['Acetaminophen'], 'A', 'FDA NSAID pregnancy labeling 2020'),
+('RxCUI:7258', 'Naproxen', 'pregnancy', 20, 30, 'major', 'mandatory_escalation',
+ 'Fetal renal dysfunction and oligohydramnios with use from 20 weeks',
+ 'Avoid NSAIDs from 20 weeks. If unavoidable, use the lowest dose for no more than 48 hours and monitor amniotic fluid.',
+ ARRAY['Acetaminophen'], 'A', 'FDA NSAID pregnancy labeling 2020'),
+('RxCUI:7258', 'Naproxen', 'pregnancy', 30, NULL, 'contraindicated', 'hard_block_governance_override',
+ 'Premature closure of the fetal ductus arteriosus and oligohydramnios',
+ 'Do not use NSAIDs from 30 weeks of gestation.',
+ ARRAY['Acetaminophen'], 'A', 'FDA NSAID pregnancy labeling 2020'),
+('RxCUI:3355', 'Diclofenac', 'pregnancy', 20, 30, 'major', 'mandatory_escalation',
+ 'Fetal renal dysfunction and oligohydramnios with use from 20 weeks',
+ 'Avoid NSAIDs from 20 weeks. If unavoidable, use the lowest dose for no more than 48 hours and monitor amniotic fluid.',
+ ARRAY['Acetaminophen'], 'A', 'FDA NSAID pregnancy labeling 2020'),
+('RxCUI:3355', 'Diclofenac', 'pregnancy', 30, NULL, 'contraindicated', 'hard_block_governance_override',
+ 'Premature closure of the fetal ductus arteriosus and oligohydramnios',
+ 'Do not use NSAIDs from 30 weeks of gestation.',
+ ARRAY['Acetaminophen'], 'A', 'FDA NSAID pregnancy labeling 2020'),
+('RxCUI:140587', 'Celecoxib', 'pregnancy', 20, 30, 'major', 'mandatory_escalation',
+ 'Fetal renal dysfunction and oligohydramnios with use from 20 weeks',
+ 'Avoid NSAIDs from 20 weeks. If unavoidable, use the lowest dose for no more than 48 hours and monitor amniotic fluid.',
+ ARRAY['Acetaminophen'], 'A', 'FDA NSAID pregnancy labeling 2020'),
+('RxCUI:140587', 'Celecoxib', 'pregnancy', 30, NULL, 'contraindicated', 'hard_block_governance_override',
+ 'Premature closure of the fetal ductus arteriosus and oligohydramnios',
+ 'Do not use NSAIDs from 30 weeks of gestation.',
+ ARRAY['Acetaminophen'], 'A', 'FDA NSAID pregnancy labeling 2020'),
+
+-- Warfarin: embryopathy in weeks 6-12, fetal hemorrhage later in pregnancy
+('RxCUI:11289', 'Warfarin', 'pregnancy', 0, 6, 'major', 'mandatory_escalation',
+ 'Warfarin crosses the placenta; exposure continuing past 6 weeks risks embryopathy',
+ 'Switch to low molecular weight heparin before 6 weeks of gestation.',
+ ARRAY['Enoxaparin', 'Unfractionated heparin'], 'A', 'ACOG Practice Bulletin 196'),
+('RxCUI:11289', 'Warfarin', 'pregnancy', 6, 13, 'contraindicated', 'hard_block_governance_override',
+ 'Warfarin embryopathy (nasal hypoplasia, stippled epiphyses) with exposure in weeks 6-12',
+ 'Do not use warfarin in the first trimester. Use low molecular weight heparin; mechanical valves require specialist review.',
+ ARRAY['Enoxaparin', 'Unfractionated heparin'], 'A', 'ACOG Practice Bulletin 196'),
+('RxCUI:11289', 'Warfarin', 'pregnancy', 13, NULL, 'major', 'mandatory_escalation',
+ 'Fetal intracranial hemorrhage and CNS abnormalities; bleeding at delivery',
+ 'Reserve for mechanical heart valves under specialist care and switch to heparin by 36 weeks.',
+ ARRAY['Enoxaparin', 'Unfractionated heparin'], 'A', 'ACOG Practice Bulletin 196'),
+
+-- Renin-angiotensin system blockers: fetotoxicity in the second and third trimesters
+('RXCUI:29046', 'Lisinopril', 'pregnancy', 0, 14, 'major', 'mandatory_escalation',
+ 'First-trimester exposure is not clearly teratogenic, but fetotoxicity follows if continued',
+ 'Stop as soon as pregnancy is detected and switch to a pregnancy-compatible antihypertensive.',
+ ARRAY['Labetalol', 'Nifedipine', 'Methyldopa'], 'A', 'FDA boxed warning; ACOG Practice Bulletin 203'),
+('RXCUI:29046', 'Lisinopril', 'pregnancy', 14, NULL, 'contraindicated', 'hard_block',
+ 'Fetal renal failure, oligohydramnios, skull hypoplasia and fetal death',
+ 'Do not use in the second or third trimester.',
+ ARRAY['Labetalol', 'Nifedipine', 'Methyldopa'], 'A', 'FDA boxed warning; ACOG Practice Bulletin 203'),
+('RxCUI:3827', 'Enalapril', 'pregnancy', 0, 14, 'major', 'mandatory_escalation',
+ 'First-trimester exposure is not clearly teratogenic, but fetotoxicity follows if continued',
+ 'Stop as soon as pregnancy is detected and switch to a pregnancy-compatible antihypertensive.',
+ ARRAY['Labetalol', 'Nifedipine', 'Methyldopa'], 'A', 'FDA boxed warning; ACOG Practice Bulletin 203'),
+('RxCUI:3827', 'Enalapril', 'pregnancy', 14, NULL, 'contraindicated', 'hard_block',
+ 'Fetal renal failure, oligohydramnios, skull hypoplasia and fetal death',
+ 'Do not use in the second or third trimester.',
+ ARRAY['Labetalol', 'Nifedipine', 'Methyldopa'], 'A', 'FDA boxed warning; ACOG Practice Bulletin 203'),
+('RXCUI:52175', 'Losartan', 'pregnancy', 0, 14, 'major', 'mandatory_escalation',
+ 'First-trimester exposure is not clearly teratogenic, but fetotoxicity follows if continued',
+ 'Stop as soon as pregnancy is detected and switch to a pregnancy-compatible antihypertensive.',
+ ARRAY['Labetalol', 'Nifedipine', 'Methyldopa'], 'A', 'FDA boxed warning; ACOG Practice Bulletin 203'),
+('RXCUI:52175', 'Losartan', 'pregnancy', 14, NULL, 'contraindicated', 'hard_block',
+ 'Fetal renal failure, oligohydramnios, skull hypoplasia and fetal death',
+ 'Do not use in the second or third trimester.',
+ ARRAY['Labetalol', 'Nifedipine', 'Methyldopa'], 'A', 'FDA boxed warning; ACOG Practice Bulletin 203'),
+
+-- Teratogens contraindicated throughout pregnancy
+('RxCUI:6064', 'Isotretinoin', 'pregnancy', 0, NULL, 'contraindicated', 'hard_block',
+ 'Severe craniofacial, cardiac and CNS malformations; high miscarriage risk',
+ 'Do not use in pregnancy. Dispensing requires a negative pregnancy test under the iPLEDGE REMS.',
+ NULL, 'A', 'iPLEDGE REMS; FDA labeling'),
+('RxCUI:11118', 'Valproic acid', 'pregnancy', 0, NULL, 'contraindicated', 'hard_block_governance_override',
+ 'Neural tube defects and dose-dependent neurodevelopmental impairment',
+ 'Avoid in pregnancy unless no alternative controls seizures; requires neurology review and high-dose folic acid.',
+ ARRAY['Lamotrigine', 'Levetiracetam'], 'A', 'FDA boxed warning; MHRA valproate pregnancy prevention programme'),
+('RxCUI:6851', 'Methotrexate', 'pregnancy', 0, NULL, 'contraindicated', 'hard_block',
+ 'Embryo-fetal death and methotrexate embryopathy',
+ 'Do not use for non-oncologic indications in pregnancy.',
+ ARRAY['Hydroxychloroquine', 'Sulfasalazine'], 'A', 'FDA labeling'),
+('RxCUI:42331', 'Misoprostol', 'pregnancy', 0, NULL, 'contraindicated', 'hard_block_governance_override',
+ 'Uterine contractions causing miscarriage or preterm labor; Mobius sequence with first-trimester exposure',
+ 'Do not use for NSAID ulcer prophylaxis in pregnancy. Obstetric use only under obstetric protocols.',
+ ARRAY['Omeprazole', 'Famotidine'], 'A', 'FDA boxed warning'),
+('RxCUI:68149', 'Mycophenolate mofetil', 'pregnancy', 0, NULL, 'contraindicated', 'hard_block_governance_override',
+ 'First-trimester pregnancy loss and congenital malformations (ear, facial, cardiac)',
+ 'Avoid in pregnancy; transplant recipients require specialist review to switch immunosuppression.',
+ ARRAY['Azathioprine', 'Tacrolimus'], 'A', 'FDA boxed warning; Mycophenolate REMS'),
+
+-- Lithium: cardiac malformation (Ebstein anomaly) with first-trimester exposure
+('RxCUI:6448', 'Lithium', 'pregnancy', 0, 13, 'major', 'mandatory_escalation',
+ 'Small absolute increase in cardiac malformations including Ebstein anomaly',
+ 'Weigh relapse risk with a psychiatrist; if continued, arrange fetal echocardiography and monitor levels closely.',
+ ARRAY['Lamotrigine', 'Quetiapine'], 'B', 'ACOG Clinical Practice Guideline 5'),
+('RxCUI:6448', 'Lithium', 'pregnancy', 13, NULL, 'moderate', NULL,
+ 'Changing clearance alters levels through pregnancy; neonatal toxicity if levels are high at delivery',
+ 'Check lithium levels every 4 weeks, then weekly from 36 weeks; consider a dose reduction around delivery.',
+ NULL, 'B', 'ACOG Clinical Practice Guideline 5'),
+
+-- Tetracyclines: tooth discoloration after calcification begins
+('RxCUI:3640', 'Doxycycline', 'pregnancy', 15, NULL, 'major', 'warn_acknowledge',
+ 'Permanent tooth discoloration and inhibited bone growth with exposure after 15 weeks',
+ 'Use an alternative antibiotic after 15 weeks unless no alternative is suitable.',
+ ARRAY['Amoxicillin', 'Azithromycin'], 'B', 'FDA labeling');
+
+-- =============================================================================
+-- Seed: lactation
+-- =============================================================================
+
+INSERT INTO reproductive_safety_rules (drug_code, drug_name, context, infant_exposure, relative_infant_dose, severity,
+                                       governance_action, risk_summary, recommendation, alternatives, evidence, source) VALUES
+('RxCUI:2670', 'Codeine', 'lactation', 'high', 8.1, 'contraindicated', 'hard_block_governance_override',
+ 'Maternal CYP2D6 ultrarapid metabolism can cause infant morphine toxicity, including death',
+ 'Do not use while breastfeeding.',
+ ARRAY['Acetaminophen', 'Ibuprofen'], 'A', 'FDA boxed warning 2017; LactMed'),
+('RxCUI:10689', 'Tramadol', 'lactation', 'moderate', 2.9, 'major', 'mandatory_escalation',
+ 'Infant respiratory depression; tramadol and its active metabolite pass into milk',
+ 'Avoid while breastfeeding; if used, give the lowest dose briefly and watch the infant for sedation.',
+ ARRAY['Acetaminophen', 'Ibuprofen'], 'B', 'FDA labeling 2017; LactMed'),
+('RxCUI:703', 'Amiodarone', 'lactation', 'high', 43.1, 'contraindicated', 'hard_block_governance_override',
+ 'High milk transfer with iodine load; risk of infant hypothyroidism and bradycardia',
+ 'Avoid breastfeeding during therapy and for several months after, given the long half-life.',
+ NULL, 'B', 'LactMed'),
+('RxCUI:6448', 'Lithium', 'lactation', 'high', 30.0, 'major', 'mandatory_escalation',
+ 'Infant serum levels up to half of maternal levels; infant toxicity reported',
+ 'Breastfeeding only with pediatric agreement and monitoring of infant lithium level, TSH and creatinine.',
+ ARRAY['Sertraline', 'Quetiapine'], 'B', 'LactMed'),
+('RxCUI:6851', 'Methotrexate', 'lactation', 'moderate', NULL, 'major', 'mandatory_escalation',
+ 'Accumulation in infant tissues with possible immunosuppression at antineoplastic doses',
+ 'Avoid breastfeeding with antineoplastic doses; low weekly doses require specialist review and a pause after each dose.',
+ ARRAY['Hydroxychloroquine', 'Sulfasalazine'], 'B', 'LactMed'),
+('RXCUI:36437', 'Sertraline', 'lactation', 'minimal', 0.5, 'minor', NULL,
+ 'Undetectable to low infant serum levels; preferred antidepressant in breastfeeding',
+ 'Compatible with breastfeeding; no infant monitoring beyond routine care.',
+ NULL, 'A', 'LactMed'),
+('RXCUI:5640', 'Ibuprofen', 'lactation', 'minimal', 0.6, 'minor', NULL,
+ 'Very low milk transfer with a short half-life',
+ 'Compatible with breastfeeding; preferred analgesic.',
+ NULL, 'A', 'LactMed');
@@ -0,0 +1,311 @@
+-- =============================================================================
+-- Migration 043: Potentially Inappropriate Medication (PIM) Criteria
+-- =============================================================================
+-- An older_adult age band only escalated governance. These tables hold explicit
+-- criteria (AGS Beers, STOPP) so the regimen and the patient's conditions can be
+-- screened the way geriatric pharmacists do by hand.
+--
+-- Criteria are versioned: pim_criteria_sets holds one row per published version,
+-- and is_current marks the version used when a request does not name one.
+-- A criterion matches a regimen drug by RxCUI (drug_codes) or ATC class at any
+-- level (atc_classes). Every populated condition must then hold:
+--   * condition_codes         - the patient has one of these ICD-10 diagnoses
+--                               (descendant codes match: I50 covers I50.22)
+--   * exception_condition_codes - the criterion does not apply with these
+--   * interacting_*           - another regimen drug matches this set
+--   * renal_metric/threshold  - patient CrCl or eGFR is below the threshold
+-- criterion_type labels the Beers/STOPP table the criterion comes from.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS pim_criteria_sets (
+    id SERIAL PRIMARY KEY,
+    set_code VARCHAR(20) NOT NULL,              -- 'BEERS', 'STOPP'
+    version VARCHAR(20) NOT NULL,               -- '2023', 'v3'
+    title VARCHAR(200) NOT NULL,
+    published_date DATE NOT NULL,
+    min_age INT NOT NULL DEFAULT 65,
+    is_current BOOLEAN NOT NULL DEFAULT TRUE,
+    source TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (set_code, version)
+);
+
+-- Only one current version per criteria set
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pim_criteria_sets_current
+    ON pim_criteria_sets(set_code) WHERE is_current;
+
+CREATE TABLE IF NOT EXISTS pim_criteria (
+    id SERIAL PRIMARY KEY,
+    set_code VARCHAR(20) NOT NULL,
+    version VARCHAR(20) NOT NULL,
+    criterion_code VARCHAR(50) NOT NULL,
+    criterion_type VARCHAR(20) NOT NULL
+        CHECK (criterion_type IN ('avoid', 'drug_disease', 'drug_drug', 'renal')),
+
+    drug_description VARCHAR(200) NOT NULL,     -- 'Benzodiazepines'
+    drug_codes TEXT[],
+    atc_classes TEXT[],
+    condition_description VARCHAR(200),
+    condition_codes TEXT[],                     -- ICD-10
+    exception_condition_codes TEXT[],           -- ICD-10
+    interacting_description VARCHAR(200),
+    interacting_drug_codes TEXT[],
+    interacting_atc_classes TEXT[],
+    renal_metric VARCHAR(10) CHECK (renal_metric IN ('crcl', 'egfr')),
+    renal_threshold NUMERIC(6,1),               -- Applies below this value, mL/min
+
+    severity VARCHAR(20) NOT NULL CHECK (severity IN ('contraindicated', 'major', 'moderate', 'minor')),
+    rationale TEXT NOT NULL,
+    recommendation TEXT NOT NULL,
+    alternatives TEXT[],
+    quality_of_evidence VARCHAR(20) CHECK (quality_of_evidence IN ('high', 'moderate', 'low')),
+    strength_of_recommendation VARCHAR(20) CHECK (strength_of_recommendation IN ('strong', 'weak')),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (set_code, version) REFERENCES pim_criteria_sets(set_code, version),
+    UNIQUE (set_code, version, criterion_code),
+    CHECK (drug_codes IS NOT NULL OR atc_classes IS NOT NULL),
+    CHECK ((renal_metric IS NULL) = (renal_threshold IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_pim_criteria_set ON pim_criteria(set_code, version) WHERE active;
+
+COMMENT ON TABLE pim_criteria_sets IS 'Published versions of explicit PIM criteria (AGS Beers, STOPP).';
+COMMENT ON TABLE pim_criteria IS 'Drugs to avoid, drug-disease, drug-drug and renal criteria for older adults.';
+
+-- =============================================================================
+-- Seed: criteria set versions
+-- =============================================================================
+
+INSERT INTO pim_criteria_sets (set_code, version, title, published_date, min_age, is_current, source) VALUES
+('BEERS', '2023', 'AGS Beers Criteria 2023', '2023-05-04', 65, TRUE,
+ 'American Geriatrics Society 2023 Updated AGS Beers Criteria. J Am Geriatr Soc. 2023;71(7):2052-2081'),
+('STOPP', 'v3', 'STOPP/START version 3', '2023-03-07', 65, TRUE,
+ 'O''Mahony D, et al. STOPP/START criteria version 3. Eur Geriatr Med. 2023;14(4):625-632')
+ON CONFLICT (set_code, version) DO NOTHING;
+
+-- =============================================================================
+-- Seed: AGS Beers 2023
+-- =============================================================================
+
+INSERT INTO pim_criteria (set_code, version, criterion_code, criterion_type, drug_description, drug_codes, atc_classes,
+                          condition_description, condition_codes, exception_condition_codes,
+                          interacting_description, interacting_drug_codes, interacting_atc_classes,
+                          renal_metric, renal_threshold, severity, rationale, recommendation, alternatives,
+                          quality_of_evidence, strength_of_recommendation) VALUES
+-- Table 2: drugs to avoid
+('BEERS', '2023', 'BEERS-T2-ANTIHISTAMINE', 'avoid', 'First-generation antihistamines',
+ ARRAY['RxCUI:3498', 'RxCUI:5553', 'RxCUI:2400', 'RxCUI:8745'], ARRAY['R06AA', 'R06AD'],
+ NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Highly anticholinergic; clearance reduced with age; confusion, dry mouth, constipation',
+ 'Avoid. Use a second-generation antihistamine for allergy.', ARRAY['Loratadine', 'Cetirizine'], 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T2-BENZODIAZEPINE', 'avoid', 'Benzodiazepines',
+ ARRAY['RxCUI:596', 'RxCUI:6470', 'RxCUI:3322', 'RxCUI:2598', 'RxCUI:10355'], ARRAY['N05BA', 'N05CD'],
+ NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Increased sensitivity and slower metabolism; cognitive impairment, delirium, falls, fractures and motor vehicle crashes',
+ 'Avoid. Taper gradually if long-term use; consider CBT for insomnia or an SSRI for anxiety.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T2-Z-DRUG', 'avoid', 'Nonbenzodiazepine hypnotics (Z-drugs)',
+ ARRAY['RxCUI:39993', 'RxCUI:74667', 'RxCUI:461016'], ARRAY['N05CF'],
+ NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Adverse events similar to benzodiazepines (delirium, falls, fractures) with minimal improvement in sleep',
+ 'Avoid. Use cognitive behavioral therapy for insomnia.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T2-SULFONYLUREA', 'avoid', 'Sulfonylureas',
+ ARRAY['RxCUI:4815', 'RxCUI:25789', 'RxCUI:4821'], ARRAY['A10BB'],
+ NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, 'moderate',
+ 'Higher risk of cardiovascular events, all-cause mortality and prolonged hypoglycemia than alternatives',
+ 'Avoid as first- or second-line therapy. If used, prefer a short-acting agent such as glipizide.',
+ ARRAY['Metformin', 'DPP-4 inhibitor', 'SGLT2 inhibitor', 'GLP-1 receptor agonist'], 'high', 'strong'),
+('BEERS', '2023', 'BEERS-T2-MUSCLE-RELAXANT', 'avoid', 'Skeletal muscle relaxants',
+ ARRAY['RxCUI:21949', 'RxCUI:6845', 'RxCUI:2101', 'RxCUI:7715'], NULL,
+ NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Anticholinergic adverse effects, sedation and fracture risk; effectiveness at tolerated doses is questionable',
+ 'Avoid.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T2-TERTIARY-TCA', 'avoid', 'Tertiary-amine tricyclic antidepressants',
+ ARRAY['RxCUI:704', 'RxCUI:5691', 'RxCUI:3638', 'RxCUI:2597'], NULL,
+ NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Highly anticholinergic and sedating; orthostatic hypotension',
+ 'Avoid. Consider nortriptyline or an SSRI if an antidepressant is needed.', ARRAY['Nortriptyline', 'Sertraline'], 'high', 'strong'),
+('BEERS', '2023', 'BEERS-T2-MEPERIDINE', 'avoid', 'Meperidine',
+ ARRAY['RxCUI:6754'], NULL,
+ NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Not effective orally at commonly used doses; neurotoxic metabolite may cause delirium',
+ 'Avoid, especially in chronic kidney disease.', ARRAY['Morphine', 'Hydromorphone'], 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T2-MEGESTROL', 'avoid', 'Megestrol',
+ ARRAY['RxCUI:6691'], NULL,
+ NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, 'moderate',
+ 'Minimal effect on weight; increased risk of thrombotic events and possibly death',
+ 'Avoid.', NULL, 'moderate', 'strong'),
+
+-- Table 3: drug-disease interactions
+('BEERS', '2023', 'BEERS-T3-HF-NSAID', 'drug_disease', 'NSAIDs and COX-2 inhibitors',
+ ARRAY['RxCUI:5640', 'RxCUI:7258', 'RxCUI:3355', 'RxCUI:140587'], ARRAY['M01A'],
+ 'Heart failure', ARRAY['I50', 'I110', 'I130', 'I132'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Fluid retention and exacerbation of heart failure',
+ 'Avoid in symptomatic heart failure.', ARRAY['Acetaminophen'], 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T3-HF-NONDHP-CCB', 'drug_disease', 'Nondihydropyridine calcium channel blockers',
+ ARRAY['RxCUI:3443', 'RxCUI:11170'], ARRAY['C08D'],
+ 'Heart failure with reduced ejection fraction', ARRAY['I502', 'I504'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Negative inotropy; fluid retention and worsening heart failure',
+ 'Avoid in heart failure with reduced ejection fraction.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T3-HF-TZD', 'drug_disease', 'Thiazolidinediones',
+ ARRAY['RxCUI:33738', 'RxCUI:84108'], ARRAY['A10BG'],
+ 'Heart failure', ARRAY['I50'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Fluid retention and exacerbation of heart failure',
+ 'Avoid in symptomatic heart failure.', ARRAY['SGLT2 inhibitor'], 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T3-FALLS-CNS', 'drug_disease', 'Benzodiazepines, Z-drugs and antipsychotics',
+ ARRAY['RxCUI:596', 'RxCUI:6470', 'RxCUI:3322', 'RxCUI:39993', 'RxCUI:35636', 'RxCUI:51272', 'RxCUI:5093'],
+ ARRAY['N05BA', 'N05CD', 'N05CF', 'N05A'],
+ 'History of falls or fractures', ARRAY['Z9181', 'W19', 'S72', 'S32', 'S42', 'S52'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Ataxia, impaired psychomotor function, syncope and additional falls',
+ 'Avoid unless safer alternatives are not available.', NULL, 'high', 'strong'),
+('BEERS', '2023', 'BEERS-T3-DEMENTIA-ANTIPSYCHOTIC', 'drug_disease', 'Antipsychotics',
+ ARRAY['RxCUI:35636', 'RxCUI:51272', 'RxCUI:5093', 'RxCUI:61381', 'RxCUI:89013'], ARRAY['N05A'],
+ 'Dementia or cognitive impairment', ARRAY['F01', 'F02', 'F03', 'G30', 'G31'], ARRAY['F20', 'F25', 'F31'],
+ NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Increased risk of stroke, cognitive decline and mortality in people with dementia',
+ 'Avoid for behavioral problems of dementia unless nonpharmacologic options have failed and the patient threatens harm.',
+ NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T3-DEMENTIA-ANTICHOLINERGIC', 'drug_disease', 'Strongly anticholinergic drugs',
+ ARRAY['RxCUI:3498', 'RxCUI:5553', 'RxCUI:704', 'RxCUI:32675', 'RxCUI:8745'], ARRAY['R06AA', 'G04BD'],
+ 'Dementia or cognitive impairment', ARRAY['F01', 'F02', 'F03', 'G30', 'G31'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Adverse CNS effects and worsening cognition',
+ 'Avoid.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T3-DELIRIUM', 'drug_disease', 'Anticholinergics, benzodiazepines, Z-drugs and opioids',
+ ARRAY['RxCUI:3498', 'RxCUI:596', 'RxCUI:6470', 'RxCUI:39993', 'RxCUI:6754'], ARRAY['R06AA', 'N05BA', 'N05CD', 'N05CF'],
+ 'Delirium', ARRAY['F05'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'May induce or worsen delirium',
+ 'Avoid. Taper benzodiazepines rather than stopping abruptly.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T3-PARKINSON', 'drug_disease', 'Antiemetics and antipsychotics with dopamine antagonism',
+ ARRAY['RxCUI:6915', 'RxCUI:8704', 'RxCUI:8745', 'RxCUI:5093', 'RxCUI:35636', 'RxCUI:61381'], NULL,
+ 'Parkinson disease', ARRAY['G20'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Dopamine-receptor antagonists worsen parkinsonian symptoms',
+ 'Avoid. Quetiapine, clozapine and pimavanserin are exceptions for psychosis.', ARRAY['Quetiapine', 'Ondansetron'], 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T3-SYNCOPE', 'drug_disease', 'Alpha-1 blockers, tertiary TCAs and cholinesterase inhibitors',
+ ARRAY['RxCUI:49276', 'RxCUI:8629', 'RxCUI:37798', 'RxCUI:704', 'RxCUI:135447'], ARRAY['C02CA', 'N06DA'],
+ 'Syncope', ARRAY['R55'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Orthostatic hypotension or bradycardia increases the risk of recurrent syncope',
+ 'Avoid.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T3-ULCER-NSAID', 'drug_disease', 'NSAIDs',
+ ARRAY['RxCUI:5640', 'RxCUI:7258', 'RxCUI:3355'], ARRAY['M01A'],
+ 'History of gastric or duodenal ulcer', ARRAY['K25', 'K26', 'K27', 'K28', 'Z8711'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'May exacerbate existing ulcers or cause new ulcers',
+ 'Avoid unless other alternatives are ineffective and the patient takes a PPI or misoprostol.', ARRAY['Acetaminophen'], 'moderate', 'strong'),
+
+-- Table 5: drug-drug interactions
+('BEERS', '2023', 'BEERS-T5-OPIOID-BENZODIAZEPINE', 'drug_drug', 'Opioids',
+ ARRAY['RxCUI:7052', 'RxCUI:7804', 'RxCUI:3423', 'RxCUI:10689', 'RxCUI:2670', 'RxCUI:5489'], ARRAY['N02A'],
+ NULL, NULL, NULL, 'Benzodiazepines', ARRAY['RxCUI:596', 'RxCUI:6470', 'RxCUI:3322', 'RxCUI:2598'], ARRAY['N05BA', 'N05CD'],
+ NULL, NULL, 'major',
+ 'Increased risk of overdose and severe sedation-related adverse events, including respiratory depression and death',
+ 'Avoid the combination.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T5-OPIOID-GABAPENTINOID', 'drug_drug', 'Opioids',
+ ARRAY['RxCUI:7052', 'RxCUI:7804', 'RxCUI:3423', 'RxCUI:10689', 'RxCUI:2670', 'RxCUI:5489'], ARRAY['N02A'],
+ NULL, NULL, NULL, 'Gabapentinoids', ARRAY['RxCUI:25480', 'RxCUI:187832'], NULL,
+ NULL, NULL, 'major',
+ 'Increased risk of severe sedation-related adverse events, including respiratory depression and death',
+ 'Avoid, except when transitioning from opioids to gabapentinoids or using gabapentinoids to reduce opioid dose.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T5-WARFARIN-SMX-TMP', 'drug_drug', 'Warfarin',
+ ARRAY['RxCUI:11289'], NULL,
+ NULL, NULL, NULL, 'Sulfamethoxazole-trimethoprim or ciprofloxacin', ARRAY['RxCUI:10831', 'RxCUI:10180', 'RxCUI:2551'], NULL,
+ NULL, NULL, 'major',
+ 'Increased risk of bleeding',
+ 'Avoid when possible; if used together, monitor INR closely.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T5-RAS-K-SPARING', 'drug_drug', 'ACE inhibitors, ARBs or ARNIs',
+ ARRAY['RXCUI:29046', 'RxCUI:3827', 'RXCUI:52175'], ARRAY['C09'],
+ NULL, NULL, NULL, 'Potassium-sparing diuretics', ARRAY['RxCUI:9997', 'RxCUI:10763', 'RxCUI:298869'], ARRAY['C03DA', 'C03DB'],
+ 'crcl', 30, 'major',
+ 'Increased risk of hyperkalemia',
+ 'Avoid in patients with CrCl <30 mL/min.', NULL, 'moderate', 'strong'),
+
+-- Table 6: renal function
+('BEERS', '2023', 'BEERS-T6-NITROFURANTOIN', 'renal', 'Nitrofurantoin',
+ ARRAY['RxCUI:7454'], NULL,
+ NULL, NULL, NULL, NULL, NULL, NULL, 'crcl', 30, 'major',
+ 'Potential for pulmonary toxicity, hepatotoxicity and peripheral neuropathy; lack of efficacy at low CrCl',
+ 'Avoid for CrCl <30 mL/min.', ARRAY['Cephalexin', 'Fosfomycin'], 'low', 'strong'),
+('BEERS', '2023', 'BEERS-T6-SPIRONOLACTONE', 'renal', 'Spironolactone',
+ ARRAY['RxCUI:9997'], NULL,
+ NULL, NULL, NULL, NULL, NULL, NULL, 'crcl', 30, 'major',
+ 'Increased potassium',
+ 'Avoid for CrCl <30 mL/min.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T6-DABIGATRAN', 'renal', 'Dabigatran',
+ ARRAY['RxCUI:1037042'], NULL,
+ NULL, NULL, NULL, NULL, NULL, NULL, 'crcl', 30, 'major',
+ 'Increased risk of bleeding',
+ 'Avoid for CrCl <30 mL/min.', ARRAY['Apixaban'], 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T6-GABAPENTIN', 'renal', 'Gabapentin',
+ ARRAY['RxCUI:25480'], NULL,
+ NULL, NULL, NULL, NULL, NULL, NULL, 'crcl', 60, 'moderate',
+ 'CNS adverse effects',
+ 'Reduce dose for CrCl <60 mL/min.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T6-FAMOTIDINE', 'renal', 'Famotidine',
+ ARRAY['RxCUI:4278'], NULL,
+ NULL, NULL, NULL, NULL, NULL, NULL, 'crcl', 50, 'moderate',
+ 'Mental status changes',
+ 'Reduce dose for CrCl <50 mL/min.', NULL, 'moderate', 'strong'),
+('BEERS', '2023', 'BEERS-T6-BACLOFEN', 'renal', 'Baclofen',
+ ARRAY['RxCUI:1292'], NULL,
+ NULL, NULL, NULL, NULL, NULL, NULL, 'egfr', 60, 'major',
+ 'Encephalopathy requiring hospitalization and dialysis',
+ 'Avoid for eGFR <60 mL/min/1.73m². If unavoidable, use the lowest effective dose and monitor for CNS toxicity.', NULL, 'moderate', 'strong');
+
+-- =============================================================================
+-- Seed: STOPP v3
+-- =============================================================================
+
+INSERT INTO pim_criteria (set_code, version, criterion_code, criterion_type, drug_description, drug_codes, atc_classes,
+                          condition_description, condition_codes, exception_condition_codes,
+                          interacting_description, interacting_drug_codes, interacting_atc_classes,
+                          renal_metric, renal_threshold, severity, rationale, recommendation, alternatives,
+                          quality_of_evidence, strength_of_recommendation) VALUES
+('STOPP', 'v3', 'STOPP-C-NSAID-ANTICOAGULANT', 'drug_drug', 'NSAIDs',
+ ARRAY['RxCUI:5640', 'RxCUI:7258', 'RxCUI:3355', 'RxCUI:140587'], ARRAY['M01A'],
+ NULL, NULL, NULL, 'Vitamin K antagonists or direct oral anticoagulants',
+ ARRAY['RxCUI:11289', 'RxCUI:1364430', 'RxCUI:1114195', 'RxCUI:1037042'], ARRAY['B01AA', 'B01AE', 'B01AF'],
+ NULL, NULL, 'major',
+ 'Risk of major gastrointestinal bleeding',
+ 'Stop the NSAID; use acetaminophen for pain.', ARRAY['Acetaminophen'], NULL, NULL),
+('STOPP', 'v3', 'STOPP-D-ANTICHOLINERGIC-DEMENTIA', 'drug_disease', 'Drugs with antimuscarinic activity',
+ ARRAY['RxCUI:3498', 'RxCUI:5553', 'RxCUI:704', 'RxCUI:32675'], ARRAY['R06AA', 'G04BD'],
+ 'Delirium or dementia', ARRAY['F01', 'F02', 'F03', 'F05', 'G30'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Risk of exacerbation of cognitive impairment',
+ 'Stop and review for alternatives without antimuscarinic activity.', NULL, NULL, NULL),
+('STOPP', 'v3', 'STOPP-E-NSAID-EGFR', 'renal', 'NSAIDs',
+ ARRAY['RxCUI:5640', 'RxCUI:7258', 'RxCUI:3355', 'RxCUI:140587'], ARRAY['M01A'],
+ NULL, NULL, NULL, NULL, NULL, NULL, 'egfr', 50, 'major',
+ 'Risk of deterioration in renal function',
+ 'Stop the NSAID when eGFR <50 mL/min/1.73m².', ARRAY['Acetaminophen'], NULL, NULL),
+('STOPP', 'v3', 'STOPP-E-METFORMIN-EGFR', 'renal', 'Metformin',
+ ARRAY['RxCUI:6809'], NULL,
+ NULL, NULL, NULL, NULL, NULL, NULL, 'egfr', 30, 'major',
+ 'Risk of lactic acidosis',
+ 'Stop metformin when eGFR <30 mL/min/1.73m².', NULL, NULL, NULL),
+('STOPP', 'v3', 'STOPP-G-BENZODIAZEPINE-RESP-FAILURE', 'drug_disease', 'Benzodiazepines',
+ ARRAY['RxCUI:596', 'RxCUI:6470', 'RxCUI:3322', 'RxCUI:2598'], ARRAY['N05BA', 'N05CD'],
+ 'Acute or chronic respiratory failure', ARRAY['J96'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Risk of exacerbation of respiratory failure',
+ 'Stop the benzodiazepine.', NULL, NULL, NULL),
+('STOPP', 'v3', 'STOPP-H-NSAID-HTN-HF', 'drug_disease', 'NSAIDs',
+ ARRAY['RxCUI:5640', 'RxCUI:7258', 'RxCUI:3355', 'RxCUI:140587'], ARRAY['M01A'],
+ 'Severe hypertension or heart failure', ARRAY['I50', 'I16'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Risk of exacerbation of hypertension or heart failure',
+ 'Stop the NSAID.', ARRAY['Acetaminophen'], NULL, NULL),
+('STOPP', 'v3', 'STOPP-J-LONG-ACTING-SULFONYLUREA', 'avoid', 'Long-acting sulfonylureas',
+ ARRAY['RxCUI:4815', 'RxCUI:25789'], NULL,
+ NULL, NULL, NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'Risk of prolonged hypoglycemia',
+ 'Stop; use an agent with lower hypoglycemia risk.', ARRAY['DPP-4 inhibitor', 'SGLT2 inhibitor'], NULL, NULL),
+('STOPP', 'v3', 'STOPP-K-FALLS-SEDATIVE', 'drug_disease', 'Sedative hypnotics, antipsychotics and Z-drugs',
+ ARRAY['RxCUI:596', 'RxCUI:6470', 'RxCUI:3322', 'RxCUI:39993', 'RxCUI:35636', 'RxCUI:5093'],
+ ARRAY['N05BA', 'N05CD', 'N05CF', 'N05A'],
+ 'History of falls', ARRAY['Z9181', 'W19', 'S72'], NULL, NULL, NULL, NULL, NULL, NULL, 'major',
+ 'May cause reduced sensorium and impaired balance',
+ 'Stop or reduce the dose, with a gradual taper where needed.', NULL, NULL, NULL),
+('STOPP', 'v3', 'STOPP-M-DUAL-ANTICHOLINERGIC', 'drug_drug', 'Drugs with antimuscarinic activity',
+ ARRAY['RxCUI:3498', 'RxCUI:5553', 'RxCUI:704', 'RxCUI:32675', 'RxCUI:21949'], ARRAY['R06AA', 'G04BD'],
+ NULL, NULL, NULL, 'Another drug with antimuscarinic activity',
+ ARRAY['RxCUI:3498', 'RxCUI:5553', 'RxCUI:704', 'RxCUI:32675', 'RxCUI:21949'], ARRAY['R06AA', 'G04BD'],
+ NULL, NULL, 'major',
+ 'Risk of increased antimuscarinic toxicity',
+ 'Stop one or both; review total anticholinergic burden.', NULL, NULL, NULL);
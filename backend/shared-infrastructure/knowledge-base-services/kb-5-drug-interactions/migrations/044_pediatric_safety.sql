@@ -0,0 +1,196 @@
+-- =============================================================================
+-- Migration 044: Pediatric Age- and Weight-Based Safety Rules
+-- =============================================================================
+-- age_band = 'pediatric' covered everyone under 18, but many pediatric risks
+-- depend on exact age or weight. Each row applies to an age range
+-- [min_age_days, max_age_days) and a weight range [min_weight_kg, max_weight_kg);
+-- NULL bounds are open. Ages are in days so neonatal rules (<= 28 days) and
+-- rules in years share one scale (1 year = 365.25 days, 1 month = 30.4375 days).
+--
+-- rule_type:
+--   * contraindication: the drug itself in the age/weight range
+--     (codeine under 12, tetracyclines under 8, promethazine under 2)
+--   * interaction:      the drug with an interacting drug or ATC class in the
+--                       regimen (ceftriaxone with IV calcium in neonates)
+--   * dose_limit:       weight-based maximums, mg/kg per dose and per day,
+--                       capped at absolute adult maximums; checked against orders
+--
+-- governance_action is the minimum governance action for a match, as for
+-- pgx_safety_rules; NULL leaves the action to the institution's severity policy.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS pediatric_safety_rules (
+    id SERIAL PRIMARY KEY,
+    rule_code VARCHAR(80) NOT NULL UNIQUE,
+    drug_code VARCHAR(50) NOT NULL,
+    drug_name VARCHAR(200) NOT NULL,
+    rule_type VARCHAR(20) NOT NULL CHECK (rule_type IN ('contraindication', 'interaction', 'dose_limit')),
+
+    min_age_days INT,                          -- inclusive
+    max_age_days INT,                          -- exclusive
+    min_weight_kg NUMERIC(6,2),                -- inclusive
+    max_weight_kg NUMERIC(6,2),                -- exclusive
+
+    interacting_description VARCHAR(200),
+    interacting_drug_codes TEXT[],
+    interacting_atc_classes TEXT[],
+
+    max_single_dose_mg_per_kg NUMERIC(8,3),
+    max_daily_dose_mg_per_kg NUMERIC(8,3),
+    max_single_dose_mg NUMERIC(10,2),          -- absolute cap
+    max_daily_dose_mg NUMERIC(10,2),           -- absolute cap
+
+    severity VARCHAR(20) NOT NULL CHECK (severity IN ('contraindicated', 'major', 'moderate', 'minor')),
+    governance_action VARCHAR(40)
+        CHECK (governance_action IN ('hard_block', 'hard_block_governance_override',
+                                     'mandatory_escalation', 'warn_acknowledge')),
+    risk_summary TEXT NOT NULL,
+    recommendation TEXT NOT NULL,
+    alternatives TEXT[],
+    evidence VARCHAR(20) NOT NULL DEFAULT 'B',
+    source VARCHAR(200) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CHECK (min_age_days IS NULL OR min_age_days >= 0),
+    CHECK (max_age_days IS NULL OR max_age_days > COALESCE(min_age_days, 0)),
+    CHECK (max_weight_kg IS NULL OR max_weight_kg > COALESCE(min_weight_kg, 0)),
+    CHECK (rule_type <> 'interaction' OR
+           interacting_drug_codes IS NOT NULL OR interacting_atc_classes IS NOT NULL),
+    CHECK (rule_type <> 'dose_limit' OR
+           COALESCE(max_single_dose_mg_per_kg, max_daily_dose_mg_per_kg,
+                    max_single_dose_mg, max_daily_dose_mg) IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pediatric_safety_rules_drug ON pediatric_safety_rules(drug_code) WHERE active;
+
+COMMENT ON TABLE pediatric_safety_rules IS 'Age- and weight-range pediatric contraindications, interactions and mg/kg dose limits.';
+
+-- =============================================================================
+-- Seed: contraindications and interactions
+-- =============================================================================
+
+INSERT INTO pediatric_safety_rules (rule_code, drug_code, drug_name, rule_type, min_age_days, max_age_days,
+                                    interacting_description, interacting_drug_codes, interacting_atc_classes,
+                                    severity, governance_action, risk_summary, recommendation, alternatives,
+                                    evidence, source) VALUES
+-- Ceftriaxone-calcium precipitates: fatal in neonates even with separate lines and times
+('PED-CEFTRIAXONE-CALCIUM-NEONATE', 'RxCUI:2193', 'Ceftriaxone', 'interaction', 0, 29,
+ 'Intravenous calcium', NULL, ARRAY['A12AA', 'B05XA07'],
+ 'contraindicated', 'hard_block_governance_override',
+ 'Fatal ceftriaxone-calcium precipitates in the lungs and kidneys of neonates',
+ 'Do not give ceftriaxone to neonates (28 days or younger) who need IV calcium, including separate lines or times.',
+ ARRAY['Cefotaxime'], 'A', 'FDA ceftriaxone labeling 2009'),
+('PED-CODEINE-UNDER-12', 'RxCUI:2670', 'Codeine', 'contraindication', 0, 4383,
+ NULL, NULL, NULL,
+ 'contraindicated', 'hard_block_governance_override',
+ 'Ultrarapid CYP2D6 metabolism to morphine causes respiratory depression and death in children',
+ 'Do not use codeine for pain or cough under 12 years.',
+ ARRAY['Acetaminophen', 'Ibuprofen'], 'A', 'FDA drug safety communication 2017'),
+('PED-TRAMADOL-UNDER-12', 'RxCUI:10689', 'Tramadol', 'contraindication', 0, 4383,
+ NULL, NULL, NULL,
+ 'contraindicated', 'hard_block_governance_override',
+ 'Ultrarapid CYP2D6 metabolism to O-desmethyltramadol causes respiratory depression in children',
+ 'Do not use tramadol under 12 years.',
+ ARRAY['Acetaminophen', 'Ibuprofen'], 'A', 'FDA drug safety communication 2017'),
+('PED-TETRACYCLINE-UNDER-8', 'RxCUI:10395', 'Tetracycline', 'contraindication', 0, 2922,
+ NULL, NULL, NULL,
+ 'major', 'mandatory_escalation',
+ 'Permanent tooth discoloration and enamel hypoplasia during tooth development',
+ 'Avoid under 8 years. Use doxycycline for short courses when a tetracycline is essential.',
+ ARRAY['Doxycycline', 'Amoxicillin', 'Azithromycin'], 'B', 'FDA tetracycline class labeling'),
+('PED-MINOCYCLINE-UNDER-8', 'RxCUI:6980', 'Minocycline', 'contraindication', 0, 2922,
+ NULL, NULL, NULL,
+ 'major', 'mandatory_escalation',
+ 'Permanent tooth discoloration and enamel hypoplasia during tooth development',
+ 'Avoid under 8 years. Use doxycycline for short courses when a tetracycline is essential.',
+ ARRAY['Doxycycline', 'Amoxicillin', 'Azithromycin'], 'B', 'FDA tetracycline class labeling'),
+('PED-DOXYCYCLINE-UNDER-8', 'RxCUI:3640', 'Doxycycline', 'contraindication', 0, 2922,
+ NULL, NULL, NULL,
+ 'moderate', 'warn_acknowledge',
+ 'Tooth staining has not been seen with courses of 21 days or less, unlike older tetracyclines',
+ 'Limit to 21 days or less under 8 years (e.g., rickettsial disease); use an alternative for longer courses.',
+ ARRAY['Amoxicillin', 'Azithromycin'], 'B', 'AAP Red Book 2021'),
+('PED-PROMETHAZINE-UNDER-2', 'RxCUI:8745', 'Promethazine', 'contraindication', 0, 730,
+ NULL, NULL, NULL,
+ 'contraindicated', 'hard_block_governance_override',
+ 'Fatal respiratory depression in children under 2 years',
+ 'Do not use promethazine under 2 years.',
+ ARRAY['Ondansetron'], 'A', 'FDA boxed warning'),
+('PED-LOPERAMIDE-UNDER-2', 'RxCUI:6468', 'Loperamide', 'contraindication', 0, 730,
+ NULL, NULL, NULL,
+ 'contraindicated', 'hard_block_governance_override',
+ 'Respiratory depression, ileus and CNS toxicity in children under 2 years',
+ 'Do not use loperamide under 2 years; treat with oral rehydration.',
+ ARRAY['Oral rehydration solution'], 'B', 'FDA loperamide labeling'),
+('PED-METOCLOPRAMIDE-UNDER-1', 'RxCUI:6915', 'Metoclopramide', 'contraindication', 0, 365,
+ NULL, NULL, NULL,
+ 'contraindicated', 'hard_block_governance_override',
+ 'Extrapyramidal reactions and methemoglobinemia in infants',
+ 'Do not use metoclopramide under 1 year.',
+ ARRAY['Ondansetron'], 'B', 'EMA metoclopramide referral 2013'),
+('PED-BENZOCAINE-UNDER-2', 'RxCUI:1399', 'Benzocaine', 'contraindication', 0, 730,
+ NULL, NULL, NULL,
+ 'contraindicated', 'hard_block_governance_override',
+ 'Methemoglobinemia with oral benzocaine products in children under 2 years',
+ 'Do not use benzocaine teething or oral products under 2 years.',
+ NULL, 'B', 'FDA drug safety communication 2018'),
+('PED-ASPIRIN-UNDER-16', 'RxCUI:1191', 'Aspirin', 'contraindication', 0, 5844,
+ NULL, NULL, NULL,
+ 'major', 'mandatory_escalation',
+ 'Reye syndrome with viral illness in children and adolescents',
+ 'Avoid under 16 years except on specialist advice (e.g., Kawasaki disease).',
+ ARRAY['Acetaminophen', 'Ibuprofen'], 'B', 'MHRA aspirin advice; AAP'),
+('PED-IBUPROFEN-UNDER-6-MONTHS', 'RxCUI:5640', 'Ibuprofen', 'contraindication', 0, 182,
+ NULL, NULL, NULL,
+ 'moderate', 'warn_acknowledge',
+ 'Oral ibuprofen is not established for pain or fever under 6 months; immature renal function',
+ 'Use acetaminophen under 6 months unless a specialist directs otherwise.',
+ ARRAY['Acetaminophen'], 'B', 'AAP fever and antipyretic use 2011'),
+('PED-CIPROFLOXACIN-UNDER-18', 'RxCUI:2551', 'Ciprofloxacin', 'contraindication', 0, 6574,
+ NULL, NULL, NULL,
+ 'moderate', NULL,
+ 'Arthropathy and tendon disorders are more frequent in children',
+ 'Reserve for infections with no safe and effective alternative.',
+ NULL, 'B', 'AAP fluoroquinolone clinical report 2016');
+
+-- =============================================================================
+-- Seed: weight-based dose limits
+-- =============================================================================
+
+INSERT INTO pediatric_safety_rules (rule_code, drug_code, drug_name, rule_type, min_age_days, max_age_days,
+                                    min_weight_kg, max_weight_kg,
+                                    max_single_dose_mg_per_kg, max_daily_dose_mg_per_kg,
+                                    max_single_dose_mg, max_daily_dose_mg,
+                                    severity, governance_action, risk_summary, recommendation, alternatives,
+                                    evidence, source) VALUES
+('PED-ACETAMINOPHEN-NEONATE-DOSE', 'RxCUI:161', 'Acetaminophen', 'dose_limit', 0, 29,
+ NULL, NULL, 15, 60, NULL, NULL,
+ 'major', NULL,
+ 'Hepatotoxicity; neonatal clearance is reduced',
+ 'Term neonates: up to 15 mg/kg per dose, maximum 60 mg/kg/day.',
+ NULL, 'B', 'BNF for Children'),
+('PED-ACETAMINOPHEN-DOSE', 'RxCUI:161', 'Acetaminophen', 'dose_limit', 29, 6574,
+ NULL, 50, 15, 75, 1000, 4000,
+ 'major', NULL,
+ 'Hepatotoxicity above weight-based maximums',
+ 'Up to 15 mg/kg per dose, maximum 75 mg/kg/day and no more than 4 g/day.',
+ NULL, 'A', 'AAP; FDA acetaminophen labeling'),
+('PED-ACETAMINOPHEN-DOSE-50KG', 'RxCUI:161', 'Acetaminophen', 'dose_limit', 29, 6574,
+ 50, NULL, NULL, NULL, 1000, 4000,
+ 'major', NULL,
+ 'Hepatotoxicity above adult maximums',
+ '50 kg and over: up to 1 g per dose, maximum 4 g/day.',
+ NULL, 'A', 'FDA acetaminophen labeling'),
+('PED-IBUPROFEN-DOSE', 'RxCUI:5640', 'Ibuprofen', 'dose_limit', 182, 6574,
+ NULL, NULL, 10, 40, 600, 2400,
+ 'major', NULL,
+ 'Renal injury and GI bleeding above weight-based maximums',
+ 'Up to 10 mg/kg per dose, maximum 40 mg/kg/day and no more than 2.4 g/day.',
+ NULL, 'A', 'AAP; BNF for Children'),
+('PED-AMOXICILLIN-DOSE', 'RxCUI:723', 'Amoxicillin', 'dose_limit', 90, 6574,
+ NULL, NULL, NULL, 100, NULL, 4000,
+ 'moderate', NULL,
+ 'Doses above high-dose regimens add toxicity without benefit',
+ 'Maximum 100 mg/kg/day (90 mg/kg/day high dose for otitis media) and no more than 4 g/day.',
+ NULL, 'B', 'AAP otitis media guideline 2013');
@@ -0,0 +1,107 @@
+-- =============================================================================
+-- Migration 045: Allowed Duplicate Therapy Patterns
+-- =============================================================================
+-- Duplicate therapy checks grouped drugs purely by ATC level, so scheduled plus
+-- PRN orders of one class, IV-to-PO transitions and intentional combinations
+-- (basal plus bolus insulin) all fired as duplicates. Each row describes an
+-- order pattern that is clinically acceptable within an ATC class. Patterns are
+-- evaluated against the medication orders of a duplicate group (route, PRN flag,
+-- status, indication, start/stop times); a group that fits is reported as an
+-- allowed combination with the pattern that allowed it.
+--
+-- pattern_type:
+--   * route_transition:        an order pending discontinuation overlaps its
+--                              replacement by a different route for no more
+--                              than max_overlap_hours
+--   * scheduled_prn:           one scheduled order plus one PRN order, allowed
+--                              when their combined maximum daily dose stays
+--                              within max_daily_dose (NULL: no dose limit)
+--   * intentional_combination: one order per component class; each entry of
+--                              component_atc_classes is one component, with
+--                              alternatives separated by '|'. When
+--                              indication_codes is set, an order indication or
+--                              patient condition must match one of them.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS ddi_duplicate_allowed_patterns (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  dataset_version TEXT NOT NULL,
+  pattern_code TEXT NOT NULL,
+  pattern_type TEXT NOT NULL
+    CHECK (pattern_type IN ('route_transition', 'scheduled_prn', 'intentional_combination')),
+  atc_code_pattern TEXT NOT NULL,     -- Every order in the group must fall under this ATC prefix
+  description TEXT NOT NULL,
+
+  max_daily_dose NUMERIC(10,2),       -- scheduled_prn: combined maximum daily dose
+  dose_unit TEXT,
+  max_overlap_hours NUMERIC(6,2),     -- route_transition: maximum overlap of the two orders
+  component_atc_classes TEXT[],       -- intentional_combination
+  indication_codes TEXT[],            -- intentional_combination: ICD-10 or SNOMED codes
+
+  rationale TEXT NOT NULL,
+  evidence evidence_level NOT NULL DEFAULT 'C',
+  active BOOLEAN DEFAULT TRUE,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+  UNIQUE (dataset_version, pattern_code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_duplicate_allowed_patterns_atc
+  ON ddi_duplicate_allowed_patterns(dataset_version, atc_code_pattern);
+
+DROP TRIGGER IF EXISTS trg_duplicate_allowed_patterns_updated ON ddi_duplicate_allowed_patterns;
+CREATE TRIGGER trg_duplicate_allowed_patterns_updated
+  BEFORE UPDATE ON ddi_duplicate_allowed_patterns
+  FOR EACH ROW EXECUTE FUNCTION update_phase3_timestamp();
+
+INSERT INTO ddi_duplicate_allowed_patterns (
+  dataset_version, pattern_code, pattern_type, atc_code_pattern, description,
+  max_daily_dose, dose_unit, max_overlap_hours, component_atc_classes, indication_codes,
+  rationale, evidence
+) VALUES
+-- Route transitions
+('2025Q4', 'DUP-ALLOW-ANTIBACTERIAL-IV-PO', 'route_transition', 'J01',
+ 'Antibacterial IV-to-oral step-down',
+ NULL, NULL, 24, NULL, NULL,
+ 'The parenteral order stays active until the first oral dose; a short overlap does not double exposure.', 'B'),
+('2025Q4', 'DUP-ALLOW-ANTIFUNGAL-IV-PO', 'route_transition', 'J02',
+ 'Antifungal IV-to-oral step-down',
+ NULL, NULL, 24, NULL, NULL,
+ 'The parenteral order stays active until the first oral dose; a short overlap does not double exposure.', 'B'),
+('2025Q4', 'DUP-ALLOW-PPI-IV-PO', 'route_transition', 'A02BC',
+ 'Proton pump inhibitor IV-to-oral transition',
+ NULL, NULL, 24, NULL, NULL,
+ 'IV proton pump inhibitors are switched to oral once tolerated; the orders overlap by at most one dosing interval.', 'C'),
+('2025Q4', 'DUP-ALLOW-CORTICOSTEROID-IV-PO', 'route_transition', 'H02AB',
+ 'Systemic corticosteroid IV-to-oral transition',
+ NULL, NULL, 24, NULL, NULL,
+ 'IV corticosteroids are switched to an oral equivalent; the orders overlap by at most one dosing interval.', 'C'),
+('2025Q4', 'DUP-ALLOW-OPIOID-IV-PO', 'route_transition', 'N02A',
+ 'Opioid parenteral-to-oral conversion',
+ NULL, NULL, 24, NULL, NULL,
+ 'Parenteral opioids are tapered while the oral regimen starts; overlap beyond 24 hours risks cumulative sedation.', 'C'),
+
+-- Scheduled plus PRN
+('2025Q4', 'DUP-ALLOW-ACETAMINOPHEN-SCHED-PRN', 'scheduled_prn', 'N02BE01',
+ 'Scheduled acetaminophen with PRN acetaminophen',
+ 4000, 'mg', NULL, NULL, NULL,
+ 'Acceptable when the scheduled dose plus the maximum PRN use stays within 4 g/day; above that the hepatotoxicity risk rises.', 'A'),
+('2025Q4', 'DUP-ALLOW-OPIOID-SCHED-PRN', 'scheduled_prn', 'N02A',
+ 'Scheduled opioid with PRN opioid for breakthrough pain',
+ NULL, NULL, NULL, NULL, NULL,
+ 'A scheduled long-acting opioid with a PRN short-acting opioid for breakthrough pain is standard practice; monitor sedation and respiratory rate.', 'B'),
+
+-- Intentional combinations
+('2025Q4', 'DUP-ALLOW-INSULIN-BASAL-BOLUS', 'intentional_combination', 'A10A',
+ 'Basal plus prandial insulin',
+ NULL, NULL, NULL, ARRAY['A10AE|A10AC', 'A10AB'], NULL,
+ 'Basal-bolus insulin combines a long- or intermediate-acting insulin with a rapid-acting insulin by design.', 'A'),
+('2025Q4', 'DUP-ALLOW-DUAL-ANTIPLATELET', 'intentional_combination', 'B01AC',
+ 'Dual antiplatelet therapy (aspirin plus P2Y12 inhibitor)',
+ NULL, NULL, NULL, ARRAY['B01AC06', 'B01AC04|B01AC22|B01AC24'],
+ ARRAY['I20', 'I21', 'I22', 'I24', 'I25', 'I63', 'G45', 'Z955'],
+ 'Aspirin with a P2Y12 inhibitor is indicated after acute coronary syndrome, coronary stenting and minor stroke or TIA.', 'A')
+ON CONFLICT (dataset_version, pattern_code) DO NOTHING;
+
+ANALYZE ddi_duplicate_allowed_patterns;
@@ -0,0 +1,128 @@
+-- =============================================================================
+-- Migration 046: Cumulative Daily Dose Limits
+-- =============================================================================
+-- Dose checks looked at one order at a time, so acetaminophen from three
+-- combination products, or two NSAIDs each at full dose, went unflagged. The
+-- cumulative dose engine (POST /api/v1/dosing/cumulative and the comprehensive
+-- check) splits every order into its active ingredients, sums the daily dose of
+-- each ingredient across the regimen and compares it with these limits.
+--
+-- ingredient_daily_dose_limits: maximum daily dose of an ingredient for adults
+-- or a patient context. A row applies when the patient is within
+-- [min_age_years, max_age_years), renal function (CrCl or eGFR) is below
+-- renal_below (dialysis counts as below), and/or the Child-Pugh class is in
+-- child_pugh_classes; NULL conditions always hold. The lowest applicable
+-- maximum is used. Ingredients sharing a cumulative_group are also summed as a
+-- fraction of each one's maximum: two NSAIDs at 75% each is 150% of one NSAID.
+--
+-- product_active_ingredients: strength of each active ingredient per dose form
+-- unit of a combination product. Orders for a product code are dosed in that
+-- unit (2 tablets). Seeded products use local product codes, as in 040.
+-- Pediatric weight-based maximums are in pediatric_safety_rules (044).
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS ingredient_daily_dose_limits (
+    id SERIAL PRIMARY KEY,
+    limit_code VARCHAR(80) NOT NULL UNIQUE,
+    ingredient_code VARCHAR(50) NOT NULL,
+    ingredient_name VARCHAR(200) NOT NULL,
+    cumulative_group VARCHAR(50),              -- e.g. 'NSAID'
+
+    min_age_years INT,                         -- inclusive
+    max_age_years INT,                         -- exclusive
+    renal_metric VARCHAR(10) CHECK (renal_metric IN ('crcl', 'egfr')),
+    renal_below NUMERIC(6,1),                  -- mL/min, exclusive
+    child_pugh_classes TEXT[],
+
+    dose_unit VARCHAR(20) NOT NULL DEFAULT 'mg',
+    max_daily_dose NUMERIC(10,2) NOT NULL,
+    rationale TEXT NOT NULL,
+    source VARCHAR(200) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((renal_metric IS NULL) = (renal_below IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingredient_dose_limits_ingredient
+    ON ingredient_daily_dose_limits(ingredient_code) WHERE active;
+
+CREATE TABLE IF NOT EXISTS product_active_ingredients (
+    id SERIAL PRIMARY KEY,
+    product_code VARCHAR(100) NOT NULL,
+    product_name VARCHAR(300) NOT NULL,
+    dose_form_unit VARCHAR(20) NOT NULL,       -- 'tablet', 'capsule', 'mL'
+    ingredient_code VARCHAR(50) NOT NULL,
+    ingredient_name VARCHAR(200) NOT NULL,
+    strength NUMERIC(10,3) NOT NULL,           -- per dose form unit
+    strength_unit VARCHAR(20) NOT NULL DEFAULT 'mg',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    UNIQUE (product_code, ingredient_code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_active_ingredients_ingredient
+    ON product_active_ingredients(ingredient_code) WHERE active;
+
+COMMENT ON TABLE ingredient_daily_dose_limits IS 'Maximum daily dose per active ingredient by age and renal/hepatic context.';
+COMMENT ON TABLE product_active_ingredients IS 'Active ingredient strengths of combination products.';
+
+INSERT INTO ingredient_daily_dose_limits (limit_code, ingredient_code, ingredient_name, cumulative_group,
+                                          min_age_years, max_age_years, renal_metric, renal_below, child_pugh_classes,
+                                          max_daily_dose, rationale, source) VALUES
+-- Acetaminophen
+('APAP-MAX', 'RxCUI:161', 'Acetaminophen', NULL, 12, NULL, NULL, NULL, NULL, 4000,
+ 'Acetaminophen above 4 g/day causes dose-dependent hepatotoxicity; combination products are a common source of unintended excess.',
+ 'FDA acetaminophen labeling'),
+('APAP-HEPATIC', 'RxCUI:161', 'Acetaminophen', NULL, 12, NULL, NULL, NULL, ARRAY['A', 'B', 'C'], 2000,
+ 'In cirrhosis, acetaminophen is preferred for pain but limited to 2 g/day.',
+ 'AASLD practice guidance; expert consensus'),
+-- NSAIDs: each ingredient's own maximum, summed as a class
+('IBUPROFEN-MAX', 'RxCUI:5640', 'Ibuprofen', 'NSAID', 18, NULL, NULL, NULL, NULL, 3200,
+ 'NSAID gastrointestinal, renal and cardiovascular toxicity is dose-dependent; NSAIDs should not be combined.',
+ 'Motrin prescribing information'),
+('NAPROXEN-MAX', 'RxCUI:7258', 'Naproxen', 'NSAID', 18, NULL, NULL, NULL, NULL, 1500,
+ 'NSAID gastrointestinal, renal and cardiovascular toxicity is dose-dependent; NSAIDs should not be combined.',
+ 'Naprosyn prescribing information'),
+('DICLOFENAC-MAX', 'RxCUI:3355', 'Diclofenac', 'NSAID', 18, NULL, NULL, NULL, NULL, 150,
+ 'NSAID gastrointestinal, renal and cardiovascular toxicity is dose-dependent; NSAIDs should not be combined.',
+ 'Voltaren prescribing information'),
+('CELECOXIB-MAX', 'RxCUI:140587', 'Celecoxib', 'NSAID', 18, NULL, NULL, NULL, NULL, 400,
+ 'Cardiovascular risk rises with dose; combining with another NSAID adds gastrointestinal risk.',
+ 'Celebrex prescribing information'),
+-- Citalopram: QT prolongation
+('CITALOPRAM-MAX', 'RxCUI:2556', 'Citalopram', NULL, 18, NULL, NULL, NULL, NULL, 40,
+ 'Citalopram prolongs the QT interval dose-dependently; doses above 40 mg/day add no benefit.',
+ 'FDA Drug Safety Communication 2011/2012'),
+('CITALOPRAM-AGE-60', 'RxCUI:2556', 'Citalopram', NULL, 60, NULL, NULL, NULL, NULL, 20,
+ 'Exposure is higher in patients over 60; doses above 20 mg/day raise QT risk.',
+ 'FDA Drug Safety Communication 2011/2012'),
+('CITALOPRAM-HEPATIC', 'RxCUI:2556', 'Citalopram', NULL, 18, NULL, NULL, NULL, ARRAY['A', 'B', 'C'], 20,
+ 'Hepatic impairment increases citalopram exposure; maximum 20 mg/day.',
+ 'Celexa prescribing information'),
+-- Metformin: including fixed-dose combinations
+('METFORMIN-MAX', 'RxCUI:6809', 'Metformin', NULL, 18, NULL, NULL, NULL, NULL, 2550,
+ 'Maximum immediate-release dose; fixed-dose combinations count toward the total.',
+ 'Glucophage prescribing information'),
+('METFORMIN-EGFR-45', 'RxCUI:6809', 'Metformin', NULL, 18, NULL, 'egfr', 45, NULL, 1000,
+ 'With eGFR below 45, reduce metformin to at most 1000 mg/day to limit accumulation and lactic acidosis risk.',
+ 'KDIGO 2022 Diabetes in CKD guideline')
+ON CONFLICT (limit_code) DO NOTHING;
+
+INSERT INTO product_active_ingredients (product_code, product_name, dose_form_unit,
+                                        ingredient_code, ingredient_name, strength) VALUES
+('LOCAL:OXYCODONE-APAP-5-325-TAB', 'Oxycodone/acetaminophen 5 mg/325 mg tablet', 'tablet', 'RxCUI:7804', 'Oxycodone', 5),
+('LOCAL:OXYCODONE-APAP-5-325-TAB', 'Oxycodone/acetaminophen 5 mg/325 mg tablet', 'tablet', 'RxCUI:161', 'Acetaminophen', 325),
+('LOCAL:HYDROCODONE-APAP-10-325-TAB', 'Hydrocodone/acetaminophen 10 mg/325 mg tablet', 'tablet', 'RxCUI:5489', 'Hydrocodone', 10),
+('LOCAL:HYDROCODONE-APAP-10-325-TAB', 'Hydrocodone/acetaminophen 10 mg/325 mg tablet', 'tablet', 'RxCUI:161', 'Acetaminophen', 325),
+('LOCAL:CODEINE-APAP-30-300-TAB', 'Acetaminophen/codeine 300 mg/30 mg tablet', 'tablet', 'RxCUI:2670', 'Codeine', 30),
+('LOCAL:CODEINE-APAP-30-300-TAB', 'Acetaminophen/codeine 300 mg/30 mg tablet', 'tablet', 'RxCUI:161', 'Acetaminophen', 300),
+('LOCAL:DIPHENHYDRAMINE-APAP-25-500-TAB', 'Acetaminophen/diphenhydramine 500 mg/25 mg caplet', 'tablet', 'RxCUI:3498', 'Diphenhydramine', 25),
+('LOCAL:DIPHENHYDRAMINE-APAP-25-500-TAB', 'Acetaminophen/diphenhydramine 500 mg/25 mg caplet', 'tablet', 'RxCUI:161', 'Acetaminophen', 500),
+('LOCAL:APAP-160MG-5ML-SOLN', 'Acetaminophen 160 mg/5 mL oral solution', 'mL', 'RxCUI:161', 'Acetaminophen', 32),
+('LOCAL:IBUPROFEN-FAMOTIDINE-800-26.6-TAB', 'Ibuprofen/famotidine 800 mg/26.6 mg tablet', 'tablet', 'RxCUI:5640', 'Ibuprofen', 800),
+('LOCAL:IBUPROFEN-FAMOTIDINE-800-26.6-TAB', 'Ibuprofen/famotidine 800 mg/26.6 mg tablet', 'tablet', 'RxCUI:4278', 'Famotidine', 26.6),
+('LOCAL:SITAGLIPTIN-METFORMIN-50-1000-TAB', 'Sitagliptin/metformin 50 mg/1000 mg tablet', 'tablet', 'RxCUI:593411', 'Sitagliptin', 50),
+('LOCAL:SITAGLIPTIN-METFORMIN-50-1000-TAB', 'Sitagliptin/metformin 50 mg/1000 mg tablet', 'tablet', 'RxCUI:6809', 'Metformin', 1000)
+ON CONFLICT (product_code, ingredient_code) DO NOTHING;
+
+ANALYZE ingredient_daily_dose_limits;
+ANALYZE product_active_ingredients;
@@ -0,0 +1,196 @@
+-- =============================================================================
+-- Migration 047: Food, Alcohol and Herbal Modifier Rules with Time Windows
+-- =============================================================================
+-- The modifier engine hardcoded grapefruit, tyramine, St John's wort and ginkgo
+-- rules by drug name and ignored when the patient ate, drank or started a
+-- product. Every modifier rule now lives in ddi_modifiers, keyed by drug code,
+-- with the window in which the exposure matters:
+--
+--   * intermittent exposures (a meal, a drink): the effect starts onset_hours
+--     after intake and lasts offset_hours after it (grapefruit CYP3A4
+--     inhibition ~72 h).
+--   * continuous exposures (an herbal product or supplement taken daily, current
+--     alcohol use): the effect becomes significant onset_hours after starting
+--     and persists offset_hours after stopping (St John's wort induction ~2
+--     weeks). While the product is taken the effect has no end.
+--
+-- A rule with food_state fires on the patient's fed or fasting state instead of
+-- a specific food. The patient counts as fed within offset_hours of the last
+-- meal: 'fasting' rules ("take with food") fire when fasting, 'fed' rules
+-- ("take on an empty stomach") when fed.
+--
+-- Modifier names and aliases match the patient's reported products
+-- case-insensitively as substrings; food_categories match FoodItem.category.
+-- drug_name lets callers that pass drug names instead of codes still match.
+-- =============================================================================
+
+ALTER TABLE ddi_modifiers DROP CONSTRAINT IF EXISTS ddi_modifiers_modifier_type_check;
+ALTER TABLE ddi_modifiers ADD CONSTRAINT ddi_modifiers_modifier_type_check
+  CHECK (modifier_type IN ('food', 'alcohol', 'herbal', 'supplement', 'disease'));
+
+ALTER TABLE ddi_modifiers
+  ADD COLUMN IF NOT EXISTS modifier_name TEXT,
+  ADD COLUMN IF NOT EXISTS modifier_aliases TEXT[],
+  ADD COLUMN IF NOT EXISTS food_categories TEXT[],
+  ADD COLUMN IF NOT EXISTS food_state TEXT CHECK (food_state IN ('fasting', 'fed')),
+  ADD COLUMN IF NOT EXISTS drug_name TEXT,
+  ADD COLUMN IF NOT EXISTS mechanism TEXT,
+  ADD COLUMN IF NOT EXISTS exposure_pattern TEXT NOT NULL DEFAULT 'intermittent'
+    CHECK (exposure_pattern IN ('intermittent', 'continuous')),
+  ADD COLUMN IF NOT EXISTS onset_hours NUMERIC(7,1) NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS offset_hours NUMERIC(7,1),      -- NULL: intermittent 24 h, continuous ends on stopping
+  ADD COLUMN IF NOT EXISTS confidence_score NUMERIC(3,2) NOT NULL DEFAULT 0.80;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ddi_modifiers_rule
+  ON ddi_modifiers (dataset_version, modifier_type, modifier_name, drug_code);
+
+-- Name and time the rules seeded in 002
+UPDATE ddi_modifiers SET modifier_name = 'tyramine',
+  modifier_aliases = ARRAY['aged', 'fermented', 'cured meat', 'tap beer', 'soy sauce'],
+  food_categories = ARRAY['tyramine_rich'], offset_hours = 24
+WHERE modifier_code = 'SNOMED:102259006' AND modifier_name IS NULL;
+
+UPDATE ddi_modifiers SET modifier_name = 'alcohol', offset_hours = 24
+WHERE modifier_code = 'SNOMED:53041004' AND modifier_name IS NULL;
+
+UPDATE ddi_modifiers SET modifier_name = 'ginkgo', modifier_aliases = ARRAY['ginkgo biloba'],
+  exposure_pattern = 'continuous', offset_hours = 336
+WHERE modifier_code = 'SNOMED:726542003' AND modifier_name IS NULL;
+
+-- Meal-state rules previously inferred from the management text
+UPDATE ddi_modifiers SET food_state = 'fasting', offset_hours = COALESCE(offset_hours, 2)
+WHERE modifier_type = 'food' AND food_state IS NULL AND management_strategy ILIKE '%take with food%';
+UPDATE ddi_modifiers SET food_state = 'fed', offset_hours = COALESCE(offset_hours, 2)
+WHERE modifier_type = 'food' AND food_state IS NULL AND management_strategy ILIKE '%empty stomach%';
+
+INSERT INTO ddi_modifiers (
+  dataset_version, modifier_type, modifier_code, modifier_name, modifier_aliases, food_categories,
+  drug_code, drug_name, mechanism, effect, management_strategy, severity, evidence,
+  exposure_pattern, onset_hours, offset_hours, confidence_score
+) VALUES
+-- Grapefruit: irreversible intestinal CYP3A4 inhibition lasting ~72 h after intake
+('2025Q4', 'food', NULL, 'grapefruit', ARRAY['pomelo', 'seville orange'], ARRAY['grapefruit'],
+ 'RxCUI:83367', 'atorvastatin', 'CYP3A4 inhibition → decreased drug metabolism',
+ 'Increased statin levels → muscle toxicity risk',
+ 'Avoid grapefruit products. Consider alternative agent if needed.', 'major', 'A', 'intermittent', 0, 72, 0.95),
+('2025Q4', 'food', NULL, 'grapefruit', ARRAY['pomelo', 'seville orange'], ARRAY['grapefruit'],
+ 'RxCUI:36567', 'simvastatin', 'CYP3A4 inhibition → decreased drug metabolism',
+ 'Increased statin levels → severe myopathy risk',
+ 'Avoid grapefruit products. Consider alternative agent if needed.', 'major', 'A', 'intermittent', 0, 72, 0.95),
+('2025Q4', 'food', NULL, 'grapefruit', ARRAY['pomelo', 'seville orange'], ARRAY['grapefruit'],
+ 'RxCUI:17767', 'amlodipine', 'CYP3A4 inhibition → decreased drug metabolism',
+ 'Enhanced hypotension → dizziness, falls',
+ 'Avoid grapefruit products. Consider alternative agent if needed.', 'major', 'A', 'intermittent', 0, 72, 0.95),
+('2025Q4', 'food', NULL, 'grapefruit', ARRAY['pomelo', 'seville orange'], ARRAY['grapefruit'],
+ 'RxCUI:4316', 'felodipine', 'CYP3A4 inhibition → decreased drug metabolism',
+ 'Excessive calcium channel blockade',
+ 'Avoid grapefruit products. Consider alternative agent if needed.', 'major', 'A', 'intermittent', 0, 72, 0.95),
+('2025Q4', 'food', NULL, 'grapefruit', ARRAY['pomelo', 'seville orange'], ARRAY['grapefruit'],
+ 'RxCUI:3008', 'cyclosporine', 'CYP3A4 inhibition → decreased drug metabolism',
+ 'Increased immunosuppression → toxicity',
+ 'Avoid grapefruit products. Consider alternative agent if needed.', 'major', 'A', 'intermittent', 0, 72, 0.95),
+('2025Q4', 'food', NULL, 'grapefruit', ARRAY['pomelo', 'seville orange'], ARRAY['grapefruit'],
+ 'RxCUI:42316', 'tacrolimus', 'CYP3A4 inhibition → decreased drug metabolism',
+ 'Nephrotoxicity and neurotoxicity risk',
+ 'Avoid grapefruit products. Consider alternative agent if needed.', 'major', 'A', 'intermittent', 0, 72, 0.95),
+('2025Q4', 'food', NULL, 'grapefruit', ARRAY['pomelo', 'seville orange'], ARRAY['grapefruit'],
+ 'RxCUI:1827', 'buspirone', 'CYP3A4 inhibition → decreased drug metabolism',
+ 'Enhanced sedation and side effects',
+ 'Avoid grapefruit products. Consider alternative agent if needed.', 'major', 'A', 'intermittent', 0, 72, 0.95),
+('2025Q4', 'food', NULL, 'grapefruit', ARRAY['pomelo', 'seville orange'], ARRAY['grapefruit'],
+ 'RxCUI:10767', 'triazolam', 'CYP3A4 inhibition → decreased drug metabolism',
+ 'Prolonged sedation → respiratory depression',
+ 'Avoid grapefruit products. Consider alternative agent if needed.', 'major', 'A', 'intermittent', 0, 72, 0.95),
+('2025Q4', 'food', NULL, 'grapefruit', ARRAY['pomelo', 'seville orange'], ARRAY['grapefruit'],
+ 'RxCUI:83395', 'saquinavir', 'CYP3A4 inhibition → decreased drug metabolism',
+ 'Increased antiviral levels',
+ 'Avoid grapefruit products. Consider alternative agent if needed.', 'major', 'A', 'intermittent', 0, 72, 0.95),
+
+-- Tyramine with MAO inhibitors: pressor response within hours of the meal
+('2025Q4', 'food', 'SNOMED:102259006', 'tyramine', ARRAY['aged', 'fermented', 'cured meat', 'tap beer', 'soy sauce'], ARRAY['tyramine_rich'],
+ 'RxCUI:8123', 'phenelzine', 'MAOI prevents tyramine breakdown → hypertensive crisis',
+ 'Severe hypertension, headache, potential stroke',
+ 'IMMEDIATE: Strict tyramine-free diet required throughout MAOI therapy and for 2 weeks after stopping.', 'contraindicated', 'A', 'intermittent', 0, 24, 0.98),
+('2025Q4', 'food', 'SNOMED:102259006', 'tyramine', ARRAY['aged', 'fermented', 'cured meat', 'tap beer', 'soy sauce'], ARRAY['tyramine_rich'],
+ 'RxCUI:10734', 'tranylcypromine', 'MAOI prevents tyramine breakdown → hypertensive crisis',
+ 'Severe hypertension, headache, potential stroke',
+ 'IMMEDIATE: Strict tyramine-free diet required throughout MAOI therapy and for 2 weeks after stopping.', 'contraindicated', 'A', 'intermittent', 0, 24, 0.98),
+('2025Q4', 'food', 'SNOMED:102259006', 'tyramine', ARRAY['aged', 'fermented', 'cured meat', 'tap beer', 'soy sauce'], ARRAY['tyramine_rich'],
+ 'RxCUI:6011', 'isocarboxazid', 'MAOI prevents tyramine breakdown → hypertensive crisis',
+ 'Severe hypertension, headache, potential stroke',
+ 'IMMEDIATE: Strict tyramine-free diet required throughout MAOI therapy and for 2 weeks after stopping.', 'contraindicated', 'A', 'intermittent', 0, 24, 0.98),
+('2025Q4', 'food', 'SNOMED:102259006', 'tyramine', ARRAY['aged', 'fermented', 'cured meat', 'tap beer', 'soy sauce'], ARRAY['tyramine_rich'],
+ 'RxCUI:9639', 'selegiline', 'MAOI prevents tyramine breakdown → hypertensive crisis',
+ 'Severe hypertension, headache, potential stroke',
+ 'IMMEDIATE: Strict tyramine-free diet required throughout MAOI therapy and for 2 weeks after stopping.', 'contraindicated', 'A', 'intermittent', 0, 24, 0.98),
+
+-- St John's wort: CYP3A4/P-gp induction builds over ~10 days and wanes ~2 weeks after stopping
+('2025Q4', 'herbal', NULL, 'st johns wort', ARRAY['hypericum'], NULL,
+ 'RxCUI:11289', 'warfarin', 'CYP3A4/P-glycoprotein induction → increased drug clearance',
+ 'Reduced anticoagulation → thrombosis risk',
+ 'Discontinue St. John''s Wort or adjust drug dosing', 'major', 'A', 'continuous', 240, 336, 0.92),
+('2025Q4', 'herbal', NULL, 'st johns wort', ARRAY['hypericum'], NULL,
+ 'RxCUI:3407', 'digoxin', 'CYP3A4/P-glycoprotein induction → increased drug clearance',
+ 'Reduced cardiac efficacy',
+ 'Discontinue St. John''s Wort or adjust drug dosing', 'major', 'A', 'continuous', 240, 336, 0.92),
+('2025Q4', 'herbal', NULL, 'st johns wort', ARRAY['hypericum'], NULL,
+ 'RxCUI:3008', 'cyclosporine', 'CYP3A4/P-glycoprotein induction → increased drug clearance',
+ 'Transplant rejection risk',
+ 'Discontinue St. John''s Wort or adjust drug dosing', 'major', 'A', 'continuous', 240, 336, 0.92),
+('2025Q4', 'herbal', NULL, 'st johns wort', ARRAY['hypericum'], NULL,
+ 'RxCUI:42316', 'tacrolimus', 'CYP3A4/P-glycoprotein induction → increased drug clearance',
+ 'Transplant rejection risk',
+ 'Discontinue St. John''s Wort or adjust drug dosing', 'major', 'A', 'continuous', 240, 336, 0.92),
+('2025Q4', 'herbal', NULL, 'st johns wort', ARRAY['hypericum'], NULL,
+ 'RxCUI:114289', 'indinavir', 'CYP3A4/P-glycoprotein induction → increased drug clearance',
+ 'Reduced antiviral efficacy',
+ 'Discontinue St. John''s Wort or adjust drug dosing', 'major', 'A', 'continuous', 240, 336, 0.92),
+('2025Q4', 'herbal', NULL, 'st johns wort', ARRAY['hypericum'], NULL,
+ 'RxCUI:51499', 'irinotecan', 'CYP3A4/P-glycoprotein induction → increased drug clearance',
+ 'Reduced chemotherapy efficacy',
+ 'Discontinue St. John''s Wort or adjust drug dosing', 'major', 'A', 'continuous', 240, 336, 0.92),
+('2025Q4', 'herbal', NULL, 'st johns wort', ARRAY['hypericum'], NULL,
+ 'RxCUI:10438', 'theophylline', 'CYP3A4/P-glycoprotein induction → increased drug clearance',
+ 'Reduced bronchodilation',
+ 'Discontinue St. John''s Wort or adjust drug dosing', 'major', 'A', 'continuous', 240, 336, 0.92),
+('2025Q4', 'herbal', NULL, 'st johns wort', ARRAY['hypericum'], NULL,
+ 'RxCUI:8183', 'phenytoin', 'CYP3A4/P-glycoprotein induction → increased drug clearance',
+ 'Breakthrough seizures',
+ 'Discontinue St. John''s Wort or adjust drug dosing', 'major', 'A', 'continuous', 240, 336, 0.92),
+('2025Q4', 'herbal', NULL, 'st johns wort', ARRAY['hypericum'], NULL,
+ 'RxCUI:36437', 'sertraline', 'CYP3A4/P-glycoprotein induction → increased drug clearance',
+ 'Reduced antidepressant efficacy',
+ 'Discontinue St. John''s Wort or adjust drug dosing', 'major', 'A', 'continuous', 240, 336, 0.92),
+('2025Q4', 'herbal', NULL, 'st johns wort', ARRAY['hypericum'], NULL,
+ 'RxCUI:32937', 'paroxetine', 'CYP3A4/P-glycoprotein induction → increased drug clearance',
+ 'Reduced antidepressant efficacy',
+ 'Discontinue St. John''s Wort or adjust drug dosing', 'major', 'A', 'continuous', 240, 336, 0.92),
+
+-- Ginkgo: antiplatelet effect from the first doses, persisting up to 2 weeks after stopping
+('2025Q4', 'herbal', 'SNOMED:726542003', 'ginkgo', ARRAY['ginkgo biloba'], NULL,
+ 'RxCUI:11289', 'warfarin', 'Platelet aggregation inhibition → bleeding risk',
+ 'Additive bleeding risk → hemorrhage',
+ 'Monitor for bleeding signs. Consider discontinuation before surgery.', 'moderate', 'B', 'continuous', 0, 336, 0.78),
+('2025Q4', 'herbal', 'SNOMED:726542003', 'ginkgo', ARRAY['ginkgo biloba'], NULL,
+ 'RxCUI:5224', 'heparin', 'Platelet aggregation inhibition → bleeding risk',
+ 'Enhanced anticoagulation',
+ 'Monitor for bleeding signs. Consider discontinuation before surgery.', 'moderate', 'B', 'continuous', 0, 336, 0.78),
+('2025Q4', 'herbal', 'SNOMED:726542003', 'ginkgo', ARRAY['ginkgo biloba'], NULL,
+ 'RxCUI:1191', 'aspirin', 'Platelet aggregation inhibition → bleeding risk',
+ 'Increased bleeding tendency',
+ 'Monitor for bleeding signs. Consider discontinuation before surgery.', 'moderate', 'B', 'continuous', 0, 336, 0.78),
+('2025Q4', 'herbal', 'SNOMED:726542003', 'ginkgo', ARRAY['ginkgo biloba'], NULL,
+ 'RxCUI:32968', 'clopidogrel', 'Platelet aggregation inhibition → bleeding risk',
+ 'Platelet dysfunction synergy',
+ 'Monitor for bleeding signs. Consider discontinuation before surgery.', 'moderate', 'B', 'continuous', 0, 336, 0.78),
+('2025Q4', 'herbal', 'SNOMED:726542003', 'ginkgo', ARRAY['ginkgo biloba'], NULL,
+ 'RxCUI:1114195', 'rivaroxaban', 'Platelet aggregation inhibition → bleeding risk',
+ 'Additive bleeding risk',
+ 'Monitor for bleeding signs. Consider discontinuation before surgery.', 'moderate', 'B', 'continuous', 0, 336, 0.78),
+('2025Q4', 'herbal', 'SNOMED:726542003', 'ginkgo', ARRAY['ginkgo biloba'], NULL,
+ 'RxCUI:1364430', 'apixaban', 'Platelet aggregation inhibition → bleeding risk',
+ 'Enhanced anticoagulation',
+ 'Monitor for bleeding signs. Consider discontinuation before surgery.', 'moderate', 'B', 'continuous', 0, 336, 0.78)
+ON CONFLICT (dataset_version, modifier_type, modifier_name, drug_code) DO NOTHING;
+
+ANALYZE ddi_modifiers;
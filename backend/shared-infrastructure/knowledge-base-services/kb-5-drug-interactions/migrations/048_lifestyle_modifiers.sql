@@ -0,0 +1,120 @@
+-- =============================================================================
+-- Migration 048: Tobacco, Cannabis and Caffeine Modifiers
+-- =============================================================================
+-- Polycyclic aromatic hydrocarbons in tobacco and cannabis smoke induce
+-- CYP1A2. Smokers need higher doses of clozapine, olanzapine and theophylline,
+-- and stopping smoking (a smoke-free admission) lets levels rise by 50% or more
+-- within a week. Nicotine itself does not induce CYP1A2, so nicotine
+-- replacement and vaping do not prevent the rise. Caffeine is a CYP1A2
+-- substrate that competes with these drugs and accumulates with CYP1A2
+-- inhibitors.
+--
+-- Lifestyle exposures (ModifierContext.lifestyle_exposures) carry a category,
+-- quantity, route and start/stop dates. trigger_event says which change in
+-- exposure a rule fires on:
+--
+--   * exposure:   while exposed, as for other continuous modifiers (047)
+--   * initiation: the exposure started within the last offset_hours and
+--                 continues (new or resumed smoking: levels fall)
+--   * cessation:  the exposure stopped within the last offset_hours, or will
+--                 stop (levels rise)
+--
+-- min_quantity (in quantity_unit) excludes lighter exposures; an exposure with
+-- no quantity or a different unit is not excluded. routes limits a rule to
+-- exposures by those routes; an exposure without a route is not excluded.
+-- =============================================================================
+
+ALTER TABLE ddi_modifiers DROP CONSTRAINT IF EXISTS ddi_modifiers_modifier_type_check;
+ALTER TABLE ddi_modifiers ADD CONSTRAINT ddi_modifiers_modifier_type_check
+  CHECK (modifier_type IN ('food', 'alcohol', 'herbal', 'supplement', 'disease', 'tobacco', 'cannabis', 'caffeine'));
+
+ALTER TABLE ddi_modifiers
+  ADD COLUMN IF NOT EXISTS trigger_event TEXT NOT NULL DEFAULT 'exposure'
+    CHECK (trigger_event IN ('exposure', 'initiation', 'cessation')),
+  ADD COLUMN IF NOT EXISTS min_quantity NUMERIC(8,2),
+  ADD COLUMN IF NOT EXISTS quantity_unit TEXT,
+  ADD COLUMN IF NOT EXISTS routes TEXT[];
+
+-- Initiation and cessation rules share a modifier and drug
+DROP INDEX IF EXISTS idx_ddi_modifiers_rule;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ddi_modifiers_rule
+  ON ddi_modifiers (dataset_version, modifier_type, modifier_name, drug_code, trigger_event);
+
+INSERT INTO ddi_modifiers (
+  dataset_version, modifier_type, modifier_name, drug_code, drug_name, mechanism, effect, management_strategy,
+  severity, evidence, exposure_pattern, trigger_event, onset_hours, offset_hours,
+  min_quantity, quantity_unit, routes, confidence_score
+) VALUES
+-- Tobacco smoke: cessation raises levels, initiation lowers them
+('2025Q4', 'tobacco', 'tobacco smoke', 'RxCUI:2626', 'clozapine',
+ 'Loss of smoke-induced CYP1A2 activity → decreased clozapine clearance',
+ 'Clozapine levels rise 50-70% within 1-2 weeks of stopping smoking → sedation, hypotension, seizures',
+ 'Check a clozapine level before and 1 week after stopping; reduce the dose by about a third over the first week and monitor for toxicity.',
+ 'major', 'A', 'continuous', 'cessation', 0, 336, 7, 'cigarettes/day', ARRAY['smoked'], 0.95),
+('2025Q4', 'tobacco', 'tobacco smoke', 'RxCUI:2626', 'clozapine',
+ 'Polycyclic aromatic hydrocarbons induce CYP1A2 → increased clozapine clearance',
+ 'Clozapine levels fall after starting or resuming smoking → loss of efficacy, relapse',
+ 'Monitor clozapine levels and symptoms; the dose may need to rise by up to 50%.',
+ 'moderate', 'A', 'continuous', 'initiation', 0, 336, 7, 'cigarettes/day', ARRAY['smoked'], 0.90),
+('2025Q4', 'tobacco', 'tobacco smoke', 'RxCUI:61381', 'olanzapine',
+ 'Loss of smoke-induced CYP1A2 activity → decreased olanzapine clearance',
+ 'Olanzapine levels rise after stopping smoking → sedation, orthostatic hypotension, anticholinergic effects',
+ 'Monitor for olanzapine adverse effects after stopping smoking; consider a dose reduction of about 25%.',
+ 'moderate', 'B', 'continuous', 'cessation', 0, 336, 7, 'cigarettes/day', ARRAY['smoked'], 0.85),
+('2025Q4', 'tobacco', 'tobacco smoke', 'RxCUI:61381', 'olanzapine',
+ 'Polycyclic aromatic hydrocarbons induce CYP1A2 → increased olanzapine clearance',
+ 'Olanzapine levels fall after starting or resuming smoking → reduced efficacy',
+ 'Monitor response; smokers may need higher olanzapine doses.',
+ 'moderate', 'B', 'continuous', 'initiation', 0, 336, 7, 'cigarettes/day', ARRAY['smoked'], 0.80),
+('2025Q4', 'tobacco', 'tobacco smoke', 'RxCUI:10438', 'theophylline',
+ 'Loss of smoke-induced CYP1A2 activity → decreased theophylline clearance',
+ 'Theophylline levels rise after stopping smoking → nausea, arrhythmias, seizures',
+ 'Check theophylline levels within a week of stopping smoking; reduce the dose by 25-33% pre-emptively.',
+ 'major', 'A', 'continuous', 'cessation', 0, 336, 7, 'cigarettes/day', ARRAY['smoked'], 0.92),
+('2025Q4', 'tobacco', 'tobacco smoke', 'RxCUI:10438', 'theophylline',
+ 'Polycyclic aromatic hydrocarbons induce CYP1A2 → increased theophylline clearance',
+ 'Theophylline clearance rises by up to 50-100% in smokers → subtherapeutic levels',
+ 'Check theophylline levels after starting or resuming smoking; the dose may need to increase.',
+ 'moderate', 'A', 'continuous', 'initiation', 0, 336, 7, 'cigarettes/day', ARRAY['smoked'], 0.88),
+
+-- Smoked cannabis induces CYP1A2 like tobacco smoke
+('2025Q4', 'cannabis', 'cannabis', 'RxCUI:2626', 'clozapine',
+ 'Loss of smoke-induced CYP1A2 activity → decreased clozapine clearance',
+ 'Clozapine levels can rise after stopping smoked cannabis → sedation, seizures',
+ 'Check a clozapine level after stopping smoked cannabis, as for tobacco cessation.',
+ 'moderate', 'C', 'continuous', 'cessation', 0, 336, NULL, NULL, ARRAY['smoked'], 0.75),
+('2025Q4', 'cannabis', 'cannabis', 'RxCUI:2626', 'clozapine',
+ 'Cannabis smoke induces CYP1A2 → increased clozapine clearance',
+ 'Clozapine levels can fall after starting smoked cannabis → loss of efficacy',
+ 'Monitor clozapine levels and symptoms.',
+ 'moderate', 'C', 'continuous', 'initiation', 0, 336, NULL, NULL, ARRAY['smoked'], 0.70),
+('2025Q4', 'cannabis', 'cannabis', 'RxCUI:10438', 'theophylline',
+ 'Loss of smoke-induced CYP1A2 activity → decreased theophylline clearance',
+ 'Theophylline levels can rise after stopping smoked cannabis',
+ 'Check theophylline levels after stopping smoked cannabis.',
+ 'moderate', 'C', 'continuous', 'cessation', 0, 336, NULL, NULL, ARRAY['smoked'], 0.70),
+
+-- Caffeine: competes for CYP1A2 and accumulates with CYP1A2 inhibitors
+('2025Q4', 'caffeine', 'caffeine', 'RxCUI:2626', 'clozapine',
+ 'Competitive CYP1A2 inhibition by caffeine → decreased clozapine clearance',
+ 'High caffeine intake raises clozapine levels; sudden changes in intake change levels',
+ 'Keep caffeine intake steady; check a clozapine level if intake changes markedly.',
+ 'moderate', 'C', 'continuous', 'exposure', 0, 72, 400, 'mg/day', NULL, 0.70),
+('2025Q4', 'caffeine', 'caffeine', 'RxCUI:10438', 'theophylline',
+ 'Additive methylxanthine effects; competition for CYP1A2',
+ 'Tachycardia, tremor, insomnia and raised theophylline levels',
+ 'Limit caffeine intake during theophylline therapy.',
+ 'moderate', 'B', 'continuous', 'exposure', 0, 72, NULL, NULL, NULL, 0.80),
+('2025Q4', 'caffeine', 'caffeine', 'RxCUI:42355', 'fluvoxamine',
+ 'Strong CYP1A2 inhibition → caffeine half-life prolonged about fivefold',
+ 'Caffeine accumulation → jitteriness, insomnia, tachycardia',
+ 'Advise reducing caffeine intake during fluvoxamine therapy.',
+ 'moderate', 'B', 'continuous', 'exposure', 0, 72, NULL, NULL, NULL, 0.80),
+('2025Q4', 'caffeine', 'caffeine', 'RxCUI:2551', 'ciprofloxacin',
+ 'CYP1A2 inhibition → reduced caffeine clearance',
+ 'Caffeine accumulation → restlessness, insomnia, palpitations',
+ 'Advise limiting caffeine intake during ciprofloxacin therapy.',
+ 'minor', 'B', 'continuous', 'exposure', 0, 72, NULL, NULL, NULL, 0.70)
+ON CONFLICT (dataset_version, modifier_type, modifier_name, drug_code, trigger_event) DO NOTHING;
+
+ANALYZE ddi_modifiers;
@@ -0,0 +1,146 @@
+-- =============================================================================
+-- Migration 049: Administration-Timing Separation Rules
+-- =============================================================================
+-- Many interactions are managed by spacing doses rather than avoiding the
+-- combination: chelation of fluoroquinolones, tetracyclines and integrase
+-- inhibitors by polyvalent cations, reduced levothyroxine absorption with
+-- calcium and iron, bisphosphonates with food, and drugs bound by bile acid
+-- sequestrants. These rules encode the required separation windows and feed
+-- the daily administration scheduler (POST /api/v1/administration/schedule).
+--
+-- A rule matches a regimen drug by RxCUI (drug_codes) or ATC class at any level
+-- (atc_classes). separate_from names what it must be spaced from:
+--   * drug     - another regimen drug matching separated_drug_codes or
+--                separated_atc_classes
+--   * meal     - the patient's meal times
+--   * any_drug - every other enterally given regimen drug (bile acid sequestrants)
+--
+-- The drug must be given at least min_hours_before before the separated item,
+-- or at least min_hours_after after it: the separated item may not fall within
+-- (dose - min_hours_after, dose + min_hours_before). Because a daily schedule
+-- repeats, both windows apply to every pair of doses. Rules apply to oral and
+-- enteral tube administration only.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS administration_separation_rules (
+    id SERIAL PRIMARY KEY,
+    rule_code VARCHAR(50) NOT NULL UNIQUE,
+
+    drug_description VARCHAR(200) NOT NULL,       -- 'Fluoroquinolones'
+    drug_codes TEXT[],
+    atc_classes TEXT[],
+    separate_from VARCHAR(10) NOT NULL CHECK (separate_from IN ('drug', 'meal', 'any_drug')),
+    separated_description VARCHAR(200) NOT NULL,  -- 'Polyvalent cations (antacids, calcium, iron, zinc)'
+    separated_drug_codes TEXT[],
+    separated_atc_classes TEXT[],
+
+    min_hours_before NUMERIC(4,1) NOT NULL DEFAULT 0 CHECK (min_hours_before >= 0),
+    min_hours_after NUMERIC(4,1) NOT NULL DEFAULT 0 CHECK (min_hours_after >= 0),
+
+    severity VARCHAR(20) NOT NULL CHECK (severity IN ('contraindicated', 'major', 'moderate', 'minor')),
+    mechanism TEXT NOT NULL,
+    recommendation TEXT NOT NULL,
+    source TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CHECK (drug_codes IS NOT NULL OR atc_classes IS NOT NULL),
+    CHECK (separate_from <> 'drug' OR separated_drug_codes IS NOT NULL OR separated_atc_classes IS NOT NULL),
+    CHECK (min_hours_before > 0 OR min_hours_after > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_administration_separation_rules_active
+    ON administration_separation_rules(rule_code) WHERE active;
+
+COMMENT ON TABLE administration_separation_rules IS
+    'Minimum spacing between a drug and chelating drugs, meals or bile acid sequestrants, used to build administration schedules.';
+
+-- =============================================================================
+-- Seed
+-- =============================================================================
+-- Polyvalent cations: antacids (A02AA magnesium, A02AB aluminium, A02AD
+-- combinations), sucralfate, calcium (A12AA), zinc (A12CB) and oral iron (B03AA)
+
+INSERT INTO administration_separation_rules (rule_code, drug_description, drug_codes, atc_classes,
+                                             separate_from, separated_description, separated_drug_codes, separated_atc_classes,
+                                             min_hours_before, min_hours_after, severity, mechanism, recommendation, source) VALUES
+('SEP-FLUOROQUINOLONE-CATION', 'Fluoroquinolones',
+ ARRAY['RxCUI:2551', 'RxCUI:82122'], ARRAY['J01MA'],
+ 'drug', 'Polyvalent cations (antacids, sucralfate, calcium, iron, zinc)',
+ ARRAY['RxCUI:1897', 'RxCUI:24947', 'RxCUI:10156'], ARRAY['A02AA', 'A02AB', 'A02AD', 'A02BX02', 'A12AA', 'A12CB', 'B03AA'],
+ 2, 6, 'major',
+ 'Chelation with di- and trivalent cations reduces fluoroquinolone absorption by up to 90%',
+ 'Give the fluoroquinolone at least 2 hours before or 6 hours after cation-containing products.',
+ 'Ciprofloxacin and levofloxacin prescribing information'),
+('SEP-MOXIFLOXACIN-CATION', 'Moxifloxacin',
+ ARRAY['RxCUI:139462'], NULL,
+ 'drug', 'Polyvalent cations (antacids, sucralfate, calcium, iron, zinc)',
+ ARRAY['RxCUI:1897', 'RxCUI:24947', 'RxCUI:10156'], ARRAY['A02AA', 'A02AB', 'A02AD', 'A02BX02', 'A12AA', 'A12CB', 'B03AA'],
+ 4, 8, 'major',
+ 'Chelation with di- and trivalent cations reduces moxifloxacin absorption',
+ 'Give moxifloxacin at least 4 hours before or 8 hours after cation-containing products.',
+ 'Moxifloxacin prescribing information'),
+('SEP-TETRACYCLINE-CATION', 'Tetracyclines',
+ ARRAY['RxCUI:3640', 'RxCUI:10395', 'RxCUI:6980'], ARRAY['J01AA'],
+ 'drug', 'Polyvalent cations (antacids, sucralfate, calcium, iron, zinc)',
+ ARRAY['RxCUI:1897', 'RxCUI:24947', 'RxCUI:10156'], ARRAY['A02AA', 'A02AB', 'A02AD', 'A02BX02', 'A12AA', 'A12CB', 'B03AA'],
+ 2, 4, 'major',
+ 'Chelation with di- and trivalent cations forms insoluble complexes and reduces tetracycline absorption',
+ 'Give the tetracycline at least 2 hours before or 4 hours after cation-containing products.',
+ 'Doxycycline and tetracycline prescribing information; BNF'),
+('SEP-INSTI-CATION', 'Integrase inhibitors (dolutegravir, bictegravir)',
+ ARRAY['RxCUI:1433868'], ARRAY['J05AJ03', 'J05AR20'],
+ 'drug', 'Polyvalent cations (antacids, sucralfate, calcium, iron, zinc)',
+ ARRAY['RxCUI:1897', 'RxCUI:24947', 'RxCUI:10156'], ARRAY['A02AA', 'A02AB', 'A02AD', 'A02BX02', 'A12AA', 'A12CB', 'B03AA'],
+ 2, 6, 'major',
+ 'Chelation with polyvalent cations reduces integrase inhibitor absorption and risks virological failure',
+ 'Give the integrase inhibitor at least 2 hours before or 6 hours after cation-containing products. Calcium or iron may be taken together with dolutegravir only with food.',
+ 'Dolutegravir prescribing information; DHHS adult antiretroviral guidelines'),
+('SEP-LEVOTHYROXINE-CALCIUM-IRON', 'Levothyroxine',
+ ARRAY['RxCUI:10582'], ARRAY['H03AA01'],
+ 'drug', 'Calcium, iron, antacids and sucralfate',
+ ARRAY['RxCUI:1897', 'RxCUI:24947', 'RxCUI:10156'], ARRAY['A02AA', 'A02AB', 'A02AD', 'A02BX02', 'A12AA', 'B03AA'],
+ 4, 4, 'moderate',
+ 'Adsorption and complex formation in the gut reduce levothyroxine absorption → raised TSH',
+ 'Give levothyroxine at least 4 hours apart from calcium, iron, antacids and sucralfate.',
+ 'Levothyroxine prescribing information; ATA hypothyroidism guideline 2014'),
+('SEP-LEVOTHYROXINE-MEAL', 'Levothyroxine',
+ ARRAY['RxCUI:10582'], ARRAY['H03AA01'],
+ 'meal', 'Meals', NULL, NULL,
+ 0.5, 3, 'moderate',
+ 'Food reduces and varies levothyroxine absorption',
+ 'Give levothyroxine on an empty stomach, 30-60 minutes before breakfast or at bedtime at least 3 hours after the last meal.',
+ 'ATA hypothyroidism guideline 2014'),
+('SEP-BISPHOSPHONATE-MEAL', 'Oral bisphosphonates',
+ ARRAY['RxCUI:46041', 'RxCUI:73056', 'RxCUI:115264'], ARRAY['M05BA'],
+ 'meal', 'Meals', NULL, NULL,
+ 0.5, 6, 'major',
+ 'Food reduces bisphosphonate absorption to negligible levels',
+ 'Give after an overnight fast, at least 30 minutes before the first food or drink other than plain water (60 minutes for ibandronate).',
+ 'Alendronate, risedronate and ibandronate prescribing information'),
+('SEP-BISPHOSPHONATE-CATION', 'Oral bisphosphonates',
+ ARRAY['RxCUI:46041', 'RxCUI:73056', 'RxCUI:115264'], ARRAY['M05BA'],
+ 'drug', 'Calcium, iron, antacids and other polyvalent cations',
+ ARRAY['RxCUI:1897', 'RxCUI:24947'], ARRAY['A02AA', 'A02AB', 'A02AD', 'A12AA', 'A12CB', 'B03AA'],
+ 0.5, 2, 'major',
+ 'Polyvalent cations bind bisphosphonates in the gut and prevent absorption',
+ 'Give the bisphosphonate at least 30 minutes before calcium, iron or antacids; take those later in the day.',
+ 'Alendronate and risedronate prescribing information'),
+('SEP-SEQUESTRANT-ANY', 'Bile acid sequestrants (colestyramine, colestipol)',
+ ARRAY['RxCUI:2447', 'RxCUI:2685'], ARRAY['C10AC01', 'C10AC02'],
+ 'any_drug', 'Other oral medicines', NULL, NULL,
+ 4, 1, 'moderate',
+ 'Sequestrants bind co-administered drugs in the gut (levothyroxine, warfarin, digoxin, thiazides, fat-soluble vitamins)',
+ 'Give other oral medicines at least 1 hour before or 4 hours after the bile acid sequestrant.',
+ 'Colestyramine and colestipol prescribing information'),
+('SEP-COLESEVELAM-LEVOTHYROXINE', 'Levothyroxine',
+ ARRAY['RxCUI:10582'], ARRAY['H03AA01'],
+ 'drug', 'Colesevelam',
+ ARRAY['RxCUI:141626'], ARRAY['C10AC04'],
+ 4, 0, 'moderate',
+ 'Colesevelam binds levothyroxine and reduces its absorption',
+ 'Give levothyroxine at least 4 hours before colesevelam.',
+ 'Colesevelam prescribing information')
+ON CONFLICT (rule_code) DO NOTHING;
+
+ANALYZE administration_separation_rules;
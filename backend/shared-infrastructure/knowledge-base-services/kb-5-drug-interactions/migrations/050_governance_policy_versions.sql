@@ -0,0 +1,92 @@
+-- =============================================================================
+-- Migration 050: Versioned Governance Policies with Approval
+-- =============================================================================
+-- ddi_severity_governance_mappings was read once at startup and only changed by
+-- hand; effective_date, expiry_date and approved_by were never enforced. Each
+-- row is now one version of a named policy:
+--
+--   * Policies are created as drafts (status 'draft') by created_by. Drafts can
+--     be edited or discarded by their author only.
+--   * Approval needs an approved_by distinct from created_by. A draft approved
+--     after its effective date takes effect on approval. The service takes
+--     created_by and approved_by from the caller identity the API gateway sets
+--     (X-User-ID), never from the request body.
+--   * Approved versions are immutable; the trigger below rejects updates and
+--     deletes. A change is a new draft version of the same policy_name, which
+--     records the version it supersedes. The one update allowed is retiring an
+--     approved version (active true → false), which stops it applying.
+--   * The policy applied to an institution at a time is its approved version
+--     with the latest effective_date on or before that time and no expiry_date
+--     at or before it. Institutions without one fall back to the global policy
+--     (institution_id ''). Activation and expiry need no update to the row.
+--
+-- Governed responses report the applied version's id.
+-- =============================================================================
+
+-- The global policy is institution '' so versions of one policy compare equal
+UPDATE ddi_severity_governance_mappings SET institution_id = '' WHERE institution_id IS NULL;
+ALTER TABLE ddi_severity_governance_mappings
+    ALTER COLUMN institution_id SET DEFAULT '',
+    ALTER COLUMN institution_id SET NOT NULL;
+
+ALTER TABLE ddi_severity_governance_mappings
+    ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'approved')),
+    ADD COLUMN IF NOT EXISTS created_by VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS change_summary TEXT,
+    ADD COLUMN IF NOT EXISTS supersedes_id UUID REFERENCES ddi_severity_governance_mappings(id);
+
+-- Existing policies were approved outside the service (the seed by 'system')
+UPDATE ddi_severity_governance_mappings
+SET status = 'approved',
+    created_by = 'migration',
+    approved_by = COALESCE(approved_by, 'system'),
+    approved_at = COALESCE(approved_at, created_at, NOW())
+WHERE status = 'draft';
+
+ALTER TABLE ddi_severity_governance_mappings
+    ALTER COLUMN created_by SET NOT NULL,
+    ADD CONSTRAINT governance_policy_approval_check CHECK (
+        status = 'draft' OR (approved_by IS NOT NULL AND approved_at IS NOT NULL AND approved_by <> created_by)
+    ),
+    ADD CONSTRAINT governance_policy_expiry_check CHECK (expiry_date IS NULL OR expiry_date > effective_date);
+
+-- A policy name now has one row per version
+ALTER TABLE ddi_severity_governance_mappings DROP CONSTRAINT IF EXISTS unique_active_policy_per_institution;
+DROP INDEX IF EXISTS idx_governance_policy_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_governance_policy_version
+    ON ddi_severity_governance_mappings(policy_name, version);
+
+CREATE INDEX IF NOT EXISTS idx_governance_policy_effective
+    ON ddi_severity_governance_mappings(institution_id, effective_date DESC)
+    WHERE status = 'approved' AND active;
+
+-- Approved versions are the audit record of what was enforced; they can only
+-- be retired
+CREATE OR REPLACE FUNCTION prevent_approved_governance_policy_change() RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.status = 'approved' THEN
+        IF TG_OP = 'UPDATE' AND OLD.active AND NOT NEW.active
+           AND to_jsonb(NEW) - 'active' - 'updated_at' = to_jsonb(OLD) - 'active' - 'updated_at' THEN
+            NEW.updated_at := NOW();
+            RETURN NEW;
+        END IF;
+        RAISE EXCEPTION 'governance policy % version % is approved and cannot be changed', OLD.policy_name, OLD.version;
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    NEW.updated_at := NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_governance_policy_immutable ON ddi_severity_governance_mappings;
+CREATE TRIGGER trg_governance_policy_immutable
+    BEFORE UPDATE OR DELETE ON ddi_severity_governance_mappings
+    FOR EACH ROW EXECUTE FUNCTION prevent_approved_governance_policy_change();
+
+COMMENT ON COLUMN ddi_severity_governance_mappings.version IS 'Version number within policy_name, from 1';
+COMMENT ON COLUMN ddi_severity_governance_mappings.status IS 'draft (editable) or approved (immutable apart from retiring)';
+COMMENT ON COLUMN ddi_severity_governance_mappings.supersedes_id IS 'The latest approved version when this version was drafted';
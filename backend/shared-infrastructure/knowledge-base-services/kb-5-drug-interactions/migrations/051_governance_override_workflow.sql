@@ -0,0 +1,73 @@
+-- =============================================================================
+-- Migration 051: Governance Override Request Workflow
+-- =============================================================================
+-- ddi_governance_override_requests (migration 005) had no service behind it.
+-- Alerts governed as mandatory_escalation or hard_block_governance_override now
+-- have a request → decision workflow (POST /api/v1/governance/overrides):
+--
+--   * A clinician submits a request for a patient and the alert's drug pair
+--     (drug2_code is the alert's second code, which may be a gene, condition or
+--     age marker rather than a drug). The required override level comes from
+--     the governance action: senior_clinical for mandatory_escalation,
+--     governance for hard_block_governance_override. hard_block is never
+--     overridable.
+--   * A pending request must be decided by decision_due_at or it expires.
+--   * The approver or denier must hold a role with authority for the required
+--     level and must not be the requester. Requester and approver IDs and
+--     roles are the caller's, from the X-User-ID and X-User-Role headers the
+--     API gateway sets; the request body cannot supply them.
+--   * An approval is valid from valid_from until valid_until, after which it
+--     expires. While valid, governed checks for the same patient, institution
+--     and pair honour it for governance actions no stricter than the one
+--     approved.
+--   * A request names the transaction_id and policy_version_id of the
+--     governed check whose alert it overrides. policy_version_id must be the
+--     built-in default or an approved version in
+--     ddi_severity_governance_mappings of the request's institution or the
+--     global policy. audit_id, when given, must exist in ddi_attribution_audit
+--     (foreign key from migration 005).
+--   * Not enforced: governed checks are not recorded in ddi_attribution_audit,
+--     so transaction_id is only checked to be a UUID and cannot be matched to
+--     the check, drug pair or patient it came from.
+--
+-- Expiry needs no job: pending and approved requests past their deadline are
+-- reported and honoured as expired, and the status is written back when
+-- requests are next read or decided.
+-- =============================================================================
+
+ALTER TABLE ddi_governance_override_requests
+    ADD COLUMN IF NOT EXISTS institution_id VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS interaction_id VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS drug1_code VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS drug2_code VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS required_override_level VARCHAR(30),
+    ADD COLUMN IF NOT EXISTS policy_version_id VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS decision_due_at TIMESTAMPTZ;
+
+ALTER TABLE ddi_governance_override_requests
+    DROP CONSTRAINT IF EXISTS override_request_decision_check,
+    DROP CONSTRAINT IF EXISTS override_request_validity_check;
+
+ALTER TABLE ddi_governance_override_requests
+    ADD CONSTRAINT override_request_decision_check CHECK (
+        status NOT IN ('approved', 'denied')
+        OR (approver_id IS NOT NULL AND approver_role IS NOT NULL AND approval_decision_at IS NOT NULL
+            AND approver_id <> requester_id)
+    ),
+    ADD CONSTRAINT override_request_validity_check CHECK (
+        status <> 'approved' OR (valid_from IS NOT NULL AND valid_until > valid_from)
+    );
+
+-- Lookup of approved overrides during governed checks
+CREATE INDEX IF NOT EXISTS idx_override_requests_patient_pair
+    ON ddi_governance_override_requests(patient_id, institution_id, drug1_code, drug2_code, valid_until)
+    WHERE status = 'approved';
+
+CREATE INDEX IF NOT EXISTS idx_override_requests_pending_due
+    ON ddi_governance_override_requests(decision_due_at)
+    WHERE status = 'pending';
+
+COMMENT ON COLUMN ddi_governance_override_requests.required_override_level IS
+    'GetOverrideLevel of governance_action: senior_clinical or governance';
+COMMENT ON COLUMN ddi_governance_override_requests.decision_due_at IS
+    'A pending request not decided by this time expires';
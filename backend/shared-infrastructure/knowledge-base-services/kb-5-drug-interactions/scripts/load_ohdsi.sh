@@ -8,7 +8,10 @@
 # This script implements the "Minimal Required Subset" principle:
 # - Filter 5.3M concepts → ~300k-600k rows
 # - Filter 36M relationships → ~1.5M-3M rows
+# - Filter CONCEPT_ANCESTOR to class closures (or compute from relationships)
 # - Create optimized indexes for Class Expansion
+# - Single-transaction direct load with release delta report:
+#     ohdsi-loader -load=<athena dir or zip> -dsn=... -report=delta.json
 # =============================================================================
 
 set -e  # Exit on error
@@ -33,10 +36,12 @@ DATA_DIR="/Users/apoorvabk/Downloads/cardiofit/backend/shared-infrastructure/kno
 # Files
 CONCEPT_FILE="$DATA_DIR/CONCEPT.csv"
 RELATIONSHIP_FILE="$DATA_DIR/CONCEPT_RELATIONSHIP.csv"
+ANCESTOR_FILE="$DATA_DIR/CONCEPT_ANCESTOR.csv"  # Optional - computed if absent
 
 # Temp files for filtered output
 FILTERED_CONCEPT="/tmp/ohdsi_concept_filtered.tsv"
 FILTERED_REL="/tmp/ohdsi_rel_filtered.tsv"
+FILTERED_ANCESTOR="/tmp/ohdsi_ancestor_filtered.tsv"
 
 # PSQL command with connection
 PSQL="psql -h $DB_HOST -p $DB_PORT -d $DB_NAME -U $DB_USER"
@@ -83,7 +88,7 @@ log "=== Step 1: Building OHDSI Loader ==="
 cd "$PROJECT_ROOT/cmd/ohdsi-loader"
 if [ ! -f "$LOADER_BIN" ] || [ "main.go" -nt "$LOADER_BIN" ]; then
     log "Compiling ohdsi-loader..."
-    go build -o ohdsi-loader main.go
+    go build -o ohdsi-loader .
     log "Loader built: $LOADER_BIN"
 else
     log "Loader already built: $LOADER_BIN"
@@ -111,6 +116,7 @@ DROP INDEX IF EXISTS idx_concept_code CASCADE;
 DROP INDEX IF EXISTS idx_rel_mapping CASCADE;
 
 -- Truncate tables (faster than DELETE)
+TRUNCATE TABLE ohdsi_concept_ancestor;
 TRUNCATE TABLE ohdsi_concept_relationship CASCADE;
 TRUNCATE TABLE ohdsi_concept CASCADE;
 " 2>/dev/null || log "Tables may not exist yet - will be created by migration"
@@ -157,6 +163,27 @@ $PSQL -c "\COPY ohdsi_concept_relationship(concept_id_1, concept_id_2, relations
 
 log "CONCEPT_RELATIONSHIP.csv loaded successfully"
 
+# =============================================================================
+# Step 4b: Load or Compute Concept Ancestor Closure
+# =============================================================================
+
+log "=== Step 4b: Building Concept Ancestor Closure ==="
+
+if [ -f "$ANCESTOR_FILE" ]; then
+    log "Filtering CONCEPT_ANCESTOR.csv to class closures..."
+    cat "$ANCESTOR_FILE" | "$LOADER_BIN" -type=ancestor -concepts="$FILTERED_CONCEPT" -v > "$FILTERED_ANCESTOR"
+
+    ANCESTOR_COUNT=$(wc -l < "$FILTERED_ANCESTOR")
+    log "Filtered ancestor rows: $ANCESTOR_COUNT rows (including header)"
+
+    $PSQL -c "\COPY ohdsi_concept_ancestor(ancestor_concept_id, descendant_concept_id, min_levels_of_separation, max_levels_of_separation) FROM '$FILTERED_ANCESTOR' WITH (FORMAT csv, DELIMITER E'\t', HEADER true)"
+else
+    log "CONCEPT_ANCESTOR.csv not found - computing closure from Is a / Subsumes / Drug has drug class"
+    ANCESTOR_COUNT=$($PSQL -t -A -c "SELECT rebuild_ohdsi_concept_ancestor();")
+fi
+
+log "Concept ancestor closure built: $ANCESTOR_COUNT rows"
+
 # =============================================================================
 # Step 5: Recreate Indexes
 # =============================================================================
@@ -216,6 +243,10 @@ $PSQL -t -c "
 SELECT 'Relationships loaded: ' || COUNT(*) FROM ohdsi_concept_relationship;
 "
 
+$PSQL -t -c "
+SELECT 'Concept ancestor rows: ' || COUNT(*) FROM ohdsi_concept_ancestor;
+"
+
 $PSQL -t -c "
 SELECT 'Drug has drug class edges: ' || COUNT(*)
 FROM ohdsi_concept_relationship
@@ -288,7 +319,7 @@ LIMIT 5;
 
 log "=== Step 8: Cleanup ==="
 
-rm -f "$FILTERED_CONCEPT" "$FILTERED_REL"
+rm -f "$FILTERED_CONCEPT" "$FILTERED_REL" "$FILTERED_ANCESTOR"
 log "Temporary files removed"
 
 # =============================================================================
@@ -300,6 +331,7 @@ log ""
 log "Summary:"
 log "  - Concepts: $CONCEPT_COUNT rows"
 log "  - Relationships: $REL_COUNT rows"
+log "  - Ancestor closure: $ANCESTOR_COUNT rows"
 log ""
 log "Next steps:"
 log "  1. Run: GET /constitutional/validate/warfarin-ibuprofen"